@@ -15,7 +15,8 @@
 package access
 
 const (
-	defaultMaxBlobSize uint32 = 1 << 22 // 4MB
+	defaultMaxBlobSize       uint32 = 1 << 22 // 4MB
+	defaultCompressBlockSize uint32 = 1 << 18 // 256KB
 
 	defaultDiskPunishIntervalS    int = 60
 	defaultServicePunishIntervalS int = 60
@@ -24,6 +25,11 @@ const (
 	defaultEncoderConcurrency     int = 1000
 	defaultMinReadShardsX         int = 1
 
+	defaultHedgePercentile float64 = 0.95
+	defaultHedgeMinDelayMS int     = 10
+	defaultHedgeMaxShards  int     = 2
+	defaultHedgeSamples    int     = 1024
+
 	// client timeout ms
 	defaultTimeoutClusterMgr int64 = 1000 * 3
 	defaultTimeoutAllocator  int64 = 1000 * 3
@@ -49,9 +49,14 @@ const (
 	AlgRoundRobin
 	// AlgRandom completely random alloc
 	AlgRandom
+	// AlgWeighted random alloc with operator-set weight scaled by available ratio
+	AlgWeighted
 	maxAlg
 )
 
+// defaultClusterWeight weight of cluster without operator-set weight
+const defaultClusterWeight = 100
+
 // IsValid returns valid algorithm or not.
 func (alg AlgChoose) IsValid() bool {
 	return alg > minAlg && alg < maxAlg
@@ -61,8 +66,12 @@ func (alg AlgChoose) String() string {
 	switch alg {
 	case AlgAvailable:
 		return "Available"
+	case AlgRoundRobin:
+		return "RoundRobin"
 	case AlgRandom:
 		return "Random"
+	case AlgWeighted:
+		return "Weighted"
 	default:
 		return "Unknow"
 	}
@@ -107,6 +116,8 @@ type ClusterConfig struct {
 
 	ServicePunishThreshold      uint32 `json:"service_punish_threshold"`
 	ServicePunishValidIntervalS int    `json:"service_punish_valid_interval_s"`
+	DiskReloadSecs              int    `json:"disk_reload_secs"`
+	SlowDiskPunishIntervalS     int    `json:"slow_disk_punish_interval_s"`
 }
 
 type cluster struct {
@@ -197,7 +208,7 @@ func (c *clusterControllerImpl) load() error {
 		}
 
 		allClusters[proto.ClusterID(clusterID)] = &cluster{clusterInfo: clusterInfo}
-		if !clusterInfo.Readonly && clusterInfo.Available > 0 {
+		if isWritable(clusterInfo) {
 			available = append(available, clusterInfo)
 			totalAvailable += clusterInfo.Available
 		} else {
@@ -226,7 +237,7 @@ func (c *clusterControllerImpl) load() error {
 
 		removeThisCluster := func() {
 			delete(allClusters, clusterID)
-			if !newCluster.Readonly && newCluster.Available > 0 {
+			if isWritable(newCluster) {
 				totalAvailable -= newCluster.Available
 				for j := range available {
 					if available[j].ClusterID == clusterID {
@@ -243,6 +254,8 @@ func (c *clusterControllerImpl) load() error {
 			ReloadSec:                   c.config.ServiceReloadSecs,
 			ServicePunishThreshold:      c.config.ServicePunishThreshold,
 			ServicePunishValidIntervalS: c.config.ServicePunishValidIntervalS,
+			DiskReloadSec:               c.config.DiskReloadSecs,
+			SlowDiskPunishIntervalS:     c.config.SlowDiskPunishIntervalS,
 		}, cmCli)
 		if err != nil {
 			removeThisCluster()
@@ -276,6 +289,24 @@ func (c *clusterControllerImpl) load() error {
 	return nil
 }
 
+// isWritable returns the cluster can be chosen to write or not,
+// readonly cluster is still readable and deletable.
+func isWritable(clusterInfo *cmapi.ClusterInfo) bool {
+	return !clusterInfo.Readonly && clusterInfo.Available > 0
+}
+
+// weightOf returns weight of cluster scaled by ratio of available space
+func weightOf(clusterInfo *cmapi.ClusterInfo) float64 {
+	weight := float64(clusterInfo.Weight)
+	if clusterInfo.Weight == 0 {
+		weight = defaultClusterWeight
+	}
+	if clusterInfo.Capacity > 0 && clusterInfo.Available < clusterInfo.Capacity {
+		weight *= float64(clusterInfo.Available) / float64(clusterInfo.Capacity)
+	}
+	return weight
+}
+
 func (c *clusterControllerImpl) Region() string {
 	return c.region
 }
@@ -322,6 +353,24 @@ func (c *clusterControllerImpl) ChooseOne() (*cmapi.ClusterInfo, error) {
 			return available[rand.Int63()%length], nil
 		}
 
+	case AlgWeighted:
+		available := c.available.Load().(clusterQueue)
+		totalWeight := float64(0)
+		for _, cluster := range available {
+			totalWeight += weightOf(cluster)
+		}
+		if totalWeight > 0 {
+			randValue := rand.Float64() * totalWeight
+			for _, cluster := range available {
+				weight := weightOf(cluster)
+				if weight > randValue {
+					return cluster, nil
+				}
+				randValue -= weight
+			}
+			return available[len(available)-1], nil
+		}
+
 	default:
 		return nil, fmt.Errorf("not implemented algorithm %s(%d)", alg.String(), alg)
 	}
@@ -264,6 +264,7 @@ func TestAccessClusterChooseOne(t *testing.T) {
 			controller.AlgAvailable,
 			controller.AlgRoundRobin,
 			controller.AlgRandom,
+			controller.AlgWeighted,
 		} {
 			cc1.ChangeChooseAlg(alg)
 			for range [100]struct{}{} {
@@ -311,6 +312,7 @@ func TestAccessClusterChangeChooseAlg(t *testing.T) {
 		{controller.AlgAvailable, nil},
 		{controller.AlgRoundRobin, nil},
 		{controller.AlgRandom, nil},
+		{controller.AlgWeighted, nil},
 		{1024, controller.ErrInvalidChooseAlg},
 	}
 	for _, cs := range cases {
@@ -325,6 +327,7 @@ func TestAccessClusterChooseBalance(t *testing.T) {
 		controller.AlgAvailable,
 		controller.AlgRoundRobin,
 		controller.AlgRandom,
+		controller.AlgWeighted,
 	} {
 		err := cc.ChangeChooseAlg(alg)
 		require.NoError(t, err)
@@ -339,3 +342,108 @@ func TestAccessClusterChooseBalance(t *testing.T) {
 		t.Logf("balance with algorithm %s: %+v", alg, m)
 	}
 }
+
+func TestAccessClusterChooseAlgString(t *testing.T) {
+	for alg, name := range map[controller.AlgChoose]string{
+		0:                        "Unknow",
+		controller.AlgAvailable:  "Available",
+		controller.AlgRoundRobin: "RoundRobin",
+		controller.AlgRandom:     "Random",
+		controller.AlgWeighted:   "Weighted",
+		1024:                     "Unknow",
+	} {
+		require.Equal(t, name, alg.String())
+	}
+}
+
+func newStableCC(clusters ...clustermgr.ClusterInfo) controller.ClusterController {
+	defer func() {
+		var data []byte
+		stableCluster.Store(data)
+	}()
+
+	var pairs api.KVPairs
+	for _, cluster := range clusters {
+		val, _ := json.Marshal(cluster)
+		pairs = append(pairs, &api.KVPair{Key: cluster.ClusterID.ToString(), Value: val})
+	}
+	data, _ := json.Marshal(pairs)
+	stableCluster.Store(data)
+	return newCC()
+}
+
+func TestAccessClusterChooseWeighted(t *testing.T) {
+	newCluster := func(clusterID proto.ClusterID, available int64, weight uint32) clustermgr.ClusterInfo {
+		return clustermgr.ClusterInfo{
+			Region:    region,
+			ClusterID: clusterID,
+			Capacity:  1 << 40,
+			Available: available,
+			Nodes:     []string{hostAddr},
+			Weight:    weight,
+		}
+	}
+
+	cc := newStableCC(
+		newCluster(1, 1<<40, 100),
+		newCluster(2, 1<<40, 300),
+		newCluster(3, 1<<39, 600),
+		newCluster(4, 1<<40, 0),
+	)
+	require.NoError(t, cc.ChangeChooseAlg(controller.AlgWeighted))
+
+	m := make(map[proto.ClusterID]int, 4)
+	n := 100000
+	for range make([]struct{}, n) {
+		cluster, err := cc.ChooseOne()
+		require.NoError(t, err)
+		m[cluster.ClusterID]++
+	}
+	t.Logf("weighted choose: %+v", m)
+	// effective weights are 100, 300, 300 and default 100
+	require.InDelta(t, 0.125, float64(m[1])/float64(n), 0.02)
+	require.InDelta(t, 0.375, float64(m[2])/float64(n), 0.02)
+	require.InDelta(t, 0.375, float64(m[3])/float64(n), 0.02)
+	require.InDelta(t, 0.125, float64(m[4])/float64(n), 0.02)
+}
+
+func TestAccessClusterReadonly(t *testing.T) {
+	writable := clustermgr.ClusterInfo{
+		Region:    region,
+		ClusterID: 1,
+		Capacity:  1 << 40,
+		Available: 1 << 30,
+		Nodes:     []string{hostAddr},
+	}
+	readonly := writable
+	readonly.ClusterID = 2
+	readonly.Available = 1 << 40
+	readonly.Readonly = true
+
+	cc := newStableCC(writable, readonly)
+	require.Equal(t, 2, len(cc.All()))
+	for _, alg := range []controller.AlgChoose{
+		controller.AlgAvailable,
+		controller.AlgRoundRobin,
+		controller.AlgRandom,
+		controller.AlgWeighted,
+	} {
+		require.NoError(t, cc.ChangeChooseAlg(alg))
+		for range [100]struct{}{} {
+			cluster, err := cc.ChooseOne()
+			require.NoError(t, err)
+			require.Equal(t, proto.ClusterID(1), cluster.ClusterID)
+		}
+	}
+
+	// reads and deletes of readonly cluster continue
+	_, err := cc.GetVolumeGetter(2)
+	require.NoError(t, err)
+	_, err = cc.GetServiceController(2)
+	require.NoError(t, err)
+
+	cc = newStableCC(readonly)
+	require.NoError(t, cc.ChangeChooseAlg(controller.AlgWeighted))
+	_, err = cc.ChooseOne()
+	require.Error(t, err)
+}
@@ -81,6 +81,25 @@ func init() {
 		},
 	}
 	dataVolumes[vid404] = cmapi.VolumeInfo{VolumeInfoBase: cmapi.VolumeInfoBase{Vid: vid404}}
+	dataVolumes[proto.Vid(11)] = cmapi.VolumeInfo{
+		VolumeInfoBase: cmapi.VolumeInfoBase{
+			Vid:         11,
+			CodeMode:    codemode.EC6P6,
+			RedirectVid: 12,
+		},
+		Units: []cmapi.Unit{
+			{Vuid: 11011, DiskID: 11021, Host: "11031"},
+		},
+	}
+	dataVolumes[proto.Vid(12)] = cmapi.VolumeInfo{
+		VolumeInfoBase: cmapi.VolumeInfoBase{
+			Vid:      12,
+			CodeMode: codemode.EC12P4,
+		},
+		Units: []cmapi.Unit{
+			{Vuid: 12011, DiskID: 12021, Host: "12031"},
+		},
+	}
 
 	dataNodes = make(map[string]cmapi.ServiceInfo)
 	dataNodes[proto.ServiceNameAllocator] = cmapi.ServiceInfo{
@@ -127,6 +146,15 @@ func init() {
 			DiskID: 10002,
 		},
 	}
+	dataDisks[10003] = bnapi.DiskInfo{
+		ClusterID: 1,
+		Idc:       idc,
+		Host:      "blobnode-3",
+		DiskHeartBeatInfo: bnapi.DiskHeartBeatInfo{
+			DiskID: 10003,
+			Slow:   true,
+		},
+	}
 
 	redismr, _ = miniredis.Run()
 	rediscli = redis.NewClusterClient(&redis.ClusterConfig{
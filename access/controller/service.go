@@ -40,6 +40,10 @@ const (
 	defaultServicePinishValidIntervalS int = 30
 	// default service punish check threshold
 	defaultServicePinishThreshold uint32 = 3
+	// default disk info reload interval, slow disk is found by reloading
+	defaultDiskReloadSec int = 60
+	// default punish interval of slow disk
+	defaultSlowDiskPunishIntervalS int = 60
 )
 
 // HostIDC item of host with idc
@@ -85,6 +89,8 @@ type hostItem struct {
 	lastModifyTime int64
 	// failedTimes record the service host failed times during some interval
 	failedTimes uint32
+	// load time record the last load time unix of disk host item
+	loadTimeUnix int64
 }
 
 func (h *hostItem) isPunish() bool {
@@ -98,6 +104,8 @@ type ServiceConfig struct {
 	ReloadSec                   int
 	ServicePunishThreshold      uint32
 	ServicePunishValidIntervalS int
+	DiskReloadSec               int
+	SlowDiskPunishIntervalS     int
 }
 
 type serviceControllerImpl struct {
@@ -116,6 +124,8 @@ type serviceControllerImpl struct {
 func NewServiceController(cfg ServiceConfig, cmCli clustermgr.APIAccess) (ServiceController, error) {
 	defaulter.Equal(&cfg.ServicePunishThreshold, defaultServicePinishThreshold)
 	defaulter.LessOrEqual(&cfg.ServicePunishValidIntervalS, defaultServicePinishValidIntervalS)
+	defaulter.LessOrEqual(&cfg.DiskReloadSec, defaultDiskReloadSec)
+	defaulter.LessOrEqual(&cfg.SlowDiskPunishIntervalS, defaultSlowDiskPunishIntervalS)
 
 	controller := &serviceControllerImpl{
 		serviceHosts: serviceMap{
@@ -262,6 +272,7 @@ func (s *serviceControllerImpl) GetDiskHost(ctx context.Context, diskID proto.Di
 	v, ok := s.allServices.Load(_diskHostServicePrefix + (diskID.ToString()))
 	if ok {
 		item := v.(*hostItem)
+		s.reloadDiskHost(diskID, item)
 		return &HostIDC{
 			Host:     item.host,
 			IDC:      item.idc,
@@ -281,8 +292,9 @@ func (s *serviceControllerImpl) GetDiskHost(ctx context.Context, diskID proto.Di
 	}
 	diskInfo := ret.(*blobnode.DiskInfo)
 
-	item := &hostItem{host: diskInfo.Host, idc: diskInfo.Idc}
+	item := &hostItem{host: diskInfo.Host, idc: diskInfo.Idc, loadTimeUnix: time.Now().Unix()}
 	s.allServices.Store(_diskHostServicePrefix+(diskInfo.DiskID.ToString()), item)
+	s.punishSlowDisk(ctx, diskInfo)
 	return &HostIDC{
 		Host:     item.host,
 		IDC:      item.idc,
@@ -290,6 +302,37 @@ func (s *serviceControllerImpl) GetDiskHost(ctx context.Context, diskID proto.Di
 	}, nil
 }
 
+// reloadDiskHost reloads disk info in background if expired,
+// then the slow disk reported by blobnode is punished proactively
+func (s *serviceControllerImpl) reloadDiskHost(diskID proto.DiskID, item *hostItem) {
+	loadTime := atomic.LoadInt64(&item.loadTimeUnix)
+	if time.Since(time.Unix(loadTime, 0)) < time.Duration(s.config.DiskReloadSec)*time.Second {
+		return
+	}
+	if !atomic.CompareAndSwapInt64(&item.loadTimeUnix, loadTime, time.Now().Unix()) {
+		return
+	}
+
+	go func() {
+		span, ctx := trace.StartSpanFromContext(context.Background(), "access_reload_disk")
+		diskInfo, err := s.cmClient.DiskInfo(ctx, diskID)
+		if err != nil {
+			span.Warn("reload disk info from clustermgr failed", diskID, err)
+			return
+		}
+		s.punishSlowDisk(ctx, diskInfo)
+	}()
+}
+
+func (s *serviceControllerImpl) punishSlowDisk(ctx context.Context, diskInfo *blobnode.DiskInfo) {
+	if !diskInfo.Slow {
+		return
+	}
+	span := trace.SpanFromContextSafe(ctx)
+	span.Warnf("punish slow disk %d of %s", diskInfo.DiskID, diskInfo.Host)
+	s.PunishDisk(ctx, diskInfo.DiskID, s.config.SlowDiskPunishIntervalS)
+}
+
 // PunishService will punish an service host for an punishTimeSec interval
 func (s *serviceControllerImpl) PunishService(ctx context.Context, service, host string, punishTimeSec int) {
 	v, ok := s.allServices.Load(service + host)
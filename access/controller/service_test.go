@@ -241,3 +241,43 @@ func TestAccessServicePunishDisk(t *testing.T) {
 		require.False(t, host.Punished)
 	}
 }
+
+func TestAccessServicePunishSlowDisk(t *testing.T) {
+	sc, err := controller.NewServiceController(
+		controller.ServiceConfig{
+			ClusterID:               0,
+			IDC:                     idc,
+			ReloadSec:               1,
+			DiskReloadSec:           2,
+			SlowDiskPunishIntervalS: 1,
+		}, cmcli)
+	require.NoError(t, err)
+
+	{
+		host, err := sc.GetDiskHost(serviceCtx, proto.DiskID(10001))
+		require.NoError(t, err)
+		require.False(t, host.Punished)
+	}
+	{
+		host, err := sc.GetDiskHost(serviceCtx, proto.DiskID(10003))
+		require.NoError(t, err)
+		require.True(t, host.Host == "blobnode-3")
+		require.True(t, host.Punished)
+	}
+	time.Sleep(time.Second)
+	{
+		host, err := sc.GetDiskHost(serviceCtx, proto.DiskID(10003))
+		require.NoError(t, err)
+		require.False(t, host.Punished)
+	}
+	// punished again after reloaded
+	require.Eventually(t, func() bool {
+		host, err := sc.GetDiskHost(serviceCtx, proto.DiskID(10003))
+		return err == nil && host.Punished
+	}, 5*time.Second, 100*time.Millisecond)
+	{
+		host, err := sc.GetDiskHost(serviceCtx, proto.DiskID(10001))
+		require.NoError(t, err)
+		require.False(t, host.Punished)
+	}
+}
@@ -44,6 +44,7 @@ type Unit = clustermgr.Unit
 
 // VolumePhy volume physical info
 //     Vid, CodeMode and Units are from cluster mgr
+//     CodeMode and Units are of the redirect volume if Vid was redirected
 //     IsPunish is cached in memory
 //     Timestamp is cached in redis to clear outdate info
 type VolumePhy struct {
@@ -294,6 +295,13 @@ func (v *volumeGetterImpl) getFromClusterAndUpdate(ctx context.Context, vid prot
 		if vInfo, err = v.cmClient.GetVolumeInfo(ctx, &clustermgr.GetVolumeArgs{Vid: vid}); err != nil {
 			return err
 		}
+		// volume had been converted, reading from the redirect volume
+		if vInfo.RedirectVid != proto.InvalidVid {
+			if vInfo, err = v.cmClient.GetVolumeInfo(ctx,
+				&clustermgr.GetVolumeArgs{Vid: vInfo.RedirectVid}); err != nil {
+				return err
+			}
+		}
 		return nil
 	}); err != nil {
 		// set local cache as nil(Timestamp is negative) when volume not exist
@@ -309,7 +317,7 @@ func (v *volumeGetterImpl) getFromClusterAndUpdate(ctx context.Context, vid prot
 	}
 
 	phy := &VolumePhy{
-		Vid:       vInfo.Vid,
+		Vid:       vid,
 		CodeMode:  vInfo.CodeMode,
 		Timestamp: time.Now().UnixNano(),
 		Units:     make([]Unit, len(vInfo.Units)),
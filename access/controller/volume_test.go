@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/cubefs/blobstore/access/controller"
+	"github.com/cubefs/blobstore/common/codemode"
 	"github.com/cubefs/blobstore/common/proto"
 	"github.com/cubefs/blobstore/common/redis"
 	"github.com/cubefs/blobstore/common/trace"
@@ -128,6 +129,24 @@ func TestAccessVolumeGetterNotExistVolume(t *testing.T) {
 	}
 }
 
+func TestAccessVolumeGetterRedirectVolume(t *testing.T) {
+	_, ctx := trace.StartSpanFromContext(context.Background(), "TestAccessVolumeGetterRedirectVolume")
+
+	getter, err := controller.NewVolumeGetter(0xfd, cmcli, rediscli, time.Millisecond*200)
+	require.Nil(t, err)
+
+	id := proto.Vid(11)
+	info := getter.Get(ctx, id, false)
+	require.NotNil(t, info)
+	require.Equal(t, id, info.Vid)
+	require.Equal(t, codemode.EC12P4, info.CodeMode)
+	require.Equal(t, proto.Vuid(12011), info.Units[0].Vuid)
+
+	info = getter.Get(ctx, id, true)
+	require.NotNil(t, info)
+	require.Equal(t, codemode.EC12P4, info.CodeMode)
+}
+
 func TestAccessVolumeGetterExpiration(t *testing.T) {
 	_, ctx := trace.StartSpanFromContext(context.Background(), "TestAccessVolumeGetterExpiration")
 	mockRedis := redis.NewClusterClient(&redis.ClusterConfig{
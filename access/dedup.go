@@ -0,0 +1,284 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/consul/api"
+
+	"github.com/cubefs/blobstore/api/access"
+	errcode "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/util/errors"
+)
+
+// ErrDedupNotIndexed the key is not indexed or not referenced
+var ErrDedupNotIndexed = errors.New("dedup not indexed")
+
+// DedupConfig content addressed dedup of put
+//
+// the default index is kept in consul and shared by all access nodes.
+// other registered indexes, like the local kvstore index, are not shared,
+// so that all requests of the same content must be routed to the same node.
+type DedupConfig struct {
+	Enable bool `json:"enable"`
+	// Index name of index, default is consul, or registered name of other index
+	Index string `json:"index"`
+	// Path local path of index
+	Path string `json:"path"`
+}
+
+// DedupIndex index of content key to encoded location with references,
+// every put of the content holds one reference with unique id,
+// so that releasing the same reference again changes nothing.
+type DedupIndex interface {
+	// Ref adds reference ref to the key and returns the indexed value,
+	// returns ErrDedupNotIndexed if the key is not indexed or not referenced.
+	Ref(key []byte, ref string) ([]byte, error)
+	// Put indexes the value with reference ref if the key is not indexed,
+	// adds reference ref and returns the indexed value if the key is referenced,
+	// returns ErrDedupNotIndexed if the key is unreferenced but not removed.
+	Put(key, value []byte, ref string) ([]byte, error)
+	// Unref removes reference ref of the key indexing the value and returns the rest count,
+	// released is false if ref is not a reference of the key,
+	// the key is kept until Remove if the rest is zero,
+	// returns ErrDedupNotIndexed if the key does not index the value.
+	Unref(key, value []byte, ref string) (rest uint64, released bool, err error)
+	// Remove removes the key indexing the value if it is not referenced.
+	Remove(key, value []byte) error
+	// Close the index
+	Close() error
+}
+
+// DedupIndexFactory returns a new dedup index
+type DedupIndexFactory func(cfg DedupConfig) (DedupIndex, error)
+
+var (
+	dedupIndexesMu sync.RWMutex
+	dedupIndexes   = make(map[string]DedupIndexFactory)
+)
+
+// RegisterDedupIndex registers dedup index with name,
+// implementation registers itself in init function,
+// which is imported into access binary only if building with its tag.
+func RegisterDedupIndex(name string, factory DedupIndexFactory) {
+	dedupIndexesMu.Lock()
+	defer dedupIndexesMu.Unlock()
+	if _, ok := dedupIndexes[name]; ok {
+		panic("dedup index registered twice: " + name)
+	}
+	dedupIndexes[name] = factory
+}
+
+func newDedupIndex(cfg DedupConfig, client *api.Client, region string) (DedupIndex, error) {
+	if cfg.Index == "" || cfg.Index == consulDedupIndexName {
+		return newConsulDedupIndex(client, region), nil
+	}
+	dedupIndexesMu.RLock()
+	factory, ok := dedupIndexes[cfg.Index]
+	dedupIndexesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("dedup index %s is not registered", cfg.Index)
+	}
+	return factory(cfg)
+}
+
+func dedupKey(loc *access.Location) ([]byte, error) {
+	if sum, ok := loc.HashSumMap[access.HashAlgSHA256]; ok && len(sum) > 0 {
+		return sum, nil
+	}
+	return nil, fmt.Errorf("dedup location without sha256")
+}
+
+// newDedupRef returns unique id of a new reference
+func newDedupRef() string {
+	return uuid.New().String()
+}
+
+// decodeDedupLocation returns location of the reference from the indexed value
+func decodeDedupLocation(value []byte, ref string) (*access.Location, error) {
+	loc, _, err := access.DecodeLocation(value)
+	if err != nil {
+		return nil, err
+	}
+	loc.DedupRef = ref
+	if err = fillCrc(&loc); err != nil {
+		return nil, err
+	}
+	return &loc, nil
+}
+
+// encodeDedupLocation returns the indexed value of location without reference
+func encodeDedupLocation(loc *access.Location) ([]byte, error) {
+	indexed := loc.Copy()
+	indexed.DedupRef = ""
+	if err := fillCrc(&indexed); err != nil {
+		return nil, err
+	}
+	return indexed.Encode(), nil
+}
+
+// dedupPut returns the referenced location if the content is indexed,
+// otherwise puts the content and indexes its location owned by the tenant.
+// the body is always read and checked with the content hash,
+// stored bytes are accounted to the tenant only if the content is stored.
+func (s *Service) dedupPut(ctx context.Context, rc io.Reader, args *access.PutArgs,
+	hasherMap access.HasherMap, tenant string) (*access.Location, error) {
+	span := trace.SpanFromContextSafe(ctx)
+	key, _ := hex.DecodeString(args.ContentSHA256)
+
+	hashers := make(access.HasherMap, len(hasherMap)+1)
+	for alg, hasher := range hasherMap {
+		hashers[alg] = hasher
+	}
+	if _, ok := hashers[access.HashAlgSHA256]; !ok {
+		hashers[access.HashAlgSHA256] = access.HashAlgSHA256.ToHasher()
+	}
+	sha := hashers[access.HashAlgSHA256]
+
+	ref := newDedupRef()
+	value, err := s.dedup.Ref(key, ref)
+	if err == nil {
+		loc, err := decodeDedupLocation(value, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := io.CopyN(hashers.ToWriter(), rc, args.Size)
+		if err != nil || n != args.Size {
+			span.Infof("read dedup body failed want:%d read:%d %v", args.Size, n, err)
+			s.dedupRelease(ctx, key, loc)
+			return nil, errcode.ErrAccessReadRequestBody
+		}
+		if loc.RawSize() != uint64(args.Size) || !bytes.Equal(key, sha.Sum(nil)) {
+			span.Warnf("mismatched content of dedup %s", args.ContentSHA256)
+			s.dedupRelease(ctx, key, loc)
+			return nil, errcode.ErrAccessContentMismatch
+		}
+		span.Debugf("dedup %s referenced %+v", args.ContentSHA256, loc)
+		return loc, nil
+	}
+	if err != ErrDedupNotIndexed {
+		span.Error("dedup index ref failed", errors.Detail(err))
+		return nil, err
+	}
+
+	loc, err := s.streamHandler.Put(ctx, rc, args.Size, hashers, PutOptions{Codec: args.Compression})
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(key, sha.Sum(nil)) {
+		span.Warnf("mismatched content of dedup %s", args.ContentSHA256)
+		s.deleteLocation(ctx, loc)
+		return nil, errcode.ErrAccessContentMismatch
+	}
+
+	loc.Version = access.LocationVersion2
+	ownLocation(loc, tenant)
+	if loc.HashSumMap == nil {
+		loc.HashSumMap = make(access.HashSumMap, 1)
+	}
+	loc.HashSumMap[access.HashAlgSHA256] = key
+	loc.Dedup = true
+	encoded, err := encodeDedupLocation(loc)
+	if err != nil {
+		s.deleteLocation(ctx, loc)
+		return nil, err
+	}
+
+	value, err = s.dedup.Put(key, encoded, ref)
+	if err == ErrDedupNotIndexed {
+		// the content is deleting, returns the location without dedup
+		span.Infof("dedup %s is deleting, put without index", args.ContentSHA256)
+		loc.Dedup = false
+		if err = fillCrc(loc); err != nil {
+			s.deleteLocation(ctx, loc)
+			return nil, err
+		}
+		s.accountTenant(tenant, args.Size)
+		return loc, nil
+	}
+	if err != nil {
+		span.Error("dedup index put failed", errors.Detail(err))
+		s.deleteLocation(ctx, loc)
+		return nil, err
+	}
+
+	if !bytes.Equal(value, encoded) {
+		// indexed by another put concurrently
+		s.deleteLocation(ctx, loc)
+	} else {
+		s.accountTenant(tenant, args.Size)
+	}
+	return decodeDedupLocation(value, ref)
+}
+
+// dedupRelease releases the reference of the location, releasing it again changes nothing.
+// Stored bytes of the owner are released with the last reference,
+// then the blobs are deleted, and deleted again if released again before removed.
+func (s *Service) dedupRelease(ctx context.Context, key []byte, loc *access.Location) error {
+	span := trace.SpanFromContextSafe(ctx)
+	if loc.DedupRef == "" {
+		return ErrDedupNotIndexed
+	}
+	encoded, err := encodeDedupLocation(loc)
+	if err != nil {
+		return err
+	}
+	refs, released, err := s.dedup.Unref(key, encoded, loc.DedupRef)
+	if err != nil {
+		span.Warnf("dedup index unref %x failed %s", key, errors.Detail(err))
+		return err
+	}
+	if refs > 0 {
+		span.Debugf("dedup %x has %d references, released:%v", key, refs, released)
+		return nil
+	}
+	if released {
+		s.accountTenant(loc.Tenant, -int64(loc.RawSize()))
+	}
+
+	if err = s.streamHandler.Delete(ctx, loc); err != nil {
+		span.Error("stream delete dedup failed", errors.Detail(err))
+		return err
+	}
+	return s.dedup.Remove(key, encoded)
+}
+
+// dedupDelete releases reference of the dedup location
+func (s *Service) dedupDelete(ctx context.Context, loc *access.Location) error {
+	if s.dedup == nil {
+		return ErrDedupNotIndexed
+	}
+	key, err := dedupKey(loc)
+	if err != nil {
+		return err
+	}
+	return s.dedupRelease(ctx, key, loc)
+}
+
+func (s *Service) deleteLocation(ctx context.Context, loc *access.Location) {
+	if err := s.streamHandler.Delete(ctx, loc); err != nil {
+		span := trace.SpanFromContextSafe(ctx)
+		span.Warn("stream delete location failed", errors.Detail(err))
+	}
+}
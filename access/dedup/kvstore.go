@@ -0,0 +1,180 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package dedup dedup index of access backed by local rocksdb,
+// import it to register the index named kvstore,
+// access binary imports it only if building with tag dedup_kvstore.
+package dedup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/cubefs/blobstore/access"
+	"github.com/cubefs/blobstore/common/kvstore"
+)
+
+// IndexName name of the registered index
+const IndexName = "kvstore"
+
+func init() {
+	access.RegisterDedupIndex(IndexName, New)
+}
+
+// kvIndex value of key is uvarint(len(refs)) + [uvarint(len(ref)) + ref]... + value
+type kvIndex struct {
+	mu sync.Mutex
+	db kvstore.KVStore
+}
+
+// New returns dedup index in rocksdb of path
+func New(cfg access.DedupConfig) (access.DedupIndex, error) {
+	db, err := kvstore.OpenDB(cfg.Path, true, &kvstore.RocksDBOption{CreateIfMissing: true})
+	if err != nil {
+		return nil, err
+	}
+	return &kvIndex{db: db}, nil
+}
+
+func encodeEntry(refs map[string]struct{}, value []byte) []byte {
+	size := binary.MaxVarintLen64 + len(value)
+	for ref := range refs {
+		size += binary.MaxVarintLen64 + len(ref)
+	}
+	buf := make([]byte, size)
+	n := binary.PutUvarint(buf, uint64(len(refs)))
+	for ref := range refs {
+		n += binary.PutUvarint(buf[n:], uint64(len(ref)))
+		n += copy(buf[n:], ref)
+	}
+	n += copy(buf[n:], value)
+	return buf[:n]
+}
+
+func decodeEntry(data []byte) (map[string]struct{}, []byte, error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, nil, fmt.Errorf("invalid dedup entry")
+	}
+	data = data[n:]
+	refs := make(map[string]struct{}, count)
+	for i := uint64(0); i < count; i++ {
+		l, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < l {
+			return nil, nil, fmt.Errorf("invalid dedup entry")
+		}
+		refs[string(data[n:n+int(l)])] = struct{}{}
+		data = data[n+int(l):]
+	}
+	return refs, data, nil
+}
+
+// get returns references and value of key, ok is false if not found
+func (idx *kvIndex) get(key []byte) (refs map[string]struct{}, value []byte, ok bool, err error) {
+	data, err := idx.db.Get(key)
+	if err == kvstore.ErrNotFound {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	refs, value, err = decodeEntry(data)
+	return refs, value, err == nil, err
+}
+
+func (idx *kvIndex) Ref(key []byte, ref string) ([]byte, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	refs, value, ok, err := idx.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || len(refs) == 0 {
+		return nil, access.ErrDedupNotIndexed
+	}
+	if _, has := refs[ref]; has {
+		return value, nil
+	}
+	refs[ref] = struct{}{}
+	if err = idx.db.Put(kvstore.KV{Key: key, Value: encodeEntry(refs, value)}); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (idx *kvIndex) Put(key, value []byte, ref string) ([]byte, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	refs, indexed, ok, err := idx.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if ok && len(refs) == 0 {
+		return nil, access.ErrDedupNotIndexed
+	}
+	if !ok {
+		refs, indexed = make(map[string]struct{}, 1), value
+	}
+	if _, has := refs[ref]; has {
+		return indexed, nil
+	}
+	refs[ref] = struct{}{}
+	if err = idx.db.Put(kvstore.KV{Key: key, Value: encodeEntry(refs, indexed)}); err != nil {
+		return nil, err
+	}
+	return indexed, nil
+}
+
+func (idx *kvIndex) Unref(key, value []byte, ref string) (uint64, bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	refs, indexed, ok, err := idx.get(key)
+	if err != nil {
+		return 0, false, err
+	}
+	if !ok || !bytes.Equal(indexed, value) {
+		return 0, false, access.ErrDedupNotIndexed
+	}
+	if _, has := refs[ref]; !has {
+		return uint64(len(refs)), false, nil
+	}
+	delete(refs, ref)
+	if err = idx.db.Put(kvstore.KV{Key: key, Value: encodeEntry(refs, indexed)}); err != nil {
+		return 0, false, err
+	}
+	return uint64(len(refs)), true, nil
+}
+
+func (idx *kvIndex) Remove(key, value []byte) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	refs, indexed, ok, err := idx.get(key)
+	if err != nil || !ok {
+		return err
+	}
+	if len(refs) > 0 || !bytes.Equal(indexed, value) {
+		return nil
+	}
+	return idx.db.Delete(key)
+}
+
+func (idx *kvIndex) Close() error {
+	return idx.db.Close()
+}
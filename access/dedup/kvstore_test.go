@@ -0,0 +1,81 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dedup
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/access"
+)
+
+func TestDedupKVIndex(t *testing.T) {
+	path, err := ioutil.TempDir("", "dedupindex")
+	require.NoError(t, err)
+	defer os.RemoveAll(path)
+
+	index, err := New(access.DedupConfig{Path: path})
+	require.NoError(t, err)
+	defer index.Close()
+
+	key, value, other := []byte("key"), []byte("value"), []byte("other")
+	_, err = index.Ref(key, "r0")
+	require.ErrorIs(t, err, access.ErrDedupNotIndexed)
+	_, _, err = index.Unref(key, value, "r0")
+	require.ErrorIs(t, err, access.ErrDedupNotIndexed)
+
+	indexed, err := index.Put(key, value, "r0")
+	require.NoError(t, err)
+	require.Equal(t, value, indexed)
+	indexed, err = index.Put(key, other, "r1")
+	require.NoError(t, err)
+	require.Equal(t, value, indexed)
+	indexed, err = index.Ref(key, "r2")
+	require.NoError(t, err)
+	require.Equal(t, value, indexed)
+	indexed, err = index.Ref(key, "r2")
+	require.NoError(t, err)
+	require.Equal(t, value, indexed)
+
+	_, _, err = index.Unref(key, other, "r0")
+	require.ErrorIs(t, err, access.ErrDedupNotIndexed)
+	for _, cs := range []struct {
+		ref      string
+		rest     uint64
+		released bool
+	}{{"r0", 2, true}, {"r0", 2, false}, {"r1", 1, true}, {"r2", 0, true}, {"r2", 0, false}} {
+		rest, released, err := index.Unref(key, value, cs.ref)
+		require.NoError(t, err)
+		require.Equal(t, cs.rest, rest)
+		require.Equal(t, cs.released, released)
+	}
+
+	// unreferenced key is kept until removed
+	_, err = index.Ref(key, "r3")
+	require.ErrorIs(t, err, access.ErrDedupNotIndexed)
+	_, err = index.Put(key, other, "r3")
+	require.ErrorIs(t, err, access.ErrDedupNotIndexed)
+	require.NoError(t, index.Remove(key, other))
+	_, err = index.Put(key, other, "r3")
+	require.ErrorIs(t, err, access.ErrDedupNotIndexed)
+
+	require.NoError(t, index.Remove(key, value))
+	indexed, err = index.Put(key, other, "r3")
+	require.NoError(t, err)
+	require.Equal(t, other, indexed)
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/cubefs/blobstore/util/errors"
+)
+
+const (
+	_dedupConsulPath = "ebs/%s/dedup/"
+
+	// consulDedupIndexName name of the dedup index shared by all access nodes
+	consulDedupIndexName = "consul"
+	// dedupCASRetries retry times of modifying one key conflicted with other nodes
+	dedupCASRetries = 16
+)
+
+var errDedupConflict = errors.New("dedup index modified concurrently")
+
+type consulDedupEntry struct {
+	Refs  map[string]struct{} `json:"refs"`
+	Value []byte              `json:"value"`
+}
+
+// consulDedupIndex keeps references of keys in consul,
+// every modification is compare-and-swap, so that all access nodes share the same index.
+type consulDedupIndex struct {
+	path string
+	kv   *api.KV
+}
+
+func newConsulDedupIndex(client *api.Client, region string) DedupIndex {
+	return &consulDedupIndex{
+		path: fmt.Sprintf(_dedupConsulPath, region),
+		kv:   client.KV(),
+	}
+}
+
+func (idx *consulDedupIndex) entryKey(key []byte) string {
+	return idx.path + hex.EncodeToString(key)
+}
+
+// get returns entry of key with its modify index, entry is nil if not found
+func (idx *consulDedupIndex) get(key []byte) (*consulDedupEntry, uint64, error) {
+	pair, _, err := idx.kv.Get(idx.entryKey(key), nil)
+	if err != nil || pair == nil {
+		return nil, 0, err
+	}
+	entry := &consulDedupEntry{}
+	if err = json.Unmarshal(pair.Value, entry); err != nil {
+		return nil, 0, err
+	}
+	return entry, pair.ModifyIndex, nil
+}
+
+// cas saves entry of key if it is not modified since modifyIndex,
+// modifyIndex 0 saves the entry only if the key does not exist.
+func (idx *consulDedupIndex) cas(key []byte, entry *consulDedupEntry, modifyIndex uint64) (bool, error) {
+	val, err := json.Marshal(entry)
+	if err != nil {
+		return false, err
+	}
+	ok, _, err := idx.kv.CAS(&api.KVPair{Key: idx.entryKey(key), Value: val, ModifyIndex: modifyIndex}, nil)
+	return ok, err
+}
+
+func (idx *consulDedupIndex) Ref(key []byte, ref string) ([]byte, error) {
+	for i := 0; i < dedupCASRetries; i++ {
+		entry, modifyIndex, err := idx.get(key)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil || len(entry.Refs) == 0 {
+			return nil, ErrDedupNotIndexed
+		}
+		if _, has := entry.Refs[ref]; has {
+			return entry.Value, nil
+		}
+		entry.Refs[ref] = struct{}{}
+		ok, err := idx.cas(key, entry, modifyIndex)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return entry.Value, nil
+		}
+	}
+	return nil, errDedupConflict
+}
+
+func (idx *consulDedupIndex) Put(key, value []byte, ref string) ([]byte, error) {
+	for i := 0; i < dedupCASRetries; i++ {
+		entry, modifyIndex, err := idx.get(key)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			entry = &consulDedupEntry{Refs: make(map[string]struct{}, 1), Value: value}
+		} else if len(entry.Refs) == 0 {
+			return nil, ErrDedupNotIndexed
+		}
+		if _, has := entry.Refs[ref]; has {
+			return entry.Value, nil
+		}
+		entry.Refs[ref] = struct{}{}
+		ok, err := idx.cas(key, entry, modifyIndex)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return entry.Value, nil
+		}
+	}
+	return nil, errDedupConflict
+}
+
+func (idx *consulDedupIndex) Unref(key, value []byte, ref string) (uint64, bool, error) {
+	for i := 0; i < dedupCASRetries; i++ {
+		entry, modifyIndex, err := idx.get(key)
+		if err != nil {
+			return 0, false, err
+		}
+		if entry == nil || !bytes.Equal(entry.Value, value) {
+			return 0, false, ErrDedupNotIndexed
+		}
+		if _, has := entry.Refs[ref]; !has {
+			return uint64(len(entry.Refs)), false, nil
+		}
+		delete(entry.Refs, ref)
+		ok, err := idx.cas(key, entry, modifyIndex)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			return uint64(len(entry.Refs)), true, nil
+		}
+	}
+	return 0, false, errDedupConflict
+}
+
+func (idx *consulDedupIndex) Remove(key, value []byte) error {
+	for i := 0; i < dedupCASRetries; i++ {
+		entry, modifyIndex, err := idx.get(key)
+		if err != nil || entry == nil {
+			return err
+		}
+		if len(entry.Refs) > 0 || !bytes.Equal(entry.Value, value) {
+			return nil
+		}
+		ok, _, err := idx.kv.DeleteCAS(&api.KVPair{Key: idx.entryKey(key), ModifyIndex: modifyIndex}, nil)
+		if err != nil || ok {
+			return err
+		}
+	}
+	return errDedupConflict
+}
+
+func (idx *consulDedupIndex) Close() error {
+	return nil
+}
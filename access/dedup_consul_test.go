@@ -0,0 +1,216 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/api/access"
+)
+
+// mockConsulKV serves get, cas put and cas delete of consul kv
+type mockConsulKV struct {
+	mu    sync.Mutex
+	index uint64
+	pairs map[string]*api.KVPair
+}
+
+func (m *mockConsulKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(m.index, 10))
+	w.Header().Set("X-Consul-LastContact", "0")
+	w.Header().Set("X-Consul-KnownLeader", "true")
+
+	pair, exist := m.pairs[key]
+	casOK := true
+	if casStr := r.URL.Query().Get("cas"); casStr != "" {
+		cas, _ := strconv.ParseUint(casStr, 10, 64)
+		casOK = (cas == 0 && !exist) || (exist && cas == pair.ModifyIndex)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exist {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]*api.KVPair{pair})
+	case http.MethodPut:
+		if casOK {
+			val, _ := ioutil.ReadAll(r.Body)
+			m.index++
+			m.pairs[key] = &api.KVPair{Key: key, Value: val, ModifyIndex: m.index}
+		}
+		json.NewEncoder(w).Encode(casOK)
+	case http.MethodDelete:
+		if casOK {
+			delete(m.pairs, key)
+		}
+		json.NewEncoder(w).Encode(casOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newMockConsulClient(t *testing.T) (*api.Client, func()) {
+	server := httptest.NewServer(&mockConsulKV{pairs: make(map[string]*api.KVPair)})
+	conf := api.DefaultConfig()
+	conf.Address = server.URL
+	client, err := api.NewClient(conf)
+	require.NoError(t, err)
+	return client, server.Close
+}
+
+func TestAccessDedupConsulIndex(t *testing.T) {
+	client, closer := newMockConsulClient(t)
+	defer closer()
+
+	index, err := newDedupIndex(DedupConfig{}, client, "test-region")
+	require.NoError(t, err)
+	defer index.Close()
+
+	key, value := []byte("key"), []byte("value")
+	_, err = index.Ref(key, "r0")
+	require.ErrorIs(t, err, ErrDedupNotIndexed)
+	_, _, err = index.Unref(key, value, "r0")
+	require.ErrorIs(t, err, ErrDedupNotIndexed)
+	require.NoError(t, index.Remove(key, value))
+
+	indexed, err := index.Put(key, value, "r0")
+	require.NoError(t, err)
+	require.Equal(t, value, indexed)
+	indexed, err = index.Put(key, []byte("other"), "r1")
+	require.NoError(t, err)
+	require.Equal(t, value, indexed)
+	indexed, err = index.Ref(key, "r2")
+	require.NoError(t, err)
+	require.Equal(t, value, indexed)
+	// the same reference is held once
+	indexed, err = index.Ref(key, "r2")
+	require.NoError(t, err)
+	require.Equal(t, value, indexed)
+
+	_, _, err = index.Unref(key, []byte("other"), "r0")
+	require.ErrorIs(t, err, ErrDedupNotIndexed)
+	for i, ref := range []string{"r0", "r1", "r2"} {
+		rest, released, err := index.Unref(key, value, ref)
+		require.NoError(t, err)
+		require.True(t, released)
+		require.Equal(t, uint64(2-i), rest)
+		// released again changes nothing
+		rest, released, err = index.Unref(key, value, ref)
+		require.NoError(t, err)
+		require.False(t, released)
+		require.Equal(t, uint64(2-i), rest)
+		if rest > 0 {
+			require.NoError(t, index.Remove(key, value))
+		}
+	}
+
+	// unreferenced key is not indexed until removed
+	_, err = index.Ref(key, "r3")
+	require.ErrorIs(t, err, ErrDedupNotIndexed)
+	_, err = index.Put(key, value, "r3")
+	require.ErrorIs(t, err, ErrDedupNotIndexed)
+	require.NoError(t, index.Remove(key, value))
+	_, _, err = index.Unref(key, value, "r0")
+	require.ErrorIs(t, err, ErrDedupNotIndexed)
+
+	indexed, err = index.Put(key, []byte("new"), "r4")
+	require.NoError(t, err)
+	require.Equal(t, []byte("new"), indexed)
+}
+
+func TestAccessDedupConsulIndexConcurrent(t *testing.T) {
+	client, closer := newMockConsulClient(t)
+	defer closer()
+
+	key, value := []byte("key"), []byte("value")
+	index := newConsulDedupIndex(client, "test-region")
+	_, err := index.Put(key, value, "owner")
+	require.NoError(t, err)
+
+	const n = 8
+	errCh := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(ref string) {
+			// every node has its own index on the same consul
+			node := newConsulDedupIndex(client, "test-region")
+			for {
+				if _, err := node.Ref(key, ref); err != errDedupConflict {
+					errCh <- err
+					return
+				}
+			}
+		}(strconv.Itoa(i))
+	}
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errCh)
+	}
+
+	rest, released, err := index.Unref(key, value, "owner")
+	require.NoError(t, err)
+	require.True(t, released)
+	require.Equal(t, uint64(n), rest)
+}
+
+func TestAccessDedupCrossNodeDelete(t *testing.T) {
+	ctx := ctxWithName("TestAccessDedupCrossNodeDelete")
+	defer dataShards.clean()
+	client, closer := newMockConsulClient(t)
+	defer closer()
+
+	nodeA := &Service{streamHandler: streamer, dedup: newConsulDedupIndex(client, "test-region")}
+	nodeB := &Service{streamHandler: streamer, dedup: newConsulDedupIndex(client, "test-region")}
+
+	size := (1 << 20) + 7
+	data := make([]byte, size)
+	rand.Read(data)
+	sum := sha256.Sum256(data)
+	args := &access.PutArgs{Size: int64(size), ContentSHA256: hex.EncodeToString(sum[:])}
+
+	loc, err := nodeA.dedupPut(ctx(), bytes.NewReader(data), args, nil, "")
+	require.NoError(t, err)
+	ref, err := nodeB.dedupPut(ctx(), bytes.NewReader(data), args, nil, "")
+	require.NoError(t, err)
+	require.Equal(t, loc.Blobs, ref.Blobs)
+
+	// deleted by any node, retried delete on another node keeps the other reference
+	require.NoError(t, nodeB.dedupDelete(ctx(), loc))
+	require.NoError(t, nodeA.dedupDelete(ctx(), loc))
+	_, err = nodeA.dedup.Ref(sum[:], ref.DedupRef)
+	require.NoError(t, err)
+	require.NoError(t, nodeA.dedupDelete(ctx(), ref))
+	require.ErrorIs(t, nodeB.dedupDelete(ctx(), ref), ErrDedupNotIndexed)
+	_, err = nodeA.dedup.Ref(sum[:], "other")
+	require.ErrorIs(t, err, ErrDedupNotIndexed)
+}
@@ -0,0 +1,200 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/api/access"
+	errcode "github.com/cubefs/blobstore/common/errors"
+)
+
+type memDedupEntry struct {
+	refs  map[string]struct{}
+	value []byte
+}
+
+type memDedupIndex struct {
+	mu      sync.Mutex
+	entries map[string]*memDedupEntry
+}
+
+func newMemDedupIndex(DedupConfig) (DedupIndex, error) {
+	return &memDedupIndex{entries: make(map[string]*memDedupEntry)}, nil
+}
+
+func (idx *memDedupIndex) Ref(key []byte, ref string) ([]byte, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	entry, ok := idx.entries[string(key)]
+	if !ok || len(entry.refs) == 0 {
+		return nil, ErrDedupNotIndexed
+	}
+	entry.refs[ref] = struct{}{}
+	return entry.value, nil
+}
+
+func (idx *memDedupIndex) Put(key, value []byte, ref string) ([]byte, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	entry, ok := idx.entries[string(key)]
+	if !ok {
+		entry = &memDedupEntry{refs: make(map[string]struct{}), value: value}
+		idx.entries[string(key)] = entry
+	} else if len(entry.refs) == 0 {
+		return nil, ErrDedupNotIndexed
+	}
+	entry.refs[ref] = struct{}{}
+	return entry.value, nil
+}
+
+func (idx *memDedupIndex) Unref(key, value []byte, ref string) (uint64, bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	entry, ok := idx.entries[string(key)]
+	if !ok || !bytes.Equal(entry.value, value) {
+		return 0, false, ErrDedupNotIndexed
+	}
+	_, released := entry.refs[ref]
+	delete(entry.refs, ref)
+	return uint64(len(entry.refs)), released, nil
+}
+
+func (idx *memDedupIndex) Remove(key, value []byte) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if entry, ok := idx.entries[string(key)]; ok && len(entry.refs) == 0 && bytes.Equal(entry.value, value) {
+		delete(idx.entries, string(key))
+	}
+	return nil
+}
+
+func (idx *memDedupIndex) Close() error { return nil }
+
+func (idx *memDedupIndex) refs(key []byte) (uint64, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	entry, ok := idx.entries[string(key)]
+	if !ok {
+		return 0, false
+	}
+	return uint64(len(entry.refs)), true
+}
+
+func TestAccessDedupIndexRegister(t *testing.T) {
+	RegisterDedupIndex("memory", newMemDedupIndex)
+	require.Panics(t, func() { RegisterDedupIndex("memory", newMemDedupIndex) })
+
+	_, err := newDedupIndex(DedupConfig{Index: "not-exist"}, nil, "")
+	require.Error(t, err)
+	index, err := newDedupIndex(DedupConfig{Index: "memory"}, nil, "")
+	require.NoError(t, err)
+	require.NoError(t, index.Close())
+}
+
+func TestAccessDedupPutDelete(t *testing.T) {
+	ctx := ctxWithName("TestAccessDedupPutDelete")
+	defer dataShards.clean()
+
+	index, _ := newMemDedupIndex(DedupConfig{})
+	svc := &Service{streamHandler: streamer, dedup: index,
+		tenants: newTenantLimiter("node", newMemTenantUsageStore())}
+	mem := index.(*memDedupIndex)
+	usedBytes := func(tenant string) int64 {
+		return svc.tenants.Status()[tenant].UsedBytes
+	}
+
+	size := (1 << 20) + 7
+	data := make([]byte, size)
+	rand.Read(data)
+	sum := sha256.Sum256(data)
+	args := &access.PutArgs{Size: int64(size), ContentSHA256: hex.EncodeToString(sum[:])}
+
+	loc, err := svc.dedupPut(ctx(), bytes.NewReader(data), args, nil, "foo")
+	require.NoError(t, err)
+	require.True(t, loc.Dedup)
+	require.Equal(t, "foo", loc.Tenant)
+	require.Equal(t, access.LocationVersion2, loc.Version)
+	require.Equal(t, sum[:], loc.HashSumMap[access.HashAlgSHA256])
+	require.NotEmpty(t, loc.DedupRef)
+	require.True(t, verifyCrc(loc))
+
+	// referenced without stored again
+	ref, err := svc.dedupPut(ctx(), bytes.NewReader(data), args, access.HasherMap{access.HashAlgMD5: access.HashAlgMD5.ToHasher()}, "bar")
+	require.NoError(t, err)
+	require.Equal(t, loc.Blobs, ref.Blobs)
+	require.Equal(t, "foo", ref.Tenant)
+	require.NotEqual(t, loc.DedupRef, ref.DedupRef)
+	require.True(t, verifyCrc(ref))
+	refs, _ := mem.refs(sum[:])
+	require.Equal(t, uint64(2), refs)
+	// stored bytes are accounted to the owner only
+	require.Equal(t, int64(size), usedBytes("foo"))
+	require.Equal(t, int64(0), usedBytes("bar"))
+
+	buff := bytes.NewBuffer(nil)
+	transfer, err := streamer.Get(ctx(), buff, *ref, uint64(size), 0)
+	require.NoError(t, err)
+	require.NoError(t, transfer())
+	require.True(t, dataEqual(data, buff.Bytes()))
+
+	// mismatched body of indexed content
+	other := make([]byte, size)
+	rand.Read(other)
+	_, err = svc.dedupPut(ctx(), bytes.NewReader(other), args, nil, "")
+	require.ErrorIs(t, err, errcode.ErrAccessContentMismatch)
+	_, err = svc.dedupPut(ctx(), bytes.NewReader(data[:size-1]), args, nil, "")
+	require.ErrorIs(t, err, errcode.ErrAccessReadRequestBody)
+	refs, _ = mem.refs(sum[:])
+	require.Equal(t, uint64(2), refs)
+
+	// mismatched body of not indexed content
+	otherSum := sha256.Sum256(data[:size-1])
+	_, err = svc.dedupPut(ctx(), bytes.NewReader(other), &access.PutArgs{
+		Size: int64(size), ContentSHA256: hex.EncodeToString(otherSum[:]),
+	}, nil, "")
+	require.ErrorIs(t, err, errcode.ErrAccessContentMismatch)
+	_, ok := mem.refs(otherSum[:])
+	require.False(t, ok)
+
+	require.NoError(t, svc.dedupDelete(ctx(), loc))
+	refs, _ = mem.refs(sum[:])
+	require.Equal(t, uint64(1), refs)
+	require.Equal(t, int64(size), usedBytes("foo"))
+	// repeated delete of the same reference keeps the other one
+	require.NoError(t, svc.dedupDelete(ctx(), loc))
+	refs, _ = mem.refs(sum[:])
+	require.Equal(t, uint64(1), refs)
+	require.Equal(t, int64(size), usedBytes("foo"))
+	noRef := loc.Copy()
+	noRef.DedupRef = ""
+	require.ErrorIs(t, svc.dedupDelete(ctx(), &noRef), ErrDedupNotIndexed)
+
+	require.NoError(t, svc.dedupDelete(ctx(), ref))
+	_, ok = mem.refs(sum[:])
+	require.False(t, ok)
+	require.Equal(t, int64(0), usedBytes("foo"))
+	require.ErrorIs(t, svc.dedupDelete(ctx(), ref), ErrDedupNotIndexed)
+
+	svc.dedup = nil
+	require.ErrorIs(t, svc.dedupDelete(ctx(), ref), ErrDedupNotIndexed)
+}
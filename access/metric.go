@@ -40,9 +40,20 @@ var downloadMetric = prometheus.NewCounterVec(
 	[]string{"cluster", "way", "reason"},
 )
 
+var hedgeMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "blobstore",
+		Subsystem: "access",
+		Name:      "hedge_read",
+		Help:      "hedged shard reads fired, and won before the blob read done",
+	},
+	[]string{"cluster", "action"},
+)
+
 func init() {
 	prometheus.MustRegister(unhealthMetric)
 	prometheus.MustRegister(downloadMetric)
+	prometheus.MustRegister(hedgeMetric)
 }
 
 func reportUnhealth(cid proto.ClusterID, action, module, host, reason string) {
@@ -52,3 +63,7 @@ func reportUnhealth(cid proto.ClusterID, action, module, host, reason string) {
 func reportDownload(cid proto.ClusterID, way, reason string) {
 	downloadMetric.WithLabelValues(cid.ToString(), way, reason).Inc()
 }
+
+func reportHedge(cid proto.ClusterID, action string) {
+	hedgeMetric.WithLabelValues(cid.ToString(), action).Inc()
+}
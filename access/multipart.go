@@ -0,0 +1,255 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/cubefs/blobstore/api/access"
+	errcode "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+const (
+	_multipartConsulPath = "ebs/%s/multipart/"
+
+	defaultMultipartExpireHours      = 24 * 7
+	defaultMultipartReclaimIntervalS = 600
+	defaultMultipartPartBlobs        = 16
+)
+
+// MultipartConfig multipart upload config
+type MultipartConfig struct {
+	// ExpireHours uncompleted uploads will be reclaimed after hours
+	ExpireHours int `json:"expire_hours"`
+	// ReclaimIntervalS interval seconds of scanning expired uploads
+	ReclaimIntervalS int `json:"reclaim_interval_s"`
+	// DefaultPartBlobs blobs of one part if part size is not specified
+	DefaultPartBlobs int `json:"default_part_blobs"`
+}
+
+type multipartState uint8
+
+const (
+	multipartUploading multipartState = iota
+	multipartCompleted
+	multipartAborted
+)
+
+// multipartUpload session of one multipart upload,
+// all blobs of the object are allocated in Location when initiating.
+// Completed upload is kept until expired, so that retry of completing gets the same location,
+// blobs of aborted upload are deleted before the upload is removed.
+//
+// part with number n has blobs [(n-1)*PartSize/BlobSize, n*PartSize/BlobSize)
+type multipartUpload struct {
+	UploadID   string          `json:"upload_id"`
+	Location   access.Location `json:"location"`
+	PartSize   uint64          `json:"part_size"`
+	CreateTime int64           `json:"create_time"`
+	ExpireAt   int64           `json:"expire_at,omitempty"`
+	State      multipartState  `json:"state"`
+
+	parts       map[int]access.MultipartPart
+	modifyIndex uint64
+}
+
+func (u *multipartUpload) partCount() int {
+	return int((u.Location.Size + u.PartSize - 1) / u.PartSize)
+}
+
+// partBlobs returns blobs of the part, nil if out of range
+func (u *multipartUpload) partBlobs(partNumber int) []access.Blob {
+	if partNumber <= 0 || u.Location.BlobSize == 0 {
+		return nil
+	}
+
+	blobs := u.Location.Spread()
+	n := int(u.PartSize / uint64(u.Location.BlobSize))
+	from := (partNumber - 1) * n
+	if from >= len(blobs) {
+		return nil
+	}
+	to := from + n
+	if to > len(blobs) {
+		to = len(blobs)
+	}
+	return blobs[from:to]
+}
+
+// sortedParts returns uploaded parts sorted by part number
+func (u *multipartUpload) sortedParts() []access.MultipartPart {
+	parts := make([]access.MultipartPart, 0, len(u.parts))
+	for _, part := range u.parts {
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	return parts
+}
+
+// multipartStore persistent storage of multipart uploads shared by access nodes
+type multipartStore interface {
+	// Create saves a new upload
+	Create(ctx context.Context, upload *multipartUpload) error
+	// Get returns the upload with its uploaded parts
+	Get(ctx context.Context, uploadID string) (*multipartUpload, error)
+	// SetPart saves one uploaded part of the upload
+	SetPart(ctx context.Context, uploadID string, part access.MultipartPart) error
+	// RemovePart removes one part saved after the upload was finished
+	RemovePart(ctx context.Context, uploadID string, partNumber int) error
+	// Update saves the upload if it has not been modified since got,
+	// returns false if the upload was modified or removed by others.
+	Update(ctx context.Context, upload *multipartUpload) (bool, error)
+	// Remove removes the upload and its parts
+	Remove(ctx context.Context, uploadID string) error
+	// List returns all uploads without parts
+	List(ctx context.Context) ([]*multipartUpload, error)
+}
+
+type consulMultipartStore struct {
+	path string
+	kv   *api.KV
+}
+
+func newConsulMultipartStore(client *api.Client, region string) multipartStore {
+	return &consulMultipartStore{
+		path: fmt.Sprintf(_multipartConsulPath, region),
+		kv:   client.KV(),
+	}
+}
+
+func (s *consulMultipartStore) uploadKey(uploadID string) string {
+	return s.path + uploadID
+}
+
+func (s *consulMultipartStore) partKey(uploadID string, partNumber int) string {
+	return s.path + uploadID + "/" + strconv.Itoa(partNumber)
+}
+
+func (s *consulMultipartStore) Create(ctx context.Context, upload *multipartUpload) error {
+	val, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.Put(&api.KVPair{Key: s.uploadKey(upload.UploadID), Value: val}, nil)
+	return err
+}
+
+func (s *consulMultipartStore) Get(ctx context.Context, uploadID string) (*multipartUpload, error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	pairs, _, err := s.kv.List(s.uploadKey(uploadID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var upload *multipartUpload
+	parts := make(map[int]access.MultipartPart, len(pairs))
+	for _, pair := range pairs {
+		if pair.Key == s.uploadKey(uploadID) {
+			upload = &multipartUpload{}
+			if err = json.Unmarshal(pair.Value, upload); err != nil {
+				return nil, err
+			}
+			upload.modifyIndex = pair.ModifyIndex
+			continue
+		}
+
+		if !strings.HasPrefix(pair.Key, s.uploadKey(uploadID)+"/") {
+			continue
+		}
+		var part access.MultipartPart
+		if err = json.Unmarshal(pair.Value, &part); err != nil {
+			span.Warnf("decode part failed, key:%s raw:%s, error:%s", pair.Key, string(pair.Value), err.Error())
+			continue
+		}
+		parts[part.PartNumber] = part
+	}
+
+	if upload == nil {
+		return nil, errcode.ErrAccessMultipartNoSuch
+	}
+	upload.parts = parts
+	return upload, nil
+}
+
+func (s *consulMultipartStore) SetPart(ctx context.Context, uploadID string, part access.MultipartPart) error {
+	val, err := json.Marshal(part)
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.Put(&api.KVPair{Key: s.partKey(uploadID, part.PartNumber), Value: val}, nil)
+	return err
+}
+
+func (s *consulMultipartStore) RemovePart(ctx context.Context, uploadID string, partNumber int) error {
+	_, err := s.kv.Delete(s.partKey(uploadID, partNumber), nil)
+	return err
+}
+
+func (s *consulMultipartStore) Update(ctx context.Context, upload *multipartUpload) (bool, error) {
+	val, err := json.Marshal(upload)
+	if err != nil {
+		return false, err
+	}
+	ok, _, err := s.kv.CAS(&api.KVPair{
+		Key:         s.uploadKey(upload.UploadID),
+		Value:       val,
+		ModifyIndex: upload.modifyIndex,
+	}, nil)
+	return ok, err
+}
+
+func (s *consulMultipartStore) Remove(ctx context.Context, uploadID string) error {
+	if _, err := s.kv.DeleteTree(s.uploadKey(uploadID)+"/", nil); err != nil {
+		return err
+	}
+	_, err := s.kv.Delete(s.uploadKey(uploadID), nil)
+	return err
+}
+
+func (s *consulMultipartStore) List(ctx context.Context) ([]*multipartUpload, error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	pairs, _, err := s.kv.List(s.path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]*multipartUpload, 0, len(pairs))
+	for _, pair := range pairs {
+		if strings.Contains(strings.TrimPrefix(pair.Key, s.path), "/") {
+			continue
+		}
+
+		upload := &multipartUpload{}
+		if err := json.Unmarshal(pair.Value, upload); err != nil {
+			span.Warnf("decode upload failed, key:%s raw:%s, error:%s", pair.Key, string(pair.Value), err.Error())
+			continue
+		}
+		upload.modifyIndex = pair.ModifyIndex
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
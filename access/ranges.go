@@ -0,0 +1,190 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+
+	"github.com/cubefs/blobstore/api/access"
+	"github.com/cubefs/blobstore/common/rpc"
+)
+
+const mimeByteRanges = "multipart/byteranges"
+
+// rangeRead one continuous read of location,
+// covers one or more sorted parts of the response.
+type rangeRead struct {
+	Offset   uint64
+	ReadSize uint64
+	parts    []access.Range
+}
+
+// genRangeReads plans reads of ranges.
+//
+// Overlapping or adjacent ranges are merged into one part of response,
+// then parts are coalesced into one read if the next part starts in
+// the last blob of the previous read, so that every blob of
+// Location.Spread() is read at most once.
+func genRangeReads(location *access.Location, ranges []access.Range) ([]rangeRead, error) {
+	blobSize := uint64(location.BlobSize)
+	if blobSize <= 0 {
+		return nil, fmt.Errorf("BlobSize:%d", blobSize)
+	}
+
+	sorted := make([]access.Range, 0, len(ranges))
+	for _, r := range ranges {
+		if r.ReadSize == 0 || r.Offset+r.ReadSize > location.RawSize() {
+			return nil, fmt.Errorf("FileSize:%d ReadSize:%d Offset:%d", location.RawSize(), r.ReadSize, r.Offset)
+		}
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	parts := make([]access.Range, 0, len(sorted))
+	for _, r := range sorted {
+		if n := len(parts); n > 0 && r.Offset <= parts[n-1].Offset+parts[n-1].ReadSize {
+			last := &parts[n-1]
+			last.ReadSize = maxU64(last.ReadSize, r.Offset+r.ReadSize-last.Offset)
+			continue
+		}
+		parts = append(parts, r)
+	}
+
+	reads := make([]rangeRead, 0, len(parts))
+	for _, part := range parts {
+		if n := len(reads); n > 0 {
+			last := &reads[n-1]
+			lastEnd := last.Offset + last.ReadSize
+			if (lastEnd-1)/blobSize == part.Offset/blobSize {
+				last.ReadSize = part.Offset + part.ReadSize - last.Offset
+				last.parts = append(last.parts, part)
+				continue
+			}
+		}
+		reads = append(reads, rangeRead{
+			Offset:   part.Offset,
+			ReadSize: part.ReadSize,
+			parts:    []access.Range{part},
+		})
+	}
+	return reads, nil
+}
+
+// rangesWriter writes multipart/byteranges body
+type rangesWriter struct {
+	mw   *multipart.Writer
+	size uint64
+}
+
+func newRangesWriter(w io.Writer, size uint64) *rangesWriter {
+	return &rangesWriter{mw: multipart.NewWriter(w), size: size}
+}
+
+func (w *rangesWriter) partHeader(part access.Range) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		rpc.HeaderContentType: {rpc.MIMEStream},
+		rpc.HeaderContentRange: {fmt.Sprintf("bytes %d-%d/%d",
+			part.Offset, part.Offset+part.ReadSize-1, w.size)},
+	}
+}
+
+// ContentType returns content type with boundary
+func (w *rangesWriter) ContentType() string {
+	return mimeByteRanges + "; boundary=" + w.mw.Boundary()
+}
+
+// ContentLength returns length of the whole body
+func (w *rangesWriter) ContentLength(reads []rangeRead) int64 {
+	var counter countWriter
+	mw := multipart.NewWriter(&counter)
+	mw.SetBoundary(w.mw.Boundary())
+
+	size := int64(0)
+	for _, read := range reads {
+		for _, part := range read.parts {
+			mw.CreatePart(w.partHeader(part))
+			size += int64(part.ReadSize)
+		}
+	}
+	mw.Close()
+	return int64(counter) + size
+}
+
+// ReadWriter returns writer of the read, bytes between parts are discarded
+func (w *rangesWriter) ReadWriter(read rangeRead) io.Writer {
+	return &rangeReadWriter{rw: w, offset: read.Offset, parts: read.parts}
+}
+
+// Close writes the trailing boundary
+func (w *rangesWriter) Close() error {
+	return w.mw.Close()
+}
+
+type rangeReadWriter struct {
+	rw     *rangesWriter
+	offset uint64
+	parts  []access.Range
+	part   io.Writer
+}
+
+func (w *rangeReadWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		if len(w.parts) == 0 {
+			break
+		}
+
+		curr := w.parts[0]
+		if w.offset < curr.Offset {
+			n := minU64(uint64(len(p)), curr.Offset-w.offset)
+			w.offset += n
+			p = p[n:]
+			continue
+		}
+
+		if w.part == nil {
+			part, err := w.rw.mw.CreatePart(w.rw.partHeader(curr))
+			if err != nil {
+				return 0, err
+			}
+			w.part = part
+		}
+
+		n := minU64(uint64(len(p)), curr.Offset+curr.ReadSize-w.offset)
+		if _, err := w.part.Write(p[:n]); err != nil {
+			return 0, err
+		}
+		w.offset += n
+		p = p[n:]
+		if w.offset == curr.Offset+curr.ReadSize {
+			w.part = nil
+			w.parts = w.parts[1:]
+		}
+	}
+	return written, nil
+}
+
+type countWriter int64
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	*w += countWriter(len(p))
+	return len(p), nil
+}
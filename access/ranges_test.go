@@ -0,0 +1,123 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/api/access"
+	"github.com/cubefs/blobstore/common/rpc"
+)
+
+func TestAccessGenRangeReads(t *testing.T) {
+	loc := &access.Location{Size: 1024 * 10, BlobSize: 1024}
+	// ranges returns ranges of offset and read size pairs
+	ranges := func(vals ...uint64) []access.Range {
+		rs := make([]access.Range, 0, len(vals)/2)
+		for idx := 0; idx < len(vals); idx += 2 {
+			rs = append(rs, access.Range{Offset: vals[idx], ReadSize: vals[idx+1]})
+		}
+		return rs
+	}
+
+	_, err := genRangeReads(&access.Location{Size: 10}, ranges(0, 1))
+	require.Error(t, err)
+	_, err = genRangeReads(loc, ranges(0, 0))
+	require.Error(t, err)
+	_, err = genRangeReads(loc, ranges(1024*10, 1))
+	require.Error(t, err)
+
+	cases := []struct {
+		ranges []access.Range
+		reads  []rangeRead
+	}{
+		{ranges(0, 1), []rangeRead{{0, 1, ranges(0, 1)}}},
+		// merge overlapping and adjacent ranges
+		{ranges(10, 10, 0, 15, 20, 5), []rangeRead{{0, 25, ranges(0, 25)}}},
+		{ranges(0, 10, 5, 2), []rangeRead{{0, 10, ranges(0, 10)}}},
+		// coalesce ranges in the same blob
+		{ranges(100, 10, 0, 10), []rangeRead{{0, 110, ranges(0, 10, 100, 10)}}},
+		{ranges(0, 1030, 2000, 10, 3000, 10), []rangeRead{
+			{0, 2010, ranges(0, 1030, 2000, 10)},
+			{3000, 10, ranges(3000, 10)},
+		}},
+		// separated blobs
+		{ranges(1023, 1, 1024, 1, 4096, 1024, 9000, 1240), []rangeRead{
+			{1023, 2, ranges(1023, 2)},
+			{4096, 1024, ranges(4096, 1024)},
+			{9000, 1240, ranges(9000, 1240)},
+		}},
+	}
+	for _, cs := range cases {
+		reads, err := genRangeReads(loc, cs.ranges)
+		require.NoError(t, err)
+		require.Equal(t, cs.reads, reads)
+	}
+}
+
+func TestAccessRangesWriter(t *testing.T) {
+	data := make([]byte, 1024)
+	for idx := range data {
+		data[idx] = byte(idx)
+	}
+	reads := []rangeRead{
+		{0, 110, []access.Range{{Offset: 0, ReadSize: 10}, {Offset: 100, ReadSize: 10}}},
+		{500, 24, []access.Range{{Offset: 500, ReadSize: 24}}},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := newRangesWriter(buf, uint64(len(data)))
+	for _, read := range reads {
+		rw := w.ReadWriter(read)
+		// write byte by byte
+		for idx := read.Offset; idx < read.Offset+read.ReadSize; idx++ {
+			n, err := rw.Write(data[idx : idx+1])
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+		}
+	}
+	require.NoError(t, w.Close())
+	require.Equal(t, int64(buf.Len()), w.ContentLength(reads))
+
+	mediaType, params, err := mime.ParseMediaType(w.ContentType())
+	require.NoError(t, err)
+	require.Equal(t, mimeByteRanges, mediaType)
+
+	reader := multipart.NewReader(buf, params["boundary"])
+	for _, contentRange := range []struct {
+		header string
+		from   int
+		to     int
+	}{
+		{"bytes 0-9/1024", 0, 10},
+		{"bytes 100-109/1024", 100, 110},
+		{"bytes 500-523/1024", 500, 524},
+	} {
+		part, err := reader.NextPart()
+		require.NoError(t, err)
+		require.Equal(t, contentRange.header, part.Header.Get(rpc.HeaderContentRange))
+		b, err := ioutil.ReadAll(part)
+		require.NoError(t, err)
+		require.Equal(t, data[contentRange.from:contentRange.to], b)
+	}
+	_, err = reader.NextPart()
+	require.Error(t, err)
+}
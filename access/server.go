@@ -15,9 +15,13 @@
 package access
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/sha1"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"strconv"
 	"sync"
 	"time"
@@ -33,8 +37,10 @@ import (
 	"github.com/cubefs/blobstore/common/proto"
 	"github.com/cubefs/blobstore/common/resourcepool"
 	"github.com/cubefs/blobstore/common/rpc"
+	"github.com/cubefs/blobstore/common/rpc/auth"
 	"github.com/cubefs/blobstore/common/trace"
 	"github.com/cubefs/blobstore/common/uptoken"
+	"github.com/cubefs/blobstore/util/defaulter"
 	"github.com/cubefs/blobstore/util/errors"
 	"github.com/cubefs/blobstore/util/log"
 )
@@ -46,6 +52,8 @@ const (
 	limitNameGet    = "get"
 	limitNameDelete = "delete"
 	limitNameSign   = "sign"
+
+	limitNameMultipart = "multipart"
 )
 
 const (
@@ -86,8 +94,9 @@ func initWithRegionMagic(regionMagic string) {
 }
 
 type accessStatus struct {
-	Limit Status              `json:"limit"`
-	Pool  resourcepool.Status `json:"pool"`
+	Limit   Status                  `json:"limit"`
+	Pool    resourcepool.Status     `json:"pool"`
+	Tenants map[string]TenantStatus `json:"tenants,omitempty"`
 }
 
 // Config service configs
@@ -97,6 +106,10 @@ type Config struct {
 	ServiceRegister consul.Config `json:"service_register"`
 	Stream          StreamConfig  `json:"stream"`
 	Limit           LimitConfig   `json:"limit"`
+
+	Multipart MultipartConfig `json:"multipart"`
+	Dedup     DedupConfig     `json:"dedup"`
+	Tenant    TenantConfig    `json:"tenant"`
 }
 
 // Service rpc service
@@ -104,6 +117,9 @@ type Service struct {
 	config        Config
 	streamHandler StreamHandler
 	limiter       Limiter
+	multipart     multipartStore
+	dedup         DedupIndex
+	tenants       *tenantLimiter
 	stopCh        chan struct{}
 }
 
@@ -120,13 +136,41 @@ func New(cfg Config) *Service {
 	// add region magic checksum to the secret keys
 	initWithRegionMagic(cfg.Stream.ClusterConfig.RegionMagic)
 
+	defaulter.LessOrEqual(&cfg.Multipart.ExpireHours, defaultMultipartExpireHours)
+	defaulter.LessOrEqual(&cfg.Multipart.ReclaimIntervalS, defaultMultipartReclaimIntervalS)
+	defaulter.LessOrEqual(&cfg.Multipart.DefaultPartBlobs, defaultMultipartPartBlobs)
+
 	stopCh := make(chan struct{})
-	return &Service{
+	service := &Service{
 		config:        cfg,
 		streamHandler: NewStreamHandler(&cfg.Stream, client, stopCh),
 		limiter:       NewLimiter(cfg.Limit),
+		multipart:     newConsulMultipartStore(client, cfg.Stream.ClusterConfig.Region),
 		stopCh:        stopCh,
 	}
+	if cfg.Dedup.Enable {
+		if service.dedup, err = newDedupIndex(cfg.Dedup, client, cfg.Stream.ClusterConfig.Region); err != nil {
+			log.Fatalf("new dedup index failed, err: %v", err)
+		}
+	}
+	if cfg.Tenant.Enable {
+		defaulter.LessOrEqual(&service.config.Tenant.ReloadIntervalS, defaultTenantReloadIntervalS)
+		defaulter.LessOrEqual(&service.config.Tenant.SyncIntervalS, defaultTenantSyncIntervalS)
+		node := cfg.Tenant.Node
+		if node == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				log.Fatalf("get hostname failed, err: %v", err)
+			}
+			node = hostname + "_" + cfg.BindAddr
+		}
+		service.tenants = newTenantLimiter(node,
+			newConsulTenantUsageStore(client, cfg.Stream.ClusterConfig.Region))
+		admin := service.streamHandler.Admin().(*streamAdmin)
+		service.loopTenant(admin.controller.GetConfig)
+	}
+	service.loopReclaimMultipart()
+	return service
 }
 
 // Close close server
@@ -134,6 +178,9 @@ func (s *Service) Close() {
 	if s.stopCh != nil {
 		close(s.stopCh)
 	}
+	if s.dedup != nil {
+		s.dedup.Close()
+	}
 }
 
 // RegisterService register service to rpc
@@ -149,6 +196,9 @@ func (s *Service) RegisterAdminHandler() {
 	profile.HandleFunc(http.MethodGet, "/access/status", func(c *rpc.Context) {
 		status := new(accessStatus)
 		status.Limit = s.limiter.Status()
+		if s.tenants != nil {
+			status.Tenants = s.tenants.Status()
+		}
 		if sa := s.streamHandler.Admin(); sa != nil {
 			if admin, ok := sa.(*streamAdmin); ok {
 				status.Pool = admin.memPool.Status()
@@ -192,12 +242,15 @@ func (s *Service) Limit(c *rpc.Context) {
 		name = limitNamePut
 	case "/putat":
 		name = limitNamePutAt
-	case "/get":
+	case "/get", "/get/ranges":
 		name = limitNameGet
 	case "/delete":
 		name = limitNameDelete
 	case "/sign":
 		name = limitNameSign
+	case "/multipart/init", "/multipart/put", "/multipart/list",
+		"/multipart/complete", "/multipart/abort":
+		name = limitNameMultipart
 	}
 	if name == "" {
 		return
@@ -210,9 +263,107 @@ func (s *Service) Limit(c *rpc.Context) {
 		return
 	}
 	defer s.limiter.Release(name)
+
+	if s.tenants != nil {
+		tenant := auth.Tenant(c.Request)
+		if tenant == "" {
+			c.AbortWithError(errcode.ErrAccessNoTenant)
+			return
+		}
+		if err := s.tenants.Acquire(tenant); err != nil {
+			span := trace.SpanFromContextSafe(c.Request.Context())
+			span.Info("access tenant limited", tenant, name, err)
+			c.AbortWithError(errcode.ErrAccessLimited)
+			return
+		}
+	}
 	c.Next()
 }
 
+// requestTenant returns tenant of the request if tenant is enabled
+func (s *Service) requestTenant(c *rpc.Context) string {
+	if s.tenants == nil {
+		return ""
+	}
+	return auth.Tenant(c.Request)
+}
+
+// tenantReader returns reader of request body with limits of service and tenant
+func (s *Service) tenantReader(c *rpc.Context) io.Reader {
+	ctx := c.Request.Context()
+	rc := s.limiter.Reader(ctx, c.Request.Body)
+	if s.tenants != nil {
+		rc = s.tenants.Reader(ctx, auth.Tenant(c.Request), rc)
+	}
+	return rc
+}
+
+// tenantWriter returns writer of response with limits of service and tenant
+func (s *Service) tenantWriter(c *rpc.Context, w io.Writer) io.Writer {
+	ctx := c.Request.Context()
+	w = s.limiter.Writer(ctx, w)
+	if s.tenants != nil {
+		w = s.tenants.Writer(ctx, auth.Tenant(c.Request), w)
+	}
+	return w
+}
+
+// checkTenantQuota returns error if stored bytes of tenant will exceed quota
+func (s *Service) checkTenantQuota(c *rpc.Context, size int64) error {
+	if s.tenants == nil {
+		return nil
+	}
+	return s.tenants.CheckQuota(auth.Tenant(c.Request), size)
+}
+
+// accountTenant adds delta of stored bytes of tenant
+func (s *Service) accountTenant(tenant string, delta int64) {
+	if s.tenants != nil {
+		s.tenants.Account(tenant, delta)
+	}
+}
+
+// tenantBlob returns id of blobs from the first bid in the cluster in tenant accounting
+func tenantBlob(clusterID proto.ClusterID, bid proto.BlobID) string {
+	return fmt.Sprintf("%d-%d", clusterID, bid)
+}
+
+// expireTenant records stored bytes of the location to be released when expired,
+// they are released only once if the location is deleted before expired.
+func (s *Service) expireTenant(ctx context.Context, loc *access.Location, expireAt int64) {
+	if s.tenants == nil || expireAt == 0 || len(loc.Blobs) == 0 {
+		return
+	}
+	blob := tenantBlob(loc.ClusterID, loc.Blobs[0].MinBid)
+	if err := s.tenants.Expire(ctx, loc.Tenant, blob, expireAt, int64(loc.RawSize())); err != nil {
+		span := trace.SpanFromContextSafe(ctx)
+		span.Errorf("expire tenant %s blob %s failed %s", loc.Tenant, blob, errors.Detail(err))
+	}
+}
+
+// releaseTenant releases stored bytes of the tenant for the deleted blobs
+// from the first bid in the cluster, deleted again releases nothing.
+func (s *Service) releaseTenant(ctx context.Context, tenant string,
+	clusterID proto.ClusterID, bid proto.BlobID, bytes int64) {
+	if s.tenants == nil {
+		return
+	}
+	if err := s.tenants.Release(ctx, tenant, tenantBlob(clusterID, bid), bytes); err != nil {
+		span := trace.SpanFromContextSafe(ctx)
+		span.Warnf("release tenant %s bytes %d failed %s", tenant, bytes, errors.Detail(err))
+	}
+}
+
+// ownLocation records the tenant as owner of the location,
+// stored bytes of the location are released by the owner when deleted.
+func ownLocation(loc *access.Location, tenant string) {
+	if tenant == "" {
+		return
+	}
+	loc.Version = access.LocationVersion2
+	loc.Tenant = tenant
+}
+
 // Put one object
 func (s *Service) Put(c *rpc.Context) {
 	args := new(access.PutArgs)
@@ -230,6 +381,10 @@ func (s *Service) Put(c *rpc.Context) {
 		c.RespondError(errcode.ErrIllegalArguments)
 		return
 	}
+	if err := s.checkTenantQuota(c, args.Size); err != nil {
+		c.RespondError(err)
+		return
+	}
 
 	hashSumMap := args.Hashes.ToHashSumMap()
 	hasherMap := make(access.HasherMap, len(hashSumMap))
@@ -238,8 +393,22 @@ func (s *Service) Put(c *rpc.Context) {
 		hasherMap[alg] = alg.ToHasher()
 	}
 
-	rc := s.limiter.Reader(ctx, c.Request.Body)
-	loc, err := s.streamHandler.Put(ctx, rc, args.Size, hasherMap)
+	tenant := s.requestTenant(c)
+	rc := s.tenantReader(c)
+	var loc *access.Location
+	var err error
+	// objects with expiry are never deduplicated
+	dedup := args.ContentSHA256 != "" && s.dedup != nil && args.ExpireAt == 0
+	if dedup {
+		// stored bytes of dedup are accounted in dedup put
+		loc, err = s.dedupPut(ctx, rc, args, hasherMap, tenant)
+	} else {
+		loc, err = s.streamHandler.Put(ctx, rc, args.Size, hasherMap,
+			PutOptions{Codec: args.Compression, ExpireAt: args.ExpireAt})
+		if err == nil {
+			ownLocation(loc, tenant)
+		}
+	}
 	if err != nil {
 		span.Error("stream put failed", errors.Detail(err))
 		c.RespondError(httpError(err))
@@ -257,6 +426,10 @@ func (s *Service) Put(c *rpc.Context) {
 		return
 	}
 
+	if !dedup {
+		s.accountTenant(tenant, args.Size)
+		s.expireTenant(ctx, loc, args.ExpireAt)
+	}
 	c.RespondJSON(access.PutResp{
 		Location:   *loc,
 		HashSumMap: hashSumMap,
@@ -281,20 +454,31 @@ func (s *Service) PutAt(c *rpc.Context) {
 		c.RespondError(errcode.ErrIllegalArguments)
 		return
 	}
+	// token of blob carries no data key, the blob would be stored in plaintext
+	if s.config.Stream.Encryption.Enable {
+		c.RespondError(errcode.ErrAccessEncrypted)
+		return
+	}
 
-	valid := false
-	for _, secretKey := range tokenSecretKeys {
-		token := uptoken.DecodeToken(args.Token)
-		if token.IsValid(args.ClusterID, args.Vid, args.Blobid, uint32(args.Size), secretKey[:]) {
-			valid = true
-			break
-		}
+	// bytes of expiring blobs are released at the location level,
+	// client puts object with expiry by multipart if accounting tenants
+	if args.ExpireAt > 0 && s.tenants != nil {
+		span.Debugf("putat with expiry %d is unsupported with tenants", args.ExpireAt)
+		c.RespondError(errcode.ErrIllegalArguments)
+		return
 	}
-	if !valid {
+
+	// token is valid only for the tenant which allocated the location
+	tenant := s.requestTenant(c)
+	if !isValidToken(args.Token, args.ClusterID, args.Vid, args.Blobid, uint32(args.Size), tenant) {
 		span.Debugf("invalid token:%s", args.Token)
 		c.RespondError(errcode.ErrIllegalArguments)
 		return
 	}
+	if err := s.checkTenantQuota(c, args.Size); err != nil {
+		c.RespondError(err)
+		return
+	}
 
 	hashSumMap := args.Hashes.ToHashSumMap()
 	hasherMap := make(access.HasherMap, len(hashSumMap))
@@ -303,8 +487,9 @@ func (s *Service) PutAt(c *rpc.Context) {
 		hasherMap[alg] = alg.ToHasher()
 	}
 
-	rc := s.limiter.Reader(ctx, c.Request.Body)
-	err := s.streamHandler.PutAt(ctx, rc, args.ClusterID, args.Vid, args.Blobid, args.Size, hasherMap)
+	rc := s.tenantReader(c)
+	err := s.streamHandler.PutAt(ctx, rc, args.ClusterID, args.Vid, args.Blobid, args.Size, hasherMap,
+		PutOptions{ExpireAt: args.ExpireAt})
 	if err != nil {
 		span.Error("stream putat failed", errors.Detail(err))
 		c.RespondError(httpError(err))
@@ -316,6 +501,8 @@ func (s *Service) PutAt(c *rpc.Context) {
 		hashSumMap[alg] = hasher.Sum(nil)
 	}
 
+	// stored bytes are accounted when blobs written, rather than allocated
+	s.accountTenant(tenant, args.Size)
 	c.RespondJSON(access.PutAtResp{HashSumMap: hashSumMap})
 	span.Infof("done /putat request hash:%+v", hashSumMap.All())
 }
@@ -337,6 +524,16 @@ func (s *Service) Alloc(c *rpc.Context) {
 		c.RespondError(errcode.ErrIllegalArguments)
 		return
 	}
+	// blobs of allocated location are put by /putat which is unsupported with encryption,
+	// client puts large object by multipart upload if rejected here.
+	if s.config.Stream.Encryption.Enable {
+		c.RespondError(errcode.ErrAccessEncrypted)
+		return
+	}
+	if err := s.checkTenantQuota(c, int64(args.Size)); err != nil {
+		c.RespondError(err)
+		return
+	}
 
 	location, err := s.streamHandler.Alloc(ctx, args.Size, args.BlobSize, args.AssignClusterID, args.CodeMode)
 	if err != nil {
@@ -345,6 +542,7 @@ func (s *Service) Alloc(c *rpc.Context) {
 		return
 	}
 
+	ownLocation(location, s.requestTenant(c))
 	if err := fillCrc(location); err != nil {
 		span.Error("stream alloc fill location crc", err)
 		c.RespondError(httpError(err))
@@ -378,7 +576,7 @@ func (s *Service) Get(c *rpc.Context) {
 	}
 
 	w := c.Writer
-	writer := s.limiter.Writer(ctx, w)
+	writer := s.tenantWriter(c, w)
 	transfer, err := s.streamHandler.Get(ctx, writer, args.Location, args.ReadSize, args.Offset)
 	if err != nil {
 		span.Error("stream get prepare failed", errors.Detail(err))
@@ -388,9 +586,9 @@ func (s *Service) Get(c *rpc.Context) {
 
 	w.Header().Set(rpc.HeaderContentType, rpc.MIMEStream)
 	w.Header().Set(rpc.HeaderContentLength, strconv.FormatInt(int64(args.ReadSize), 10))
-	if args.ReadSize > 0 && args.ReadSize != args.Location.Size {
+	if args.ReadSize > 0 && args.ReadSize != args.Location.RawSize() {
 		w.Header().Set(rpc.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d",
-			args.Offset, args.Offset+args.ReadSize-1, args.Location.Size))
+			args.Offset, args.Offset+args.ReadSize-1, args.Location.RawSize()))
 		c.RespondStatus(http.StatusPartialContent)
 	} else {
 		c.RespondStatus(http.StatusOK)
@@ -407,6 +605,65 @@ func (s *Service) Get(c *rpc.Context) {
 	span.Info("done /get request")
 }
 
+// GetRanges read multiple ranges of file, response with multipart/byteranges
+func (s *Service) GetRanges(c *rpc.Context) {
+	args := new(access.GetRangesArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Debugf("accept /get/ranges request args:%+v", args)
+	if !args.IsValid() || !verifyCrc(&args.Location) {
+		span.Debugf("invalid args:%+v", args)
+		c.RespondError(errcode.ErrIllegalArguments)
+		return
+	}
+
+	reads, err := genRangeReads(&args.Location, args.Ranges)
+	if err != nil {
+		span.Info("illegal argument", err)
+		c.RespondError(errcode.ErrIllegalArguments)
+		return
+	}
+
+	w := c.Writer
+	rangesWriter := newRangesWriter(s.tenantWriter(c, w), args.Location.RawSize())
+	transfers := make([]func() error, 0, len(reads))
+	for _, read := range reads {
+		transfer, err := s.streamHandler.Get(ctx, rangesWriter.ReadWriter(read),
+			args.Location, read.ReadSize, read.Offset)
+		if err != nil {
+			span.Error("stream get prepare failed", errors.Detail(err))
+			c.RespondError(httpError(err))
+			return
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	w.Header().Set(rpc.HeaderContentType, rangesWriter.ContentType())
+	w.Header().Set(rpc.HeaderContentLength, strconv.FormatInt(rangesWriter.ContentLength(reads), 10))
+	c.RespondStatus(http.StatusPartialContent)
+
+	// flush headers to client firstly
+	c.Flush()
+
+	for _, transfer := range transfers {
+		if err = transfer(); err != nil {
+			span.Error("stream get transfer failed", errors.Detail(err))
+			return
+		}
+	}
+	if err = rangesWriter.Close(); err != nil {
+		span.Error("write ranges boundary failed", err)
+		return
+	}
+	span.Infof("done /get/ranges request ranges:%d reads:%d", len(args.Ranges), len(reads))
+}
+
 // Delete  all blobs in this location
 func (s *Service) Delete(c *rpc.Context) {
 	args := new(access.DeleteArgs)
@@ -426,6 +683,19 @@ func (s *Service) Delete(c *rpc.Context) {
 			return
 		}
 
+		// stored bytes of deleted locations are released by their owners once,
+		// bytes of dedup locations are released after the last reference deleted
+		failed := make(map[uint32]struct{}, len(resp.FailedLocations))
+		for _, loc := range resp.FailedLocations {
+			failed[loc.Crc] = struct{}{}
+		}
+		for _, loc := range args.Locations {
+			if _, ok := failed[loc.Crc]; ok || loc.Dedup || len(loc.Blobs) == 0 {
+				continue
+			}
+			s.releaseTenant(ctx, loc.Tenant, loc.ClusterID, loc.Blobs[0].MinBid, int64(loc.RawSize()))
+		}
+
 		if len(resp.FailedLocations) > 0 {
 			span.Errorf("failed locations N %d of %d", len(resp.FailedLocations), len(args.Locations))
 			// must return 2xx even if has failed locations,
@@ -445,21 +715,40 @@ func (s *Service) Delete(c *rpc.Context) {
 	span.Debugf("accept /delete request args: locations %d", len(args.Locations))
 	defer span.Info("done /delete request")
 
-	clusterBlobsN := make(map[proto.ClusterID]int, 4)
 	for _, loc := range args.Locations {
 		if !verifyCrc(&loc) {
 			span.Infof("invalid crc %+v", loc)
 			err = errcode.ErrIllegalArguments
 			return
 		}
+	}
+
+	// release reference of dedup locations, blobs are deleted after the last one
+	locations := make([]access.Location, 0, len(args.Locations))
+	for _, loc := range args.Locations {
+		if !loc.Dedup {
+			locations = append(locations, loc)
+			continue
+		}
+		if err := s.dedupDelete(ctx, &loc); err != nil {
+			span.Error("dedup delete failed", errors.Detail(err))
+			resp.FailedLocations = append(resp.FailedLocations, loc)
+		}
+	}
+	if len(locations) == 0 {
+		return
+	}
+
+	clusterBlobsN := make(map[proto.ClusterID]int, 4)
+	for _, loc := range locations {
 		clusterBlobsN[loc.ClusterID] += len(loc.Blobs)
 	}
 
-	if len(args.Locations) == 1 {
-		loc := args.Locations[0]
+	if len(locations) == 1 {
+		loc := locations[0]
 		if err := s.streamHandler.Delete(ctx, &loc); err != nil {
 			span.Error("stream delete failed", errors.Detail(err))
-			resp.FailedLocations = []access.Location{loc}
+			resp.FailedLocations = append(resp.FailedLocations, loc)
 		}
 		return
 	}
@@ -475,7 +764,7 @@ func (s *Service) Delete(c *rpc.Context) {
 	for id, n := range clusterBlobsN {
 		merged[id] = make([]access.SliceInfo, 0, n)
 	}
-	for _, loc := range args.Locations {
+	for _, loc := range locations {
 		merged[loc.ClusterID] = append(merged[loc.ClusterID], loc.Blobs...)
 	}
 
@@ -487,7 +776,7 @@ func (s *Service) Delete(c *rpc.Context) {
 			if resp.FailedLocations == nil {
 				resp.FailedLocations = make([]access.Location, 0, len(args.Locations))
 			}
-			for _, loc := range args.Locations {
+			for _, loc := range locations {
 				if loc.ClusterID == id {
 					resp.FailedLocations = append(resp.FailedLocations, loc)
 				}
@@ -534,15 +823,8 @@ func (s *Service) DeleteBlob(c *rpc.Context) {
 		return
 	}
 
-	valid := false
-	for _, secretKey := range tokenSecretKeys {
-		token := uptoken.DecodeToken(args.Token)
-		if token.IsValid(args.ClusterID, args.Vid, args.Blobid, uint32(args.Size), secretKey[:]) {
-			valid = true
-			break
-		}
-	}
-	if !valid {
+	tenant := s.requestTenant(c)
+	if !isValidToken(args.Token, args.ClusterID, args.Vid, args.Blobid, uint32(args.Size), tenant) {
 		span.Debugf("invalid token:%s", args.Token)
 		c.RespondError(errcode.ErrIllegalArguments)
 		return
@@ -562,6 +844,7 @@ func (s *Service) DeleteBlob(c *rpc.Context) {
 		return
 	}
 
+	s.releaseTenant(ctx, tenant, args.ClusterID, args.Blobid, args.Size)
 	c.Respond()
 	span.Info("done /deleteblob request")
 }
@@ -613,8 +896,30 @@ func httpError(err error) error {
 //    will be used by the last blob, even if the last slice blobs' size
 //    less than blobsize.
 // 5. Each segment blob has its specified token include the last blob.
+// tenantSecretKey returns secret key of tokens of the tenant
+func tenantSecretKey(secretKey []byte, tenant string) []byte {
+	if tenant == "" {
+		return secretKey
+	}
+	h := hmac.New(sha1.New, secretKey)
+	h.Write([]byte(tenant))
+	return h.Sum(nil)
+}
+
+func isValidToken(s string, clusterID proto.ClusterID, vid proto.Vid, bid proto.BlobID,
+	size uint32, tenant string) bool {
+	token := uptoken.DecodeToken(s)
+	for _, secretKey := range tokenSecretKeys {
+		if token.IsValid(clusterID, vid, bid, size, tenantSecretKey(secretKey[:], tenant)) {
+			return true
+		}
+	}
+	return false
+}
+
 func genTokens(location *access.Location) []string {
 	tokens := make([]string, 0, len(location.Blobs)+1)
+	secretKey := tenantSecretKey(tokenSecretKeys[0][:], location.Tenant)
 
 	hasMultiBlobs := location.Size >= uint64(location.BlobSize)
 	lastSize := uint32(location.Size % uint64(location.BlobSize))
@@ -627,14 +932,14 @@ func genTokens(location *access.Location) []string {
 			}
 			tokens = append(tokens, uptoken.EncodeToken(uptoken.NewUploadToken(location.ClusterID,
 				blob.Vid, blob.MinBid, count,
-				location.BlobSize, _tokenExpiration, tokenSecretKeys[0][:])))
+				location.BlobSize, _tokenExpiration, secretKey)))
 		}
 
 		// token of the last blob
 		if idx == len(location.Blobs)-1 && lastSize > 0 {
 			tokens = append(tokens, uptoken.EncodeToken(uptoken.NewUploadToken(location.ClusterID,
 				blob.Vid, blob.MinBid+proto.BlobID(blob.Count)-1, 1,
-				lastSize, _tokenExpiration, tokenSecretKeys[0][:])))
+				lastSize, _tokenExpiration, secretKey)))
 		}
 	}
 
@@ -50,7 +50,7 @@ func initLocationSecret(b []byte) {
 func calcCrc(loc *access.Location) (uint32, error) {
 	crcWriter := crc32.New(_crcTable)
 
-	buf := bytespool.Alloc(1024)
+	buf := bytespool.Alloc(loc.EncodeSize())
 	defer bytespool.Free(buf)
 
 	n := loc.Encode2(buf)
@@ -0,0 +1,466 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cubefs/blobstore/api/access"
+	errcode "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/rpc"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/util/errors"
+)
+
+// MultipartInit initiate a multipart upload, alloc all blobs of the object
+func (s *Service) MultipartInit(c *rpc.Context) {
+	args := new(access.MultipartInitArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Debugf("accept /multipart/init request args:%+v", args)
+	if !args.IsValid() {
+		span.Debugf("invalid args:%+v", args)
+		c.RespondError(errcode.ErrIllegalArguments)
+		return
+	}
+	if err := s.checkTenantQuota(c, int64(args.Size)); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	location, err := s.streamHandler.Alloc(ctx, args.Size, 0, 0, 0)
+	if err != nil {
+		span.Error("stream alloc failed", errors.Detail(err))
+		c.RespondError(httpError(err))
+		return
+	}
+	// parts are encrypted with the same data key at their offsets
+	encryption, err := s.streamHandler.NewEncryption()
+	if err != nil {
+		span.Error("new encryption failed", errors.Detail(err))
+		if err := s.streamHandler.Delete(ctx, location); err != nil {
+			span.Warn(errors.Detail(err))
+		}
+		c.RespondError(httpError(err))
+		return
+	}
+	if encryption != nil {
+		location.Version = access.LocationVersion2
+		location.Encryption = encryption
+	}
+	ownLocation(location, s.requestTenant(c))
+
+	blobSize := uint64(location.BlobSize)
+	partSize := args.PartSize
+	if partSize == 0 {
+		partSize = blobSize * uint64(s.config.Multipart.DefaultPartBlobs)
+	}
+	if partSize < blobSize {
+		partSize = blobSize
+	}
+	partSize = (partSize + blobSize - 1) / blobSize * blobSize
+
+	upload := &multipartUpload{
+		UploadID:   uuid.New().String(),
+		Location:   *location,
+		PartSize:   partSize,
+		CreateTime: time.Now().Unix(),
+		ExpireAt:   args.ExpireAt,
+	}
+	if err = s.multipart.Create(ctx, upload); err != nil {
+		span.Error("create multipart upload failed", err)
+		if err := s.streamHandler.Delete(ctx, location); err != nil {
+			span.Warn(errors.Detail(err))
+		}
+		c.RespondError(httpError(err))
+		return
+	}
+
+	resp := access.MultipartInitResp{
+		UploadID:  upload.UploadID,
+		PartSize:  upload.PartSize,
+		PartCount: upload.partCount(),
+	}
+	c.RespondJSON(resp)
+	span.Infof("done /multipart/init request resp:%+v location:%+v", resp, location)
+}
+
+// MultipartPut put one part of the multipart upload
+func (s *Service) MultipartPut(c *rpc.Context) {
+	args := new(access.MultipartPutArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Debugf("accept /multipart/put request args:%+v", args)
+	if !args.IsValid() {
+		span.Debugf("invalid args:%+v", args)
+		c.RespondError(errcode.ErrIllegalArguments)
+		return
+	}
+
+	upload, err := s.multipart.Get(ctx, args.UploadID)
+	if err != nil {
+		span.Info("get multipart upload failed", args.UploadID, err)
+		c.RespondError(httpError(err))
+		return
+	}
+	if upload.State != multipartUploading {
+		span.Infof("upload(%s) is not uploading, state:%d", upload.UploadID, upload.State)
+		c.RespondError(errcode.ErrAccessMultipartNoSuch)
+		return
+	}
+
+	blobs := upload.partBlobs(args.PartNumber)
+	size := int64(0)
+	for _, blob := range blobs {
+		size += int64(blob.Size)
+	}
+	if len(blobs) == 0 || size != args.Size {
+		span.Infof("mismatch part(%d) size %d != %d", args.PartNumber, args.Size, size)
+		c.RespondError(errcode.ErrIllegalArguments)
+		return
+	}
+
+	hashSumMap := args.Hashes.ToHashSumMap()
+	hasherMap := make(access.HasherMap, len(hashSumMap))
+	// make hashser
+	for alg := range hashSumMap {
+		hasherMap[alg] = alg.ToHasher()
+	}
+
+	rc := s.tenantReader(c)
+	if len(hasherMap) > 0 {
+		rc = io.TeeReader(rc, hasherMap.ToWriter())
+	}
+	offset := uint64(args.PartNumber-1) * upload.PartSize
+	if rc, err = s.streamHandler.EncryptAt(upload.Location.Encryption, offset, rc); err != nil {
+		span.Error("encrypt part failed", errors.Detail(err))
+		c.RespondError(httpError(err))
+		return
+	}
+
+	clusterID := upload.Location.ClusterID
+	for _, blob := range blobs {
+		err = s.streamHandler.PutAt(ctx, rc, clusterID, blob.Vid, blob.Bid, int64(blob.Size), nil,
+			PutOptions{ExpireAt: upload.ExpireAt})
+		if err != nil {
+			span.Error("stream putat failed", errors.Detail(err))
+			c.RespondError(httpError(err))
+			return
+		}
+	}
+
+	// hasher sum
+	for alg, hasher := range hasherMap {
+		hashSumMap[alg] = hasher.Sum(nil)
+	}
+
+	part := access.MultipartPart{
+		PartNumber: args.PartNumber,
+		Size:       args.Size,
+		HashSumMap: hashSumMap,
+	}
+	if err = s.multipart.SetPart(ctx, upload.UploadID, part); err != nil {
+		span.Error("save multipart part failed", err)
+		c.RespondError(httpError(err))
+		return
+	}
+	if err = s.checkMultipartPart(ctx, upload, args.PartNumber, blobs); err != nil {
+		span.Warn("check multipart part failed", errors.Detail(err))
+		c.RespondError(httpError(err))
+		return
+	}
+
+	c.RespondJSON(access.MultipartPutResp{HashSumMap: hashSumMap})
+	span.Infof("done /multipart/put request upload:%s part:%d hash:%+v",
+		upload.UploadID, args.PartNumber, hashSumMap.All())
+}
+
+// checkMultipartPart checks the upload is still uploading after the part was saved,
+// the upload may be finished while putting the part, and reclaiming may have done.
+// Then the saved part is removed, and blobs of the part are deleted if the upload was aborted.
+func (s *Service) checkMultipartPart(ctx context.Context, upload *multipartUpload,
+	partNumber int, blobs []access.Blob) error {
+	span := trace.SpanFromContextSafe(ctx)
+
+	saved, err := s.multipart.Get(ctx, upload.UploadID)
+	if err == nil && saved.State == multipartUploading {
+		return nil
+	}
+	if err != nil && err != errcode.ErrAccessMultipartNoSuch {
+		return err
+	}
+	span.Infof("upload(%s) was finished while putting part(%d)", upload.UploadID, partNumber)
+
+	if err = s.multipart.RemovePart(ctx, upload.UploadID, partNumber); err != nil {
+		return err
+	}
+
+	// blobs of completed upload belong to the object, completed upload
+	// is kept until expired, so removed upload in expire time was aborted.
+	expired := time.Now().Add(-time.Duration(s.config.Multipart.ExpireHours) * time.Hour).Unix()
+	aborted := saved != nil && saved.State == multipartAborted
+	if saved == nil && upload.CreateTime > expired {
+		aborted = true
+	}
+	if aborted {
+		location := access.Location{
+			ClusterID: upload.Location.ClusterID,
+			BlobSize:  upload.Location.BlobSize,
+			Blobs:     make([]access.SliceInfo, 0, len(blobs)),
+		}
+		for _, blob := range blobs {
+			location.Size += uint64(blob.Size)
+			location.Blobs = append(location.Blobs, access.SliceInfo{MinBid: blob.Bid, Vid: blob.Vid, Count: 1})
+		}
+		if err = s.streamHandler.Delete(ctx, &location); err != nil {
+			return err
+		}
+	}
+	return errcode.ErrAccessMultipartNoSuch
+}
+
+// MultipartList list uploaded parts of the multipart upload
+func (s *Service) MultipartList(c *rpc.Context) {
+	args := new(access.MultipartUploadArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Debugf("accept /multipart/list request args:%+v", args)
+	if !args.IsValid() {
+		c.RespondError(errcode.ErrIllegalArguments)
+		return
+	}
+
+	upload, err := s.multipart.Get(ctx, args.UploadID)
+	if err != nil {
+		span.Info("get multipart upload failed", args.UploadID, err)
+		c.RespondError(httpError(err))
+		return
+	}
+	if upload.State != multipartUploading {
+		c.RespondError(errcode.ErrAccessMultipartNoSuch)
+		return
+	}
+
+	c.RespondJSON(access.MultipartListResp{
+		UploadID:  upload.UploadID,
+		Size:      upload.Location.Size,
+		PartSize:  upload.PartSize,
+		PartCount: upload.partCount(),
+		Parts:     upload.sortedParts(),
+	})
+	span.Infof("done /multipart/list request upload:%s parts:%d", upload.UploadID, len(upload.parts))
+}
+
+// MultipartComplete complete the multipart upload if all parts were uploaded
+func (s *Service) MultipartComplete(c *rpc.Context) {
+	args := new(access.MultipartUploadArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Debugf("accept /multipart/complete request args:%+v", args)
+	if !args.IsValid() {
+		c.RespondError(errcode.ErrIllegalArguments)
+		return
+	}
+
+	upload, err := s.multipart.Get(ctx, args.UploadID)
+	if err != nil {
+		span.Info("get multipart upload failed", args.UploadID, err)
+		c.RespondError(httpError(err))
+		return
+	}
+
+	switch upload.State {
+	case multipartCompleted:
+		// retry of completing, responds the saved location
+		c.RespondJSON(access.MultipartCompleteResp{Location: upload.Location})
+		span.Infof("done /multipart/complete request again upload:%s", upload.UploadID)
+		return
+	case multipartAborted:
+		c.RespondError(errcode.ErrAccessMultipartNoSuch)
+		return
+	}
+
+	for n := 1; n <= upload.partCount(); n++ {
+		if _, ok := upload.parts[n]; !ok {
+			span.Infof("missing part(%d) of upload(%s)", n, upload.UploadID)
+			c.RespondError(errcode.ErrAccessMultipartParts)
+			return
+		}
+	}
+
+	location := upload.Location.Copy()
+	if err := fillCrc(&location); err != nil {
+		span.Error("multipart complete fill location crc", err)
+		c.RespondError(httpError(err))
+		return
+	}
+
+	upload.Location = location
+	upload.State = multipartCompleted
+	updated, err := s.multipart.Update(ctx, upload)
+	if err != nil {
+		span.Error("update multipart upload failed", err)
+		c.RespondError(httpError(err))
+		return
+	}
+	if !updated {
+		span.Infof("upload(%s) was modified by others", upload.UploadID)
+		if saved, err := s.multipart.Get(ctx, upload.UploadID); err == nil && saved.State == multipartCompleted {
+			c.RespondJSON(access.MultipartCompleteResp{Location: saved.Location})
+			return
+		}
+		c.RespondError(errcode.ErrAccessMultipartNoSuch)
+		return
+	}
+
+	s.accountTenant(location.Tenant, int64(location.RawSize()))
+	s.expireTenant(ctx, &location, upload.ExpireAt)
+	c.RespondJSON(access.MultipartCompleteResp{Location: location})
+	span.Infof("done /multipart/complete request upload:%s location:%+v", upload.UploadID, location)
+}
+
+// MultipartAbort abort the multipart upload and delete all blobs
+func (s *Service) MultipartAbort(c *rpc.Context) {
+	args := new(access.MultipartUploadArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Debugf("accept /multipart/abort request args:%+v", args)
+	if !args.IsValid() {
+		c.RespondError(errcode.ErrIllegalArguments)
+		return
+	}
+
+	upload, err := s.multipart.Get(ctx, args.UploadID)
+	if err != nil {
+		span.Info("get multipart upload failed", args.UploadID, err)
+		c.RespondError(httpError(err))
+		return
+	}
+	if upload.State == multipartCompleted {
+		c.RespondError(errcode.ErrAccessMultipartNoSuch)
+		return
+	}
+
+	if err := s.reclaimMultipart(ctx, upload); err != nil {
+		span.Error("abort multipart upload failed", errors.Detail(err))
+		c.RespondError(httpError(err))
+		return
+	}
+
+	c.Respond()
+	span.Infof("done /multipart/abort request upload:%s", upload.UploadID)
+}
+
+// reclaimMultipart marks the upload aborted, deletes blobs through mqproxy then removes the upload,
+// the aborted upload is reclaimed again next round if failed to delete.
+func (s *Service) reclaimMultipart(ctx context.Context, upload *multipartUpload) error {
+	span := trace.SpanFromContextSafe(ctx)
+
+	if upload.State == multipartCompleted {
+		return errcode.ErrAccessMultipartNoSuch
+	}
+	if upload.State != multipartAborted {
+		upload.State = multipartAborted
+		updated, err := s.multipart.Update(ctx, upload)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return errcode.ErrAccessMultipartNoSuch
+		}
+	}
+
+	if err := s.streamHandler.Delete(ctx, &upload.Location); err != nil {
+		span.Warnf("delete location of upload(%s) failed, reclaim next round %s", upload.UploadID, err.Error())
+		return err
+	}
+	return s.multipart.Remove(ctx, upload.UploadID)
+}
+
+func (s *Service) loopReclaimMultipart() {
+	go func() {
+		cfg := s.config.Multipart
+		ticker := time.NewTicker(time.Duration(cfg.ReclaimIntervalS) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+
+			span, ctx := trace.StartSpanFromContext(context.Background(), "")
+			uploads, err := s.multipart.List(ctx)
+			if err != nil {
+				span.Warn("list multipart uploads failed", err)
+				continue
+			}
+
+			expired := time.Now().Add(-time.Duration(cfg.ExpireHours) * time.Hour).Unix()
+			for _, upload := range uploads {
+				if upload.State != multipartAborted && upload.CreateTime > expired {
+					continue
+				}
+				// blobs of completed upload belong to the object
+				if upload.State == multipartCompleted {
+					if err := s.multipart.Remove(ctx, upload.UploadID); err != nil {
+						span.Warnf("remove completed upload(%s) failed %s", upload.UploadID, err.Error())
+					}
+					continue
+				}
+				if err := s.reclaimMultipart(ctx, upload); err != nil {
+					span.Warnf("reclaim upload(%s) failed %s", upload.UploadID, err.Error())
+					continue
+				}
+				span.Infof("reclaimed expired upload(%s) location:%+v", upload.UploadID, upload.Location)
+			}
+		}
+	}()
+}
@@ -0,0 +1,458 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/api/access"
+	errcode "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/rpc"
+)
+
+type memMultipartStore struct {
+	mu      sync.Mutex
+	index   uint64
+	uploads map[string]*multipartUpload
+}
+
+func newMemMultipartStore() *memMultipartStore {
+	return &memMultipartStore{uploads: make(map[string]*multipartUpload)}
+}
+
+func (s *memMultipartStore) Create(ctx context.Context, upload *multipartUpload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index++
+	saved := *upload
+	saved.parts = make(map[int]access.MultipartPart)
+	saved.modifyIndex = s.index
+	s.uploads[upload.UploadID] = &saved
+	return nil
+}
+
+func (s *memMultipartStore) Get(ctx context.Context, uploadID string) (*multipartUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved, ok := s.uploads[uploadID]
+	if !ok {
+		return nil, errcode.ErrAccessMultipartNoSuch
+	}
+	upload := *saved
+	upload.parts = make(map[int]access.MultipartPart, len(saved.parts))
+	for n, part := range saved.parts {
+		upload.parts[n] = part
+	}
+	return &upload, nil
+}
+
+func (s *memMultipartStore) SetPart(ctx context.Context, uploadID string, part access.MultipartPart) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if saved, ok := s.uploads[uploadID]; ok {
+		saved.parts[part.PartNumber] = part
+	}
+	return nil
+}
+
+func (s *memMultipartStore) RemovePart(ctx context.Context, uploadID string, partNumber int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if saved, ok := s.uploads[uploadID]; ok {
+		delete(saved.parts, partNumber)
+	}
+	return nil
+}
+
+func (s *memMultipartStore) Update(ctx context.Context, upload *multipartUpload) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved, ok := s.uploads[upload.UploadID]
+	if !ok || saved.modifyIndex != upload.modifyIndex {
+		return false, nil
+	}
+	s.index++
+	updated := *upload
+	updated.parts = saved.parts
+	updated.modifyIndex = s.index
+	s.uploads[upload.UploadID] = &updated
+	return true, nil
+}
+
+func (s *memMultipartStore) Remove(ctx context.Context, uploadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, uploadID)
+	return nil
+}
+
+func (s *memMultipartStore) List(ctx context.Context) ([]*multipartUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uploads := make([]*multipartUpload, 0, len(s.uploads))
+	for _, saved := range s.uploads {
+		upload := *saved
+		uploads = append(uploads, &upload)
+	}
+	return uploads, nil
+}
+
+func TestAccessServiceMultipartUpload(t *testing.T) {
+	host := runMockService(newService())
+	cli := newClient()
+
+	putPart := func(uploadID string, partNumber int, buf []byte) (*access.MultipartPutResp, error) {
+		urlStr := fmt.Sprintf("%s/multipart/put?upload_id=%s&part_number=%d&size=%d&hashes=%d",
+			host, uploadID, partNumber, len(buf), access.HashAlgCRC32)
+		req, _ := http.NewRequest(http.MethodPut, urlStr, bytes.NewReader(buf))
+		resp := &access.MultipartPutResp{}
+		err := cli.DoWith(ctx, req, resp, rpc.WithCrcEncode())
+		return resp, err
+	}
+
+	{
+		resp := &access.MultipartInitResp{}
+		err := cli.PostWith(ctx, host+"/multipart/init", resp, access.MultipartInitArgs{})
+		assertErrorCode(t, 400, err)
+	}
+
+	// 11 blobs in location, 3 blobs in one part
+	size := uint64(_blobSize)*11 - 1024
+	init := &access.MultipartInitResp{}
+	err := cli.PostWith(ctx, host+"/multipart/init", init, access.MultipartInitArgs{
+		Size:     size,
+		PartSize: uint64(_blobSize)*3 - 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(_blobSize)*3, init.PartSize)
+	require.Equal(t, 4, init.PartCount)
+
+	list := &access.MultipartListResp{}
+	err = cli.GetWith(ctx, fmt.Sprintf("%s/multipart/list?upload_id=%s", host, init.UploadID), list)
+	require.NoError(t, err)
+	require.Equal(t, size, list.Size)
+	require.Equal(t, 0, len(list.Parts))
+
+	_, err = putPart("no-such-upload", 1, make([]byte, init.PartSize))
+	assertErrorCode(t, errcode.CodeAccessMultipartNoSuch, err)
+	_, err = putPart(init.UploadID, 5, make([]byte, init.PartSize))
+	assertErrorCode(t, 400, err)
+	_, err = putPart(init.UploadID, 1, make([]byte, init.PartSize-1))
+	assertErrorCode(t, 400, err)
+
+	for n := 1; n <= 3; n++ {
+		buf := make([]byte, init.PartSize)
+		rand.Read(buf)
+		resp, err := putPart(init.UploadID, n, buf)
+		require.NoError(t, err)
+		// mocked putat does not read body
+		_, ok := resp.HashSumMap.GetSum(access.HashAlgCRC32)
+		require.True(t, ok)
+	}
+
+	complete := &access.MultipartCompleteResp{}
+	err = cli.PostWith(ctx, host+"/multipart/complete", complete,
+		access.MultipartUploadArgs{UploadID: init.UploadID})
+	assertErrorCode(t, errcode.CodeAccessMultipartParts, err)
+
+	_, err = putPart(init.UploadID, 4, make([]byte, size-3*init.PartSize))
+	require.NoError(t, err)
+
+	err = cli.GetWith(ctx, fmt.Sprintf("%s/multipart/list?upload_id=%s", host, init.UploadID), list)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(list.Parts))
+	for idx, part := range list.Parts {
+		require.Equal(t, idx+1, part.PartNumber)
+	}
+
+	err = cli.PostWith(ctx, host+"/multipart/complete", complete,
+		access.MultipartUploadArgs{UploadID: init.UploadID})
+	require.NoError(t, err)
+	require.Equal(t, size, complete.Location.Size)
+	require.True(t, verifyCrc(&complete.Location))
+
+	// retry of completing gets the same location
+	retry := &access.MultipartCompleteResp{}
+	err = cli.PostWith(ctx, host+"/multipart/complete", retry,
+		access.MultipartUploadArgs{UploadID: init.UploadID})
+	require.NoError(t, err)
+	require.Equal(t, complete.Location, retry.Location)
+
+	// completed upload can not be modified or aborted
+	_, err = putPart(init.UploadID, 1, make([]byte, init.PartSize))
+	assertErrorCode(t, errcode.CodeAccessMultipartNoSuch, err)
+	err = cli.GetWith(ctx, fmt.Sprintf("%s/multipart/list?upload_id=%s", host, init.UploadID), list)
+	assertErrorCode(t, errcode.CodeAccessMultipartNoSuch, err)
+	err = cli.PostWith(ctx, host+"/multipart/abort", nil, access.MultipartUploadArgs{UploadID: init.UploadID})
+	assertErrorCode(t, errcode.CodeAccessMultipartNoSuch, err)
+
+	// abort
+	err = cli.PostWith(ctx, host+"/multipart/init", init, access.MultipartInitArgs{Size: size})
+	require.NoError(t, err)
+	require.Equal(t, uint64(_blobSize)*4, init.PartSize)
+	require.Equal(t, 3, init.PartCount)
+	err = cli.PostWith(ctx, host+"/multipart/abort", nil, access.MultipartUploadArgs{UploadID: init.UploadID})
+	require.NoError(t, err)
+	err = cli.PostWith(ctx, host+"/multipart/abort", nil, access.MultipartUploadArgs{UploadID: init.UploadID})
+	assertErrorCode(t, errcode.CodeAccessMultipartNoSuch, err)
+}
+
+func TestAccessServiceMultipartReclaim(t *testing.T) {
+	s := newService()
+	store := s.multipart.(*memMultipartStore)
+
+	upload := &multipartUpload{
+		UploadID: "reclaim-failed",
+		Location: access.Location{ClusterID: 10, BlobSize: 1, Size: 1,
+			Blobs: []access.SliceInfo{{MinBid: 1, Vid: 1, Count: 1}}},
+		PartSize: 1,
+	}
+	require.NoError(t, store.Create(ctx, upload))
+	upload, _ = store.Get(ctx, upload.UploadID)
+	require.Error(t, s.reclaimMultipart(ctx, upload))
+	// kept as aborted to reclaim next round
+	uploads, _ := store.List(ctx)
+	require.Equal(t, 1, len(uploads))
+	require.Equal(t, multipartAborted, uploads[0].State)
+
+	upload = uploads[0]
+	upload.Location.ClusterID = 1
+	require.NoError(t, s.reclaimMultipart(ctx, upload))
+	uploads, _ = store.List(ctx)
+	require.Equal(t, 0, len(uploads))
+
+	// modified by others
+	upload = &multipartUpload{UploadID: "reclaim-modified", Location: upload.Location, PartSize: 1}
+	require.NoError(t, store.Create(ctx, upload))
+	upload, _ = store.Get(ctx, upload.UploadID)
+	stale := *upload
+	require.NoError(t, s.reclaimMultipart(ctx, upload))
+	require.NoError(t, store.Create(ctx, &stale))
+	require.ErrorIs(t, s.reclaimMultipart(ctx, &stale), errcode.ErrAccessMultipartNoSuch)
+
+	// blobs of completed upload are not deleted
+	upload, _ = store.Get(ctx, stale.UploadID)
+	upload.State = multipartCompleted
+	ok, err := store.Update(ctx, upload)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.ErrorIs(t, s.reclaimMultipart(ctx, upload), errcode.ErrAccessMultipartNoSuch)
+}
+
+type encryptStreamHandler struct {
+	StreamHandler
+	mu      sync.Mutex
+	offsets []uint64
+}
+
+func (h *encryptStreamHandler) NewEncryption() (*access.Encryption, error) {
+	return &access.Encryption{KeyID: 1, WrappedKey: []byte("wrapped"), IV: make([]byte, 16)}, nil
+}
+
+func (h *encryptStreamHandler) EncryptAt(encryption *access.Encryption, offset uint64, rc io.Reader) (io.Reader, error) {
+	if encryption == nil {
+		return nil, errors.New("no encryption of location")
+	}
+	h.mu.Lock()
+	h.offsets = append(h.offsets, offset)
+	h.mu.Unlock()
+	return rc, nil
+}
+
+func TestAccessServiceEncryption(t *testing.T) {
+	s := newService()
+	handler := &encryptStreamHandler{StreamHandler: s.streamHandler}
+	s.streamHandler = handler
+	s.config.Stream.Encryption.Enable = true
+
+	rpc.RegisterArgsParser(&access.PutAtArgs{}, "json")
+	rpc.RegisterArgsParser(&access.MultipartPutArgs{}, "json")
+	router := rpc.New()
+	router.Handle(http.MethodPost, "/alloc", s.Alloc, rpc.OptArgsBody())
+	router.Handle(http.MethodPut, "/putat", s.PutAt, rpc.OptArgsQuery())
+	router.Handle(http.MethodPost, "/multipart/init", s.MultipartInit, rpc.OptArgsBody())
+	router.Handle(http.MethodPut, "/multipart/put", s.MultipartPut, rpc.OptArgsQuery())
+	router.Handle(http.MethodPost, "/multipart/complete", s.MultipartComplete, rpc.OptArgsBody())
+	server := httptest.NewServer(router)
+	defer server.Close()
+	cli := newClient()
+
+	// alloc and putat can not carry data key of location
+	err := cli.PostWith(ctx, server.URL+"/alloc", &access.AllocResp{}, access.AllocArgs{Size: 1 << 20})
+	assertErrorCode(t, errcode.CodeAccessEncrypted, err)
+	req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/putat?clusterid=1&volumeid=1111&blobid=111&size=1024&token=x",
+		server.URL), bytes.NewReader(make([]byte, 1024)))
+	err = cli.DoWith(ctx, req, nil)
+	assertErrorCode(t, errcode.CodeAccessEncrypted, err)
+
+	size := uint64(_blobSize)*11 - 1024
+	init := &access.MultipartInitResp{}
+	require.NoError(t, cli.PostWith(ctx, server.URL+"/multipart/init", init, access.MultipartInitArgs{Size: size}))
+	for n := 1; n <= init.PartCount; n++ {
+		partSize := init.PartSize
+		if n == init.PartCount {
+			partSize = size - uint64(n-1)*init.PartSize
+		}
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/multipart/put?upload_id=%s&part_number=%d&size=%d",
+			server.URL, init.UploadID, n, partSize), bytes.NewReader(make([]byte, partSize)))
+		require.NoError(t, cli.DoWith(ctx, req, &access.MultipartPutResp{}))
+	}
+	require.Equal(t, []uint64{0, init.PartSize, 2 * init.PartSize}, handler.offsets)
+
+	complete := &access.MultipartCompleteResp{}
+	require.NoError(t, cli.PostWith(ctx, server.URL+"/multipart/complete", complete,
+		access.MultipartUploadArgs{UploadID: init.UploadID}))
+	require.Equal(t, access.LocationVersion2, complete.Location.Version)
+	require.NotNil(t, complete.Location.Encryption)
+	require.Equal(t, uint32(1), complete.Location.Encryption.KeyID)
+}
+
+type expireStreamHandler struct {
+	StreamHandler
+	mu      sync.Mutex
+	expires []int64
+}
+
+func (h *expireStreamHandler) PutAt(ctx context.Context, rc io.Reader,
+	clusterID proto.ClusterID, vid proto.Vid, bid proto.BlobID, size int64,
+	hasherMap access.HasherMap, opts PutOptions) error {
+	h.mu.Lock()
+	h.expires = append(h.expires, opts.ExpireAt)
+	h.mu.Unlock()
+	return h.StreamHandler.PutAt(ctx, rc, clusterID, vid, bid, size, hasherMap, opts)
+}
+
+func TestAccessServiceMultipartExpire(t *testing.T) {
+	s := newService()
+	handler := &expireStreamHandler{StreamHandler: s.streamHandler}
+	s.streamHandler = handler
+
+	rpc.RegisterArgsParser(&access.MultipartPutArgs{}, "json")
+	router := rpc.New()
+	router.Handle(http.MethodPost, "/multipart/init", s.MultipartInit, rpc.OptArgsBody())
+	router.Handle(http.MethodPut, "/multipart/put", s.MultipartPut, rpc.OptArgsQuery())
+	server := httptest.NewServer(router)
+	defer server.Close()
+	cli := newClient()
+
+	init := &access.MultipartInitResp{}
+	err := cli.PostWith(ctx, server.URL+"/multipart/init", init,
+		access.MultipartInitArgs{Size: uint64(_blobSize) * 2, ExpireAt: -1})
+	assertErrorCode(t, 400, err)
+
+	expireAt := time.Now().Unix() + 3600
+	require.NoError(t, cli.PostWith(ctx, server.URL+"/multipart/init", init,
+		access.MultipartInitArgs{Size: uint64(_blobSize) * 2, PartSize: uint64(_blobSize), ExpireAt: expireAt}))
+	for n := 1; n <= init.PartCount; n++ {
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/multipart/put?upload_id=%s&part_number=%d&size=%d",
+			server.URL, init.UploadID, n, init.PartSize), bytes.NewReader(make([]byte, init.PartSize)))
+		require.NoError(t, cli.DoWith(ctx, req, &access.MultipartPutResp{}))
+	}
+	require.Equal(t, []int64{expireAt, expireAt}, handler.expires)
+}
+
+// finishingMultipartStore finishes the upload before saving part
+type finishingMultipartStore struct {
+	*memMultipartStore
+	finish       func(upload *multipartUpload)
+	removedParts []int
+}
+
+func (s *finishingMultipartStore) SetPart(ctx context.Context, uploadID string, part access.MultipartPart) error {
+	upload, err := s.memMultipartStore.Get(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	s.finish(upload)
+	return s.memMultipartStore.SetPart(ctx, uploadID, part)
+}
+
+func (s *finishingMultipartStore) RemovePart(ctx context.Context, uploadID string, partNumber int) error {
+	s.removedParts = append(s.removedParts, partNumber)
+	return s.memMultipartStore.RemovePart(ctx, uploadID, partNumber)
+}
+
+type deleteStreamHandler struct {
+	StreamHandler
+	deleted []access.Blob
+}
+
+func (h *deleteStreamHandler) Delete(ctx context.Context, location *access.Location) error {
+	h.deleted = append(h.deleted, location.Spread()...)
+	return nil
+}
+
+func TestAccessServiceMultipartPutFinished(t *testing.T) {
+	s := newService()
+	handler := &deleteStreamHandler{StreamHandler: s.streamHandler}
+	s.streamHandler = handler
+	store := &finishingMultipartStore{memMultipartStore: newMemMultipartStore()}
+	s.multipart = store
+	s.config.Multipart.ExpireHours = 1
+
+	rpc.RegisterArgsParser(&access.MultipartPutArgs{}, "json")
+	router := rpc.New()
+	router.Handle(http.MethodPost, "/multipart/init", s.MultipartInit, rpc.OptArgsBody())
+	router.Handle(http.MethodPut, "/multipart/put", s.MultipartPut, rpc.OptArgsQuery())
+	server := httptest.NewServer(router)
+	defer server.Close()
+	cli := newClient()
+
+	putPart := func(finish func(upload *multipartUpload)) error {
+		init := &access.MultipartInitResp{}
+		require.NoError(t, cli.PostWith(ctx, server.URL+"/multipart/init", init,
+			access.MultipartInitArgs{Size: uint64(_blobSize) * 4, PartSize: uint64(_blobSize) * 2}))
+		store.finish = finish
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/multipart/put?upload_id=%s&part_number=2&size=%d",
+			server.URL, init.UploadID, init.PartSize), bytes.NewReader(make([]byte, init.PartSize)))
+		return cli.DoWith(ctx, req, &access.MultipartPutResp{})
+	}
+
+	// still uploading
+	require.NoError(t, putPart(func(*multipartUpload) {}))
+	require.Equal(t, 0, len(store.removedParts))
+
+	// aborted and reclaimed, blobs of the part are deleted
+	err := putPart(func(upload *multipartUpload) {
+		store.Remove(ctx, upload.UploadID)
+	})
+	assertErrorCode(t, errcode.CodeAccessMultipartNoSuch, err)
+	require.Equal(t, []int{2}, store.removedParts)
+	require.Equal(t, 2, len(handler.deleted))
+	require.Equal(t, location.Spread()[2:4], handler.deleted)
+
+	// completed, blobs belong to the object
+	handler.deleted = nil
+	err = putPart(func(upload *multipartUpload) {
+		upload.State = multipartCompleted
+		store.Update(ctx, upload)
+	})
+	assertErrorCode(t, errcode.CodeAccessMultipartNoSuch, err)
+	require.Equal(t, []int{2, 2}, store.removedParts)
+	require.Equal(t, 0, len(handler.deleted))
+}
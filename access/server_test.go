@@ -21,6 +21,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -81,18 +84,25 @@ func newService() *Service {
 		})
 
 	s.EXPECT().PutAt(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
-		gomock.Any()).AnyTimes().DoAndReturn(
+		gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
 		func(ctx context.Context, rc io.Reader,
 			clusterID proto.ClusterID, vid proto.Vid, bid proto.BlobID, size int64,
-			hasherMap access.HasherMap) error {
+			hasherMap access.HasherMap, opts PutOptions) error {
 			if size < 1024 {
 				return errcode.ErrAccessLimited
 			}
 			return nil
 		})
 
-	s.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
-		func(ctx context.Context, rc io.Reader, size int64, hasherMap access.HasherMap) (*access.Location, error) {
+	s.EXPECT().NewEncryption().AnyTimes().Return(nil, nil)
+	s.EXPECT().EncryptAt(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(encryption *access.Encryption, offset uint64, rc io.Reader) (io.Reader, error) {
+			return rc, nil
+		})
+
+	s.EXPECT().Put(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(ctx context.Context, rc io.Reader, size int64, hasherMap access.HasherMap,
+			opts PutOptions) (*access.Location, error) {
 			if size < 1024 {
 				return nil, errors.New("fake put nil body")
 			}
@@ -107,7 +117,14 @@ func newService() *Service {
 			if readSize < 1024 {
 				return nil, errors.New("fake get nil body")
 			}
-			return func() error { return nil }, nil
+			return func() error {
+				buf := make([]byte, readSize)
+				for idx := range buf {
+					buf[idx] = byte(offset + uint64(idx))
+				}
+				_, err := w.Write(buf)
+				return err
+			}, nil
 		})
 	s.EXPECT().Delete(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
 		func(ctx context.Context, location *access.Location) error {
@@ -120,7 +137,11 @@ func newService() *Service {
 		})
 
 	return &Service{
+		config: Config{
+			Multipart: MultipartConfig{DefaultPartBlobs: 4},
+		},
 		streamHandler: s,
+		multipart:     newMemMultipartStore(),
 		limiter: NewLimiter(LimitConfig{
 			NameRps: map[string]int{
 				limitNameAlloc: 2,
@@ -330,6 +351,66 @@ func TestAccessServiceGet(t *testing.T) {
 	}
 }
 
+func TestAccessServiceGetRanges(t *testing.T) {
+	host := runMockService(newService())
+	cli := newClient()
+
+	url := fmt.Sprintf("%s/get/ranges", host)
+	args := access.GetRangesArgs{Location: location.Copy()}
+	args.Location.Size = 10240
+	fillCrc(&args.Location)
+	{
+		resp, err := cli.Post(ctx, url, args)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, 400, resp.StatusCode, resp.Status)
+	}
+	{
+		args.Ranges = []access.Range{{Offset: 0, ReadSize: 10}}
+		resp, err := cli.Post(ctx, url, args)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, 500, resp.StatusCode, resp.Status)
+	}
+	{
+		args.Ranges = []access.Range{
+			{Offset: 2000, ReadSize: 100},
+			{Offset: 0, ReadSize: 1000},
+			{Offset: 4096, ReadSize: 2048},
+		}
+		resp, err := cli.Post(ctx, url, args)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, 206, resp.StatusCode, resp.Status)
+
+		_, params, err := mime.ParseMediaType(resp.Header.Get(rpc.HeaderContentType))
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, resp.Header.Get(rpc.HeaderContentLength), strconv.Itoa(len(body)))
+
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for _, r := range []access.Range{
+			{Offset: 0, ReadSize: 1000},
+			{Offset: 2000, ReadSize: 100},
+			{Offset: 4096, ReadSize: 2048},
+		} {
+			part, err := reader.NextPart()
+			require.NoError(t, err)
+			require.Equal(t, fmt.Sprintf("bytes %d-%d/10240", r.Offset, r.Offset+r.ReadSize-1),
+				part.Header.Get(rpc.HeaderContentRange))
+			data, err := ioutil.ReadAll(part)
+			require.NoError(t, err)
+			require.Equal(t, int(r.ReadSize), len(data))
+			for idx := range data {
+				require.Equal(t, byte(r.Offset+uint64(idx)), data[idx])
+			}
+		}
+		_, err = reader.NextPart()
+		require.Equal(t, io.EOF, err)
+	}
+}
+
 func TestAccessServiceDelete(t *testing.T) {
 	host := runMockService(newService())
 	cli := newClient()
@@ -61,6 +61,8 @@ func NewHandler(service *Service) *rpc.Router {
 	rpc.RegisterArgsParser(&access.PutArgs{}, "json")
 	rpc.RegisterArgsParser(&access.PutAtArgs{}, "json")
 	rpc.RegisterArgsParser(&access.DeleteBlobArgs{}, "json")
+	rpc.RegisterArgsParser(&access.MultipartPutArgs{}, "json")
+	rpc.RegisterArgsParser(&access.MultipartUploadArgs{}, "json")
 
 	rpc.Use(service.Limit)
 
@@ -88,6 +90,11 @@ func NewHandler(service *Service) *rpc.Router {
 	// response body:  DataStream
 	rpc.POST("/get", service.Get, rpc.OptArgsBody())
 
+	// POST /get/ranges
+	// request  body:  json
+	// response body:  multipart/byteranges
+	rpc.POST("/get/ranges", service.GetRanges, rpc.OptArgsBody())
+
 	// POST /delete
 	// request  body:  json
 	// response body:  json
@@ -100,5 +107,24 @@ func NewHandler(service *Service) *rpc.Router {
 	// response body:  json
 	rpc.POST("/sign", service.Sign, rpc.OptArgsBody())
 
+	// POST /multipart/init
+	// request  body:  json
+	// response body:  json
+	rpc.POST("/multipart/init", service.MultipartInit, rpc.OptArgsBody())
+	// PUT /multipart/put?upload_id={upload_id}&part_number={part_number}&size={size}&hashes={hashes}
+	// request  body:  DataStream
+	// response body:  json
+	rpc.PUT("/multipart/put", service.MultipartPut, rpc.OptArgsQuery())
+	// GET /multipart/list?upload_id={upload_id}
+	// response body:  json
+	rpc.GET("/multipart/list", service.MultipartList, rpc.OptArgsQuery())
+	// POST /multipart/complete
+	// request  body:  json
+	// response body:  json
+	rpc.POST("/multipart/complete", service.MultipartComplete, rpc.OptArgsBody())
+	// POST /multipart/abort
+	// request  body:  json
+	rpc.POST("/multipart/abort", service.MultipartAbort, rpc.OptArgsBody())
+
 	return rpc.DefaultRouter
 }
@@ -52,6 +52,14 @@ const (
 	serviceMQProxy   = proto.ServiceNameMQProxy
 )
 
+// PutOptions optional arguments of putting data
+type PutOptions struct {
+	// Codec to compress data, CompressNone follows compression config, ignored by PutAt
+	Codec access.CompressCodec
+	// ExpireAt unix seconds the data expires at, 0 means never expire
+	ExpireAt int64
+}
+
 // StreamHandler stream http handler
 type StreamHandler interface {
 	// Alloc access interface /alloc
@@ -68,13 +76,25 @@ type StreamHandler interface {
 	//     required: clusterID VolumeID BlobID
 	//     required: size, one blob size
 	//     optional: hasherMap, computing hash
+	//     optional: opts, expiry of the blob
 	PutAt(ctx context.Context, rc io.Reader,
-		clusterID proto.ClusterID, vid proto.Vid, bid proto.BlobID, size int64, hasherMap access.HasherMap) error
+		clusterID proto.ClusterID, vid proto.Vid, bid proto.BlobID, size int64,
+		hasherMap access.HasherMap, opts PutOptions) error
+
+	// NewEncryption returns encryption of a new location, nil if encryption disabled
+	NewEncryption() (*access.Encryption, error)
+
+	// EncryptAt returns reader of stored data encrypted from offset of the location
+	//     required: encryption of the location, rc is returned if it is nil
+	//     required: offset, offset of rc in the location
+	EncryptAt(encryption *access.Encryption, offset uint64, rc io.Reader) (io.Reader, error)
 
 	// Put put one object
 	//     required: size, file size
 	//     optional: hasher map to calculate hash.Hash
-	Put(ctx context.Context, rc io.Reader, size int64, hasherMap access.HasherMap) (*access.Location, error)
+	//     optional: opts, compression codec and expiry of the object
+	Put(ctx context.Context, rc io.Reader, size int64,
+		hasherMap access.HasherMap, opts PutOptions) (*access.Location, error)
 
 	// Get read file
 	//     required: location, readSize
@@ -128,6 +148,20 @@ type StreamConfig struct {
 	MinReadShardsX             int    `json:"min_read_shards_x"`
 	ShardCrcDisabled           bool   `json:"shard_crc_disabled"`
 
+	// LocationVersion version of location encoding on put,
+	// location of version 2 carries crc of every blob to verify content on get.
+	LocationVersion uint8 `json:"location_version"`
+	// LocationHashSum carries hash summary of put in location of version 2
+	LocationHashSum bool `json:"location_hash_sum"`
+	// Compression compresses data before ec encoding, location of version 2
+	// carries block index, get decompresses the blocks transparently.
+	Compression CompressionConfig `json:"compression"`
+	// Encryption encrypts stored data with a data key of every put,
+	// location of version 2 carries data key wrapped by master key.
+	Encryption EncryptionConfig `json:"encryption"`
+	// HedgeRead reads extra shards if some shards are slow
+	HedgeRead HedgeReadConfig `json:"hedge_read"`
+
 	MemPoolSizeClasses map[int]int `json:"mem_pool_size_classes"`
 
 	// CodeModesPutQuorums
@@ -174,6 +208,11 @@ type Handler struct {
 	allCodeModes  CodeModePairs
 	maxObjectSize int64
 
+	// keyProvider is not nil if encryption enabled
+	keyProvider KeyProvider
+	// hedgeLatency is not nil if hedge read enabled
+	hedgeLatency *shardLatency
+
 	discardVidChan chan discardVid
 	stopCh         <-chan struct{}
 
@@ -197,6 +236,14 @@ func confCheck(cfg *StreamConfig) {
 		}
 	}
 
+	if cfg.LocationVersion > access.LocationVersion2 {
+		log.Fatalf("invalid location version %d", cfg.LocationVersion)
+	}
+	if _, err := access.ParseCompressCodec(cfg.Compression.Codec); err != nil {
+		log.Fatal(err)
+	}
+	defaulter.Equal(&cfg.Compression.BlockSize, defaultCompressBlockSize)
+
 	defaulter.Equal(&cfg.MaxBlobSize, defaultMaxBlobSize)
 	defaulter.LessOrEqual(&cfg.DiskPunishIntervalS, defaultDiskPunishIntervalS)
 	defaulter.LessOrEqual(&cfg.DiskTimeoutPunishIntervalS, defaultDiskPunishIntervalS/10)
@@ -207,6 +254,13 @@ func confCheck(cfg *StreamConfig) {
 	}
 	defaulter.LessOrEqual(&cfg.EncoderConcurrency, defaultEncoderConcurrency)
 	defaulter.LessOrEqual(&cfg.MinReadShardsX, defaultMinReadShardsX)
+	defaulter.LessOrEqual(&cfg.HedgeRead.Percentile, defaultHedgePercentile)
+	defaulter.LessOrEqual(&cfg.HedgeRead.MinDelayMS, defaultHedgeMinDelayMS)
+	defaulter.LessOrEqual(&cfg.HedgeRead.MaxShards, defaultHedgeMaxShards)
+	defaulter.LessOrEqual(&cfg.HedgeRead.Samples, defaultHedgeSamples)
+	if cfg.HedgeRead.Percentile >= 1 {
+		log.Fatal("invalid hedge read percentile:", cfg.HedgeRead.Percentile)
+	}
 
 	defaulter.LessOrEqual(&cfg.ClusterConfig.CMClientConfig.Config.ClientTimeoutMs, defaultTimeoutClusterMgr)
 	defaulter.LessOrEqual(&cfg.AllocatorConfig.ClientTimeoutMs, defaultTimeoutAllocator)
@@ -251,6 +305,15 @@ func NewStreamHandler(cfg *StreamConfig, kvClient *api.Client, stopCh <-chan str
 		StreamConfig:  *cfg,
 	}
 
+	if cfg.Encryption.Enable {
+		if handler.keyProvider, err = NewKeyFileProvider(cfg.Encryption.KeyFile); err != nil {
+			log.Fatalf("load master keys failed, err: %v", err)
+		}
+	}
+	if cfg.HedgeRead.Enable {
+		handler.hedgeLatency = newShardLatency(cfg.HedgeRead)
+	}
+
 	rawCodeModePolicies, err := handler.clusterController.GetConfig(context.Background(), proto.CodeModeConfigKey)
 	if err != nil {
 		log.Fatal("get codemode policy from cluster manager failed, err: ", err)
@@ -464,6 +527,13 @@ func minU64(a, b uint64) uint64 {
 	return b
 }
 
+func maxU64(a, b uint64) uint64 {
+	if a >= b {
+		return a
+	}
+	return b
+}
+
 func errorTimeout(err error) bool {
 	msg := err.Error()
 	return strings.Contains(msg, "Timeout") || strings.Contains(msg, "timeout")
@@ -0,0 +1,267 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/DataDog/zstd"
+	"github.com/golang/snappy"
+
+	"github.com/cubefs/blobstore/api/access"
+	errcode "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+// CompressionConfig compression of put
+type CompressionConfig struct {
+	// Codec default codec of put, "snappy" or "zstd", empty means not compressed
+	Codec string `json:"codec"`
+	// BlockSize raw size of compression block,
+	// range read decompresses whole blocks it covers
+	BlockSize uint32 `json:"block_size"`
+	// MinSize object smaller than it is not compressed by default codec
+	MinSize int64 `json:"min_size"`
+}
+
+func compressBlock(codec access.CompressCodec, dst, src []byte) ([]byte, error) {
+	switch codec {
+	case access.CompressSnappy:
+		return snappy.Encode(dst[:cap(dst)], src), nil
+	case access.CompressZstd:
+		return zstd.Compress(dst, src)
+	default:
+		return nil, fmt.Errorf("unsupported compress codec %s", codec)
+	}
+}
+
+func decompressBlock(codec access.CompressCodec, dst, src []byte) ([]byte, error) {
+	switch codec {
+	case access.CompressSnappy:
+		return snappy.Decode(dst, src)
+	case access.CompressZstd:
+		return zstd.Decompress(dst, src)
+	default:
+		return nil, fmt.Errorf("unsupported compress codec %s", codec)
+	}
+}
+
+// compressor reads raw data and compresses it in blocks,
+// the block is stored uncompressed if compression does not save bytes,
+// so that stored size is never larger than raw size.
+type compressor struct {
+	rc          io.Reader
+	compression *access.Compression
+
+	raw     []byte
+	encoded []byte
+	pending []byte
+	err     error
+}
+
+func newCompressor(rc io.Reader, codec access.CompressCodec, blockSize uint32) *compressor {
+	return &compressor{
+		rc: rc,
+		compression: &access.Compression{
+			Codec:     codec,
+			BlockSize: blockSize,
+		},
+		raw: make([]byte, blockSize),
+	}
+}
+
+func (c *compressor) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+
+		n, err := io.ReadFull(c.rc, c.raw)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		c.err = err
+		if n == 0 {
+			continue
+		}
+
+		encoded, err := compressBlock(c.compression.Codec, c.encoded, c.raw[:n])
+		if err != nil {
+			c.err = err
+			continue
+		}
+		c.encoded = encoded[:0]
+
+		c.pending = encoded
+		if len(encoded) >= n {
+			c.pending = c.raw[:n]
+		}
+		c.compression.RawSize += uint64(n)
+		c.compression.Blocks = append(c.compression.Blocks, uint32(len(c.pending)))
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Compression returns block index of all compressed data
+func (c *compressor) Compression() *access.Compression {
+	return c.compression
+}
+
+// compressedRead is stored range of raw range in compressed location
+type compressedRead struct {
+	Offset   uint64
+	ReadSize uint64
+
+	codec  access.CompressCodec
+	blocks [][2]uint32 // raw size and stored size of blocks
+	skip   uint64      // raw bytes skipped of the first block
+	size   uint64      // raw bytes to write
+}
+
+// genCompressedRead plans stored range of the whole blocks covered by raw range
+func genCompressedRead(location *access.Location, readSize, offset uint64) (*compressedRead, error) {
+	compression := location.Compression
+	if !compression.IsValid(location.Size) {
+		return nil, fmt.Errorf("invalid compression of size %d", location.Size)
+	}
+	if offset+readSize > compression.RawSize {
+		return nil, fmt.Errorf("RawSize:%d ReadSize:%d Offset:%d", compression.RawSize, readSize, offset)
+	}
+
+	read := &compressedRead{codec: compression.Codec, size: readSize}
+	if readSize == 0 {
+		return read, nil
+	}
+
+	blockSize := uint64(compression.BlockSize)
+	first := int(offset / blockSize)
+	last := int((offset + readSize - 1) / blockSize)
+	for idx := 0; idx <= last; idx++ {
+		stored := uint64(compression.Blocks[idx])
+		if idx < first {
+			read.Offset += stored
+			continue
+		}
+		read.ReadSize += stored
+		read.blocks = append(read.blocks, [2]uint32{compression.BlockRawSize(idx), uint32(stored)})
+	}
+	read.skip = offset - uint64(first)*blockSize
+	return read, nil
+}
+
+// Writer returns writer decompresses stored blocks in order
+func (r *compressedRead) Writer(w io.Writer) *decompressWriter {
+	return &decompressWriter{w: w, codec: r.codec, blocks: r.blocks, skip: r.skip, left: r.size}
+}
+
+type decompressWriter struct {
+	w      io.Writer
+	codec  access.CompressCodec
+	blocks [][2]uint32
+
+	stored []byte
+	raw    []byte
+	skip   uint64
+	left   uint64
+}
+
+func (w *decompressWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		if len(w.blocks) == 0 {
+			break
+		}
+
+		rawSize, storedSize := int(w.blocks[0][0]), int(w.blocks[0][1])
+		n := storedSize - len(w.stored)
+		if n > len(p) {
+			n = len(p)
+		}
+		w.stored = append(w.stored, p[:n]...)
+		p = p[n:]
+		if len(w.stored) < storedSize {
+			break
+		}
+
+		data := w.stored
+		if storedSize < rawSize {
+			if cap(w.raw) < rawSize {
+				w.raw = make([]byte, rawSize)
+			}
+			raw, err := decompressBlock(w.codec, w.raw[:rawSize], w.stored)
+			if err != nil {
+				return 0, err
+			}
+			if len(raw) != rawSize {
+				return 0, fmt.Errorf("decompressed block size %d != %d", len(raw), rawSize)
+			}
+			data = raw
+		}
+
+		data = data[w.skip:]
+		w.skip = 0
+		if uint64(len(data)) > w.left {
+			data = data[:w.left]
+		}
+		if _, err := w.w.Write(data); err != nil {
+			return 0, err
+		}
+		w.left -= uint64(len(data))
+
+		w.stored = w.stored[:0]
+		w.blocks = w.blocks[1:]
+	}
+	return written, nil
+}
+
+// Finished returns error if not all raw bytes were written
+func (w *decompressWriter) Finished() error {
+	if w.left > 0 || len(w.blocks) > 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// getCompressed reads stored blocks covered by the raw range and decompresses them
+func (h *Handler) getCompressed(ctx context.Context, w io.Writer, location access.Location,
+	readSize, offset uint64) (func() error, error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	read, err := genCompressedRead(&location, readSize, offset)
+	if err != nil {
+		span.Info("illegal argument", err)
+		return func() error { return nil }, errcode.ErrIllegalArguments
+	}
+	span.Debugf("get compressed %s stored size:%d offset:%d", read.codec, read.ReadSize, read.Offset)
+
+	stored := location.Copy()
+	stored.Compression = nil
+	writer := read.Writer(w)
+	transfer, err := h.Get(ctx, writer, stored, read.ReadSize, read.Offset)
+	if err != nil {
+		return transfer, err
+	}
+	return func() error {
+		if err := transfer(); err != nil {
+			return err
+		}
+		return writer.Finished()
+	}, nil
+}
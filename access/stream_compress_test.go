@@ -0,0 +1,145 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/api/access"
+)
+
+func compressibleData(size int) []byte {
+	buff := bytes.NewBuffer(nil)
+	for idx := 0; buff.Len() < size; idx++ {
+		fmt.Fprintf(buff, `{"level":"info","id":%d,"msg":"request done"}`+"\n", idx)
+	}
+	return buff.Bytes()[:size]
+}
+
+func TestAccessStreamCompressBlocks(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.Read(random)
+	raw := append(compressibleData(10000), random...)
+
+	for _, codec := range []access.CompressCodec{access.CompressSnappy, access.CompressZstd} {
+		comp := newCompressor(bytes.NewReader(raw), codec, 1024)
+		stored, err := ioutil.ReadAll(comp)
+		require.NoError(t, err)
+
+		compression := comp.Compression()
+		require.Equal(t, uint64(len(raw)), compression.RawSize)
+		require.Equal(t, 14, len(compression.Blocks))
+		require.Less(t, len(stored), len(raw))
+		require.True(t, compression.IsValid(uint64(len(stored))))
+		// random data is stored uncompressed
+		last := len(compression.Blocks) - 1
+		require.Equal(t, compression.BlockRawSize(last), compression.Blocks[last])
+		require.Equal(t, uint32(len(raw)%1024), compression.Blocks[last])
+
+		loc := access.Location{Size: uint64(len(stored)), Compression: compression}
+		for _, cs := range []struct {
+			offset, readSize uint64
+		}{
+			{0, uint64(len(raw))},
+			{0, 0},
+			{0, 1},
+			{1000, 48},
+			{1023, 2},
+			{5000, 8000},
+			{uint64(len(raw)) - 1, 1},
+		} {
+			read, err := genCompressedRead(&loc, cs.readSize, cs.offset)
+			require.NoError(t, err)
+
+			buff := bytes.NewBuffer(nil)
+			w := read.Writer(buff)
+			_, err = w.Write(stored[read.Offset : read.Offset+read.ReadSize])
+			require.NoError(t, err)
+			require.NoError(t, w.Finished())
+			require.True(t, bytes.Equal(raw[cs.offset:cs.offset+cs.readSize], buff.Bytes()))
+		}
+
+		_, err = genCompressedRead(&loc, 1, uint64(len(raw)))
+		require.Error(t, err)
+		loc.Size--
+		_, err = genCompressedRead(&loc, 1, 0)
+		require.Error(t, err)
+	}
+}
+
+func TestAccessStreamCompressPutGet(t *testing.T) {
+	ctx := ctxWithName("TestAccessStreamCompressPutGet")
+	streamer.Compression.BlockSize = 1 << 16
+	defer func() {
+		streamer.Compression = CompressionConfig{}
+		dataShards.clean()
+	}()
+
+	size := (1 << 22) * 3
+	data := compressibleData(size)
+	for _, codec := range []access.CompressCodec{access.CompressSnappy, access.CompressZstd} {
+		dataShards.clean()
+		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(size), nil, PutOptions{Codec: codec})
+		require.NoError(t, err)
+		require.Equal(t, access.LocationVersion2, loc.Version)
+		require.NotNil(t, loc.Compression)
+		require.Equal(t, codec, loc.Compression.Codec)
+		require.Equal(t, uint64(size), loc.RawSize())
+		require.Less(t, loc.Size, uint64(size))
+		require.Equal(t, 1, len(loc.Spread()))
+
+		for _, cs := range []struct {
+			offset, readSize uint64
+		}{
+			{0, uint64(size)},
+			{0, 1},
+			{(1 << 16) - 10, 20},
+			{1 << 22, 1 << 20},
+			{uint64(size) - 100, 100},
+		} {
+			buff := bytes.NewBuffer(nil)
+			transfer, err := streamer.Get(ctx(), buff, *loc, cs.readSize, cs.offset)
+			require.NoError(t, err)
+			require.NoError(t, transfer())
+			require.True(t, dataEqual(data[cs.offset:cs.offset+cs.readSize], buff.Bytes()))
+		}
+
+		_, err = streamer.Get(ctx(), bytes.NewBuffer(nil), *loc, 1, uint64(size))
+		require.Error(t, err)
+	}
+
+	// compress with default codec of config
+	dataShards.clean()
+	streamer.Compression.Codec = "snappy"
+	streamer.Compression.MinSize = int64(size) + 1
+	loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(size), nil, PutOptions{})
+	require.NoError(t, err)
+	require.Nil(t, loc.Compression)
+
+	streamer.Compression.MinSize = 0
+	loc, err = streamer.Put(ctx(), bytes.NewReader(data), int64(size), nil, PutOptions{})
+	require.NoError(t, err)
+	require.Equal(t, access.CompressSnappy, loc.Compression.Codec)
+
+	// body is less than size
+	_, err = streamer.Put(ctx(), bytes.NewReader(data[:size-1]), int64(size), nil, PutOptions{})
+	require.Error(t, err)
+}
@@ -0,0 +1,252 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/cubefs/blobstore/api/access"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/util/errors"
+)
+
+const (
+	masterKeySize = 32 // AES-256
+	dataKeySize   = 32 // AES-256
+)
+
+var errEncryptionDisabled = errors.New("encryption is disabled")
+
+// EncryptionConfig encryption at rest of put
+type EncryptionConfig struct {
+	// Enable encrypts stored data of every put with a new data key
+	Enable bool `json:"enable"`
+	// KeyFile json file of master keys, data key is wrapped by the active key,
+	// the others are kept to unwrap data keys of old locations.
+	//   {"active": 2, "keys": {"1": "hex of 32 bytes", "2": "hex of 32 bytes"}}
+	KeyFile string `json:"key_file"`
+}
+
+// KeyProvider provides master keys to wrap data keys
+type KeyProvider interface {
+	// ActiveKey returns id and master key to wrap new data key
+	ActiveKey() (uint32, []byte, error)
+	// Key returns master key of the id
+	Key(id uint32) ([]byte, error)
+}
+
+type keyFileProvider struct {
+	Active uint32            `json:"active"`
+	Keys   map[uint32]string `json:"keys"`
+
+	keys map[uint32][]byte
+}
+
+// NewKeyFileProvider returns key provider loading master keys from local keyfile
+func NewKeyFileProvider(path string) (KeyProvider, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &keyFileProvider{}
+	if err = json.Unmarshal(data, p); err != nil {
+		return nil, errors.Info(err, "decode keyfile", path)
+	}
+
+	p.keys = make(map[uint32][]byte, len(p.Keys))
+	for id, val := range p.Keys {
+		key, err := hex.DecodeString(val)
+		if err != nil {
+			return nil, errors.Info(err, "decode master key", id)
+		}
+		if len(key) != masterKeySize {
+			return nil, fmt.Errorf("master key %d size %d != %d", id, len(key), masterKeySize)
+		}
+		p.keys[id] = key
+	}
+	if _, ok := p.keys[p.Active]; !ok {
+		return nil, fmt.Errorf("active master key %d not found", p.Active)
+	}
+	return p, nil
+}
+
+func (p *keyFileProvider) ActiveKey() (uint32, []byte, error) {
+	return p.Active, p.keys[p.Active], nil
+}
+
+func (p *keyFileProvider) Key(id uint32) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("master key %d not found", id)
+	}
+	return key, nil
+}
+
+func keyIDAdditional(id uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], id)
+	return b[:]
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newEncryption generates a new data key wrapped by the active master key,
+// returns cipher stream at offset zero of stored data.
+func newEncryption(provider KeyProvider) (*access.Encryption, cipher.Stream, error) {
+	keyID, masterKey, err := provider.ActiveKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newGCM(masterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	iv := make([]byte, aes.BlockSize)
+	nonce := make([]byte, aead.NonceSize())
+	for _, b := range [][]byte{dataKey, iv, nonce} {
+		if _, err = io.ReadFull(rand.Reader, b); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stream, err := newCTRStream(dataKey, iv, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &access.Encryption{
+		KeyID:      keyID,
+		WrappedKey: aead.Seal(nonce, nonce, dataKey, keyIDAdditional(keyID)),
+		IV:         iv,
+	}, stream, nil
+}
+
+// NewEncryption returns encryption of a new location, nil if encryption disabled
+func (h *Handler) NewEncryption() (*access.Encryption, error) {
+	if h.keyProvider == nil {
+		return nil, nil
+	}
+	encryption, _, err := newEncryption(h.keyProvider)
+	return encryption, err
+}
+
+// EncryptAt returns reader of stored data encrypted from offset of the location,
+// the location is encrypted by parts such as multipart upload.
+func (h *Handler) EncryptAt(encryption *access.Encryption, offset uint64, rc io.Reader) (io.Reader, error) {
+	if encryption == nil {
+		return rc, nil
+	}
+	if h.keyProvider == nil {
+		return nil, errEncryptionDisabled
+	}
+	dataKey, err := unwrapDataKey(h.keyProvider, encryption)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := newCTRStream(dataKey, encryption.IV, offset)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamReader{S: stream, R: rc}, nil
+}
+
+// unwrapDataKey opens data key with master key of the encryption
+func unwrapDataKey(provider KeyProvider, encryption *access.Encryption) ([]byte, error) {
+	masterKey, err := provider.Key(encryption.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := encryption.WrappedKey
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key size %d", len(wrapped))
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, keyIDAdditional(encryption.KeyID))
+}
+
+// newCTRStream returns AES-CTR stream seeked to offset,
+// counter block of the offset is iv + offset/16.
+func newCTRStream(key, iv []byte, offset uint64) (cipher.Stream, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("iv size %d != %d", len(iv), aes.BlockSize)
+	}
+
+	counter := make([]byte, aes.BlockSize)
+	low := binary.BigEndian.Uint64(iv[8:])
+	high := binary.BigEndian.Uint64(iv[:8])
+	blocks := offset / aes.BlockSize
+	if low+blocks < low {
+		high++
+	}
+	binary.BigEndian.PutUint64(counter[:8], high)
+	binary.BigEndian.PutUint64(counter[8:], low+blocks)
+
+	stream := cipher.NewCTR(block, counter)
+	if skip := offset % aes.BlockSize; skip > 0 {
+		var discard [aes.BlockSize]byte
+		stream.XORKeyStream(discard[:skip], discard[:skip])
+	}
+	return stream, nil
+}
+
+// getEncrypted decrypts stored data of the range
+func (h *Handler) getEncrypted(ctx context.Context, w io.Writer, location access.Location,
+	readSize, offset uint64) (func() error, error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	if h.keyProvider == nil {
+		span.Error("get encrypted location", errEncryptionDisabled)
+		return func() error { return nil }, errEncryptionDisabled
+	}
+	dataKey, err := unwrapDataKey(h.keyProvider, location.Encryption)
+	if err != nil {
+		span.Error("unwrap data key", errors.Detail(err))
+		return func() error { return nil }, err
+	}
+	stream, err := newCTRStream(dataKey, location.Encryption.IV, offset)
+	if err != nil {
+		return func() error { return nil }, err
+	}
+
+	plain := location.Copy()
+	plain.Encryption = nil
+	return h.Get(ctx, cipher.StreamWriter{S: stream, W: w}, plain, readSize, offset)
+}
@@ -0,0 +1,244 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/api/access"
+	"github.com/cubefs/blobstore/common/proto"
+)
+
+func writeKeyFile(t *testing.T, dir string, active uint32, ids ...uint32) string {
+	keys := ""
+	for idx, id := range ids {
+		key := make([]byte, masterKeySize)
+		key[0] = byte(id)
+		if idx > 0 {
+			keys += ","
+		}
+		keys += fmt.Sprintf(`"%d":"%s"`, id, hex.EncodeToString(key))
+	}
+	path := filepath.Join(dir, "keyfile")
+	require.NoError(t, ioutil.WriteFile(path,
+		[]byte(fmt.Sprintf(`{"active":%d,"keys":{%s}}`, active, keys)), 0o600))
+	return path
+}
+
+func TestAccessStreamEncryptKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = NewKeyFileProvider(filepath.Join(dir, "not-exist"))
+	require.Error(t, err)
+	_, err = NewKeyFileProvider(writeKeyFile(t, dir, 3, 1, 2))
+	require.Error(t, err)
+	path := filepath.Join(dir, "keyfile")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"active":1,"keys":{"1":"0011"}}`), 0o600))
+	_, err = NewKeyFileProvider(path)
+	require.Error(t, err)
+
+	provider, err := NewKeyFileProvider(writeKeyFile(t, dir, 1, 1))
+	require.NoError(t, err)
+	encryption, _, err := newEncryption(provider)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), encryption.KeyID)
+
+	// rotate active key, old key is kept to unwrap
+	rotated, err := NewKeyFileProvider(writeKeyFile(t, dir, 2, 1, 2))
+	require.NoError(t, err)
+	key1, err := unwrapDataKey(provider, encryption)
+	require.NoError(t, err)
+	key2, err := unwrapDataKey(rotated, encryption)
+	require.NoError(t, err)
+	require.Equal(t, key1, key2)
+
+	removed, err := NewKeyFileProvider(writeKeyFile(t, dir, 2, 2))
+	require.NoError(t, err)
+	_, err = unwrapDataKey(removed, encryption)
+	require.Error(t, err)
+
+	encryption.KeyID = 2
+	_, err = unwrapDataKey(rotated, encryption)
+	require.Error(t, err)
+}
+
+func TestAccessStreamEncryptCTRSeek(t *testing.T) {
+	key := make([]byte, dataKeySize)
+	rand.Read(key)
+	data := make([]byte, 1024)
+	rand.Read(data)
+
+	for _, iv := range [][]byte{
+		make([]byte, aes.BlockSize),
+		bytes.Repeat([]byte{0xff}, aes.BlockSize),
+	} {
+		stream, err := newCTRStream(key, iv, 0)
+		require.NoError(t, err)
+		encrypted := make([]byte, len(data))
+		stream.XORKeyStream(encrypted, data)
+
+		for _, offset := range []uint64{0, 1, 15, 16, 17, 500, 1023} {
+			stream, err := newCTRStream(key, iv, offset)
+			require.NoError(t, err)
+			decrypted := make([]byte, len(data)-int(offset))
+			stream.XORKeyStream(decrypted, encrypted[offset:])
+			require.Equal(t, data[offset:], decrypted)
+		}
+	}
+
+	_, err := newCTRStream(key, make([]byte, 8), 0)
+	require.Error(t, err)
+}
+
+func TestAccessStreamEncryptPutGet(t *testing.T) {
+	ctx := ctxWithName("TestAccessStreamEncryptPutGet")
+	dir, err := ioutil.TempDir("", "keyfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider, err := NewKeyFileProvider(writeKeyFile(t, dir, 1, 1))
+	require.NoError(t, err)
+	streamer.keyProvider = provider
+	streamer.Compression.BlockSize = 1 << 16
+	defer func() {
+		streamer.keyProvider = nil
+		streamer.Compression = CompressionConfig{}
+		dataShards.clean()
+	}()
+
+	size := (1 << 22) + 1023
+	data := compressibleData(size)
+	for _, codec := range []access.CompressCodec{access.CompressNone, access.CompressZstd} {
+		dataShards.clean()
+		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(size), nil, PutOptions{Codec: codec})
+		require.NoError(t, err)
+		require.Equal(t, access.LocationVersion2, loc.Version)
+		require.NotNil(t, loc.Encryption)
+		require.Equal(t, codec != access.CompressNone, loc.Compression != nil)
+
+		// stored shards are not plaintext
+		if codec == access.CompressNone {
+			blob := loc.Spread()[0]
+			shard := dataShards.get(proto.Vuid(allID[0]), blob.Bid)
+			require.NotEmpty(t, shard)
+			require.False(t, bytes.Equal(data[:len(shard)], shard))
+		}
+
+		for _, cs := range []struct {
+			offset, readSize uint64
+		}{
+			{0, uint64(size)},
+			{0, 1},
+			{17, 100},
+			{(1 << 22) - 7, 100},
+			{uint64(size) - 1, 1},
+		} {
+			buff := bytes.NewBuffer(nil)
+			transfer, err := streamer.Get(ctx(), buff, *loc, cs.readSize, cs.offset)
+			require.NoError(t, err)
+			require.NoError(t, transfer())
+			require.True(t, dataEqual(data[cs.offset:cs.offset+cs.readSize], buff.Bytes()))
+		}
+
+		streamer.keyProvider = nil
+		_, err = streamer.Get(ctx(), bytes.NewBuffer(nil), *loc, 1, 0)
+		require.ErrorIs(t, err, errEncryptionDisabled)
+		streamer.keyProvider = provider
+
+		wrong := loc.Copy()
+		wrong.Encryption.WrappedKey[len(wrong.Encryption.WrappedKey)-1]++
+		_, err = streamer.Get(ctx(), bytes.NewBuffer(nil), wrong, 1, 0)
+		require.Error(t, err)
+	}
+}
+
+func TestAccessStreamEncryptAt(t *testing.T) {
+	ctx := ctxWithName("TestAccessStreamEncryptAt")
+	dir, err := ioutil.TempDir("", "keyfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider, err := NewKeyFileProvider(writeKeyFile(t, dir, 1, 1))
+	require.NoError(t, err)
+	defer func() {
+		streamer.keyProvider = nil
+		dataShards.clean()
+	}()
+
+	// encryption disabled
+	encryption, err := streamer.NewEncryption()
+	require.NoError(t, err)
+	require.Nil(t, encryption)
+	reader := bytes.NewReader(nil)
+	rc, err := streamer.EncryptAt(nil, 0, reader)
+	require.NoError(t, err)
+	require.Equal(t, reader, rc)
+	_, err = streamer.EncryptAt(&access.Encryption{}, 0, reader)
+	require.ErrorIs(t, err, errEncryptionDisabled)
+
+	streamer.keyProvider = provider
+	size := (1 << 22) + 1023
+	data := make([]byte, size)
+	rand.Read(data)
+
+	loc, err := streamer.Alloc(ctx(), uint64(size), 0, 0, 0)
+	require.NoError(t, err)
+	encryption, err = streamer.NewEncryption()
+	require.NoError(t, err)
+	require.NotNil(t, encryption)
+	loc.Version = access.LocationVersion2
+	loc.Encryption = encryption
+
+	// blobs are put by parts such as multipart upload
+	blobs := loc.Spread()
+	require.True(t, len(blobs) > 1)
+	offset := uint64(0)
+	for _, blob := range blobs {
+		part := data[offset : offset+uint64(blob.Size)]
+		rc, err := streamer.EncryptAt(encryption, offset, bytes.NewReader(part))
+		require.NoError(t, err)
+		require.NoError(t, streamer.PutAt(ctx(), rc, loc.ClusterID, blob.Vid, blob.Bid, int64(blob.Size), nil, PutOptions{}))
+		if offset == 0 {
+			shard := dataShards.get(proto.Vuid(allID[0]), blob.Bid)
+			require.False(t, bytes.Equal(part[:len(shard)], shard))
+		}
+		offset += uint64(blob.Size)
+	}
+
+	for _, cs := range []struct {
+		offset, readSize uint64
+	}{
+		{0, uint64(size)},
+		{(1 << 22) - 7, 100},
+	} {
+		buff := bytes.NewBuffer(nil)
+		transfer, err := streamer.Get(ctx(), buff, *loc, cs.readSize, cs.offset)
+		require.NoError(t, err)
+		require.NoError(t, transfer())
+		require.True(t, dataEqual(data[cs.offset:cs.offset+cs.readSize], buff.Bytes()))
+	}
+}
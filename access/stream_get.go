@@ -17,6 +17,7 @@ package access
 import (
 	"context"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math/rand"
 	"sort"
@@ -42,6 +43,7 @@ var (
 	errNeedReconstructRead = errors.New("need to reconstruct read")
 	errCanceledReadShard   = errors.New("canceled read shard")
 	errPunishedDisk        = errors.New("punished disk")
+	errMismatchedBlobCrc   = errors.New("mismatched blob crc")
 )
 
 type blobGetArgs struct {
@@ -50,11 +52,18 @@ type blobGetArgs struct {
 	BlobSize uint64
 	Offset   uint64
 	ReadSize uint64
+	// code mode of the blob when it was written
+	CodeMode codemode.CodeMode
+
+	// verify crc of the whole blob content if location carries blob crcs
+	VerifyCrc bool
+	BlobCrc   uint32
 }
 
 type shardData struct {
 	index  int
 	status bool
+	hedged bool
 	buffer []byte
 }
 
@@ -97,6 +106,13 @@ func (h *Handler) Get(ctx context.Context, w io.Writer, location access.Location
 	span := trace.SpanFromContextSafe(ctx)
 	span.Debugf("get request cluster:%d size:%d offset:%d", location.ClusterID, readSize, offset)
 
+	if location.Compression != nil {
+		return h.getCompressed(ctx, w, location, readSize, offset)
+	}
+	if location.Encryption != nil {
+		return h.getEncrypted(ctx, w, location, readSize, offset)
+	}
+
 	blobs, err := genLocationBlobs(&location, readSize, offset)
 	if err != nil {
 		span.Info("illegal argument", err)
@@ -190,7 +206,7 @@ func (h *Handler) Get(ctx context.Context, w io.Writer, location access.Location
 
 					codeMode := blobVolume.CodeMode
 					tactic := codeMode.Tactic()
-					sizes, _ := ec.GetBufferSizes(int(blob.BlobSize), tactic)
+					sizes, _ := ec.GetBufferSizes(encodedSize(blob, codeMode), tactic)
 					shardSize := sizes.ShardSize
 
 					st := time.Now()
@@ -203,6 +219,12 @@ func (h *Handler) Get(ctx context.Context, w io.Writer, location access.Location
 
 					err = h.readOneBlob(ctx, getTime, serviceController, clusterID,
 						blobVolume.Vid, codeMode, blob, sortedVuids, shards)
+					if err == nil && blob.VerifyCrc {
+						if crc := blobDataCrc(shards[:tactic.N], blob.BlobSize); crc != blob.BlobCrc {
+							span.Errorf("blob(%d %d %d) crc %d != %d", clusterID, blob.Vid, blob.Bid, crc, blob.BlobCrc)
+							err = errMismatchedBlobCrc
+						}
+					}
 					if err != nil {
 						span.Error("read one blob", blob.Bid, err)
 						for _, buf := range shards {
@@ -280,7 +302,7 @@ func (h *Handler) readOneBlob(ctx context.Context, getTime *timeReadWrite,
 	span := trace.SpanFromContextSafe(ctx)
 
 	tactic := codeMode.Tactic()
-	sizes, err := ec.GetBufferSizes(int(blob.BlobSize), tactic)
+	sizes, err := ec.GetBufferSizes(encodedSize(blob, codeMode), tactic)
 	if err != nil {
 		return err
 	}
@@ -316,23 +338,46 @@ func (h *Handler) readOneBlob(ctx context.Context, getTime *timeReadWrite,
 				}
 			}
 
+			// read extra shard after hedge delay of recent shard reads
+			var (
+				hedgeTimer *time.Timer
+				hedgeC     <-chan time.Time
+				hedges     int
+			)
+			if h.hedgeLatency != nil {
+				hedgeTimer = time.NewTimer(h.hedgeLatency.Delay())
+				defer hedgeTimer.Stop()
+				hedgeC = hedgeTimer.C
+			}
+
 			for _, vuid := range sortedVuids[minShardsRead:] {
 				if _, ok := empties[vuid.index]; ok {
 					continue
 				}
 
+				hedged := false
 				select {
 				case <-stopChan:
 					return
 				case <-nextChan:
+				case <-hedgeC:
+					hedged = true
+					if hedges++; hedges < h.HedgeRead.MaxShards {
+						hedgeTimer.Reset(h.hedgeLatency.Delay())
+					} else {
+						hedgeC = nil
+					}
+					reportHedge(clusterID, "fire")
 				}
 
 				wg.Add(1)
-				go func(vuid sortedVuid) {
-					ch <- h.readOneShard(ctx, serviceController, clusterID, vid,
+				go func(vuid sortedVuid, hedged bool) {
+					shard := h.readOneShard(ctx, serviceController, clusterID, vid,
 						shardSize, blob, vuid, stopChan)
+					shard.hedged = hedged
+					ch <- shard
 					wg.Done()
-				}(vuid)
+				}(vuid, hedged)
 			}
 		}()
 
@@ -347,9 +392,11 @@ func (h *Handler) readOneBlob(ctx context.Context, getTime *timeReadWrite,
 
 	startRead := time.Now()
 	reconstructed := false
+	hedgeArrived := false
 	for shard := range shardPipe {
 		// swap shard buffer
 		if shard.status {
+			hedgeArrived = hedgeArrived || shard.hedged
 			buf := shards[shard.index]
 			shards[shard.index] = shard.buffer
 			h.memPool.Put(buf)
@@ -423,6 +470,9 @@ func (h *Handler) readOneBlob(ctx context.Context, getTime *timeReadWrite,
 	}()
 
 	if reconstructed {
+		if hedgeArrived {
+			reportHedge(clusterID, "win")
+		}
 		return nil
 	}
 	return fmt.Errorf("broken blob(%d %d %d)", clusterID, blob.Vid, blob.Bid)
@@ -436,6 +486,7 @@ func (h *Handler) readOneShard(ctx context.Context, serviceController controller
 		index:  vuid.index,
 		status: false,
 	}
+	startRead := time.Now()
 
 	args := blobnode.RangeGetShardArgs{
 		GetShardArgs: blobnode.GetShardArgs{
@@ -482,6 +533,9 @@ func (h *Handler) readOneShard(ctx context.Context, serviceController controller
 		return shardResult
 	}
 
+	if h.hedgeLatency != nil {
+		h.hedgeLatency.Add(time.Since(startRead))
+	}
 	shardResult.status = true
 	shardResult.buffer = buf
 	return shardResult
@@ -502,7 +556,7 @@ func (h *Handler) getDataShardOnly(ctx context.Context, getTime *timeReadWrite,
 	tactic := blobVolume.CodeMode.Tactic()
 
 	from, to := int(blob.Offset), int(blob.Offset+blob.ReadSize)
-	buffer, err := ec.NewRangeBuffer(int(blob.BlobSize), from, to, tactic, h.memPool)
+	buffer, err := ec.NewRangeBuffer(encodedSize(blob, blobVolume.CodeMode), from, to, tactic, h.memPool)
 	if err != nil {
 		return err
 	}
@@ -559,6 +613,15 @@ func (h *Handler) getDataShardOnly(ctx context.Context, getTime *timeReadWrite,
 		return fmt.Errorf("no enough data to read %d", remainSize)
 	}
 
+	// verify crc if read the whole blob, reconstruct read if mismatched
+	if blob.VerifyCrc && blob.Offset == 0 && blob.ReadSize == blob.BlobSize {
+		if crc := crc32.ChecksumIEEE(buffer.DataBuf[:int(blob.ReadSize)]); crc != blob.BlobCrc {
+			span.Warnf("read blob(%d %d %d) data shard only crc %d != %d",
+				clusterID, blob.Vid, blob.Bid, crc, blob.BlobCrc)
+			return errNeedReconstructRead
+		}
+	}
+
 	startWrite := time.Now()
 	if _, err := w.Write(buffer.DataBuf[:int(blob.ReadSize)]); err != nil {
 		getTime.IncW(time.Since(startWrite))
@@ -629,6 +692,13 @@ func (h *Handler) getOneShardFromHost(ctx context.Context, serviceController con
 				span.Warnf("update volume info with no cache %d %d err: %s", clusterID, vid, e)
 				return false, err
 			}
+			// source units are released after volume redirected, units of redirected volume
+			// are not matched with the blob index in source, read again with the updated volume
+			if index >= len(latestVolume.Units) ||
+				latestVolume.Units[index].Vuid.Vid() != args.GetShardArgs.Vuid.Vid() {
+				span.Infof("volume %d has been redirected", vid)
+				return true, err
+			}
 			newUnit := latestVolume.Units[index]
 
 			newDiskID := newUnit.DiskID
@@ -676,6 +746,8 @@ func genLocationBlobs(location *access.Location, readSize uint64, offset uint64)
 	remainSize := readSize
 	firstBlobIdx := offset / blobSize
 	blobOffset := offset % blobSize
+	verifyCrc := location.Version >= access.LocationVersion2 &&
+		uint64(len(location.BlobCrcs)) == blobCount(location.Size, location.BlobSize)
 
 	idx := uint64(0)
 	blobs := make([]blobGetArgs, 0, 1+(readSize+blobOffset)/blobSize)
@@ -690,13 +762,19 @@ func genLocationBlobs(location *access.Location, readSize uint64, offset uint64)
 			if idx >= firstBlobIdx {
 				toReadSize := minU64(remainSize, blobSize-blobOffset)
 				if toReadSize > 0 {
-					blobs = append(blobs, blobGetArgs{
+					args := blobGetArgs{
 						Vid:      blob.Vid,
 						Bid:      currBlobID,
 						BlobSize: minU64(location.Size-idx*blobSize, blobSize), // update the last blob size
 						Offset:   blobOffset,
 						ReadSize: toReadSize,
-					})
+						CodeMode: location.CodeMode,
+					}
+					if verifyCrc {
+						args.VerifyCrc = true
+						args.BlobCrc = location.BlobCrcs[idx]
+					}
+					blobs = append(blobs, args)
 				}
 
 				// reset next blob offset
@@ -716,6 +794,31 @@ func genLocationBlobs(location *access.Location, readSize uint64, offset uint64)
 	return blobs, nil
 }
 
+// encodedSize returns size of data encoded in volume with code mode,
+// the padded data of blob is re-encoded when volume was converted into another code mode.
+func encodedSize(blob blobGetArgs, codeMode codemode.CodeMode) int {
+	if blob.CodeMode.IsValid() && blob.CodeMode != codeMode {
+		if sizes, err := ec.GetBufferSizes(int(blob.BlobSize), blob.CodeMode.Tactic()); err == nil {
+			return sizes.ECDataSize
+		}
+	}
+	return int(blob.BlobSize)
+}
+
+// blobDataCrc returns crc32 of blob content in data shards
+func blobDataCrc(dataShards [][]byte, blobSize uint64) uint32 {
+	crc := crc32.NewIEEE()
+	for _, shard := range dataShards {
+		if blobSize == 0 {
+			break
+		}
+		n := minU64(blobSize, uint64(len(shard)))
+		crc.Write(shard[:n])
+		blobSize -= n
+	}
+	return crc.Sum32()
+}
+
 func genSortedVuidByIDC(ctx context.Context, serviceController controller.ServiceController, idc string,
 	vuidPhys []controller.Unit) []sortedVuid {
 	span := trace.SpanFromContextSafe(ctx)
@@ -17,6 +17,7 @@ package access
 import (
 	"bytes"
 	"crypto/rand"
+	"hash/crc32"
 	mrand "math/rand"
 	"testing"
 	"time"
@@ -34,7 +35,7 @@ func TestAccessStreamGetBase(t *testing.T) {
 	{
 		dataShards.clean()
 		data := []byte("x")
-		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(len(data)), nil)
+		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(len(data)), nil, PutOptions{})
 		require.NoError(t, err)
 
 		buff := bytes.NewBuffer(nil)
@@ -47,7 +48,7 @@ func TestAccessStreamGetBase(t *testing.T) {
 	{
 		dataShards.clean()
 		data := []byte("x")
-		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(len(data)), nil)
+		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(len(data)), nil, PutOptions{})
 		require.NoError(t, err)
 
 		buff := bytes.NewBuffer(nil)
@@ -83,7 +84,7 @@ func TestAccessStreamGetBase(t *testing.T) {
 		size := cs.size
 		data := make([]byte, size)
 		rand.Read(data)
-		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(size), nil)
+		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(size), nil, PutOptions{})
 		require.NoError(t, err)
 
 		buff := bytes.NewBuffer(nil)
@@ -100,6 +101,42 @@ func TestAccessStreamGetBase(t *testing.T) {
 	dataShards.clean()
 }
 
+func TestAccessStreamGetBlobCrc(t *testing.T) {
+	ctx := ctxWithName("TestAccessStreamGetBlobCrc")
+	streamer.LocationVersion = access.LocationVersion2
+	streamer.LocationHashSum = true
+	defer func() {
+		streamer.LocationVersion = 0
+		streamer.LocationHashSum = false
+	}()
+
+	for _, size := range []int{1, (1 << 22) + 1023} {
+		dataShards.clean()
+		data := make([]byte, size)
+		rand.Read(data)
+		hasherMap := access.HasherMap{access.HashAlgCRC32: access.HashAlgCRC32.ToHasher()}
+		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(size), hasherMap, PutOptions{})
+		require.NoError(t, err)
+		require.Equal(t, access.LocationVersion2, loc.Version)
+		require.Equal(t, len(loc.Spread()), len(loc.BlobCrcs))
+		require.Equal(t, crc32.ChecksumIEEE(data), loc.HashSumMap.GetSumVal(access.HashAlgCRC32))
+
+		buff := bytes.NewBuffer(nil)
+		transfer, err := streamer.Get(ctx(), buff, *loc, uint64(size), 0)
+		require.NoError(t, err)
+		require.NoError(t, transfer())
+		require.True(t, dataEqual(data, buff.Bytes()))
+
+		loc.BlobCrcs[0]++
+		buff.Reset()
+		transfer, err = streamer.Get(ctx(), buff, *loc, uint64(size), 0)
+		require.NoError(t, err)
+		require.ErrorIs(t, transfer(), errMismatchedBlobCrc)
+	}
+
+	dataShards.clean()
+}
+
 func TestAccessStreamGetBroken(t *testing.T) {
 	ctx := ctxWithName("TestAccessStreamGetBroken")
 	defer func() {
@@ -117,7 +154,7 @@ func TestAccessStreamGetBroken(t *testing.T) {
 	rand.Read(data)
 	// time wait the punished services
 	time.Sleep(time.Second * time.Duration(punishServiceS))
-	loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(size), nil)
+	loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(size), nil, PutOptions{})
 	require.NoError(t, err)
 
 	cases := []struct {
@@ -178,7 +215,7 @@ func TestAccessStreamGetOffset(t *testing.T) {
 		size := cs.size
 		data := make([]byte, size)
 		rand.Read(data)
-		loc, err := streamer.Put(ctx(), bytes.NewReader(data), size, nil)
+		loc, err := streamer.Put(ctx(), bytes.NewReader(data), size, nil, PutOptions{})
 		require.NoError(t, err)
 
 		buff := bytes.NewBuffer(nil)
@@ -203,7 +240,7 @@ func TestAccessStreamGetShardTimeout(t *testing.T) {
 	size := 1 << 22
 	buff := make([]byte, size)
 	rand.Read(buff)
-	loc, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil)
+	loc, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil, PutOptions{})
 	require.NoError(t, err)
 
 	// no delay when blocking one shard, cos MinReadShardsX = 1
@@ -253,7 +290,7 @@ func TestAccessStreamGetShardBroken(t *testing.T) {
 	size := 1 << 22
 	buff := make([]byte, size)
 	rand.Read(buff)
-	loc, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil)
+	loc, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil, PutOptions{})
 	require.NoError(t, err)
 
 	// no delay when blocking one shard, cos MinReadShardsX = 1
@@ -292,7 +329,7 @@ func TestAccessStreamGetLocalIDC(t *testing.T) {
 	size := 1 << 22
 	buff := make([]byte, size)
 	rand.Read(buff)
-	loc, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil)
+	loc, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil, PutOptions{})
 	require.NoError(t, err)
 
 	// no delay when blocking other idc all shards
@@ -407,7 +444,7 @@ func TestAccessStreamGetAligned(t *testing.T) {
 
 		data := make([]byte, cs.size)
 		rand.Read(data)
-		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(cs.size), nil)
+		loc, err := streamer.Put(ctx(), bytes.NewReader(data), int64(cs.size), nil, PutOptions{})
 		require.NoError(t, err)
 
 		randomGoodShards(cs.goodShards)
@@ -552,7 +589,7 @@ func BenchmarkAccessStreamGet(b *testing.B) {
 	for _, cs := range cases {
 		b.ResetTimer()
 		b.Run(cs.name, func(b *testing.B) {
-			loc, err := streamer.Put(ctx, newReader(cs.size), int64(cs.size), nil)
+			loc, err := streamer.Put(ctx, newReader(cs.size), int64(cs.size), nil, PutOptions{})
 			require.NoError(b, err)
 
 			b.ResetTimer()
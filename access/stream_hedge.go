@@ -0,0 +1,100 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HedgeReadConfig hedged reading of shards
+//
+// extra shards are read from other units if the blob is not
+// reconstructed after the percentile latency of recent shard reads,
+// the blob is reconstructed from whichever shards arrive first.
+type HedgeReadConfig struct {
+	Enable bool `json:"enable"`
+	// Percentile of recent shard read latency to hedge, in (0, 1)
+	Percentile float64 `json:"percentile"`
+	// MinDelayMS lower bound of hedge delay, also the delay before enough samples
+	MinDelayMS int `json:"min_delay_ms"`
+	// MaxShards max extra shards of one blob to hedge
+	MaxShards int `json:"max_shards"`
+	// Samples window size of recent shard read latency
+	Samples int `json:"samples"`
+}
+
+// shardLatency sliding window of shard read latency,
+// the hedge delay is recalculated every window/8 samples.
+type shardLatency struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+	added   int
+
+	percentile float64
+	minDelay   time.Duration
+	delay      int64 // atomic time.Duration
+}
+
+func newShardLatency(cfg HedgeReadConfig) *shardLatency {
+	minDelay := time.Duration(cfg.MinDelayMS) * time.Millisecond
+	return &shardLatency{
+		samples:    make([]time.Duration, cfg.Samples),
+		percentile: cfg.Percentile,
+		minDelay:   minDelay,
+		delay:      int64(minDelay),
+	}
+}
+
+// Add adds a sample of shard read latency
+func (l *shardLatency) Add(d time.Duration) {
+	l.mu.Lock()
+	l.samples[l.next] = d
+	l.next++
+	if l.next == len(l.samples) {
+		l.next = 0
+		l.full = true
+	}
+	l.added++
+	if l.added < len(l.samples)/8+1 {
+		l.mu.Unlock()
+		return
+	}
+	l.added = 0
+
+	n := l.next
+	if l.full {
+		n = len(l.samples)
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, l.samples[:n])
+	l.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	delay := sorted[int(l.percentile*float64(n-1))]
+	if delay < l.minDelay {
+		delay = l.minDelay
+	}
+	atomic.StoreInt64(&l.delay, int64(delay))
+}
+
+// Delay returns delay to hedge reading
+func (l *shardLatency) Delay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&l.delay))
+}
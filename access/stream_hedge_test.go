@@ -0,0 +1,90 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessStreamHedgeLatency(t *testing.T) {
+	l := newShardLatency(HedgeReadConfig{Percentile: 0.9, MinDelayMS: 5, Samples: 8})
+	require.Equal(t, 5*time.Millisecond, l.Delay())
+
+	l.Add(time.Second)
+	require.Equal(t, 5*time.Millisecond, l.Delay())
+	l.Add(time.Second)
+	require.Equal(t, time.Second, l.Delay())
+
+	for ii := 1; ii <= 8; ii++ {
+		l.Add(time.Duration(ii) * time.Millisecond)
+	}
+	require.Equal(t, 7*time.Millisecond, l.Delay())
+
+	// window slides to the recent samples
+	for range [8]struct{}{} {
+		l.Add(time.Millisecond)
+	}
+	require.Equal(t, 5*time.Millisecond, l.Delay())
+}
+
+func TestAccessStreamGetHedged(t *testing.T) {
+	ctx := ctxWithName("TestAccessStreamGetHedged")
+	dataShards.clean()
+	vuidController.Unbreak(1005)
+	streamer.HedgeRead = HedgeReadConfig{Enable: true, Percentile: 0.9, MinDelayMS: 10, MaxShards: 2, Samples: 16}
+	streamer.hedgeLatency = newShardLatency(streamer.HedgeRead)
+	defer func() {
+		streamer.HedgeRead = HedgeReadConfig{}
+		streamer.hedgeLatency = nil
+		vuidController.Break(1005)
+		dataShards.clean()
+	}()
+
+	size := 1 << 22
+	buff := make([]byte, size)
+	rand.Read(buff)
+	loc, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil, PutOptions{})
+	require.NoError(t, err)
+
+	fired := hedgeMetric.WithLabelValues(loc.ClusterID.ToString(), "fire")
+	won := hedgeMetric.WithLabelValues(loc.ClusterID.ToString(), "win")
+	firedN, wonN := testutil.ToFloat64(fired), testutil.ToFloat64(won)
+
+	// no delay of one duration when blocking two shards, cos hedged
+	vuidController.Block(1001)
+	vuidController.Block(1002)
+	defer func() {
+		vuidController.Unblock(1001)
+		vuidController.Unblock(1002)
+	}()
+
+	startTime := time.Now()
+	w := bytes.NewBuffer(nil)
+	transfer, err := streamer.Get(ctx(), w, *loc, uint64(size), 0)
+	require.NoError(t, err)
+	require.NoError(t, transfer())
+	require.True(t, dataEqual(buff, w.Bytes()))
+
+	duration := time.Since(startTime)
+	require.Greater(t, vuidController.duration/2, duration, "greater duration: ", duration)
+	require.Less(t, firedN, testutil.ToFloat64(fired))
+	require.Less(t, wonN, testutil.ToFloat64(won))
+}
@@ -112,8 +112,9 @@ type shardKey struct {
 }
 
 type shardsData struct {
-	mutex sync.RWMutex
-	data  map[shardKey][]byte
+	mutex   sync.RWMutex
+	data    map[shardKey][]byte
+	expires map[shardKey]int64
 }
 
 func (d *shardsData) clean() {
@@ -147,6 +148,21 @@ func (d *shardsData) set(vuid proto.Vuid, bid proto.BlobID, b []byte) {
 	d.mutex.Unlock()
 }
 
+func (d *shardsData) getExpire(vuid proto.Vuid, bid proto.BlobID) int64 {
+	key := shardKey{Vuid: vuid, Bid: bid}
+	d.mutex.RLock()
+	expireAt := d.expires[key]
+	d.mutex.RUnlock()
+	return expireAt
+}
+
+func (d *shardsData) setExpire(vuid proto.Vuid, bid proto.BlobID, expireAt int64) {
+	key := shardKey{Vuid: vuid, Bid: bid}
+	d.mutex.Lock()
+	d.expires[key] = expireAt
+	d.mutex.Unlock()
+}
+
 type vuidControl struct {
 	mutex    sync.Mutex
 	broken   map[proto.Vuid]bool
@@ -272,6 +288,7 @@ var storageAPIPutShard = func(ctx context.Context, host string, args *blobnode.P
 
 	crc = crc32.ChecksumIEEE(buffer)
 	dataShards.set(args.Vuid, args.Bid, buffer)
+	dataShards.setExpire(args.Vuid, args.Bid, args.ExpireAt)
 	return
 }
 
@@ -343,7 +360,8 @@ func initMockData() {
 	}
 
 	dataShards = &shardsData{
-		data: make(map[shardKey][]byte, len(allID)),
+		data:    make(map[shardKey][]byte, len(allID)),
+		expires: make(map[shardKey]int64, len(allID)),
 	}
 	dataShards.clean()
 
@@ -17,6 +17,7 @@ package access
 import (
 	"bytes"
 	"context"
+	"crypto/cipher"
 	"fmt"
 	"hash/crc32"
 	"io"
@@ -42,12 +43,15 @@ import (
 // Put put one object
 //     required: size, file size
 //     optional: hasher map to calculate hash.Hash
+//     optional: opts, compression codec and expiry of the object
 func (h *Handler) Put(ctx context.Context, rc io.Reader, size int64,
-	hasherMap access.HasherMap) (*access.Location, error) {
+	hasherMap access.HasherMap, opts PutOptions) (*access.Location, error) {
 	span := trace.SpanFromContextSafe(ctx)
-	span.Debugf("put request size:%d hashes:b(%b)", size, hasherMap.ToHashAlgorithm())
+	span.Debugf("put request size:%d hashes:b(%b) options:%+v",
+		size, hasherMap.ToHashAlgorithm(), opts)
 
-	if size <= 0 {
+	codec, expireAt := opts.Codec, opts.ExpireAt
+	if size <= 0 || expireAt < 0 || !codec.IsValid() {
 		return nil, errcode.ErrIllegalArguments
 	}
 	if size > h.maxObjectSize {
@@ -74,6 +78,36 @@ func (h *Handler) Put(ctx context.Context, rc io.Reader, size int64,
 
 	// 3.read body and split, alloc from mem pool;ec encode and put into data node
 	limitReader := io.LimitReader(rc, int64(size))
+
+	// compressed data is not larger than raw data, fill allocated blobs in order,
+	// then the rest blobs were not written and is trimmed from location.
+	if codec == access.CompressNone && size >= h.Compression.MinSize {
+		codec, _ = access.ParseCompressCodec(h.Compression.Codec)
+	}
+	var (
+		comp   *compressor
+		stage  []byte
+		reader = limitReader // reader of stored data
+	)
+	if codec != access.CompressNone {
+		comp = newCompressor(limitReader, codec, h.Compression.BlockSize)
+		reader = comp
+		if stage, err = h.memPool.Alloc(int(blobSize)); err != nil {
+			return nil, err
+		}
+		defer h.memPool.Put(stage)
+	}
+
+	// encrypt stored data after compression
+	var encryption *access.Encryption
+	if h.keyProvider != nil {
+		var stream cipher.Stream
+		if encryption, stream, err = newEncryption(h.keyProvider); err != nil {
+			span.Error("new encryption failed", errors.Detail(err))
+			return nil, err
+		}
+		reader = cipher.StreamReader{S: stream, R: reader}
+	}
 	location := &access.Location{
 		ClusterID: clusterID,
 		CodeMode:  selectedCodeMode,
@@ -107,11 +141,31 @@ func (h *Handler) Put(ctx context.Context, rc io.Reader, size int64,
 		ready <- struct{}{}
 	}
 
+	var blobCrcs []uint32
+	withBlobCrc := h.LocationVersion >= access.LocationVersion2
+	if withBlobCrc {
+		blobCrcs = make([]uint32, 0, blobCount(uint64(size), blobSize))
+	}
+
 	encoder := h.encoder[selectedCodeMode]
 	tactic := selectedCodeMode.Tactic()
 	for _, blob := range location.Spread() {
 		vid, bid, bsize := blob.Vid, blob.Bid, int(blob.Size)
 
+		if comp != nil {
+			startRead := time.Now()
+			n, err := io.ReadFull(reader, stage[:bsize])
+			putTime.IncR(time.Since(startRead))
+			if n == 0 && err == io.EOF {
+				break
+			}
+			if err != nil && err != io.ErrUnexpectedEOF {
+				span.Infof("read compressed blob data failed want:%d read:%d %s", bsize, n, err.Error())
+				return nil, errcode.ErrAccessReadRequestBody
+			}
+			bsize = n
+		}
+
 		// new an empty ec buffer for per blob
 		var err error
 		st := time.Now()
@@ -127,16 +181,23 @@ func (h *Handler) Put(ctx context.Context, rc io.Reader, size int64,
 			return nil, err
 		}
 
-		startRead := time.Now()
-		n, err := io.ReadFull(limitReader, readBuff)
-		putTime.IncR(time.Since(startRead))
-		if err != nil && err != io.EOF {
-			span.Infof("read blob data failed want:%d read:%d %s", bsize, n, err.Error())
-			return nil, errcode.ErrAccessReadRequestBody
+		if comp != nil {
+			copy(readBuff, stage[:bsize])
+		} else {
+			startRead := time.Now()
+			n, err := io.ReadFull(reader, readBuff)
+			putTime.IncR(time.Since(startRead))
+			if err != nil && err != io.EOF {
+				span.Infof("read blob data failed want:%d read:%d %s", bsize, n, err.Error())
+				return nil, errcode.ErrAccessReadRequestBody
+			}
+			if n != bsize {
+				span.Infof("read blob less data want:%d but:%d", bsize, n)
+				return nil, errcode.ErrAccessReadRequestBody
+			}
 		}
-		if n != bsize {
-			span.Infof("read blob less data want:%d but:%d", bsize, n)
-			return nil, errcode.ErrAccessReadRequestBody
+		if withBlobCrc {
+			blobCrcs = append(blobCrcs, crc32.ChecksumIEEE(readBuff))
 		}
 
 		// ec encode
@@ -152,7 +213,7 @@ func (h *Handler) Put(ctx context.Context, rc io.Reader, size int64,
 		buffer = nil
 		<-ready
 		startWrite := time.Now()
-		err = h.writeToBlobnodesWithHystrix(ctx, blobident, shards, func() {
+		err = h.writeToBlobnodesWithHystrix(ctx, blobident, shards, expireAt, func() {
 			takeoverBuffer.Release()
 			ready <- struct{}{}
 		})
@@ -162,16 +223,68 @@ func (h *Handler) Put(ctx context.Context, rc io.Reader, size int64,
 		}
 	}
 
+	if comp != nil {
+		compression := comp.Compression()
+		if compression.RawSize != uint64(size) {
+			span.Infof("read compressed less data want:%d but:%d", size, compression.RawSize)
+			return nil, errcode.ErrAccessReadRequestBody
+		}
+
+		storedSize := uint64(0)
+		for _, blockSize := range compression.Blocks {
+			storedSize += uint64(blockSize)
+		}
+		span.Debugf("compressed %s %d -> %d in %d blocks", codec, size, storedSize, len(compression.Blocks))
+
+		compressed := location.Copy()
+		compressed.Size = storedSize
+		compressed.Blobs = trimBlobs(location.Blobs, blobCount(storedSize, blobSize))
+		compressed.Version = access.LocationVersion2
+		compressed.Compression = compression
+		location = &compressed
+	}
+	if encryption != nil {
+		location.Version = access.LocationVersion2
+		location.Encryption = encryption
+	}
+
+	if withBlobCrc {
+		location.Version = access.LocationVersion2
+		location.BlobCrcs = blobCrcs
+		if h.LocationHashSum && len(hasherMap) > 0 {
+			location.HashSumMap = make(access.HashSumMap, len(hasherMap))
+			for alg, hasher := range hasherMap {
+				location.HashSumMap[alg] = hasher.Sum(nil)
+			}
+		}
+	}
+
 	uploadSucc = true
 	return location, nil
 }
 
+// trimBlobs returns slices of the first count blobs
+func trimBlobs(blobs []access.SliceInfo, count uint64) []access.SliceInfo {
+	trimmed := make([]access.SliceInfo, 0, len(blobs))
+	for _, blob := range blobs {
+		if count == 0 {
+			break
+		}
+		if uint64(blob.Count) > count {
+			blob.Count = uint32(count)
+		}
+		count -= uint64(blob.Count)
+		trimmed = append(trimmed, blob)
+	}
+	return trimmed
+}
+
 func (h *Handler) writeToBlobnodesWithHystrix(ctx context.Context,
-	blob blobIdent, shards [][]byte, callback func()) error {
+	blob blobIdent, shards [][]byte, expireAt int64, callback func()) error {
 	safe := make(chan struct{}, 1)
 	err := hystrix.Do(rwCommand, func() error {
 		safe <- struct{}{}
-		return h.writeToBlobnodes(ctx, blob, shards, callback)
+		return h.writeToBlobnodes(ctx, blob, shards, expireAt, callback)
 	}, nil)
 
 	select {
@@ -191,7 +304,7 @@ type shardPutStatus struct {
 // takeover ec buffer release by callback.
 // return if had quorum successful shards, then wait all shards in background.
 func (h *Handler) writeToBlobnodes(ctx context.Context,
-	blob blobIdent, shards [][]byte, callback func()) (err error) {
+	blob blobIdent, shards [][]byte, expireAt int64, callback func()) (err error) {
 	span := trace.SpanFromContextSafe(ctx)
 	clusterID, vid, bid := blob.cid, blob.vid, blob.bid
 
@@ -239,11 +352,12 @@ func (h *Handler) writeToBlobnodes(ctx context.Context,
 
 			diskID := unit.DiskID
 			args := &blobnode.PutShardArgs{
-				DiskID: diskID,
-				Vuid:   unit.Vuid,
-				Bid:    bid,
-				Size:   int64(len(shards[index])),
-				Type:   blobnode.NormalIO,
+				DiskID:   diskID,
+				Vuid:     unit.Vuid,
+				Bid:      bid,
+				Size:     int64(len(shards[index])),
+				Type:     blobnode.NormalIO,
+				ExpireAt: expireAt,
 			}
 
 			crcDisabled := h.ShardCrcDisabled
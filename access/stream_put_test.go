@@ -42,13 +42,19 @@ func TestAccessStreamPutBase(t *testing.T) {
 	// 0
 	{
 		size := 0
-		_, err := streamer.Put(ctx(), newReader(size), int64(size), nil)
+		_, err := streamer.Put(ctx(), newReader(size), int64(size), nil, PutOptions{})
 		require.Error(t, err)
 	}
+	// invalid expiry
+	{
+		size := 1
+		_, err := streamer.Put(ctx(), newReader(size), int64(size), nil, PutOptions{ExpireAt: -1})
+		require.ErrorIs(t, err, errcode.ErrIllegalArguments)
+	}
 	// 1 byte
 	{
 		size := 1
-		loc, err := streamer.Put(ctx(), newReader(size), int64(size), nil)
+		loc, err := streamer.Put(ctx(), newReader(size), int64(size), nil, PutOptions{})
 		require.NoError(t, err)
 		require.Equal(t, 1, len(loc.Blobs))
 		require.Equal(t, uint32(1), loc.Blobs[0].Count)
@@ -58,7 +64,7 @@ func TestAccessStreamPutBase(t *testing.T) {
 	// <4M
 	{
 		size := 1 << 18
-		loc, err := streamer.Put(ctx(), newReader(size), int64(size), nil)
+		loc, err := streamer.Put(ctx(), newReader(size), int64(size), nil, PutOptions{})
 		require.NoError(t, err)
 		require.Equal(t, 1, len(loc.Blobs))
 		require.Equal(t, uint32(1), loc.Blobs[0].Count)
@@ -67,7 +73,7 @@ func TestAccessStreamPutBase(t *testing.T) {
 	// 8M + 1k
 	{
 		size := (1 << 23) + 1024
-		loc, err := streamer.Put(ctx(), newReader(size), int64(size), nil)
+		loc, err := streamer.Put(ctx(), newReader(size), int64(size), nil, PutOptions{})
 		require.NoError(t, err)
 		require.Equal(t, 2, len(loc.Blobs))
 		require.Equal(t, uint32(2), loc.Blobs[1].Count)
@@ -76,7 +82,7 @@ func TestAccessStreamPutBase(t *testing.T) {
 	// max size + 1
 	{
 		size := defaultMaxObjectSize + 1
-		_, err := streamer.Put(ctx(), nil, int64(size), nil)
+		_, err := streamer.Put(ctx(), nil, int64(size), nil, PutOptions{})
 		require.EqualError(t, errcode.ErrAccessExceedSize, err.Error())
 	}
 
@@ -97,7 +103,7 @@ func TestAccessStreamPutSum(t *testing.T) {
 		}
 		hashSumMap := make(access.HashSumMap, len(hasherMap))
 
-		_, err := streamer.Put(ctx(), bytes.NewReader(data), int64(len(data)), hasherMap)
+		_, err := streamer.Put(ctx(), bytes.NewReader(data), int64(len(data)), hasherMap, PutOptions{})
 		require.NoError(t, err)
 		for alg, hasher := range hasherMap {
 			hashSumMap[alg] = hasher.Sum(nil)
@@ -231,7 +237,7 @@ func TestAccessStreamPutShardTimeout(t *testing.T) {
 	buff := make([]byte, size)
 	rand.Read(buff)
 	startTime := time.Now()
-	loc, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil)
+	loc, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil, PutOptions{})
 	require.NoError(t, err)
 
 	// response immediately if had quorum shards
@@ -247,7 +253,7 @@ func TestAccessStreamPutShardTimeout(t *testing.T) {
 	vuidController.Block(1002)
 	{
 		startTime := time.Now()
-		_, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil)
+		_, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil, PutOptions{})
 		require.Error(t, err)
 
 		duration := time.Since(startTime)
@@ -299,7 +305,7 @@ func TestAccessStreamPutQuorum(t *testing.T) {
 			vuidController.Break(id)
 		}
 
-		_, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil)
+		_, err := streamer.Put(ctx(), bytes.NewReader(buff), int64(size), nil, PutOptions{})
 		if cs.hasError {
 			require.NotNil(t, err)
 		} else {
@@ -338,7 +344,7 @@ func BenchmarkAccessStreamPut(b *testing.B) {
 		b.ResetTimer()
 		b.Run(cs.name, func(b *testing.B) {
 			for ii := 0; ii <= b.N; ii++ {
-				streamer.Put(ctx, bytes.NewReader(buff[:cs.size]), int64(cs.size), nil)
+				streamer.Put(ctx, bytes.NewReader(buff[:cs.size]), int64(cs.size), nil, PutOptions{})
 			}
 		})
 	}
@@ -31,12 +31,17 @@ import (
 //     required: clusterID VolumeID BlobID
 //     required: size, one blob size
 //     optional: hasherMap, computing hash
+//     optional: opts, expiry of the blob
 func (h *Handler) PutAt(ctx context.Context, rc io.Reader,
 	clusterID proto.ClusterID, vid proto.Vid, bid proto.BlobID, size int64,
-	hasherMap access.HasherMap) error {
+	hasherMap access.HasherMap, opts PutOptions) error {
 	span := trace.SpanFromContextSafe(ctx)
-	span.Debugf("putat request cluster:%d vid:%d bid:%d size:%d hashes:b(%b)",
-		clusterID, vid, bid, size, hasherMap.ToHashAlgorithm())
+	span.Debugf("putat request cluster:%d vid:%d bid:%d size:%d hashes:b(%b) expire_at:%d",
+		clusterID, vid, bid, size, hasherMap.ToHashAlgorithm(), opts.ExpireAt)
+
+	if opts.ExpireAt < 0 {
+		return errcode.ErrIllegalArguments
+	}
 
 	if len(hasherMap) > 0 {
 		rc = io.TeeReader(rc, hasherMap.ToWriter())
@@ -89,7 +94,7 @@ func (h *Handler) PutAt(ctx context.Context, rc io.Reader,
 	takeoverBuffer := buffer
 	buffer = nil
 	startWrite := time.Now()
-	err = h.writeToBlobnodesWithHystrix(ctx, blobident, shards, func() {
+	err = h.writeToBlobnodesWithHystrix(ctx, blobident, shards, opts.ExpireAt, func() {
 		takeoverBuffer.Release()
 	})
 	putTime.IncW(time.Since(startWrite))
@@ -19,6 +19,7 @@ import (
 	"crypto/rand"
 	"hash/crc32"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -34,7 +35,7 @@ func TestAccessStreamPutAtBase(t *testing.T) {
 			access.HashAlgCRC32: access.HashAlgCRC32.ToHasher(),
 		}
 		hashSumMap := make(access.HashSumMap, len(hasherMap))
-		err := streamer.PutAt(ctx(), bytes.NewReader(data), clusterID, 1, 10000, int64(size), hasherMap)
+		err := streamer.PutAt(ctx(), bytes.NewReader(data), clusterID, 1, 10000, int64(size), hasherMap, PutOptions{})
 		require.Nil(t, err)
 		for alg, hasher := range hasherMap {
 			hashSumMap[alg] = hasher.Sum(nil)
@@ -44,7 +45,7 @@ func TestAccessStreamPutAtBase(t *testing.T) {
 	// 0
 	{
 		size := 0
-		err := streamer.PutAt(ctx(), newReader(size), clusterID, 1, 10000, int64(size), nil)
+		err := streamer.PutAt(ctx(), newReader(size), clusterID, 1, 10000, int64(size), nil, PutOptions{})
 		require.NotNil(t, err)
 	}
 	// 1 byte
@@ -88,10 +89,24 @@ func TestAccessStreamPutAtBase(t *testing.T) {
 	{
 		dataShards.clean()
 		size := 1 << 22
-		err := streamer.PutAt(ctx(), newReader(size), clusterID, 1, 10000, int64(size)+1, nil)
+		err := streamer.PutAt(ctx(), newReader(size), clusterID, 1, 10000, int64(size)+1, nil, PutOptions{})
 		require.NotNil(t, err)
 		require.Equal(t, 0, len(dataShards.get(1001, 10000)))
 	}
+	// with expiry
+	{
+		dataShards.clean()
+		size := 1024
+		expireAt := time.Now().Unix() + 3600
+		err := streamer.PutAt(ctx(), newReader(size), clusterID, 1, 10000, int64(size), nil, PutOptions{ExpireAt: expireAt})
+		require.NoError(t, err)
+		require.Equal(t, expireAt, dataShards.getExpire(1001, 10000))
+		require.Equal(t, expireAt, dataShards.getExpire(1007, 10000))
+
+		err = streamer.PutAt(ctx(), newReader(size), clusterID, 1, 10000, int64(size), nil, PutOptions{})
+		require.NoError(t, err)
+		require.Equal(t, int64(0), dataShards.getExpire(1001, 10000))
+	}
 
 	dataShards.clean()
 }
@@ -0,0 +1,495 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"golang.org/x/time/rate"
+
+	errcode "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+const (
+	_tenantConsulPath        = "ebs/%s/tenant/"
+	_tenantDeletedConsulPath = "ebs/%s/tenant_deleted/"
+	_tenantExpireConsulPath  = "ebs/%s/tenant_expire/"
+
+	defaultTenantReloadIntervalS = 30
+	defaultTenantSyncIntervalS   = 10
+)
+
+// TenantConfig per-tenant limits and stored bytes quota
+//
+// tenant of request is the auth tenant header, signed in auth token
+// if auth enabled, requests without tenant are rejected if enabled.
+// limits of tenants are loaded from clustermgr config of key tenant_limit,
+// requests of tenant without limit are not limited.
+// stored bytes are accounted to the tenant recorded as owner in location
+// when data is written, and released by the owner when deleted,
+// deleted blobs are marked in tombstones so that they are released only once.
+// bytes of expiring data are accounted too, and released when expired
+// unless deleted before, data with expiry is never put by /putat.
+//   {"tenant-a": {"rps": 100, "reader_mbps": 64, "writer_mbps": 64, "quota_bytes": 1099511627776}}
+type TenantConfig struct {
+	Enable bool `json:"enable"`
+	// Node unique name of this access node in usage accounting
+	Node string `json:"node"`
+	// ReloadIntervalS interval of reloading limits of tenants
+	ReloadIntervalS int `json:"reload_interval_s"`
+	// SyncIntervalS interval of saving and loading stored bytes of tenants
+	SyncIntervalS int `json:"sync_interval_s"`
+}
+
+// TenantLimit limits of one tenant
+type TenantLimit struct {
+	Rps        int   `json:"rps"`         // request n/s
+	ReaderMBps int   `json:"reader_mbps"` // read from client with MB/s
+	WriterMBps int   `json:"writer_mbps"` // write to client with MB/s
+	QuotaBytes int64 `json:"quota_bytes"` // stored bytes quota
+}
+
+// TenantStatus running status of one tenant
+type TenantStatus struct {
+	Limit     TenantLimit `json:"limit"`
+	UsedBytes int64       `json:"used_bytes"`
+}
+
+// tenantExpiring stored bytes of the blob of tenant expiring at
+type tenantExpiring struct {
+	Tenant   string `json:"-"`
+	Blob     string `json:"-"`
+	ExpireAt int64  `json:"expire_at"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// tenantUsageStore persistent storage of stored bytes of tenants,
+// every access node saves its own bytes, bytes of tenant is the sum.
+type tenantUsageStore interface {
+	// Load returns stored bytes of tenants by node
+	Load(ctx context.Context) (map[string]map[string]int64, error)
+	// Save saves stored bytes of the tenant by the node
+	Save(ctx context.Context, tenant, node string, bytes int64) error
+	// Tombstone marks the blob of the tenant deleted by all nodes,
+	// returns false if it has been marked already.
+	Tombstone(ctx context.Context, tenant, blob string) (bool, error)
+	// Expire records expiring bytes of the blob of the tenant
+	Expire(ctx context.Context, expiring tenantExpiring) error
+	// Expiring returns all recorded expiring blobs
+	Expiring(ctx context.Context) ([]tenantExpiring, error)
+	// Unexpire removes the record of expiring blob
+	Unexpire(ctx context.Context, tenant, blob string) error
+}
+
+type consulTenantUsageStore struct {
+	path        string
+	deletedPath string
+	expirePath  string
+	kv          *api.KV
+}
+
+func newConsulTenantUsageStore(client *api.Client, region string) tenantUsageStore {
+	return &consulTenantUsageStore{
+		path:        fmt.Sprintf(_tenantConsulPath, region),
+		deletedPath: fmt.Sprintf(_tenantDeletedConsulPath, region),
+		expirePath:  fmt.Sprintf(_tenantExpireConsulPath, region),
+		kv:          client.KV(),
+	}
+}
+
+func (s *consulTenantUsageStore) Load(ctx context.Context) (map[string]map[string]int64, error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	pairs, _, err := s.kv.List(s.path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]map[string]int64)
+	for _, pair := range pairs {
+		keys := strings.SplitN(strings.TrimPrefix(pair.Key, s.path), "/", 2)
+		if len(keys) != 2 {
+			continue
+		}
+		bytes, err := strconv.ParseInt(string(pair.Value), 10, 64)
+		if err != nil {
+			span.Warnf("decode tenant usage failed, key:%s raw:%s, error:%s", pair.Key, string(pair.Value), err.Error())
+			continue
+		}
+		if usage[keys[0]] == nil {
+			usage[keys[0]] = make(map[string]int64)
+		}
+		usage[keys[0]][keys[1]] = bytes
+	}
+	return usage, nil
+}
+
+func (s *consulTenantUsageStore) Save(ctx context.Context, tenant, node string, bytes int64) error {
+	_, err := s.kv.Put(&api.KVPair{
+		Key:   s.path + tenant + "/" + node,
+		Value: []byte(strconv.FormatInt(bytes, 10)),
+	}, nil)
+	return err
+}
+
+func (s *consulTenantUsageStore) Tombstone(ctx context.Context, tenant, blob string) (bool, error) {
+	// modify index 0 puts the key only if it does not exist
+	ok, _, err := s.kv.CAS(&api.KVPair{
+		Key:   s.deletedPath + tenant + "/" + blob,
+		Value: []byte(strconv.FormatInt(time.Now().Unix(), 10)),
+	}, nil)
+	return ok, err
+}
+
+func (s *consulTenantUsageStore) Expire(ctx context.Context, expiring tenantExpiring) error {
+	val, err := json.Marshal(expiring)
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.Put(&api.KVPair{Key: s.expirePath + expiring.Tenant + "/" + expiring.Blob, Value: val}, nil)
+	return err
+}
+
+func (s *consulTenantUsageStore) Expiring(ctx context.Context) ([]tenantExpiring, error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	pairs, _, err := s.kv.List(s.expirePath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	expiring := make([]tenantExpiring, 0, len(pairs))
+	for _, pair := range pairs {
+		keys := strings.SplitN(strings.TrimPrefix(pair.Key, s.expirePath), "/", 2)
+		if len(keys) != 2 {
+			continue
+		}
+		e := tenantExpiring{Tenant: keys[0], Blob: keys[1]}
+		if err = json.Unmarshal(pair.Value, &e); err != nil {
+			span.Warnf("decode tenant expiring failed, key:%s raw:%s, error:%s", pair.Key, string(pair.Value), err.Error())
+			continue
+		}
+		expiring = append(expiring, e)
+	}
+	return expiring, nil
+}
+
+func (s *consulTenantUsageStore) Unexpire(ctx context.Context, tenant, blob string) error {
+	_, err := s.kv.Delete(s.expirePath+tenant+"/"+blob, nil)
+	return err
+}
+
+type tenantLimit struct {
+	config TenantLimit
+	rps    *rate.Limiter
+	reader *rate.Limiter
+	writer *rate.Limiter
+}
+
+func newTenantLimit(cfg TenantLimit) *tenantLimit {
+	mb := 1 << 20
+	l := &tenantLimit{config: cfg}
+	if cfg.Rps > 0 {
+		l.rps = rate.NewLimiter(rate.Limit(cfg.Rps), cfg.Rps)
+	}
+	if cfg.ReaderMBps > 0 {
+		l.reader = rate.NewLimiter(rate.Limit(cfg.ReaderMBps*mb), 2*cfg.ReaderMBps*mb)
+	}
+	if cfg.WriterMBps > 0 {
+		l.writer = rate.NewLimiter(rate.Limit(cfg.WriterMBps*mb), 2*cfg.WriterMBps*mb)
+	}
+	return l
+}
+
+// tenantLimiter limits requests and accounts stored bytes of tenants
+type tenantLimiter struct {
+	node  string
+	store tenantUsageStore
+
+	mu     sync.RWMutex
+	limits map[string]*tenantLimit
+	used   map[string]int64 // stored bytes of all nodes
+	local  map[string]int64 // stored bytes of this node
+	dirty  map[string]struct{}
+	loaded bool
+}
+
+func newTenantLimiter(node string, store tenantUsageStore) *tenantLimiter {
+	return &tenantLimiter{
+		node:   node,
+		store:  store,
+		limits: make(map[string]*tenantLimit),
+		used:   make(map[string]int64),
+		local:  make(map[string]int64),
+		dirty:  make(map[string]struct{}),
+	}
+}
+
+// Update updates limits of tenants, limiter is kept if its limit not changed
+func (t *tenantLimiter) Update(limits map[string]TenantLimit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	newLimits := make(map[string]*tenantLimit, len(limits))
+	for tenant, cfg := range limits {
+		if l, ok := t.limits[tenant]; ok && l.config == cfg {
+			newLimits[tenant] = l
+			continue
+		}
+		newLimits[tenant] = newTenantLimit(cfg)
+	}
+	t.limits = newLimits
+}
+
+// Reload reloads limits of tenants from cluster config
+func (t *tenantLimiter) Reload(ctx context.Context, getConfig func(context.Context, string) (string, error)) error {
+	val, err := getConfig(ctx, proto.TenantLimitConfigKey)
+	if err != nil {
+		return err
+	}
+	limits := make(map[string]TenantLimit)
+	if err = json.Unmarshal([]byte(val), &limits); err != nil {
+		return err
+	}
+	t.Update(limits)
+	return nil
+}
+
+func (t *tenantLimiter) limit(tenant string) *tenantLimit {
+	t.mu.RLock()
+	l := t.limits[tenant]
+	t.mu.RUnlock()
+	return l
+}
+
+// Acquire acquires one request of the tenant
+func (t *tenantLimiter) Acquire(tenant string) error {
+	if l := t.limit(tenant); l != nil && l.rps != nil && !l.rps.Allow() {
+		return errcode.ErrAccessLimited
+	}
+	return nil
+}
+
+// Reader returns io.Reader with bandwidth rate limit of the tenant
+func (t *tenantLimiter) Reader(ctx context.Context, tenant string, r io.Reader) io.Reader {
+	if l := t.limit(tenant); l != nil && l.reader != nil {
+		return &Reader{ctx: ctx, rate: l.reader, underlying: r}
+	}
+	return r
+}
+
+// Writer returns io.Writer with bandwidth rate limit of the tenant
+func (t *tenantLimiter) Writer(ctx context.Context, tenant string, w io.Writer) io.Writer {
+	if l := t.limit(tenant); l != nil && l.writer != nil {
+		return &Writer{ctx: ctx, rate: l.writer, underlying: w}
+	}
+	return w
+}
+
+// CheckQuota returns error if stored bytes of the tenant will exceed quota
+func (t *tenantLimiter) CheckQuota(tenant string, size int64) error {
+	l := t.limit(tenant)
+	if l == nil || l.config.QuotaBytes <= 0 {
+		return nil
+	}
+	t.mu.RLock()
+	used := t.used[tenant]
+	t.mu.RUnlock()
+	if used+size > l.config.QuotaBytes {
+		return errcode.ErrAccessExceedQuota
+	}
+	return nil
+}
+
+// Account adds delta of stored bytes of the tenant on this node
+func (t *tenantLimiter) Account(tenant string, delta int64) {
+	if tenant == "" || delta == 0 {
+		return
+	}
+	t.mu.Lock()
+	t.used[tenant] += delta
+	t.local[tenant] += delta
+	t.dirty[tenant] = struct{}{}
+	t.mu.Unlock()
+}
+
+// Release releases stored bytes of the tenant once for the deleted blob,
+// blob is unique id of the deleted data, like cluster and the first bid of location.
+func (t *tenantLimiter) Release(ctx context.Context, tenant, blob string, bytes int64) error {
+	if tenant == "" || bytes == 0 {
+		return nil
+	}
+	first, err := t.store.Tombstone(ctx, tenant, blob)
+	if err != nil {
+		return err
+	}
+	if first {
+		t.Account(tenant, -bytes)
+	}
+	return nil
+}
+
+// Expire records stored bytes of the blob of the tenant to be released at expireAt
+func (t *tenantLimiter) Expire(ctx context.Context, tenant, blob string, expireAt, bytes int64) error {
+	if tenant == "" || bytes == 0 || expireAt == 0 {
+		return nil
+	}
+	return t.store.Expire(ctx, tenantExpiring{Tenant: tenant, Blob: blob, ExpireAt: expireAt, Bytes: bytes})
+}
+
+// ReleaseExpired releases stored bytes of blobs expired before now,
+// bytes of blobs deleted before expired have been released by the tombstone.
+func (t *tenantLimiter) ReleaseExpired(ctx context.Context, now int64) error {
+	expiring, err := t.store.Expiring(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range expiring {
+		if e.ExpireAt > now {
+			continue
+		}
+		if err = t.Release(ctx, e.Tenant, e.Blob, e.Bytes); err != nil {
+			return err
+		}
+		if err = t.store.Unexpire(ctx, e.Tenant, e.Blob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync saves stored bytes of this node and loads bytes of all nodes,
+// bytes of this node are loaded from store at the first time.
+func (t *tenantLimiter) Sync(ctx context.Context) error {
+	t.mu.RLock()
+	loaded := t.loaded
+	saving := make(map[string]int64, len(t.dirty))
+	for tenant := range t.dirty {
+		saving[tenant] = t.local[tenant]
+	}
+	t.mu.RUnlock()
+
+	if loaded {
+		for tenant, bytes := range saving {
+			if err := t.store.Save(ctx, tenant, t.node, bytes); err != nil {
+				return err
+			}
+			t.mu.Lock()
+			if t.local[tenant] == bytes {
+				delete(t.dirty, tenant)
+			}
+			t.mu.Unlock()
+		}
+	}
+
+	usage, err := t.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.loaded {
+		// accounted before loaded are deltas of this node
+		for tenant, nodes := range usage {
+			if bytes, ok := nodes[t.node]; ok {
+				t.local[tenant] += bytes
+				t.dirty[tenant] = struct{}{}
+			}
+		}
+		t.loaded = true
+	}
+
+	used := make(map[string]int64, len(usage))
+	for tenant, nodes := range usage {
+		for node, bytes := range nodes {
+			if node != t.node {
+				used[tenant] += bytes
+			}
+		}
+	}
+	for tenant, bytes := range t.local {
+		used[tenant] += bytes
+	}
+	t.used = used
+	return nil
+}
+
+// Status returns running status of tenants
+func (t *tenantLimiter) Status() map[string]TenantStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	st := make(map[string]TenantStatus, len(t.used))
+	for tenant, bytes := range t.used {
+		st[tenant] = TenantStatus{UsedBytes: bytes}
+	}
+	for tenant, l := range t.limits {
+		status := st[tenant]
+		status.Limit = l.config
+		st[tenant] = status
+	}
+	return st
+}
+
+// loopTenant reloads limits and syncs stored bytes of tenants
+func (s *Service) loopTenant(getConfig func(context.Context, string) (string, error)) {
+	reload := func() {
+		span, ctx := trace.StartSpanFromContext(context.Background(), "")
+		if err := s.tenants.Reload(ctx, getConfig); err != nil {
+			span.Warn("reload tenant limits failed", err)
+		}
+	}
+	sync := func() {
+		span, ctx := trace.StartSpanFromContext(context.Background(), "")
+		if err := s.tenants.ReleaseExpired(ctx, time.Now().Unix()); err != nil {
+			span.Warn("release expired tenant usage failed", err)
+		}
+		if err := s.tenants.Sync(ctx); err != nil {
+			span.Warn("sync tenant usage failed", err)
+		}
+	}
+	reload()
+	sync()
+
+	go func() {
+		cfg := s.config.Tenant
+		reloadTicker := time.NewTicker(time.Duration(cfg.ReloadIntervalS) * time.Second)
+		defer reloadTicker.Stop()
+		syncTicker := time.NewTicker(time.Duration(cfg.SyncIntervalS) * time.Second)
+		defer syncTicker.Stop()
+
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-reloadTicker.C:
+				reload()
+			case <-syncTicker.C:
+				sync()
+			}
+		}
+	}()
+}
@@ -0,0 +1,316 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/api/access"
+	errcode "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/rpc"
+	"github.com/cubefs/blobstore/common/rpc/auth"
+)
+
+type memTenantUsageStore struct {
+	mu       sync.Mutex
+	usage    map[string]map[string]int64
+	deleted  map[string]struct{}
+	expiring map[string]tenantExpiring
+}
+
+func newMemTenantUsageStore() *memTenantUsageStore {
+	return &memTenantUsageStore{
+		usage:    make(map[string]map[string]int64),
+		deleted:  make(map[string]struct{}),
+		expiring: make(map[string]tenantExpiring),
+	}
+}
+
+func (s *memTenantUsageStore) Load(ctx context.Context) (map[string]map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage := make(map[string]map[string]int64, len(s.usage))
+	for tenant, nodes := range s.usage {
+		usage[tenant] = make(map[string]int64, len(nodes))
+		for node, bytes := range nodes {
+			usage[tenant][node] = bytes
+		}
+	}
+	return usage, nil
+}
+
+func (s *memTenantUsageStore) Save(ctx context.Context, tenant, node string, bytes int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.usage[tenant] == nil {
+		s.usage[tenant] = make(map[string]int64)
+	}
+	s.usage[tenant][node] = bytes
+	return nil
+}
+
+func (s *memTenantUsageStore) Tombstone(ctx context.Context, tenant, blob string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := tenant + "/" + blob
+	if _, ok := s.deleted[key]; ok {
+		return false, nil
+	}
+	s.deleted[key] = struct{}{}
+	return true, nil
+}
+
+func (s *memTenantUsageStore) Expire(ctx context.Context, expiring tenantExpiring) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expiring[expiring.Tenant+"/"+expiring.Blob] = expiring
+	return nil
+}
+
+func (s *memTenantUsageStore) Expiring(ctx context.Context) ([]tenantExpiring, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiring := make([]tenantExpiring, 0, len(s.expiring))
+	for _, e := range s.expiring {
+		expiring = append(expiring, e)
+	}
+	return expiring, nil
+}
+
+func (s *memTenantUsageStore) Unexpire(ctx context.Context, tenant, blob string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expiring, tenant+"/"+blob)
+	return nil
+}
+
+func TestAccessTenantLimit(t *testing.T) {
+	tenants := newTenantLimiter("node", newMemTenantUsageStore())
+	require.NoError(t, tenants.Acquire("foo"))
+
+	getConfig := func(val string, err error) func(context.Context, string) (string, error) {
+		return func(_ context.Context, key string) (string, error) {
+			require.Equal(t, proto.TenantLimitConfigKey, key)
+			return val, err
+		}
+	}
+	require.Error(t, tenants.Reload(ctx, getConfig("", errors.New("not found"))))
+	require.Error(t, tenants.Reload(ctx, getConfig("{invalid json", nil)))
+	require.NoError(t, tenants.Reload(ctx, getConfig(`{"foo":{"rps":2,"reader_mbps":1},"bar":{"writer_mbps":1}}`, nil)))
+
+	require.NoError(t, tenants.Acquire("foo"))
+	require.NoError(t, tenants.Acquire("foo"))
+	require.ErrorIs(t, tenants.Acquire("foo"), errcode.ErrAccessLimited)
+	require.NoError(t, tenants.Acquire("bar"))
+	require.NoError(t, tenants.Acquire(""))
+
+	r := bytes.NewReader(nil)
+	require.IsType(t, &Reader{}, tenants.Reader(ctx, "foo", r))
+	require.Equal(t, r, tenants.Reader(ctx, "bar", r))
+	w := bytes.NewBuffer(nil)
+	require.Equal(t, w, tenants.Writer(ctx, "foo", w))
+	require.IsType(t, &Writer{}, tenants.Writer(ctx, "bar", w))
+
+	// limiter is kept if limit not changed
+	foo := tenants.limit("foo")
+	tenants.Update(map[string]TenantLimit{"foo": foo.config})
+	require.True(t, foo == tenants.limit("foo"))
+	require.Nil(t, tenants.limit("bar"))
+	tenants.Update(map[string]TenantLimit{"foo": {Rps: 3}})
+	require.False(t, foo == tenants.limit("foo"))
+}
+
+func TestAccessTenantQuota(t *testing.T) {
+	store := newMemTenantUsageStore()
+	store.Save(ctx, "foo", "node1", 100)
+
+	node1 := newTenantLimiter("node1", store)
+	node2 := newTenantLimiter("node2", store)
+	for _, tenants := range []*tenantLimiter{node1, node2} {
+		tenants.Update(map[string]TenantLimit{"foo": {QuotaBytes: 1000}})
+	}
+
+	// accounted before loaded
+	node1.Account("foo", 10)
+	node1.Account("", 10)
+	require.NoError(t, node1.Sync(ctx))
+	require.Equal(t, int64(110), node1.Status()["foo"].UsedBytes)
+	require.NoError(t, node1.Sync(ctx))
+	require.Equal(t, int64(110), store.usage["foo"]["node1"])
+
+	node2.Account("foo", 500)
+	require.NoError(t, node2.Sync(ctx))
+	require.NoError(t, node2.Sync(ctx))
+	require.NoError(t, node1.Sync(ctx))
+	for _, tenants := range []*tenantLimiter{node1, node2} {
+		require.Equal(t, int64(610), tenants.Status()["foo"].UsedBytes)
+		require.Equal(t, int64(1000), tenants.Status()["foo"].Limit.QuotaBytes)
+		require.NoError(t, tenants.CheckQuota("foo", 390))
+		require.ErrorIs(t, tenants.CheckQuota("foo", 391), errcode.ErrAccessExceedQuota)
+		require.NoError(t, tenants.CheckQuota("bar", 1<<40))
+	}
+
+	node2.Account("foo", -500)
+	require.NoError(t, node2.CheckQuota("foo", 500))
+	require.ErrorIs(t, node1.CheckQuota("foo", 500), errcode.ErrAccessExceedQuota)
+	require.NoError(t, node2.Sync(ctx))
+	require.NoError(t, node1.Sync(ctx))
+	require.NoError(t, node1.CheckQuota("foo", 500))
+}
+
+func TestAccessTenantExpire(t *testing.T) {
+	store := newMemTenantUsageStore()
+	tenants := newTenantLimiter("node", store)
+	tenants.Account("foo", 300)
+	require.NoError(t, tenants.Expire(ctx, "foo", "1-1", 100, 100))
+	require.NoError(t, tenants.Expire(ctx, "foo", "1-2", 100, 100))
+	require.NoError(t, tenants.Expire(ctx, "foo", "1-3", 200, 100))
+	require.NoError(t, tenants.Expire(ctx, "foo", "1-4", 0, 100))
+	require.Equal(t, 3, len(store.expiring))
+
+	// deleted before expired
+	require.NoError(t, tenants.Release(ctx, "foo", "1-1", 100))
+	require.Equal(t, int64(200), tenants.Status()["foo"].UsedBytes)
+
+	require.NoError(t, tenants.ReleaseExpired(ctx, 99))
+	require.Equal(t, int64(200), tenants.Status()["foo"].UsedBytes)
+	require.NoError(t, tenants.ReleaseExpired(ctx, 100))
+	require.Equal(t, int64(100), tenants.Status()["foo"].UsedBytes)
+	require.Equal(t, 1, len(store.expiring))
+
+	// deleted after expired
+	require.NoError(t, tenants.Release(ctx, "foo", "1-2", 100))
+	require.Equal(t, int64(100), tenants.Status()["foo"].UsedBytes)
+	require.NoError(t, tenants.ReleaseExpired(ctx, 200))
+	require.Equal(t, int64(0), tenants.Status()["foo"].UsedBytes)
+	require.Equal(t, 0, len(store.expiring))
+}
+
+func TestAccessTenantService(t *testing.T) {
+	s := newService()
+	store := newMemTenantUsageStore()
+	s.tenants = newTenantLimiter("node", store)
+	s.tenants.Update(map[string]TenantLimit{
+		"foo": {QuotaBytes: 4096},
+		"bar": {Rps: 1},
+	})
+
+	rpc.RegisterArgsParser(&access.PutArgs{}, "json")
+	rpc.RegisterArgsParser(&access.PutAtArgs{}, "json")
+	rpc.RegisterArgsParser(&access.DeleteBlobArgs{}, "json")
+	router := rpc.New()
+	router.Use(s.Limit)
+	router.Handle(http.MethodPost, "/put", s.Put, rpc.OptArgsQuery())
+	router.Handle(http.MethodPost, "/alloc", s.Alloc, rpc.OptArgsBody())
+	router.Handle(http.MethodPost, "/putat", s.PutAt, rpc.OptArgsQuery())
+	router.Handle(http.MethodPost, "/delete", s.Delete, rpc.OptArgsBody())
+	router.Handle(http.MethodDelete, "/deleteblob", s.DeleteBlob, rpc.OptArgsQuery())
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	newTenantClient := func(tenant string) rpc.Client {
+		return rpc.NewClient(&rpc.Config{Tc: rpc.TransportConfig{Auth: auth.Config{Tenant: tenant}}})
+	}
+	put := func(cli rpc.Client, size int64) (access.Location, error) {
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/put?size=%d", server.URL, size),
+			bytes.NewReader(make([]byte, size)))
+		resp := &access.PutResp{}
+		err := cli.DoWith(ctx, req, resp)
+		return resp.Location, err
+	}
+	blobRequest := func(cli rpc.Client, method, path string, loc access.Location, token string) error {
+		blobs := loc.Spread()
+		blob := blobs[len(blobs)-1]
+		req, _ := http.NewRequest(method, fmt.Sprintf("%s/%s?clusterid=%d&volumeid=%d&blobid=%d&size=%d&token=%s",
+			server.URL, path, loc.ClusterID, blob.Vid, blob.Bid, blob.Size, token),
+			bytes.NewReader(make([]byte, blob.Size)))
+		return cli.DoWith(ctx, req, nil)
+	}
+	usedBytes := func(tenant string) int64 {
+		return s.tenants.Status()[tenant].UsedBytes
+	}
+
+	// tenant is required
+	_, err := put(newClient(), 1024)
+	assertErrorCode(t, errcode.CodeAccessNoTenant, err)
+
+	foo := newTenantClient("foo")
+	loc, err := put(foo, 2048)
+	require.NoError(t, err)
+	require.Equal(t, "foo", loc.Tenant)
+	require.True(t, verifyCrc(&loc))
+	_, err = put(foo, 2048)
+	require.NoError(t, err)
+	_, err = put(foo, 1024)
+	assertErrorCode(t, errcode.CodeAccessExceedQuota, err)
+
+	// stored bytes are released by owner of the location
+	other := newTenantClient("other")
+	require.NoError(t, other.PostWith(ctx, server.URL+"/delete", nil,
+		access.DeleteArgs{Locations: []access.Location{loc}}))
+	require.Equal(t, int64(2048), usedBytes("foo"))
+	require.Equal(t, int64(0), usedBytes("other"))
+	// repeated delete releases nothing
+	require.NoError(t, foo.PostWith(ctx, server.URL+"/delete", nil,
+		access.DeleteArgs{Locations: []access.Location{loc}}))
+	require.Equal(t, int64(2048), usedBytes("foo"))
+	_, err = put(foo, 1024)
+	require.NoError(t, err)
+	require.Equal(t, int64(3072), usedBytes("foo"))
+
+	// allocated location is accounted when blobs written
+	alloc := &access.AllocResp{}
+	require.NoError(t, other.PostWith(ctx, server.URL+"/alloc", alloc, access.AllocArgs{Size: 2048}))
+	require.Equal(t, "other", alloc.Location.Tenant)
+	require.Equal(t, int64(0), usedBytes("other"))
+	// token is valid only for the tenant which allocated
+	err = blobRequest(foo, http.MethodPost, "putat", alloc.Location, alloc.Tokens[0])
+	assertErrorCode(t, 400, err)
+	require.NoError(t, blobRequest(other, http.MethodPost, "putat", alloc.Location, alloc.Tokens[0]))
+	require.Equal(t, int64(2048), usedBytes("other"))
+	require.NoError(t, blobRequest(other, http.MethodDelete, "deleteblob", alloc.Location, alloc.Tokens[0]))
+	require.Equal(t, int64(0), usedBytes("other"))
+	require.NoError(t, blobRequest(other, http.MethodDelete, "deleteblob", alloc.Location, alloc.Tokens[0]))
+	require.Equal(t, int64(0), usedBytes("other"))
+	require.Equal(t, int64(3072), usedBytes("foo"))
+
+	// expiring bytes are accounted and released when expired
+	blob := alloc.Location.Spread()[0]
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/putat?clusterid=%d&volumeid=%d&blobid=%d&size=%d&token=%s&expire_at=100",
+		server.URL, alloc.Location.ClusterID, blob.Vid, blob.Bid, blob.Size, alloc.Tokens[0]), bytes.NewReader(make([]byte, blob.Size)))
+	assertErrorCode(t, 400, other.DoWith(ctx, req, nil))
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/put?size=1024&expire_at=100", bytes.NewReader(make([]byte, 1024)))
+	require.NoError(t, other.DoWith(ctx, req, &access.PutResp{}))
+	require.Equal(t, int64(1024), usedBytes("other"))
+	require.Equal(t, 1, len(store.expiring))
+	require.NoError(t, s.tenants.ReleaseExpired(ctx, 100))
+	require.Equal(t, int64(0), usedBytes("other"))
+
+	bar := newTenantClient("bar")
+	_, err = put(bar, 1024)
+	require.NoError(t, err)
+	_, err = put(bar, 1024)
+	assertErrorCode(t, errcode.CodeAccessLimited, err)
+}
@@ -163,6 +163,13 @@ type Config struct {
 	// PartConcurrence concurrence of put parts
 	PartConcurrence int
 
+	// DirectRead client-side erasure-coded direct read config
+	DirectRead DirectReadConfig
+
+	// Tenant identity of the client, access limits and accounts by tenant.
+	// It is signed in auth token if auth of rpc config enabled.
+	Tenant string
+
 	// RPCConfig user-defined rpc config
 	// All connections will use the config if it's not nil
 	// ConnMode will be ignored if rpc config is setting
@@ -188,19 +195,36 @@ type client struct {
 	consulClient *api.Client
 	selector     selector.Selector
 	rpcClient    rpc.Client
+	direct       *directReader
 }
 
 // API access api for s3
 // To trace request id, the ctx is better WithRequestID(ctx, rid).
 type API interface {
-	// Put object once if size is not greater than MaxSizePutOnce, otherwise put blobs one by one.
+	// Put object once if size is not greater than MaxSizePutOnce, otherwise put blobs one by one,
+	// or put parts by multipart upload if access encrypts data or the object expires.
 	// return a location and map of hash summary bytes you excepted.
 	Put(ctx context.Context, args *PutArgs) (location Location, hashSumMap HashSumMap, err error)
 	// Get object, range is supported.
 	Get(ctx context.Context, args *GetArgs) (body io.ReadCloser, err error)
+	// GetRanges get multiple ranges of object in one request,
+	// parts of body are sorted by offset, overlapping or adjacent ranges are merged.
+	GetRanges(ctx context.Context, args *GetRangesArgs) (body *RangesBody, err error)
 	// Delete all blobs in these locations.
 	// return failed locations which have yet been deleted if error is not nil.
 	Delete(ctx context.Context, args *DeleteArgs) (failedLocations []Location, err error)
+
+	// MultipartInit initiate a multipart upload with size of the whole object,
+	// return upload id, put parts with number in [1, PartCount] then.
+	MultipartInit(ctx context.Context, args *MultipartInitArgs) (resp MultipartInitResp, err error)
+	// MultipartPut put one part of the upload, retry the part if failed.
+	MultipartPut(ctx context.Context, args *MultipartPutArgs) (hashSumMap HashSumMap, err error)
+	// MultipartList list uploaded parts, resume the upload by putting the missing parts.
+	MultipartList(ctx context.Context, uploadID string) (resp MultipartListResp, err error)
+	// MultipartComplete complete the upload, return location of the whole object.
+	MultipartComplete(ctx context.Context, uploadID string) (location Location, err error)
+	// MultipartAbort abort the upload and delete all uploaded parts.
+	MultipartAbort(ctx context.Context, uploadID string) error
 }
 
 var _ API = (*client)(nil)
@@ -257,10 +281,15 @@ func New(cfg Config) (API, error) {
 
 	var rpcClient rpc.Client
 	if cfg.RPCConfig != nil {
-		rpcClient = rpc.NewClient(cfg.RPCConfig)
+		rpcConfig := *cfg.RPCConfig
+		if rpcConfig.Tc.Auth.Tenant == "" {
+			rpcConfig.Tc.Auth.Tenant = cfg.Tenant
+		}
+		rpcClient = rpc.NewClient(&rpcConfig)
 	} else {
 		rpcConfig := cfg.ConnMode.getConfig(cfg.BodyBandwidthMBPs,
 			cfg.ClientTimeoutMs, cfg.BodyBaseTimeoutMs)
+		rpcConfig.Tc.Auth.Tenant = cfg.Tenant
 		rpcClient = rpc.NewClient(&rpcConfig)
 	}
 
@@ -310,11 +339,17 @@ func New(cfg Config) (API, error) {
 		return nil, errcode.ErrAccessServiceDiscovery
 	}
 
+	var direct *directReader
+	if cfg.DirectRead.Enable {
+		direct = newDirectReader(cfg.DirectRead, consulClient)
+	}
+
 	return &client{
 		config:       cfg,
 		consulClient: consulClient,
 		selector:     hostSelector,
 		rpcClient:    rpcClient,
+		direct:       direct,
 	}, nil
 }
 
@@ -331,7 +366,16 @@ func (c *client) Put(ctx context.Context, args *PutArgs) (location Location, has
 	if args.Size <= c.config.MaxSizePutOnce {
 		return c.putObject(ctx, args)
 	}
-	return c.putParts(ctx, args)
+	// expiry of object is accounted at location level rather than blobs
+	if args.ExpireAt > 0 {
+		return c.putMultipart(ctx, args)
+	}
+	location, hashSumMap, err = c.putParts(ctx, args)
+	// access with encryption rejects allocating before the body was read
+	if rpc.DetectStatusCode(err) == errcode.CodeAccessEncrypted {
+		return c.putMultipart(ctx, args)
+	}
+	return
 }
 
 func (c *client) putObject(ctx context.Context, args *PutArgs) (location Location, hashSumMap HashSumMap, err error) {
@@ -373,6 +417,15 @@ func (c *client) putObject(ctx context.Context, args *PutArgs) (location Locatio
 		}
 
 		urlStr := fmt.Sprintf("%s/put?size=%d&hashes=%d", host, args.Size, args.Hashes)
+		if args.Compression != CompressNone {
+			urlStr += fmt.Sprintf("&compression=%d", args.Compression)
+		}
+		if args.ContentSHA256 != "" {
+			urlStr += "&content_sha256=" + args.ContentSHA256
+		}
+		if args.ExpireAt > 0 {
+			urlStr += fmt.Sprintf("&expire_at=%d", args.ExpireAt)
+		}
 		req, e := http.NewRequest(http.MethodPut, urlStr, body)
 		if e != nil {
 			return e
@@ -397,6 +450,8 @@ type blobPart struct {
 	index int
 	token string
 	buf   []byte
+
+	expireAt int64
 }
 
 func (c *client) putPartsBatch(ctx context.Context, parts []blobPart) error {
@@ -407,6 +462,9 @@ func (c *client) putPartsBatch(ctx context.Context, parts []blobPart) error {
 			return c.tryN(ctx, c.config.MaxHostRetry, func(host string) error {
 				urlStr := fmt.Sprintf("%s/putat?clusterid=%d&volumeid=%d&blobid=%d&size=%d&hashes=%d&token=%s",
 					host, part.cid, part.vid, part.bid, part.size, 0, part.token)
+				if part.expireAt > 0 {
+					urlStr += fmt.Sprintf("&expire_at=%d", part.expireAt)
+				}
 				req, err := http.NewRequest(http.MethodPut, urlStr, bytes.NewReader(part.buf))
 				if err != nil {
 					return err
@@ -606,6 +664,7 @@ func (c *client) putParts(ctx context.Context, args *PutArgs) (Location, HashSum
 				parts[i].cid = loc.ClusterID
 				parts[i].vid = loc.Blobs[currIdx].Vid
 				parts[i].bid = loc.Blobs[currIdx].MinBid + proto.BlobID(currCount)
+				parts[i].expireAt = args.ExpireAt
 
 				currCount++
 				if loc.Blobs[currIdx].Count == currCount {
@@ -717,6 +776,15 @@ func (c *client) Get(ctx context.Context, args *GetArgs) (body io.ReadCloser, er
 		return noopBody{}, nil
 	}
 
+	// compressed or encrypted location is decoded in access
+	if c.direct != nil && args.Location.Compression == nil && args.Location.Encryption == nil &&
+		args.ReadSize >= c.config.DirectRead.MinReadSize {
+		return c.direct.Get(ctx, args, c.getFromAccess), nil
+	}
+	return c.getFromAccess(ctx, args)
+}
+
+func (c *client) getFromAccess(ctx context.Context, args *GetArgs) (body io.ReadCloser, err error) {
 	err = c.tryN(ctx, c.config.MaxHostRetry, func(host string) error {
 		gotBody, e := func() (io.ReadCloser, error) {
 			resp, e := c.rpcClient.Post(ctx, fmt.Sprintf("%s/get", host), args)
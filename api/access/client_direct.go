@@ -0,0 +1,436 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/api/clustermgr"
+	"github.com/cubefs/blobstore/common/codemode"
+	"github.com/cubefs/blobstore/common/ec"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+const defaultDirectVolumeExpireS = 600
+
+// DirectReadConfig client-side erasure-coded direct read config.
+// Reads shards from blobnodes directly and reconstructs locally,
+// falls back to access service on any error.
+// Use it only in trusted networks, location crc is not verified.
+type DirectReadConfig struct {
+	// Enable direct read if read size is not less than MinReadSize
+	Enable      bool
+	MinReadSize uint64
+	// Region discovers cluster managers of clusters from consul
+	Region string
+	// ClusterMgrHosts hosts of cluster managers, has priority over discovering
+	ClusterMgrHosts map[proto.ClusterID][]string
+	// VolumeExpireS expiration seconds of cached volume units
+	VolumeExpireS int
+	// ClusterMgrConfig cluster manager client config, hosts will be overwritten
+	ClusterMgrConfig clustermgr.Config
+	// BlobnodeConfig blobnode client config
+	BlobnodeConfig blobnode.Config
+}
+
+type directVolumeKey struct {
+	cid proto.ClusterID
+	vid proto.Vid
+}
+
+type directVolume struct {
+	*clustermgr.VolumeInfo
+	expiration time.Time
+}
+
+// directBlob one blob to read in direct mode
+type directBlob struct {
+	Vid      proto.Vid
+	Bid      proto.BlobID
+	BlobSize uint64
+	Offset   uint64
+	ReadSize uint64
+	CodeMode codemode.CodeMode
+
+	VerifyCrc bool
+	BlobCrc   uint32
+}
+
+type directReader struct {
+	config         DirectReadConfig
+	consulClient   *api.Client
+	blobnodeClient blobnode.StorageAPI
+
+	lock     sync.Mutex
+	clusters map[proto.ClusterID]clustermgr.APIAccess
+	volumes  map[directVolumeKey]directVolume
+	encoders map[codemode.CodeMode]ec.Encoder
+}
+
+func newDirectReader(cfg DirectReadConfig, consulClient *api.Client) *directReader {
+	if cfg.VolumeExpireS <= 0 {
+		cfg.VolumeExpireS = defaultDirectVolumeExpireS
+	}
+	return &directReader{
+		config:         cfg,
+		consulClient:   consulClient,
+		blobnodeClient: blobnode.New(&cfg.BlobnodeConfig),
+
+		clusters: make(map[proto.ClusterID]clustermgr.APIAccess),
+		volumes:  make(map[directVolumeKey]directVolume),
+		encoders: make(map[codemode.CodeMode]ec.Encoder),
+	}
+}
+
+func (r *directReader) clusterMgr(clusterID proto.ClusterID) (clustermgr.APIAccess, error) {
+	r.lock.Lock()
+	cmClient, ok := r.clusters[clusterID]
+	r.lock.Unlock()
+	if ok {
+		return cmClient, nil
+	}
+
+	hosts := r.config.ClusterMgrHosts[clusterID]
+	if len(hosts) == 0 {
+		if r.config.Region == "" {
+			return nil, fmt.Errorf("no cluster manager of cluster(%d)", clusterID)
+		}
+		key := clustermgr.GetConsulClusterPath(r.config.Region) + clusterID.ToString()
+		pair, _, err := r.consulClient.KV().Get(key, nil)
+		if err != nil {
+			return nil, err
+		}
+		if pair == nil {
+			return nil, fmt.Errorf("not found cluster(%d) in consul", clusterID)
+		}
+		clusterInfo := &clustermgr.ClusterInfo{}
+		if err = json.Unmarshal(pair.Value, clusterInfo); err != nil {
+			return nil, err
+		}
+		hosts = clusterInfo.Nodes
+	}
+
+	cfg := r.config.ClusterMgrConfig
+	cfg.Hosts = hosts
+	cmClient = clustermgr.New(&cfg)
+
+	r.lock.Lock()
+	r.clusters[clusterID] = cmClient
+	r.lock.Unlock()
+	return cmClient, nil
+}
+
+func (r *directReader) volume(ctx context.Context, clusterID proto.ClusterID, vid proto.Vid) (*clustermgr.VolumeInfo, error) {
+	key := directVolumeKey{cid: clusterID, vid: vid}
+	r.lock.Lock()
+	volume, ok := r.volumes[key]
+	r.lock.Unlock()
+	if ok && time.Now().Before(volume.expiration) {
+		return volume.VolumeInfo, nil
+	}
+
+	cmClient, err := r.clusterMgr(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := cmClient.GetVolumeInfo(ctx, &clustermgr.GetVolumeArgs{Vid: vid})
+	if err != nil {
+		return nil, err
+	}
+	// volume had been converted, reading from the redirect volume
+	if info.RedirectVid != proto.InvalidVid {
+		if info, err = cmClient.GetVolumeInfo(ctx, &clustermgr.GetVolumeArgs{Vid: info.RedirectVid}); err != nil {
+			return nil, err
+		}
+	}
+
+	r.lock.Lock()
+	r.volumes[key] = directVolume{
+		VolumeInfo: info,
+		expiration: time.Now().Add(time.Duration(r.config.VolumeExpireS) * time.Second),
+	}
+	r.lock.Unlock()
+	return info, nil
+}
+
+// invalidVolume removes the cached volume, units may be migrated
+func (r *directReader) invalidVolume(clusterID proto.ClusterID, vid proto.Vid) {
+	r.lock.Lock()
+	delete(r.volumes, directVolumeKey{cid: clusterID, vid: vid})
+	r.lock.Unlock()
+}
+
+func (r *directReader) encoder(mode codemode.CodeMode) (ec.Encoder, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if encoder, ok := r.encoders[mode]; ok {
+		return encoder, nil
+	}
+
+	encoder, err := ec.NewEncoder(&ec.Config{CodeMode: mode.Tactic()})
+	if err != nil {
+		return nil, err
+	}
+	r.encoders[mode] = encoder
+	return encoder, nil
+}
+
+// Get returns body of the location, reads blobs one by one directly,
+// gets the rest bytes from access service if failed to read any blob.
+func (r *directReader) Get(ctx context.Context, args *GetArgs,
+	fallback func(context.Context, *GetArgs) (io.ReadCloser, error)) io.ReadCloser {
+	span := trace.SpanFromContextSafe(ctx)
+	pr, pw := io.Pipe()
+
+	go func() {
+		loc := args.Location
+		read := uint64(0)
+		for _, blob := range genDirectBlobs(&loc, args.ReadSize, args.Offset) {
+			shards, err := r.readBlob(ctx, loc.ClusterID, blob)
+			if err != nil {
+				span.Warnf("direct read blob(%d %d %d) failed, fallback to access: %s",
+					loc.ClusterID, blob.Vid, blob.Bid, err.Error())
+				r.invalidVolume(loc.ClusterID, blob.Vid)
+				break
+			}
+			if err = writeShardsRange(pw, shards, blob.Offset, blob.ReadSize); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			read += blob.ReadSize
+		}
+
+		if read < args.ReadSize {
+			body, err := fallback(ctx, &GetArgs{
+				Location: loc,
+				Offset:   args.Offset + read,
+				ReadSize: args.ReadSize - read,
+			})
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			_, err = io.CopyN(pw, body, int64(args.ReadSize-read))
+			body.Close()
+			pw.CloseWithError(err)
+			return
+		}
+		pw.Close()
+	}()
+
+	return pr
+}
+
+// readBlob returns data shards of the blob,
+// reads data shards firstly, then reads parity shards to reconstruct.
+func (r *directReader) readBlob(ctx context.Context, clusterID proto.ClusterID, blob directBlob) ([][]byte, error) {
+	volume, err := r.volume(ctx, clusterID, blob.Vid)
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := r.encoder(volume.CodeMode)
+	if err != nil {
+		return nil, err
+	}
+
+	tactic := volume.CodeMode.Tactic()
+	dataN, dataParityN := tactic.N, tactic.N+tactic.M
+	if len(volume.Units) < dataParityN {
+		return nil, fmt.Errorf("volume(%d) units %d < %d", blob.Vid, len(volume.Units), dataParityN)
+	}
+	dataSize := int(blob.BlobSize)
+	// padded data of blob was re-encoded if volume was converted into another code mode
+	if blob.CodeMode.IsValid() && blob.CodeMode != volume.CodeMode {
+		srcSizes, err := ec.GetBufferSizes(dataSize, blob.CodeMode.Tactic())
+		if err != nil {
+			return nil, err
+		}
+		dataSize = srcSizes.ECDataSize
+	}
+	sizes, err := ec.GetBufferSizes(dataSize, tactic)
+	if err != nil {
+		return nil, err
+	}
+	shardSize := sizes.ShardSize
+
+	buffer := make([]byte, shardSize*dataParityN)
+	shards := make([][]byte, dataParityN)
+	for idx := range shards {
+		shards[idx] = buffer[idx*shardSize : (idx+1)*shardSize]
+	}
+
+	succs := make([]bool, dataParityN)
+	readShards := func(indexes []int) {
+		var wg sync.WaitGroup
+		for _, idx := range indexes {
+			wg.Add(1)
+			go func(idx int) {
+				defer wg.Done()
+				succs[idx] = r.readShard(ctx, volume.Units[idx], blob.Bid, shards[idx]) == nil
+			}(idx)
+		}
+		wg.Wait()
+	}
+
+	// data shards out of blob size are empty
+	firstEmptyIdx := (sizes.DataSize + shardSize - 1) / shardSize
+	indexes := make([]int, 0, dataParityN)
+	for idx := 0; idx < dataN; idx++ {
+		if idx >= firstEmptyIdx {
+			succs[idx] = true
+			continue
+		}
+		indexes = append(indexes, idx)
+	}
+	readShards(indexes)
+
+	badIdx := make([]int, 0, dataParityN)
+	for idx := 0; idx < dataN; idx++ {
+		if !succs[idx] {
+			badIdx = append(badIdx, idx)
+		}
+	}
+
+	if len(badIdx) > 0 {
+		indexes = indexes[:0]
+		for idx := dataN; idx < dataParityN; idx++ {
+			indexes = append(indexes, idx)
+		}
+		readShards(indexes)
+
+		for idx := dataN; idx < dataParityN; idx++ {
+			if !succs[idx] {
+				badIdx = append(badIdx, idx)
+			}
+		}
+		if len(badIdx) > dataParityN-dataN {
+			return nil, fmt.Errorf("bad shards %d has no enough to reconstruct", len(badIdx))
+		}
+		if err = encoder.ReconstructData(shards, badIdx); err != nil {
+			return nil, err
+		}
+	}
+
+	dataShards := shards[:dataN]
+	if blob.VerifyCrc {
+		crc := crc32.NewIEEE()
+		if err = writeShardsRange(crc, dataShards, 0, blob.BlobSize); err != nil {
+			return nil, err
+		}
+		if crc.Sum32() != blob.BlobCrc {
+			return nil, fmt.Errorf("mismatched blob crc %d != %d", crc.Sum32(), blob.BlobCrc)
+		}
+	}
+	return dataShards, nil
+}
+
+func (r *directReader) readShard(ctx context.Context, unit clustermgr.Unit, bid proto.BlobID, buf []byte) error {
+	body, _, err := r.blobnodeClient.RangeGetShard(ctx, unit.Host, &blobnode.RangeGetShardArgs{
+		GetShardArgs: blobnode.GetShardArgs{
+			DiskID: unit.DiskID,
+			Vuid:   unit.Vuid,
+			Bid:    bid,
+		},
+		Offset: 0,
+		Size:   int64(len(buf)),
+	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	_, err = io.ReadFull(body, buf)
+	return err
+}
+
+// genDirectBlobs returns blobs to read of the location range
+func genDirectBlobs(loc *Location, readSize, offset uint64) []directBlob {
+	blobs := loc.Spread()
+	verifyCrc := loc.Version >= LocationVersion2 && len(loc.BlobCrcs) == len(blobs)
+
+	directBlobs := make([]directBlob, 0, 4)
+	for idx, blob := range blobs {
+		if readSize == 0 {
+			break
+		}
+
+		blobSize := uint64(blob.Size)
+		if offset >= blobSize {
+			offset -= blobSize
+			continue
+		}
+
+		toRead := blobSize - offset
+		if toRead > readSize {
+			toRead = readSize
+		}
+		directBlob := directBlob{
+			Vid:      blob.Vid,
+			Bid:      blob.Bid,
+			BlobSize: blobSize,
+			Offset:   offset,
+			ReadSize: toRead,
+			CodeMode: loc.CodeMode,
+		}
+		if verifyCrc {
+			directBlob.VerifyCrc = true
+			directBlob.BlobCrc = loc.BlobCrcs[idx]
+		}
+		directBlobs = append(directBlobs, directBlob)
+
+		offset = 0
+		readSize -= toRead
+	}
+	return directBlobs
+}
+
+// writeShardsRange writes range [offset, offset+size) of joined shards
+func writeShardsRange(w io.Writer, shards [][]byte, offset, size uint64) error {
+	for _, shard := range shards {
+		if size == 0 {
+			break
+		}
+
+		l := uint64(len(shard))
+		if offset >= l {
+			offset -= l
+			continue
+		}
+
+		toWrite := l - offset
+		if toWrite > size {
+			toWrite = size
+		}
+		if _, err := w.Write(shard[offset : offset+toWrite]); err != nil {
+			return err
+		}
+		offset = 0
+		size -= toWrite
+	}
+	if size > 0 {
+		return fmt.Errorf("no enough data in shards %d", size)
+	}
+	return nil
+}
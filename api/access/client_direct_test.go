@@ -0,0 +1,178 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/api/access"
+	"github.com/cubefs/blobstore/api/clustermgr"
+	"github.com/cubefs/blobstore/common/codemode"
+	"github.com/cubefs/blobstore/common/ec"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/rpc"
+)
+
+type directCluster struct {
+	mode   codemode.CodeMode
+	host   string
+	shards sync.Map // key: "vuid-bid"
+
+	brokenDisks sync.Map
+	shardReads  int32
+}
+
+func (d *directCluster) volume(vid proto.Vid) *clustermgr.VolumeInfo {
+	tactic := d.mode.Tactic()
+	volume := &clustermgr.VolumeInfo{}
+	volume.Vid = vid
+	volume.CodeMode = d.mode
+	for idx := 0; idx < tactic.N+tactic.M+tactic.L; idx++ {
+		volume.Units = append(volume.Units, clustermgr.Unit{
+			Vuid:   proto.EncodeVuid(proto.EncodeVuidPrefix(vid, uint8(idx)), 1),
+			DiskID: proto.DiskID(idx + 1),
+			Host:   d.host,
+		})
+	}
+	return volume
+}
+
+func (d *directCluster) putBlob(t *testing.T, vid proto.Vid, bid proto.BlobID, data []byte) {
+	tactic := d.mode.Tactic()
+	encoder, err := ec.NewEncoder(&ec.Config{CodeMode: tactic})
+	require.NoError(t, err)
+	sizes, err := ec.GetBufferSizes(len(data), tactic)
+	require.NoError(t, err)
+	buffer := make([]byte, sizes.ECSize)
+	copy(buffer, data)
+	shards, err := encoder.Split(buffer[:sizes.ECDataSize])
+	require.NoError(t, err)
+	require.NoError(t, encoder.Encode(shards))
+
+	for idx, unit := range d.volume(vid).Units {
+		d.shards.Store(fmt.Sprintf("%d-%d", unit.Vuid, bid), append([]byte{}, shards[idx]...))
+	}
+}
+
+func (d *directCluster) handler() http.Handler {
+	handler := rpc.New()
+	handler.Handle(http.MethodGet, "/volume/get", func(c *rpc.Context) {
+		vid, _ := strconv.Atoi(c.Request.URL.Query().Get("vid"))
+		if vid == 0 {
+			c.RespondStatus(http.StatusNotFound)
+			return
+		}
+		c.RespondJSON(d.volume(proto.Vid(vid)))
+	})
+	handler.Handle(http.MethodGet, "/shard/get/diskid/:diskid/vuid/:vuid/bid/:bid", func(c *rpc.Context) {
+		atomic.AddInt32(&d.shardReads, 1)
+		diskID, _ := strconv.Atoi(c.Param.ByName("diskid"))
+		if _, ok := d.brokenDisks.Load(diskID); ok {
+			c.RespondStatus(http.StatusInternalServerError)
+			return
+		}
+		val, ok := d.shards.Load(c.Param.ByName("vuid") + "-" + c.Param.ByName("bid"))
+		if !ok {
+			c.RespondStatus(http.StatusNotFound)
+			return
+		}
+		shard := val.([]byte)
+		c.RespondWithReader(http.StatusOK, len(shard), rpc.MIMEStream, bytes.NewReader(shard), nil)
+	})
+	return handler
+}
+
+func TestAccessClientDirectRead(t *testing.T) {
+	cluster := &directCluster{mode: codemode.EC6P6}
+	server := httptest.NewServer(cluster.handler())
+	defer server.Close()
+	cluster.host = server.URL
+
+	cfg := access.Config{}
+	cfg.Consul.Address = mockServer.URL[7:]
+	cfg.PriorityAddrs = []string{mockServer.URL}
+	cfg.DirectRead = access.DirectReadConfig{
+		Enable:          true,
+		MinReadSize:     2,
+		ClusterMgrHosts: map[proto.ClusterID][]string{1: {server.URL}},
+	}
+	cli, err := access.New(cfg)
+	require.NoError(t, err)
+
+	size := blobSize*3 + 1024
+	data := make([]byte, size)
+	rand.Read(data)
+	loc := access.Location{
+		ClusterID: 1,
+		CodeMode:  cluster.mode,
+		Size:      uint64(size),
+		BlobSize:  blobSize,
+		Blobs:     []access.SliceInfo{{MinBid: 1000, Vid: 10, Count: 4}},
+		Version:   access.LocationVersion2,
+	}
+	for _, blob := range loc.Spread() {
+		from := int(blob.Bid-1000) * blobSize
+		blobData := data[from : from+int(blob.Size)]
+		cluster.putBlob(t, blob.Vid, blob.Bid, blobData)
+		loc.BlobCrcs = append(loc.BlobCrcs, crc32.ChecksumIEEE(blobData))
+	}
+
+	read := func(offset, readSize uint64) []byte {
+		body, err := cli.Get(randCtx(), &access.GetArgs{Location: loc, Offset: offset, ReadSize: readSize})
+		require.NoError(t, err)
+		defer body.Close()
+		buf, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+		return buf
+	}
+
+	for _, cs := range []struct {
+		offset, readSize uint64
+	}{
+		{0, uint64(size)},
+		{0, 1024},
+		{blobSize - 10, 20},
+		{blobSize * 3, 1024},
+		{1000, blobSize * 2},
+	} {
+		require.Equal(t, data[cs.offset:cs.offset+cs.readSize], read(cs.offset, cs.readSize))
+	}
+
+	// reconstruct with broken data shards
+	cluster.brokenDisks.Store(1, struct{}{})
+	cluster.brokenDisks.Store(3, struct{}{})
+	require.Equal(t, data, read(0, uint64(size)))
+
+	// fallback to access
+	dataCache.clean()
+	dataCache.put(0, data[blobSize:blobSize+10])
+	loc.BlobCrcs[1]++
+	fillCrc(&loc)
+	reads := atomic.LoadInt32(&cluster.shardReads)
+	require.Equal(t, data[10:blobSize+10], read(10, blobSize))
+	require.Less(t, reads, atomic.LoadInt32(&cluster.shardReads))
+}
@@ -0,0 +1,197 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	errcode "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/rpc"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+func (c *client) MultipartInit(ctx context.Context, args *MultipartInitArgs) (resp MultipartInitResp, err error) {
+	if !args.IsValid() {
+		return resp, errcode.ErrIllegalArguments
+	}
+
+	ctx = withReqidContext(ctx)
+	err = c.tryN(ctx, c.config.MaxHostRetry, func(host string) error {
+		return c.rpcClient.PostWith(ctx, fmt.Sprintf("%s/multipart/init", host), &resp, args)
+	})
+	return
+}
+
+func (c *client) MultipartPut(ctx context.Context, args *MultipartPutArgs) (hashSumMap HashSumMap, err error) {
+	if !args.IsValid() {
+		return nil, errcode.ErrIllegalArguments
+	}
+
+	ctx = withReqidContext(ctx)
+	span := trace.SpanFromContextSafe(ctx)
+
+	var (
+		cached bool
+		buffer []byte
+		reader *hadReader
+	)
+	if args.Size <= _cacheBufferPutOnce {
+		cached = true
+		buffer, _ = memPool.Alloc(int(args.Size))
+		buffer = buffer[:args.Size]
+		defer memPool.Put(buffer)
+
+		_, err := io.ReadFull(args.Body, buffer)
+		if err != nil {
+			span.Error("read buffer from request", err)
+			return nil, errcode.ErrAccessReadRequestBody
+		}
+	} else {
+		reader = &hadReader{
+			isRead: false,
+			reader: args.Body,
+		}
+	}
+
+	err = c.tryN(ctx, c.config.MaxHostRetry, func(host string) error {
+		var body io.Reader
+		if cached {
+			body = bytes.NewReader(buffer)
+		} else {
+			if reader.isRead {
+				span.Info("retry on other access host which had been read body")
+				return errcode.ErrAccessReadConflictBody
+			}
+			body = reader
+		}
+
+		urlStr := fmt.Sprintf("%s/multipart/put?upload_id=%s&part_number=%d&size=%d&hashes=%d",
+			host, url.QueryEscape(args.UploadID), args.PartNumber, args.Size, args.Hashes)
+		req, e := http.NewRequest(http.MethodPut, urlStr, body)
+		if e != nil {
+			return e
+		}
+
+		resp := &MultipartPutResp{}
+		e = c.rpcClient.DoWith(ctx, req, resp, rpc.WithCrcEncode())
+		if e == nil {
+			hashSumMap = resp.HashSumMap
+		}
+		return e
+	})
+	return
+}
+
+func (c *client) MultipartList(ctx context.Context, uploadID string) (resp MultipartListResp, err error) {
+	if uploadID == "" {
+		return resp, errcode.ErrIllegalArguments
+	}
+
+	ctx = withReqidContext(ctx)
+	err = c.tryN(ctx, c.config.MaxHostRetry, func(host string) error {
+		return c.rpcClient.GetWith(ctx, fmt.Sprintf("%s/multipart/list?upload_id=%s",
+			host, url.QueryEscape(uploadID)), &resp)
+	})
+	return
+}
+
+func (c *client) MultipartComplete(ctx context.Context, uploadID string) (location Location, err error) {
+	if uploadID == "" {
+		return location, errcode.ErrIllegalArguments
+	}
+
+	ctx = withReqidContext(ctx)
+	err = c.tryN(ctx, c.config.MaxHostRetry, func(host string) error {
+		resp := &MultipartCompleteResp{}
+		if e := c.rpcClient.PostWith(ctx, fmt.Sprintf("%s/multipart/complete", host), resp,
+			MultipartUploadArgs{UploadID: uploadID}); e != nil {
+			return e
+		}
+		location = resp.Location
+		return nil
+	})
+	return
+}
+
+func (c *client) MultipartAbort(ctx context.Context, uploadID string) error {
+	if uploadID == "" {
+		return errcode.ErrIllegalArguments
+	}
+
+	ctx = withReqidContext(ctx)
+	return c.tryN(ctx, c.config.MaxHostRetry, func(host string) error {
+		return c.rpcClient.PostWith(ctx, fmt.Sprintf("%s/multipart/abort", host), nil,
+			MultipartUploadArgs{UploadID: uploadID})
+	})
+}
+
+// putMultipart puts parts of the object one by one by multipart upload,
+// the upload is aborted if any part failed.
+func (c *client) putMultipart(ctx context.Context, args *PutArgs) (location Location, hashSumMap HashSumMap, err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	hashSumMap = args.Hashes.ToHashSumMap()
+	hasherMap := make(HasherMap, len(hashSumMap))
+	for alg := range hashSumMap {
+		hasherMap[alg] = alg.ToHasher()
+	}
+	body := args.Body
+	if len(hasherMap) > 0 {
+		body = io.TeeReader(args.Body, hasherMap.ToWriter())
+	}
+
+	init, err := c.MultipartInit(ctx, &MultipartInitArgs{Size: uint64(args.Size), ExpireAt: args.ExpireAt})
+	if err != nil {
+		return location, nil, err
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if e := c.MultipartAbort(ctx, init.UploadID); e != nil {
+			span.Warnf("abort multipart upload(%s) failed %s", init.UploadID, e.Error())
+		}
+	}()
+
+	remain := uint64(args.Size)
+	for n := 1; n <= init.PartCount; n++ {
+		size := init.PartSize
+		if size > remain {
+			size = remain
+		}
+		if _, err = c.MultipartPut(ctx, &MultipartPutArgs{
+			UploadID:   init.UploadID,
+			PartNumber: n,
+			Size:       int64(size),
+			Body:       io.LimitReader(body, int64(size)),
+		}); err != nil {
+			return location, nil, err
+		}
+		remain -= size
+	}
+
+	if location, err = c.MultipartComplete(ctx, init.UploadID); err != nil {
+		return location, nil, err
+	}
+	for alg, hasher := range hasherMap {
+		hashSumMap[alg] = hasher.Sum(nil)
+	}
+	return location, hashSumMap, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package access
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+
+	errcode "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/rpc"
+)
+
+// RangesBody multipart/byteranges body of GetRanges
+type RangesBody struct {
+	body   io.ReadCloser
+	reader *multipart.Reader
+}
+
+// Next returns range and data of the next part, returns io.EOF if no more parts
+func (b *RangesBody) Next() (Range, io.Reader, error) {
+	part, err := b.reader.NextPart()
+	if err != nil {
+		return Range{}, nil, err
+	}
+
+	var start, end, size uint64
+	contentRange := part.Header.Get(rpc.HeaderContentRange)
+	if _, err = fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &size); err != nil || end < start {
+		return Range{}, nil, fmt.Errorf("invalid content range '%s'", contentRange)
+	}
+	return Range{Offset: start, ReadSize: end - start + 1}, part, nil
+}
+
+// Close closes the body
+func (b *RangesBody) Close() error {
+	return b.body.Close()
+}
+
+func (c *client) GetRanges(ctx context.Context, args *GetRangesArgs) (body *RangesBody, err error) {
+	if !args.IsValid() {
+		return nil, errcode.ErrIllegalArguments
+	}
+
+	ctx = withReqidContext(ctx)
+	err = c.tryN(ctx, c.config.MaxHostRetry, func(host string) error {
+		resp, e := c.rpcClient.Post(ctx, fmt.Sprintf("%s/get/ranges", host), args)
+		if e != nil {
+			return e
+		}
+		if resp.StatusCode >= 400 {
+			resp.Body.Close()
+			return rpc.NewError(resp.StatusCode, "StatusCode", fmt.Errorf("code: %d", resp.StatusCode))
+		}
+
+		_, params, e := mime.ParseMediaType(resp.Header.Get(rpc.HeaderContentType))
+		if e != nil || params["boundary"] == "" {
+			resp.Body.Close()
+			return fmt.Errorf("invalid content type '%s'", resp.Header.Get(rpc.HeaderContentType))
+		}
+		body = &RangesBody{
+			body:   resp.Body,
+			reader: multipart.NewReader(resp.Body, params["boundary"]),
+		}
+		return nil
+	})
+	return
+}
@@ -23,8 +23,10 @@ import (
 	"io"
 	"io/ioutil"
 	mrand "math/rand"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"os"
 	"strconv"
 	"strings"
@@ -112,6 +114,7 @@ func init() {
 	handler.Handle(http.MethodPut, "/put", handlePut, rpc.OptArgsQuery())
 	handler.Handle(http.MethodPut, "/putat", handlePutAt, rpc.OptArgsQuery())
 	handler.Handle(http.MethodPost, "/get", handleGet, rpc.OptArgsBody())
+	handler.Handle(http.MethodPost, "/get/ranges", handleGetRanges, rpc.OptArgsBody())
 	handler.Handle(http.MethodPost, "/delete", handleDelete, rpc.OptArgsBody())
 	handler.Handle(http.MethodPost, "/sign", handleSign, rpc.OptArgsBody())
 	handler.Router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -312,6 +315,32 @@ func handleGet(c *rpc.Context) {
 	}
 }
 
+func handleGetRanges(c *rpc.Context) {
+	args := new(access.GetRangesArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	if !verifyCrc(&args.Location) {
+		c.RespondStatus(http.StatusForbidden)
+		return
+	}
+
+	buf := dataCache.get(0)
+	mw := multipart.NewWriter(c.Writer)
+	c.Writer.Header().Set(rpc.HeaderContentType, "multipart/byteranges; boundary="+mw.Boundary())
+	c.RespondStatus(http.StatusPartialContent)
+	for _, r := range args.Ranges {
+		part, _ := mw.CreatePart(textproto.MIMEHeader{
+			rpc.HeaderContentRange: {fmt.Sprintf("bytes %d-%d/%d",
+				r.Offset, r.Offset+r.ReadSize-1, args.Location.Size)},
+		})
+		part.Write(buf[r.Offset : r.Offset+r.ReadSize])
+	}
+	mw.Close()
+}
+
 func handleDelete(c *rpc.Context) {
 	args := new(access.DeleteArgs)
 	if err := c.ParseArgs(args); err != nil {
@@ -537,6 +566,123 @@ func TestAccessClientPutGet(t *testing.T) {
 	}
 }
 
+func TestAccessClientPutEncrypted(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		size     uint64
+		expireAt int64
+		allocs   int
+		parts    = make(map[int][]byte)
+	)
+	rpc.RegisterArgsParser(&access.MultipartPutArgs{}, "json")
+	handler := rpc.New()
+	handler.Handle(http.MethodPost, "/alloc", func(c *rpc.Context) {
+		allocs++
+		c.RespondError(errcode.ErrAccessEncrypted)
+	})
+	handler.Handle(http.MethodPost, "/multipart/init", func(c *rpc.Context) {
+		args := new(access.MultipartInitArgs)
+		if err := c.ParseArgs(args); err != nil {
+			c.RespondError(err)
+			return
+		}
+		size, expireAt = args.Size, args.ExpireAt
+		c.RespondJSON(access.MultipartInitResp{
+			UploadID:  "encrypted",
+			PartSize:  blobSize,
+			PartCount: int((args.Size + blobSize - 1) / blobSize),
+		})
+	}, rpc.OptArgsBody())
+	handler.Handle(http.MethodPut, "/multipart/put", func(c *rpc.Context) {
+		args := new(access.MultipartPutArgs)
+		if err := c.ParseArgs(args); err != nil {
+			c.RespondError(err)
+			return
+		}
+		buf, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil || int64(len(buf)) != args.Size {
+			c.RespondError(errcode.ErrAccessReadRequestBody)
+			return
+		}
+		mu.Lock()
+		parts[args.PartNumber] = buf
+		mu.Unlock()
+		c.RespondJSON(access.MultipartPutResp{})
+	}, rpc.OptArgsQuery())
+	handler.Handle(http.MethodPost, "/multipart/complete", func(c *rpc.Context) {
+		c.RespondJSON(access.MultipartCompleteResp{Location: access.Location{Size: size, BlobSize: blobSize}})
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	cfg := access.Config{}
+	cfg.Consul.Address = server.URL[7:]
+	cfg.PriorityAddrs = []string{server.URL}
+	cfg.MaxSizePutOnce = 1 << 20
+	cli, err := access.New(cfg)
+	require.NoError(t, err)
+
+	buff := make([]byte, 3*blobSize-1)
+	rand.Read(buff)
+	loc, hashSumMap, err := cli.Put(randCtx(), &access.PutArgs{
+		Size:   int64(len(buff)),
+		Hashes: access.HashAlgCRC32,
+		Body:   bytes.NewReader(buff),
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(buff)), loc.Size)
+	crc, _ := hashSumMap.GetSum(access.HashAlgCRC32)
+	require.Equal(t, crc32.ChecksumIEEE(buff), crc)
+	require.Equal(t, 3, len(parts))
+	require.Equal(t, buff, append(append(parts[1], parts[2]...), parts[3]...))
+	require.Equal(t, 1, allocs)
+
+	// object with expiry is put by multipart directly
+	parts = make(map[int][]byte)
+	_, _, err = cli.Put(randCtx(), &access.PutArgs{
+		Size:     int64(len(buff)),
+		Body:     bytes.NewReader(buff),
+		ExpireAt: 1 << 40,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, allocs)
+	require.Equal(t, int64(1<<40), expireAt)
+	require.Equal(t, buff, append(append(parts[1], parts[2]...), parts[3]...))
+}
+
+func TestAccessClientGetRanges(t *testing.T) {
+	_, err := client.GetRanges(randCtx(), &access.GetRangesArgs{})
+	require.ErrorIs(t, err, errcode.ErrIllegalArguments)
+
+	buff := make([]byte, 1<<10)
+	rand.Read(buff)
+	loc, _, err := client.Put(randCtx(), &access.PutArgs{
+		Size: int64(len(buff)),
+		Body: bytes.NewBuffer(buff),
+	})
+	require.NoError(t, err)
+
+	ranges := []access.Range{
+		{Offset: 0, ReadSize: 1},
+		{Offset: 100, ReadSize: 200},
+		{Offset: 1000, ReadSize: 24},
+	}
+	body, err := client.GetRanges(randCtx(), &access.GetRangesArgs{Location: loc, Ranges: ranges})
+	require.NoError(t, err)
+	defer body.Close()
+
+	for _, r := range ranges {
+		got, reader, err := body.Next()
+		require.NoError(t, err)
+		require.Equal(t, r, got)
+		data, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, buff[r.Offset:r.Offset+r.ReadSize], data)
+	}
+	_, _, err = body.Next()
+	require.Equal(t, io.EOF, err)
+}
+
 func TestAccessClientPutAtBase(t *testing.T) {
 	cfg := access.Config{}
 	cfg.Consul.Address = mockServer.URL[7:]
@@ -25,6 +25,7 @@ import (
 	"hash"
 	"hash/crc32"
 	"io"
+	"sort"
 
 	"github.com/cubefs/blobstore/common/codemode"
 	"github.com/cubefs/blobstore/common/proto"
@@ -50,10 +51,69 @@ const (
 	MaxLocationBlobs uint32 = 4
 	// MaxDeleteLocations max locations of delete request
 	MaxDeleteLocations int = 1024
+	// MaxGetRanges max ranges of get ranges request
+	MaxGetRanges int = 128
 	// MaxBlobSize max blob size for allocation
 	MaxBlobSize uint32 = 1 << 25 // 32MB
 )
 
+// defined version of location encoding
+const (
+	LocationVersion1 uint8 = 1
+	LocationVersion2 uint8 = 2
+
+	locationVersionBit      byte = 0x80
+	locationFlagBlobCrcs    byte = 1 << 0
+	locationFlagHashSumMap  byte = 1 << 1
+	locationFlagCompression byte = 1 << 2
+	locationFlagEncryption  byte = 1 << 3
+	locationFlagDedup       byte = 1 << 4
+	locationFlagTenant      byte = 1 << 5
+	locationFlagDedupRef    byte = 1 << 6
+)
+
+// CompressCodec codec of compression when uploading data
+type CompressCodec uint8
+
+// defined compression codec
+const (
+	CompressNone   CompressCodec = iota // not compressed
+	CompressSnappy                      // snappy block format
+	CompressZstd                        // zstd frame of one block
+)
+
+// IsValid is valid codec
+func (codec CompressCodec) IsValid() bool {
+	return codec <= CompressZstd
+}
+
+func (codec CompressCodec) String() string {
+	switch codec {
+	case CompressNone:
+		return "none"
+	case CompressSnappy:
+		return "snappy"
+	case CompressZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(codec))
+	}
+}
+
+// ParseCompressCodec returns codec of the name, empty name means CompressNone
+func ParseCompressCodec(name string) (CompressCodec, error) {
+	switch name {
+	case "", "none":
+		return CompressNone, nil
+	case "snappy":
+		return CompressSnappy, nil
+	case "zstd":
+		return CompressZstd, nil
+	default:
+		return CompressNone, fmt.Errorf("unknown compress codec %s", name)
+	}
+}
+
 type dummyHash struct{}
 
 var _ hash.Hash = (*dummyHash)(nil)
@@ -208,6 +268,17 @@ func (h HashSumMap) All() map[string]interface{} {
 // BlobSize is every blob's size but the last one which's size=(Size mod BlobSize)
 // Crc is the checksum, change anything of the location, crc will mismatch
 // Blobs all blob information
+//
+// Version is version of encoding, zero means LocationVersion1
+// BlobCrcs is crc32(IEEE) of every blob content, optional since LocationVersion2
+// HashSumMap is hash summary of the file computed on put, optional since LocationVersion2
+// Compression is block index of compressed file, optional since LocationVersion2,
+// then Size is the stored size after compression
+// Encryption is wrapped data key of encrypted file, optional since LocationVersion2
+// Dedup means blobs are shared by all puts of the same content, indexed by
+// sha256 in HashSumMap, the blobs are deleted after the last reference deleted
+// Tenant is owner of the stored bytes, optional since LocationVersion2
+// DedupRef is id of the reference to dedup blobs held by this put, optional since LocationVersion2
 type Location struct {
 	_         [0]byte
 	ClusterID proto.ClusterID   `json:"cluster_id"`
@@ -216,6 +287,74 @@ type Location struct {
 	BlobSize  uint32            `json:"blob_size"`
 	Crc       uint32            `json:"crc"`
 	Blobs     []SliceInfo       `json:"blobs"`
+
+	Version     uint8        `json:"version,omitempty"`
+	BlobCrcs    []uint32     `json:"blob_crcs,omitempty"`
+	HashSumMap  HashSumMap   `json:"hash_sum_map,omitempty"`
+	Compression *Compression `json:"compression,omitempty"`
+	Encryption  *Encryption  `json:"encryption,omitempty"`
+	Dedup       bool         `json:"dedup,omitempty"`
+	Tenant      string       `json:"tenant,omitempty"`
+	DedupRef    string       `json:"dedup_ref,omitempty"`
+}
+
+// Compression file was compressed in blocks
+//
+// Codec is the codec of compression
+// RawSize is file size before compression
+// BlockSize is raw size of every block but the last one
+// Blocks is stored size of every block, a block is stored uncompressed
+// if its stored size is equal to the raw size
+//
+// stored offset of a block is the sum of stored sizes of the previous blocks,
+// so that a range of raw data can be read by decompressing whole blocks only.
+type Compression struct {
+	Codec     CompressCodec `json:"codec"`
+	RawSize   uint64        `json:"raw_size"`
+	BlockSize uint32        `json:"block_size"`
+	Blocks    []uint32      `json:"blocks"`
+}
+
+// BlockRawSize returns raw size of the idx-th block
+func (c *Compression) BlockRawSize(idx int) uint32 {
+	if idx == len(c.Blocks)-1 && c.BlockSize > 0 {
+		if lastSize := c.RawSize % uint64(c.BlockSize); lastSize > 0 {
+			return uint32(lastSize)
+		}
+	}
+	return c.BlockSize
+}
+
+// IsValid is valid compression of stored size
+func (c *Compression) IsValid(size uint64) bool {
+	if c == nil || !c.Codec.IsValid() || c.BlockSize == 0 {
+		return false
+	}
+	if uint64(len(c.Blocks)) != (c.RawSize+uint64(c.BlockSize)-1)/uint64(c.BlockSize) {
+		return false
+	}
+	stored := uint64(0)
+	for idx, blockSize := range c.Blocks {
+		if blockSize == 0 || blockSize > c.BlockRawSize(idx) {
+			return false
+		}
+		stored += uint64(blockSize)
+	}
+	return stored == size
+}
+
+// Encryption file was encrypted at rest
+//
+// stored data (compressed if Compression is set) is encrypted with AES-256-CTR,
+// counter of offset is IV + offset/16, so that any range can be decrypted.
+//
+// KeyID is id of the master key which wrapped the data key
+// WrappedKey is the data key sealed by the master key
+// IV is the initial counter block of AES-CTR
+type Encryption struct {
+	KeyID      uint32 `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+	IV         []byte `json:"iv"`
 }
 
 // SliceInfo blobs info, 8 + 4 + 4 bytes
@@ -252,11 +391,45 @@ func (loc *Location) Copy() Location {
 		BlobSize:  loc.BlobSize,
 		Crc:       loc.Crc,
 		Blobs:     make([]SliceInfo, len(loc.Blobs)),
+		Version:   loc.Version,
+		Dedup:     loc.Dedup,
+		Tenant:    loc.Tenant,
+		DedupRef:  loc.DedupRef,
 	}
 	copy(dst.Blobs, loc.Blobs)
+	if loc.BlobCrcs != nil {
+		dst.BlobCrcs = make([]uint32, len(loc.BlobCrcs))
+		copy(dst.BlobCrcs, loc.BlobCrcs)
+	}
+	if loc.HashSumMap != nil {
+		dst.HashSumMap = make(HashSumMap, len(loc.HashSumMap))
+		for alg, sum := range loc.HashSumMap {
+			dst.HashSumMap[alg] = append([]byte{}, sum...)
+		}
+	}
+	if loc.Compression != nil {
+		compression := *loc.Compression
+		compression.Blocks = append([]uint32{}, loc.Compression.Blocks...)
+		dst.Compression = &compression
+	}
+	if loc.Encryption != nil {
+		dst.Encryption = &Encryption{
+			KeyID:      loc.Encryption.KeyID,
+			WrappedKey: append([]byte{}, loc.Encryption.WrappedKey...),
+			IV:         append([]byte{}, loc.Encryption.IV...),
+		}
+	}
 	return dst
 }
 
+// RawSize returns file size before compression
+func (loc *Location) RawSize() uint64 {
+	if loc.Compression != nil {
+		return loc.Compression.RawSize
+	}
+	return loc.Size
+}
+
 // Encode transfer Location to slice byte
 // Returns the buf created by me
 //  (n) means max-n bytes
@@ -271,16 +444,55 @@ func (loc *Location) Copy() Location {
 //  - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
 //  | n-bytes |  (10)  | (5) |  (5)  | (20) | (20) |       ...         |
 //  - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+//
+// Since LocationVersion2, a version byte with the highest bit set is
+// placed before codemode, the highest bit of codemode in version 1 is
+// always zero, so that DecodeLocation can detect the version.
+// Optional checksums are appended after blobs, marked in flags.
+//  - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+//  |  field  | crc | clusterid  | 0x80|version | codemode |    ...    |
+//  - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+//  |  blobs  |  flags  | (5){len(crcs)} | crc(4) | ... |
+//  - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+//  | (5){len(hashes)} | alg(1) | (5){len(sum)} | sum | ... |
+//  - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+//  | codec(1) | rawsize(10) | blocksize(5) | (5){len(blocks)} | (5) | ... |
+//  - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+//  | keyid(5) | (5){len(wrappedkey)} | wrappedkey | (5){len(iv)} | iv |
+//  - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+//  | (5){len(tenant)} | tenant | (5){len(dedupref)} | dedupref |
+//  - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
 func (loc *Location) Encode() []byte {
 	if loc == nil {
 		return nil
 	}
-	n := 25 + 5 + len(loc.Blobs)*20
-	buf := make([]byte, n)
-	n = loc.Encode2(buf)
+	buf := make([]byte, loc.EncodeSize())
+	n := loc.Encode2(buf)
 	return buf[:n]
 }
 
+// EncodeSize returns max bytes of the encoded location
+func (loc *Location) EncodeSize() int {
+	if loc == nil {
+		return 0
+	}
+	n := 25 + 5 + len(loc.Blobs)*20
+	if loc.Version >= LocationVersion2 {
+		n += 1 + 1 + 5 + len(loc.BlobCrcs)*4 + 5
+		for _, sum := range loc.HashSumMap {
+			n += 1 + 5 + len(sum)
+		}
+		if loc.Compression != nil {
+			n += 1 + 10 + 5 + 5 + len(loc.Compression.Blocks)*5
+		}
+		if loc.Encryption != nil {
+			n += 5 + 5 + len(loc.Encryption.WrappedKey) + 5 + len(loc.Encryption.IV)
+		}
+		n += 5 + len(loc.Tenant) + 5 + len(loc.DedupRef)
+	}
+	return n
+}
+
 // Encode2 transfer Location to the buf, the buf reuse by yourself
 // Returns the number of bytes read
 // If the buffer is too small, Encode2 will panic
@@ -293,6 +505,10 @@ func (loc *Location) Encode2(buf []byte) int {
 	binary.BigEndian.PutUint32(buf[n:], loc.Crc)
 	n += 4
 	n += binary.PutUvarint(buf[n:], uint64(loc.ClusterID))
+	if loc.Version >= LocationVersion2 {
+		buf[n] = locationVersionBit | loc.Version
+		n++
+	}
 	buf[n] = byte(loc.CodeMode)
 	n++
 	n += binary.PutUvarint(buf[n:], uint64(loc.Size))
@@ -305,6 +521,71 @@ func (loc *Location) Encode2(buf []byte) int {
 		n += binary.PutUvarint(buf[n:], uint64(blob.Count))
 	}
 
+	if loc.Version < LocationVersion2 {
+		return n
+	}
+
+	flagN := n
+	buf[flagN] = 0
+	n++
+	if len(loc.BlobCrcs) > 0 {
+		buf[flagN] |= locationFlagBlobCrcs
+		n += binary.PutUvarint(buf[n:], uint64(len(loc.BlobCrcs)))
+		for _, crc := range loc.BlobCrcs {
+			binary.BigEndian.PutUint32(buf[n:], crc)
+			n += 4
+		}
+	}
+	if len(loc.HashSumMap) > 0 {
+		buf[flagN] |= locationFlagHashSumMap
+		algs := make([]HashAlgorithm, 0, len(loc.HashSumMap))
+		for alg := range loc.HashSumMap {
+			algs = append(algs, alg)
+		}
+		sort.Slice(algs, func(i, j int) bool { return algs[i] < algs[j] })
+
+		n += binary.PutUvarint(buf[n:], uint64(len(algs)))
+		for _, alg := range algs {
+			sum := loc.HashSumMap[alg]
+			buf[n] = byte(alg)
+			n++
+			n += binary.PutUvarint(buf[n:], uint64(len(sum)))
+			n += copy(buf[n:], sum)
+		}
+	}
+	if loc.Compression != nil {
+		buf[flagN] |= locationFlagCompression
+		buf[n] = byte(loc.Compression.Codec)
+		n++
+		n += binary.PutUvarint(buf[n:], loc.Compression.RawSize)
+		n += binary.PutUvarint(buf[n:], uint64(loc.Compression.BlockSize))
+		n += binary.PutUvarint(buf[n:], uint64(len(loc.Compression.Blocks)))
+		for _, blockSize := range loc.Compression.Blocks {
+			n += binary.PutUvarint(buf[n:], uint64(blockSize))
+		}
+	}
+	if loc.Encryption != nil {
+		buf[flagN] |= locationFlagEncryption
+		n += binary.PutUvarint(buf[n:], uint64(loc.Encryption.KeyID))
+		n += binary.PutUvarint(buf[n:], uint64(len(loc.Encryption.WrappedKey)))
+		n += copy(buf[n:], loc.Encryption.WrappedKey)
+		n += binary.PutUvarint(buf[n:], uint64(len(loc.Encryption.IV)))
+		n += copy(buf[n:], loc.Encryption.IV)
+	}
+	if loc.Dedup {
+		buf[flagN] |= locationFlagDedup
+	}
+	if loc.Tenant != "" {
+		buf[flagN] |= locationFlagTenant
+		n += binary.PutUvarint(buf[n:], uint64(len(loc.Tenant)))
+		n += copy(buf[n:], loc.Tenant)
+	}
+	if loc.DedupRef != "" {
+		buf[flagN] |= locationFlagDedupRef
+		n += binary.PutUvarint(buf[n:], uint64(len(loc.DedupRef)))
+		n += copy(buf[n:], loc.DedupRef)
+	}
+
 	return n
 }
 
@@ -401,6 +682,17 @@ func DecodeLocation(buf []byte) (Location, int, error) {
 	if len(buf) < 1 {
 		return loc, n, fmt.Errorf("bytes codemode %d", len(buf))
 	}
+	if buf[0]&locationVersionBit != 0 {
+		loc.Version = buf[0] &^ locationVersionBit
+		if loc.Version != LocationVersion2 {
+			return loc, n, fmt.Errorf("unsupported version %d", loc.Version)
+		}
+		n++
+		buf = buf[1:]
+		if len(buf) < 1 {
+			return loc, n, fmt.Errorf("bytes codemode %d", len(buf))
+		}
+	}
 	loc.CodeMode = codemode.CodeMode(buf[0])
 	n++
 	buf = buf[1:]
@@ -418,6 +710,10 @@ func DecodeLocation(buf []byte) (Location, int, error) {
 	if val, nn = next(); nn <= 0 {
 		return loc, n, fmt.Errorf("bytes length blobs %d", nn)
 	}
+	// every blob takes 3 bytes at least
+	if val > uint64(len(buf))/3 {
+		return loc, n, fmt.Errorf("bytes blobs %d < length %d", len(buf), val)
+	}
 	length := int(val)
 
 	if length > 0 {
@@ -443,6 +739,147 @@ func DecodeLocation(buf []byte) (Location, int, error) {
 		loc.Blobs = append(loc.Blobs, blob)
 	}
 
+	if loc.Version < LocationVersion2 {
+		return loc, n, nil
+	}
+
+	if len(buf) < 1 {
+		return loc, n, fmt.Errorf("bytes flags %d", len(buf))
+	}
+	flags := buf[0]
+	n++
+	buf = buf[1:]
+
+	if flags&locationFlagBlobCrcs != 0 {
+		if val, nn = next(); nn <= 0 {
+			return loc, n, fmt.Errorf("bytes length blob crcs %d", nn)
+		}
+		if val > uint64(len(buf))/4 {
+			return loc, n, fmt.Errorf("bytes blob crcs %d < length %d", len(buf), val)
+		}
+		length := int(val)
+		loc.BlobCrcs = make([]uint32, length)
+		for index := range loc.BlobCrcs {
+			loc.BlobCrcs[index] = binary.BigEndian.Uint32(buf)
+			n += 4
+			buf = buf[4:]
+		}
+	}
+
+	if flags&locationFlagHashSumMap != 0 {
+		if val, nn = next(); nn <= 0 {
+			return loc, n, fmt.Errorf("bytes length hashes %d", nn)
+		}
+		// every hash takes 2 bytes at least
+		if val > uint64(len(buf))/2 {
+			return loc, n, fmt.Errorf("bytes hashes %d < length %d", len(buf), val)
+		}
+		length := int(val)
+		loc.HashSumMap = make(HashSumMap, length)
+		for index := 0; index < length; index++ {
+			if len(buf) < 1 {
+				return loc, n, fmt.Errorf("bytes %dth-hash alg %d", index, len(buf))
+			}
+			alg := HashAlgorithm(buf[0])
+			n++
+			buf = buf[1:]
+
+			if val, nn = next(); nn <= 0 {
+				return loc, n, fmt.Errorf("bytes %dth-hash length %d", index, nn)
+			}
+			if uint64(len(buf)) < val {
+				return loc, n, fmt.Errorf("bytes %dth-hash sum %d < %d", index, len(buf), val)
+			}
+			loc.HashSumMap[alg] = append([]byte{}, buf[:val]...)
+			n += int(val)
+			buf = buf[val:]
+		}
+	}
+
+	if flags&locationFlagCompression != 0 {
+		if len(buf) < 1 {
+			return loc, n, fmt.Errorf("bytes compression codec %d", len(buf))
+		}
+		compression := &Compression{Codec: CompressCodec(buf[0])}
+		n++
+		buf = buf[1:]
+
+		if val, nn = next(); nn <= 0 {
+			return loc, n, fmt.Errorf("bytes compression raw_size %d", nn)
+		}
+		compression.RawSize = val
+		if val, nn = next(); nn <= 0 {
+			return loc, n, fmt.Errorf("bytes compression block_size %d", nn)
+		}
+		compression.BlockSize = uint32(val)
+		if val, nn = next(); nn <= 0 {
+			return loc, n, fmt.Errorf("bytes length compression blocks %d", nn)
+		}
+		// every block size takes 1 byte at least
+		if val > uint64(len(buf)) {
+			return loc, n, fmt.Errorf("bytes compression blocks %d < %d", len(buf), val)
+		}
+		length := int(val)
+		compression.Blocks = make([]uint32, 0, length)
+		for index := 0; index < length; index++ {
+			if val, nn = next(); nn <= 0 {
+				return loc, n, fmt.Errorf("bytes %dth-block size %d", index, nn)
+			}
+			compression.Blocks = append(compression.Blocks, uint32(val))
+		}
+		loc.Compression = compression
+	}
+
+	if flags&locationFlagEncryption != 0 {
+		encryption := &Encryption{}
+		if val, nn = next(); nn <= 0 {
+			return loc, n, fmt.Errorf("bytes encryption key_id %d", nn)
+		}
+		encryption.KeyID = uint32(val)
+		for _, field := range []struct {
+			name string
+			val  *[]byte
+		}{
+			{"wrapped_key", &encryption.WrappedKey},
+			{"iv", &encryption.IV},
+		} {
+			if val, nn = next(); nn <= 0 {
+				return loc, n, fmt.Errorf("bytes length encryption %s %d", field.name, nn)
+			}
+			if uint64(len(buf)) < val {
+				return loc, n, fmt.Errorf("bytes encryption %s %d < %d", field.name, len(buf), val)
+			}
+			*field.val = append([]byte{}, buf[:val]...)
+			n += int(val)
+			buf = buf[val:]
+		}
+		loc.Encryption = encryption
+	}
+	loc.Dedup = flags&locationFlagDedup != 0
+
+	if flags&locationFlagTenant != 0 {
+		if val, nn = next(); nn <= 0 {
+			return loc, n, fmt.Errorf("bytes length tenant %d", nn)
+		}
+		if val == 0 || uint64(len(buf)) < val {
+			return loc, n, fmt.Errorf("bytes tenant %d < %d", len(buf), val)
+		}
+		loc.Tenant = string(buf[:val])
+		n += int(val)
+		buf = buf[val:]
+	}
+
+	if flags&locationFlagDedupRef != 0 {
+		if val, nn = next(); nn <= 0 {
+			return loc, n, fmt.Errorf("bytes length dedup ref %d", nn)
+		}
+		if val == 0 || uint64(len(buf)) < val {
+			return loc, n, fmt.Errorf("bytes dedup ref %d < %d", len(buf), val)
+		}
+		loc.DedupRef = string(buf[:val])
+		n += int(val)
+	}
+
 	return loc, n, nil
 }
 
@@ -482,10 +919,16 @@ func DecodeLocationFromBase64(s string) (Location, error) {
 // PutArgs for service /put
 // Hashes means how to calculate check sum,
 // HashAlgCRC32 | HashAlgMD5 equal 2 + 4 = 6
+// Compression means how to compress data of /put, CompressNone follows config of access
+// ContentSHA256 is hex of sha256 of the body, put is deduplicated by content
+// if access enabled dedup, the same location is returned to every put of the content
 type PutArgs struct {
-	Size   int64         `json:"size"`
-	Hashes HashAlgorithm `json:"hashes,omitempty"`
-	Body   io.Reader     `json:"-"`
+	Size          int64         `json:"size"`
+	Hashes        HashAlgorithm `json:"hashes,omitempty"`
+	Compression   CompressCodec `json:"compression,omitempty"`
+	ContentSHA256 string        `json:"content_sha256,omitempty"`
+	ExpireAt      int64         `json:"expire_at,omitempty"` // unix seconds, 0 means never expire
+	Body          io.Reader     `json:"-"`
 }
 
 // IsValid is valid put args
@@ -493,7 +936,12 @@ func (args *PutArgs) IsValid() bool {
 	if args == nil {
 		return false
 	}
-	return args.Size > 0
+	if args.ContentSHA256 != "" {
+		if sum, err := hex.DecodeString(args.ContentSHA256); err != nil || len(sum) != sha256.Size {
+			return false
+		}
+	}
+	return args.Size > 0 && args.ExpireAt >= 0 && args.Compression.IsValid()
 }
 
 // PutResp put response result
@@ -510,6 +958,7 @@ type PutAtArgs struct {
 	Size      int64           `json:"size"`
 	Hashes    HashAlgorithm   `json:"hashes,omitempty"`
 	Token     string          `json:"token"`
+	ExpireAt  int64           `json:"expire_at,omitempty"` // unix seconds, 0 means never expire
 	Body      io.Reader       `json:"-"`
 }
 
@@ -521,7 +970,8 @@ func (args *PutAtArgs) IsValid() bool {
 	return args.ClusterID > proto.ClusterID(0) &&
 		args.Vid > proto.Vid(0) &&
 		args.Blobid > proto.BlobID(0) &&
-		args.Size > 0
+		args.Size > 0 &&
+		args.ExpireAt >= 0
 }
 
 // PutAtResp putat response result
@@ -569,7 +1019,35 @@ func (args *GetArgs) IsValid() bool {
 	if args == nil {
 		return false
 	}
-	return args.Offset+args.ReadSize <= args.Location.Size
+	return args.Offset+args.ReadSize <= args.Location.RawSize()
+}
+
+// Range one byte range of object
+type Range struct {
+	Offset   uint64 `json:"offset"`
+	ReadSize uint64 `json:"read_size"`
+}
+
+// GetRangesArgs for service /get/ranges
+type GetRangesArgs struct {
+	Location Location `json:"location"`
+	Ranges   []Range  `json:"ranges"`
+}
+
+// IsValid is valid get ranges args
+func (args *GetRangesArgs) IsValid() bool {
+	if args == nil {
+		return false
+	}
+	if len(args.Ranges) == 0 || len(args.Ranges) > MaxGetRanges {
+		return false
+	}
+	for _, r := range args.Ranges {
+		if r.ReadSize == 0 || r.Offset+r.ReadSize > args.Location.RawSize() {
+			return false
+		}
+	}
+	return true
 }
 
 // DeleteArgs for service /delete
@@ -630,3 +1108,86 @@ func (args *SignArgs) IsValid() bool {
 type SignResp struct {
 	Location Location `json:"location"`
 }
+
+// MultipartInitArgs for service /multipart/init
+// Size is the whole object size, all blobs are allocated when initiating
+// PartSize is size of every part but the last one, aligned with blob size
+// ExpireAt is expiry of all parts, unix seconds, 0 means never expire
+type MultipartInitArgs struct {
+	Size     uint64 `json:"size"`
+	PartSize uint64 `json:"part_size,omitempty"`
+	ExpireAt int64  `json:"expire_at,omitempty"`
+}
+
+// IsValid is valid multipart init args
+func (args *MultipartInitArgs) IsValid() bool {
+	if args == nil {
+		return false
+	}
+	return args.Size > 0 && args.ExpireAt >= 0
+}
+
+// MultipartInitResp multipart init response
+// upload parts with number in [1, PartCount]
+type MultipartInitResp struct {
+	UploadID  string `json:"upload_id"`
+	PartSize  uint64 `json:"part_size"`
+	PartCount int    `json:"part_count"`
+}
+
+// MultipartPutArgs for service /multipart/put
+// Size must be equal to PartSize, but the last part is the rest size
+type MultipartPutArgs struct {
+	UploadID   string        `json:"upload_id"`
+	PartNumber int           `json:"part_number"`
+	Size       int64         `json:"size"`
+	Hashes     HashAlgorithm `json:"hashes,omitempty"`
+	Body       io.Reader     `json:"-"`
+}
+
+// IsValid is valid multipart put args
+func (args *MultipartPutArgs) IsValid() bool {
+	if args == nil {
+		return false
+	}
+	return args.UploadID != "" && args.PartNumber > 0 && args.Size > 0
+}
+
+// MultipartPutResp multipart put response result
+type MultipartPutResp struct {
+	HashSumMap HashSumMap `json:"hashsum"`
+}
+
+// MultipartPart one uploaded part of multipart upload
+type MultipartPart struct {
+	PartNumber int        `json:"part_number"`
+	Size       int64      `json:"size"`
+	HashSumMap HashSumMap `json:"hashsum"`
+}
+
+// MultipartUploadArgs for service /multipart/list /multipart/complete /multipart/abort
+type MultipartUploadArgs struct {
+	UploadID string `json:"upload_id"`
+}
+
+// IsValid is valid multipart upload args
+func (args *MultipartUploadArgs) IsValid() bool {
+	if args == nil {
+		return false
+	}
+	return args.UploadID != ""
+}
+
+// MultipartListResp multipart list response with uploaded parts
+type MultipartListResp struct {
+	UploadID  string          `json:"upload_id"`
+	Size      uint64          `json:"size"`
+	PartSize  uint64          `json:"part_size"`
+	PartCount int             `json:"part_count"`
+	Parts     []MultipartPart `json:"parts"`
+}
+
+// MultipartCompleteResp multipart complete response with the whole location
+type MultipartCompleteResp struct {
+	Location Location `json:"location"`
+}
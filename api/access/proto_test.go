@@ -19,9 +19,12 @@ import (
 	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
+	"encoding/binary"
 	"hash/crc32"
 	"math"
 	mrand "math/rand"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -297,7 +300,7 @@ func TestLocationEncodeDecode(t *testing.T) {
 	for ii := 0; ii < 100; ii++ {
 		loc := &access.Location{
 			ClusterID: proto.ClusterID(mrand.Uint32()),
-			CodeMode:  codemode.CodeMode(mrand.Intn(0xff)),
+			CodeMode:  codemode.CodeMode(mrand.Intn(0x80)), // the highest bit is version flag
 			Size:      mrand.Uint64(),
 			BlobSize:  mrand.Uint32(),
 			Crc:       mrand.Uint32(),
@@ -378,6 +381,126 @@ func TestLocationDecodeError(t *testing.T) {
 	}
 }
 
+func TestLocationDecodeHugeLength(t *testing.T) {
+	uvarint := func(val uint64) []byte {
+		buf := make([]byte, binary.MaxVarintLen64)
+		return buf[:binary.PutUvarint(buf, val)]
+	}
+	v1 := (&access.Location{CodeMode: codemode.EC6P6, Size: 1, BlobSize: 1}).Encode()
+	v2 := (&access.Location{CodeMode: codemode.EC6P6, Size: 1, BlobSize: 1, Version: access.LocationVersion2}).Encode()
+	// trim length of blobs in v1, and flags in v2
+	v1, v2 = v1[:len(v1)-1], v2[:len(v2)-1]
+
+	for _, val := range []uint64{1 << 20, 1 << 31, 1 << 32, 1 << 62, 1 << 63, math.MaxUint64} {
+		for _, cs := range []struct {
+			name   string
+			prefix []byte
+			flags  []byte
+			tail   []byte
+		}{
+			{"blobs", v1, nil, nil},
+			{"blob crcs", v2, []byte{1 << 0}, []byte{1, 2, 3, 4}},
+			{"hashes", v2, []byte{1 << 1}, []byte{1, 2, 3, 4}},
+			{"compression blocks", v2, []byte{1 << 2, 1, 1, 1}, []byte{1, 2, 3, 4}},
+		} {
+			buf := append(append([]byte{}, cs.prefix...), cs.flags...)
+			buf = append(append(buf, uvarint(val)...), cs.tail...)
+			_, _, err := access.DecodeLocation(buf)
+			require.Error(t, err, cs.name, val)
+		}
+	}
+}
+
+func TestLocationEncodeDecodeV2(t *testing.T) {
+	for ii := 0; ii < 100; ii++ {
+		loc := &access.Location{
+			ClusterID: proto.ClusterID(mrand.Uint32()),
+			CodeMode:  codemode.CodeMode(mrand.Intn(0xff)),
+			Size:      mrand.Uint64(),
+			BlobSize:  mrand.Uint32(),
+			Crc:       mrand.Uint32(),
+			Version:   access.LocationVersion2,
+		}
+		num := mrand.Intn(5)
+		for i := 0; i < num; i++ {
+			loc.Blobs = append(loc.Blobs, access.SliceInfo{
+				MinBid: proto.BlobID(mrand.Uint64()),
+				Vid:    proto.Vid(mrand.Uint32()),
+				Count:  mrand.Uint32(),
+			})
+		}
+		if mrand.Intn(2) == 0 {
+			loc.BlobCrcs = make([]uint32, mrand.Intn(100)+1)
+			for idx := range loc.BlobCrcs {
+				loc.BlobCrcs[idx] = mrand.Uint32()
+			}
+		}
+		if mrand.Intn(2) == 0 {
+			hashSumMap := access.HashAlgorithm(mrand.Intn(0x10) + 1).ToHashSumMap()
+			for alg := range hashSumMap {
+				hasher := alg.ToHasher()
+				hasher.Write([]byte{byte(ii)})
+				hashSumMap[alg] = hasher.Sum(nil)
+			}
+			loc.HashSumMap = hashSumMap
+		}
+		if mrand.Intn(2) == 0 {
+			loc.Compression = &access.Compression{
+				Codec:     access.CompressCodec(mrand.Intn(3)),
+				RawSize:   mrand.Uint64(),
+				BlockSize: mrand.Uint32(),
+				Blocks:    make([]uint32, mrand.Intn(100)+1),
+			}
+			for idx := range loc.Compression.Blocks {
+				loc.Compression.Blocks[idx] = mrand.Uint32()
+			}
+		}
+		if mrand.Intn(2) == 0 {
+			loc.Encryption = &access.Encryption{
+				KeyID:      mrand.Uint32(),
+				WrappedKey: make([]byte, 60),
+				IV:         make([]byte, 16),
+			}
+			rand.Read(loc.Encryption.WrappedKey)
+			rand.Read(loc.Encryption.IV)
+		}
+		loc.Dedup = mrand.Intn(2) == 0
+		if mrand.Intn(2) == 0 {
+			loc.Tenant = "tenant-" + strconv.Itoa(mrand.Intn(100))
+		}
+		if loc.Dedup && mrand.Intn(2) == 0 {
+			loc.DedupRef = "ref-" + strconv.Itoa(mrand.Intn(100))
+		}
+
+		buf := loc.Encode()
+		require.LessOrEqual(t, len(buf), loc.EncodeSize())
+		locx, n, err := access.DecodeLocation(buf)
+		require.NoError(t, err)
+		require.Equal(t, len(buf), n)
+		require.Equal(t, *loc, locx)
+		locy := loc.Copy()
+		require.Equal(t, buf, locy.Encode())
+
+		for _, n := range []int{n / 2, n - 1} {
+			_, _, err := access.DecodeLocation(buf[:n])
+			require.Error(t, err)
+		}
+	}
+
+	// v1 is decoded without version
+	loc := access.Location{CodeMode: codemode.EC6P6, Size: 1, BlobSize: 1}
+	locx, _, err := access.DecodeLocation(loc.Encode())
+	require.NoError(t, err)
+	require.Equal(t, uint8(0), locx.Version)
+	loc.Version = access.LocationVersion1
+	require.Equal(t, locx.Encode(), loc.Encode())
+
+	// unsupported version
+	loc.Version = access.LocationVersion2 + 1
+	_, _, err = access.DecodeLocation(loc.Encode())
+	require.Error(t, err)
+}
+
 func TestLocationSpread(t *testing.T) {
 	{
 		var loc access.Location
@@ -461,6 +584,46 @@ func TestPutArgs(t *testing.T) {
 		args := access.PutArgs{Size: cs.size}
 		require.Equal(t, cs.valid, args.IsValid())
 	}
+
+	args := access.PutArgs{Size: 1, Compression: access.CompressZstd}
+	require.True(t, args.IsValid())
+	args.Compression++
+	require.False(t, args.IsValid())
+
+	args = access.PutArgs{Size: 1, ContentSHA256: strings.Repeat("0a", 32)}
+	require.True(t, args.IsValid())
+	for _, sum := range []string{"0a", strings.Repeat("0a", 33), strings.Repeat("xx", 32)} {
+		args.ContentSHA256 = sum
+		require.False(t, args.IsValid())
+	}
+
+	args = access.PutArgs{Size: 1, ExpireAt: 1652345678}
+	require.True(t, args.IsValid())
+	args.ExpireAt = -1
+	require.False(t, args.IsValid())
+}
+
+func TestCompressCodec(t *testing.T) {
+	for _, codec := range []access.CompressCodec{access.CompressNone, access.CompressSnappy, access.CompressZstd} {
+		parsed, err := access.ParseCompressCodec(codec.String())
+		require.NoError(t, err)
+		require.Equal(t, codec, parsed)
+	}
+	codec, err := access.ParseCompressCodec("")
+	require.NoError(t, err)
+	require.Equal(t, access.CompressNone, codec)
+	_, err = access.ParseCompressCodec("gzip")
+	require.Error(t, err)
+
+	c := &access.Compression{Codec: access.CompressSnappy, RawSize: 10, BlockSize: 4, Blocks: []uint32{3, 4, 2}}
+	require.Equal(t, uint32(4), c.BlockRawSize(0))
+	require.Equal(t, uint32(2), c.BlockRawSize(2))
+	require.True(t, c.IsValid(9))
+	require.False(t, c.IsValid(10))
+	c.Blocks[2] = 3
+	require.False(t, c.IsValid(10))
+	c.Blocks = c.Blocks[:2]
+	require.False(t, c.IsValid(7))
 }
 
 func TestPutAtArgs(t *testing.T) {
@@ -525,6 +688,32 @@ func TestAllocArgs(t *testing.T) {
 func TestGetArgs(t *testing.T) {
 	args := access.GetArgs{}
 	require.True(t, args.IsValid())
+
+	args.Location.Size = 10
+	args.Location.Compression = &access.Compression{RawSize: 100}
+	args.ReadSize = 100
+	require.True(t, args.IsValid())
+	args.Offset = 1
+	require.False(t, args.IsValid())
+}
+
+func TestGetRangesArgs(t *testing.T) {
+	args := access.GetRangesArgs{Location: access.Location{Size: 10}}
+	require.False(t, args.IsValid())
+	require.False(t, (*access.GetRangesArgs)(nil).IsValid())
+	args.Ranges = []access.Range{{Offset: 0, ReadSize: 10}}
+	require.True(t, args.IsValid())
+	args.Ranges = []access.Range{{Offset: 1, ReadSize: 10}}
+	require.False(t, args.IsValid())
+	args.Ranges = []access.Range{{Offset: 1, ReadSize: 0}}
+	require.False(t, args.IsValid())
+	args.Ranges = make([]access.Range, access.MaxGetRanges+1)
+	for idx := range args.Ranges {
+		args.Ranges[idx].ReadSize = 1
+	}
+	require.False(t, args.IsValid())
+	args.Ranges = args.Ranges[:access.MaxGetRanges]
+	require.True(t, args.IsValid())
 }
 
 func TestDeleteArgs(t *testing.T) {
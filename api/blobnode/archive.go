@@ -0,0 +1,300 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blobnode
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+
+	"github.com/cubefs/blobstore/common/proto"
+)
+
+// Chunk archive is a self-describing stream of a chunk:
+//  --------------
+// | magic number |   ---- 4 bytes
+// | version      |   ---- 1 byte
+// | meta length  |   ---- 4 bytes
+// | meta         |   ---- json of ChunkArchiveMeta
+// | meta crc     |   ---- 4 bytes
+//  --------------
+// | shard record |   ---- tag(1) bid(8) size(4) crc(4) data(size)
+// | shard record |   ---- tag(1) bid(8) size(4) crc(4) expire(8) data(size)
+// |    ....      |
+// | end record   |   ---- tag(1) shard count(8)
+//  --------------
+
+const (
+	ChunkArchiveVersion = uint8(1)
+
+	archiveTagShard       = uint8(1)
+	archiveTagEnd         = uint8(2)
+	archiveTagExpireShard = uint8(3) // shard record with expiry
+
+	archiveMaxMetaSize     = 1 << 20
+	archiveShardHeaderSize = 1 + 8 + 4 + 4
+)
+
+var chunkArchiveMagic = [4]byte{0x62, 0x63, 0x61, 0x72}
+
+var (
+	ErrArchiveMagic      = errors.New("chunk archive: magic not match")
+	ErrArchiveVersion    = errors.New("chunk archive: unsupported version")
+	ErrArchiveMeta       = errors.New("chunk archive: meta corrupted")
+	ErrArchiveTag        = errors.New("chunk archive: unknown record tag")
+	ErrArchiveShardCount = errors.New("chunk archive: shard count not match")
+	ErrArchiveWriteSize  = errors.New("chunk archive: write size not match")
+	ErrArchiveClosed     = errors.New("chunk archive: closed")
+)
+
+// ChunkArchiveMeta describes the exported chunk
+type ChunkArchiveMeta struct {
+	Version     uint8        `json:"version"`
+	Vuid        proto.Vuid   `json:"vuid"`
+	DiskID      proto.DiskID `json:"diskid"`
+	ChunkId     ChunkId      `json:"chunkname"`
+	ParentChunk ChunkId      `json:"parentchunk"`
+	ChunkSize   int64        `json:"chunksize"`
+	Ctime       int64        `json:"ctime"` // nsec
+	Mtime       int64        `json:"mtime"` // nsec
+	Status      ChunkStatus  `json:"status"`
+}
+
+// ChunkArchiveShard is header of a shard record
+type ChunkArchiveShard struct {
+	Bid      proto.BlobID `json:"bid"`
+	Size     uint32       `json:"size"`
+	Crc      uint32       `json:"crc"`
+	ExpireAt int64        `json:"expire_at,omitempty"` // unix seconds, 0 means never expire
+}
+
+// ChunkArchiveWriter writes chunk archive, like archive/tar:
+// WriteShard begins a new shard record, then data of shard is written by Write.
+type ChunkArchiveWriter struct {
+	w      io.Writer
+	remain int64
+	count  uint64
+	closed bool
+}
+
+func NewChunkArchiveWriter(w io.Writer, meta *ChunkArchiveMeta) (*ChunkArchiveWriter, error) {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4+1+4, 4+1+4+len(data)+4)
+	copy(buf, chunkArchiveMagic[:])
+	buf[4] = ChunkArchiveVersion
+	binary.BigEndian.PutUint32(buf[5:], uint32(len(data)))
+	buf = append(buf, data...)
+	buf = append(buf, make([]byte, 4)...)
+	binary.BigEndian.PutUint32(buf[len(buf)-4:], crc32.ChecksumIEEE(data))
+
+	if _, err = w.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return &ChunkArchiveWriter{w: w}, nil
+}
+
+func (aw *ChunkArchiveWriter) WriteShard(hdr *ChunkArchiveShard) error {
+	if aw.closed {
+		return ErrArchiveClosed
+	}
+	if aw.remain != 0 {
+		return ErrArchiveWriteSize
+	}
+
+	buf := make([]byte, archiveShardHeaderSize, archiveShardHeaderSize+8)
+	buf[0] = archiveTagShard
+	binary.BigEndian.PutUint64(buf[1:], uint64(hdr.Bid))
+	binary.BigEndian.PutUint32(buf[9:], hdr.Size)
+	binary.BigEndian.PutUint32(buf[13:], hdr.Crc)
+	if hdr.ExpireAt != 0 {
+		buf[0] = archiveTagExpireShard
+		buf = buf[:archiveShardHeaderSize+8]
+		binary.BigEndian.PutUint64(buf[archiveShardHeaderSize:], uint64(hdr.ExpireAt))
+	}
+	if _, err := aw.w.Write(buf); err != nil {
+		return err
+	}
+
+	aw.remain = int64(hdr.Size)
+	aw.count++
+	return nil
+}
+
+// Write writes data of current shard
+func (aw *ChunkArchiveWriter) Write(p []byte) (n int, err error) {
+	if aw.closed {
+		return 0, ErrArchiveClosed
+	}
+	if int64(len(p)) > aw.remain {
+		return 0, ErrArchiveWriteSize
+	}
+
+	n, err = aw.w.Write(p)
+	aw.remain -= int64(n)
+	return
+}
+
+// Close writes the end record, the archive is incomplete without it
+func (aw *ChunkArchiveWriter) Close() error {
+	if aw.closed {
+		return ErrArchiveClosed
+	}
+	if aw.remain != 0 {
+		return ErrArchiveWriteSize
+	}
+	aw.closed = true
+
+	buf := make([]byte, 1+8)
+	buf[0] = archiveTagEnd
+	binary.BigEndian.PutUint64(buf[1:], aw.count)
+	_, err := aw.w.Write(buf)
+	return err
+}
+
+// ChunkArchiveReader reads chunk archive, Next returns io.EOF after the end record.
+type ChunkArchiveReader struct {
+	r      io.Reader
+	meta   ChunkArchiveMeta
+	remain int64
+	count  uint64
+	eof    bool
+}
+
+func NewChunkArchiveReader(r io.Reader) (*ChunkArchiveReader, error) {
+	buf := make([]byte, 4+1+4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	var magic [4]byte
+	copy(magic[:], buf)
+	if magic != chunkArchiveMagic {
+		return nil, ErrArchiveMagic
+	}
+	if buf[4] != ChunkArchiveVersion {
+		return nil, ErrArchiveVersion
+	}
+
+	size := binary.BigEndian.Uint32(buf[5:])
+	if size > archiveMaxMetaSize {
+		return nil, ErrArchiveMeta
+	}
+	data := make([]byte, size+4)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(data[:size]) != binary.BigEndian.Uint32(data[size:]) {
+		return nil, ErrArchiveMeta
+	}
+
+	ar := &ChunkArchiveReader{r: r}
+	if err := json.Unmarshal(data[:size], &ar.meta); err != nil {
+		return nil, fmt.Errorf("%s: %v", ErrArchiveMeta.Error(), err)
+	}
+
+	return ar, nil
+}
+
+func (ar *ChunkArchiveReader) Meta() ChunkArchiveMeta {
+	return ar.meta
+}
+
+// Next skips the rest data of current shard, and returns header of the next shard
+func (ar *ChunkArchiveReader) Next() (*ChunkArchiveShard, error) {
+	if ar.eof {
+		return nil, io.EOF
+	}
+
+	if ar.remain > 0 {
+		if _, err := io.CopyN(ioutil.Discard, ar.r, ar.remain); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		ar.remain = 0
+	}
+
+	tag := make([]byte, 1)
+	if _, err := io.ReadFull(ar.r, tag); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	switch tag[0] {
+	case archiveTagShard, archiveTagExpireShard:
+		buf := make([]byte, archiveShardHeaderSize-1, archiveShardHeaderSize-1+8)
+		if tag[0] == archiveTagExpireShard {
+			buf = buf[:archiveShardHeaderSize-1+8]
+		}
+		if _, err := io.ReadFull(ar.r, buf); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		hdr := &ChunkArchiveShard{
+			Bid:  proto.BlobID(binary.BigEndian.Uint64(buf[0:])),
+			Size: binary.BigEndian.Uint32(buf[8:]),
+			Crc:  binary.BigEndian.Uint32(buf[12:]),
+		}
+		if tag[0] == archiveTagExpireShard {
+			hdr.ExpireAt = int64(binary.BigEndian.Uint64(buf[16:]))
+		}
+		ar.remain = int64(hdr.Size)
+		ar.count++
+		return hdr, nil
+
+	case archiveTagEnd:
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(ar.r, buf); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if binary.BigEndian.Uint64(buf) != ar.count {
+			return nil, ErrArchiveShardCount
+		}
+		ar.eof = true
+		return nil, io.EOF
+
+	default:
+		return nil, ErrArchiveTag
+	}
+}
+
+// Read reads data of current shard
+func (ar *ChunkArchiveReader) Read(p []byte) (n int, err error) {
+	if ar.remain <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > ar.remain {
+		p = p[:ar.remain]
+	}
+
+	n, err = ar.r.Read(p)
+	ar.remain -= int64(n)
+	if err == io.EOF && ar.remain > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blobnode
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/common/proto"
+)
+
+func TestChunkArchive(t *testing.T) {
+	meta := &ChunkArchiveMeta{
+		Version:   1,
+		Vuid:      proto.Vuid(2001),
+		DiskID:    proto.DiskID(101),
+		ChunkId:   NewChunkId(proto.Vuid(2001)),
+		ChunkSize: 1 << 30,
+		Status:    ChunkStatusReadOnly,
+	}
+	datas := [][]byte{[]byte("first shard"), {}, bytes.Repeat([]byte("x"), 100<<10)}
+
+	buf := &bytes.Buffer{}
+	aw, err := NewChunkArchiveWriter(buf, meta)
+	require.NoError(t, err)
+	for i, data := range datas {
+		hdr := &ChunkArchiveShard{Bid: proto.BlobID(i + 1), Size: uint32(len(data)), Crc: crc32.ChecksumIEEE(data), ExpireAt: int64(i)}
+		require.NoError(t, aw.WriteShard(hdr))
+		_, err = io.Copy(aw, bytes.NewReader(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, aw.Close())
+	require.Equal(t, ErrArchiveClosed, aw.Close())
+	archive := buf.Bytes()
+
+	ar, err := NewChunkArchiveReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.Equal(t, *meta, ar.Meta())
+	for i, data := range datas {
+		hdr, err := ar.Next()
+		require.NoError(t, err)
+		require.Equal(t, proto.BlobID(i+1), hdr.Bid)
+		require.Equal(t, uint32(len(data)), hdr.Size)
+		require.Equal(t, crc32.ChecksumIEEE(data), hdr.Crc)
+		require.Equal(t, int64(i), hdr.ExpireAt)
+		got, err := ioutil.ReadAll(ar)
+		require.NoError(t, err)
+		require.Equal(t, data, got)
+	}
+	_, err = ar.Next()
+	require.Equal(t, io.EOF, err)
+
+	// skip data of shards
+	ar, err = NewChunkArchiveReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	for range datas {
+		_, err = ar.Next()
+		require.NoError(t, err)
+	}
+	_, err = ar.Next()
+	require.Equal(t, io.EOF, err)
+
+	// truncated
+	ar, err = NewChunkArchiveReader(bytes.NewReader(archive[:len(archive)-20]))
+	require.NoError(t, err)
+	for err == nil {
+		_, err = ar.Next()
+	}
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// bad magic
+	bad := append([]byte{}, archive...)
+	bad[0] = 0
+	_, err = NewChunkArchiveReader(bytes.NewReader(bad))
+	require.Equal(t, ErrArchiveMagic, err)
+
+	// bad meta
+	bad = append([]byte{}, archive...)
+	bad[10]++
+	_, err = NewChunkArchiveReader(bytes.NewReader(bad))
+	require.Equal(t, ErrArchiveMeta, err)
+
+	// shard count not match
+	bad = append([]byte{}, archive...)
+	bad[len(bad)-1]++
+	ar, err = NewChunkArchiveReader(bytes.NewReader(bad))
+	require.NoError(t, err)
+	for err == nil {
+		_, err = ar.Next()
+	}
+	require.Equal(t, ErrArchiveShardCount, err)
+}
+
+func TestChunkArchiveWriteSize(t *testing.T) {
+	aw, err := NewChunkArchiveWriter(ioutil.Discard, &ChunkArchiveMeta{})
+	require.NoError(t, err)
+
+	_, err = aw.Write([]byte("no shard"))
+	require.Equal(t, ErrArchiveWriteSize, err)
+
+	require.NoError(t, aw.WriteShard(&ChunkArchiveShard{Bid: 1, Size: 4}))
+	_, err = aw.Write([]byte("too long"))
+	require.Equal(t, ErrArchiveWriteSize, err)
+	_, err = aw.Write([]byte("ab"))
+	require.NoError(t, err)
+
+	require.Equal(t, ErrArchiveWriteSize, aw.WriteShard(&ChunkArchiveShard{Bid: 2}))
+	require.Equal(t, ErrArchiveWriteSize, aw.Close())
+
+	_, err = aw.Write([]byte("cd"))
+	require.NoError(t, err)
+	require.NoError(t, aw.Close())
+}
@@ -20,10 +20,13 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"time"
 
 	bloberr "github.com/cubefs/blobstore/common/errors"
 	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/rpc"
 )
 
 const (
@@ -37,6 +40,7 @@ const (
 const (
 	ReleaseForUser    = "release for user"
 	ReleaseForCompact = "release for compact"
+	ReleaseForMigrate = "release for migrate"
 )
 
 // Chunk ID
@@ -274,6 +278,77 @@ type CompactChunkArgs struct {
 	Vuid   proto.Vuid   `json:"vuid"`
 }
 
+type MigrateChunkArgs struct {
+	DiskID    proto.DiskID `json:"diskid"`
+	Vuid      proto.Vuid   `json:"vuid"`
+	DstDiskID proto.DiskID `json:"dstdiskid"`
+}
+
+func (c *client) MigrateChunk(ctx context.Context, host string, args *MigrateChunkArgs) (err error) {
+	if !IsValidDiskID(args.DiskID) || !IsValidDiskID(args.DstDiskID) {
+		err = bloberr.ErrInvalidDiskId
+		return
+	}
+
+	urlStr := fmt.Sprintf("%v/chunk/migrate/diskid/%v/vuid/%v/dstdiskid/%v",
+		host, args.DiskID, args.Vuid, args.DstDiskID)
+	err = c.PostWith(ctx, urlStr, nil, nil)
+	return
+}
+
+type ExportChunkArgs struct {
+	DiskID proto.DiskID `json:"diskid"`
+	Vuid   proto.Vuid   `json:"vuid"`
+}
+
+// ExportChunk returns chunk archive of vuid, read it by ChunkArchiveReader
+func (c *client) ExportChunk(ctx context.Context, host string, args *ExportChunkArgs) (body io.ReadCloser, err error) {
+	if !IsValidDiskID(args.DiskID) {
+		err = bloberr.ErrInvalidDiskId
+		return
+	}
+
+	urlStr := fmt.Sprintf("%v/chunk/export/diskid/%v/vuid/%v", host, args.DiskID, args.Vuid)
+	resp, err := c.Get(ctx, urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		err = rpc.ParseResponseErr(resp)
+		return
+	}
+
+	return resp.Body, nil
+}
+
+type ImportChunkArgs struct {
+	DiskID proto.DiskID `json:"diskid"`
+	Vuid   proto.Vuid   `json:"vuid"`
+	Body   io.Reader    `json:"-"`
+}
+
+// ImportChunk creates chunk of vuid from chunk archive
+func (c *client) ImportChunk(ctx context.Context, host string, args *ImportChunkArgs) (err error) {
+	if !IsValidDiskID(args.DiskID) {
+		err = bloberr.ErrInvalidDiskId
+		return
+	}
+	if args.Body == nil {
+		err = bloberr.ErrInvalidParam
+		return
+	}
+
+	urlStr := fmt.Sprintf("%v/chunk/import/diskid/%v/vuid/%v", host, args.DiskID, args.Vuid)
+	req, err := http.NewRequest(http.MethodPost, urlStr, args.Body)
+	if err != nil {
+		return
+	}
+	err = c.DoWith(ctx, req, nil)
+	return
+}
+
 type DiskProbeArgs struct {
 	Path string `json:"path"`
 }
@@ -70,12 +70,29 @@ func (c *client) DiskInfo(ctx context.Context, host string, args *DiskStatArgs)
 	return
 }
 
+type DiskAttachArgs struct {
+	Path string `json:"path"`
+}
+
+// AttachDisk formats and registers a new disk at path, which replaces the broken one
+func (c *client) AttachDisk(ctx context.Context, host string, args *DiskAttachArgs) (di *DiskInfo, err error) {
+	if args.Path == "" {
+		return nil, errors.ErrInvalidParam
+	}
+
+	urlStr := fmt.Sprintf("%v/disk/attach", host)
+	di = new(DiskInfo)
+	err = c.PostWith(ctx, urlStr, di, args)
+	return
+}
+
 type StorageAPI interface {
 	String(ctx context.Context, host string) string
 	IsOnline(ctx context.Context, host string) bool
 	Close(ctx context.Context, host string) error
 	Stat(ctx context.Context, host string) (infos []*DiskInfo, err error)
 	DiskInfo(ctx context.Context, host string, args *DiskStatArgs) (di *DiskInfo, err error)
+	AttachDisk(ctx context.Context, host string, args *DiskAttachArgs) (di *DiskInfo, err error)
 
 	// chunks
 	CreateChunk(ctx context.Context, host string, args *CreateChunkArgs) (err error)
@@ -84,12 +101,16 @@ type StorageAPI interface {
 	SetChunkReadonly(ctx context.Context, host string, args *ChangeChunkStatusArgs) (err error)
 	SetChunkReadwrite(ctx context.Context, host string, args *ChangeChunkStatusArgs) (err error)
 	ListChunks(ctx context.Context, host string, args *ListChunkArgs) (cis []*ChunkInfo, err error)
+	MigrateChunk(ctx context.Context, host string, args *MigrateChunkArgs) (err error)
+	ExportChunk(ctx context.Context, host string, args *ExportChunkArgs) (body io.ReadCloser, err error)
+	ImportChunk(ctx context.Context, host string, args *ImportChunkArgs) (err error)
 
 	// shard
 	GetShard(ctx context.Context, host string, args *GetShardArgs) (body io.ReadCloser, shardCrc uint32, err error)
 	RangeGetShard(ctx context.Context, host string, args *RangeGetShardArgs) (body io.ReadCloser, shardCrc uint32, err error)
 	GetShards(ctx context.Context, host string, args *GetShardsArgs) (body io.ReadCloser, err error)
 	PutShard(ctx context.Context, host string, args *PutShardArgs) (crc uint32, err error)
+	PutShards(ctx context.Context, host string, args *PutShardsArgs) (ret *PutShardsRet, err error)
 	StatShard(ctx context.Context, host string, args *StatShardArgs) (si *ShardInfo, err error)
 	MarkDeleteShard(ctx context.Context, host string, args *DeleteShardArgs) (err error)
 	DeleteShard(ctx context.Context, host string, args *DeleteShardArgs) (err error)
@@ -17,6 +17,8 @@ package blobnode
 import (
 	"bytes"
 	"context"
+	"hash/crc32"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -24,7 +26,9 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	bloberr "github.com/cubefs/blobstore/common/errors"
 	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/rpc"
 	"github.com/cubefs/blobstore/common/trace"
 )
 
@@ -55,6 +59,12 @@ func TestNewBlobNodeClient(t *testing.T) {
 	require.NoError(t, err)
 	span.Infof("disk info: %v\n", diskInfo)
 
+	_, err = cli.AttachDisk(ctx, mockServer.URL, &DiskAttachArgs{})
+	require.Error(t, err)
+	diskInfo, err = cli.AttachDisk(ctx, mockServer.URL, &DiskAttachArgs{Path: "/home/service/disks/data1"})
+	require.NoError(t, err)
+	span.Infof("attached disk info: %v\n", diskInfo)
+
 	creteChunkArgs := &CreateChunkArgs{
 		DiskID: diskid,
 		Vuid:   20001,
@@ -84,6 +94,29 @@ func TestNewBlobNodeClient(t *testing.T) {
 	require.NoError(t, err)
 	span.Infof("chunks: %v\n", chunks)
 
+	migrateChunkArgs := &MigrateChunkArgs{
+		DiskID:    diskid,
+		Vuid:      20005,
+		DstDiskID: diskid + 1,
+	}
+	err = cli.MigrateChunk(ctx, mockServer.URL, migrateChunkArgs)
+	require.NoError(t, err)
+	migrateChunkArgs.DstDiskID = proto.InvalidDiskID
+	err = cli.MigrateChunk(ctx, mockServer.URL, migrateChunkArgs)
+	require.Error(t, err)
+
+	exportChunkArgs := &ExportChunkArgs{DiskID: diskid, Vuid: 20005}
+	archive, err := cli.ExportChunk(ctx, mockServer.URL, exportChunkArgs)
+	require.NoError(t, err)
+	archive.Close()
+
+	importChunkArgs := &ImportChunkArgs{DiskID: diskid, Vuid: 20005}
+	err = cli.ImportChunk(ctx, mockServer.URL, importChunkArgs)
+	require.Error(t, err)
+	importChunkArgs.Body = bytes.NewReader(nil)
+	err = cli.ImportChunk(ctx, mockServer.URL, importChunkArgs)
+	require.NoError(t, err)
+
 	databytes := []byte("test context")
 	putShardArgs := &PutShardArgs{
 		DiskID: diskid,
@@ -168,3 +201,80 @@ func TestNewBlobNodeClient(t *testing.T) {
 	_ = cli.Close(ctx, mockServer.URL)
 	_ = cli.IsOnline(ctx, mockServer.URL)
 }
+
+func TestPutShards(t *testing.T) {
+	ctx := context.Background()
+
+	rpc.RegisterArgsParser(&PutShardsArgs{}, "json")
+	router := rpc.New()
+	router.Handle(http.MethodPost, "/shards/put/diskid/:diskid/vuid/:vuid", func(c *rpc.Context) {
+		args := new(PutShardsArgs)
+		if err := c.ParseArgs(args); err != nil {
+			c.RespondError(err)
+			return
+		}
+		shards, err := DecodePutShardsHeader(c.Request.Body)
+		if err != nil {
+			c.RespondError(err)
+			return
+		}
+		ret := &PutShardsRet{}
+		for _, shard := range shards {
+			data := make([]byte, shard.Size)
+			if _, err = io.ReadFull(c.Request.Body, data); err != nil {
+				c.RespondError(err)
+				return
+			}
+			item := PutShardsRetItem{Bid: shard.Bid, Crc: crc32.ChecksumIEEE(data), Code: http.StatusOK}
+			if shard.Bid == 3 {
+				item.Code = bloberr.CodeOverload
+			}
+			ret.Shards = append(ret.Shards, item)
+		}
+		c.RespondJSON(ret)
+	}, rpc.OptArgsURI(), rpc.OptArgsQuery())
+	mockServer := httptest.NewServer(router)
+	defer mockServer.Close()
+
+	cli := New(&Config{})
+
+	datas := [][]byte{[]byte("shard-1"), []byte("shard-22"), []byte("")}
+	args := &PutShardsArgs{DiskID: 100, Vuid: 20001}
+	for i, data := range datas {
+		args.Shards = append(args.Shards, PutShardsItem{
+			Bid:  proto.BlobID(i + 1),
+			Size: int64(len(data)),
+			Body: bytes.NewReader(data),
+		})
+	}
+	ret, err := cli.PutShards(ctx, mockServer.URL, args)
+	require.NoError(t, err)
+	require.Equal(t, []PutShardsRetItem{
+		{Bid: 1, Crc: crc32.ChecksumIEEE(datas[0]), Code: http.StatusOK},
+		{Bid: 2, Crc: crc32.ChecksumIEEE(datas[1]), Code: http.StatusOK},
+		{Bid: 3, Crc: crc32.ChecksumIEEE(datas[2]), Code: bloberr.CodeOverload},
+	}, ret.Shards)
+
+	_, err = cli.PutShards(ctx, mockServer.URL, &PutShardsArgs{DiskID: 100, Vuid: 20001})
+	require.ErrorIs(t, err, bloberr.ErrInvalidParam)
+	_, err = cli.PutShards(ctx, mockServer.URL, &PutShardsArgs{DiskID: 100, Vuid: 20001,
+		Shards: make([]PutShardsItem, PutShardsLimit+1)})
+	require.ErrorIs(t, err, bloberr.ErrInvalidParam)
+
+	// invalid header
+	_, err = DecodePutShardsHeader(bytes.NewReader(EncodePutShardsHeader(nil)))
+	require.ErrorIs(t, err, bloberr.ErrInvalidParam)
+	_, err = DecodePutShardsHeader(bytes.NewReader(EncodePutShardsHeader(args.Shards)[:10]))
+	require.Error(t, err)
+
+	// expire time of every shard
+	args.Shards[1].ExpireAt = 1652345678
+	shards, err := DecodePutShardsHeader(bytes.NewReader(EncodePutShardsHeader(args.Shards)))
+	require.NoError(t, err)
+	require.Equal(t, []int64{0, 1652345678, 0}, []int64{shards[0].ExpireAt, shards[1].ExpireAt, shards[2].ExpireAt})
+	args.Shards[1].ExpireAt = -1
+	_, err = cli.PutShards(ctx, mockServer.URL, args)
+	require.ErrorIs(t, err, bloberr.ErrInvalidParam)
+	_, err = DecodePutShardsHeader(bytes.NewReader(EncodePutShardsHeader(args.Shards)))
+	require.ErrorIs(t, err, bloberr.ErrInvalidParam)
+}
@@ -28,6 +28,7 @@ type DiskHeartBeatInfo struct {
 	MaxChunkCnt  int64        `json:"max_chunk_cnt"`  // note: maintained by clustermgr
 	FreeChunkCnt int64        `json:"free_chunk_cnt"` // note: maintained by clustermgr
 	UsedChunkCnt int64        `json:"used_chunk_cnt"` // current number of chunks on the disk
+	Slow         bool         `json:"slow,omitempty"` // io latency of disk exceeds the threshold
 }
 
 type DiskInfo struct {
@@ -56,10 +57,11 @@ type ChunkInfo struct {
 }
 
 type ShardInfo struct {
-	Vuid   proto.Vuid   `json:"vuid"`
-	Bid    proto.BlobID `json:"bid"`
-	Size   int64        `json:"size"`
-	Crc    uint32       `json:"crc"`
-	Flag   ShardStatus  `json:"flag"` // 1:normal,2:markDelete
-	Inline bool         `json:"inline"`
+	Vuid     proto.Vuid   `json:"vuid"`
+	Bid      proto.BlobID `json:"bid"`
+	Size     int64        `json:"size"`
+	Crc      uint32       `json:"crc"`
+	Flag     ShardStatus  `json:"flag"` // 1:normal,2:markDelete
+	Inline   bool         `json:"inline"`
+	ExpireAt int64        `json:"expire_at,omitempty"` // unix seconds, 0 means never expire
 }
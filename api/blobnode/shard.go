@@ -15,7 +15,9 @@
 package blobnode
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math"
@@ -45,12 +47,13 @@ const (
 )
 
 type PutShardArgs struct {
-	DiskID proto.DiskID `json:"diskid"`
-	Vuid   proto.Vuid   `json:"vuid"`
-	Bid    proto.BlobID `json:"bid"`
-	Size   int64        `json:"size"`
-	Type   IOType       `json:"iotype,omitempty"`
-	Body   io.Reader    `json:"-"`
+	DiskID   proto.DiskID `json:"diskid"`
+	Vuid     proto.Vuid   `json:"vuid"`
+	Bid      proto.BlobID `json:"bid"`
+	Size     int64        `json:"size"`
+	Type     IOType       `json:"iotype,omitempty"`
+	ExpireAt int64        `json:"expire_at,omitempty"` // unix seconds, 0 means never expire
+	Body     io.Reader    `json:"-"`
 }
 
 type PutShardRet struct {
@@ -71,12 +74,19 @@ func (c *client) PutShard(ctx context.Context, host string, args *PutShardArgs)
 		err = bloberr.ErrInvalidDiskId
 		return
 	}
+	if args.ExpireAt < 0 {
+		err = bloberr.ErrInvalidParam
+		return
+	}
 
 	ret := &PutShardRet{
 		Crc: proto.InvalidCrc32,
 	}
 	urlStr := fmt.Sprintf("%v/shard/put/diskid/%v/vuid/%v/bid/%v/size/%v?iotype=%d",
 		host, args.DiskID, args.Vuid, args.Bid, args.Size, args.Type)
+	if args.ExpireAt > 0 {
+		urlStr += fmt.Sprintf("&expire_at=%d", args.ExpireAt)
+	}
 	req, err := http.NewRequest(http.MethodPost, urlStr, args.Body)
 	if err != nil {
 		return
@@ -90,6 +100,134 @@ func (c *client) PutShard(ctx context.Context, host string, args *PutShardArgs)
 	return
 }
 
+// PutShardsLimit is the max count of shards in one batch put
+const PutShardsLimit = 1024
+
+// put shards request body is header followed by data of shards in order,
+// header is [count uint32] and [bid uint64, size uint32, expire_at uint64] of every shard
+const (
+	putShardsCountLen = 4
+	putShardsItemLen  = 20
+)
+
+type PutShardsItem struct {
+	Bid      proto.BlobID `json:"bid"`
+	Size     int64        `json:"size"`
+	ExpireAt int64        `json:"expire_at,omitempty"` // unix seconds, 0 means never expire
+	Body     io.Reader    `json:"-"`
+}
+
+type PutShardsArgs struct {
+	DiskID proto.DiskID    `json:"diskid"`
+	Vuid   proto.Vuid      `json:"vuid"`
+	Type   IOType          `json:"iotype,omitempty"`
+	Shards []PutShardsItem `json:"-"`
+}
+
+type PutShardsRetItem struct {
+	Bid  proto.BlobID `json:"bid"`
+	Crc  uint32       `json:"crc"`
+	Code int          `json:"code"`
+}
+
+type PutShardsRet struct {
+	Shards []PutShardsRetItem `json:"shards"`
+}
+
+// EncodePutShardsHeader returns header of put shards request body
+func EncodePutShardsHeader(shards []PutShardsItem) []byte {
+	buf := make([]byte, putShardsCountLen+putShardsItemLen*len(shards))
+	binary.BigEndian.PutUint32(buf, uint32(len(shards)))
+	off := putShardsCountLen
+	for _, shard := range shards {
+		binary.BigEndian.PutUint64(buf[off:], uint64(shard.Bid))
+		binary.BigEndian.PutUint32(buf[off+8:], uint32(shard.Size))
+		binary.BigEndian.PutUint64(buf[off+12:], uint64(shard.ExpireAt))
+		off += putShardsItemLen
+	}
+	return buf
+}
+
+// DecodePutShardsHeader reads header of put shards request body,
+// shard data follows in order of returned items.
+func DecodePutShardsHeader(r io.Reader) (shards []PutShardsItem, err error) {
+	buf := make([]byte, putShardsCountLen)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	count := binary.BigEndian.Uint32(buf)
+	if count == 0 || count > PutShardsLimit {
+		return nil, bloberr.ErrInvalidParam
+	}
+
+	buf = make([]byte, putShardsItemLen*int(count))
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	shards = make([]PutShardsItem, 0, count)
+	for off := 0; off < len(buf); off += putShardsItemLen {
+		item := PutShardsItem{
+			Bid:      proto.BlobID(binary.BigEndian.Uint64(buf[off:])),
+			Size:     int64(binary.BigEndian.Uint32(buf[off+8:])),
+			ExpireAt: int64(binary.BigEndian.Uint64(buf[off+12:])),
+		}
+		if item.ExpireAt < 0 {
+			return nil, bloberr.ErrInvalidParam
+		}
+		shards = append(shards, item)
+	}
+	return shards, nil
+}
+
+// PutShards puts shards of one vuid in one request, ret is one-to-one with args.Shards.
+// Code of ret item is http.StatusOK if the shard is put successfully.
+func (c *client) PutShards(ctx context.Context, host string, args *PutShardsArgs) (ret *PutShardsRet, err error) {
+	if len(args.Shards) == 0 || len(args.Shards) > PutShardsLimit {
+		err = bloberr.ErrInvalidParam
+		return
+	}
+	if !args.Type.IsValid() {
+		err = bloberr.ErrInvalidParam
+		return
+	}
+
+	if !IsValidDiskID(args.DiskID) {
+		err = bloberr.ErrInvalidDiskId
+		return
+	}
+
+	header := EncodePutShardsHeader(args.Shards)
+	size := int64(len(header))
+	readers := make([]io.Reader, 0, len(args.Shards)+1)
+	readers = append(readers, bytes.NewReader(header))
+	for _, shard := range args.Shards {
+		if shard.Size > MaxShardSize {
+			err = bloberr.ErrShardSizeTooLarge
+			return
+		}
+		if shard.ExpireAt < 0 {
+			err = bloberr.ErrInvalidParam
+			return
+		}
+		size += shard.Size
+		readers = append(readers, io.LimitReader(shard.Body, shard.Size))
+	}
+
+	ret = &PutShardsRet{}
+	urlStr := fmt.Sprintf("%v/shards/put/diskid/%v/vuid/%v?iotype=%d",
+		host, args.DiskID, args.Vuid, args.Type)
+	req, err := http.NewRequest(http.MethodPost, urlStr, io.MultiReader(readers...))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	if err = c.DoWith(ctx, req, ret, rpc.WithCrcEncode()); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
 type GetShardArgs struct {
 	DiskID proto.DiskID `json:"diskid"`
 	Vuid   proto.Vuid   `json:"vuid"`
@@ -37,14 +37,39 @@ var retryCodes = []int{
 	int(errors.ErrRaftReadIndex),
 }
 
+// HeaderReadConsistency is the request header carrying read consistency of read-only request
+const HeaderReadConsistency = "X-Read-Consistency"
+
+// ReadConsistency indicate how clustermgr serve read-only request
+type ReadConsistency string
+
+const (
+	// ReadBoundedStale allow follower serve read-only request locally
+	// when its applied index lags behind commit index within bound, otherwise request will be forwarded to leader
+	ReadBoundedStale = ReadConsistency("bounded_stale")
+	// ReadLinearizable make follower or leader execute raft ReadIndex before serving read-only request
+	ReadLinearizable = ReadConsistency("linearizable")
+)
+
+type readConsistencyKey struct{}
+
+// WithReadConsistency return context with read consistency, which will overwrite the default read consistency of client
+func WithReadConsistency(ctx context.Context, consistency ReadConsistency) context.Context {
+	return context.WithValue(ctx, readConsistencyKey{}, consistency)
+}
+
 type MemberType uint8
 
 type Config struct {
 	lbClient.LbConfig
+	// ReadConsistency is the default read consistency of read-only requests, default is bounded_stale,
+	// so that read-only requests can be served by followers
+	ReadConsistency ReadConsistency `json:"read_consistency"`
 }
 
 type Client struct {
 	lbClient.Client
+	readConsistency ReadConsistency
 }
 
 var _ ClientAPI = (*Client)(nil)
@@ -63,7 +88,26 @@ func New(cfg *Config) *Client {
 	if cfg.ShouldRetry == nil {
 		cfg.ShouldRetry = defaultShouldRetry
 	}
-	return &Client{lbClient.NewLbClient(&cfg.LbConfig, nil)}
+	if cfg.ReadConsistency == "" {
+		cfg.ReadConsistency = ReadBoundedStale
+	}
+	return &Client{Client: lbClient.NewLbClient(&cfg.LbConfig, nil), readConsistency: cfg.ReadConsistency}
+}
+
+// GetWith send read-only request with read consistency of context or client
+func (c *Client) GetWith(ctx context.Context, url string, ret interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	consistency := c.readConsistency
+	if val, ok := ctx.Value(readConsistencyKey{}).(ReadConsistency); ok {
+		consistency = val
+	}
+	if consistency != "" {
+		req.Header.Set(HeaderReadConsistency, string(consistency))
+	}
+	return c.DoWith(ctx, req, ret)
 }
 
 type BidScopeArgs struct {
@@ -120,3 +164,9 @@ func (c *Client) Stat(ctx context.Context) (ret *StatInfo, err error) {
 func (c *Client) Snapshot(ctx context.Context) (*http.Response, error) {
 	return c.Get(ctx, "/snapshot/dump")
 }
+
+// SnapshotBackup return a self-contained snapshot backup of clustermgr,
+// which can be restored to bootstrap a brand-new clustermgr raft group
+func (c *Client) SnapshotBackup(ctx context.Context) (*http.Response, error) {
+	return c.Get(ctx, "/snapshot/backup")
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clustermgr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/common/rpc"
+)
+
+func TestClientReadConsistency(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		header = req.Header.Get(HeaderReadConsistency)
+		w.Header().Set(rpc.HeaderContentType, rpc.MIMEJSON)
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	cli := New(&Config{LbConfig: rpc.LbConfig{Hosts: []string{server.URL}}})
+	_, err := cli.Stat(ctx)
+	require.NoError(t, err)
+	require.Equal(t, string(ReadBoundedStale), header)
+
+	_, err = cli.Stat(WithReadConsistency(ctx, ReadLinearizable))
+	require.NoError(t, err)
+	require.Equal(t, string(ReadLinearizable), header)
+
+	cli = New(&Config{LbConfig: rpc.LbConfig{Hosts: []string{server.URL}}, ReadConsistency: ReadLinearizable})
+	_, err = cli.Stat(ctx)
+	require.NoError(t, err)
+	require.Equal(t, string(ReadLinearizable), header)
+}
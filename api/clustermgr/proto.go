@@ -34,8 +34,12 @@ type ClusterInfo struct {
 	ClusterID proto.ClusterID `json:"cluster_id"`
 	Capacity  int64           `json:"capacity"`
 	Available int64           `json:"available"`
-	Readonly  bool            `json:"readonly"`
-	Nodes     []string        `json:"nodes"`
+	// Readonly cluster is never chosen for new writes but still readable and deletable,
+	// set it to drain the cluster scheduled for decommission
+	Readonly bool     `json:"readonly"`
+	Nodes    []string `json:"nodes"`
+	// Weight operator-set weight of choosing cluster to write, zero means default
+	Weight uint32 `json:"weight,omitempty"`
 }
 
 type StatInfo struct {
@@ -54,6 +54,8 @@ type VolumeInfoBase struct {
 	Free           uint64             `json:"free"`
 	Used           uint64             `json:"used"`
 	CreateByNodeID uint64             `json:"create_by_node_id"`
+	// blobs of volume were converted into the redirect volume with the same bids
+	RedirectVid proto.Vid `json:"redirect_vid,omitempty"`
 }
 
 type AllocVolumeInfo struct {
@@ -157,6 +159,72 @@ func (c *Client) UnlockVolume(ctx context.Context, args *UnlockVolumeArgs) (err
 	return
 }
 
+type SetVolumeRedirectArgs struct {
+	Vid         proto.Vid `json:"vid"`
+	RedirectVid proto.Vid `json:"redirect_vid"`
+}
+
+// SetVolumeRedirect redirects reading blobs of locked volume Vid to volume RedirectVid,
+// volume Vid can not be unlocked any more
+func (c *Client) SetVolumeRedirect(ctx context.Context, args *SetVolumeRedirectArgs) (err error) {
+	err = c.PostWith(ctx, "/volume/redirect/set", nil, args)
+	return
+}
+
+const (
+	FailureDomainRack = "rack"
+	FailureDomainHost = "host"
+)
+
+// FailureDomainViolation units of one volume placed in the same rack or host
+// are more than the limit of volume's code mode
+type FailureDomainViolation struct {
+	Idc    string       `json:"idc"`
+	Domain string       `json:"domain"`
+	Name   string       `json:"name"`
+	Limit  int          `json:"limit"`
+	Vuids  []proto.Vuid `json:"vuids"`
+}
+
+type VolumeFailureDomainViolation struct {
+	Vid        proto.Vid                `json:"vid"`
+	CodeMode   codemode.CodeMode        `json:"code_mode"`
+	Violations []FailureDomainViolation `json:"violations"`
+}
+
+type ListFailureDomainViolationsArgs struct {
+	// list volumes after Marker marker
+	Marker proto.Vid `json:"marker,omitempty"`
+	// count of volumes to scan in one page
+	Count int `json:"count"`
+}
+
+type ListFailureDomainViolationsRet struct {
+	Volumes []VolumeFailureDomainViolation `json:"volumes"`
+	// last scanned vid, it is zero when all volumes have been scanned
+	Marker proto.Vid `json:"marker"`
+}
+
+// ListFailureDomainViolations scans one page of volumes and returns the volumes
+// whose units violate the failure domain policy of code mode
+func (c *Client) ListFailureDomainViolations(ctx context.Context, args *ListFailureDomainViolationsArgs) (ret ListFailureDomainViolationsRet, err error) {
+	err = c.GetWith(ctx, fmt.Sprintf("/volume/failure_domain/violations?marker=%d&count=%d", args.Marker, args.Count), &ret)
+	return
+}
+
+type PlacementViolations struct {
+	// units should be migrated to restore spread of volumes
+	Vuids []proto.Vuid `json:"vuids"`
+	// unix time of last finished placement audit, zero means not finished yet
+	ScanTime int64 `json:"scan_time"`
+}
+
+// ListPlacementViolations returns the units found by periodic placement audit of clustermgr
+func (c *Client) ListPlacementViolations(ctx context.Context) (ret PlacementViolations, err error) {
+	err = c.GetWith(ctx, "/volume/placement/violations", &ret)
+	return
+}
+
 type AllocVolumeUnitArgs struct {
 	Vuid proto.Vuid `json:"vuid"`
 }
@@ -41,10 +41,13 @@ type IScheduler interface {
 	BalanceTaskDetail(ctx context.Context, args *TaskStatArgs) (ret MigrateTaskDetail, err error)
 	DropTaskDetail(ctx context.Context, args *TaskStatArgs) (ret MigrateTaskDetail, err error)
 	ManualMigrateTaskDetail(ctx context.Context, args *TaskStatArgs) (ret MigrateTaskDetail, err error)
+	VolumeConvertTaskDetail(ctx context.Context, args *TaskStatArgs) (ret VolumeConvertTaskDetail, err error)
 	Stats(ctx context.Context) (ret TasksStat, err error)
 
 	// add manual migrate task
 	AddManualMigrateTask(ctx context.Context, args *AddManualMigrateArgs) (err error)
+	// add volume convert task
+	AddVolumeConvertTask(ctx context.Context, args *AddVolumeConvertArgs) (err error)
 }
 
 type Config struct {
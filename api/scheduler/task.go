@@ -31,6 +31,8 @@ type WorkerTask struct {
 	Balance       *proto.MigrateTask   `json:"balance"`        // balance task
 	DiskDrop      *proto.MigrateTask   `json:"disk_drop"`      // disk drop task
 	ManualMigrate *proto.MigrateTask   `json:"manual_migrate"` // manual migrate task
+
+	VolumeConvert *proto.VolumeConvertTask `json:"volume_convert"` // volume convert task
 }
 
 func (task *WorkerTask) IsValid() bool {
@@ -56,6 +58,8 @@ func (task *WorkerTask) IsValid() bool {
 		mode = task.ManualMigrate.CodeMode
 		destination = task.ManualMigrate.Destination
 		srcs = task.ManualMigrate.Sources
+	case proto.VolumeConvertType:
+		return task.VolumeConvert.IsValid()
 	default:
 		return false
 	}
@@ -110,6 +114,7 @@ type TaskRenewalArgs struct {
 	Balance       map[string]struct{} `json:"balance"`
 	DiskDrop      map[string]struct{} `json:"disk_drop"`
 	ManualMigrate map[string]struct{} `json:"manual_migrate"`
+	VolumeConvert map[string]struct{} `json:"volume_convert"`
 }
 
 type TaskRenewalRet struct {
@@ -117,6 +122,7 @@ type TaskRenewalRet struct {
 	Balance       map[string]string `json:"balance"`
 	DiskDrop      map[string]string `json:"disk_drop"`
 	ManualMigrate map[string]string `json:"manual_migrate"`
+	VolumeConvert map[string]string `json:"volume_convert"`
 }
 
 func (c *client) RenewalTask(ctx context.Context, args *TaskRenewalArgs) (ret *TaskRenewalRet, err error) {
@@ -196,6 +202,19 @@ func (c *client) AddManualMigrateTask(ctx context.Context, args *AddManualMigrat
 	return c.PostWith(ctx, c.Host+"/manual/migrate/task/add", nil, args)
 }
 
+type AddVolumeConvertArgs struct {
+	Vid      proto.Vid         `json:"vid"`
+	CodeMode codemode.CodeMode `json:"code_mode"` // code mode to convert to
+}
+
+func (args *AddVolumeConvertArgs) Valid() bool {
+	return args.Vid != proto.InvalidVid && args.CodeMode.IsValid()
+}
+
+func (c *client) AddVolumeConvertTask(ctx context.Context, args *AddVolumeConvertArgs) (err error) {
+	return c.PostWith(ctx, c.Host+"/volume/convert/task/add", nil, args)
+}
+
 // for task stat
 type TaskStatArgs struct {
 	TaskId string `json:"task_id"`
@@ -211,6 +230,11 @@ type MigrateTaskDetail struct {
 	RunStats proto.TaskStatistics `json:"run_stats"`
 }
 
+type VolumeConvertTaskDetail struct {
+	TaskInfo proto.VolumeConvertTask `json:"task_info"`
+	RunStats proto.TaskStatistics    `json:"run_stats"`
+}
+
 type PerMinStats struct {
 	FinishedCnt    string `json:"finished_cnt"`
 	ShardCnt       string `json:"shard_cnt"`
@@ -252,6 +276,10 @@ type ManualMigrateTasksStat struct {
 	MigrateTasksStat
 }
 
+type VolumeConvertTasksStat struct {
+	MigrateTasksStat
+}
+
 type InspectTasksStats struct {
 	Switch         string `json:"switch"`
 	FinishedPerMin string `json:"finished_per_min"`
@@ -263,6 +291,7 @@ type TasksStat struct {
 	Drop          DiskDropTasksStat      `json:"drop"`
 	Balance       BalanceTasksStat       `json:"balance"`
 	ManualMigrate ManualMigrateTasksStat `json:"manual_migrate"`
+	VolumeConvert VolumeConvertTasksStat `json:"volume_convert"`
 	Inspect       InspectTasksStats      `json:"inspect"`
 }
 
@@ -286,6 +315,11 @@ func (c *client) ManualMigrateTaskDetail(ctx context.Context, args *TaskStatArgs
 	return
 }
 
+func (c *client) VolumeConvertTaskDetail(ctx context.Context, args *TaskStatArgs) (ret VolumeConvertTaskDetail, err error) {
+	err = c.PostWith(ctx, c.Host+"/volume/convert/task/detail", &ret, args)
+	return
+}
+
 func (c *client) Stats(ctx context.Context) (ret TasksStat, err error) {
 	err = c.GetWith(ctx, c.Host+"/stats", &ret)
 	return
@@ -0,0 +1,276 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blobnode
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/blobnode/core/disk"
+	bloberr "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/rpc"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+const archiveListBatch = 1024
+
+/*
+ *  method:         GET
+ *  url:            /chunk/export/diskid/{diskid}/vuid/{vuid}
+ *  response body:  chunk archive
+ */
+func (s *Service) ChunkExport_(c *rpc.Context) {
+	args := new(bnapi.ExportChunkArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Infof("chunk export args:%v", args)
+
+	if !bnapi.IsValidDiskID(args.DiskID) {
+		c.RespondError(bloberr.ErrInvalidDiskId)
+		return
+	}
+
+	ctx = bnapi.Setiotype(ctx, bnapi.BackgroundIO)
+
+	// chunk can not be set readwrite during exporting
+	limitKey := args.Vuid
+	err := s.ChunkLimitPerVuid.Acquire(limitKey)
+	if err != nil {
+		span.Errorf("vuid(%v) status concurry conflict", args.Vuid)
+		c.RespondError(bloberr.ErrOverload)
+		return
+	}
+	defer s.ChunkLimitPerVuid.Release(limitKey)
+
+	s.lock.RLock()
+	ds, exist := s.Disks[args.DiskID]
+	s.lock.RUnlock()
+	if !exist {
+		c.RespondError(bloberr.ErrNoSuchDisk)
+		return
+	}
+
+	cs, exist := ds.GetChunkStorage(args.Vuid)
+	if !exist {
+		c.RespondError(bloberr.ErrNoSuchVuid)
+		return
+	}
+
+	if cs.Status() != bnapi.ChunkStatusReadOnly {
+		span.Errorf("chunk(%s) is not readonly", cs.ID())
+		c.RespondError(bloberr.ErrChunkNotReadonly)
+		return
+	}
+	// wait for modifications before readonly, the exported copy is fixed
+	cs.Freeze(ctx, bnapi.ChunkStatusReadOnly)
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Transfer-Encoding", "binary")
+	w.WriteHeader(http.StatusOK)
+
+	// status can not be responded after header, broken archive has no end record
+	n, err := exportChunk(ctx, cs, w)
+	if err != nil {
+		span.Errorf("Failed export vuid:%v chunk:%s, exported:%d, err:%v", args.Vuid, cs.ID(), n, err)
+		return
+	}
+
+	span.Infof("export vuid:%v chunk:%s success, shards:%d", args.Vuid, cs.ID(), n)
+}
+
+// exportChunk writes normal shards of chunk in order of bid, expired shards are skipped,
+// chunk must be frozen, so that listed shards are not modified during exporting.
+func exportChunk(ctx context.Context, cs core.ChunkAPI, w io.Writer) (n int, err error) {
+	vm := cs.VuidMeta()
+	aw, err := bnapi.NewChunkArchiveWriter(w, &bnapi.ChunkArchiveMeta{
+		Version:     vm.Version,
+		Vuid:        vm.Vuid,
+		DiskID:      vm.DiskID,
+		ChunkId:     vm.ChunkId,
+		ParentChunk: vm.ParentChunk,
+		ChunkSize:   vm.ChunkSize,
+		Ctime:       vm.Ctime,
+		Mtime:       vm.Mtime,
+		Status:      cs.Status(),
+	})
+	if err != nil {
+		return
+	}
+
+	now := time.Now().Unix()
+	startBid := proto.InValidBlobID
+	for {
+		infos, next, err := cs.ListShards(ctx, startBid, archiveListBatch, bnapi.ShardStatusDefault)
+		if err != nil {
+			return n, err
+		}
+
+		for _, info := range infos {
+			if info.Flag != bnapi.ShardStatusNormal || (info.ExpireAt > 0 && info.ExpireAt <= now) {
+				continue
+			}
+
+			err = aw.WriteShard(&bnapi.ChunkArchiveShard{
+				Bid: info.Bid, Size: uint32(info.Size), Crc: info.Crc, ExpireAt: info.ExpireAt,
+			})
+			if err != nil {
+				return n, err
+			}
+
+			shard := core.NewShardReader(info.Bid, cs.Vuid(), 0, 0, aw)
+			if _, err = cs.Read(ctx, shard); err != nil {
+				return n, err
+			}
+			n++
+		}
+
+		if next == proto.InValidBlobID {
+			break
+		}
+		startBid = next
+	}
+
+	return n, aw.Close()
+}
+
+/*
+ *  method:         POST
+ *  url:            /chunk/import/diskid/{diskid}/vuid/{vuid}
+ *  request body:   chunk archive
+ */
+func (s *Service) ChunkImport_(c *rpc.Context) {
+	args := new(bnapi.ImportChunkArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Infof("chunk import args:%v", args)
+
+	if !bnapi.IsValidDiskID(args.DiskID) {
+		c.RespondError(bloberr.ErrInvalidDiskId)
+		return
+	}
+
+	ar, err := bnapi.NewChunkArchiveReader(c.Request.Body)
+	if err != nil {
+		span.Errorf("Failed read chunk archive, err:%v", err)
+		c.RespondError(bloberr.ErrInvalidParam)
+		return
+	}
+
+	meta := ar.Meta()
+	if meta.Vuid != args.Vuid {
+		span.Errorf("archive vuid:%v not match vuid:%v", meta.Vuid, args.Vuid)
+		c.RespondError(bloberr.ErrVuidNotMatch)
+		return
+	}
+	if meta.ChunkSize < 0 || meta.ChunkSize > disk.MaxChunkSize {
+		c.RespondError(bloberr.ErrInvalidParam)
+		return
+	}
+	if meta.ChunkSize == 0 {
+		meta.ChunkSize = core.DefaultChunkSize
+	}
+
+	ctx = bnapi.Setiotype(ctx, bnapi.BackgroundIO)
+
+	limitKey := args.Vuid
+	err = s.ChunkLimitPerVuid.Acquire(limitKey)
+	if err != nil {
+		span.Errorf("can not import chunk with same vuid(%v) at the same time", args.Vuid)
+		c.RespondError(bloberr.ErrOutOfLimit)
+		return
+	}
+	defer s.ChunkLimitPerVuid.Release(limitKey)
+
+	s.lock.RLock()
+	ds, exist := s.Disks[args.DiskID]
+	s.lock.RUnlock()
+	if !exist {
+		c.RespondError(bloberr.ErrNoSuchDisk)
+		return
+	}
+
+	cs, err := ds.CreateChunk(ctx, args.Vuid, meta.ChunkSize)
+	if err != nil {
+		span.Errorf("Failed create vuid:%v, err:%v", args.Vuid, err)
+		c.RespondError(err)
+		return
+	}
+
+	n, err := importChunk(ctx, cs, ar)
+	if err == nil && meta.Status == bnapi.ChunkStatusReadOnly {
+		err = ds.UpdateChunkStatus(ctx, args.Vuid, bnapi.ChunkStatusReadOnly)
+	}
+	if err != nil {
+		span.Errorf("Failed import vuid:%v chunk:%s, imported:%d, err:%v", args.Vuid, cs.ID(), n, err)
+		if rerr := ds.ReleaseChunk(ctx, args.Vuid, true); rerr != nil {
+			span.Errorf("Failed release imported vuid:%v, err:%v", args.Vuid, rerr)
+		}
+		s.invalidateChunkCache(args.Vuid)
+		c.RespondError(err)
+		return
+	}
+
+	span.Infof("import vuid:%d chunk:%s success, shards:%d", args.Vuid, cs.ID(), n)
+}
+
+func importChunk(ctx context.Context, cs core.ChunkAPI, ar *bnapi.ChunkArchiveReader) (n int, err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		if hdr.Bid <= proto.InValidBlobID {
+			return n, bloberr.ErrInvalidParam
+		}
+		if !cs.HasEnoughSpace(int64(hdr.Size)) {
+			return n, bloberr.ErrChunkNoSpace
+		}
+
+		shard := core.NewShardWriter(hdr.Bid, cs.Vuid(), hdr.Size, ar)
+		shard.ExpireAt = hdr.ExpireAt
+		if err = cs.Write(ctx, shard); err != nil {
+			return n, err
+		}
+		if shard.Crc != hdr.Crc {
+			span.Errorf("bid:%v crc:%v not match archive crc:%v", hdr.Bid, shard.Crc, hdr.Crc)
+			return n, bloberr.ErrInvalidParam
+		}
+		n++
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blobnode
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	bloberr "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/rpc"
+)
+
+func TestChunkExportImport(t *testing.T) {
+	service, _ := newTestBlobNodeService(t, "ChunkExportImport")
+	defer cleanTestBlobNodeService(service)
+
+	host := runTestServer(service)
+	client := bnapi.New(&bnapi.Config{})
+
+	ctx := context.TODO()
+
+	diskID, dstDiskID := proto.DiskID(101), proto.DiskID(102)
+	vuid := proto.Vuid(2001)
+
+	err := client.CreateChunk(ctx, host, &bnapi.CreateChunkArgs{DiskID: diskID, Vuid: vuid})
+	require.NoError(t, err)
+
+	shardData := []byte("test export")
+	for bid := proto.BlobID(1); bid <= 4; bid++ {
+		_, err = client.PutShard(ctx, host, &bnapi.PutShardArgs{
+			DiskID: diskID,
+			Vuid:   vuid,
+			Bid:    bid,
+			Size:   int64(len(shardData)),
+			Body:   bytes.NewReader(shardData),
+		})
+		require.NoError(t, err)
+	}
+	// deleted shard is not exported
+	delArgs := &bnapi.DeleteShardArgs{DiskID: diskID, Vuid: vuid, Bid: 2}
+	require.NoError(t, client.MarkDeleteShard(ctx, host, delArgs))
+	require.NoError(t, client.DeleteShard(ctx, host, delArgs))
+
+	// err: live chunk can not be exported
+	_, err = client.ExportChunk(ctx, host, &bnapi.ExportChunkArgs{DiskID: diskID, Vuid: vuid})
+	require.Error(t, err)
+	require.Equal(t, bloberr.CodeChunkNotReadonly, rpc.DetectStatusCode(err))
+
+	err = client.SetChunkReadonly(ctx, host, &bnapi.ChangeChunkStatusArgs{DiskID: diskID, Vuid: vuid})
+	require.NoError(t, err)
+
+	_, err = client.ExportChunk(ctx, host, &bnapi.ExportChunkArgs{DiskID: diskID, Vuid: proto.Vuid(2002)})
+	require.Error(t, err)
+	require.Equal(t, bloberr.CodeVuidNotFound, rpc.DetectStatusCode(err))
+
+	body, err := client.ExportChunk(ctx, host, &bnapi.ExportChunkArgs{DiskID: diskID, Vuid: vuid})
+	require.NoError(t, err)
+	archive, err := ioutil.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+
+	ar, err := bnapi.NewChunkArchiveReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.Equal(t, vuid, ar.Meta().Vuid)
+	require.Equal(t, bnapi.ChunkStatusReadOnly, ar.Meta().Status)
+	bids := make([]proto.BlobID, 0)
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(ar)
+		require.NoError(t, err)
+		require.Equal(t, shardData, data)
+		bids = append(bids, hdr.Bid)
+	}
+	require.Equal(t, []proto.BlobID{1, 3, 4}, bids)
+
+	// vuid not match
+	err = client.ImportChunk(ctx, host, &bnapi.ImportChunkArgs{
+		DiskID: dstDiskID, Vuid: proto.Vuid(2002), Body: bytes.NewReader(archive),
+	})
+	require.Error(t, err)
+	require.Equal(t, bloberr.CodeVuidNotMatch, rpc.DetectStatusCode(err))
+
+	// broken archive, imported chunk is released
+	err = client.ImportChunk(ctx, host, &bnapi.ImportChunkArgs{
+		DiskID: dstDiskID, Vuid: vuid, Body: bytes.NewReader(archive[:len(archive)-1]),
+	})
+	require.Error(t, err)
+	_, err = client.StatChunk(ctx, host, &bnapi.StatChunkArgs{DiskID: dstDiskID, Vuid: vuid})
+	require.Error(t, err)
+
+	err = client.ImportChunk(ctx, host, &bnapi.ImportChunkArgs{
+		DiskID: dstDiskID, Vuid: vuid, Body: bytes.NewReader(archive),
+	})
+	require.NoError(t, err)
+
+	ci, err := client.StatChunk(ctx, host, &bnapi.StatChunkArgs{DiskID: dstDiskID, Vuid: vuid})
+	require.NoError(t, err)
+	require.Equal(t, bnapi.ChunkStatusReadOnly, ci.Status)
+
+	for _, bid := range bids {
+		rc, _, err := client.GetShard(ctx, host, &bnapi.GetShardArgs{DiskID: dstDiskID, Vuid: vuid, Bid: bid})
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		require.Equal(t, shardData, data)
+	}
+	_, err = client.StatShard(ctx, host, &bnapi.StatShardArgs{DiskID: dstDiskID, Vuid: vuid, Bid: 2})
+	require.Error(t, err)
+
+	// vuid exists already
+	err = client.ImportChunk(ctx, host, &bnapi.ImportChunkArgs{
+		DiskID: dstDiskID, Vuid: vuid, Body: bytes.NewReader(archive),
+	})
+	require.Error(t, err)
+}
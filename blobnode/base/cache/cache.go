@@ -0,0 +1,508 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/common/proto"
+)
+
+const (
+	// cached files are in the sub directory of disk path
+	cacheDirName = "shard_cache"
+	// header of cached file: crc(4) + expire_at(8)
+	fileHeaderSize = 12
+	// evicted entries of memory waiting to be written to disk tier,
+	// entries are dropped if the queue is full
+	spillQueueLen = 64
+)
+
+// invalidation of keys are counted in stripes, a put is dropped
+// if its stripe was invalidated after the shard was read.
+const epochStripes = 256
+
+var errCorrupted = errors.New("cache: corrupted entry")
+
+var ShardCacheMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "blobstore",
+		Subsystem: "blobnode",
+		Name:      "shard_cache",
+		Help:      "blobnode shard read cache",
+	},
+	[]string{"item"},
+)
+
+func init() {
+	prometheus.MustRegister(ShardCacheMetric)
+}
+
+type Key struct {
+	Vuid proto.Vuid
+	Bid  proto.BlobID
+}
+
+type entry struct {
+	data     []byte
+	crc      uint32
+	expireAt int64
+	epoch    uint64
+}
+
+type spillItem struct {
+	key   Key
+	entry *entry
+}
+
+type diskEntry struct {
+	crc      uint32
+	expireAt int64
+}
+
+type counter struct {
+	name string
+	n    uint64
+}
+
+func (c *counter) inc() {
+	atomic.AddUint64(&c.n, 1)
+	ShardCacheMetric.WithLabelValues(c.name).Inc()
+}
+
+func (c *counter) load() uint64 {
+	return atomic.LoadUint64(&c.n)
+}
+
+type Stat struct {
+	MemoryCount int     `json:"memory_count"`
+	MemorySize  int64   `json:"memory_size"`
+	DiskCount   int     `json:"disk_count"`
+	DiskSize    int64   `json:"disk_size"`
+	MemoryHits  uint64  `json:"memory_hits"`
+	DiskHits    uint64  `json:"disk_hits"`
+	Misses      uint64  `json:"misses"`
+	HitRate     float64 `json:"hit_rate"`
+	Admitted    uint64  `json:"admitted"`
+	Rejected    uint64  `json:"rejected"`
+	Evicted     uint64  `json:"evicted"`
+	Invalidated uint64  `json:"invalidated"`
+	Corrupted   uint64  `json:"corrupted"`
+}
+
+// ShardCache read cache of shards keyed by vuid and bid,
+// data is verified by crc when admitted and loaded from disk.
+// Evicted entries of memory are moved to disk in background if disk tier is enabled.
+type ShardCache struct {
+	maxShardSize int64
+	iotypes      [bnapi.IOTypeMax]bool
+	diskPath     string
+
+	memLock sync.Mutex
+	mem     *lru
+
+	diskLock sync.Mutex
+	disk     *lru // nil if disk tier is disabled
+
+	spillCh   chan spillItem
+	spilling  sync.WaitGroup
+	closeCh   chan struct{}
+	closeOnce sync.Once
+
+	epochs [epochStripes]uint64
+
+	memoryHits, diskHits, misses                        counter
+	admitted, rejected, evicted, invalidated, corrupted counter
+
+	now func() time.Time
+}
+
+func New(conf Config) (*ShardCache, error) {
+	if err := initConfig(&conf); err != nil {
+		return nil, err
+	}
+
+	c := &ShardCache{
+		maxShardSize: conf.MaxShardSizeB,
+		mem:          newLRU(conf.MemoryCapacityMB << 20),
+
+		memoryHits:  counter{name: "hit_memory"},
+		diskHits:    counter{name: "hit_disk"},
+		misses:      counter{name: "miss"},
+		admitted:    counter{name: "admit"},
+		rejected:    counter{name: "reject"},
+		evicted:     counter{name: "evict"},
+		invalidated: counter{name: "invalidate"},
+		corrupted:   counter{name: "corrupt"},
+
+		closeCh: make(chan struct{}),
+		now:     time.Now,
+	}
+	for _, name := range conf.IOTypes {
+		c.iotypes[ioTypeOf(name)] = true
+	}
+
+	if conf.DiskPath != "" {
+		c.diskPath = filepath.Join(conf.DiskPath, cacheDirName)
+		// entries of last run may be stale
+		if err := os.RemoveAll(c.diskPath); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(c.diskPath, 0o755); err != nil {
+			return nil, err
+		}
+		c.disk = newLRU(conf.DiskCapacityMB << 20)
+		c.spillCh = make(chan spillItem, spillQueueLen)
+		go c.spillLoop()
+	}
+
+	return c, nil
+}
+
+// Close stops writing evicted entries to disk tier
+func (c *ShardCache) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
+}
+
+// AdmitIOType returns whether reads of iot use the cache
+func (c *ShardCache) AdmitIOType(iot bnapi.IOType) bool {
+	return iot.IsValid() && c.iotypes[iot]
+}
+
+// Admit returns whether a shard read by iot with size is cacheable
+func (c *ShardCache) Admit(iot bnapi.IOType, size int64) bool {
+	return c.AdmitIOType(iot) && size > 0 && size <= c.maxShardSize
+}
+
+// Epoch returns invalidation epoch of key, which should be got before reading the shard
+func (c *ShardCache) Epoch(key Key) uint64 {
+	return atomic.LoadUint64(&c.epochs[c.stripe(key)])
+}
+
+func (c *ShardCache) stripe(key Key) uint64 {
+	return (uint64(key.Vuid) ^ uint64(key.Bid)*0x9e3779b97f4a7c15) % epochStripes
+}
+
+func (c *ShardCache) expired(expireAt int64) bool {
+	return expireAt > 0 && expireAt <= c.now().Unix()
+}
+
+// Get returns data and crc of the cached shard, data must not be modified
+func (c *ShardCache) Get(key Key) (data []byte, crc uint32, ok bool) {
+	c.memLock.Lock()
+	value, ok := c.mem.get(key)
+	if ok && c.expired(value.(*entry).expireAt) {
+		c.mem.remove(key)
+		ok = false
+	}
+	c.memLock.Unlock()
+	if ok {
+		c.memoryHits.inc()
+		e := value.(*entry)
+		return e.data, e.crc, true
+	}
+
+	if c.disk != nil {
+		if e, ok := c.getFromDisk(key); ok {
+			c.diskHits.inc()
+			c.addToMemory(key, e)
+			return e.data, e.crc, true
+		}
+	}
+
+	c.misses.inc()
+	return nil, 0, false
+}
+
+func (c *ShardCache) getFromDisk(key Key) (*entry, bool) {
+	epoch := c.Epoch(key)
+
+	c.diskLock.Lock()
+	value, ok := c.disk.get(key)
+	if ok && c.expired(value.(*diskEntry).expireAt) {
+		c.removeFromDisk(key)
+		ok = false
+	}
+	c.diskLock.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	e, err := c.readFile(key)
+	if err != nil {
+		if err == errCorrupted {
+			c.corrupted.inc()
+		}
+		c.diskLock.Lock()
+		if current, ok := c.disk.get(key); ok && current == value {
+			c.removeFromDisk(key)
+		}
+		c.diskLock.Unlock()
+		return nil, false
+	}
+
+	// removed while reading the file
+	c.diskLock.Lock()
+	current, ok := c.disk.get(key)
+	c.diskLock.Unlock()
+	if !ok || current != value || c.Epoch(key) != epoch {
+		return nil, false
+	}
+
+	e.epoch = epoch
+	return e, true
+}
+
+// Put adds the shard into cache, epoch is got before reading the shard.
+// It returns false if the shard is not admitted.
+func (c *ShardCache) Put(key Key, epoch uint64, data []byte, crc uint32, expireAt int64) bool {
+	size := int64(len(data))
+	if size <= 0 || size > c.maxShardSize || c.expired(expireAt) {
+		c.rejected.inc()
+		return false
+	}
+	if crc32.ChecksumIEEE(data) != crc {
+		c.corrupted.inc()
+		return false
+	}
+
+	e := &entry{
+		data:     append([]byte(nil), data...),
+		crc:      crc,
+		expireAt: expireAt,
+		epoch:    epoch,
+	}
+	if !c.addToMemory(key, e) {
+		c.rejected.inc()
+		return false
+	}
+	c.admitted.inc()
+	return true
+}
+
+func (c *ShardCache) addToMemory(key Key, e *entry) bool {
+	c.memLock.Lock()
+	if c.Epoch(key) != e.epoch {
+		c.memLock.Unlock()
+		return false
+	}
+	evicted := c.mem.add(key, e, int64(len(e.data)))
+	c.memLock.Unlock()
+
+	for _, item := range evicted {
+		c.evicted.inc()
+		if c.disk != nil {
+			c.spill(item.key, item.value.(*entry))
+		}
+	}
+	return true
+}
+
+// spill queues the evicted entry of memory to be written to disk tier,
+// so that reads are not blocked by writing files
+func (c *ShardCache) spill(key Key, e *entry) {
+	if c.expired(e.expireAt) {
+		return
+	}
+
+	c.spilling.Add(1)
+	select {
+	case c.spillCh <- spillItem{key: key, entry: e}:
+	default:
+		c.spilling.Done()
+	}
+}
+
+func (c *ShardCache) spillLoop() {
+	for {
+		select {
+		case item := <-c.spillCh:
+			c.addToDisk(item.key, item.entry)
+			c.spilling.Done()
+		case <-c.closeCh:
+			return
+		}
+	}
+}
+
+// addToDisk writes file without diskLock held, it is called by spill loop only,
+// so that no one else adds entries into disk tier concurrently.
+func (c *ShardCache) addToDisk(key Key, e *entry) {
+	if c.expired(e.expireAt) {
+		return
+	}
+
+	c.diskLock.Lock()
+	_, exist := c.disk.get(key)
+	c.diskLock.Unlock()
+	if exist || c.Epoch(key) != e.epoch {
+		return
+	}
+	if err := c.writeFile(key, e); err != nil {
+		return
+	}
+
+	c.diskLock.Lock()
+	// invalidated while writing the file
+	if c.Epoch(key) != e.epoch {
+		c.diskLock.Unlock()
+		os.Remove(c.filename(key))
+		return
+	}
+	evicted := c.disk.add(key, &diskEntry{crc: e.crc, expireAt: e.expireAt}, int64(len(e.data)))
+	c.diskLock.Unlock()
+
+	for _, item := range evicted {
+		c.evicted.inc()
+		os.Remove(c.filename(item.key))
+	}
+}
+
+// Invalidate removes the shard from cache, called when the shard is modified
+func (c *ShardCache) Invalidate(key Key) {
+	atomic.AddUint64(&c.epochs[c.stripe(key)], 1)
+
+	c.memLock.Lock()
+	_, removed := c.mem.remove(key)
+	c.memLock.Unlock()
+
+	if c.disk != nil {
+		c.diskLock.Lock()
+		if c.removeFromDisk(key) {
+			removed = true
+		}
+		c.diskLock.Unlock()
+	}
+
+	if removed {
+		c.invalidated.inc()
+	}
+}
+
+// InvalidateVuid removes all shards of vuid from cache, called when the chunk is released
+func (c *ShardCache) InvalidateVuid(vuid proto.Vuid) {
+	// shards of vuid are in all stripes
+	for i := range c.epochs {
+		atomic.AddUint64(&c.epochs[i], 1)
+	}
+
+	c.memLock.Lock()
+	for key := range c.mem.items {
+		if key.Vuid == vuid {
+			c.mem.remove(key)
+			c.invalidated.inc()
+		}
+	}
+	c.memLock.Unlock()
+
+	if c.disk != nil {
+		c.diskLock.Lock()
+		for key := range c.disk.items {
+			if key.Vuid == vuid && c.removeFromDisk(key) {
+				c.invalidated.inc()
+			}
+		}
+		c.diskLock.Unlock()
+	}
+}
+
+// removeFromDisk must be called with diskLock held
+func (c *ShardCache) removeFromDisk(key Key) bool {
+	if _, ok := c.disk.remove(key); !ok {
+		return false
+	}
+	os.Remove(c.filename(key))
+	return true
+}
+
+func (c *ShardCache) filename(key Key) string {
+	return filepath.Join(c.diskPath, fmt.Sprintf("%d_%d", key.Vuid, key.Bid))
+}
+
+func (c *ShardCache) writeFile(key Key, e *entry) error {
+	buf := make([]byte, fileHeaderSize+len(e.data))
+	binary.LittleEndian.PutUint32(buf[0:4], e.crc)
+	binary.LittleEndian.PutUint64(buf[4:12], uint64(e.expireAt))
+	copy(buf[fileHeaderSize:], e.data)
+
+	name := c.filename(key)
+	tmp := name + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0o644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+func (c *ShardCache) readFile(key Key) (*entry, error) {
+	buf, err := ioutil.ReadFile(c.filename(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) <= fileHeaderSize {
+		return nil, errCorrupted
+	}
+
+	e := &entry{
+		crc:      binary.LittleEndian.Uint32(buf[0:4]),
+		expireAt: int64(binary.LittleEndian.Uint64(buf[4:12])),
+		data:     buf[fileHeaderSize:],
+	}
+	if crc32.ChecksumIEEE(e.data) != e.crc {
+		return nil, errCorrupted
+	}
+	return e, nil
+}
+
+func (c *ShardCache) Stat() *Stat {
+	stat := &Stat{
+		MemoryHits:  c.memoryHits.load(),
+		DiskHits:    c.diskHits.load(),
+		Misses:      c.misses.load(),
+		Admitted:    c.admitted.load(),
+		Rejected:    c.rejected.load(),
+		Evicted:     c.evicted.load(),
+		Invalidated: c.invalidated.load(),
+		Corrupted:   c.corrupted.load(),
+	}
+	if total := stat.MemoryHits + stat.DiskHits + stat.Misses; total > 0 {
+		stat.HitRate = float64(stat.MemoryHits+stat.DiskHits) / float64(total)
+	}
+
+	c.memLock.Lock()
+	stat.MemoryCount, stat.MemorySize = c.mem.len(), c.mem.size
+	c.memLock.Unlock()
+
+	if c.disk != nil {
+		c.diskLock.Lock()
+		stat.DiskCount, stat.DiskSize = c.disk.len(), c.disk.size
+		c.diskLock.Unlock()
+	}
+	return stat
+}
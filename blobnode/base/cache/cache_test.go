@@ -0,0 +1,210 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cache
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+)
+
+func shardData(b byte, size int) ([]byte, uint32) {
+	data := bytes.Repeat([]byte{b}, size)
+	return data, crc32.ChecksumIEEE(data)
+}
+
+func TestCacheConfig(t *testing.T) {
+	_, err := New(Config{MemoryCapacityMB: -1})
+	require.ErrorIs(t, err, ErrWrongConfig)
+	_, err = New(Config{IOTypes: []string{"iotype9"}})
+	require.ErrorIs(t, err, ErrWrongConfig)
+
+	c, err := New(Config{})
+	require.NoError(t, err)
+	require.Nil(t, c.disk)
+	require.Equal(t, int64(defaultMemoryCapacityMB<<20), c.mem.capacity)
+	require.True(t, c.Admit(bnapi.NormalIO, 1))
+	require.True(t, c.Admit(bnapi.NormalIO, defaultMaxShardSizeB))
+	require.False(t, c.Admit(bnapi.NormalIO, defaultMaxShardSizeB+1))
+	require.False(t, c.Admit(bnapi.NormalIO, 0))
+	require.False(t, c.Admit(bnapi.BackgroundIO, 1))
+	require.False(t, c.Admit(bnapi.IOTypeMax, 1))
+
+	c, err = New(Config{IOTypes: []string{"normal", "background"}, MaxShardSizeB: 10})
+	require.NoError(t, err)
+	require.True(t, c.Admit(bnapi.BackgroundIO, 10))
+	require.False(t, c.Admit(bnapi.NormalIO, 11))
+}
+
+func TestCacheMemory(t *testing.T) {
+	c, err := New(Config{MemoryCapacityMB: 1, MaxShardSizeB: 1 << 20})
+	require.NoError(t, err)
+	now := time.Unix(1000000, 0)
+	c.now = func() time.Time { return now }
+
+	key := Key{Vuid: 1, Bid: 1}
+	_, _, ok := c.Get(key)
+	require.False(t, ok)
+
+	data, crc := shardData('a', 1024)
+	require.False(t, c.Put(key, c.Epoch(key), data, crc+1, 0))
+	require.True(t, c.Put(key, c.Epoch(key), data, crc, 0))
+	data[0] = 'b'
+	cached, cachedCrc, ok := c.Get(key)
+	require.True(t, ok)
+	require.Equal(t, crc, cachedCrc)
+	require.Equal(t, byte('a'), cached[0])
+
+	// invalidated after read
+	epoch := c.Epoch(key)
+	c.Invalidate(key)
+	_, _, ok = c.Get(key)
+	require.False(t, ok)
+	data, crc = shardData('c', 1024)
+	require.False(t, c.Put(key, epoch, data, crc, 0))
+	require.True(t, c.Put(key, c.Epoch(key), data, crc, 0))
+
+	// expired
+	key2 := Key{Vuid: 1, Bid: 2}
+	require.False(t, c.Put(key2, c.Epoch(key2), data, crc, now.Unix()))
+	require.True(t, c.Put(key2, c.Epoch(key2), data, crc, now.Unix()+10))
+	_, _, ok = c.Get(key2)
+	require.True(t, ok)
+	now = now.Add(10 * time.Second)
+	_, _, ok = c.Get(key2)
+	require.False(t, ok)
+
+	stat := c.Stat()
+	require.Equal(t, 1, stat.MemoryCount)
+	require.Equal(t, int64(1024), stat.MemorySize)
+	require.Equal(t, uint64(2), stat.MemoryHits)
+	require.Equal(t, uint64(3), stat.Misses)
+	require.Equal(t, uint64(3), stat.Admitted)
+	require.Equal(t, uint64(2), stat.Rejected)
+	require.Equal(t, uint64(1), stat.Corrupted)
+	require.Equal(t, uint64(1), stat.Invalidated)
+	require.InDelta(t, 0.4, stat.HitRate, 0.001)
+}
+
+func TestCacheDiskTier(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), "ShardCacheDiskTier")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	// stale files of last run are removed
+	staleDir := filepath.Join(testDir, cacheDirName)
+	require.NoError(t, os.MkdirAll(staleDir, 0o755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(staleDir, "1_1"), []byte("stale"), 0o644))
+
+	c, err := New(Config{MemoryCapacityMB: 1, DiskPath: testDir, DiskCapacityMB: 1, MaxShardSizeB: 512 << 10})
+	require.NoError(t, err)
+	defer c.Close()
+	_, err = os.Stat(filepath.Join(staleDir, "1_1"))
+	require.True(t, os.IsNotExist(err))
+
+	keys := []Key{{Vuid: 1, Bid: 1}, {Vuid: 1, Bid: 2}, {Vuid: 1, Bid: 3}}
+	for i, key := range keys {
+		data, crc := shardData(byte('a'+i), 400<<10)
+		require.True(t, c.Put(key, c.Epoch(key), data, crc, 0))
+	}
+	c.spilling.Wait()
+	stat := c.Stat()
+	require.Equal(t, 2, stat.MemoryCount)
+	require.Equal(t, 1, stat.DiskCount)
+	require.Equal(t, uint64(1), stat.Evicted)
+
+	// promoted from disk, keys[1] is moved to disk
+	data, crc, ok := c.Get(keys[0])
+	require.True(t, ok)
+	expected, expectedCrc := shardData('a', 400<<10)
+	require.Equal(t, expected, data)
+	require.Equal(t, expectedCrc, crc)
+	c.spilling.Wait()
+	stat = c.Stat()
+	require.Equal(t, uint64(1), stat.DiskHits)
+	require.Equal(t, 2, stat.MemoryCount)
+	require.Equal(t, 2, stat.DiskCount)
+
+	// corrupted file is dropped
+	name := c.filename(keys[1])
+	buf, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	buf[len(buf)-1]++
+	require.NoError(t, ioutil.WriteFile(name, buf, 0o644))
+	_, _, ok = c.Get(keys[1])
+	require.False(t, ok)
+	_, err = os.Stat(name)
+	require.True(t, os.IsNotExist(err))
+	require.Equal(t, uint64(1), c.Stat().Corrupted)
+
+	// invalidation removes file of disk tier
+	require.FileExists(t, c.filename(keys[0]))
+	c.Invalidate(keys[0])
+	_, _, ok = c.Get(keys[0])
+	require.False(t, ok)
+	_, err = os.Stat(c.filename(keys[0]))
+	require.True(t, os.IsNotExist(err))
+
+	stat = c.Stat()
+	require.Equal(t, 1, stat.MemoryCount)
+	require.Equal(t, 0, stat.DiskCount)
+	require.Equal(t, uint64(1), stat.Invalidated)
+}
+
+func TestCacheInvalidateVuid(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), "ShardCacheInvalidateVuid")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	c, err := New(Config{MemoryCapacityMB: 1, DiskPath: testDir, DiskCapacityMB: 1, MaxShardSizeB: 512 << 10})
+	require.NoError(t, err)
+	defer c.Close()
+
+	// keys[0] is moved to disk
+	keys := []Key{{Vuid: 1, Bid: 1}, {Vuid: 1, Bid: 2}, {Vuid: 2, Bid: 1}}
+	for i, key := range keys {
+		data, crc := shardData(byte('a'+i), 400<<10)
+		require.True(t, c.Put(key, c.Epoch(key), data, crc, 0))
+	}
+	c.spilling.Wait()
+	require.FileExists(t, c.filename(keys[0]))
+
+	// shard of vuid read before invalidation is not admitted
+	epoch := c.Epoch(keys[1])
+	c.InvalidateVuid(1)
+	for _, key := range keys[:2] {
+		_, _, ok := c.Get(key)
+		require.False(t, ok)
+	}
+	_, err = os.Stat(c.filename(keys[0]))
+	require.True(t, os.IsNotExist(err))
+	data, crc := shardData('b', 400<<10)
+	require.False(t, c.Put(keys[1], epoch, data, crc, 0))
+
+	_, _, ok := c.Get(keys[2])
+	require.True(t, ok)
+	stat := c.Stat()
+	require.Equal(t, 1, stat.MemoryCount)
+	require.Equal(t, 0, stat.DiskCount)
+	require.Equal(t, uint64(2), stat.Invalidated)
+}
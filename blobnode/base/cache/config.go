@@ -0,0 +1,73 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cache
+
+import (
+	"errors"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+)
+
+const (
+	defaultMemoryCapacityMB = 256
+	defaultDiskCapacityMB   = 10 << 10 // 10 GiB
+	defaultMaxShardSizeB    = 1 << 20  // 1 MiB
+)
+
+var ErrWrongConfig = errors.New("cache: wrong config")
+
+// Config read cache of hot shards, memory is the first tier
+// and an optional directory (on ssd) is the second tier.
+type Config struct {
+	Enable           bool     `json:"enable"`
+	MemoryCapacityMB int64    `json:"memory_capacity_MB"`
+	DiskPath         string   `json:"disk_path"` // second tier is disabled if empty
+	DiskCapacityMB   int64    `json:"disk_capacity_MB"`
+	MaxShardSizeB    int64    `json:"max_shard_size_B"` // larger shards are not admitted
+	IOTypes          []string `json:"iotypes"`          // only reads of these io types are admitted
+}
+
+func initConfig(conf *Config) error {
+	if conf.MemoryCapacityMB < 0 || conf.DiskCapacityMB < 0 || conf.MaxShardSizeB < 0 {
+		return ErrWrongConfig
+	}
+	if conf.MemoryCapacityMB == 0 {
+		conf.MemoryCapacityMB = defaultMemoryCapacityMB
+	}
+	if conf.DiskCapacityMB == 0 {
+		conf.DiskCapacityMB = defaultDiskCapacityMB
+	}
+	if conf.MaxShardSizeB == 0 {
+		conf.MaxShardSizeB = defaultMaxShardSizeB
+	}
+	if len(conf.IOTypes) == 0 {
+		conf.IOTypes = []string{bnapi.NormalIO.String()}
+	}
+	for _, name := range conf.IOTypes {
+		if !ioTypeOf(name).IsValid() {
+			return ErrWrongConfig
+		}
+	}
+	return nil
+}
+
+func ioTypeOf(name string) bnapi.IOType {
+	for i, n := range bnapi.IOtypemap {
+		if n == name {
+			return bnapi.IOType(i)
+		}
+	}
+	return bnapi.IOTypeMax
+}
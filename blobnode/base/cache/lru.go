@@ -0,0 +1,84 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cache
+
+import (
+	"container/list"
+)
+
+type lruItem struct {
+	key   Key
+	size  int64
+	value interface{}
+}
+
+// lru is bounded by total size of values, not goroutine safe
+type lru struct {
+	capacity int64
+	size     int64
+	ll       *list.List
+	items    map[Key]*list.Element
+}
+
+func newLRU(capacity int64) *lru {
+	return &lru{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[Key]*list.Element),
+	}
+}
+
+func (l *lru) get(key Key) (interface{}, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.ll.MoveToFront(elem)
+	return elem.Value.(*lruItem).value, true
+}
+
+// add replaces the value of key and returns the evicted items,
+// value larger than capacity is not added.
+func (l *lru) add(key Key, value interface{}, size int64) (evicted []*lruItem) {
+	l.remove(key)
+	if size > l.capacity {
+		return
+	}
+
+	l.items[key] = l.ll.PushFront(&lruItem{key: key, size: size, value: value})
+	l.size += size
+	for l.size > l.capacity {
+		item := l.ll.Remove(l.ll.Back()).(*lruItem)
+		delete(l.items, item.key)
+		l.size -= item.size
+		evicted = append(evicted, item)
+	}
+	return
+}
+
+func (l *lru) remove(key Key) (*lruItem, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := l.ll.Remove(elem).(*lruItem)
+	delete(l.items, key)
+	l.size -= item.size
+	return item, true
+}
+
+func (l *lru) len() int {
+	return l.ll.Len()
+}
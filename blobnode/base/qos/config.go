@@ -15,6 +15,7 @@
 package qos
 
 import (
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
 	"github.com/cubefs/blobstore/blobnode/base/flow"
 	"github.com/cubefs/blobstore/blobnode/base/priority"
 	"github.com/cubefs/blobstore/common/iostat"
@@ -22,16 +23,52 @@ import (
 
 const (
 	thresholdNotSet = -1
+
+	defaultQueueDepth  = 32
+	defaultMaxQueueLen = 1024
+
+	defaultLatencyWindowSec = 60
+	defaultSlowMinSamples   = 100
+	defaultSlowNormalP99Ms  = 500
+)
+
+// default weight and deadline of every priority level
+var (
+	defaultClassWeights    = [...]int{8, 4, 2, 1}
+	defaultClassDeadlineMs = [...]int64{100, 500, 2000, 5000}
 )
 
 type Config struct {
 	DiskBandwidthMBPS int64           `json:"disk_bandwidth_MBPS"`
 	DiskIOPS          int64           `json:"disk_iops"`
 	LevelConfigs      LevelConfig     `json:"flow_conf"`
+	Scheduler         SchedulerConfig `json:"scheduler"`
+	Latency           LatencyConfig   `json:"latency"`
 	DiskViewer        iostat.IOViewer `json:"-"`
 	StatGetter        flow.StatGetter `json:"-"`
 }
 
+// SchedulerConfig io scheduler of disk, classes are priority levels
+type SchedulerConfig struct {
+	Enable      bool                   `json:"enable"`
+	QueueDepth  int                    `json:"queue_depth"`   // max inflight io of disk
+	MaxQueueLen int                    `json:"max_queue_len"` // max waiting io of class, overload if exceeded
+	Classes     map[string]ClassConfig `json:"classes"`
+}
+
+type ClassConfig struct {
+	Weight     int   `json:"weight"`
+	DeadlineMs int64 `json:"deadline_ms"`
+}
+
+// LatencyConfig latency tracking of disk, keys of SlowP99Ms are io types
+type LatencyConfig struct {
+	Enable         bool             `json:"enable"`
+	WindowSec      int64            `json:"window_sec"`       // rolling window of latency histograms
+	SlowMinSamples uint64           `json:"slow_min_samples"` // io type with fewer samples in window is not judged
+	SlowP99Ms      map[string]int64 `json:"slow_p99_ms"`      // disk is slow if p99 latency of any io type exceeds
+}
+
 type ParaConfig struct {
 	Iops      int64   `json:"iops"`
 	Bandwidth int64   `json:"bandwidth_MBPS"`
@@ -97,5 +134,59 @@ func initConfig(conf *Config) (err error) {
 		conf.DiskIOPS = thresholdNotSet
 	}
 
+	return initSchedulerConfig(&conf.Scheduler)
+}
+
+func initSchedulerConfig(conf *SchedulerConfig) (err error) {
+	if conf.QueueDepth < 0 || conf.MaxQueueLen < 0 {
+		return ErrWrongConfig
+	}
+	if conf.QueueDepth == 0 {
+		conf.QueueDepth = defaultQueueDepth
+	}
+	if conf.MaxQueueLen == 0 {
+		conf.MaxQueueLen = defaultMaxQueueLen
+	}
+
+	classes := make(map[string]ClassConfig)
+	for l, class := range conf.Classes {
+		if !priority.IsValidPriName(l) || class.Weight < 0 || class.DeadlineMs < 0 {
+			return ErrWrongConfig
+		}
+		classes[l] = class
+	}
+	for pri, name := range priority.GetLevels() {
+		class := classes[name]
+		if class.Weight == 0 {
+			class.Weight = defaultClassWeights[pri]
+		}
+		if class.DeadlineMs == 0 {
+			class.DeadlineMs = defaultClassDeadlineMs[pri]
+		}
+		classes[name] = class
+	}
+	conf.Classes = classes
+
+	return nil
+}
+
+func initLatencyConfig(conf *LatencyConfig) error {
+	if conf.WindowSec < 0 {
+		return ErrWrongConfig
+	}
+	if conf.WindowSec == 0 {
+		conf.WindowSec = defaultLatencyWindowSec
+	}
+	if conf.SlowMinSamples == 0 {
+		conf.SlowMinSamples = defaultSlowMinSamples
+	}
+	if conf.SlowP99Ms == nil {
+		conf.SlowP99Ms = map[string]int64{bnapi.NormalIO.String(): defaultSlowNormalP99Ms}
+	}
+	for name, ms := range conf.SlowP99Ms {
+		if !ioTypeOf(name).IsValid() || ms < 0 {
+			return ErrWrongConfig
+		}
+	}
 	return nil
 }
@@ -0,0 +1,316 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package qos
+
+import (
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+)
+
+// rolling window is split into slots, the oldest slot is dropped as time goes
+const latencySlots = 6
+
+// upper bounds of histogram buckets, the last bucket has no bound
+var latencyBounds = [...]time.Duration{
+	500 * time.Microsecond,
+	1 * time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+const latencyBuckets = len(latencyBounds) + 1
+
+type latencySlot struct {
+	epoch   int64
+	buckets [latencyBuckets]uint64
+	count   uint64
+	sum     time.Duration
+	max     time.Duration
+}
+
+// rollingHistogram is latency histogram of recent window
+type rollingHistogram struct {
+	lock  sync.Mutex
+	slots [latencySlots]latencySlot
+}
+
+func (h *rollingHistogram) observe(epoch int64, d time.Duration) {
+	idx := sort.Search(len(latencyBounds), func(i int) bool { return d <= latencyBounds[i] })
+
+	h.lock.Lock()
+	slot := &h.slots[epoch%latencySlots]
+	if slot.epoch != epoch {
+		*slot = latencySlot{epoch: epoch}
+	}
+	slot.buckets[idx]++
+	slot.count++
+	slot.sum += d
+	if d > slot.max {
+		slot.max = d
+	}
+	h.lock.Unlock()
+}
+
+// merge returns the sum of slots in window ending with epoch
+func (h *rollingHistogram) merge(epoch int64) (sum latencySlot) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for i := range h.slots {
+		slot := &h.slots[i]
+		if slot.count == 0 || slot.epoch <= epoch-latencySlots {
+			continue
+		}
+		for b := range slot.buckets {
+			sum.buckets[b] += slot.buckets[b]
+		}
+		sum.count += slot.count
+		sum.sum += slot.sum
+		if slot.max > sum.max {
+			sum.max = slot.max
+		}
+	}
+	return
+}
+
+// quantile returns upper bound of the bucket which q falls in,
+// max latency is returned if it falls in the last bucket.
+func (s *latencySlot) quantile(q float64) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(s.count)))
+
+	var cum uint64
+	for b, n := range s.buckets {
+		cum += n
+		if cum >= rank {
+			if b < len(latencyBounds) && latencyBounds[b] < s.max {
+				return latencyBounds[b]
+			}
+			return s.max
+		}
+	}
+	return s.max
+}
+
+// LatencyTracker tracks io latency of disk by io type,
+// disk is slow if p99 latency of any io type exceeds the threshold.
+type LatencyTracker struct {
+	slotDuration time.Duration
+	minSamples   uint64
+	thresholds   [bnapi.IOTypeMax]time.Duration
+	hists        [bnapi.IOTypeMax]rollingHistogram
+
+	now func() time.Time
+}
+
+type HistogramStat struct {
+	Count   uint64   `json:"count"`
+	AvgUs   int64    `json:"avg_us"`
+	P50Us   int64    `json:"p50_us"`
+	P99Us   int64    `json:"p99_us"`
+	MaxUs   int64    `json:"max_us"`
+	Buckets []uint64 `json:"buckets"`
+}
+
+type LatencyStat struct {
+	WindowSec   int64                    `json:"window_sec"`
+	BoundsUs    []int64                  `json:"bounds_us"`
+	Slow        bool                     `json:"slow"`
+	SlowIOTypes []string                 `json:"slow_iotypes,omitempty"`
+	IOTypes     map[string]HistogramStat `json:"iotypes"`
+}
+
+func NewLatencyTracker(conf LatencyConfig) (*LatencyTracker, error) {
+	if err := initLatencyConfig(&conf); err != nil {
+		return nil, err
+	}
+
+	slotDuration := time.Duration(conf.WindowSec) * time.Second / latencySlots
+	if slotDuration < time.Second {
+		slotDuration = time.Second
+	}
+
+	t := &LatencyTracker{
+		slotDuration: slotDuration,
+		minSamples:   conf.SlowMinSamples,
+		now:          time.Now,
+	}
+	for name, ms := range conf.SlowP99Ms {
+		t.thresholds[ioTypeOf(name)] = time.Duration(ms) * time.Millisecond
+	}
+
+	return t, nil
+}
+
+func (t *LatencyTracker) epoch() int64 {
+	return t.now().UnixNano() / int64(t.slotDuration)
+}
+
+func (t *LatencyTracker) Observe(iot bnapi.IOType, d time.Duration) {
+	if !iot.IsValid() {
+		return
+	}
+	t.hists[iot].observe(t.epoch(), d)
+}
+
+// SlowIOTypes returns io types whose latency exceed the threshold in window
+func (t *LatencyTracker) SlowIOTypes() (slows []string) {
+	epoch := t.epoch()
+	for iot := bnapi.NormalIO; iot < bnapi.IOTypeMax; iot++ {
+		if t.isSlow(iot, t.hists[iot].merge(epoch)) {
+			slows = append(slows, iot.String())
+		}
+	}
+	return
+}
+
+func (t *LatencyTracker) IsSlow() bool {
+	return len(t.SlowIOTypes()) > 0
+}
+
+func (t *LatencyTracker) isSlow(iot bnapi.IOType, sum latencySlot) bool {
+	threshold := t.thresholds[iot]
+	if threshold <= 0 || sum.count == 0 || sum.count < t.minSamples {
+		return false
+	}
+	return sum.quantile(0.99) > threshold
+}
+
+func (t *LatencyTracker) Stat() *LatencyStat {
+	stat := &LatencyStat{
+		WindowSec: int64(t.slotDuration*latencySlots) / int64(time.Second),
+		BoundsUs:  make([]int64, len(latencyBounds)),
+		IOTypes:   make(map[string]HistogramStat, bnapi.IOTypeMax),
+	}
+	for i, bound := range latencyBounds {
+		stat.BoundsUs[i] = bound.Microseconds()
+	}
+
+	epoch := t.epoch()
+	for iot := bnapi.NormalIO; iot < bnapi.IOTypeMax; iot++ {
+		sum := t.hists[iot].merge(epoch)
+		hs := HistogramStat{
+			Count:   sum.count,
+			P50Us:   sum.quantile(0.5).Microseconds(),
+			P99Us:   sum.quantile(0.99).Microseconds(),
+			MaxUs:   sum.max.Microseconds(),
+			Buckets: append([]uint64(nil), sum.buckets[:]...),
+		}
+		if sum.count > 0 {
+			hs.AvgUs = sum.sum.Microseconds() / int64(sum.count)
+		}
+		stat.IOTypes[iot.String()] = hs
+
+		if t.isSlow(iot, sum) {
+			stat.SlowIOTypes = append(stat.SlowIOTypes, iot.String())
+		}
+	}
+	stat.Slow = len(stat.SlowIOTypes) > 0
+
+	return stat
+}
+
+func ioTypeOf(name string) bnapi.IOType {
+	for i, n := range bnapi.IOtypemap {
+		if n == name {
+			return bnapi.IOType(i)
+		}
+	}
+	return bnapi.IOTypeMax
+}
+
+type latencyReader struct {
+	underlying io.Reader
+	t          *LatencyTracker
+	iot        bnapi.IOType
+}
+
+type latencyReaderAt struct {
+	underlying io.ReaderAt
+	t          *LatencyTracker
+	iot        bnapi.IOType
+}
+
+type latencyWriter struct {
+	underlying io.Writer
+	t          *LatencyTracker
+	iot        bnapi.IOType
+}
+
+type latencyWriterAt struct {
+	underlying io.WriterAt
+	t          *LatencyTracker
+	iot        bnapi.IOType
+}
+
+func (r *latencyReader) Read(p []byte) (n int, err error) {
+	start := time.Now()
+	n, err = r.underlying.Read(p)
+	r.t.Observe(r.iot, time.Since(start))
+	return
+}
+
+func (rt *latencyReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	start := time.Now()
+	n, err = rt.underlying.ReadAt(p, off)
+	rt.t.Observe(rt.iot, time.Since(start))
+	return
+}
+
+func (w *latencyWriter) Write(p []byte) (written int, err error) {
+	start := time.Now()
+	written, err = w.underlying.Write(p)
+	w.t.Observe(w.iot, time.Since(start))
+	return
+}
+
+func (wt *latencyWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	start := time.Now()
+	n, err = wt.underlying.WriteAt(p, off)
+	wt.t.Observe(wt.iot, time.Since(start))
+	return
+}
+
+func (t *LatencyTracker) Reader(iot bnapi.IOType, underlying io.Reader) io.Reader {
+	return &latencyReader{underlying: underlying, t: t, iot: iot}
+}
+
+func (t *LatencyTracker) ReaderAt(iot bnapi.IOType, underlying io.ReaderAt) io.ReaderAt {
+	return &latencyReaderAt{underlying: underlying, t: t, iot: iot}
+}
+
+func (t *LatencyTracker) Writer(iot bnapi.IOType, underlying io.Writer) io.Writer {
+	return &latencyWriter{underlying: underlying, t: t, iot: iot}
+}
+
+func (t *LatencyTracker) WriterAt(iot bnapi.IOType, underlying io.WriterAt) io.WriterAt {
+	return &latencyWriterAt{underlying: underlying, t: t, iot: iot}
+}
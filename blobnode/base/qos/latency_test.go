@@ -0,0 +1,125 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package qos
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+)
+
+func TestLatencyConfig(t *testing.T) {
+	_, err := NewLatencyTracker(LatencyConfig{WindowSec: -1})
+	require.ErrorIs(t, err, ErrWrongConfig)
+	_, err = NewLatencyTracker(LatencyConfig{SlowP99Ms: map[string]int64{"iotype9": 100}})
+	require.ErrorIs(t, err, ErrWrongConfig)
+	_, err = NewLatencyTracker(LatencyConfig{SlowP99Ms: map[string]int64{"normal": -1}})
+	require.ErrorIs(t, err, ErrWrongConfig)
+
+	lt, err := NewLatencyTracker(LatencyConfig{})
+	require.NoError(t, err)
+	require.Equal(t, int64(defaultLatencyWindowSec), lt.Stat().WindowSec)
+	require.Equal(t, uint64(defaultSlowMinSamples), lt.minSamples)
+	require.Equal(t, defaultSlowNormalP99Ms*time.Millisecond, lt.thresholds[bnapi.NormalIO])
+	require.Equal(t, time.Duration(0), lt.thresholds[bnapi.BackgroundIO])
+}
+
+func TestLatencyTracker(t *testing.T) {
+	lt, err := NewLatencyTracker(LatencyConfig{
+		WindowSec:      60,
+		SlowMinSamples: 10,
+		SlowP99Ms:      map[string]int64{"normal": 100, "background": 1000},
+	})
+	require.NoError(t, err)
+	now := time.Unix(1000000, 0)
+	lt.now = func() time.Time { return now }
+
+	for i := 0; i < 9; i++ {
+		lt.Observe(bnapi.NormalIO, 3*time.Millisecond)
+	}
+	lt.Observe(bnapi.NormalIO, 300*time.Millisecond)
+	lt.Observe(bnapi.CompactIO, 10*time.Second)
+	lt.Observe(bnapi.IOTypeMax, time.Second)
+
+	stat := lt.Stat()
+	normal := stat.IOTypes[bnapi.NormalIO.String()]
+	require.Equal(t, uint64(10), normal.Count)
+	require.Equal(t, int64(5000), normal.P50Us)
+	require.Equal(t, int64(300000), normal.P99Us)
+	require.Equal(t, int64(300000), normal.MaxUs)
+	require.Equal(t, int64(32700), normal.AvgUs)
+	require.Equal(t, uint64(9), normal.Buckets[3])
+	require.Equal(t, len(stat.BoundsUs)+1, len(normal.Buckets))
+	// compact io has no threshold
+	require.Equal(t, int64(10000000), stat.IOTypes[bnapi.CompactIO.String()].P99Us)
+	require.True(t, stat.Slow)
+	require.Equal(t, []string{"normal"}, stat.SlowIOTypes)
+	require.True(t, lt.IsSlow())
+
+	// not enough samples
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 9; i++ {
+		lt.Observe(bnapi.BackgroundIO, 2*time.Second)
+	}
+	require.Equal(t, []string{"normal"}, lt.SlowIOTypes())
+	lt.Observe(bnapi.BackgroundIO, 2*time.Second)
+	require.Equal(t, []string{"normal", "background"}, lt.SlowIOTypes())
+
+	// slow samples roll out of window
+	now = now.Add(55 * time.Second)
+	require.Equal(t, []string{"background"}, lt.SlowIOTypes())
+	require.Equal(t, uint64(0), lt.Stat().IOTypes[bnapi.NormalIO.String()].Count)
+	now = now.Add(10 * time.Second)
+	require.False(t, lt.IsSlow())
+	require.Equal(t, uint64(0), lt.Stat().IOTypes[bnapi.BackgroundIO.String()].Count)
+}
+
+func TestQosManagerLatency(t *testing.T) {
+	ctx := context.Background()
+
+	q, err := NewQosManager(Config{})
+	require.NoError(t, err)
+	require.Nil(t, q.LatencyStat())
+	require.False(t, q.IsSlow())
+
+	_, err = NewQosManager(Config{Latency: LatencyConfig{Enable: true, WindowSec: -1}})
+	require.ErrorIs(t, err, ErrWrongConfig)
+
+	q, err = NewQosManager(Config{Latency: LatencyConfig{Enable: true}})
+	require.NoError(t, err)
+
+	data := []byte("tracked io")
+	buf := make([]byte, len(data))
+	_, err = q.ReaderAt(ctx, bnapi.NormalIO, bytes.NewReader(data)).ReadAt(buf, 0)
+	require.NoError(t, err)
+	_, err = q.Reader(ctx, bnapi.NormalIO, bytes.NewReader(data)).Read(buf)
+	require.NoError(t, err)
+
+	w := bytes.NewBuffer(nil)
+	_, err = q.Writer(ctx, bnapi.CompactIO, w).Write(data)
+	require.NoError(t, err)
+	require.Equal(t, data, w.Bytes())
+
+	stat := q.LatencyStat()
+	require.NotNil(t, stat)
+	require.Equal(t, uint64(2), stat.IOTypes[bnapi.NormalIO.String()].Count)
+	require.Equal(t, uint64(1), stat.IOTypes[bnapi.CompactIO.String()].Count)
+	require.False(t, q.IsSlow())
+}
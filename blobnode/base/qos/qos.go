@@ -25,8 +25,10 @@ import (
 )
 
 type IOQos struct {
-	LevelMgr LevelGetter     // Identify: a level qos controller
-	StatMgr  flow.StatGetter // Identify: a io flow
+	LevelMgr  LevelGetter     // Identify: a level qos controller
+	StatMgr   flow.StatGetter // Identify: a io flow
+	Scheduler *Scheduler      // Identify: io scheduler of disk, nil if disabled
+	Latency   *LatencyTracker // Identify: io latency of disk, nil if disabled
 }
 
 type Qos interface {
@@ -34,6 +36,9 @@ type Qos interface {
 	WriterAt(context.Context, bnapi.IOType, io.WriterAt) io.WriterAt
 	Writer(context.Context, bnapi.IOType, io.Writer) io.Writer
 	Reader(context.Context, bnapi.IOType, io.Reader) io.Reader
+	SchedulerStat() *SchedulerStat
+	LatencyStat() *LatencyStat
+	IsSlow() bool
 }
 
 func (qos *IOQos) getiostat(iot bnapi.IOType) (ios iostat.StatMgrAPI) {
@@ -46,11 +51,19 @@ func (qos *IOQos) getiostat(iot bnapi.IOType) (ios iostat.StatMgrAPI) {
 func (qos *IOQos) ReaderAt(ctx context.Context, ioType bnapi.IOType, reader io.ReaderAt) (r io.ReaderAt) {
 	r = reader
 
-	if ios := qos.getiostat(ioType); ios != nil {
-		r = ios.ReaderAt(reader)
+	if qos.Latency != nil {
+		r = qos.Latency.ReaderAt(ioType, r)
 	}
 
 	priority := prio.GetPriority(ioType)
+	if qos.Scheduler != nil {
+		r = qos.Scheduler.ReaderAt(ctx, priority, r)
+	}
+
+	if ios := qos.getiostat(ioType); ios != nil {
+		r = ios.ReaderAt(r)
+	}
+
 	if level := qos.LevelMgr.GetLevel(priority); level != nil {
 		r = level.ReaderAt(ctx, r)
 	}
@@ -61,11 +74,19 @@ func (qos *IOQos) ReaderAt(ctx context.Context, ioType bnapi.IOType, reader io.R
 func (qos *IOQos) WriterAt(ctx context.Context, ioType bnapi.IOType, writer io.WriterAt) (w io.WriterAt) {
 	w = writer
 
-	if ios := qos.getiostat(ioType); ios != nil {
-		w = ios.WriterAt(writer)
+	if qos.Latency != nil {
+		w = qos.Latency.WriterAt(ioType, w)
 	}
 
 	priority := prio.GetPriority(ioType)
+	if qos.Scheduler != nil {
+		w = qos.Scheduler.WriterAt(ctx, priority, w)
+	}
+
+	if ios := qos.getiostat(ioType); ios != nil {
+		w = ios.WriterAt(w)
+	}
+
 	if level := qos.LevelMgr.GetLevel(priority); level != nil {
 		w = level.WriterAt(ctx, w)
 	}
@@ -76,11 +97,19 @@ func (qos *IOQos) WriterAt(ctx context.Context, ioType bnapi.IOType, writer io.W
 func (qos *IOQos) Writer(ctx context.Context, ioType bnapi.IOType, writer io.Writer) (w io.Writer) {
 	w = writer
 
-	if ios := qos.getiostat(ioType); ios != nil {
-		w = ios.Writer(writer)
+	if qos.Latency != nil {
+		w = qos.Latency.Writer(ioType, w)
 	}
 
 	priority := prio.GetPriority(ioType)
+	if qos.Scheduler != nil {
+		w = qos.Scheduler.Writer(ctx, priority, w)
+	}
+
+	if ios := qos.getiostat(ioType); ios != nil {
+		w = ios.Writer(w)
+	}
+
 	if level := qos.LevelMgr.GetLevel(priority); level != nil {
 		w = level.Writer(ctx, w)
 	}
@@ -91,11 +120,19 @@ func (qos *IOQos) Writer(ctx context.Context, ioType bnapi.IOType, writer io.Wri
 func (qos *IOQos) Reader(ctx context.Context, ioType bnapi.IOType, reader io.Reader) (r io.Reader) {
 	r = reader
 
-	if ios := qos.getiostat(ioType); ios != nil {
-		r = ios.Reader(reader)
+	if qos.Latency != nil {
+		r = qos.Latency.Reader(ioType, r)
 	}
 
 	priority := prio.GetPriority(ioType)
+	if qos.Scheduler != nil {
+		r = qos.Scheduler.Reader(ctx, priority, r)
+	}
+
+	if ios := qos.getiostat(ioType); ios != nil {
+		r = ios.Reader(r)
+	}
+
 	if level := qos.LevelMgr.GetLevel(priority); level != nil {
 		r = level.Reader(ctx, r)
 	}
@@ -103,6 +140,25 @@ func (qos *IOQos) Reader(ctx context.Context, ioType bnapi.IOType, reader io.Rea
 	return r
 }
 
+func (qos *IOQos) SchedulerStat() *SchedulerStat {
+	if qos.Scheduler == nil {
+		return nil
+	}
+	return qos.Scheduler.Stat()
+}
+
+func (qos *IOQos) LatencyStat() *LatencyStat {
+	if qos.Latency == nil {
+		return nil
+	}
+	return qos.Latency.Stat()
+}
+
+// IsSlow returns true if latency of disk exceeds the threshold
+func (qos *IOQos) IsSlow() bool {
+	return qos.Latency != nil && qos.Latency.IsSlow()
+}
+
 func NewQosManager(conf Config) (Qos, error) {
 	// disk multi-level flow control
 	levelMgr, err := NewLevelQosMgr(conf, conf.DiskViewer)
@@ -115,5 +171,21 @@ func NewQosManager(conf Config) (Qos, error) {
 		StatMgr:  conf.StatGetter,
 	}
 
+	// disk io scheduling
+	if conf.Scheduler.Enable {
+		qos.Scheduler, err = NewScheduler(conf.Scheduler)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// disk io latency tracking
+	if conf.Latency.Enable {
+		qos.Latency, err = NewLatencyTracker(conf.Latency)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return qos, nil
 }
@@ -0,0 +1,328 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package qos
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cubefs/blobstore/blobnode/base/priority"
+	bloberr "github.com/cubefs/blobstore/common/errors"
+)
+
+// virtual time cost of one io, divided by weight of class
+const _vtimeCost = 1 << 20
+
+// Scheduler admits at most QueueDepth inflight io of disk,
+// waiting io are dispatched in order:
+//  1. the expired one with the earliest deadline
+//  2. weighted fair queuing between classes
+type Scheduler struct {
+	lock        sync.Mutex
+	depth       int
+	maxQueueLen int
+	inflight    int
+	waiting     int
+	vtime       uint64
+	classes     []*ioClass
+}
+
+type ioClass struct {
+	name     string
+	weight   uint64
+	deadline time.Duration
+	vtime    uint64
+	waiters  list.List
+
+	dispatched uint64
+	rejected   uint64
+	expired    uint64
+	totalWait  time.Duration
+	maxWait    time.Duration
+}
+
+type ioWaiter struct {
+	class      *ioClass
+	enqueue    time.Time
+	deadline   time.Time
+	ready      chan struct{}
+	dispatched bool
+}
+
+type ClassStat struct {
+	Weight     uint64 `json:"weight"`
+	DeadlineMs int64  `json:"deadline_ms"`
+	QueueLen   int    `json:"queue_len"`
+	Dispatched uint64 `json:"dispatched"`
+	Rejected   uint64 `json:"rejected"`
+	Expired    uint64 `json:"expired"`
+	AvgWaitUs  int64  `json:"avg_wait_us"`
+	MaxWaitUs  int64  `json:"max_wait_us"`
+}
+
+type SchedulerStat struct {
+	QueueDepth int                  `json:"queue_depth"`
+	Inflight   int                  `json:"inflight"`
+	Classes    map[string]ClassStat `json:"classes"`
+}
+
+func NewScheduler(conf SchedulerConfig) (*Scheduler, error) {
+	if err := initSchedulerConfig(&conf); err != nil {
+		return nil, err
+	}
+
+	levels := priority.GetLevels()
+	s := &Scheduler{
+		depth:       conf.QueueDepth,
+		maxQueueLen: conf.MaxQueueLen,
+		classes:     make([]*ioClass, len(levels)),
+	}
+	for pri, name := range levels {
+		class := conf.Classes[name]
+		s.classes[pri] = &ioClass{
+			name:     name,
+			weight:   uint64(class.Weight),
+			deadline: time.Duration(class.DeadlineMs) * time.Millisecond,
+		}
+	}
+
+	return s, nil
+}
+
+// Acquire waits for an inflight slot of disk, Release must be called after io done
+func (s *Scheduler) Acquire(ctx context.Context, pri priority.Priority) error {
+	class := s.classes[pri]
+	now := time.Now()
+
+	s.lock.Lock()
+	if s.inflight < s.depth && s.waiting == 0 {
+		s.inflight++
+		s.dispatchLocked(class, 0)
+		s.lock.Unlock()
+		return nil
+	}
+
+	if class.waiters.Len() >= s.maxQueueLen {
+		class.rejected++
+		s.lock.Unlock()
+		return bloberr.ErrOverload
+	}
+
+	w := &ioWaiter{
+		class:    class,
+		enqueue:  now,
+		deadline: now.Add(class.deadline),
+		ready:    make(chan struct{}),
+	}
+	elem := class.waiters.PushBack(w)
+	s.waiting++
+	s.lock.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.lock.Lock()
+	if !w.dispatched {
+		class.waiters.Remove(elem)
+		s.waiting--
+		s.lock.Unlock()
+		return ctx.Err()
+	}
+	s.lock.Unlock()
+
+	// dispatched concurrently, give back the slot
+	s.Release()
+	return ctx.Err()
+}
+
+func (s *Scheduler) Release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.inflight--
+	now := time.Now()
+	for s.inflight < s.depth && s.waiting > 0 {
+		class := s.pickLocked(now)
+		w := class.waiters.Remove(class.waiters.Front()).(*ioWaiter)
+		s.waiting--
+		s.inflight++
+
+		if now.After(w.deadline) {
+			class.expired++
+		}
+		s.dispatchLocked(class, now.Sub(w.enqueue))
+		w.dispatched = true
+		close(w.ready)
+	}
+}
+
+// pickLocked returns the class to dispatch, there must be waiting io
+func (s *Scheduler) pickLocked(now time.Time) (picked *ioClass) {
+	// expired io first
+	var earliest time.Time
+	for _, class := range s.classes {
+		if class.waiters.Len() == 0 {
+			continue
+		}
+		deadline := class.waiters.Front().Value.(*ioWaiter).deadline
+		if now.After(deadline) && (picked == nil || deadline.Before(earliest)) {
+			picked, earliest = class, deadline
+		}
+	}
+	if picked != nil {
+		return picked
+	}
+
+	// the smallest virtual time
+	var minVtime uint64
+	for _, class := range s.classes {
+		if class.waiters.Len() == 0 {
+			continue
+		}
+		vtime := s.classVtime(class)
+		if picked == nil || vtime < minVtime {
+			picked, minVtime = class, vtime
+		}
+	}
+	return picked
+}
+
+// an idle class catches up with scheduler, it can not save up share when idle
+func (s *Scheduler) classVtime(class *ioClass) uint64 {
+	if class.vtime < s.vtime {
+		return s.vtime
+	}
+	return class.vtime
+}
+
+func (s *Scheduler) dispatchLocked(class *ioClass, wait time.Duration) {
+	s.vtime = s.classVtime(class)
+	class.vtime = s.vtime + _vtimeCost/class.weight
+
+	class.dispatched++
+	class.totalWait += wait
+	if wait > class.maxWait {
+		class.maxWait = wait
+	}
+}
+
+func (s *Scheduler) Stat() *SchedulerStat {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stat := &SchedulerStat{
+		QueueDepth: s.depth,
+		Inflight:   s.inflight,
+		Classes:    make(map[string]ClassStat, len(s.classes)),
+	}
+	for _, class := range s.classes {
+		cs := ClassStat{
+			Weight:     class.weight,
+			DeadlineMs: class.deadline.Milliseconds(),
+			QueueLen:   class.waiters.Len(),
+			Dispatched: class.dispatched,
+			Rejected:   class.rejected,
+			Expired:    class.expired,
+			MaxWaitUs:  class.maxWait.Microseconds(),
+		}
+		if class.dispatched > 0 {
+			cs.AvgWaitUs = class.totalWait.Microseconds() / int64(class.dispatched)
+		}
+		stat.Classes[class.name] = cs
+	}
+	return stat
+}
+
+type schedReader struct {
+	underlying io.Reader
+	s          *Scheduler
+	pri        priority.Priority
+	ctx        context.Context
+}
+
+type schedReaderAt struct {
+	underlying io.ReaderAt
+	s          *Scheduler
+	pri        priority.Priority
+	ctx        context.Context
+}
+
+type schedWriter struct {
+	underlying io.Writer
+	s          *Scheduler
+	pri        priority.Priority
+	ctx        context.Context
+}
+
+type schedWriterAt struct {
+	underlying io.WriterAt
+	s          *Scheduler
+	pri        priority.Priority
+	ctx        context.Context
+}
+
+func (r *schedReader) Read(p []byte) (n int, err error) {
+	if err = r.s.Acquire(r.ctx, r.pri); err != nil {
+		return
+	}
+	defer r.s.Release()
+	return r.underlying.Read(p)
+}
+
+func (rt *schedReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if err = rt.s.Acquire(rt.ctx, rt.pri); err != nil {
+		return
+	}
+	defer rt.s.Release()
+	return rt.underlying.ReadAt(p, off)
+}
+
+func (w *schedWriter) Write(p []byte) (written int, err error) {
+	if err = w.s.Acquire(w.ctx, w.pri); err != nil {
+		return
+	}
+	defer w.s.Release()
+	return w.underlying.Write(p)
+}
+
+func (wt *schedWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	if err = wt.s.Acquire(wt.ctx, wt.pri); err != nil {
+		return
+	}
+	defer wt.s.Release()
+	return wt.underlying.WriteAt(p, off)
+}
+
+func (s *Scheduler) Reader(ctx context.Context, pri priority.Priority, underlying io.Reader) io.Reader {
+	return &schedReader{underlying: underlying, s: s, pri: pri, ctx: ctx}
+}
+
+func (s *Scheduler) ReaderAt(ctx context.Context, pri priority.Priority, underlying io.ReaderAt) io.ReaderAt {
+	return &schedReaderAt{underlying: underlying, s: s, pri: pri, ctx: ctx}
+}
+
+func (s *Scheduler) Writer(ctx context.Context, pri priority.Priority, underlying io.Writer) io.Writer {
+	return &schedWriter{underlying: underlying, s: s, pri: pri, ctx: ctx}
+}
+
+func (s *Scheduler) WriterAt(ctx context.Context, pri priority.Priority, underlying io.WriterAt) io.WriterAt {
+	return &schedWriterAt{underlying: underlying, s: s, pri: pri, ctx: ctx}
+}
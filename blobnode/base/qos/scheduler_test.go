@@ -0,0 +1,203 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package qos
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/base/priority"
+	bloberr "github.com/cubefs/blobstore/common/errors"
+)
+
+var (
+	priNormal     = priority.GetPriority(bnapi.NormalIO)
+	priCompact    = priority.GetPriority(bnapi.CompactIO)
+	priInternal   = priority.GetPriority(bnapi.InternalIO)
+	nameNormal    = priNormal.String()
+	nameInternal  = priInternal.String()
+	schedDeadline = 10 * time.Second
+)
+
+// enqueue acquires in background, returns after it is queued
+func enqueue(t *testing.T, s *Scheduler, pri priority.Priority, done func(error)) {
+	queued := s.Stat().Classes[pri.String()].QueueLen
+	go func() {
+		done(s.Acquire(context.Background(), pri))
+	}()
+	require.Eventually(t, func() bool {
+		return s.Stat().Classes[pri.String()].QueueLen == queued+1
+	}, schedDeadline, time.Millisecond)
+}
+
+func TestSchedulerConfig(t *testing.T) {
+	_, err := NewScheduler(SchedulerConfig{QueueDepth: -1})
+	require.ErrorIs(t, err, ErrWrongConfig)
+	_, err = NewScheduler(SchedulerConfig{Classes: map[string]ClassConfig{"level9": {}}})
+	require.ErrorIs(t, err, ErrWrongConfig)
+
+	s, err := NewScheduler(SchedulerConfig{Classes: map[string]ClassConfig{nameNormal: {Weight: 16}}})
+	require.NoError(t, err)
+	stat := s.Stat()
+	require.Equal(t, defaultQueueDepth, stat.QueueDepth)
+	require.Equal(t, uint64(16), stat.Classes[nameNormal].Weight)
+	require.Equal(t, defaultClassDeadlineMs[0], stat.Classes[nameNormal].DeadlineMs)
+	require.Equal(t, uint64(defaultClassWeights[3]), stat.Classes[nameInternal].Weight)
+}
+
+func TestSchedulerAdmission(t *testing.T) {
+	s, err := NewScheduler(SchedulerConfig{QueueDepth: 1, MaxQueueLen: 1})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, s.Acquire(ctx, priNormal))
+
+	acquired := make(chan error, 1)
+	enqueue(t, s, priInternal, func(err error) { acquired <- err })
+	// queue of class is full
+	require.ErrorIs(t, s.Acquire(ctx, priInternal), bloberr.ErrOverload)
+	require.Equal(t, uint64(1), s.Stat().Classes[nameInternal].Rejected)
+
+	// canceled when waiting
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, s.Acquire(cctx, priNormal), context.DeadlineExceeded)
+	require.Equal(t, 0, s.Stat().Classes[nameNormal].QueueLen)
+
+	s.Release()
+	require.NoError(t, <-acquired)
+	stat := s.Stat()
+	require.Equal(t, 1, stat.Inflight)
+	require.Equal(t, 0, stat.Classes[nameInternal].QueueLen)
+	require.Equal(t, uint64(1), stat.Classes[nameInternal].Dispatched)
+
+	s.Release()
+	require.Equal(t, 0, s.Stat().Inflight)
+}
+
+func TestSchedulerWeightedFair(t *testing.T) {
+	s, err := NewScheduler(SchedulerConfig{QueueDepth: 1})
+	require.NoError(t, err)
+	require.NoError(t, s.Acquire(context.Background(), priNormal))
+
+	var (
+		lock  sync.Mutex
+		order []priority.Priority
+		wg    sync.WaitGroup
+	)
+	done := func(pri priority.Priority) func(error) {
+		return func(err error) {
+			require.NoError(t, err)
+			lock.Lock()
+			order = append(order, pri)
+			lock.Unlock()
+			s.Release()
+			wg.Done()
+		}
+	}
+
+	// compact io queued before normal io
+	wg.Add(8)
+	for i := 0; i < 4; i++ {
+		enqueue(t, s, priCompact, done(priCompact))
+	}
+	for i := 0; i < 4; i++ {
+		enqueue(t, s, priNormal, done(priNormal))
+	}
+	s.Release()
+	wg.Wait()
+
+	require.Equal(t, []priority.Priority{
+		priCompact, priNormal, priNormal, priNormal, priNormal, priCompact, priCompact, priCompact,
+	}, order)
+	require.Equal(t, 0, s.Stat().Inflight)
+}
+
+func TestSchedulerDeadline(t *testing.T) {
+	s, err := NewScheduler(SchedulerConfig{
+		QueueDepth: 1,
+		Classes:    map[string]ClassConfig{nameInternal: {DeadlineMs: 1}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Acquire(context.Background(), priNormal))
+
+	var (
+		lock  sync.Mutex
+		order []priority.Priority
+		wg    sync.WaitGroup
+	)
+	done := func(pri priority.Priority) func(error) {
+		return func(err error) {
+			require.NoError(t, err)
+			lock.Lock()
+			order = append(order, pri)
+			lock.Unlock()
+			s.Release()
+			wg.Done()
+		}
+	}
+
+	wg.Add(3)
+	enqueue(t, s, priNormal, done(priNormal))
+	enqueue(t, s, priInternal, done(priInternal))
+	enqueue(t, s, priNormal, done(priNormal))
+	time.Sleep(5 * time.Millisecond)
+	s.Release()
+	wg.Wait()
+
+	// expired io is dispatched first
+	require.Equal(t, []priority.Priority{priInternal, priNormal, priNormal}, order)
+	stat := s.Stat()
+	require.Equal(t, uint64(1), stat.Classes[nameInternal].Expired)
+	require.True(t, stat.Classes[nameInternal].MaxWaitUs >= 5000)
+	require.Equal(t, uint64(3), stat.Classes[nameNormal].Dispatched)
+}
+
+func TestQosManagerScheduler(t *testing.T) {
+	ctx := context.Background()
+
+	q, err := NewQosManager(Config{})
+	require.NoError(t, err)
+	require.Nil(t, q.SchedulerStat())
+
+	_, err = NewQosManager(Config{Scheduler: SchedulerConfig{Enable: true, MaxQueueLen: -1}})
+	require.ErrorIs(t, err, ErrWrongConfig)
+
+	q, err = NewQosManager(Config{Scheduler: SchedulerConfig{Enable: true}})
+	require.NoError(t, err)
+
+	data := []byte("scheduled io")
+	buf := make([]byte, len(data))
+	_, err = q.ReaderAt(ctx, bnapi.NormalIO, bytes.NewReader(data)).ReadAt(buf, 0)
+	require.NoError(t, err)
+	require.Equal(t, data, buf)
+
+	w := bytes.NewBuffer(nil)
+	_, err = q.Writer(ctx, bnapi.CompactIO, w).Write(data)
+	require.NoError(t, err)
+	require.Equal(t, data, w.Bytes())
+
+	stat := q.SchedulerStat()
+	require.NotNil(t, stat)
+	require.Equal(t, 0, stat.Inflight)
+	require.Equal(t, uint64(1), stat.Classes[nameNormal].Dispatched)
+	require.Equal(t, uint64(1), stat.Classes[priCompact.String()].Dispatched)
+}
@@ -145,6 +145,7 @@ func (s *Service) ChunkRelease_(c *rpc.Context) {
 		c.RespondError(err)
 		return
 	}
+	s.invalidateChunkCache(args.Vuid)
 
 	span.Infof("disk release vuid:%v success", args.Vuid)
 }
@@ -157,6 +157,7 @@ func (s *Service) checkAndCleanGarbageEpoch(ctx context.Context) {
 			if err != nil {
 				span.Errorf("release ChunkStorage(%s) form disk(%v) failed: %v", cs.ID(), disk.ID(), err)
 			}
+			s.invalidateChunkCache(vuid)
 			span.Infof("vuid(%v) have been release", vuid)
 		}
 	}
@@ -18,6 +18,8 @@ import (
 	"os"
 
 	cmapi "github.com/cubefs/blobstore/api/clustermgr"
+	mqapi "github.com/cubefs/blobstore/api/mqproxy"
+	"github.com/cubefs/blobstore/blobnode/base/cache"
 	"github.com/cubefs/blobstore/blobnode/core"
 	"github.com/cubefs/blobstore/blobnode/db"
 	"github.com/cubefs/blobstore/cmd"
@@ -47,6 +49,10 @@ type Config struct {
 	FlockFilename string             `json:"flock_filename"`
 
 	Clustermgr *cmapi.Config `json:"clustermgr"`
+	// MQProxy to report corrupt shards found by scrubber
+	MQProxy mqapi.LbConfig `json:"mqproxy"`
+	// ShardCache read cache of hot shards
+	ShardCache cache.Config `json:"shard_cache"`
 
 	HeartbeatIntervalSec        int `json:"heartbeat_interval_S"`
 	ChunkReportIntervalSec      int `json:"chunk_report_interval_S"`
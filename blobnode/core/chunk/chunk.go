@@ -20,6 +20,7 @@ import (
 	"io"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -103,7 +104,7 @@ func newChunkStorage(ctx context.Context, dataPath string, vm core.VuidMeta, opt
 	}
 
 	// new chunkData fd
-	cd, err := storage.NewChunkData(ctx, vm, chunkFile, opt.Conf, opt.CreateDataIfMiss, opt.IoQos)
+	cd, err := storage.NewDataHandler(ctx, vm, chunkFile, opt.Conf, opt.CreateDataIfMiss, opt.IoQos)
 	if err != nil {
 		span.Errorf("Failed new chunk data. dp:%s, err:%v", dataPath, err)
 		return nil, err
@@ -267,6 +268,10 @@ func (cs *chunk) Write(ctx context.Context, b *core.Shard) (err error) {
 
 	cs.lock.RLock()
 
+	if err = cs.frozenErr(); err != nil {
+		cs.lock.RUnlock()
+		return err
+	}
 	if cs.compacting {
 		cs.bidlimiter.Acquire(b.Bid)
 		defer cs.bidlimiter.Release(b.Bid)
@@ -287,6 +292,71 @@ func (cs *chunk) Write(ctx context.Context, b *core.Shard) (err error) {
 	return nil
 }
 
+// WriteBatch writes shards of this chunk in order, metas are written in one batch.
+// errs are one-to-one with bs.
+func (cs *chunk) WriteBatch(ctx context.Context, bs []*core.Shard) (errs []error) {
+	errs = make([]error, len(bs))
+	for _, b := range bs {
+		if b.Vuid != cs.vuid {
+			for i := range errs {
+				errs[i] = bloberr.ErrVuidNotMatch
+			}
+			return errs
+		}
+	}
+
+	elem := cs.consistent.Begin(struct{}{})
+	defer cs.consistent.End(elem)
+
+	var size uint64
+	for _, b := range bs {
+		size += uint64(b.Size)
+	}
+
+	// statistics
+	cs.stats.writeBefore()
+	defer cs.stats.writeAfter(size, time.Now())
+
+	cs.lock.RLock()
+
+	if err := cs.frozenErr(); err != nil {
+		cs.lock.RUnlock()
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	if cs.compacting {
+		// acquire in order of bid, avoid deadlock between batches
+		bids := make([]proto.BlobID, 0, len(bs))
+		for _, b := range bs {
+			bids = append(bids, b.Bid)
+		}
+		sort.Slice(bids, func(i, j int) bool { return bids[i] < bids[j] })
+		for _, bid := range bids {
+			cs.bidlimiter.Acquire(bid)
+			defer cs.bidlimiter.Release(bid)
+		}
+	}
+	stg := cs.GetStg()
+	defer cs.PutStg(stg)
+
+	cs.lock.RUnlock()
+
+	errs = stg.WriteBatch(ctx, bs)
+
+	// update stats
+	for i, b := range bs {
+		if errs[i] != nil {
+			continue
+		}
+		atomic.AddUint64(&cs.fileInfo.Used, uint64(core.Alignphysize(int64(b.Size))))
+		atomic.StoreUint32(&cs.dirty, 1)
+	}
+
+	return errs
+}
+
 func (cs *chunk) Disk() core.DiskAPI {
 	return cs.disk
 }
@@ -545,6 +615,9 @@ func (cs *chunk) rangeRead(ctx context.Context, stg core.Storage, s *core.Shard,
 func (cs *chunk) MarkDelete(ctx context.Context, bid proto.BlobID) (err error) {
 	span := trace.SpanFromContextSafe(ctx)
 
+	elem := cs.consistent.Begin(bid)
+	defer cs.consistent.End(elem)
+
 	// statistics
 	cs.stats.markdeleteBefore()
 	defer cs.stats.markdeleteAfter(time.Now())
@@ -555,6 +628,10 @@ func (cs *chunk) MarkDelete(ctx context.Context, bid proto.BlobID) (err error) {
 		cs.lock.RUnlock()
 		return bloberr.ErrChunkInCompact
 	}
+	if err = cs.frozenErr(); err != nil {
+		cs.lock.RUnlock()
+		return err
+	}
 
 	stg := cs.GetStg()
 	defer cs.PutStg(stg)
@@ -573,6 +650,9 @@ func (cs *chunk) MarkDelete(ctx context.Context, bid proto.BlobID) (err error) {
 func (cs *chunk) Delete(ctx context.Context, bid proto.BlobID) (err error) {
 	span := trace.SpanFromContextSafe(ctx)
 
+	elem := cs.consistent.Begin(bid)
+	defer cs.consistent.End(elem)
+
 	// statistics
 	cs.stats.deleteBefore()
 	defer cs.stats.deleteAfter(time.Now())
@@ -583,6 +663,10 @@ func (cs *chunk) Delete(ctx context.Context, bid proto.BlobID) (err error) {
 		cs.lock.RUnlock()
 		return bloberr.ErrChunkInCompact
 	}
+	if err = cs.frozenErr(); err != nil {
+		cs.lock.RUnlock()
+		return err
+	}
 
 	stg := cs.GetStg()
 	defer cs.PutStg(stg)
@@ -649,12 +733,13 @@ func (cs *chunk) ListShards(ctx context.Context, startBid proto.BlobID, cnt int,
 		}
 
 		infos = append(infos, &bnapi.ShardInfo{
-			Vuid:   cs.vuid,
-			Bid:    bid,
-			Size:   int64(shard.Size),
-			Crc:    shard.Crc,
-			Flag:   shard.Flag,
-			Inline: shard.Inline,
+			Vuid:     cs.vuid,
+			Bid:      bid,
+			Size:     int64(shard.Size),
+			Crc:      shard.Crc,
+			Flag:     shard.Flag,
+			Inline:   shard.Inline,
+			ExpireAt: shard.ExpireAt,
 		})
 
 		next = bid
@@ -777,6 +862,31 @@ func (cs *chunk) SetStatus(status bnapi.ChunkStatus) (err error) {
 	return nil
 }
 
+// frozenErr returns error of modifying readonly or released chunk, cs.lock must be held
+func (cs *chunk) frozenErr() error {
+	switch cs.status {
+	case bnapi.ChunkStatusReadOnly:
+		return bloberr.ErrReadonlyVUID
+	case bnapi.ChunkStatusRelease:
+		return bloberr.ErrReleaseVUID
+	default:
+		return nil
+	}
+}
+
+// Freeze sets status of chunk which rejects modifications,
+// and waits for the modifications in flight to complete
+func (cs *chunk) Freeze(ctx context.Context, status bnapi.ChunkStatus) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	cs.lock.Lock()
+	cs.status = status
+	cs.lock.Unlock()
+
+	timestamp := cs.consistent.Synchronize()
+	span.Infof("chunk:%s frozen with status:%v, timestamp:%v", cs.ID(), status, timestamp)
+}
+
 func (cs *chunk) Status() (status bnapi.ChunkStatus) {
 	cs.lock.RLock()
 	defer cs.lock.RUnlock()
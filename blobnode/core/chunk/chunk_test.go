@@ -17,7 +17,9 @@ package chunk
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"hash/crc32"
+	"io"
 	"io/ioutil"
 	"os"
 	"runtime"
@@ -31,6 +33,7 @@ import (
 	"github.com/cubefs/blobstore/blobnode/base/qos"
 	"github.com/cubefs/blobstore/blobnode/core"
 	"github.com/cubefs/blobstore/blobnode/db"
+	bloberr "github.com/cubefs/blobstore/common/errors"
 	"github.com/cubefs/blobstore/common/proto"
 	"github.com/cubefs/blobstore/common/trace"
 )
@@ -214,6 +217,20 @@ func TestChunkStorage_ReadWrite(t *testing.T) {
 	shard.Body = bytes.NewReader(shardData)
 	err = cs.Write(ctx, shard)
 	require.NoError(t, err)
+	cs.compacting = false
+
+	// frozen chunk rejects modifications, reading is ok
+	cs.Freeze(ctx, bnapi.ChunkStatusReadOnly)
+	shard.Body = bytes.NewReader(shardData)
+	require.ErrorIs(t, cs.Write(ctx, shard), bloberr.ErrReadonlyVUID)
+	require.ErrorIs(t, cs.MarkDelete(ctx, bid), bloberr.ErrReadonlyVUID)
+	require.ErrorIs(t, cs.Delete(ctx, bid), bloberr.ErrReadonlyVUID)
+	_, err = cs.ReadShardMeta(ctx, bid)
+	require.NoError(t, err)
+
+	cs.Freeze(ctx, bnapi.ChunkStatusRelease)
+	errs := cs.WriteBatch(ctx, []*core.Shard{shard})
+	require.ErrorIs(t, errs[0], bloberr.ErrReleaseVUID)
 }
 
 func TestChunkStorage_ReadWriteInline(t *testing.T) {
@@ -334,6 +351,105 @@ func TestChunkStorage_ReadWriteInline(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestChunkStorage_WriteBatch(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), defaultDiskTestDir+"ChunkStorageWriteBatch")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	ctx := context.Background()
+
+	conf := &core.Config{
+		RuntimeConfig: core.RuntimeConfig{
+			MetricReportIntervalS: 30,
+		},
+		MetaConfig: db.MetaConfig{
+			SupportInline: true,
+		},
+	}
+
+	vuid := proto.Vuid(1)
+	chunkid := bnapi.NewChunkId(vuid)
+
+	err = core.EnsureDiskArea(testDir, "")
+	require.NoError(t, err)
+
+	datapath := core.GetDataPath(testDir)
+	metapath := core.GetMetaPath(testDir, "")
+
+	kvdb, err := db.NewMetaHandler(metapath, db.MetaConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, kvdb)
+
+	vm := core.VuidMeta{
+		Vuid:    vuid,
+		DiskID:  12,
+		ChunkId: chunkid,
+		Mtime:   time.Now().UnixNano(),
+		Status:  bnapi.ChunkStatusNormal,
+	}
+
+	ioQos, _ := qos.NewQosManager(qos.Config{})
+	cs, err := NewChunkStorage(ctx, datapath, vm, func(option *core.Option) {
+		option.Conf = conf
+		option.DB = kvdb
+		option.CreateDataIfMiss = true
+		option.IoQos = ioQos
+	})
+	require.NoError(t, err)
+	require.NotNil(t, cs)
+
+	// inline, normal and truncated shard in one stream
+	datas := [][]byte{[]byte("test"), make([]byte, 64*1024), []byte("truncated")}
+	rand.Read(datas[1])
+	body := bytes.NewReader(append(append([]byte{}, datas[0]...), datas[1]...))
+
+	shards := make([]*core.Shard, 0, len(datas))
+	for i, data := range datas {
+		shards = append(shards, core.NewShardWriter(proto.BlobID(i+1), vuid, uint32(len(data)),
+			io.LimitReader(body, int64(len(data)))))
+	}
+
+	errs := cs.WriteBatch(ctx, shards)
+	require.Equal(t, 3, len(errs))
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	require.Error(t, errs[2])
+	require.NoError(t, cs.SyncData(ctx))
+
+	for i, data := range datas[:2] {
+		require.Equal(t, crc32.ChecksumIEEE(data), shards[i].Crc)
+
+		rs, err := cs.NewReader(ctx, shards[i].Bid)
+		require.NoError(t, err)
+		rd, err := ioutil.ReadAll(rs.Body)
+		require.NoError(t, err)
+		require.Equal(t, data, rd)
+	}
+	sm, err := cs.ReadShardMeta(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, sm.Inline)
+	sm, err = cs.ReadShardMeta(ctx, 2)
+	require.NoError(t, err)
+	require.False(t, sm.Inline)
+	_, err = cs.ReadShardMeta(ctx, 3)
+	require.True(t, os.IsNotExist(err))
+
+	// vuid not match
+	errs = cs.WriteBatch(ctx, []*core.Shard{
+		core.NewShardWriter(4, vuid, 1, bytes.NewReader([]byte("a"))),
+		core.NewShardWriter(5, vuid+1, 1, bytes.NewReader([]byte("b"))),
+	})
+	require.Equal(t, []error{bloberr.ErrVuidNotMatch, bloberr.ErrVuidNotMatch}, errs)
+
+	// write batch when compacting
+	cs.compacting = true
+	errs = cs.WriteBatch(ctx, []*core.Shard{
+		core.NewShardWriter(5, vuid, 1, bytes.NewReader([]byte("b"))),
+		core.NewShardWriter(4, vuid, 1, bytes.NewReader([]byte("a"))),
+	})
+	require.Equal(t, []error{nil, nil}, errs)
+}
+
 func TestChunkStorage_DeleteOp(t *testing.T) {
 	testDir, err := ioutil.TempDir(os.TempDir(), defaultDiskTestDir+"ChunkStorageDelete")
 	require.NoError(t, err)
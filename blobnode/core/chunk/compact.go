@@ -23,6 +23,7 @@ import (
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
 	"github.com/cubefs/blobstore/blobnode/core"
 	"github.com/cubefs/blobstore/blobnode/core/storage"
+	bloberr "github.com/cubefs/blobstore/common/errors"
 	"github.com/cubefs/blobstore/common/proto"
 	"github.com/cubefs/blobstore/common/trace"
 )
@@ -76,6 +77,33 @@ func (cs *chunk) StartCompact(ctx context.Context) (newcs core.ChunkAPI, err err
 		return nil, err
 	}
 
+	if err = cs.replicateTo(ctx, ncs); err != nil {
+		return nil, err
+	}
+
+	return ncs, nil
+}
+
+// StartMigrate copies all shards to ncs which may be on another disk,
+// chunk keeps double writing to ncs until StopCompact
+func (cs *chunk) StartMigrate(ctx context.Context, ncs core.ChunkAPI) (err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Debugf("==== start migrate chunk:(%s) to disk:%v ====", cs.ID(), ncs.Disk().ID())
+
+	return cs.replicateTo(ctx, ncs.(*chunk))
+}
+
+// AbortMigrate stops double writing and destroys ncs, migration must be started
+func (cs *chunk) AbortMigrate(ctx context.Context, ncs core.ChunkAPI) (err error) {
+	cs.handleErrCompact(ctx, ncs)
+	return nil
+}
+
+// replicateTo sets double write stg of chunk and ncs, then copies shards to ncs
+func (cs *chunk) replicateTo(ctx context.Context, ncs *chunk) (err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
 	task := cs.compactTask.Load().(*compactTask)
 	notify := func(err error) {
 		task.once.Do(func() {
@@ -83,11 +111,19 @@ func (cs *chunk) StartCompact(ctx context.Context) (newcs core.ChunkAPI, err err
 		})
 	}
 
+	cs.lock.Lock()
+	stg := cs.getStg()
+	if stg.RawStorage() != nil {
+		cs.lock.Unlock()
+		span.Errorf("chunk:%s is replicating already", cs.ID())
+		ncs.Destroy(ctx)
+		return bloberr.ErrChunkInCompact
+	}
+
 	// create replicate stg
 	backgroundStg := ncs.getStg()
 	repStg := storage.NewReplicateStg(stg, backgroundStg, notify)
 
-	cs.lock.Lock()
 	{
 		cs.compacting = true
 		// note: set double write stg
@@ -116,10 +152,10 @@ func (cs *chunk) StartCompact(ctx context.Context) (newcs core.ChunkAPI, err err
 ErrCompact:
 	if err != nil {
 		cs.handleErrCompact(ctx, ncs)
-		return nil, err
+		return err
 	}
 
-	return ncs, nil
+	return nil
 }
 
 func (cs *chunk) handleErrCompact(ctx context.Context, ncs core.ChunkAPI) {
@@ -136,6 +172,9 @@ func (cs *chunk) handleErrCompact(ctx context.Context, ncs core.ChunkAPI) {
 		// restore raw stg
 		cs.setStg(rawStg)
 
+		// replicating is over, the chunk can be compacted or migrated again
+		cs.compacting = false
+		cs.resetCompactTask()
 	}
 	cs.lock.Unlock()
 
@@ -161,6 +200,12 @@ func (cs *chunk) doCompact(ctx context.Context, ncs *chunk) (err error) {
 		// update startBid
 		startBid = blobID
 
+		// expired shard is dropped
+		if srcMeta.Expired(time.Now()) {
+			span.Debugf("drop expired shard(%v), expire at:%d", blobID, srcMeta.ExpireAt)
+			return nil
+		}
+
 		cs.bidlimiter.Acquire(blobID)
 		defer cs.bidlimiter.Release(blobID)
 
@@ -351,6 +396,11 @@ func (cs *chunk) compactCheck(ctx context.Context, ncs *chunk) (err error) {
 		// update start bid
 		startBid = blobID
 
+		// expired shard may be dropped in copying
+		if srcMeta.Expired(time.Now()) {
+			return nil
+		}
+
 		cs.bidlimiter.Acquire(blobID)
 		defer cs.bidlimiter.Release(blobID)
 
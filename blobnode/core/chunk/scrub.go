@@ -0,0 +1,115 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package chunk
+
+import (
+	"context"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/common/crc32block"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+/*
+Scrub scans at most cnt shards after startBid, re-reads body of every
+normal shard and verifies its crc.
+Return:
+	- bad:  bids of corrupt shards
+	- next: the last scanned bid, InValidBlobID if reached the end of chunk
+*/
+func (cs *chunk) Scrub(ctx context.Context, startBid proto.BlobID, cnt int) (
+	bad []proto.BlobID, next proto.BlobID, err error,
+) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	// scrub io is background io
+	ctx = bnapi.Setiotype(ctx, bnapi.InternalIO)
+
+	bids := make([]proto.BlobID, 0, cnt)
+	fn := func(bid proto.BlobID, sm *core.ShardMeta) error {
+		next = bid
+		if sm.Flag == bnapi.ShardStatusNormal {
+			bids = append(bids, bid)
+		}
+		return nil
+	}
+
+	stg := cs.GetStg()
+	err = stg.ScanMeta(ctx, startBid, cnt, fn)
+	cs.PutStg(stg)
+	if err != nil {
+		if err != core.ErrChunkScanEOF {
+			span.Errorf("scan vuid:%v shard occur error: %v", cs.vuid, err)
+			return nil, proto.InValidBlobID, err
+		}
+		next = proto.InValidBlobID
+	}
+
+	for _, bid := range bids {
+		ok, err := cs.verifyShard(ctx, bid)
+		if err != nil {
+			span.Errorf("verify vuid:%v bid:%d occur error: %v", cs.vuid, bid, err)
+			return nil, proto.InValidBlobID, err
+		}
+		if !ok {
+			span.Warnf("vuid:%v bid:%d is corrupt", cs.vuid, bid)
+			bad = append(bad, bid)
+		}
+	}
+
+	return bad, next, nil
+}
+
+func (cs *chunk) verifyShard(ctx context.Context, bid proto.BlobID) (ok bool, err error) {
+	elem := cs.consistent.Begin(bid)
+	defer cs.consistent.End(elem)
+
+	stg := cs.GetStg()
+	defer cs.PutStg(stg)
+
+	sm, err := stg.ReadShardMeta(ctx, bid)
+	if err != nil {
+		// deleted after scanned
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	s := core.NewShardReader(bid, cs.vuid, 0, int64(sm.Size), nil)
+	s.FillMeta(*sm)
+
+	rc, err := stg.NewRangeReader(ctx, s, 0, int64(sm.Size))
+	if err != nil {
+		return false, err
+	}
+
+	crc := crc32.NewIEEE()
+	_, err = io.CopyN(ioutil.Discard, io.TeeReader(rc, crc), int64(sm.Size))
+	if err == crc32block.ErrMismatchedCrc {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return crc.Sum32() == sm.Crc, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package chunk
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/base/qos"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/blobnode/db"
+	"github.com/cubefs/blobstore/common/proto"
+)
+
+func TestChunkStorage_Scrub(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), defaultDiskTestDir+"ChunkStorageScrub")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	ctx := context.Background()
+
+	conf := &core.Config{
+		RuntimeConfig: core.RuntimeConfig{
+			MetricReportIntervalS: 30,
+		},
+	}
+
+	vuid := proto.Vuid(1)
+	chunkid := bnapi.NewChunkId(vuid)
+
+	err = core.EnsureDiskArea(testDir, "")
+	require.NoError(t, err)
+
+	datapath := core.GetDataPath(testDir)
+	metapath := core.GetMetaPath(testDir, "")
+
+	kvdb, err := db.NewMetaHandler(metapath, db.MetaConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, kvdb)
+
+	vm := core.VuidMeta{
+		Vuid:    vuid,
+		DiskID:  12,
+		ChunkId: chunkid,
+		Mtime:   time.Now().UnixNano(),
+		Status:  bnapi.ChunkStatusNormal,
+	}
+
+	ioQos, _ := qos.NewQosManager(qos.Config{})
+	cs, err := NewChunkStorage(ctx, datapath, vm, func(option *core.Option) {
+		option.Conf = conf
+		option.DB = kvdb
+		option.CreateDataIfMiss = true
+		option.IoQos = ioQos
+	})
+	require.NoError(t, err)
+	require.NotNil(t, cs)
+
+	shardData := make([]byte, 128*1024)
+	rand.Read(shardData)
+	for bid := proto.BlobID(1); bid <= 5; bid++ {
+		shard := &core.Shard{
+			Bid:  bid,
+			Vuid: vuid,
+			Flag: bnapi.ShardStatusNormal,
+			Size: uint32(len(shardData)),
+			Body: bytes.NewReader(shardData),
+		}
+		require.NoError(t, cs.Write(ctx, shard))
+	}
+	require.NoError(t, cs.MarkDelete(ctx, 5))
+
+	// all shards are fine
+	bad, next, err := cs.Scrub(ctx, proto.InValidBlobID, 10)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(bad))
+	require.Equal(t, proto.InValidBlobID, next)
+
+	// corrupt the body of bid 2 and bid 5
+	f, err := os.OpenFile(filepath.Join(datapath, chunkid.String()), os.O_RDWR, 0o644)
+	require.NoError(t, err)
+	defer f.Close()
+	for _, bid := range []proto.BlobID{2, 5} {
+		sm, err := cs.ReadShardMeta(ctx, bid)
+		require.NoError(t, err)
+		_, err = f.WriteAt([]byte("corrupt"), sm.Offset+core.GetShardHeaderSize()+1024)
+		require.NoError(t, err)
+	}
+
+	// scan in batches, mark deleted shard is skipped
+	bad, next, err = cs.Scrub(ctx, proto.InValidBlobID, 2)
+	require.NoError(t, err)
+	require.Equal(t, []proto.BlobID{2}, bad)
+	require.Equal(t, proto.BlobID(2), next)
+
+	bad, next, err = cs.Scrub(ctx, next, 2)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(bad))
+	require.Equal(t, proto.BlobID(4), next)
+
+	bad, next, err = cs.Scrub(ctx, next, 2)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(bad))
+	require.Equal(t, proto.InValidBlobID, next)
+}
@@ -41,6 +41,16 @@ const (
 	DefaultCompactTriggerThreshold      = 1 * (1 << 40)   // 1 TiB
 	DefaultMetricReportIntervalS        = 30              // 30 Sec
 	DefaultCompactEmptyRateThreshold    = float64(0.8)    // 80% rate
+	DefaultScrubIntervalSec             = 7 * 24 * 3600   // 7 days
+	DefaultScrubBatchSize               = 128             // 128 counts
+	DefaultSegmentSizeB                 = int64(64 << 20) // 64 MiB
+	DefaultShardExpireIntervalSec       = 60 * 60         // 60 min
+)
+
+// data engines of chunk
+const (
+	DataEngineFile    = "datafile" // shard aligned with header and footer
+	DataEngineSegment = "segment"  // shards packed in append-only segments
 )
 
 // Config for disk
@@ -49,6 +59,9 @@ type BaseConfig struct {
 	AutoFormat  bool   `json:"auto_format"`
 	MaxChunks   int32  `json:"max_chunks"`
 	DisableSync bool   `json:"disable_sync"`
+	// DataEngine of new chunks, existing chunks keep their own engine
+	DataEngine   string `json:"data_engine"`
+	SegmentSizeB int64  `json:"segment_size_B"`
 }
 
 type RuntimeConfig struct {
@@ -73,6 +86,10 @@ type RuntimeConfig struct {
 	IOStatFileDryRun             bool       `json:"iostat_file_dryrun"`
 	MetricReportIntervalS        int64      `json:"metric_report_interval_S"`
 	DiskQos                      qos.Config `json:"data_qos"`
+	EnableScrub                  bool       `json:"enable_scrub"`
+	ScrubIntervalSec             int64      `json:"scrub_interval_S"` // loop
+	ScrubBatchSize               int        `json:"scrub_batch_size"`
+	ShardExpireIntervalSec       int64      `json:"shard_expire_interval_S"` // loop
 }
 
 type HostInfo struct {
@@ -91,6 +108,10 @@ type Config struct {
 	AllocDiskID      func(ctx context.Context) (proto.DiskID, error)
 	HandleIOError    func(ctx context.Context, diskID proto.DiskID, diskErr error)
 	NotifyCompacting func(ctx context.Context, args *cmapi.SetCompactChunkArgs) (err error)
+	// NotifyShardRepair reports the corrupt shard found by scrubber
+	NotifyShardRepair func(ctx context.Context, vuid proto.Vuid, bid proto.BlobID, reason string) (err error)
+	// NotifyChunkMigrated reports the new disk of vuid migrated between local disks
+	NotifyChunkMigrated func(ctx context.Context, args *cmapi.UpdateVolumeArgs) (err error)
 }
 
 func InitConfig(conf *Config) error {
@@ -103,6 +124,16 @@ func InitConfig(conf *Config) error {
 	if conf.AllocDiskID == nil {
 		return errors.New("allocDiskID is not specified")
 	}
+	switch conf.DataEngine {
+	case "":
+		conf.DataEngine = DataEngineFile
+	case DataEngineFile, DataEngineSegment:
+	default:
+		return errors.New("unknown data engine")
+	}
+	if conf.SegmentSizeB <= 0 {
+		conf.SegmentSizeB = DefaultSegmentSizeB
+	}
 	if conf.DiskReservedSpaceB <= 0 {
 		conf.DiskReservedSpaceB = DefaultDiskReservedSpaceB
 	}
@@ -115,6 +146,9 @@ func InitConfig(conf *Config) error {
 	if conf.ChunkCompactIntervalSec <= 0 {
 		conf.ChunkCompactIntervalSec = DefaultChunkCompactIntervalSec
 	}
+	if conf.ShardExpireIntervalSec <= 0 {
+		conf.ShardExpireIntervalSec = DefaultShardExpireIntervalSec
+	}
 	if conf.ChunkCleanIntervalSec <= 0 {
 		conf.ChunkCleanIntervalSec = DefaultChunkCleanIntervalSec
 	}
@@ -147,6 +181,16 @@ func InitConfig(conf *Config) error {
 	if conf.CompactBatchSize <= 0 {
 		conf.CompactBatchSize = DefaultCompactBatchSize
 	}
+	if conf.ScrubIntervalSec <= 0 {
+		conf.ScrubIntervalSec = DefaultScrubIntervalSec
+	}
+	if conf.ScrubBatchSize <= 0 {
+		conf.ScrubBatchSize = DefaultScrubBatchSize
+	}
+	if conf.EnableScrub && conf.NotifyShardRepair == nil {
+		return errors.New("notifyShardRepair is not specified")
+	}
+
 	if conf.MetricReportIntervalS <= 0 {
 		conf.MetricReportIntervalS = DefaultMetricReportIntervalS
 	}
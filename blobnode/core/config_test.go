@@ -40,4 +40,15 @@ func TestInitConfig(t *testing.T) {
 	conf.HandleIOError = func(ctx context.Context, diskID proto.DiskID, diskErr error) {}
 	err = InitConfig(conf)
 	require.Error(t, err)
+
+	conf.AllocDiskID = func(ctx context.Context) (proto.DiskID, error) { return 1, nil }
+	conf.DataEngine = "unknown"
+	err = InitConfig(conf)
+	require.Error(t, err)
+
+	conf.DataEngine = ""
+	err = InitConfig(conf)
+	require.NoError(t, err)
+	require.Equal(t, DataEngineFile, conf.DataEngine)
+	require.Equal(t, DefaultSegmentSizeB, conf.SegmentSizeB)
 }
@@ -84,6 +84,12 @@ func (ds *DiskStorage) runCompactFiles() {
 	}
 	ds.Lock.RUnlock()
 
+	// garbage of expired shards is reclaimed by the following compaction
+	if now := time.Now().Unix(); now >= ds.nextReapExpired {
+		ds.nextReapExpired = now + ds.Conf.ShardExpireIntervalSec
+		ds.reapExpiredShards(ctx, chunks)
+	}
+
 	for _, chunk := range chunks {
 		if !chunk.NeedCompact(ctx) {
 			continue
@@ -112,7 +118,6 @@ func (ds *DiskStorage) CompactChunkInternal(ctx context.Context, vuid proto.Vuid
 	ncs, err := cs.StartCompact(ctx)
 	if err != nil {
 		span.Errorf("Failed start compact, err:%v", err)
-		_ = cs.StopCompact(ctx, nil)
 		return err
 	}
 
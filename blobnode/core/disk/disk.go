@@ -90,6 +90,9 @@ type DiskStorage struct {
 	compactCh chan proto.Vuid
 	closeCh   chan struct{}
 
+	// unix seconds of the next expired shards reaping, see runCompactFiles
+	nextReapExpired int64
+
 	// ctx is used for initiated requests that
 	// may need to be canceled on server shutdown.
 	wg  sync.WaitGroup
@@ -182,6 +185,7 @@ func (ds *DiskStorage) DiskInfo() (info bnapi.DiskInfo) {
 	if info.Size < 0 {
 		info.Size = 0
 	}
+	info.Slow = ds.dataQos.IsSlow()
 
 	// config
 	hostInfo := ds.Conf.HostInfo
@@ -514,6 +518,9 @@ func newDiskStorage(ctx context.Context, conf core.Config) (ds *DiskStorage, err
 	ds.loopAttach(ds.loopDiskUsage)
 	ds.loopAttach(ds.loopCleanTrash)
 	ds.loopAttach(ds.loopMetricReport)
+	if conf.EnableScrub {
+		ds.loopAttach(ds.loopScrub)
+	}
 
 	return ds, nil
 }
@@ -0,0 +1,94 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package disk
+
+import (
+	"context"
+	"time"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+// reapExpiredShards mark deletes and deletes shards whose expiry passed,
+// chunks which are not allowed to modify (readonly, compacting) are skipped.
+func (ds *DiskStorage) reapExpiredShards(ctx context.Context, chunks []core.ChunkAPI) {
+	span := trace.SpanFromContextSafe(ctx)
+	ctx = bnapi.Setiotype(ctx, bnapi.InternalIO)
+
+	var reaped, failed int
+	for _, cs := range chunks {
+		select {
+		case <-ds.closeCh:
+			span.Warnf("reap expired shards stopped, reaped:%d failed:%d", reaped, failed)
+			return
+		default:
+		}
+
+		n, m := ds.reapChunkExpiredShards(ctx, cs)
+		reaped += n
+		failed += m
+	}
+
+	if reaped > 0 || failed > 0 {
+		span.Infof("reap expired shards done, reaped:%d failed:%d", reaped, failed)
+	}
+}
+
+func (ds *DiskStorage) reapChunkExpiredShards(ctx context.Context, cs core.ChunkAPI) (reaped, failed int) {
+	span := trace.SpanFromContextSafe(ctx)
+	now := time.Now().Unix()
+
+	startBid := proto.InValidBlobID
+	for {
+		if err := cs.AllowModify(); err != nil {
+			span.Debugf("skip reaping chunk:%s, err:%v", cs.ID(), err)
+			return
+		}
+
+		infos, next, err := cs.ListShards(ctx, startBid, ds.Conf.CompactBatchSize, bnapi.ShardStatusDefault)
+		if err != nil {
+			span.Errorf("list chunk:%s shards failed: %v", cs.ID(), err)
+			return
+		}
+
+		for _, info := range infos {
+			if info.ExpireAt <= 0 || info.ExpireAt > now {
+				continue
+			}
+			if info.Flag == bnapi.ShardStatusNormal {
+				if err = cs.MarkDelete(ctx, info.Bid); err != nil {
+					span.Errorf("mark delete expired shard chunk:%s bid:%d failed: %v", cs.ID(), info.Bid, err)
+					failed++
+					continue
+				}
+			}
+			if err = cs.Delete(ctx, info.Bid); err != nil {
+				span.Errorf("delete expired shard chunk:%s bid:%d failed: %v", cs.ID(), info.Bid, err)
+				failed++
+				continue
+			}
+			span.Debugf("reaped expired shard chunk:%s bid:%d expire_at:%d", cs.ID(), info.Bid, info.ExpireAt)
+			reaped++
+		}
+
+		if len(infos) == 0 || next == proto.InValidBlobID {
+			return
+		}
+		startBid = next
+	}
+}
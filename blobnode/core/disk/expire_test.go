@@ -0,0 +1,119 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package disk
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/common/proto"
+)
+
+func TestDiskStorage_ReapExpiredShards(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), "DiskStorageReapExpired")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	ctx := context.Background()
+
+	diskpath := filepath.Join(testDir, "DiskPath")
+	err = os.MkdirAll(diskpath, 0o755)
+	require.NoError(t, err)
+
+	diskConfig := core.Config{
+		BaseConfig: core.BaseConfig{
+			Path:       diskpath,
+			AutoFormat: true,
+		},
+		RuntimeConfig: core.RuntimeConfig{
+			CompactBatchSize: 2,
+		},
+		AllocDiskID:      getDiskIDFn,
+		NotifyCompacting: setChunkCompactFn,
+		HandleIOError:    handleIOErrorFn,
+	}
+	ds, err := NewDiskStorage(ctx, diskConfig)
+	require.NoError(t, err)
+	require.NotNil(t, ds)
+	defer ds.ResetChunks(ctx)
+
+	now := time.Now().Unix()
+	expires := map[proto.BlobID]int64{
+		1: 0,
+		2: now - 10,
+		3: now + 3600,
+		4: now - 1,
+		5: now - 100,
+	}
+	shardData := []byte("test data")
+	for _, vuid := range []proto.Vuid{1, 2} {
+		cs, err := ds.CreateChunk(ctx, vuid, core.DefaultChunkSize)
+		require.NoError(t, err)
+		for bid := proto.BlobID(1); bid <= 5; bid++ {
+			shard := &core.Shard{
+				Bid:      bid,
+				Vuid:     vuid,
+				Flag:     bnapi.ShardStatusNormal,
+				Size:     uint32(len(shardData)),
+				Body:     bytes.NewReader(shardData),
+				ExpireAt: expires[bid],
+			}
+			require.NoError(t, cs.Write(ctx, shard))
+		}
+	}
+	// expired shard which was mark deleted
+	cs1, found := ds.GetChunkStorage(1)
+	require.True(t, found)
+	require.NoError(t, cs1.MarkDelete(ctx, 5))
+
+	// readonly chunk is untouched
+	require.NoError(t, ds.UpdateChunkStatus(ctx, 2, bnapi.ChunkStatusReadOnly))
+
+	ds.Lock.RLock()
+	chunks := make([]core.ChunkAPI, 0, len(ds.Chunks))
+	for _, cs := range ds.Chunks {
+		chunks = append(chunks, cs)
+	}
+	ds.Lock.RUnlock()
+	ds.reapExpiredShards(ctx, chunks)
+
+	infos, _, err := cs1.ListShards(ctx, proto.InValidBlobID, 10, bnapi.ShardStatusDefault)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(infos))
+	require.Equal(t, proto.BlobID(1), infos[0].Bid)
+	require.Equal(t, int64(0), infos[0].ExpireAt)
+	require.Equal(t, proto.BlobID(3), infos[1].Bid)
+	require.Equal(t, expires[3], infos[1].ExpireAt)
+
+	cs2, found := ds.GetChunkStorage(2)
+	require.True(t, found)
+	infos, _, err = cs2.ListShards(ctx, proto.InValidBlobID, 10, bnapi.ShardStatusDefault)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(infos))
+
+	// reaping runs on compact interval
+	require.Equal(t, int64(0), ds.nextReapExpired)
+	ds.runCompactFiles()
+	require.True(t, ds.nextReapExpired >= now+ds.Conf.ShardExpireIntervalSec)
+}
@@ -0,0 +1,248 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package disk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	cmapi "github.com/cubefs/blobstore/api/clustermgr"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/blobnode/core/chunk"
+	bloberr "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+const (
+	DefaultNotifyMigratedCnt = 3
+	notifyMigratedInterval   = 10 * time.Second
+)
+
+var ErrNoMigratedNotifier = errors.New("notifier of migrated chunk is not specified")
+
+/*
+ * 1. create a new chunk on dst disk, copy shards with double write
+ * 2. bind vuid to the new chunk on dst, release the old chunk
+ * 3. notify clustermgr the new disk of vuid
+ */
+func (ds *DiskStorage) MigrateChunk(ctx context.Context, vuid proto.Vuid, dst core.DiskAPI) (err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	dstDsw, ok := dst.(*DiskStorageWrapper)
+	if !ok || dstDsw.DiskStorage == ds {
+		return bloberr.ErrInvalidParam
+	}
+	dstDs := dstDsw.DiskStorage
+
+	if ds.Conf.NotifyChunkMigrated == nil {
+		return ErrNoMigratedNotifier
+	}
+
+	if ds.Status() >= proto.DiskStatusBroken || dstDs.Status() != proto.DiskStatusNormal {
+		return bloberr.ErrDiskBroken
+	}
+
+	cs, found := ds.GetChunkStorage(vuid)
+	if !found {
+		if _, found = dstDs.GetChunkStorage(vuid); found {
+			// migrated already, clustermgr may not be notified
+			span.Warnf("vuid:%v has been migrated to disk:%v", vuid, dstDs.DiskID)
+			return ds.notifyMigrated(ctx, vuid, dstDs.DiskID)
+		}
+		return bloberr.ErrNoSuchVuid
+	}
+	if _, found = dstDs.GetChunkStorage(vuid); found {
+		span.Errorf("vuid:%v already exist on disk:%v", vuid, dstDs.DiskID)
+		return bloberr.ErrAlreadyExist
+	}
+	if cs.Status() == bnapi.ChunkStatusRelease {
+		return bloberr.ErrReleaseVUID
+	}
+
+	vm := cs.VuidMeta()
+	if dstDs.isChunksExceeded(ctx, vm.ChunkSize) {
+		return bloberr.ErrTooManyChunks
+	}
+	stats := dstDs.Stats()
+	if dstDs.isMountPoint && stats.Free < int64(cs.ChunkInfo(ctx).Used) {
+		return bloberr.ErrDiskNoSpace
+	}
+
+	now := time.Now().UnixNano()
+	nvm := core.VuidMeta{
+		Version:     vm.Version,
+		Vuid:        vuid,
+		DiskID:      dstDs.DiskID,
+		ChunkId:     bnapi.NewChunkId(vuid),
+		ParentChunk: cs.ID(),
+		ChunkSize:   vm.ChunkSize,
+		Ctime:       now,
+		Mtime:       now,
+		Status:      bnapi.ChunkStatusDefault,
+	}
+
+	ncs, err := chunk.NewChunkStorage(ctx, dstDs.DataPath, nvm, func(option *core.Option) {
+		option.CreateDataIfMiss = true
+		option.DB = dstDs.SuperBlock.db
+		option.Conf = dstDs.Conf
+		option.IoQos = dstDs.dataQos
+		option.Disk = dstDsw
+	})
+	if err != nil {
+		span.Errorf("Failed new chunk:<%s>, err:%v", dstDs.DataPath, err)
+		return err
+	}
+
+	span.Infof("start migrate vuid:%v chunk:%s to disk:%v chunk:%s", vuid, cs.ID(), dstDs.DiskID, ncs.ID())
+
+	// no lock here. copy shards, keep double writing after that
+	if err = cs.StartMigrate(ctx, ncs); err != nil {
+		span.Errorf("Failed start migrate, err:%v", err)
+		return err
+	}
+
+	if err = ds.commitMigrate(ctx, cs, ncs); err != nil {
+		span.Errorf("Failed commit migrate vuid:%v, err:%v", vuid, err)
+		_ = cs.AbortMigrate(ctx, ncs)
+		return err
+	}
+
+	// the new chunk have been serving, stop double writing
+	_ = cs.StopCompact(ctx, ncs)
+
+	// mark destroy old chunk
+	ds.loopAttach(func() {
+		if err := ds.destroyMigrated(ctx, cs); err != nil {
+			span.Errorf("Failed update chunk[%s] status. err:%v", cs.ID(), err)
+		}
+	})
+
+	span.Infof("migrate success. vuid:%v chunk:%s disk:%v", vuid, ncs.ID(), dstDs.DiskID)
+
+	return ds.notifyMigrated(ctx, vuid, dstDs.DiskID)
+}
+
+// commitMigrate rebinds vuid to ncs on dst disk, and removes it from source disk.
+// any status change of vuid is prohibited on both disks.
+func (ds *DiskStorage) commitMigrate(ctx context.Context, cs, ncs core.ChunkAPI) (err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	vuid := cs.Vuid()
+	dstDs := ncs.Disk().(*DiskStorageWrapper).DiskStorage
+
+	// The following logic, for the same vuid, only allows serial execution
+	if ds.ChunkLimitPerKey.Acquire(vuid) != nil {
+		return bloberr.ErrOverload
+	}
+	defer ds.ChunkLimitPerKey.Release(vuid)
+	if dstDs.ChunkLimitPerKey.Acquire(vuid) != nil {
+		return bloberr.ErrOverload
+	}
+	defer dstDs.ChunkLimitPerKey.Release(vuid)
+
+	// released during copying
+	if cur, found := ds.GetChunkStorage(vuid); !found || cur != cs {
+		span.Errorf("vuid:%v chunk:%s has been released", vuid, cs.ID())
+		return bloberr.ErrNoSuchVuid
+	}
+
+	ncsMeta := ncs.VuidMeta()
+	ncsMeta.Status = cs.Status()
+	ncsMeta.Mtime = time.Now().UnixNano()
+
+	err = dstDs.SuperBlock.UpsertChunk(ctx, ncs.ID(), *ncsMeta)
+	if err != nil {
+		span.Errorf("Failed upsert chunk<%s>, err:%v", ncs.ID(), err)
+		return err
+	}
+
+	err = dstDs.SuperBlock.BindVuidChunk(ctx, vuid, ncs.ID())
+	if err != nil {
+		span.Errorf("Failed vuid[%d] bind new chunkfile[%s]", vuid, ncs.ID())
+		_ = dstDs.SuperBlock.DeleteChunk(ctx, ncs.ID())
+		return err
+	}
+
+	err = ds.SuperBlock.UnbindVuidChunk(ctx, vuid, cs.ID())
+	if err != nil {
+		span.Errorf("Failed unbind vuid:%d chunk:%s", vuid, cs.ID())
+		_ = dstDs.SuperBlock.UnbindVuidChunk(ctx, vuid, ncs.ID())
+		_ = dstDs.SuperBlock.DeleteChunk(ctx, ncs.ID())
+		return err
+	}
+
+	ncs.SetStatus(ncsMeta.Status)
+
+	// change memory map. new requests go to dst disk
+	dstDs.Lock.Lock()
+	dstDs.Chunks[vuid] = ncs
+	dstDs.Lock.Unlock()
+
+	ds.Lock.Lock()
+	delete(ds.Chunks, vuid)
+	ds.Lock.Unlock()
+
+	// writes already on the old chunk are still double written, wait for them.
+	// later writes on the old chunk are rejected, rather than lost after stopping double writing
+	cs.Freeze(ctx, bnapi.ChunkStatusRelease)
+
+	return nil
+}
+
+func (ds *DiskStorage) destroyMigrated(ctx context.Context, cs core.ChunkAPI) (err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	// wait old chunk all request done
+	for cs.HasPendingRequest() {
+		span.Debugf("=== wait chunk(%s) all request done ===", cs.ID())
+		time.Sleep(10 * time.Second)
+	}
+
+	// isolated. safe
+	cs.Close(ctx)
+
+	// update chunk status, mark destroy. destroy async
+	vm := cs.VuidMeta()
+	vm.Status = bnapi.ChunkStatusRelease
+	vm.Reason = bnapi.ReleaseForMigrate
+	vm.Compacting = false
+	vm.Mtime = time.Now().UnixNano()
+
+	return ds.SuperBlock.UpsertChunk(ctx, vm.ChunkId, *vm)
+}
+
+func (ds *DiskStorage) notifyMigrated(ctx context.Context, vuid proto.Vuid, diskID proto.DiskID) (err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	args := &cmapi.UpdateVolumeArgs{
+		NewVuid:   vuid,
+		NewDiskID: diskID,
+		OldVuid:   vuid,
+	}
+	for i := 0; i < DefaultNotifyMigratedCnt; i++ {
+		err = ds.Conf.NotifyChunkMigrated(ctx, args)
+		if err == nil {
+			span.Infof("send chunk(%v) migrated to disk(%v) to cm success", vuid, diskID)
+			return
+		}
+		span.Warnf("send chunk(%v) migrated to disk(%v) to cm failed: %v", vuid, diskID, err)
+		time.Sleep(notifyMigratedInterval)
+	}
+
+	return
+}
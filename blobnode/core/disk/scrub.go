@@ -0,0 +1,171 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package disk
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/base"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+const (
+	scrubRepairReason   = "scrub"
+	scrubRetryIntervalS = 60
+)
+
+func (ds *DiskStorage) loopScrub() {
+	span, ctx := trace.StartSpanFromContextWithTraceID(context.Background(), "", "Scrub"+ds.Conf.Path)
+	span.Infof("loop scrub start")
+
+	progress, err := ds.SuperBlock.LoadScrubProgress(ctx)
+	if err != nil && !os.IsNotExist(err) {
+		span.Errorf("Failed load scrub progress, start a new round. err:%v", err)
+		progress = core.ScrubProgress{}
+	}
+	span.Infof("load scrub progress:%+v", progress)
+
+	timer := initTimer(scrubDelay(ds.Conf.ScrubIntervalSec, progress))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ds.closeCh:
+			span.Infof("loop scrub done")
+			return
+		case <-timer.C:
+			if err := ds.scrubRound(&progress); err != nil {
+				span.Errorf("Failed exec scrub. progress:%+v err:%v", progress, err)
+				resetTimer(scrubRetryIntervalS, timer)
+				continue
+			}
+			resetTimer(scrubDelay(ds.Conf.ScrubIntervalSec, progress), timer)
+		}
+	}
+}
+
+// scrubDelay returns seconds to wait before scrubbing,
+// an interrupted round is resumed without waiting.
+func scrubDelay(intervalS int64, progress core.ScrubProgress) int64 {
+	if progress.Vuid != proto.InvalidVuid || progress.FinishTime == 0 {
+		return 0
+	}
+	delay := progress.FinishTime + intervalS - time.Now().Unix()
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// scrubRound scrubs chunks in order of vuid from the progress, reports
+// corrupt shards to be repaired, progress is persisted after every batch.
+// returns if the round finished, disk closed or error occurred.
+func (ds *DiskStorage) scrubRound(progress *core.ScrubProgress) (err error) {
+	span, ctx := trace.StartSpanFromContextWithTraceID(context.Background(), "", base.BackgroudReqID("Scrub"+ds.Conf.Path))
+
+	if ds.Status() >= proto.DiskStatusBroken {
+		span.Warnf("disk(%v) status:%v, skip scrub", ds.DiskID, ds.Status())
+		return nil
+	}
+
+	if progress.Vuid == proto.InvalidVuid {
+		progress.Round++
+		progress.ChunkId = bnapi.InvalidChunkId
+		progress.Bid = proto.InValidBlobID
+		progress.ScrubbedChunks = 0
+		progress.Corrupted = 0
+		progress.StartTime = time.Now().Unix()
+	}
+	span.Infof("scrub round start, progress:%+v", progress)
+
+	ds.Lock.RLock()
+	vuids := make([]proto.Vuid, 0, len(ds.Chunks))
+	for vuid := range ds.Chunks {
+		vuids = append(vuids, vuid)
+	}
+	ds.Lock.RUnlock()
+	sort.Slice(vuids, func(i, j int) bool { return vuids[i] < vuids[j] })
+
+	for _, vuid := range vuids {
+		if vuid < progress.Vuid {
+			continue
+		}
+
+		cs, found := ds.GetChunkStorage(vuid)
+		if !found || cs.Status() == bnapi.ChunkStatusRelease {
+			continue
+		}
+		if vuid != progress.Vuid || cs.ID() != progress.ChunkId {
+			progress.Vuid, progress.ChunkId, progress.Bid = vuid, cs.ID(), proto.InValidBlobID
+		}
+
+		if err = ds.scrubChunk(ctx, cs, progress); err != nil {
+			return err
+		}
+		progress.ScrubbedChunks++
+	}
+
+	progress.Vuid = proto.InvalidVuid
+	progress.ChunkId = bnapi.InvalidChunkId
+	progress.Bid = proto.InValidBlobID
+	progress.FinishTime = time.Now().Unix()
+	if err = ds.SuperBlock.UpsertScrubProgress(ctx, *progress); err != nil {
+		return err
+	}
+
+	span.Infof("scrub round finished, progress:%+v", progress)
+	return nil
+}
+
+func (ds *DiskStorage) scrubChunk(ctx context.Context, cs core.ChunkAPI, progress *core.ScrubProgress) error {
+	span := trace.SpanFromContextSafe(ctx)
+
+	for {
+		select {
+		case <-ds.closeCh:
+			return ErrStopped
+		default:
+		}
+
+		bad, next, err := cs.Scrub(ctx, progress.Bid, ds.Conf.ScrubBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, bid := range bad {
+			span.Warnf("found corrupt shard, chunk:%s bid:%d", cs.ID(), bid)
+			if err = ds.Conf.NotifyShardRepair(ctx, cs.Vuid(), bid, scrubRepairReason); err != nil {
+				span.Errorf("notify repair chunk:%s bid:%d failed: %v", cs.ID(), bid, err)
+				return err
+			}
+			progress.Corrupted++
+		}
+
+		if next == proto.InValidBlobID {
+			return nil
+		}
+
+		progress.Bid = next
+		if err = ds.SuperBlock.UpsertScrubProgress(ctx, *progress); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package disk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/common/proto"
+)
+
+func TestScrubDelay(t *testing.T) {
+	require.Equal(t, int64(0), scrubDelay(100, core.ScrubProgress{}))
+	require.Equal(t, int64(0), scrubDelay(100, core.ScrubProgress{Vuid: 1, FinishTime: time.Now().Unix()}))
+	require.Equal(t, int64(0), scrubDelay(100, core.ScrubProgress{FinishTime: time.Now().Unix() - 200}))
+	delay := scrubDelay(100, core.ScrubProgress{FinishTime: time.Now().Unix() - 10})
+	require.True(t, delay > 80 && delay <= 90)
+}
+
+func TestDiskStorage_Scrub(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), "DiskStorageScrub")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	ctx := context.Background()
+
+	diskpath := filepath.Join(testDir, "DiskPath")
+	err = os.MkdirAll(diskpath, 0o755)
+	require.NoError(t, err)
+
+	type repair struct {
+		vuid proto.Vuid
+		bid  proto.BlobID
+	}
+	var (
+		repairs   []repair
+		repairErr error
+	)
+	diskConfig := core.Config{
+		BaseConfig: core.BaseConfig{
+			Path:       diskpath,
+			AutoFormat: true,
+		},
+		RuntimeConfig: core.RuntimeConfig{
+			ScrubBatchSize: 2,
+		},
+		AllocDiskID:      getDiskIDFn,
+		NotifyCompacting: setChunkCompactFn,
+		HandleIOError:    handleIOErrorFn,
+		NotifyShardRepair: func(ctx context.Context, vuid proto.Vuid, bid proto.BlobID, reason string) error {
+			if repairErr != nil {
+				return repairErr
+			}
+			repairs = append(repairs, repair{vuid: vuid, bid: bid})
+			return nil
+		},
+	}
+	ds, err := NewDiskStorage(ctx, diskConfig)
+	require.NoError(t, err)
+	require.NotNil(t, ds)
+	defer ds.ResetChunks(ctx)
+
+	shardData := []byte("test data")
+	for _, vuid := range []proto.Vuid{1, 2} {
+		cs, err := ds.CreateChunk(ctx, vuid, core.DefaultChunkSize)
+		require.NoError(t, err)
+		for bid := proto.BlobID(1); bid <= 3; bid++ {
+			shard := &core.Shard{
+				Bid:  bid,
+				Vuid: vuid,
+				Flag: bnapi.ShardStatusNormal,
+				Size: uint32(len(shardData)),
+				Body: bytes.NewReader(shardData),
+			}
+			require.NoError(t, cs.Write(ctx, shard))
+		}
+	}
+
+	// all shards are fine
+	progress := core.ScrubProgress{}
+	require.NoError(t, ds.scrubRound(&progress))
+	require.Equal(t, uint64(1), progress.Round)
+	require.Equal(t, uint64(2), progress.ScrubbedChunks)
+	require.Equal(t, uint64(0), progress.Corrupted)
+	require.Equal(t, proto.InvalidVuid, progress.Vuid)
+	require.NotEqual(t, int64(0), progress.FinishTime)
+	require.Equal(t, 0, len(repairs))
+
+	saved, err := ds.SuperBlock.LoadScrubProgress(ctx)
+	require.NoError(t, err)
+	require.Equal(t, progress, saved)
+
+	// corrupt vuid 2 bid 3
+	cs, found := ds.GetChunkStorage(2)
+	require.True(t, found)
+	sm, err := cs.ReadShardMeta(ctx, 3)
+	require.NoError(t, err)
+	f, err := os.OpenFile(filepath.Join(ds.DataPath, cs.ID().String()), os.O_RDWR, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("corrupt"), sm.Offset+core.GetShardHeaderSize()+4)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// failed to notify, progress is kept to be resumed
+	repairErr = errors.New("notify failed")
+	require.Error(t, ds.scrubRound(&progress))
+	require.Equal(t, uint64(2), progress.Round)
+	require.Equal(t, proto.Vuid(2), progress.Vuid)
+	require.Equal(t, cs.ID(), progress.ChunkId)
+	require.Equal(t, proto.BlobID(2), progress.Bid)
+	saved, err = ds.SuperBlock.LoadScrubProgress(ctx)
+	require.NoError(t, err)
+	require.Equal(t, progress, saved)
+
+	// resume from the saved progress
+	repairErr = nil
+	require.NoError(t, ds.scrubRound(&saved))
+	require.Equal(t, uint64(2), saved.Round)
+	require.Equal(t, uint64(2), saved.ScrubbedChunks)
+	require.Equal(t, uint64(1), saved.Corrupted)
+	require.Equal(t, []repair{{vuid: 2, bid: 3}}, repairs)
+}
@@ -68,7 +68,8 @@ const (
 	_chunkSpacePrefix = "chunks"
 	_vuidSpacePrefix  = "vuids"
 
-	_diskmetaKey = "diskinfo"
+	_diskmetaKey      = "diskinfo"
+	_scrubProgressKey = "scrub"
 )
 
 var (
@@ -253,6 +254,28 @@ func (s *SuperBlock) LoadDiskInfo(ctx context.Context) (dm core.DiskMeta, err er
 	return dm, err
 }
 
+func (s *SuperBlock) UpsertScrubProgress(ctx context.Context, progress core.ScrubProgress) (err error) {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+
+	key := []byte(GenDiskKey(_scrubProgressKey))
+
+	return s.writeData(ctx, key, data)
+}
+
+func (s *SuperBlock) LoadScrubProgress(ctx context.Context) (progress core.ScrubProgress, err error) {
+	key := []byte(GenDiskKey(_scrubProgressKey))
+	data, err := s.readData(ctx, key)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &progress)
+	return
+}
+
 func (s *SuperBlock) ListChunks(ctx context.Context) (chunks map[bnapi.ChunkId]core.VuidMeta, err error) {
 	iter := s.db.NewIterator(ctx)
 	defer iter.Close()
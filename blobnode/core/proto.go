@@ -53,6 +53,18 @@ type DiskMeta struct {
 	Mtime      int64            `json:"mtime"`
 }
 
+// scrub progress of disk for rocksdb
+type ScrubProgress struct {
+	Round          uint64        `json:"round"`
+	Vuid           proto.Vuid    `json:"vuid"`            // scrubbing chunk, 0 means idle
+	ChunkId        bnapi.ChunkId `json:"chunkid"`         // scrubbing chunk
+	Bid            proto.BlobID  `json:"bid"`             // the last scrubbed bid of chunk
+	ScrubbedChunks uint64        `json:"scrubbed_chunks"` // scrubbed chunks of this round
+	Corrupted      uint64        `json:"corrupted"`       // corrupt shards of this round
+	StartTime      int64         `json:"start_time"`      // sec
+	FinishTime     int64         `json:"finish_time"`     // sec
+}
+
 type DiskStats struct {
 	Used          int64 `json:"used"`            // actual physical space usage
 	Free          int64 `json:"free"`            // actual remaining physical space on the disk
@@ -72,6 +84,7 @@ type MetaHandler interface {
 	InnerDB() db.MetaHandler
 	SupportInline() bool
 	Write(ctx context.Context, bid proto.BlobID, value ShardMeta) (err error)
+	WriteBatch(ctx context.Context, values map[proto.BlobID]ShardMeta) (err error)
 	Read(ctx context.Context, bid proto.BlobID) (value ShardMeta, err error)
 	Delete(ctx context.Context, bid proto.BlobID) (err error)
 	Scan(ctx context.Context, startBid proto.BlobID, limit int,
@@ -96,6 +109,7 @@ type Storage interface {
 	DataHandler() DataHandler
 	RawStorage() Storage
 	Write(ctx context.Context, b *Shard) (err error)
+	WriteBatch(ctx context.Context, bs []*Shard) (errs []error)
 	ReadShardMeta(ctx context.Context, bid proto.BlobID) (sm *ShardMeta, err error)
 	NewRangeReader(ctx context.Context, b *Shard, from, to int64) (rc io.Reader, err error)
 	MarkDelete(ctx context.Context, bid proto.BlobID) (err error)
@@ -125,12 +139,14 @@ type ChunkAPI interface {
 
 	// method
 	Write(ctx context.Context, b *Shard) (err error)
+	WriteBatch(ctx context.Context, bs []*Shard) (errs []error)
 	Read(ctx context.Context, b *Shard) (n int64, err error)
 	RangeRead(ctx context.Context, b *Shard) (n int64, err error)
 	MarkDelete(ctx context.Context, bid proto.BlobID) (err error)
 	Delete(ctx context.Context, bid proto.BlobID) (err error)
 	ReadShardMeta(ctx context.Context, bid proto.BlobID) (sm *ShardMeta, err error)
 	ListShards(ctx context.Context, startBid proto.BlobID, cnt int, status bnapi.ShardStatus) (infos []*bnapi.ShardInfo, next proto.BlobID, err error)
+	Scrub(ctx context.Context, startBid proto.BlobID, cnt int) (bad []proto.BlobID, next proto.BlobID, err error)
 	Sync(ctx context.Context) (err error)
 	SyncData(ctx context.Context) (err error)
 	Close(ctx context.Context)
@@ -139,6 +155,8 @@ type ChunkAPI interface {
 	StartCompact(ctx context.Context) (ncs ChunkAPI, err error)
 	CommitCompact(ctx context.Context, ncs ChunkAPI) (err error)
 	StopCompact(ctx context.Context, ncs ChunkAPI) (err error)
+	StartMigrate(ctx context.Context, ncs ChunkAPI) (err error)
+	AbortMigrate(ctx context.Context, ncs ChunkAPI) (err error)
 	NeedCompact(ctx context.Context) bool
 	IsDirty() bool
 	IsClosed() bool
@@ -146,6 +164,7 @@ type ChunkAPI interface {
 	HasEnoughSpace(needSize int64) bool
 	HasPendingRequest() bool
 	SetStatus(status bnapi.ChunkStatus) (err error)
+	Freeze(ctx context.Context, status bnapi.ChunkStatus)
 	SetDirty(dirty bool)
 }
 
@@ -168,6 +187,7 @@ type DiskAPI interface {
 	UpdateChunkCompactState(ctx context.Context, vuid proto.Vuid, compacting bool) (err error)
 	ListChunks(ctx context.Context) (chunks []VuidMeta, err error)
 	EnqueueCompact(ctx context.Context, vuid proto.Vuid)
+	MigrateChunk(ctx context.Context, vuid proto.Vuid, dst DiskAPI) (err error)
 	GcRubbishChunk(ctx context.Context) (mayBeLost []bnapi.ChunkId, err error)
 	WalkChunksWithLock(ctx context.Context, fn func(cs ChunkAPI) error) (err error)
 	ResetChunks(ctx context.Context)
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io"
+	"time"
 
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
 	"github.com/cubefs/blobstore/common/crc32block"
@@ -126,14 +127,19 @@ type ShardKey struct {
 
 // meta db value
 type ShardMeta struct {
-	Version uint8
-	Flag    bnapi.ShardStatus
-	Offset  int64
-	Size    uint32
-	Crc     uint32
-	Padding [8]byte
-	Inline  bool
-	Buffer  []byte
+	Version  uint8
+	Flag     bnapi.ShardStatus
+	Offset   int64
+	Size     uint32
+	Crc      uint32
+	ExpireAt int64 // unix seconds, 0 means never expire
+	Inline   bool
+	Buffer   []byte
+}
+
+// Expired returns true if the shard is expired at now
+func (sm *ShardMeta) Expired(now time.Time) bool {
+	return sm.ExpireAt > 0 && sm.ExpireAt <= now.Unix()
 }
 
 // Blob Shard in memory
@@ -146,6 +152,8 @@ type Shard struct {
 	Crc    uint32            // crc for shard data
 	Flag   bnapi.ShardStatus // shard status
 
+	ExpireAt int64 // expiry of shard in unix seconds, 0 means never expire
+
 	Inline bool   // shard data inline
 	Buffer []byte // inline data
 
@@ -178,7 +186,7 @@ func (sm *ShardMeta) Marshal() ([]byte, error) {
 	binary.LittleEndian.PutUint32(buf[16:20], uint32(sm.Size))
 	binary.LittleEndian.PutUint32(buf[20:24], uint32(sm.Crc))
 
-	copy(buf[24:32], sm.Padding[:])
+	binary.LittleEndian.PutUint64(buf[24:32], uint64(sm.ExpireAt))
 
 	if sm.Inline && sm.Buffer != nil {
 		copy(buf[32:32+sm.Size], sm.Buffer)
@@ -199,7 +207,7 @@ func (sm *ShardMeta) Unmarshal(data []byte) error {
 	sm.Size = binary.LittleEndian.Uint32(data[16:20])
 	sm.Crc = binary.LittleEndian.Uint32(data[20:24])
 
-	copy(sm.Padding[:], data[24:32])
+	sm.ExpireAt = int64(binary.LittleEndian.Uint64(data[24:32]))
 
 	sm.Inline = sm.Flag&bnapi.ShardDataInline != 0
 	if sm.Inline {
@@ -304,6 +312,7 @@ func (b *Shard) FillMeta(meta ShardMeta) {
 	b.Size = meta.Size
 	b.Crc = meta.Crc
 	b.Flag = meta.Flag
+	b.ExpireAt = meta.ExpireAt
 
 	b.Inline = meta.Inline
 	b.Buffer = meta.Buffer
@@ -350,6 +359,7 @@ func ShardCopy(src *Shard) (dest *Shard) {
 	dest.Offset = src.Offset
 	dest.Crc = src.Crc
 	dest.Flag = src.Flag
+	dest.ExpireAt = src.ExpireAt
 
 	dest.Body = src.Body
 	dest.From, dest.To = src.From, src.To
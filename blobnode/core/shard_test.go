@@ -81,14 +81,15 @@ func TestShard_writeFooter(t *testing.T) {
 
 func TestShardCopy(t *testing.T) {
 	s1 := Shard{
-		Bid:    1,
-		Vuid:   2,
-		Size:   3,
-		Offset: 4,
-		Crc:    5,
-		Flag:   blobnode.ShardStatusNormal,
-		From:   0,
-		To:     3,
+		Bid:      1,
+		Vuid:     2,
+		Size:     3,
+		Offset:   4,
+		Crc:      5,
+		Flag:     blobnode.ShardStatusNormal,
+		From:     0,
+		To:       3,
+		ExpireAt: 6,
 	}
 
 	s2 := ShardCopy(&s1)
@@ -101,17 +102,19 @@ func TestShardCopy(t *testing.T) {
 	require.Equal(t, s1.Flag, s2.Flag)
 	require.Equal(t, s1.From, s2.From)
 	require.Equal(t, s1.To, s2.To)
+	require.Equal(t, s1.ExpireAt, s2.ExpireAt)
 	require.Equal(t, s1.Body, s2.Body)
 	require.Equal(t, s1.Writer, s2.Writer)
 }
 
 func TestShardMeta_Marshal(t *testing.T) {
 	sm := &ShardMeta{
-		Version: 0x1,
-		Flag:    1,
-		Offset:  1024,
-		Size:    2048,
-		Crc:     4096,
+		Version:  0x1,
+		Flag:     1,
+		Offset:   1024,
+		Size:     2048,
+		Crc:      4096,
+		ExpireAt: 1652345678,
 	}
 
 	require.Equal(t, int(unsafe.Sizeof(ShardMeta{})) >= _ShardMetaSize, true)
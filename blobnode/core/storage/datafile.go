@@ -125,15 +125,19 @@ func (hdr *ChunkHeader) Marshal() ([]byte, error) {
 }
 
 func (hdr *ChunkHeader) Unmarshal(data []byte) error {
+	return hdr.unmarshal(data, chunkHeaderMagic)
+}
+
+func (hdr *ChunkHeader) unmarshal(data []byte, expectMagic [_chunkMagicSize]byte) error {
 	if len(data) != _chunkHeaderSize {
 		panic(ErrChunkHeaderBufSize)
 	}
 
 	magic := data[_chunkMagicOffset : _chunkMagicOffset+_chunkMagicSize]
-	if !bytes.Equal(magic, chunkHeaderMagic[:]) {
+	if !bytes.Equal(magic, expectMagic[:]) {
 		return ErrChunkDataMagic
 	}
-	hdr.magic = chunkHeaderMagic
+	hdr.magic = expectMagic
 	hdr.version = data[_chunkVerOffset : _chunkVerOffset+_chunkVerSize][0]
 	copy(hdr.parentChunk[:], data[_chunkParentChunkOffset:_chunkParentChunkOffset+_chunkParentChunkSize])
 	hdr.createTime = int64(binary.BigEndian.Uint64(data[_chunkCreateTimeOffset : _chunkCreateTimeOffset+_chunkCreateTimeSize]))
@@ -302,11 +306,7 @@ func (cd *datafile) allocSpace(fsize int64) (pos int64, err error) {
 func (cd *datafile) Write(ctx context.Context, shard *core.Shard) error {
 	span := trace.SpanFromContextSafe(ctx)
 
-	var (
-		w      *bncomm.Writer
-		buffer []byte
-		start  time.Time
-	)
+	var start time.Time
 
 	phySize := core.Alignphysize(int64(shard.Size))
 
@@ -321,7 +321,7 @@ func (cd *datafile) Write(ctx context.Context, shard *core.Shard) error {
 	headerbuf := make([]byte, core.GetShardHeaderSize())
 	footerbuf := make([]byte, core.GetShardFooterSize())
 
-	qoswAt := cd.qosWriterAt(ctx, cd.ef)
+	qoswAt := qosWriterAt(ctx, cd.ioQos, cd.ef)
 
 	// header
 	err = shard.WriterHeader(headerbuf)
@@ -338,25 +338,53 @@ func (cd *datafile) Write(ctx context.Context, shard *core.Shard) error {
 
 	pos += core.GetShardHeaderSize()
 
-	w = &bncomm.Writer{WriterAt: cd.ef, Offset: pos}
+	buffer := cd.pool.Get().([]byte)
+	defer cd.pool.Put(buffer) // nolint: staticcheck
+
+	// write shard body
+	pos, err = writeShardBody(ctx, cd.ef, cd.ioQos, buffer, shard, pos)
+	if err != nil {
+		return err
+	}
+
+	// write footer
+	err = shard.WriterFooter(footerbuf)
+	if err != nil {
+		return err
+	}
+
+	start = time.Now()
+
+	_, err = qoswAt.WriteAt(footerbuf, pos)
+	span.AppendTrackLog("fo.w", start, err)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeShardBody encodes body of shard with crc block at pos,
+// crc of shard is filled, returns the end offset of body.
+func writeShardBody(ctx context.Context, ef core.BlobFile, ioQos qos.Qos, buffer []byte, shard *core.Shard, pos int64) (
+	end int64, err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	w := &bncomm.Writer{WriterAt: ef, Offset: pos}
 	twRaw := bncomm.NewTimeWriter(w)
 
-	qosw := cd.qosWriter(ctx, twRaw)
+	qosw := qosWriter(ctx, ioQos, twRaw)
 
 	crc := crc32.NewIEEE()
 	body := io.LimitReader(shard.Body, int64(shard.Size))
 	body = io.TeeReader(body, crc)
 
-	buffer = cd.pool.Get().([]byte)
-	defer cd.pool.Put(buffer) // nolint: staticcheck
-
 	tw := bncomm.NewTimeWriter(qosw)
 	tr := bncomm.NewTimeReader(body)
 
-	// write shard body
 	encoder, err := crc32block.NewEncoder(buffer)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	_, err = encoder.Encode(tr, int64(shard.Size), tw)
@@ -368,27 +396,12 @@ func (cd *datafile) Write(ctx context.Context, shard *core.Shard) error {
 		if _, ok := err.(crc32block.ReaderError); ok {
 			err = bloberr.ErrReaderError
 		}
-		return err
+		return 0, err
 	}
 
 	shard.Crc = crc.Sum32()
 
-	// write footer
-	err = shard.WriterFooter(footerbuf)
-	if err != nil {
-		return err
-	}
-
-	pos = w.Offset
-	start = time.Now()
-
-	_, err = qoswAt.WriteAt(footerbuf, pos)
-	span.AppendTrackLog("fo.w", start, err)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return w.Offset, nil
 }
 
 func (cd *datafile) Read(ctx context.Context, shard *core.Shard, from, to uint32) (r io.Reader, err error) {
@@ -396,6 +409,15 @@ func (cd *datafile) Read(ctx context.Context, shard *core.Shard, from, to uint32
 		return nil, bloberr.ErrInvalidParam
 	}
 
+	// skip header
+	pos := shard.Offset + core.GetShardHeaderSize()
+
+	return readShardBody(ctx, cd.ef, cd.ioQos, shard, pos, from, to)
+}
+
+// readShardBody returns reader of range body at pos, crc block is verified
+func readShardBody(ctx context.Context, ef core.BlobFile, ioQos qos.Qos, shard *core.Shard, pos int64, from, to uint32) (
+	r io.Reader, err error) {
 	//   from                          to
 	//    |                            |
 	// |---------------------------------------------------|
@@ -404,11 +426,8 @@ func (cd *datafile) Read(ctx context.Context, shard *core.Shard, from, to uint32
 		return nil, bloberr.ErrInvalidParam
 	}
 
-	// skip header
-	pos := shard.Offset + core.GetShardHeaderSize()
-
 	// new reader
-	iosr := cd.qosReaderAt(ctx, cd.ef)
+	iosr := qosReaderAt(ctx, ioQos, ef)
 
 	// new buffer
 	block := make([]byte, core.CrcBlockUnitSize)
@@ -505,18 +524,18 @@ func (cd *datafile) spaceInfo() (size int64, phySpace int64, err error) {
 	return
 }
 
-func (cd *datafile) qosReaderAt(ctx context.Context, reader io.ReaderAt) io.ReaderAt {
+func qosReaderAt(ctx context.Context, ioQos qos.Qos, reader io.ReaderAt) io.ReaderAt {
 	ioType := bnapi.Getiotype(ctx)
-	return cd.ioQos.ReaderAt(ctx, ioType, reader)
+	return ioQos.ReaderAt(ctx, ioType, reader)
 }
 
-func (cd *datafile) qosWriterAt(ctx context.Context, writer io.WriterAt) io.WriterAt {
+func qosWriterAt(ctx context.Context, ioQos qos.Qos, writer io.WriterAt) io.WriterAt {
 	ioType := bnapi.Getiotype(ctx)
-	w := cd.ioQos.WriterAt(ctx, ioType, writer)
+	w := ioQos.WriterAt(ctx, ioType, writer)
 	return w
 }
 
-func (cd *datafile) qosWriter(ctx context.Context, writer io.Writer) io.Writer {
+func qosWriter(ctx context.Context, ioQos qos.Qos, writer io.Writer) io.Writer {
 	ioType := bnapi.Getiotype(ctx)
-	return cd.ioQos.Writer(ctx, ioType, writer)
+	return ioQos.Writer(ctx, ioType, writer)
 }
@@ -190,6 +190,24 @@ func (cm *metafile) Write(ctx context.Context, bid proto.BlobID, value core.Shar
 	return cm.writeData(ctx, key, valBytes)
 }
 
+// WriteBatch writes metas of multiple shards atomically
+func (cm *metafile) WriteBatch(ctx context.Context, values map[proto.BlobID]core.ShardMeta) (err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	kvs := make([]rdb.KV, 0, len(values))
+	for bid, value := range values {
+		key := GenShardKey(&core.ShardKey{Chunk: cm.id, Bid: bid})
+		valBytes, _ := value.Marshal()
+		kvs = append(kvs, rdb.KV{Key: key, Value: valBytes})
+	}
+
+	start := time.Now()
+
+	err = cm.db.PutBatch(ctx, kvs)
+	span.AppendTrackLog("md.wb", start, err)
+	return err
+}
+
 func (cm *metafile) Read(ctx context.Context, bid proto.BlobID) (value core.ShardMeta, err error) {
 	id := core.ShardKey{
 		Chunk: cm.id,
@@ -0,0 +1,482 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cubefs/blobstore/blobnode/base/qos"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/common/crc32block"
+	bloberr "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/util/log"
+)
+
+// Segfile packs shards into append-only segments, it has a header (64k).
+// Segfile header format:
+//  --------------
+// | magic number |   ---- 4 bytes
+// | version      |   ---- 1 byte
+// | parent chunk |   ---- 16 byte
+// | create time  |   ---- 8 byte
+// | segment size |   ---- 8 byte
+// | padding      |   ---- aligned with 4k
+// | garbage      |   ---- 8 bytes per segment, deleted bytes of segment
+//  --------------
+// |   segment    |   ---- records are packed without alignment,
+// |   segment    |        only record larger than segment crosses segments
+// |    ....      |
+//
+// Record format:
+//  --------------
+// | bid          |   ---- 8 bytes
+// | size         |   ---- 4 bytes
+// | crc          |   ---- 4 bytes
+// | body         |   ---- crc block encoded
+//  --------------
+
+const (
+	_segHeaderSize       = 64 * 1024
+	_segSizeOffset       = _chunkCreateTimeOffset + _chunkCreateTimeSize
+	_segGarbageOffset    = _pagesize
+	_segGarbageSize      = 8
+	_segMaxSegments      = (_segHeaderSize - _segGarbageOffset) / _segGarbageSize
+	_segRecordHeaderSize = 16
+	_segRecordBidOffset  = 0
+	_segRecordSizeOffset = _segRecordBidOffset + 8
+	_segRecordCrcOffset  = _segRecordSizeOffset + 4
+	_segMinSegmentSize   = _pagesize
+)
+
+var segHeaderMagic = [_chunkMagicSize]byte{0x20, 0x22, 0x05, 0x26}
+
+var ErrSegmentsExceeded = errors.New("segfile: segments exceeded")
+
+type segfile struct {
+	ef   core.BlobFile
+	pool sync.Pool
+
+	// protect wOff and garbage of segments
+	lock    sync.Mutex
+	wOff    int64
+	garbage []int64
+
+	File    string
+	header  ChunkHeader
+	segSize int64
+	conf    *core.Config
+
+	ioQos  qos.Qos
+	closed bool
+}
+
+// NewDataHandler opens chunk data with the engine it was created by,
+// a new chunk data is created with the engine of config.
+func NewDataHandler(ctx context.Context, vm core.VuidMeta, file string, conf *core.Config, createIfMiss bool, ioQos qos.Qos) (
+	cd core.DataHandler, err error) {
+	if conf == nil {
+		return nil, bloberr.ErrInvalidParam
+	}
+
+	engine, err := detectDataEngine(file, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if engine == core.DataEngineSegment {
+		return NewSegmentData(ctx, vm, file, conf, createIfMiss, ioQos)
+	}
+	return NewChunkData(ctx, vm, file, conf, createIfMiss, ioQos)
+}
+
+func detectDataEngine(file string, conf *core.Config) (engine string, err error) {
+	engine = conf.DataEngine
+
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return engine, nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	var magic [_chunkMagicSize]byte
+	n, err := f.ReadAt(magic[:], _chunkMagicOffset)
+	if n == 0 && err == io.EOF {
+		// empty file, not formatted
+		return engine, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch magic {
+	case chunkHeaderMagic:
+		return core.DataEngineFile, nil
+	case segHeaderMagic:
+		return core.DataEngineSegment, nil
+	default:
+		return "", ErrChunkDataMagic
+	}
+}
+
+func NewSegmentData(ctx context.Context, vm core.VuidMeta, file string, conf *core.Config, createIfMiss bool, ioQos qos.Qos) (
+	sf *segfile, err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	if file == "" || conf == nil {
+		span.Errorf("file:%s, conf:%v, create:%v", file, conf, createIfMiss)
+		return nil, bloberr.ErrInvalidParam
+	}
+
+	fd, err := core.OpenFile(file, createIfMiss)
+	if err != nil {
+		err = fmt.Errorf("os.OpenFile(\"%s\") error(%v)", file, err)
+		return nil, err
+	}
+
+	handleIOError := func(err error) {
+		conf.HandleIOError(context.Background(), vm.DiskID, err)
+	}
+
+	sf = &segfile{
+		File:   file,
+		conf:   conf,
+		closed: false,
+		ef:     core.NewBlobFile(fd, handleIOError),
+		ioQos:  ioQos,
+		pool: sync.Pool{
+			New: func() interface{} {
+				return make([]byte, bufsize)
+			},
+		},
+	}
+
+	if err = sf.init(&vm); err != nil {
+		err = fmt.Errorf("segment: %s init() error(%v)", file, err)
+		sf.Close()
+		return nil, err
+	}
+
+	return sf, nil
+}
+
+func (sf *segfile) init(meta *core.VuidMeta) (err error) {
+	var sysstat syscall.Stat_t
+
+	if sysstat, err = sf.ef.SysStat(); err != nil {
+		return
+	}
+
+	if sysstat.Size == 0 {
+		// first time. auto format
+		sf.header = ChunkHeader{
+			magic:       segHeaderMagic,
+			version:     meta.Version,
+			parentChunk: meta.ParentChunk,
+			createTime:  meta.Ctime,
+		}
+		sf.segSize = core.AlignSize(sf.conf.SegmentSizeB, _pagesize)
+		if sf.segSize < _segMinSegmentSize {
+			sf.segSize = core.AlignSize(core.DefaultSegmentSizeB, _pagesize)
+		}
+		sf.garbage = make([]int64, _segMaxSegments)
+		if err = sf.writeMeta(); err != nil {
+			return
+		}
+		sf.wOff = _segHeaderSize
+		return
+	}
+
+	if err = sf.parseMeta(); err != nil {
+		return
+	}
+	// the file size is the end of written records
+	sf.wOff = sysstat.Size
+	if sf.wOff < _segHeaderSize {
+		sf.wOff = _segHeaderSize
+	}
+
+	return
+}
+
+func (sf *segfile) writeMeta() (err error) {
+	if err = sf.ef.Allocate(0, _segHeaderSize); err != nil {
+		return
+	}
+
+	buf := make([]byte, _segHeaderSize)
+	hdr, _ := sf.header.Marshal()
+	copy(buf, hdr)
+	binary.BigEndian.PutUint64(buf[_segSizeOffset:], uint64(sf.segSize))
+
+	if _, err = sf.ef.WriteAt(buf, _chunkMagicOffset); err != nil {
+		return
+	}
+
+	return sf.ef.Sync()
+}
+
+func (sf *segfile) parseMeta() (err error) {
+	buf := make([]byte, _segHeaderSize)
+	if _, err = sf.ef.ReadAt(buf, 0); err != nil {
+		return
+	}
+
+	if err = sf.header.unmarshal(buf[:_chunkHeaderSize], segHeaderMagic); err != nil {
+		return
+	}
+	sf.segSize = int64(binary.BigEndian.Uint64(buf[_segSizeOffset:]))
+	if sf.segSize < _segMinSegmentSize {
+		return ErrChunkDataMagic
+	}
+
+	sf.garbage = make([]int64, _segMaxSegments)
+	for i := range sf.garbage {
+		off := _segGarbageOffset + i*_segGarbageSize
+		sf.garbage[i] = int64(binary.BigEndian.Uint64(buf[off : off+_segGarbageSize]))
+	}
+
+	return nil
+}
+
+func (sf *segfile) segmentOf(off int64) int {
+	return int((off - _segHeaderSize) / sf.segSize)
+}
+
+func (sf *segfile) segmentStart(seg int) int64 {
+	return _segHeaderSize + int64(seg)*sf.segSize
+}
+
+func recordSize(shardSize uint32) int64 {
+	return _segRecordHeaderSize + crc32block.EncodeSize(int64(shardSize), core.CrcBlockUnitSize)
+}
+
+// allocSpace appends record to the current segment, seals it if no enough space.
+func (sf *segfile) allocSpace(size int64) (pos int64, err error) {
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+
+	seg := sf.segmentOf(sf.wOff)
+	segStart, segEnd := sf.segmentStart(seg), sf.segmentStart(seg+1)
+	if sf.wOff+size > segEnd && sf.wOff > segStart {
+		// extends file to the end first, then the tail is never reused after restart
+		if _, err = sf.ef.WriteAt([]byte{0}, segEnd-1); err != nil {
+			return
+		}
+		if err = sf.addGarbageLocked(sf.wOff, segEnd-sf.wOff); err != nil {
+			return
+		}
+		sf.wOff = segEnd
+	}
+
+	if sf.segmentOf(sf.wOff+size-1) >= len(sf.garbage) {
+		return 0, ErrSegmentsExceeded
+	}
+
+	pos = sf.wOff
+	sf.wOff += size
+
+	return pos, nil
+}
+
+// addGarbageLocked accounts deleted range to its segments,
+// sealed segment is discarded if all of it is garbage.
+func (sf *segfile) addGarbageLocked(off, size int64) (err error) {
+	for size > 0 {
+		seg := sf.segmentOf(off)
+		segEnd := sf.segmentStart(seg + 1)
+		n := segEnd - off
+		if n > size {
+			n = size
+		}
+
+		sf.garbage[seg] += n
+		buf := make([]byte, _segGarbageSize)
+		binary.BigEndian.PutUint64(buf, uint64(sf.garbage[seg]))
+		if _, err = sf.ef.WriteAt(buf, int64(_segGarbageOffset+seg*_segGarbageSize)); err != nil {
+			return
+		}
+
+		if sf.garbage[seg] >= sf.segSize && segEnd <= sf.wOff {
+			if err = sf.ef.Discard(sf.segmentStart(seg), sf.segSize); err != nil {
+				return
+			}
+		}
+
+		off += n
+		size -= n
+	}
+	return nil
+}
+
+func (sf *segfile) Write(ctx context.Context, shard *core.Shard) error {
+	span := trace.SpanFromContextSafe(ctx)
+
+	pos, err := sf.allocSpace(recordSize(shard.Size))
+	if err != nil {
+		return err
+	}
+
+	shard.Offset = pos
+
+	buffer := sf.pool.Get().([]byte)
+	defer sf.pool.Put(buffer) // nolint: staticcheck
+
+	// write shard body
+	_, err = writeShardBody(ctx, sf.ef, sf.ioQos, buffer, shard, pos+_segRecordHeaderSize)
+	if err != nil {
+		return err
+	}
+
+	// write record header, with crc of body
+	headerbuf := make([]byte, _segRecordHeaderSize)
+	binary.BigEndian.PutUint64(headerbuf[_segRecordBidOffset:], uint64(shard.Bid))
+	binary.BigEndian.PutUint32(headerbuf[_segRecordSizeOffset:], shard.Size)
+	binary.BigEndian.PutUint32(headerbuf[_segRecordCrcOffset:], shard.Crc)
+
+	start := time.Now()
+	_, err = qosWriterAt(ctx, sf.ioQos, sf.ef).WriteAt(headerbuf, pos)
+	span.AppendTrackLog("hdr.w", start, err)
+
+	return err
+}
+
+func (sf *segfile) Read(ctx context.Context, shard *core.Shard, from, to uint32) (r io.Reader, err error) {
+	if shard == nil {
+		return nil, bloberr.ErrInvalidParam
+	}
+	if shard.Offset < _segHeaderSize {
+		return nil, bloberr.ErrShardInvalidOffset
+	}
+
+	// skip record header
+	pos := shard.Offset + _segRecordHeaderSize
+
+	return readShardBody(ctx, sf.ef, sf.ioQos, shard, pos, from, to)
+}
+
+func (sf *segfile) Delete(ctx context.Context, shard *core.Shard) (err error) {
+	if shard.Offset < _segHeaderSize {
+		return bloberr.ErrShardInvalidOffset
+	}
+
+	// read record header
+	buf := make([]byte, _segRecordHeaderSize)
+	if _, err = sf.ef.ReadAt(buf, shard.Offset); err != nil {
+		return err
+	}
+
+	// verify
+	bid := proto.BlobID(binary.BigEndian.Uint64(buf[_segRecordBidOffset:]))
+	size := binary.BigEndian.Uint32(buf[_segRecordSizeOffset:])
+	if shard.Bid != bid || shard.Size != size {
+		return ErrShardHeaderNotMatch
+	}
+
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+
+	// punch hole, pages shared with other records are zeroed only in range
+	recSize := recordSize(shard.Size)
+	if err = sf.ef.Discard(shard.Offset, recSize); err != nil {
+		return err
+	}
+
+	return sf.addGarbageLocked(shard.Offset, recSize)
+}
+
+func (sf *segfile) Flush() (err error) {
+	if sf.conf.DisableSync {
+		return
+	}
+
+	return sf.ef.Sync()
+}
+
+func (sf *segfile) Close() {
+	if sf.ef == nil {
+		sf.closed = true
+		return
+	}
+
+	if err := sf.Flush(); err != nil {
+		log.Errorf("flush err(%v)", err)
+	}
+
+	if err := sf.ef.Close(); err != nil {
+		log.Errorf("close err(%v)", err)
+	}
+
+	sf.ef = nil
+	sf.closed = true
+}
+
+func (sf *segfile) Destroy(ctx context.Context) (err error) {
+	log.Warnf("destroy segment data: %s", sf.ef.Name())
+	return os.Remove(sf.File)
+}
+
+// Stat returns live bytes as phy size, garbage of segments is the empty space to be compacted
+func (sf *segfile) Stat() (stat *core.StorageStat, err error) {
+	sysstat, err := sf.ef.SysStat()
+	if err != nil {
+		log.Errorf("get segfile sysstat_t failed: %v", err)
+		return nil, err
+	}
+
+	sf.lock.Lock()
+	var garbage int64
+	for _, n := range sf.garbage {
+		garbage += n
+	}
+	sf.lock.Unlock()
+
+	phySize := sysstat.Size - garbage
+	if phySize < 0 {
+		phySize = 0
+	}
+
+	stat = &core.StorageStat{
+		FileSize:   sysstat.Size,
+		PhySize:    phySize,
+		ParentID:   sf.header.parentChunk,
+		CreateTime: sf.header.createTime,
+	}
+
+	return stat, nil
+}
+
+func (sf *segfile) String() string {
+	return fmt.Sprintf(`
+-----------------------------
+wOff:           %d
+File:           %s
+Ver:            %d
+SegmentSize:    %d
+-----------------------------
+`, sf.wOff, sf.ef.Name(), sf.header.version, sf.segSize)
+}
@@ -0,0 +1,281 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/base/qos"
+	"github.com/cubefs/blobstore/blobnode/core"
+	"github.com/cubefs/blobstore/common/proto"
+)
+
+func newTestSegShard(bid proto.BlobID, data []byte) *core.Shard {
+	return &core.Shard{
+		Bid:  bid,
+		Vuid: 10,
+		Flag: bnapi.ShardStatusNormal,
+		Size: uint32(len(data)),
+		Body: bytes.NewReader(data),
+	}
+}
+
+func readTestSegShard(t *testing.T, sf *segfile, shard *core.Shard, from, to uint32) []byte {
+	r, err := sf.Read(context.Background(), shard, from, to)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	return data
+}
+
+func TestSegfile_WriteRead(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), defaultDiskTestDir+"SegfileWriteRead")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	ctx := context.Background()
+	chunkname := filepath.Join(testDir, bnapi.NewChunkId(0).String())
+
+	_, err = NewSegmentData(ctx, core.VuidMeta{}, "", nil, false, nil)
+	require.Error(t, err)
+
+	diskConfig := &core.Config{
+		BaseConfig: core.BaseConfig{Path: testDir, SegmentSizeB: 4096},
+	}
+	ioQos, _ := qos.NewQosManager(qos.Config{})
+	sf, err := NewSegmentData(ctx, core.VuidMeta{}, chunkname, diskConfig, true, ioQos)
+	require.NoError(t, err)
+	defer sf.Close()
+
+	require.Equal(t, int64(_segHeaderSize), sf.wOff)
+	require.Equal(t, int64(4096), sf.segSize)
+
+	_, err = sf.Read(ctx, nil, 0, 1)
+	require.Error(t, err)
+	_, err = sf.Read(ctx, &core.Shard{}, 0, 1)
+	require.Error(t, err)
+
+	data := []byte("test segment data")
+	shard := newTestSegShard(1, data)
+	err = sf.Write(ctx, shard)
+	require.NoError(t, err)
+	require.Equal(t, int64(_segHeaderSize), shard.Offset)
+	require.Equal(t, shard.Offset+recordSize(shard.Size), sf.wOff)
+
+	require.Equal(t, data, readTestSegShard(t, sf, shard, 0, shard.Size))
+	require.Equal(t, data[5:12], readTestSegShard(t, sf, shard, 5, 12))
+
+	// small shards are packed without alignment
+	shard2 := newTestSegShard(2, data)
+	err = sf.Write(ctx, shard2)
+	require.NoError(t, err)
+	require.Equal(t, shard.Offset+recordSize(shard.Size), shard2.Offset)
+	require.Equal(t, data, readTestSegShard(t, sf, shard2, 0, shard2.Size))
+
+	// no enough space in the first segment, seal it
+	bigData := bytes.Repeat([]byte("a"), 4000)
+	shard3 := newTestSegShard(3, bigData)
+	err = sf.Write(ctx, shard3)
+	require.NoError(t, err)
+	require.Equal(t, int64(_segHeaderSize+4096), shard3.Offset)
+	require.Equal(t, 4096-2*recordSize(uint32(len(data))), sf.garbage[0])
+
+	// larger than segment, crosses segments
+	hugeData := bytes.Repeat([]byte("b"), 10000)
+	shard4 := newTestSegShard(4, hugeData)
+	err = sf.Write(ctx, shard4)
+	require.NoError(t, err)
+	require.Equal(t, int64(_segHeaderSize+2*4096), shard4.Offset)
+	require.Equal(t, hugeData, readTestSegShard(t, sf, shard4, 0, shard4.Size))
+	require.Equal(t, bigData, readTestSegShard(t, sf, shard3, 0, shard3.Size))
+
+	stat, err := sf.Stat()
+	require.NoError(t, err)
+	require.Equal(t, sf.wOff, stat.FileSize)
+}
+
+func TestSegfile_Delete(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), defaultDiskTestDir+"SegfileDelete")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	ctx := context.Background()
+	chunkname := filepath.Join(testDir, bnapi.NewChunkId(0).String())
+
+	diskConfig := &core.Config{
+		BaseConfig: core.BaseConfig{Path: testDir, SegmentSizeB: 4096},
+	}
+	ioQos, _ := qos.NewQosManager(qos.Config{})
+	sf, err := NewSegmentData(ctx, core.VuidMeta{}, chunkname, diskConfig, true, ioQos)
+	require.NoError(t, err)
+	defer sf.Close()
+
+	data := bytes.Repeat([]byte("c"), 1000)
+	shards := make([]*core.Shard, 0)
+	for i := 1; i <= 6; i++ {
+		shard := newTestSegShard(proto.BlobID(i), data)
+		require.NoError(t, sf.Write(ctx, shard))
+		shards = append(shards, shard)
+	}
+	recSize := recordSize(uint32(len(data)))
+	// 4 records a segment
+	require.Equal(t, int64(_segHeaderSize+4096), shards[4].Offset)
+	require.Equal(t, 4096-4*recSize, sf.garbage[0])
+
+	err = sf.Delete(ctx, &core.Shard{Offset: _segHeaderSize - 1})
+	require.Error(t, err)
+
+	// mismatch
+	err = sf.Delete(ctx, &core.Shard{Bid: 100, Size: shards[0].Size, Offset: shards[0].Offset})
+	require.Equal(t, ErrShardHeaderNotMatch, err)
+
+	before, err := sf.Stat()
+	require.NoError(t, err)
+
+	require.NoError(t, sf.Delete(ctx, shards[0]))
+	require.Equal(t, 4096-3*recSize, sf.garbage[0])
+
+	stat, err := sf.Stat()
+	require.NoError(t, err)
+	require.Equal(t, before.PhySize-recSize, stat.PhySize)
+
+	// deleted record can not be deleted twice
+	err = sf.Delete(ctx, shards[0])
+	require.Error(t, err)
+	require.Equal(t, data, readTestSegShard(t, sf, shards[1], 0, shards[1].Size))
+
+	// whole sealed segment is dead
+	for _, shard := range shards[1:4] {
+		require.NoError(t, sf.Delete(ctx, shard))
+	}
+	require.Equal(t, int64(4096), sf.garbage[0])
+	require.Equal(t, data, readTestSegShard(t, sf, shards[4], 0, shards[4].Size))
+
+	// garbage is persisted
+	wOff := sf.wOff
+	sf.Close()
+	sf, err = NewSegmentData(ctx, core.VuidMeta{}, chunkname, diskConfig, false, ioQos)
+	require.NoError(t, err)
+	defer sf.Close()
+	require.Equal(t, int64(4096), sf.garbage[0])
+	require.Equal(t, int64(0), sf.garbage[1])
+	require.Equal(t, wOff, sf.wOff)
+	require.Equal(t, data, readTestSegShard(t, sf, shards[5], 0, shards[5].Size))
+}
+
+func TestSegfile_Reopen(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), defaultDiskTestDir+"SegfileReopen")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	ctx := context.Background()
+	chunkname := filepath.Join(testDir, bnapi.NewChunkId(0).String())
+
+	meta := core.VuidMeta{
+		Version:     0x1,
+		ParentChunk: bnapi.NewChunkId(1),
+		Ctime:       1000,
+	}
+	diskConfig := &core.Config{
+		BaseConfig: core.BaseConfig{Path: testDir, SegmentSizeB: 8192},
+	}
+	ioQos, _ := qos.NewQosManager(qos.Config{})
+	sf, err := NewSegmentData(ctx, meta, chunkname, diskConfig, true, ioQos)
+	require.NoError(t, err)
+
+	data := []byte("test reopen")
+	shard := newTestSegShard(1, data)
+	require.NoError(t, sf.Write(ctx, shard))
+	wOff := sf.wOff
+	sf.Close()
+
+	// segment size of file wins
+	diskConfig.SegmentSizeB = 4096
+	sf, err = NewSegmentData(ctx, meta, chunkname, diskConfig, false, ioQos)
+	require.NoError(t, err)
+	defer sf.Close()
+
+	require.Equal(t, int64(8192), sf.segSize)
+	require.Equal(t, wOff, sf.wOff)
+	require.Equal(t, meta.ParentChunk, sf.header.parentChunk)
+	require.Equal(t, meta.Ctime, sf.header.createTime)
+	require.Equal(t, data, readTestSegShard(t, sf, shard, 0, shard.Size))
+
+	stat, err := sf.Stat()
+	require.NoError(t, err)
+	require.Equal(t, meta.ParentChunk, stat.ParentID)
+	require.Equal(t, meta.Ctime, stat.CreateTime)
+
+	// not a chunk data file of datafile engine
+	_, err = NewChunkData(ctx, meta, chunkname, diskConfig, false, nil)
+	require.Error(t, err)
+}
+
+func TestNewDataHandler(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), defaultDiskTestDir+"NewDataHandler")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	ctx := context.Background()
+	segName := filepath.Join(testDir, bnapi.NewChunkId(0).String())
+	fileName := filepath.Join(testDir, bnapi.NewChunkId(1).String())
+
+	_, err = NewDataHandler(ctx, core.VuidMeta{}, segName, nil, true, nil)
+	require.Error(t, err)
+
+	conf := &core.Config{
+		BaseConfig: core.BaseConfig{Path: testDir, DataEngine: core.DataEngineSegment},
+	}
+	cd, err := NewDataHandler(ctx, core.VuidMeta{}, segName, conf, true, nil)
+	require.NoError(t, err)
+	_, ok := cd.(*segfile)
+	require.True(t, ok)
+	require.Equal(t, core.AlignSize(core.DefaultSegmentSizeB, _pagesize), cd.(*segfile).segSize)
+	cd.Close()
+
+	conf.DataEngine = core.DataEngineFile
+	cd, err = NewDataHandler(ctx, core.VuidMeta{}, fileName, conf, true, nil)
+	require.NoError(t, err)
+	_, ok = cd.(*datafile)
+	require.True(t, ok)
+	cd.Close()
+
+	// existing chunks keep their own engine
+	cd, err = NewDataHandler(ctx, core.VuidMeta{}, segName, conf, false, nil)
+	require.NoError(t, err)
+	_, ok = cd.(*segfile)
+	require.True(t, ok)
+	cd.Close()
+
+	conf.DataEngine = core.DataEngineSegment
+	cd, err = NewDataHandler(ctx, core.VuidMeta{}, fileName, conf, false, nil)
+	require.NoError(t, err)
+	_, ok = cd.(*datafile)
+	require.True(t, ok)
+	cd.Close()
+
+	badName := filepath.Join(testDir, "bad")
+	require.NoError(t, ioutil.WriteFile(badName, []byte("bad magic"), 0o644))
+	_, err = NewDataHandler(ctx, core.VuidMeta{}, badName, conf, false, nil)
+	require.Error(t, err)
+}
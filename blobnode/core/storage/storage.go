@@ -17,6 +17,7 @@ package storage
 import (
 	"context"
 	"io"
+	"io/ioutil"
 	"sync/atomic"
 
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
@@ -83,14 +84,83 @@ func (stg *storage) Write(ctx context.Context, b *core.Shard) (err error) {
 
 	// write meta
 	return meta.Write(ctx, b.Bid, core.ShardMeta{
-		Version: _shardVer[0],
-		Size:    b.Size,
-		Crc:     b.Crc,
-		Offset:  b.Offset,
-		Flag:    b.Flag,
+		Version:  _shardVer[0],
+		Size:     b.Size,
+		Crc:      b.Crc,
+		Offset:   b.Offset,
+		Flag:     b.Flag,
+		ExpireAt: b.ExpireAt,
 	})
 }
 
+// WriteBatch writes data of shards in order, then writes all metas in one batch.
+// shards read from one stream, body of failed shard is drained to keep the rest aligned.
+func (stg *storage) WriteBatch(ctx context.Context, bs []*core.Shard) (errs []error) {
+	return stg.writeBatch(ctx, bs, nil)
+}
+
+// writeMemFunc returns the inline buffer of shard, inline is false if shard should be written to data file.
+type writeMemFunc func(b *core.Shard) (buffer []byte, inline bool, err error)
+
+func (stg *storage) writeBatch(ctx context.Context, bs []*core.Shard, writeMem writeMemFunc) (errs []error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	data, meta := stg.data, stg.meta
+
+	errs = make([]error, len(bs))
+	metas := make(map[proto.BlobID]core.ShardMeta, len(bs))
+
+	for i, b := range bs {
+		var (
+			buffer []byte
+			inline bool
+			err    error
+		)
+		if writeMem != nil {
+			buffer, inline, err = writeMem(b)
+		}
+		if err == nil && !inline {
+			// write data file, will modify *shard
+			err = data.Write(ctx, b)
+		}
+		if err != nil {
+			span.Errorf("Failed write shard:%v, err:%v", b.Bid, err)
+			errs[i] = err
+			if _, err = io.Copy(ioutil.Discard, b.Body); err != nil {
+				span.Errorf("Failed drain shard:%v, err:%v", b.Bid, err)
+			}
+			continue
+		}
+
+		metas[b.Bid] = core.ShardMeta{
+			Version:  _shardVer[0],
+			Size:     b.Size,
+			Crc:      b.Crc,
+			Offset:   b.Offset,
+			Flag:     b.Flag,
+			ExpireAt: b.ExpireAt,
+			Inline:   inline,
+			Buffer:   buffer,
+		}
+	}
+
+	if len(metas) == 0 {
+		return errs
+	}
+
+	// write meta
+	if err := meta.WriteBatch(ctx, metas); err != nil {
+		span.Errorf("Failed write batch meta, err:%v", err)
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+	}
+
+	return errs
+}
+
 func (stg *storage) ReadShardMeta(ctx context.Context, bid proto.BlobID) (sm *core.ShardMeta, err error) {
 	meta := stg.meta
 	shard, err := meta.Read(ctx, bid)
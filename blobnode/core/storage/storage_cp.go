@@ -17,6 +17,7 @@ package storage
 import (
 	"context"
 	"io"
+	"io/ioutil"
 	"sync"
 	"time"
 
@@ -171,6 +172,24 @@ func (stg *replicateStorage) Write(ctx context.Context, b *core.Shard) (err erro
 	return nil
 }
 
+// WriteBatch forwards shards one by one, the slave has no batch to join during compacting
+func (stg *replicateStorage) WriteBatch(ctx context.Context, bs []*core.Shard) (errs []error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	errs = make([]error, len(bs))
+	for i, b := range bs {
+		// body is teed to slave while writing
+		body := b.Body
+		if errs[i] = stg.Write(ctx, b); errs[i] != nil {
+			if _, err := io.Copy(ioutil.Discard, body); err != nil {
+				span.Errorf("Failed drain shard:%v, err:%v", b.Bid, err)
+			}
+		}
+	}
+
+	return errs
+}
+
 func (stg *replicateStorage) ReadShardMeta(ctx context.Context, bid proto.BlobID) (sm *core.ShardMeta, err error) {
 	return stg.masterStg.ReadShardMeta(ctx, bid)
 }
@@ -46,6 +46,10 @@ func (mm *mockBrokenMeta) Write(ctx context.Context, bid proto.BlobID, value cor
 	return bloberr.ErrUnexpected
 }
 
+func (mm *mockBrokenMeta) WriteBatch(ctx context.Context, values map[proto.BlobID]core.ShardMeta) (err error) {
+	return bloberr.ErrUnexpected
+}
+
 func (mm *mockBrokenMeta) Read(ctx context.Context, bid proto.BlobID) (value core.ShardMeta, err error) {
 	err = bloberr.ErrUnexpected
 
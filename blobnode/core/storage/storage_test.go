@@ -86,6 +86,13 @@ func (mm *mockmeta) Write(ctx context.Context, bid proto.BlobID, value core.Shar
 	return
 }
 
+func (mm *mockmeta) WriteBatch(ctx context.Context, values map[proto.BlobID]core.ShardMeta) (err error) {
+	for bid, value := range values {
+		mm.bids[bid] = value
+	}
+	return
+}
+
 func (mm *mockmeta) Read(ctx context.Context, bid proto.BlobID) (value core.ShardMeta, err error) {
 	index := int64(bid)
 
@@ -229,6 +236,41 @@ func TestStorage_Operations(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestStorage_WriteBatch(t *testing.T) {
+	meta := &mockmeta{
+		id:            bnapi.ChunkId{0x1},
+		bids:          map[proto.BlobID]core.ShardMeta{},
+		supportInline: true,
+	}
+	stg := NewTinyFileStg(NewStorage(meta, &mockdata{}), 4)
+	require.NotNil(t, stg)
+
+	ctx := context.TODO()
+	bodies := []*bytes.Reader{
+		bytes.NewReader([]byte("bid1")),
+		bytes.NewReader([]byte("bid-2")),
+		bytes.NewReader([]byte("bid-4")),
+	}
+	bs := []*core.Shard{
+		{Bid: 1, Size: 4, Body: bodies[0]},
+		{Bid: 2, Size: 5, Body: bodies[1]},
+		{Bid: 4, Size: 5, Body: bodies[2]},
+	}
+
+	errs := stg.WriteBatch(ctx, bs)
+	require.Equal(t, 3, len(errs))
+	require.NoError(t, errs[0])
+	require.ErrorIs(t, errs[1], bloberr.ErrUnexpected)
+	require.NoError(t, errs[2])
+	// body of failed shard is drained
+	require.Equal(t, 0, bodies[1].Len())
+
+	require.Equal(t, 2, len(meta.bids))
+	require.True(t, meta.bids[1].Inline)
+	require.Equal(t, []byte("bid1"), meta.bids[1].Buffer)
+	require.False(t, meta.bids[4].Inline)
+}
+
 func TestStorage_ShardInline(t *testing.T) {
 	stg := NewStorage(&mockmeta{
 		id:            bnapi.ChunkId{0x1},
@@ -84,13 +84,24 @@ func (stg *tinyfileStorage) Write(ctx context.Context, b *core.Shard) (err error
 
 	// write meta
 	return stg.meta.Write(ctx, b.Bid, core.ShardMeta{
-		Version: _shardVer[0],
-		Size:    b.Size,
-		Crc:     b.Crc,
-		Offset:  b.Offset,
-		Flag:    b.Flag,
-		Inline:  true,
-		Buffer:  buffer,
+		Version:  _shardVer[0],
+		Size:     b.Size,
+		Crc:      b.Crc,
+		Offset:   b.Offset,
+		Flag:     b.Flag,
+		ExpireAt: b.ExpireAt,
+		Inline:   true,
+		Buffer:   buffer,
+	})
+}
+
+func (stg *tinyfileStorage) WriteBatch(ctx context.Context, bs []*core.Shard) (errs []error) {
+	return stg.storage.writeBatch(ctx, bs, func(b *core.Shard) (buffer []byte, inline bool, err error) {
+		if !stg.canInline(b.Size) {
+			return nil, false, nil
+		}
+		buffer, err = stg.writeToMemory(b)
+		return buffer, true, err
 	})
 }
 
@@ -50,6 +50,7 @@ var (
 type MetaHandler interface {
 	Get(ctx context.Context, key []byte) ([]byte, error)
 	Put(ctx context.Context, kv rdb.KV) error
+	PutBatch(ctx context.Context, kvs []rdb.KV) error
 	Delete(ctx context.Context, key []byte) error
 	DeleteRange(ctx context.Context, start, end []byte) error
 	Flush(ctx context.Context) error
@@ -151,6 +152,35 @@ func (md *metadb) Put(ctx context.Context, kv rdb.KV) (err error) {
 	}
 }
 
+// PutBatch writes all kvs atomically in one write batch
+func (md *metadb) PutBatch(ctx context.Context, kvs []rdb.KV) (err error) {
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	req := Request{
+		Type: msgPutBatch,
+		Data: kvs,
+	}
+
+	mgr, iot := md.getiotype(ctx)
+
+	mgr.WriteBegin(uint64(len(kvs)))
+	defer mgr.WriteEnd(time.Now())
+
+	resCh := req.Register()
+
+	md.applyToken(ctx, iot)
+	md.writeReqs <- req
+
+	select {
+	case <-md.closeCh:
+		return ErrStopped
+	case x := <-resCh:
+		return x.err
+	}
+}
+
 func (md *metadb) Delete(ctx context.Context, key []byte) (err error) {
 	req := Request{
 		Type: msgDel,
@@ -303,6 +333,10 @@ func (md *metadb) doBatch(ctx context.Context, reqs []Request) (err error) {
 		case msgPut:
 			kv := req.Data.(rdb.KV)
 			writeBatch.Put(kv.Key, kv.Value)
+		case msgPutBatch:
+			for _, kv := range req.Data.([]rdb.KV) {
+				writeBatch.Put(kv.Key, kv.Value)
+			}
 		case msgDel:
 			kv := req.Data.(rdb.KV)
 			writeBatch.Delete(kv.Key)
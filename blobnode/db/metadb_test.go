@@ -383,3 +383,33 @@ func TestDeleteRange(t *testing.T) {
 	err = md.Close(ctx)
 	require.NoError(t, err)
 }
+
+func TestKVDB_PutBatch(t *testing.T) {
+	testDir, err := ioutil.TempDir(os.TempDir(), "PutBatch")
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	ctx := context.Background()
+
+	md, err := newMetaDB(testDir, MetaConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, md)
+	defer md.Close(ctx)
+
+	require.NoError(t, md.PutBatch(ctx, nil))
+
+	kvs := make([]rdb.KV, 0, 10)
+	for i := 0; i < 10; i++ {
+		kvs = append(kvs, rdb.KV{
+			Key:   []byte(fmt.Sprintf("key-%d", i)),
+			Value: []byte(fmt.Sprintf("value-%d", i)),
+		})
+	}
+	require.NoError(t, md.PutBatch(ctx, kvs))
+
+	for _, kv := range kvs {
+		value, err := md.Get(ctx, kv.Key)
+		require.NoError(t, err)
+		require.Equal(t, kv.Value, value)
+	}
+}
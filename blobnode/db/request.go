@@ -20,6 +20,7 @@ const (
 	msgPut MessageType = iota + 1
 	msgDel
 	msgDelRange
+	msgPutBatch
 )
 
 type Request struct {
@@ -15,12 +15,18 @@
 package blobnode
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"path/filepath"
+	"time"
 
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
 	"github.com/cubefs/blobstore/blobnode/base"
+	"github.com/cubefs/blobstore/blobnode/core"
 	"github.com/cubefs/blobstore/blobnode/core/disk"
 	bloberr "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
 	"github.com/cubefs/blobstore/common/rpc"
 	"github.com/cubefs/blobstore/common/trace"
 )
@@ -59,89 +65,205 @@ func (s *Service) DiskProbe(c *rpc.Context) {
 	}
 	defer s.DiskLimitPerKey.Release(probePath)
 
-	// Verify that the directory path exists
-	fileExists, err := base.IsFileExists(probePath)
-	if err != nil || !fileExists {
-		span.Errorf("probePath(%s) is not exist, err:%v", probePath, err)
+	// Verify that the directory path exists and must be empty
+	if err = checkFreshDiskPath(probePath); err != nil {
+		span.Errorf("probePath(%s) is not a fresh disk, err:%v", probePath, err)
 		c.RespondError(bloberr.ErrInvalidParam)
 		return
 	}
 
-	// Must be empty
-	empty, err := base.IsEmptyDisk(probePath)
-	if err != nil || !empty {
-		span.Errorf("probePath(%s) is not empty. err:%v", probePath, err)
+	// must be no corresponding active handle
+	if _, found := s.findOnlineDisk(probePath); found {
+		span.Errorf("path<%s> found online disk.", probePath)
 		c.RespondError(bloberr.ErrInvalidParam)
 		return
 	}
 
-	var foundOnlineDisk bool
-	s.lock.RLock()
-	for _, d := range s.Disks {
-		path, err := filepath.Abs(d.GetConfig().Path)
-		if err != nil {
-			s.lock.RUnlock()
-			c.RespondError(err)
-			return
-		}
-		if path == probePath {
-			foundOnlineDisk = true
-			break
-		}
+	// The corresponding configuration file must exist
+	diskConf, found := s.findDiskConfig(probePath)
+	if !found {
+		span.Errorf("can not found<%s> disk config", probePath)
+		c.RespondError(bloberr.ErrNotFound)
+		return
 	}
-	s.lock.RUnlock()
 
-	// must be no corresponding active handle
-	if foundOnlineDisk {
-		span.Errorf("path<%s> found online disk.", probePath)
+	// fix init config
+	s.fixDiskConf(&diskConf)
+
+	diskInfo, err := s.openAndRegisterDisk(ctx, diskConf)
+	if err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	span.Infof("probe path<%s> diskId:%d success.", probePath, diskInfo.DiskID)
+}
+
+/*
+ *  method:         POST
+ *  url:            /disk/attach
+ *  request body:   json.Marshal(DiskAttachArgs)
+ *  response body:  json.Marshal(DiskInfo)
+ */
+func (s *Service) DiskAttach(c *rpc.Context) {
+	args := new(bnapi.DiskAttachArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Debugf("disk attach args: %v", args)
+
+	attachPath, err := filepath.Abs(args.Path)
+	if err != nil {
+		span.Errorf("Failed abs(path):%s invalid: err:%v", args.Path, err)
+		c.RespondError(err)
+		return
+	}
+
+	err = s.DiskLimitPerKey.Acquire(attachPath)
+	if err != nil {
+		span.Errorf("attachPath (%v) are loading at the same time", attachPath)
+		c.RespondError(bloberr.ErrOutOfLimit)
+		return
+	}
+	defer s.DiskLimitPerKey.Release(attachPath)
+
+	// Must be a freshly formatted disk
+	if err = checkFreshDiskPath(attachPath); err != nil {
+		span.Errorf("attachPath(%s) is not a fresh disk, err:%v", attachPath, err)
 		c.RespondError(bloberr.ErrInvalidParam)
 		return
 	}
 
-	// The corresponding configuration file must exist
-	foundIdx := -1
-	for idx, conf := range s.Conf.Disks {
-		path, err := filepath.Abs(conf.Path)
+	diskConf, found := s.findDiskConfig(attachPath)
+	if !found {
+		span.Errorf("can not found<%s> disk config", attachPath)
+		c.RespondError(bloberr.ErrNotFound)
+		return
+	}
+
+	// the replaced disk must have been repaired, then clustermgr accepts the same path
+	if old, found := s.findOnlineDisk(attachPath); found {
+		info, err := s.ClusterMgrClient.DiskInfo(ctx, old.ID())
 		if err != nil {
+			span.Errorf("Failed get clustermgr diskinfo %v, err:%v", old.ID(), err)
 			c.RespondError(err)
 			return
 		}
-		if probePath == path {
-			foundIdx = idx
+		if info.Status < proto.DiskStatusRepaired {
+			span.Errorf("path<%s> online disk:%v status:%v not repaired", attachPath, old.ID(), info.Status)
+			c.RespondError(bloberr.ErrInvalidParam)
+			return
 		}
+		s.detachDisk(ctx, old)
 	}
 
-	if foundIdx < 0 {
-		span.Errorf("can not found<%s> disk config", probePath)
-		c.RespondError(bloberr.ErrNotFound)
+	// superblock out of the disk belongs to the replaced one
+	if err = s.archiveStaleMeta(ctx, attachPath, diskConf.MetaRootPrefix); err != nil {
+		span.Errorf("Failed archive stale meta of path<%s>, err:%v", attachPath, err)
+		c.RespondError(err)
 		return
 	}
 
-	// fix init config
-	diskConf := s.Conf.Disks[foundIdx]
+	// format, allocate disk id and open
 	s.fixDiskConf(&diskConf)
+	diskConf.AutoFormat = true
+	diskInfo, err := s.openAndRegisterDisk(ctx, diskConf)
+	if err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	span.Infof("attach path<%s> diskId:%d success.", attachPath, diskInfo.DiskID)
+	c.RespondJSON(&diskInfo)
+}
+
+// checkFreshDiskPath checks that path exists and is an empty disk
+func checkFreshDiskPath(path string) error {
+	fileExists, err := base.IsFileExists(path)
+	if err != nil {
+		return err
+	}
+	if !fileExists {
+		return os.ErrNotExist
+	}
+
+	empty, err := base.IsEmptyDisk(path)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return os.ErrExist
+	}
+	return nil
+}
+
+// openAndRegisterDisk opens disk storage, registers it to clustermgr and adds it to service map
+func (s *Service) openAndRegisterDisk(ctx context.Context, diskConf core.Config) (diskInfo bnapi.DiskInfo, err error) {
+	span := trace.SpanFromContextSafe(ctx)
 
-	// new disk storage
 	ds, err := disk.NewDiskStorage(ctx, diskConf)
 	if err != nil {
 		span.Errorf("Failed Open DiskStorage. conf:%v, err:%v", diskConf, err)
-		c.RespondError(err)
 		return
 	}
 
-	// add disk to cluster mgr
-	diskInfo := ds.DiskInfo()
+	diskInfo = ds.DiskInfo()
 	err = s.ClusterMgrClient.AddDisk(ctx, &diskInfo)
 	if err != nil {
 		span.Errorf("Failed register disk: %v, err:%v", diskInfo, err)
-		c.RespondError(err)
+		ds.Close(ctx)
 		return
 	}
 
-	// add to service map
 	s.lock.Lock()
 	s.Disks[ds.DiskID] = ds
 	s.lock.Unlock()
 
-	span.Infof("probe path<%s> diskId:%d success.", probePath, ds.DiskID)
+	return diskInfo, nil
+}
+
+func (s *Service) findDiskConfig(path string) (conf core.Config, found bool) {
+	for _, dc := range s.Conf.Disks {
+		p, err := filepath.Abs(dc.Path)
+		if err == nil && p == path {
+			return dc, true
+		}
+	}
+	return
+}
+
+func (s *Service) findOnlineDisk(path string) (core.DiskAPI, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, d := range s.Disks {
+		p, err := filepath.Abs(d.GetConfig().Path)
+		if err == nil && p == path {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Service) archiveStaleMeta(ctx context.Context, path string, metaRootPrefix string) error {
+	span := trace.SpanFromContextSafe(ctx)
+
+	if metaRootPrefix == "" {
+		return nil
+	}
+
+	metaPath := core.GetMetaPath(path, metaRootPrefix)
+	exist, err := base.IsFileExists(metaPath)
+	if err != nil || !exist {
+		return err
+	}
+
+	archivePath := fmt.Sprintf("%s.stale.%d", metaPath, time.Now().UnixNano())
+	span.Warnf("archive stale meta %s to %s", metaPath, archivePath)
+
+	return os.Rename(metaPath, archivePath)
 }
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
@@ -28,6 +29,9 @@ import (
 
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
 	"github.com/cubefs/blobstore/blobnode/core/disk"
+	bloberr "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/rpc"
 	"github.com/cubefs/blobstore/common/trace"
 )
 
@@ -113,3 +117,64 @@ func TestService_WsprpcDiskProbe(t *testing.T) {
 	span.Infof("=== resp:%v, err:%v ===", resp, err)
 	require.Equal(t, 200, resp.StatusCode)
 }
+
+func TestDiskAttach(t *testing.T) {
+	service, mockcm := newTestBlobNodeService(t, "DiskAttach")
+	defer cleanTestBlobNodeService(service)
+
+	host := runTestServer(service)
+	client := bnapi.New(&bnapi.Config{})
+
+	ctx := context.TODO()
+
+	testDisk := mockcm.disks[0]
+	args := &bnapi.DiskAttachArgs{Path: testDisk.path}
+
+	// err: non-empty path
+	_, err := client.AttachDisk(ctx, host, args)
+	require.Error(t, err)
+	require.Equal(t, bloberr.CodeInvalidParam, rpc.DetectStatusCode(err))
+
+	// err: not in config
+	otherPath := filepath.Join(filepath.Dir(testDisk.path), "disk3")
+	require.NoError(t, os.MkdirAll(otherPath, 0o755))
+	_, err = client.AttachDisk(ctx, host, &bnapi.DiskAttachArgs{Path: otherPath})
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, rpc.DetectStatusCode(err))
+
+	// replace disk with a fresh one
+	require.NoError(t, os.RemoveAll(testDisk.path))
+	require.NoError(t, os.MkdirAll(filepath.Join(testDisk.path, "lost+found"), 0o755))
+
+	// err: replaced disk is not repaired
+	_, err = client.AttachDisk(ctx, host, args)
+	require.Error(t, err)
+	require.Equal(t, bloberr.CodeInvalidParam, rpc.DetectStatusCode(err))
+	service.lock.RLock()
+	_, exist := service.Disks[testDisk.diskId]
+	service.lock.RUnlock()
+	require.True(t, exist)
+
+	mockcm.disks[0].status = proto.DiskStatusRepaired
+	info, err := client.AttachDisk(ctx, host, args)
+	require.NoError(t, err)
+	require.NotEqual(t, testDisk.diskId, info.DiskID)
+	require.Equal(t, testDisk.path, info.Path)
+	require.Equal(t, proto.DiskStatusNormal, info.Status)
+
+	service.lock.RLock()
+	_, exist = service.Disks[testDisk.diskId]
+	require.False(t, exist)
+	ds, exist := service.Disks[info.DiskID]
+	service.lock.RUnlock()
+	require.True(t, exist)
+	require.Equal(t, testDisk.path, ds.GetConfig().Path)
+
+	// serving without restart
+	err = client.CreateChunk(ctx, host, &bnapi.CreateChunkArgs{DiskID: info.DiskID, Vuid: proto.Vuid(2001)})
+	require.NoError(t, err)
+
+	// err: formatted already
+	_, err = client.AttachDisk(ctx, host, args)
+	require.Error(t, err)
+}
@@ -57,6 +57,9 @@ func (s *Service) heartbeatToClusterMgr() {
 
 		diskInfo := ds.DiskInfo()
 		span.Debugf("id:%v, info: %v", diskInfo.DiskID, diskInfo)
+		if diskInfo.Slow {
+			span.Warnf("slow disk:%v, latency:%+v", diskInfo.DiskID, ds.GetIoQos().LatencyStat())
+		}
 
 		dis = append(dis, &diskInfo.DiskHeartBeatInfo)
 	}
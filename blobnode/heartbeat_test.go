@@ -32,6 +32,7 @@ import (
 
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
 	cmapi "github.com/cubefs/blobstore/api/clustermgr"
+	"github.com/cubefs/blobstore/blobnode/base/qos"
 	"github.com/cubefs/blobstore/blobnode/core"
 	"github.com/cubefs/blobstore/blobnode/db"
 	"github.com/cubefs/blobstore/common/kvstore"
@@ -181,3 +182,86 @@ func TestHeartbeat3(t *testing.T) {
 
 	service.heartbeatToClusterMgr()
 }
+
+func TestHeartbeatSlowDisk(t *testing.T) {
+	lock := sync.Mutex{}
+	diskId := proto.DiskID(101)
+	reported := make(map[proto.DiskID]bool)
+	mockClusterMgrServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if strings.HasPrefix(req.URL.Path, "/diskid/alloc") {
+			b, _ := json.Marshal(cmapi.DiskIDAllocRet{DiskID: diskId})
+			_, _ = w.Write(b)
+			diskId++
+			return
+		}
+		if strings.HasPrefix(req.URL.Path, "/disk/heartbeat") {
+			args := &cmapi.DisksHeartbeatArgs{}
+			_ = json.NewDecoder(req.Body).Decode(args)
+			for _, info := range args.Disks {
+				reported[info.DiskID] = info.Slow
+			}
+			_, _ = w.Write([]byte("{}"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cc := &cmapi.Config{}
+	cc.Hosts = []string{mockClusterMgrServer.URL}
+
+	workDir, err := ioutil.TempDir(os.TempDir(), defaultSvrTestDir+"HeartbeatSlowDisk")
+	require.NoError(t, err)
+	defer os.Remove(workDir)
+
+	path1 := filepath.Join(workDir, "disk1")
+	path2 := filepath.Join(workDir, "disk2")
+	require.NoError(t, os.MkdirAll(core.GetMetaPath(path1, ""), 0o755))
+	require.NoError(t, os.MkdirAll(core.GetMetaPath(path2, ""), 0o755))
+
+	diskQos := qos.Config{Latency: qos.LatencyConfig{
+		Enable:         true,
+		SlowMinSamples: 1,
+		SlowP99Ms:      map[string]int64{"normal": 100},
+	}}
+	conf := Config{
+		HostInfo: core.HostInfo{
+			IDC:  "testIdc",
+			Rack: "testRack",
+		},
+		Disks: []core.Config{
+			{BaseConfig: core.BaseConfig{Path: path1, AutoFormat: true, MaxChunks: 700}, MetaConfig: db.MetaConfig{RocksdbOption: kvstore.RocksDBOption{WriteBufferSize: 1024}}},
+			{BaseConfig: core.BaseConfig{Path: path2, AutoFormat: true, MaxChunks: 700}, MetaConfig: db.MetaConfig{RocksdbOption: kvstore.RocksDBOption{WriteBufferSize: 1024}}},
+		},
+		DiskConfig:           core.RuntimeConfig{DiskQos: diskQos},
+		Clustermgr:           cc,
+		HeartbeatIntervalSec: 600,
+	}
+	service, err := NewService(conf)
+	require.NoError(t, err)
+	defer cleanTestBlobNodeService(service)
+
+	slowDisk, fastDisk := proto.DiskID(101), proto.DiskID(102)
+	ioQos := service.Disks[slowDisk].GetIoQos().(*qos.IOQos)
+	ioQos.Latency.Observe(bnapi.NormalIO, time.Second)
+	require.True(t, service.Disks[slowDisk].DiskInfo().Slow)
+	require.False(t, service.Disks[fastDisk].DiskInfo().Slow)
+
+	service.heartbeatToClusterMgr()
+	lock.Lock()
+	require.Equal(t, map[proto.DiskID]bool{slowDisk: true, fastDisk: false}, reported)
+	lock.Unlock()
+
+	host := runTestServer(service)
+	resp, err := http.Get(host + "/debug/stat")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	ret := struct {
+		Latencies map[proto.DiskID]qos.LatencyStat `json:"io_latencies"`
+	}{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	require.True(t, ret.Latencies[slowDisk].Slow)
+	require.Equal(t, []string{"normal"}, ret.Latencies[slowDisk].SlowIOTypes)
+	require.False(t, ret.Latencies[fastDisk].Slow)
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blobnode
+
+import (
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	bloberr "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/rpc"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+/*
+ *  method:         POST
+ *  url:            /chunk/migrate/diskid/{diskid}/vuid/{vuid}/dstdiskid/{dstdiskid}
+ *  request body:   json.Marshal(MigrateChunkArgs)
+ */
+func (s *Service) ChunkMigrate_(c *rpc.Context) {
+	args := new(bnapi.MigrateChunkArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	span.Debugf("args: %v", args)
+
+	if !bnapi.IsValidDiskID(args.DiskID) || !bnapi.IsValidDiskID(args.DstDiskID) {
+		span.Debugf("args:%v", args)
+		c.RespondError(bloberr.ErrInvalidDiskId)
+		return
+	}
+	if args.DiskID == args.DstDiskID {
+		c.RespondError(bloberr.ErrInvalidParam)
+		return
+	}
+
+	// status of vuid can not be changed when migrating
+	limitKey := args.Vuid
+	err := s.ChunkLimitPerVuid.Acquire(limitKey)
+	if err != nil {
+		span.Errorf("vuid(%v) status concurry conflict", args.Vuid)
+		c.RespondError(bloberr.ErrOverload)
+		return
+	}
+	defer s.ChunkLimitPerVuid.Release(limitKey)
+
+	s.lock.RLock()
+	ds, exist := s.Disks[args.DiskID]
+	dst, dstExist := s.Disks[args.DstDiskID]
+	s.lock.RUnlock()
+	if !exist || !dstExist {
+		span.Errorf("disk:%v or dst disk:%v not found", args.DiskID, args.DstDiskID)
+		c.RespondError(bloberr.ErrNoSuchDisk)
+		return
+	}
+
+	err = ds.MigrateChunk(ctx, args.Vuid, dst)
+	if err != nil {
+		span.Errorf("migrate args:(%v) failed: %v", args, err)
+		c.RespondError(err)
+		return
+	}
+
+	span.Infof("migrate vuid:%v from disk:%v to disk:%v success", args.Vuid, args.DiskID, args.DstDiskID)
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package blobnode
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	cmapi "github.com/cubefs/blobstore/api/clustermgr"
+	"github.com/cubefs/blobstore/common/proto"
+)
+
+func TestMigrateChunk(t *testing.T) {
+	service, mcm := newTestBlobNodeService(t, "MigrateChunk")
+	defer cleanTestBlobNodeService(service)
+
+	host := runTestServer(service)
+	client := bnapi.New(&bnapi.Config{})
+
+	ctx := context.TODO()
+
+	diskID, dstDiskID := proto.DiskID(101), proto.DiskID(102)
+	vuid := proto.Vuid(2001)
+
+	err := client.CreateChunk(ctx, host, &bnapi.CreateChunkArgs{DiskID: diskID, Vuid: vuid})
+	require.NoError(t, err)
+
+	shardData := []byte("test migrate")
+	for bid := proto.BlobID(1); bid <= 3; bid++ {
+		_, err = client.PutShard(ctx, host, &bnapi.PutShardArgs{
+			DiskID: diskID,
+			Vuid:   vuid,
+			Bid:    bid,
+			Size:   int64(len(shardData)),
+			Body:   bytes.NewReader(shardData),
+		})
+		require.NoError(t, err)
+	}
+
+	migrateArgs := &bnapi.MigrateChunkArgs{DiskID: diskID, Vuid: vuid, DstDiskID: diskID}
+	err = client.MigrateChunk(ctx, host, migrateArgs)
+	require.Error(t, err)
+
+	migrateArgs.DstDiskID = proto.DiskID(103)
+	err = client.MigrateChunk(ctx, host, migrateArgs)
+	require.Error(t, err)
+
+	migrateArgs.DstDiskID = dstDiskID
+	migrateArgs.Vuid = proto.Vuid(2002)
+	err = client.MigrateChunk(ctx, host, migrateArgs)
+	require.Error(t, err)
+
+	migrateArgs.Vuid = vuid
+	err = client.MigrateChunk(ctx, host, migrateArgs)
+	require.NoError(t, err)
+	require.Equal(t, []cmapi.UpdateVolumeArgs{{NewVuid: vuid, NewDiskID: dstDiskID, OldVuid: vuid}}, mcm.updates)
+
+	cis, err := client.ListChunks(ctx, host, &bnapi.ListChunkArgs{DiskID: diskID})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(cis))
+	cis, err = client.ListChunks(ctx, host, &bnapi.ListChunkArgs{DiskID: dstDiskID})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(cis))
+	require.Equal(t, vuid, cis[0].Vuid)
+
+	for bid := proto.BlobID(1); bid <= 3; bid++ {
+		body, _, err := client.GetShard(ctx, host, &bnapi.GetShardArgs{DiskID: dstDiskID, Vuid: vuid, Bid: bid})
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(body)
+		body.Close()
+		require.NoError(t, err)
+		require.Equal(t, shardData, data)
+	}
+
+	// migrated already, notify clustermgr again
+	err = client.MigrateChunk(ctx, host, migrateArgs)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(mcm.updates))
+}
+
+func TestMigrateChunkConcurrentWrite(t *testing.T) {
+	service, _ := newTestBlobNodeService(t, "MigrateChunkConcurrentWrite")
+	defer cleanTestBlobNodeService(service)
+
+	host := runTestServer(service)
+	client := bnapi.New(&bnapi.Config{})
+
+	ctx := context.TODO()
+
+	diskID, dstDiskID := proto.DiskID(101), proto.DiskID(102)
+	vuid := proto.Vuid(2001)
+
+	err := client.CreateChunk(ctx, host, &bnapi.CreateChunkArgs{DiskID: diskID, Vuid: vuid})
+	require.NoError(t, err)
+
+	shardData := []byte("test migrate concurrent write")
+	putShard := func(bid proto.BlobID) error {
+		_, err := client.PutShard(ctx, host, &bnapi.PutShardArgs{
+			DiskID: diskID,
+			Vuid:   vuid,
+			Bid:    bid,
+			Size:   int64(len(shardData)),
+			Body:   bytes.NewReader(shardData),
+		})
+		return err
+	}
+	for bid := proto.BlobID(1); bid <= 100; bid++ {
+		require.NoError(t, putShard(bid))
+	}
+
+	// keep writing to the source disk during migrating, every successful write must survive
+	writers := 4
+	done := make(chan struct{})
+	written := make([][]proto.BlobID, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for bid := proto.BlobID(1000*(i+1)) + 1; ; bid++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if putShard(bid) == nil {
+					written[i] = append(written[i], bid)
+				}
+			}
+		}(i)
+	}
+
+	err = client.MigrateChunk(ctx, host, &bnapi.MigrateChunkArgs{DiskID: diskID, Vuid: vuid, DstDiskID: dstDiskID})
+	close(done)
+	wg.Wait()
+	require.NoError(t, err)
+
+	// vuid is on dst disk only, writes to source disk are rejected
+	require.Error(t, putShard(proto.BlobID(100000)))
+
+	for _, bids := range written {
+		for _, bid := range bids {
+			body, _, err := client.GetShard(ctx, host, &bnapi.GetShardArgs{DiskID: dstDiskID, Vuid: vuid, Bid: bid})
+			require.NoError(t, err, "bid:%d", bid)
+			data, err := ioutil.ReadAll(body)
+			body.Close()
+			require.NoError(t, err)
+			require.Equal(t, shardData, data)
+		}
+	}
+}
@@ -77,12 +77,16 @@ func NewHandler(service *Service) *rpc.Router {
 
 	rpc.RegisterArgsParser(&bnapi.DiskStatArgs{}, "json")
 	rpc.RegisterArgsParser(&bnapi.DiskProbeArgs{}, "json")
+	rpc.RegisterArgsParser(&bnapi.DiskAttachArgs{}, "json")
 
 	rpc.RegisterArgsParser(&bnapi.CreateChunkArgs{}, "json")
 	rpc.RegisterArgsParser(&bnapi.ChangeChunkStatusArgs{}, "json")
 	rpc.RegisterArgsParser(&bnapi.ListChunkArgs{}, "json")
 	rpc.RegisterArgsParser(&bnapi.StatChunkArgs{}, "json")
 	rpc.RegisterArgsParser(&bnapi.CompactChunkArgs{}, "json")
+	rpc.RegisterArgsParser(&bnapi.MigrateChunkArgs{}, "json")
+	rpc.RegisterArgsParser(&bnapi.ExportChunkArgs{}, "json")
+	rpc.RegisterArgsParser(&bnapi.ImportChunkArgs{}, "json")
 
 	rpc.RegisterArgsParser(&bnapi.GetShardArgs{}, "json")
 	rpc.RegisterArgsParser(&bnapi.ListShardsArgs{}, "json")
@@ -90,6 +94,7 @@ func NewHandler(service *Service) *rpc.Router {
 	rpc.RegisterArgsParser(&bnapi.StatShardArgs{}, "json")
 	rpc.RegisterArgsParser(&bnapi.DeleteShardArgs{}, "json")
 	rpc.RegisterArgsParser(&bnapi.PutShardArgs{}, "json")
+	rpc.RegisterArgsParser(&bnapi.PutShardsArgs{}, "json")
 
 	rpc.Use(service.requestCounter) // first interceptor
 	r.Handle(http.MethodGet, "/stat", service.Stat, rpc.OptArgsQuery())
@@ -97,6 +102,7 @@ func NewHandler(service *Service) *rpc.Router {
 
 	r.Handle(http.MethodGet, "/disk/stat/diskid/:diskid", service.DiskStat_, rpc.OptArgsURI())
 	r.Handle(http.MethodPost, "/disk/probe", service.DiskProbe, rpc.OptArgsBody())
+	r.Handle(http.MethodPost, "/disk/attach", service.DiskAttach, rpc.OptArgsBody())
 
 	r.Handle(http.MethodPost, "/chunk/create/diskid/:diskid/vuid/:vuid", service.ChunkCreate_, rpc.OptArgsURI(), rpc.OptArgsQuery())
 	r.Handle(http.MethodPost, "/chunk/release/diskid/:diskid/vuid/:vuid", service.ChunkRelease_, rpc.OptArgsURI(), rpc.OptArgsQuery())
@@ -105,6 +111,9 @@ func NewHandler(service *Service) *rpc.Router {
 	r.Handle(http.MethodGet, "/chunk/list/diskid/:diskid", service.ChunkList_, rpc.OptArgsURI())
 	r.Handle(http.MethodGet, "/chunk/stat/diskid/:diskid/vuid/:vuid", service.ChunkStat_, rpc.OptArgsURI())
 	r.Handle(http.MethodPost, "/chunk/compact/diskid/:diskid/vuid/:vuid", service.ChunkCompact_, rpc.OptArgsURI())
+	r.Handle(http.MethodPost, "/chunk/migrate/diskid/:diskid/vuid/:vuid/dstdiskid/:dstdiskid", service.ChunkMigrate_, rpc.OptArgsURI())
+	r.Handle(http.MethodGet, "/chunk/export/diskid/:diskid/vuid/:vuid", service.ChunkExport_, rpc.OptArgsURI())
+	r.Handle(http.MethodPost, "/chunk/import/diskid/:diskid/vuid/:vuid", service.ChunkImport_, rpc.OptArgsURI())
 
 	r.Handle(http.MethodGet, "/shard/get/diskid/:diskid/vuid/:vuid/bid/:bid", service.ShardGet_, rpc.OptArgsURI(), rpc.OptArgsQuery())
 	r.Handle(http.MethodGet, "/shard/list/diskid/:diskid/vuid/:vuid/startbid/:startbid/status/:status/count/:count", service.ShardList_, rpc.OptArgsURI())
@@ -113,6 +122,7 @@ func NewHandler(service *Service) *rpc.Router {
 	r.Handle(http.MethodPost, "/shard/markdelete/diskid/:diskid/vuid/:vuid/bid/:bid", service.ShardMarkdelete_, rpc.OptArgsURI())
 	r.Handle(http.MethodPost, "/shard/delete/diskid/:diskid/vuid/:vuid/bid/:bid", service.ShardDelete_, rpc.OptArgsURI())
 	r.Handle(http.MethodPost, "/shard/put/diskid/:diskid/vuid/:vuid/bid/:bid/size/:size", service.ShardPut_, rpc.OptArgsURI(), rpc.OptArgsQuery())
+	r.Handle(http.MethodPost, "/shards/put/diskid/:diskid/vuid/:vuid", service.ShardsPut, rpc.OptArgsURI(), rpc.OptArgsQuery())
 
 	return r
 }
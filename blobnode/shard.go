@@ -15,6 +15,8 @@
 package blobnode
 
 import (
+	"bytes"
+	"io"
 	"math"
 	"net/http"
 	"os"
@@ -23,6 +25,7 @@ import (
 
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
 	"github.com/cubefs/blobstore/blobnode/base"
+	"github.com/cubefs/blobstore/blobnode/base/cache"
 	"github.com/cubefs/blobstore/blobnode/base/limitio"
 	"github.com/cubefs/blobstore/blobnode/core"
 	bloberr "github.com/cubefs/blobstore/common/errors"
@@ -96,6 +99,26 @@ func (s *Service) ShardGet_(c *rpc.Context) {
 		return
 	}
 
+	// hot shards are served from read cache
+	var (
+		cacheKey   = cache.Key{Vuid: args.Vuid, Bid: args.Bid}
+		cacheEpoch uint64
+		cached     *bytes.Buffer
+		useCache   = s.ShardCache != nil && s.ShardCache.AdmitIOType(args.Type)
+	)
+	if useCache {
+		start := time.Now()
+		data, crc, ok := s.ShardCache.Get(cacheKey)
+		span.AppendTrackLog("cache", start, nil)
+		if ok {
+			if err = respondCachedShard(c, data, crc, rangeBytesStr != "", from, to); err != nil {
+				span.Errorf("Failed respond cached shard. args:%v err:%v", args, err)
+			}
+			return
+		}
+		cacheEpoch = s.ShardCache.Epoch(cacheKey)
+	}
+
 	start := time.Now()
 	limitKey := args.Bid
 	err = s.GetQpsLimitPerKey.Acquire(limitKey)
@@ -122,25 +145,7 @@ func (s *Service) ShardGet_(c *rpc.Context) {
 	shard := core.NewShardReader(args.Bid, args.Vuid, from, to, w)
 
 	shard.PrepareHook = func(shard *core.Shard) {
-		// set crc to header
-		// build http response header
-		w.Header().Set("Content-Type", "application/octet-stream")
-		w.Header().Set("Content-Transfer-Encoding", "binary")
-		w.Header().Set("CRC", strconv.FormatUint(uint64(shard.Crc), 10))
-
-		from, to := shard.From, shard.To
-		bodySize := int64(shard.Size)
-
-		if rangeBytesStr != "" {
-			bodySize = to - from
-			rangeResp := "bytes " + strconv.FormatInt(from, 10) + "-" + strconv.FormatInt(to-1, 10) + "/" + strconv.FormatInt(int64(shard.Size), 10)
-			w.Header().Set("Content-Length", strconv.FormatInt(int64(bodySize), 10))
-			w.Header().Set("Content-Range", rangeResp)
-			w.WriteHeader(206)
-		} else {
-			w.Header().Set("Content-Length", strconv.FormatInt(bodySize, 10))
-			w.WriteHeader(200)
-		}
+		writeShardHeader(w, shard.Crc, int64(shard.Size), shard.From, shard.To, rangeBytesStr != "")
 
 		wroteHeader = true
 
@@ -148,6 +153,13 @@ func (s *Service) ShardGet_(c *rpc.Context) {
 		if wf, ok := w.(http.Flusher); ok {
 			wf.Flush()
 		}
+
+		// whole shard is copied to fill read cache
+		if useCache && rangeBytesStr == "" && shard.Flag == bnapi.ShardStatusNormal &&
+			s.ShardCache.Admit(args.Type, int64(shard.Size)) {
+			cached = bytes.NewBuffer(make([]byte, 0, shard.Size))
+			shard.Writer = io.MultiWriter(w, cached)
+		}
 	}
 
 	if rangeBytesStr != "" {
@@ -164,6 +176,10 @@ func (s *Service) ShardGet_(c *rpc.Context) {
 		}
 		return
 	}
+
+	if cached != nil {
+		s.ShardCache.Put(cacheKey, cacheEpoch, cached.Bytes(), shard.Crc, shard.ExpireAt)
+	}
 }
 
 /*
@@ -335,12 +351,13 @@ func (s *Service) ShardStat_(c *rpc.Context) {
 	}
 
 	stat := bnapi.ShardInfo{
-		Vuid:   args.Vuid,
-		Bid:    args.Bid,
-		Size:   int64(sm.Size),
-		Crc:    sm.Crc,
-		Flag:   sm.Flag,
-		Inline: sm.Inline,
+		Vuid:     args.Vuid,
+		Bid:      args.Bid,
+		Size:     int64(sm.Size),
+		Crc:      sm.Crc,
+		Flag:     sm.Flag,
+		Inline:   sm.Inline,
+		ExpireAt: sm.ExpireAt,
 	}
 	c.RespondJSON(stat)
 }
@@ -409,6 +426,7 @@ func (s *Service) ShardMarkdelete_(c *rpc.Context) {
 	ctx = limitio.SetLimitTrack(ctx)
 
 	err = cs.MarkDelete(ctx, args.Bid)
+	s.invalidateShardCache(args.Vuid, args.Bid)
 	if err != nil {
 		err = handlerBidNotFoundErr(err)
 		span.Errorf("Failed to mark delete, err:%v", err)
@@ -480,6 +498,7 @@ func (s *Service) ShardDelete_(c *rpc.Context) {
 	ctx = limitio.SetLimitTrack(ctx)
 
 	err = cs.Delete(ctx, args.Bid)
+	s.invalidateShardCache(args.Vuid, args.Bid)
 	if err != nil {
 		err = handlerBidNotFoundErr(err)
 		span.Errorf("Failed to delete, err:%v", err)
@@ -490,7 +509,7 @@ func (s *Service) ShardDelete_(c *rpc.Context) {
 
 /*
  *  method:         POST
- *  url:            /shard/put/diskid/{diskid}/vuid/{vuid}/bid/{bid}/size/{size}?iotype={iotype}
+ *  url:            /shard/put/diskid/{diskid}/vuid/{vuid}/bid/{bid}/size/{size}?iotype={iotype}&expire_at={expire_at}
  *  request body:   bidData
  */
 func (s *Service) ShardPut_(c *rpc.Context) {
@@ -522,7 +541,7 @@ func (s *Service) ShardPut_(c *rpc.Context) {
 		return
 	}
 
-	if !args.Type.IsValid() {
+	if !args.Type.IsValid() || args.ExpireAt < 0 {
 		c.RespondError(bloberr.ErrInvalidParam)
 		return
 	}
@@ -572,11 +591,13 @@ func (s *Service) ShardPut_(c *rpc.Context) {
 	}
 
 	shard := core.NewShardWriter(args.Bid, args.Vuid, uint32(args.Size), c.Request.Body)
+	shard.ExpireAt = args.ExpireAt
 
 	start = time.Now()
 
 	err = cs.Write(ctx, shard)
 	span.AppendTrackLog("disk.put", start, err)
+	s.invalidateShardCache(args.Vuid, args.Bid)
 	if err != nil {
 		span.Errorf("Failed to put shard, args: %+v, err: %v", args, err)
 		c.RespondError(err)
@@ -598,6 +619,204 @@ func (s *Service) ShardPut_(c *rpc.Context) {
 	c.RespondJSON(ret)
 }
 
+/*
+ *  method:         POST
+ *  url:            /shards/put/diskid/{diskid}/vuid/{vuid}?iotype={iotype}
+ *  request body:   [header][bidData]...[bidData]
+ *  response body:  json.Marshal(bnapi.PutShardsRet)
+ */
+func (s *Service) ShardsPut(c *rpc.Context) {
+	args := new(bnapi.PutShardsArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+
+	if !bnapi.IsValidDiskID(args.DiskID) {
+		c.RespondError(bloberr.ErrInvalidDiskId)
+		return
+	}
+
+	if !args.Type.IsValid() {
+		c.RespondError(bloberr.ErrInvalidParam)
+		return
+	}
+
+	items, err := bnapi.DecodePutShardsHeader(c.Request.Body)
+	if err != nil {
+		span.Errorf("Failed to decode header, args:%v err:%v", args, err)
+		c.RespondError(bloberr.ErrInvalidParam)
+		return
+	}
+
+	var size int64
+	bids := make(map[proto.BlobID]struct{}, len(items))
+	for _, item := range items {
+		if item.Bid == proto.InValidBlobID {
+			c.RespondError(bloberr.ErrShardInvalidBid)
+			return
+		}
+		if item.Size > bnapi.MaxShardSize {
+			span.Warnf("shard size too large, bid:%v size:%d", item.Bid, item.Size)
+			c.RespondError(bloberr.ErrShardSizeTooLarge)
+			return
+		}
+		if _, ok := bids[item.Bid]; ok {
+			span.Warnf("duplicated bid:%v in batch", item.Bid)
+			c.RespondError(bloberr.ErrInvalidParam)
+			return
+		}
+		bids[item.Bid] = struct{}{}
+		size += item.Size
+	}
+
+	// set io type
+	ctx = bnapi.Setiotype(ctx, args.Type)
+	ctx = limitio.SetLimitTrack(ctx)
+
+	s.lock.RLock()
+	ds, exist := s.Disks[args.DiskID]
+	s.lock.RUnlock()
+	if !exist {
+		c.RespondError(bloberr.ErrNoSuchDisk)
+		return
+	}
+
+	cs, exist := ds.GetChunkStorage(args.Vuid)
+	if !exist {
+		c.RespondError(bloberr.ErrNoSuchVuid)
+		return
+	}
+
+	err = cs.AllowModify()
+	if err != nil {
+		span.Errorf("cs status check Invalid. err: %v", err)
+		c.RespondError(err)
+		return
+	}
+
+	start := time.Now()
+
+	limitKey := cs.Disk().ID()
+	err = s.PutQpsLimitPerDisk.Acquire(limitKey)
+	span.AppendTrackLog("lk.disk", start, err)
+	if err != nil {
+		span.Errorf("shards put overload. args:%v err:%v", args, err)
+		c.RespondError(bloberr.ErrOverload)
+		return
+	}
+	defer s.PutQpsLimitPerDisk.Release(limitKey)
+
+	if !cs.HasEnoughSpace(size) {
+		span.Errorf("cs has no enougn space. args:%v, size:%d, chunk info:%v, disk:%v",
+			args, size, cs.ChunkInfo(ctx), cs.Disk().Stats())
+		c.RespondError(bloberr.ErrChunkNoSpace)
+		return
+	}
+
+	// shards are read from body in order
+	shards := make([]*core.Shard, 0, len(items))
+	for _, item := range items {
+		body := io.LimitReader(c.Request.Body, item.Size)
+		shard := core.NewShardWriter(item.Bid, args.Vuid, uint32(item.Size), body)
+		shard.ExpireAt = item.ExpireAt
+		shards = append(shards, shard)
+	}
+
+	start = time.Now()
+	errs := cs.WriteBatch(ctx, shards)
+	span.AppendTrackLog("disk.put", start, nil)
+	for _, shard := range shards {
+		s.invalidateShardCache(args.Vuid, shard.Bid)
+	}
+
+	needSync := false
+	for i, shard := range shards {
+		if errs[i] == nil && !shard.Inline {
+			needSync = true
+			break
+		}
+	}
+	if needSync {
+		start = time.Now()
+		err = cs.SyncData(ctx)
+		span.AppendTrackLog("sync", start, err)
+		if err != nil {
+			span.Errorf("Failed to sync shards, args: %+v, err: %v", args, err)
+			for i := range errs {
+				if errs[i] == nil {
+					errs[i] = err
+				}
+			}
+		}
+	}
+
+	ret := &bnapi.PutShardsRet{Shards: make([]bnapi.PutShardsRetItem, 0, len(shards))}
+	for i, shard := range shards {
+		item := bnapi.PutShardsRetItem{Bid: shard.Bid, Crc: shard.Crc, Code: http.StatusOK}
+		if errs[i] != nil {
+			span.Errorf("Failed to put shard, bid:%v err:%v", shard.Bid, errs[i])
+			item.Crc = proto.InvalidCrc32
+			item.Code = rpc.DetectStatusCode(errs[i])
+		}
+		ret.Shards = append(ret.Shards, item)
+	}
+
+	c.RespondJSON(ret)
+}
+
+func writeShardHeader(w http.ResponseWriter, crc uint32, size, from, to int64, ranged bool) {
+	// set crc to header
+	// build http response header
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Transfer-Encoding", "binary")
+	w.Header().Set("CRC", strconv.FormatUint(uint64(crc), 10))
+
+	if ranged {
+		bodySize := to - from
+		rangeResp := "bytes " + strconv.FormatInt(from, 10) + "-" + strconv.FormatInt(to-1, 10) + "/" + strconv.FormatInt(size, 10)
+		w.Header().Set("Content-Length", strconv.FormatInt(bodySize, 10))
+		w.Header().Set("Content-Range", rangeResp)
+		w.WriteHeader(206)
+	} else {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(200)
+	}
+}
+
+// respondCachedShard responds shard data in read cache, start and end is the http range
+func respondCachedShard(c *rpc.Context, data []byte, crc uint32, ranged bool, start, end int64) error {
+	size := int64(len(data))
+	from, to := int64(0), size
+	if ranged {
+		var err error
+		from, to, err = base.FixHttpRange(start, end, size)
+		if err != nil {
+			c.RespondError(err)
+			return err
+		}
+	}
+
+	writeShardHeader(c.Writer, crc, size, from, to, ranged)
+	_, err := c.Writer.Write(data[from:to])
+	return err
+}
+
+func (s *Service) invalidateShardCache(vuid proto.Vuid, bid proto.BlobID) {
+	if s.ShardCache != nil {
+		s.ShardCache.Invalidate(cache.Key{Vuid: vuid, Bid: bid})
+	}
+}
+
+func (s *Service) invalidateChunkCache(vuid proto.Vuid) {
+	if s.ShardCache != nil {
+		s.ShardCache.InvalidateVuid(vuid)
+	}
+}
+
 func handlerBidNotFoundErr(err error) error {
 	if os.IsNotExist(err) {
 		return bloberr.ErrNoSuchBid
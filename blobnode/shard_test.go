@@ -26,10 +26,12 @@ import (
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/blobnode/base/cache"
 	bloberr "github.com/cubefs/blobstore/common/errors"
 	"github.com/cubefs/blobstore/common/proto"
 	"github.com/cubefs/blobstore/util/limit/keycount"
@@ -223,6 +225,73 @@ func TestShardPutAndGet(t *testing.T) {
 	_, _ = client.PutShard(ctx, host, putShardArg)
 }
 
+func TestShardsPut(t *testing.T) {
+	service, _ := newTestBlobNodeService(t, "ShardsPut")
+	defer cleanTestBlobNodeService(service)
+
+	host := runTestServer(service)
+	client := bnapi.New(&bnapi.Config{})
+	ctx := context.TODO()
+
+	diskID := proto.DiskID(101)
+	vuid := proto.Vuid(2001)
+
+	datas := [][]byte{[]byte("shard-1"), make([]byte, 64*1024), []byte("shard-3")}
+	newArgs := func(bids ...proto.BlobID) *bnapi.PutShardsArgs {
+		args := &bnapi.PutShardsArgs{DiskID: diskID, Vuid: vuid}
+		for i, bid := range bids {
+			args.Shards = append(args.Shards, bnapi.PutShardsItem{
+				Bid:  bid,
+				Size: int64(len(datas[i])),
+				Body: bytes.NewReader(datas[i]),
+			})
+		}
+		return args
+	}
+
+	// no such vuid
+	_, err := client.PutShards(ctx, host, newArgs(1, 2, 3))
+	require.Error(t, err)
+
+	err = client.CreateChunk(ctx, host, &bnapi.CreateChunkArgs{DiskID: diskID, Vuid: vuid})
+	require.NoError(t, err)
+
+	// invalid or duplicated bid
+	_, err = client.PutShards(ctx, host, newArgs(1, proto.InValidBlobID))
+	require.Error(t, err)
+	_, err = client.PutShards(ctx, host, newArgs(1, 2, 1))
+	require.Error(t, err)
+
+	args := newArgs(1, 2, 3)
+	expireAt := time.Now().Add(time.Hour).Unix()
+	args.Shards[2].ExpireAt = expireAt
+	ret, err := client.PutShards(ctx, host, args)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(ret.Shards))
+	si, err := client.StatShard(ctx, host, &bnapi.StatShardArgs{DiskID: diskID, Vuid: vuid, Bid: 3})
+	require.NoError(t, err)
+	require.Equal(t, expireAt, si.ExpireAt)
+	for i, item := range ret.Shards {
+		require.Equal(t, proto.BlobID(i+1), item.Bid)
+		require.Equal(t, http.StatusOK, item.Code)
+		require.Equal(t, crc32.ChecksumIEEE(datas[i]), item.Crc)
+
+		body, crc, err := client.GetShard(ctx, host, &bnapi.GetShardArgs{DiskID: diskID, Vuid: vuid, Bid: item.Bid})
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(body)
+		body.Close()
+		require.NoError(t, err)
+		require.Equal(t, datas[i], data)
+		require.Equal(t, item.Crc, crc)
+	}
+
+	// readonly chunk
+	err = client.SetChunkReadonly(ctx, host, &bnapi.ChangeChunkStatusArgs{DiskID: diskID, Vuid: vuid})
+	require.NoError(t, err)
+	_, err = client.PutShards(ctx, host, newArgs(4))
+	require.Error(t, err)
+}
+
 func TestService_CmdShardStat_(t *testing.T) {
 	service, _ := newTestBlobNodeService(t, "TestService_CmdShardStat_")
 	defer cleanTestBlobNodeService(service)
@@ -955,6 +1024,108 @@ func TestShardRangeGet(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestShardGetWithCache(t *testing.T) {
+	service, _ := newTestBlobNodeService(t, "ShardGetWithCache")
+	defer cleanTestBlobNodeService(service)
+
+	var err error
+	service.ShardCache, err = cache.New(cache.Config{Enable: true, MemoryCapacityMB: 1})
+	require.NoError(t, err)
+
+	host := runTestServer(service)
+	client := bnapi.New(&bnapi.Config{})
+	ctx := context.TODO()
+
+	diskID := proto.DiskID(101)
+	vuid := proto.Vuid(2001)
+	bid := proto.BlobID(30001)
+	shardData := []byte("testData")
+	dataCrc := crc32.ChecksumIEEE(shardData)
+
+	err = client.CreateChunk(ctx, host, &bnapi.CreateChunkArgs{DiskID: diskID, Vuid: vuid})
+	require.NoError(t, err)
+	_, err = client.PutShard(ctx, host, &bnapi.PutShardArgs{
+		DiskID: diskID,
+		Vuid:   vuid,
+		Bid:    bid,
+		Size:   int64(len(shardData)),
+		Body:   bytes.NewReader(shardData),
+	})
+	require.NoError(t, err)
+
+	getShard := func(iot bnapi.IOType) {
+		body, crc, err := client.GetShard(ctx, host, &bnapi.GetShardArgs{DiskID: diskID, Vuid: vuid, Bid: bid, Type: iot})
+		require.NoError(t, err)
+		defer body.Close()
+		b, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, shardData, b)
+		require.Equal(t, dataCrc, crc)
+	}
+
+	// miss and fill, then hit
+	getShard(bnapi.NormalIO)
+	getShard(bnapi.NormalIO)
+	stat := service.ShardCache.Stat()
+	require.Equal(t, uint64(1), stat.Misses)
+	require.Equal(t, uint64(1), stat.Admitted)
+	require.Equal(t, uint64(1), stat.MemoryHits)
+
+	// range read from cache
+	body, _, err := client.RangeGetShard(ctx, host, &bnapi.RangeGetShardArgs{
+		GetShardArgs: bnapi.GetShardArgs{DiskID: diskID, Vuid: vuid, Bid: bid},
+		Offset:       4,
+		Size:         2,
+	})
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	require.Equal(t, []byte("Da"), b)
+	require.Equal(t, uint64(2), service.ShardCache.Stat().MemoryHits)
+
+	// io type not admitted
+	getShard(bnapi.BackgroundIO)
+	stat = service.ShardCache.Stat()
+	require.Equal(t, uint64(2), stat.MemoryHits)
+	require.Equal(t, uint64(1), stat.Misses)
+
+	// mark deleted shard is invalidated and not cached again
+	err = client.MarkDeleteShard(ctx, host, &bnapi.DeleteShardArgs{DiskID: diskID, Vuid: vuid, Bid: bid})
+	require.NoError(t, err)
+	getShard(bnapi.NormalIO)
+	stat = service.ShardCache.Stat()
+	require.Equal(t, uint64(1), stat.Invalidated)
+	require.Equal(t, uint64(2), stat.Misses)
+	require.Equal(t, uint64(1), stat.Admitted)
+	require.Equal(t, 0, stat.MemoryCount)
+
+	// shards of released chunk are dropped
+	bid2 := proto.BlobID(30002)
+	_, err = client.PutShard(ctx, host, &bnapi.PutShardArgs{
+		DiskID: diskID,
+		Vuid:   vuid,
+		Bid:    bid2,
+		Size:   int64(len(shardData)),
+		Body:   bytes.NewReader(shardData),
+	})
+	require.NoError(t, err)
+	body, _, err = client.GetShard(ctx, host, &bnapi.GetShardArgs{DiskID: diskID, Vuid: vuid, Bid: bid2, Type: bnapi.NormalIO})
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	require.Equal(t, 1, service.ShardCache.Stat().MemoryCount)
+
+	err = client.ReleaseChunk(ctx, host, &bnapi.ChangeChunkStatusArgs{DiskID: diskID, Vuid: vuid, Force: true})
+	require.NoError(t, err)
+	stat = service.ShardCache.Stat()
+	require.Equal(t, uint64(2), stat.Invalidated)
+	require.Equal(t, 0, stat.MemoryCount)
+	_, _, err = client.GetShard(ctx, host, &bnapi.GetShardArgs{DiskID: diskID, Vuid: vuid, Bid: bid2, Type: bnapi.NormalIO})
+	require.Error(t, err)
+}
+
 func TestShardGetConcurrency(t *testing.T) {
 	service, _ := newTestBlobNodeService(t, "ShardGetCon")
 	defer cleanTestBlobNodeService(service)
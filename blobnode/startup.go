@@ -23,6 +23,8 @@ import (
 
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
 	cmapi "github.com/cubefs/blobstore/api/clustermgr"
+	mqapi "github.com/cubefs/blobstore/api/mqproxy"
+	"github.com/cubefs/blobstore/blobnode/base/cache"
 	"github.com/cubefs/blobstore/blobnode/base/flow"
 	"github.com/cubefs/blobstore/blobnode/core"
 	"github.com/cubefs/blobstore/blobnode/core/disk"
@@ -164,11 +166,19 @@ func (s *Service) waitRepairAndClose(ctx context.Context, disk core.DiskAPI) {
 	}
 
 	// after the repair is triggered, the handle can be safely removed
+	s.detachDisk(ctx, disk)
+}
+
+func (s *Service) detachDisk(ctx context.Context, disk core.DiskAPI) {
+	span := trace.SpanFromContextSafe(ctx)
 
+	diskId := disk.ID()
 	span.Infof("Delete %v from the map table of the service", diskId)
 
 	s.lock.Lock()
-	delete(s.Disks, disk.ID())
+	if cur, ok := s.Disks[diskId]; ok && cur == disk {
+		delete(s.Disks, diskId)
+	}
 	s.lock.Unlock()
 
 	disk.ResetChunks(ctx)
@@ -185,10 +195,27 @@ func setDefaultIOStat(dryRun bool) error {
 	return nil
 }
 
+func (s *Service) notifyShardRepair(ctx context.Context, vuid proto.Vuid, bid proto.BlobID, reason string) error {
+	span := trace.SpanFromContextSafe(ctx)
+
+	args := &mqapi.ShardRepairArgs{
+		ClusterID: s.Conf.ClusterID,
+		Bid:       bid,
+		Vid:       vuid.Vid(),
+		BadIdxes:  []uint8{vuid.Index()},
+		Reason:    reason,
+	}
+	span.Infof("send shard repair message: %+v", args)
+
+	return s.MQProxyClient.SendShardRepairMsg(ctx, args)
+}
+
 func (s *Service) fixDiskConf(config *core.Config) {
 	config.AllocDiskID = s.ClusterMgrClient.AllocDiskID
 	config.NotifyCompacting = s.ClusterMgrClient.SetCompactChunk
 	config.HandleIOError = s.handleDiskIOError
+	config.NotifyShardRepair = s.notifyShardRepair
+	config.NotifyChunkMigrated = s.ClusterMgrClient.UpdateVolume
 
 	// init configs
 	config.RuntimeConfig = s.Conf.DiskConfig
@@ -219,8 +246,18 @@ func NewService(conf Config) (svr *Service, err error) {
 	}
 	span.Infof("registered disks are all in config")
 
+	var mqproxyCli mqapi.LbMsgSender
+	if conf.DiskConfig.EnableScrub {
+		mqproxyCli, err = mqapi.NewLbClient(&conf.MQProxy, clusterMgrCli, conf.ClusterID)
+		if err != nil {
+			span.Errorf("Failed new mqproxy client. err:%v", err)
+			return nil, err
+		}
+	}
+
 	svr = &Service{
 		ClusterMgrClient: clusterMgrCli,
+		MQProxyClient:    mqproxyCli,
 		Disks:            make(map[proto.DiskID]core.DiskAPI),
 		Conf:             &conf,
 
@@ -236,6 +273,14 @@ func NewService(conf Config) (svr *Service, err error) {
 		closeCh: make(chan struct{}),
 	}
 
+	if conf.ShardCache.Enable {
+		svr.ShardCache, err = cache.New(conf.ShardCache)
+		if err != nil {
+			span.Errorf("Failed new shard cache. err:%v", err)
+			return nil, err
+		}
+	}
+
 	svr.ctx, svr.cancel = context.WithCancel(context.Background())
 
 	wg := sync.WaitGroup{}
@@ -23,6 +23,9 @@ import (
 
 	bnapi "github.com/cubefs/blobstore/api/blobnode"
 	cmapi "github.com/cubefs/blobstore/api/clustermgr"
+	mqapi "github.com/cubefs/blobstore/api/mqproxy"
+	"github.com/cubefs/blobstore/blobnode/base/cache"
+	"github.com/cubefs/blobstore/blobnode/base/qos"
 	"github.com/cubefs/blobstore/blobnode/core"
 	bloberr "github.com/cubefs/blobstore/common/errors"
 	"github.com/cubefs/blobstore/common/proto"
@@ -43,6 +46,7 @@ type Service struct {
 
 	// client handler
 	ClusterMgrClient *cmapi.Client
+	MQProxyClient    mqapi.LbMsgSender
 	groupRun         singleflight.Group
 
 	Conf *Config
@@ -56,6 +60,9 @@ type Service struct {
 	ChunkLimitPerVuid     limit.Limiter
 	DiskLimitPerKey       limit.Limiter
 
+	// ShardCache is nil if read cache is disabled
+	ShardCache *cache.ShardCache
+
 	RequestCount int64
 
 	// ctx is used for initiated requests that
@@ -114,6 +121,10 @@ func (s *Service) Close() {
 		}
 	}
 
+	if s.ShardCache != nil {
+		s.ShardCache.Close()
+	}
+
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -159,7 +170,7 @@ func (s *Service) Stat(c *rpc.Context) {
 /*
  *  method:         GET
  *  url:            /debug/stat
- *  response body:  json.Marshal([]DiskInfo)
+ *  response body:  json.Marshal(map[string]interface{})
  */
 func (s *Service) DebugStat(c *rpc.Context) {
 	ctx := c.Request.Context()
@@ -169,15 +180,28 @@ func (s *Service) DebugStat(c *rpc.Context) {
 
 	disks := s.copyDiskStorages(ctx)
 	chunks := make([]core.ChunkAPI, 0)
+	schedulers := make(map[proto.DiskID]*qos.SchedulerStat)
+	latencies := make(map[proto.DiskID]*qos.LatencyStat)
 	for _, ds := range disks {
 		_ = ds.WalkChunksWithLock(ctx, func(cs core.ChunkAPI) (err error) {
 			chunks = append(chunks, cs)
 			return nil
 		})
+		if stat := ds.GetIoQos().SchedulerStat(); stat != nil {
+			schedulers[ds.ID()] = stat
+		}
+		if stat := ds.GetIoQos().LatencyStat(); stat != nil {
+			latencies[ds.ID()] = stat
+		}
 	}
 
 	ret := make(map[string]interface{})
 	ret["chunks"] = chunks
+	ret["io_schedulers"] = schedulers
+	ret["io_latencies"] = latencies
+	if s.ShardCache != nil {
+		ret["shard_cache"] = s.ShardCache.Stat()
+	}
 	c.RespondJSON(ret)
 }
 
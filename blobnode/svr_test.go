@@ -479,8 +479,9 @@ type mockDiskInfo struct {
 var _mockDiskIdBase = int64(100)
 
 type mockClusterMgr struct {
-	reqIdx int64
-	disks  []mockDiskInfo
+	reqIdx  int64
+	disks   []mockDiskInfo
+	updates []cmapi.UpdateVolumeArgs
 }
 
 func mockClusterMgrRouter(service *mockClusterMgr) *rpc.Router {
@@ -492,6 +493,7 @@ func mockClusterMgrRouter(service *mockClusterMgr) *rpc.Router {
 	rpc.RegisterArgsParser(&cmapi.DiskSetArgs{}, "json")
 	rpc.RegisterArgsParser(&cmapi.ReportChunkArgs{}, "json")
 	rpc.RegisterArgsParser(&cmapi.GetVolumeArgs{}, "json")
+	rpc.RegisterArgsParser(&cmapi.UpdateVolumeArgs{}, "json")
 
 	r.Handle(http.MethodGet, "/disk/list", service.DiskList, rpc.OptArgsQuery())
 	r.Handle(http.MethodGet, "/volume/unit/list", service.VolumeUnitList, rpc.OptArgsQuery())
@@ -502,6 +504,7 @@ func mockClusterMgrRouter(service *mockClusterMgr) *rpc.Router {
 	r.Handle(http.MethodPost, "/disk/set", service.DiskSet, rpc.OptArgsBody())
 	r.Handle(http.MethodPost, "/chunk/report", service.ChunkReport, rpc.OptArgsBody())
 	r.Handle(http.MethodGet, "/volume/get", service.VolumeGet, rpc.OptArgsQuery())
+	r.Handle(http.MethodPost, "/volume/update", service.VolumeUpdate, rpc.OptArgsBody())
 	return r
 }
 
@@ -570,6 +573,12 @@ func (mcm *mockClusterMgr) DiskInfo(c *rpc.Context) {
 	}
 	ret := &bnapi.DiskInfo{}
 	ret.DiskID = args.DiskID
+	for _, d := range mcm.disks {
+		if d.diskId == args.DiskID {
+			ret.Path = d.path
+			ret.Status = d.status
+		}
+	}
 	c.RespondJSON(ret)
 }
 
@@ -612,6 +621,15 @@ func (mcm *mockClusterMgr) DiskSet(c *rpc.Context) {
 func (mcm *mockClusterMgr) ChunkReport(c *rpc.Context) {
 }
 
+func (mcm *mockClusterMgr) VolumeUpdate(c *rpc.Context) {
+	args := new(cmapi.UpdateVolumeArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(bloberr.ErrIllegalArguments)
+		return
+	}
+	mcm.updates = append(mcm.updates, *args)
+}
+
 func (mcm *mockClusterMgr) VolumeGet(c *rpc.Context) {
 	args := new(cmapi.GetVolumeArgs)
 	if err := c.ParseArgs(args); err != nil {
@@ -0,0 +1,149 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clustermgr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/desertbit/grumble"
+
+	"github.com/cubefs/blobstore/cli/common"
+	"github.com/cubefs/blobstore/clustermgr/base"
+	"github.com/cubefs/blobstore/common/rpc"
+)
+
+func addCmdSnapshot(cmd *grumble.Command) {
+	command := &grumble.Command{
+		Name:     "snapshot",
+		Help:     "snapshot tools",
+		LongHelp: "snapshot backup and restore tools for clustermgr",
+	}
+	cmd.AddCommand(command)
+
+	command.AddCommand(&grumble.Command{
+		Name: "backup",
+		Help: "backup snapshot of clustermgr into local file",
+		Run:  cmdSnapshotBackup,
+		Args: func(a *grumble.Args) {
+			a.String("out", "snapshot backup output filename")
+		},
+		Flags: func(f *grumble.Flags) {
+			clusterFlags(f)
+		},
+	})
+	command.AddCommand(&grumble.Command{
+		Name: "restore",
+		Help: "restore snapshot backup into empty db",
+		LongHelp: "restore snapshot backup into empty normal db and volume db of clustermgr, " +
+			"restore the same backup into every node of a brand-new raft group, " +
+			"and start the nodes with empty raft db and raft wal",
+		Run: cmdSnapshotRestore,
+		Args: func(a *grumble.Args) {
+			a.String("filename", "snapshot backup filename")
+			a.String("normalDBPath", "normal db path")
+			a.String("volumeDBPath", "volume db path")
+		},
+	})
+}
+
+func cmdSnapshotBackup(c *grumble.Context) error {
+	out := c.Args.String("out")
+	if out == "" {
+		return errors.New("snapshot backup output filename can't be null")
+	}
+	cli, ctx := newCMClient(c.Flags.String("secret"), specificHosts(c.Flags)...), common.CmdContext()
+
+	resp, err := cli.SnapshotBackup(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return rpc.ParseResponseErr(resp)
+	}
+
+	tmpFile := out + ".tmp"
+	f, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile)
+	if _, err = io.Copy(f, resp.Body); err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	// verify the whole backup before it can be used
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	header, err := base.VerifySnapshotBackup(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFile, out); err != nil {
+		return err
+	}
+
+	fmt.Println("backup snapshot into", out)
+	fmt.Println(common.Readable(header))
+	return nil
+}
+
+func cmdSnapshotRestore(c *grumble.Context) error {
+	filename := c.Args.String("filename")
+	normalDBPath := c.Args.String("normalDBPath")
+	volumeDBPath := c.Args.String("volumeDBPath")
+	if filename == "" || normalDBPath == "" || volumeDBPath == "" {
+		return errors.New("invalid command arguments")
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	normalDB, err := openNormalDB(normalDBPath, false)
+	if err != nil {
+		return err
+	}
+	defer normalDB.Close()
+	volumeDB, err := openVolumeDB(volumeDBPath, false)
+	if err != nil {
+		return err
+	}
+	defer volumeDB.Close()
+
+	header, err := base.RestoreSnapshotBackup(common.CmdContext(), f, map[string]base.SnapshotDB{
+		base.NormalDBName: normalDB,
+		base.VolumeDBName: volumeDB,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("restore snapshot from", filename)
+	fmt.Println(common.Readable(header))
+	return nil
+}
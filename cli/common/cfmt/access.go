@@ -63,7 +63,32 @@ func LocationF(loc *access.Location) (vals []string) {
 			idx, blob.MinBid, blob.Vid, blob.Count))
 	}
 	vals = append(vals, "]")
-	vals = append(vals, fmt.Sprintf("--> Encode: %d of %d bytes", len(loc.Encode()), 21+16*len(loc.Blobs)))
+	if loc.Version >= access.LocationVersion2 {
+		vals = append(vals, fmt.Sprintf("Version    : %d", loc.Version))
+		vals = append(vals, fmt.Sprintf("BlobCrcs: (%d) %v", len(loc.BlobCrcs), loc.BlobCrcs))
+		if len(loc.HashSumMap) > 0 {
+			vals = append(vals, "HashSumMap: [")
+			for _, line := range HashSumMapF(loc.HashSumMap) {
+				vals = append(vals, " >: "+line)
+			}
+			vals = append(vals, "]")
+		}
+		if c := loc.Compression; c != nil {
+			vals = append(vals, fmt.Sprintf("Compression: %s RawSize: %d (%s) BlockSize: %d (%s) Blocks: %d",
+				c.Codec, c.RawSize, humanize.IBytes(c.RawSize),
+				c.BlockSize, humanize.IBytes(uint64(c.BlockSize)), len(c.Blocks)))
+		}
+		if e := loc.Encryption; e != nil {
+			vals = append(vals, fmt.Sprintf("Encryption: KeyID: %d WrappedKey: %x IV: %x", e.KeyID, e.WrappedKey, e.IV))
+		}
+		if loc.Dedup {
+			vals = append(vals, "Dedup: true")
+		}
+		if loc.Tenant != "" {
+			vals = append(vals, fmt.Sprintf("Tenant: %s", loc.Tenant))
+		}
+	}
+	vals = append(vals, fmt.Sprintf("--> Encode: %d of %d bytes", len(loc.Encode()), loc.EncodeSize()))
 	vals = append(vals, fmt.Sprintf("--> Hex   : %s", loc.HexString()))
 	vals = append(vals, fmt.Sprintf("--> Base64: %s", loc.Base64String()))
 	return
@@ -87,6 +87,19 @@ func TestLocation(t *testing.T) {
 	printLine()
 	fmt.Println(cfmt.LocationJoin(nil, "\t--> "))
 	printLine()
+
+	loc.Version = access.LocationVersion2
+	loc.BlobCrcs = []uint32{1, 2, 3}
+	loc.HashSumMap = access.HashSumMap{access.HashAlgCRC32: []byte{0, 0, 0, 1}}
+	fmt.Println(cfmt.LocationJoin(&loc, "\t-->\t"))
+	printLine()
+
+	loc.Compression = &access.Compression{Codec: access.CompressZstd, RawSize: 1 << 20, BlockSize: 1 << 18, Blocks: []uint32{1, 2, 3, 4}}
+	loc.Encryption = &access.Encryption{KeyID: 1, WrappedKey: []byte{1, 2, 3}, IV: []byte{4, 5, 6}}
+	loc.Dedup = true
+	loc.Tenant = "tenant-a"
+	fmt.Println(cfmt.LocationJoin(&loc, "\t-->\t"))
+	printLine()
 }
 
 func TestHashSumMap(t *testing.T) {
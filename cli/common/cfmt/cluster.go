@@ -66,11 +66,12 @@ func ClusterInfoF(info *clustermgr.ClusterInfo) []string {
 		return []string{" <nil> "}
 	}
 	avaiC := common.ColorizeInt64(-info.Available, info.Capacity)
-	vals := make([]string, 0, 8)
+	vals := make([]string, 0, 9)
 	vals = append(vals, []string{
 		fmt.Sprintf("Region    : %s", info.Region),
 		fmt.Sprintf("ClusterID : %d", info.ClusterID),
 		fmt.Sprintf("Readonly  : %v", info.Readonly),
+		fmt.Sprintf("Weight    : %d", info.Weight),
 		fmt.Sprintf("Capacity  : %-16d (%s)", info.Capacity, humanize.IBytes(uint64(info.Capacity))),
 		fmt.Sprintf("Available : %-16d (%s)", info.Available, avaiC.Sprint(humanize.IBytes(uint64(info.Available)))),
 		fmt.Sprintf("Nodes: (%d) [", len(info.Nodes)),
@@ -63,6 +63,7 @@ func TestClusterInfo(t *testing.T) {
 		Available: 1 << 38,
 		Readonly:  false,
 		Nodes:     []string{"node-1", "node-2", "node-xxx"},
+		Weight:    100,
 	}
 	printLine()
 	for _, line := range cfmt.ClusterInfoF(&val) {
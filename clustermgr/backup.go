@@ -0,0 +1,143 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clustermgr
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cubefs/blobstore/common/rpc"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/util/errors"
+)
+
+const (
+	snapshotBackupFilePrefix = "snapshot-"
+	snapshotBackupFileSuffix = ".bak"
+)
+
+// SnapshotBackupConfig config of scheduled snapshot backup, backup is disabled when dir is empty
+type SnapshotBackupConfig struct {
+	Dir        string `json:"dir"`
+	IntervalM  int    `json:"interval_m"`
+	ReserveNum int    `json:"reserve_num"`
+}
+
+// SnapshotBackup will write a self-contained snapshot backup of all dbs,
+// which can be used to restore a brand-new clustermgr raft group
+func (s *Service) SnapshotBackup(c *rpc.Context) {
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+	span.Info("accept SnapshotBackup request")
+
+	c.Writer.Header().Set(rpc.HeaderContentType, rpc.MIMEStream)
+	header, err := s.raftNode.CreateSnapshotBackup(ctx, c.Writer, s.dbs, s.RaftConfig.SnapshotPatchNum)
+	if err != nil {
+		span.Errorf("write snapshot backup failed: %s", errors.Detail(err))
+		return
+	}
+	span.Infof("write snapshot backup[%s] success, apply index: %d", header.Name, header.ApplyIndex)
+}
+
+// backupSnapshotToFile write snapshot backup into backup dir, and remove the expired backup files
+func (s *Service) backupSnapshotToFile(ctx context.Context) (string, error) {
+	span := trace.SpanFromContextSafe(ctx)
+	dir := s.SnapshotBackupConfig.Dir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(dir, ".tmp-"+snapshotBackupFilePrefix)
+	if err != nil {
+		return "", err
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	header, err := s.raftNode.CreateSnapshotBackup(ctx, f, s.dbs, s.RaftConfig.SnapshotPatchNum)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s%d-%d%s", snapshotBackupFilePrefix, header.ApplyIndex, header.CreateTime, snapshotBackupFileSuffix))
+	if err = os.Rename(tmpPath, path); err != nil {
+		return "", err
+	}
+	span.Infof("backup snapshot into %s success", path)
+
+	if err = s.removeExpiredSnapshotBackups(); err != nil {
+		span.Warnf("remove expired snapshot backups failed: %s", err.Error())
+	}
+	return path, nil
+}
+
+// removeExpiredSnapshotBackups keep the latest ReserveNum backup files
+func (s *Service) removeExpiredSnapshotBackups() error {
+	fis, err := ioutil.ReadDir(s.SnapshotBackupConfig.Dir)
+	if err != nil {
+		return err
+	}
+	backups := make([]os.FileInfo, 0, len(fis))
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasPrefix(fi.Name(), snapshotBackupFilePrefix) || !strings.HasSuffix(fi.Name(), snapshotBackupFileSuffix) {
+			continue
+		}
+		backups = append(backups, fi)
+	}
+	if len(backups) <= s.SnapshotBackupConfig.ReserveNum {
+		return nil
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime().After(backups[j].ModTime())
+	})
+	for _, fi := range backups[s.SnapshotBackupConfig.ReserveNum:] {
+		if err = os.Remove(filepath.Join(s.SnapshotBackupConfig.Dir, fi.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) snapshotBackupLoop() {
+	ticker := time.NewTicker(time.Duration(s.SnapshotBackupConfig.IntervalM) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if atomic.LoadUint32(&s.status) != ServiceStatusNormal {
+				continue
+			}
+			span, ctx := trace.StartSpanFromContext(context.Background(), "snapshot-backup")
+			if _, err := s.backupSnapshotToFile(ctx); err != nil {
+				span.Errorf("backup snapshot failed: %s", errors.Detail(err))
+			}
+		case <-s.closeCh:
+			return
+		}
+	}
+}
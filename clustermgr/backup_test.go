@@ -0,0 +1,68 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package clustermgr
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cubefs/blobstore/clustermgr/base"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+func TestSnapshotBackup(t *testing.T) {
+	testService := initTestService(t)
+	defer clear(testService)
+	defer testService.Close()
+	testClusterClient := initTestClusterClient(testService)
+	_, ctx := trace.StartSpanFromContext(context.Background(), "")
+
+	// backup from api
+	resp, err := testClusterClient.SnapshotBackup(ctx)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	header, err := base.VerifySnapshotBackup(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(header.DBNames))
+
+	// backup into file and keep the latest backups
+	testService.SnapshotBackupConfig = SnapshotBackupConfig{
+		Dir:        "/tmp/tmpsvrsnapshotbackup-" + strconv.Itoa(rand.Intn(10000000)),
+		ReserveNum: 1,
+	}
+	defer os.RemoveAll(testService.SnapshotBackupConfig.Dir)
+	for i := 0; i < 2; i++ {
+		path, err := testService.backupSnapshotToFile(ctx)
+		assert.NoError(t, err)
+		f, err := os.Open(path)
+		assert.NoError(t, err)
+		_, err = base.VerifySnapshotBackup(f)
+		f.Close()
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, ioutil.WriteFile(testService.SnapshotBackupConfig.Dir+"/snapshot-0-0.bak", nil, 0o644))
+	assert.NoError(t, testService.removeExpiredSnapshotBackups())
+	fis, err := ioutil.ReadDir(testService.SnapshotBackupConfig.Dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fis))
+}
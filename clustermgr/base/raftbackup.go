@@ -0,0 +1,267 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package base
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/cubefs/blobstore/common/raftserver"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/util/errors"
+)
+
+// snapshot backup file layout:
+// | magic | header frame | data frame ... | end frame |
+// frame: | data length(uint32) | data | crc32 of data(uint32) |, end frame is a frame with zero length
+const snapshotBackupMagic = "CMSNAPBK"
+
+// maxBackupFrameSize is far larger than a snapshot patch of clustermgr,
+// frames larger than it are regarded as corrupted rather than allocated
+const maxBackupFrameSize = 64 << 20
+
+var (
+	ErrInvalidSnapshotBackup  = errors.New("invalid snapshot backup")
+	ErrSnapshotBackupChecksum = errors.New("snapshot backup checksum mismatch")
+	ErrRestoreDBNotEmpty      = errors.New("restore db is not empty")
+)
+
+// SnapshotBackupHeader is the meta of snapshot backup file
+type SnapshotBackupHeader struct {
+	Name       string       `json:"name"`
+	ApplyIndex uint64       `json:"apply_index"`
+	Members    []RaftMember `json:"members"`
+	DBNames    []string     `json:"db_names"`
+	CreateTime int64        `json:"create_time"`
+}
+
+// CreateSnapshotBackup write a self-contained snapshot of all dbs into w,
+// which contains apply index and raft members of current node
+func (r *RaftNode) CreateSnapshotBackup(ctx context.Context, w io.Writer, dbs map[string]SnapshotDB, patchNum int) (*SnapshotBackupHeader, error) {
+	members, err := r.GetRaftMembers(ctx)
+	if err != nil {
+		return nil, errors.Info(err, "get raft members failed").Detail(err)
+	}
+	snapshot := r.CreateRaftSnapshot(dbs, patchNum)
+	defer snapshot.Close()
+
+	header := &SnapshotBackupHeader{
+		Name:       snapshot.Name(),
+		ApplyIndex: snapshot.Index(),
+		Members:    members,
+		CreateTime: time.Now().Unix(),
+	}
+	for dbName := range dbs {
+		header.DBNames = append(header.DBNames, dbName)
+	}
+	if err = WriteSnapshotBackup(w, header, snapshot); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// WriteSnapshotBackup write header and all data of snapshot into w
+func WriteSnapshotBackup(w io.Writer, header *SnapshotBackupHeader, st raftserver.Snapshot) error {
+	if _, err := w.Write([]byte(snapshotBackupMagic)); err != nil {
+		return err
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	if err = writeBackupFrame(w, data); err != nil {
+		return err
+	}
+	for data, err = st.Read(); err == nil; data, err = st.Read() {
+		if len(data) == 0 {
+			continue
+		}
+		if err = writeBackupFrame(w, data); err != nil {
+			return err
+		}
+	}
+	if err != io.EOF {
+		return err
+	}
+	return writeBackupFrame(w, nil)
+}
+
+// ReadSnapshotBackup read header of snapshot backup, and return the snapshot which read data of backup from r
+func ReadSnapshotBackup(r io.Reader) (*SnapshotBackupHeader, raftserver.Snapshot, error) {
+	magic := make([]byte, len(snapshotBackupMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, nil, errors.Info(ErrInvalidSnapshotBackup, "read magic failed").Detail(err)
+	}
+	if string(magic) != snapshotBackupMagic {
+		return nil, nil, ErrInvalidSnapshotBackup
+	}
+	data, err := readBackupFrame(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil, ErrInvalidSnapshotBackup
+	}
+	header := &SnapshotBackupHeader{}
+	if err = json.Unmarshal(data, header); err != nil {
+		return nil, nil, errors.Info(ErrInvalidSnapshotBackup, "decode header failed").Detail(err)
+	}
+	return header, &backupSnapshot{header: header, reader: r}, nil
+}
+
+// VerifySnapshotBackup read all data of snapshot backup to check its integrity
+func VerifySnapshotBackup(r io.Reader) (*SnapshotBackupHeader, error) {
+	header, snapshot, err := ReadSnapshotBackup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Close()
+
+	for _, err = snapshot.Read(); err == nil; _, err = snapshot.Read() {
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+	return header, nil
+}
+
+// RestoreSnapshotBackup put all data of snapshot backup into empty dbs,
+// apply index and raft members of backup are returned in header but not restored,
+// so that the dbs can be used to bootstrap a brand-new raft group
+func RestoreSnapshotBackup(ctx context.Context, r io.Reader, dbs map[string]SnapshotDB) (*SnapshotBackupHeader, error) {
+	span := trace.SpanFromContextSafe(ctx)
+	header, snapshot, err := ReadSnapshotBackup(r)
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Close()
+
+	for _, dbName := range header.DBNames {
+		db, ok := dbs[dbName]
+		if !ok {
+			return nil, errors.Info(ErrInvalidSnapshotBackup, fmt.Sprintf("db[%s] not found", dbName)).Detail(ErrInvalidSnapshotBackup)
+		}
+		if !isEmptySnapshotDB(db) {
+			return nil, errors.Info(ErrRestoreDBNotEmpty, fmt.Sprintf("db[%s]", dbName)).Detail(ErrRestoreDBNotEmpty)
+		}
+	}
+	if err = putSnapshotData(ctx, dbs, snapshot); err != nil {
+		return nil, err
+	}
+	span.Infof("restore snapshot backup[%s] success, apply index: %d", header.Name, header.ApplyIndex)
+	return header, nil
+}
+
+type backupSnapshot struct {
+	header *SnapshotBackupHeader
+	reader io.Reader
+	done   bool
+}
+
+func (b *backupSnapshot) Read() ([]byte, error) {
+	if b.done {
+		return nil, io.EOF
+	}
+	data, err := readBackupFrame(b.reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		b.done = true
+		return nil, io.EOF
+	}
+	return data, nil
+}
+
+func (b *backupSnapshot) Name() string {
+	return b.header.Name
+}
+
+func (b *backupSnapshot) Index() uint64 {
+	return b.header.ApplyIndex
+}
+
+func (b *backupSnapshot) Close() {}
+
+func writeBackupFrame(w io.Writer, data []byte) error {
+	if len(data) > maxBackupFrameSize {
+		return errors.Info(ErrInvalidSnapshotBackup, fmt.Sprintf("frame size %d exceeds limit", len(data))).Detail(ErrInvalidSnapshotBackup)
+	}
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(buf, crc32.ChecksumIEEE(data))
+	_, err := w.Write(buf)
+	return err
+}
+
+// readBackupFrame return empty data when read end frame, backup which ends without end frame is truncated
+func readBackupFrame(r io.Reader) ([]byte, error) {
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, errors.Info(ErrInvalidSnapshotBackup, "read frame length failed").Detail(err)
+	}
+	size := binary.BigEndian.Uint32(buf)
+	if size == 0 {
+		return nil, nil
+	}
+	if size > maxBackupFrameSize {
+		return nil, errors.Info(ErrInvalidSnapshotBackup, fmt.Sprintf("frame size %d exceeds limit", size)).Detail(ErrInvalidSnapshotBackup)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, errors.Info(ErrInvalidSnapshotBackup, "read frame data failed").Detail(err)
+	}
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, errors.Info(ErrInvalidSnapshotBackup, "read frame checksum failed").Detail(err)
+	}
+	if binary.BigEndian.Uint32(buf) != crc32.ChecksumIEEE(data) {
+		return nil, ErrSnapshotBackupChecksum
+	}
+	return data, nil
+}
+
+func isEmptySnapshotDB(db SnapshotDB) bool {
+	cfs := db.GetAllCfNames()
+	if len(cfs) == 0 {
+		iter := db.NewIterator(nil)
+		defer iter.Close()
+		iter.SeekToFirst()
+		return !iter.Valid()
+	}
+	for _, cf := range cfs {
+		iter := db.Table(cf).NewIterator(nil)
+		iter.SeekToFirst()
+		valid := iter.Valid()
+		iter.Close()
+		if valid {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package base
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cubefs/blobstore/clustermgr/persistence/normaldb"
+	"github.com/cubefs/blobstore/clustermgr/persistence/raftdb"
+	"github.com/cubefs/blobstore/common/kvstore"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/util/errors"
+)
+
+func openTestNormalDB(t *testing.T) (*normaldb.NormalDB, func()) {
+	path := "/tmp/tmpbackupnormaldb" + strconv.Itoa(rand.Intn(1000000000))
+	db, err := normaldb.OpenNormalDB(path, false, &kvstore.RocksDBOption{ReadOnly: false})
+	assert.NoError(t, err)
+	return db, func() {
+		db.Close()
+		os.RemoveAll(path)
+	}
+}
+
+func TestSnapshotBackup(t *testing.T) {
+	_, ctx := trace.StartSpanFromContext(context.Background(), "")
+
+	normalDB, closeNormalDB := openTestNormalDB(t)
+	defer closeNormalDB()
+	scopeTbl, err := normaldb.OpenScopeTable(normalDB)
+	assert.NoError(t, err)
+	assert.NoError(t, scopeTbl.Put("testName", uint64(10)))
+	diskDropTbl, err := normaldb.OpenDroppedDiskTable(normalDB)
+	assert.NoError(t, err)
+	assert.NoError(t, diskDropTbl.AddDroppingDisk(proto.DiskID(1)))
+
+	tmpDBPath := "/tmp/tmpbackupraftdb" + strconv.Itoa(rand.Intn(1000000000))
+	os.MkdirAll(tmpDBPath, 0o755)
+	defer os.RemoveAll(tmpDBPath)
+	raftDB, err := raftdb.OpenRaftDB(tmpDBPath, false, &kvstore.RocksDBOption{ReadOnly: false})
+	assert.NoError(t, err)
+	defer raftDB.Close()
+	raftNode, err := NewRaftNode(&RaftNodeConfig{FlushNumInterval: 1, TruncateNumInterval: 1, ApplyIndex: 5}, raftDB)
+	assert.NoError(t, err)
+	member := RaftMember{ID: 1, Host: "127.0.0.1:10110"}
+	assert.NoError(t, raftNode.RecordRaftMember(ctx, member, false))
+
+	dbs := map[string]SnapshotDB{NormalDBName: normalDB}
+	buf := bytes.NewBuffer(nil)
+	header, err := raftNode.CreateSnapshotBackup(ctx, buf, dbs, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), header.ApplyIndex)
+	assert.Equal(t, []RaftMember{member}, header.Members)
+	assert.Equal(t, []string{NormalDBName}, header.DBNames)
+	data := buf.Bytes()
+
+	verifyHeader, err := VerifySnapshotBackup(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, header, verifyHeader)
+
+	// restore into empty db
+	restoreDB, closeRestoreDB := openTestNormalDB(t)
+	defer closeRestoreDB()
+	restoreHeader, err := RestoreSnapshotBackup(ctx, bytes.NewReader(data), map[string]SnapshotDB{NormalDBName: restoreDB})
+	assert.NoError(t, err)
+	assert.Equal(t, header, restoreHeader)
+	restoreScopeTbl, err := normaldb.OpenScopeTable(restoreDB)
+	assert.NoError(t, err)
+	commit, err := restoreScopeTbl.Get("testName")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), commit)
+	restoreDiskDropTbl, err := normaldb.OpenDroppedDiskTable(restoreDB)
+	assert.NoError(t, err)
+	dropping, err := restoreDiskDropTbl.IsDroppingDisk(proto.DiskID(1))
+	assert.NoError(t, err)
+	assert.True(t, dropping)
+
+	// restore into db which has data already
+	_, err = RestoreSnapshotBackup(ctx, bytes.NewReader(data), map[string]SnapshotDB{NormalDBName: restoreDB})
+	assert.Equal(t, ErrRestoreDBNotEmpty, errors.Cause(err))
+	// db of backup not found
+	_, err = RestoreSnapshotBackup(ctx, bytes.NewReader(data), map[string]SnapshotDB{VolumeDBName: restoreDB})
+	assert.Equal(t, ErrInvalidSnapshotBackup, errors.Cause(err))
+
+	// truncated backup
+	_, err = VerifySnapshotBackup(bytes.NewReader(data[:len(data)-1]))
+	assert.Equal(t, ErrInvalidSnapshotBackup, errors.Cause(err))
+	// corrupted backup
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)-10] ^= 0xff
+	_, err = VerifySnapshotBackup(bytes.NewReader(corrupted))
+	assert.Equal(t, ErrSnapshotBackupChecksum, errors.Cause(err))
+	// invalid magic
+	_, err = VerifySnapshotBackup(bytes.NewReader(data[1:]))
+	assert.Equal(t, ErrInvalidSnapshotBackup, errors.Cause(err))
+	// huge frame size
+	huge := append([]byte(snapshotBackupMagic), 0xff, 0xff, 0xff, 0xff)
+	_, err = VerifySnapshotBackup(bytes.NewReader(huge))
+	assert.Equal(t, ErrInvalidSnapshotBackup, errors.Cause(err))
+	err = writeBackupFrame(&bytes.Buffer{}, make([]byte, maxBackupFrameSize+1))
+	assert.Equal(t, ErrInvalidSnapshotBackup, errors.Cause(err))
+}
@@ -324,6 +324,23 @@ func (r *RaftNode) CreateRaftSnapshot(dbs map[string]SnapshotDB, patchNum int) r
 
 // ApplyRaftSnapshot apply snapshot's data into db
 func (r *RaftNode) ApplyRaftSnapshot(ctx context.Context, dbs map[string]SnapshotDB, st raftserver.Snapshot) error {
+	span := trace.SpanFromContextSafe(ctx)
+	if err := putSnapshotData(ctx, dbs, st); err != nil {
+		return err
+	}
+	// applier LoadData callback
+	for _, applier := range r.appliers {
+		if err := applier.LoadData(ctx); err != nil {
+			span.Errorf("applier[%s] load data failed, err: %s", applier.GetModuleName(), err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putSnapshotData decode snapshot's data and put into db
+func putSnapshotData(ctx context.Context, dbs map[string]SnapshotDB, st raftserver.Snapshot) error {
 	var (
 		err   error
 		data  []byte
@@ -345,11 +362,16 @@ func (r *RaftNode) ApplyRaftSnapshot(ctx context.Context, dbs map[string]Snapsho
 			count++
 			dbName := snapData.Header.DbName
 			cfName := snapData.Header.CfName
+			db, ok := dbs[dbName]
+			if !ok {
+				span.Errorf("ApplyRaftSnapshot db[%s] of snapshot data not found", dbName)
+				return errors.New("snapshot db not found")
+			}
 
 			if snapData.Header.CfName != "" {
-				err = dbs[dbName].Table(cfName).Put(kvstore.KV{Key: snapData.Key, Value: snapData.Value})
+				err = db.Table(cfName).Put(kvstore.KV{Key: snapData.Key, Value: snapData.Value})
 			} else {
-				err = dbs[dbName].Put(kvstore.KV{Key: snapData.Key, Value: snapData.Value})
+				err = db.Put(kvstore.KV{Key: snapData.Key, Value: snapData.Value})
 			}
 			if err != nil {
 				span.Errorf("ApplyRaftSnapshot put snapshot data failed, snapshot data: %v, err: %v", snapData, err)
@@ -362,14 +384,6 @@ func (r *RaftNode) ApplyRaftSnapshot(ctx context.Context, dbs map[string]Snapsho
 		span.Errorf("ApplyRaftSnapshot read unexpected error, err: %v", err)
 		return err
 	}
-	// applier LoadData callback
-	for _, applier := range r.appliers {
-		if err := applier.LoadData(ctx); err != nil {
-			span.Errorf("applier[%s] load data failed, err: %s", applier.GetModuleName(), err.Error())
-			return err
-		}
-	}
-
 	return nil
 }
 
@@ -144,6 +144,109 @@ func (s *idcStorage) alloc(ctx context.Context, count int, excludes map[proto.Di
 	return ret, nil
 }
 
+// allocWithFailureDomain choose data node storage one by one with free chunk weight,
+// the racks and hosts which reach the limit of failure domain are not candidates any more,
+// so that the result never violates failure domain
+func (s *idcStorage) allocWithFailureDomain(ctx context.Context, count int, excludes map[proto.DiskID]*diskItem, fd FailureDomain, placed *domainUnits) ([]proto.DiskID, error) {
+	span := trace.SpanFromContextSafe(ctx)
+	if atomic.LoadInt64(&s.freeChunk) < int64(count) {
+		return nil, ErrNoEnoughSpace
+	}
+
+	excludeHosts := make(map[string]bool)
+	chosenDisks := make(map[proto.DiskID]*diskItem, len(excludes)+count)
+	for id, disk := range excludes {
+		chosenDisks[id] = disk
+		if s.diffHost && disk != nil {
+			disk.lock.RLock()
+			excludeHosts[disk.info.Host] = true
+			disk.lock.RUnlock()
+		}
+	}
+
+	type candidate struct {
+		rack string
+		stg  *blobNodeStorage
+	}
+	candidates := make([]candidate, 0, len(s.blobNodeStorages))
+	for rack, rackStg := range s.rackStorages {
+		for _, stg := range rackStg.blobNodeStorages {
+			if !excludeHosts[stg.host] {
+				candidates = append(candidates, candidate{rack: rack, stg: stg})
+			}
+		}
+	}
+
+	chosenRacks := make(map[string]int)
+	chosenDataStorages := make(map[*blobNodeStorage]int)
+	ret := make([]proto.DiskID, 0, count)
+	limited := false
+	for len(ret) < count {
+		// filter the candidates which are full or reach the limit of failure domain
+		available := make([]candidate, 0, len(candidates))
+		totalFreeChunk := int64(0)
+		for _, c := range candidates {
+			if (fd.MaxUnitsPerRack > 0 && placed.racks[c.rack] >= fd.MaxUnitsPerRack) ||
+				(fd.MaxUnitsPerHost > 0 && placed.hosts[c.stg.host] >= fd.MaxUnitsPerHost) {
+				limited = true
+				continue
+			}
+			freeChunk := atomic.LoadInt64(&c.stg.freeChunk) - int64(chosenDataStorages[c.stg])
+			if freeChunk <= 0 {
+				continue
+			}
+			available = append(available, c)
+			totalFreeChunk += freeChunk
+		}
+		candidates = available
+		if len(candidates) == 0 {
+			span.Warnf("%s alloc with failure domain failed, chosen disks: %v, placed: %+v", s.idc, ret, placed)
+			if limited {
+				return nil, ErrFailureDomainViolated
+			}
+			return nil, ErrNoEnoughSpace
+		}
+
+		idx := 0
+		randNum := rand.Int63n(totalFreeChunk)
+		for i, c := range candidates {
+			freeChunk := atomic.LoadInt64(&c.stg.freeChunk) - int64(chosenDataStorages[c.stg])
+			if freeChunk > randNum {
+				idx = i
+				break
+			}
+			randNum -= freeChunk
+		}
+		chosen := candidates[idx]
+		disk := chosen.stg.allocDisk(ctx, chosenDisks)
+		if disk == nil {
+			// no writable disk in data node storage, remove it from candidates
+			candidates = append(candidates[:idx], candidates[idx+1:]...)
+			continue
+		}
+		chosenDisks[disk.diskID] = disk
+		chosenDataStorages[chosen.stg]++
+		chosenRacks[chosen.rack]++
+		placed.racks[chosen.rack]++
+		placed.hosts[chosen.stg.host]++
+		ret = append(ret, disk.diskID)
+	}
+
+	atomic.AddInt64(&s.freeChunk, int64(-count))
+	for rack, num := range chosenRacks {
+		atomic.AddInt64(&s.rackStorages[rack].freeChunk, int64(-num))
+	}
+	for stg, num := range chosenDataStorages {
+		atomic.AddInt64(&stg.freeChunk, int64(-num))
+	}
+	for _, id := range ret {
+		chosenDisks[id].lock.Lock()
+		chosenDisks[id].info.FreeChunkCnt -= 1
+		chosenDisks[id].lock.Unlock()
+	}
+	return ret, nil
+}
+
 // 1. alloc rack with free chunk weight
 // 2. alloc from rack's data node storage
 // 3. if can't meet the alloc count request, then retry with enable same rack
@@ -47,6 +47,7 @@ var (
 	ErrDiskNotExist              = errors.New("disk not exist")
 	ErrNoEnoughSpace             = errors.New("no enough space to alloc")
 	ErrBlobNodeCreateChunkFailed = errors.New("blob node create chunk failed")
+	ErrFailureDomainViolated     = errors.New("alloc chunks violate failure domain")
 )
 
 var validSetStatus = map[proto.DiskStatus]int{
@@ -75,6 +76,8 @@ type DiskMgrAPI interface {
 	ListDroppingDisk(ctx context.Context) ([]*blobnode.DiskInfo, error)
 	// AllocChunk return available chunks in data center
 	AllocChunks(ctx context.Context, policy *AllocPolicy) ([]proto.DiskID, error)
+	// CheckFailureDomain return racks and hosts which hold more units than the failure domain of code mode
+	CheckFailureDomain(ctx context.Context, mode codemode.CodeMode, units []clustermgr.Unit) ([]clustermgr.FailureDomainViolation, error)
 	// ListDiskInfo
 	ListDiskInfo(ctx context.Context, opt *clustermgr.ListOptionArgs) (*clustermgr.ListDiskRet, error)
 	// Stat return disk statistic info of a cluster
@@ -89,6 +92,13 @@ type AllocPolicy struct {
 	Idc      string
 	Vuids    []proto.Vuid
 	Excludes []proto.DiskID
+	// CodeMode enforce failure domain of code mode when it is valid,
+	// Placed are the other units of volume which count in failure domain
+	CodeMode codemode.CodeMode
+	Placed   []clustermgr.Unit
+	// FailureDomain enforces failure domain even if it's not enforced by config,
+	// like allocating the unit migrated to restore spread of volume
+	FailureDomain bool
 }
 
 type HeartbeatEvent struct {
@@ -106,10 +116,15 @@ type DiskMgrConfig struct {
 	BlobNodeConfig           blobnode.Config `json:"blob_node_config"`
 	AllocTolerateBuffer      int64           `json:"alloc_tolerate_buffer"`
 	EnsureIndex              bool            `json:"ensure_index"`
+	// EnforceFailureDomain make chunk allocation follow failure domain of code mode,
+	// failure domain is only audited when it's not enabled,
+	// but units migrated for placement violations always follow it
+	EnforceFailureDomain bool `json:"enforce_failure_domain"`
 
-	IDC       []string            `json:"-"`
-	CodeModes []codemode.CodeMode `json:"-"`
-	ChunkSize int64               `json:"-"`
+	IDC            []string                            `json:"-"`
+	CodeModes      []codemode.CodeMode                 `json:"-"`
+	ChunkSize      int64                               `json:"-"`
+	FailureDomains map[codemode.CodeMode]FailureDomain `json:"-"`
 }
 
 type DiskMgr struct {
@@ -204,6 +219,14 @@ func New(scopeMgr scopemgr.ScopeMgrAPI, db *normaldb.NormalDB, cfg DiskMgrConfig
 	if cfg.AllocTolerateBuffer >= 0 {
 		defaultAllocTolerateBuff = cfg.AllocTolerateBuffer
 	}
+	if cfg.FailureDomains == nil {
+		cfg.FailureDomains = make(map[codemode.CodeMode]FailureDomain)
+	}
+	for _, mode := range cfg.CodeModes {
+		if _, ok := cfg.FailureDomains[mode]; !ok {
+			cfg.FailureDomains[mode] = DefaultFailureDomain(mode)
+		}
+	}
 
 	allocators := make(map[string]*atomic.Value)
 	for _, idc := range cfg.IDC {
@@ -418,8 +441,18 @@ func (d *DiskMgr) AllocChunks(ctx context.Context, policy *AllocPolicy) (ret []p
 		}
 	}
 
-	ret, err = allocator.alloc(ctx, len(policy.Vuids), excludes)
+	fd := d.failureDomain(policy.CodeMode)
+	if (d.EnforceFailureDomain || policy.FailureDomain) && (fd.MaxUnitsPerRack > 0 || fd.MaxUnitsPerHost > 0) {
+		var placed *domainUnits
+		if placed, err = d.placedDomainUnits(policy); err != nil {
+			return
+		}
+		ret, err = allocator.allocWithFailureDomain(ctx, len(policy.Vuids), excludes, fd, placed)
+	} else {
+		ret, err = allocator.alloc(ctx, len(policy.Vuids), excludes)
+	}
 	if err != nil {
+		span.Warnf("alloc chunks failed, failure domain: %+v, err: %s", fd, err.Error())
 		return
 	}
 
@@ -681,6 +714,10 @@ func (d *DiskMgr) heartBeatDiskInfo(ctx context.Context, infos []*blobnode.DiskH
 		diskInfo.info.Size = info.Size
 		diskInfo.info.Used = info.Used
 		diskInfo.info.UsedChunkCnt = info.UsedChunkCnt
+		if diskInfo.info.Slow != info.Slow {
+			span.Warnf("disk slow changed, diskID: %d, slow: %v", info.DiskID, info.Slow)
+		}
+		diskInfo.info.Slow = info.Slow
 		// calculate free and max chunk count
 		diskInfo.info.MaxChunkCnt = info.Size / d.ChunkSize
 		// use the minimum value as free chunk count
@@ -158,6 +158,7 @@ func TestDiskMgr_Heartbeat(t *testing.T) {
 		assert.NoError(t, err)
 		diskInfo.DiskHeartBeatInfo.Free = 0
 		diskInfo.DiskHeartBeatInfo.FreeChunkCnt = 0
+		diskInfo.DiskHeartBeatInfo.Slow = i == 1
 		heartbeatInfos = append(heartbeatInfos, &diskInfo.DiskHeartBeatInfo)
 	}
 	err := testDiskMgr.heartBeatDiskInfo(ctx, heartbeatInfos)
@@ -169,6 +170,7 @@ func TestDiskMgr_Heartbeat(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, diskInfo.Free/testDiskMgr.ChunkSize, diskInfo.FreeChunkCnt)
 		assert.Equal(t, int64(0), diskInfo.Free)
+		assert.Equal(t, i == 1, diskInfo.Slow)
 	}
 
 	// get heartbeat change disk
@@ -0,0 +1,146 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diskmgr
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cubefs/blobstore/api/clustermgr"
+	"github.com/cubefs/blobstore/common/codemode"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/util/errors"
+)
+
+// FailureDomain limits units of one volume placed in the same rack or host of an idc,
+// zero means no limit
+type FailureDomain struct {
+	MaxUnitsPerRack int
+	MaxUnitsPerHost int
+}
+
+// DefaultFailureDomain keeps loss of a single rack or host of every idc within the parity budget of code mode
+func DefaultFailureDomain(mode codemode.CodeMode) FailureDomain {
+	tactic := mode.Tactic()
+	perRack := tactic.M / tactic.AZCount
+	if perRack < 1 {
+		perRack = 1
+	}
+	return FailureDomain{MaxUnitsPerRack: perRack, MaxUnitsPerHost: 1}
+}
+
+// failureDomain return the enforced failure domain of code mode,
+// only the rack or host which cluster is aware of will be limited
+func (d *DiskMgr) failureDomain(mode codemode.CodeMode) (fd FailureDomain) {
+	if !mode.IsValid() {
+		return
+	}
+	fd, ok := d.FailureDomains[mode]
+	if !ok {
+		fd = DefaultFailureDomain(mode)
+	}
+	if !d.RackAware {
+		fd.MaxUnitsPerRack = 0
+	}
+	if !d.HostAware {
+		fd.MaxUnitsPerHost = 0
+	}
+	return
+}
+
+type failureDomainKey struct {
+	idc    string
+	domain string
+	name   string
+}
+
+// CheckFailureDomain return the racks and hosts which hold more units than the failure domain of code mode
+func (d *DiskMgr) CheckFailureDomain(ctx context.Context, mode codemode.CodeMode, units []clustermgr.Unit) ([]clustermgr.FailureDomainViolation, error) {
+	return d.checkFailureDomain(d.failureDomain(mode), units)
+}
+
+func (d *DiskMgr) checkFailureDomain(fd FailureDomain, units []clustermgr.Unit) (ret []clustermgr.FailureDomainViolation, err error) {
+	if fd.MaxUnitsPerRack <= 0 && fd.MaxUnitsPerHost <= 0 {
+		return nil, nil
+	}
+
+	keys := make([]failureDomainKey, 0)
+	placed := make(map[failureDomainKey][]proto.Vuid)
+	add := func(key failureDomainKey, vuid proto.Vuid) {
+		if _, ok := placed[key]; !ok {
+			keys = append(keys, key)
+		}
+		placed[key] = append(placed[key], vuid)
+	}
+	for _, unit := range units {
+		disk, ok := d.getDisk(unit.DiskID)
+		if !ok {
+			return nil, errors.Info(ErrDiskNotExist, fmt.Sprintf("disk[%d]", unit.DiskID)).Detail(ErrDiskNotExist)
+		}
+		disk.lock.RLock()
+		idc, rack, host := disk.info.Idc, disk.info.Rack, disk.info.Host
+		disk.lock.RUnlock()
+		if fd.MaxUnitsPerRack > 0 {
+			add(failureDomainKey{idc: idc, domain: clustermgr.FailureDomainRack, name: rack}, unit.Vuid)
+		}
+		if fd.MaxUnitsPerHost > 0 {
+			add(failureDomainKey{idc: idc, domain: clustermgr.FailureDomainHost, name: host}, unit.Vuid)
+		}
+	}
+
+	for _, key := range keys {
+		limit := fd.MaxUnitsPerHost
+		if key.domain == clustermgr.FailureDomainRack {
+			limit = fd.MaxUnitsPerRack
+		}
+		if len(placed[key]) > limit {
+			ret = append(ret, clustermgr.FailureDomainViolation{
+				Idc:    key.idc,
+				Domain: key.domain,
+				Name:   key.name,
+				Limit:  limit,
+				Vuids:  placed[key],
+			})
+		}
+	}
+	return ret, nil
+}
+
+// domainUnits count units of one volume placed in every rack and host of an idc
+type domainUnits struct {
+	racks map[string]int
+	hosts map[string]int
+}
+
+// placedDomainUnits count the placed units of volume in the idc of alloc policy,
+// rack is named with idc as the rack storage of allocator
+func (d *DiskMgr) placedDomainUnits(policy *AllocPolicy) (*domainUnits, error) {
+	placed := &domainUnits{racks: make(map[string]int), hosts: make(map[string]int)}
+	for _, unit := range policy.Placed {
+		disk, ok := d.getDisk(unit.DiskID)
+		if !ok {
+			return nil, errors.Info(ErrDiskNotExist, fmt.Sprintf("disk[%d]", unit.DiskID)).Detail(ErrDiskNotExist)
+		}
+		disk.lock.RLock()
+		idc, rack, host := disk.info.Idc, disk.info.Rack, disk.info.Host
+		disk.lock.RUnlock()
+		if idc != policy.Idc {
+			continue
+		}
+		placed.racks[idc+"-"+rack]++
+		placed.hosts[host]++
+	}
+	return placed, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package diskmgr
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/api/clustermgr"
+	"github.com/cubefs/blobstore/common/codemode"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+func TestDefaultFailureDomain(t *testing.T) {
+	require.Equal(t, FailureDomain{MaxUnitsPerRack: 4, MaxUnitsPerHost: 1}, DefaultFailureDomain(codemode.EC15P12))
+	require.Equal(t, FailureDomain{MaxUnitsPerRack: 6, MaxUnitsPerHost: 1}, DefaultFailureDomain(codemode.EC6P6))
+	require.Equal(t, FailureDomain{MaxUnitsPerRack: 5, MaxUnitsPerHost: 1}, DefaultFailureDomain(codemode.EC6P10L2))
+}
+
+func TestCheckFailureDomain(t *testing.T) {
+	testDiskMgr, closeTestDiskMgr := initTestDiskMgr(t)
+	defer closeTestDiskMgr()
+	_, ctx := trace.StartSpanFromContext(context.Background(), "")
+
+	// all disks are in the same rack and host
+	initTestDiskMgrDisks(t, testDiskMgr, 1, 10, testIdcs[0])
+	require.Equal(t, DefaultFailureDomain(codemode.EC6P6), testDiskMgr.FailureDomains[codemode.EC6P6])
+
+	units := make([]clustermgr.Unit, 0)
+	for i := 1; i <= 3; i++ {
+		units = append(units, clustermgr.Unit{Vuid: proto.EncodeVuid(proto.EncodeVuidPrefix(1, uint8(i)), 1), DiskID: proto.DiskID(i)})
+	}
+	vuids := []proto.Vuid{units[0].Vuid, units[1].Vuid, units[2].Vuid}
+
+	// only host aware
+	testDiskMgr.HostAware = true
+	testDiskMgr.RackAware = false
+	violations, err := testDiskMgr.CheckFailureDomain(ctx, codemode.EC6P6, units)
+	require.NoError(t, err)
+	require.Equal(t, []clustermgr.FailureDomainViolation{
+		{Idc: testIdcs[0], Domain: clustermgr.FailureDomainHost, Name: testIdcs[0] + hostPrefix + "0", Limit: 1, Vuids: vuids},
+	}, violations)
+
+	// invalid code mode will not be checked
+	violations, err = testDiskMgr.CheckFailureDomain(ctx, codemode.CodeMode(0), units)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(violations))
+
+	// rack and host aware
+	testDiskMgr.RackAware = true
+	testDiskMgr.FailureDomains[codemode.EC6P6] = FailureDomain{MaxUnitsPerRack: 2, MaxUnitsPerHost: 3}
+	violations, err = testDiskMgr.CheckFailureDomain(ctx, codemode.EC6P6, units)
+	require.NoError(t, err)
+	require.Equal(t, []clustermgr.FailureDomainViolation{
+		{Idc: testIdcs[0], Domain: clustermgr.FailureDomainRack, Name: "0", Limit: 2, Vuids: vuids},
+	}, violations)
+	violations, err = testDiskMgr.CheckFailureDomain(ctx, codemode.EC6P6, units[:2])
+	require.NoError(t, err)
+	require.Equal(t, 0, len(violations))
+
+	// disk not exist
+	_, err = testDiskMgr.CheckFailureDomain(ctx, codemode.EC6P6, append(units, clustermgr.Unit{DiskID: 100}))
+	require.Error(t, err)
+}
+
+func TestAllocChunksWithFailureDomain(t *testing.T) {
+	testDiskMgr, closeTestDiskMgr := initTestDiskMgr(t)
+	defer closeTestDiskMgr()
+	// disk never expire
+	testDiskMgr.HeartbeatExpireIntervalS = 6000
+	_, ctx := trace.StartSpanFromContext(context.Background(), "alloc-failure-domain")
+
+	// 1-8 use test-rack-[1-8]
+	// 9-10 use same rack: test-rack-8
+	initTestDiskMgrDisks(t, testDiskMgr, 1, 10, testIdcs[0])
+	testDiskMgr.metaLock.RLock()
+	for i := 1; i <= 10; i++ {
+		diskItem := testDiskMgr.allDisks[proto.DiskID(i)]
+		diskItem.lock.Lock()
+		diskItem.info.Host = "test-host-" + strconv.Itoa(i)
+		diskItem.info.Rack = "test-rack-" + strconv.Itoa(i)
+		if i > 8 {
+			diskItem.info.Rack = "test-rack-8"
+		}
+		diskItem.lock.Unlock()
+	}
+	testDiskMgr.metaLock.RUnlock()
+	testDiskMgr.HostAware = true
+	testDiskMgr.RackAware = true
+	testDiskMgr.FailureDomains[codemode.EC6P6] = FailureDomain{MaxUnitsPerRack: 1, MaxUnitsPerHost: 1}
+	testDiskMgr.refresh(ctx)
+	testMockBlobNode.EXPECT().CreateChunk(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+
+	vuids := make([]proto.Vuid, 0)
+	for i := 0; i < 10; i++ {
+		vuids = append(vuids, proto.EncodeVuid(proto.EncodeVuidPrefix(1, uint8(i)), 1))
+	}
+
+	// failure domain is only audited by default
+	require.False(t, testDiskMgr.EnforceFailureDomain)
+	diskIDs, err := testDiskMgr.AllocChunks(ctx, &AllocPolicy{Idc: testIdcs[0], Vuids: vuids, CodeMode: codemode.EC6P6})
+	require.NoError(t, err)
+	require.Equal(t, 10, len(diskIDs))
+	// enforced by policy
+	_, err = testDiskMgr.AllocChunks(ctx, &AllocPolicy{Idc: testIdcs[0], Vuids: vuids, CodeMode: codemode.EC6P6, FailureDomain: true})
+	require.Equal(t, ErrFailureDomainViolated, err)
+
+	testDiskMgr.EnforceFailureDomain = true
+	// 10 units can not be placed in 9 racks
+	_, err = testDiskMgr.AllocChunks(ctx, &AllocPolicy{Idc: testIdcs[0], Vuids: vuids, CodeMode: codemode.EC6P6})
+	require.Equal(t, ErrFailureDomainViolated, err)
+	// 9 units are placed in 9 racks directly
+	for i := 0; i < 10; i++ {
+		diskIDs, err = testDiskMgr.AllocChunks(ctx, &AllocPolicy{Idc: testIdcs[0], Vuids: vuids[:9], CodeMode: codemode.EC6P6})
+		require.NoError(t, err)
+		require.Equal(t, 9, len(diskIDs))
+		units := make([]clustermgr.Unit, 0, len(diskIDs))
+		for idx := range diskIDs {
+			units = append(units, clustermgr.Unit{Vuid: vuids[idx], DiskID: diskIDs[idx]})
+		}
+		violations, err := testDiskMgr.CheckFailureDomain(ctx, codemode.EC6P6, units)
+		require.NoError(t, err)
+		require.Equal(t, 0, len(violations))
+	}
+	// no failure domain enforced without code mode
+	diskIDs, err = testDiskMgr.AllocChunks(ctx, &AllocPolicy{Idc: testIdcs[0], Vuids: vuids})
+	require.NoError(t, err)
+	require.Equal(t, 10, len(diskIDs))
+
+	// placed units count in failure domain
+	placed := make([]clustermgr.Unit, 0)
+	excludes := make([]proto.DiskID, 0)
+	for i := 1; i <= 8; i++ {
+		placed = append(placed, clustermgr.Unit{Vuid: vuids[i], DiskID: proto.DiskID(i)})
+		excludes = append(excludes, proto.DiskID(i))
+	}
+	policy := &AllocPolicy{Idc: testIdcs[0], Vuids: vuids[:1], Excludes: excludes, CodeMode: codemode.EC6P6, Placed: placed}
+	_, err = testDiskMgr.AllocChunks(ctx, policy)
+	require.Equal(t, ErrFailureDomainViolated, err)
+
+	policy.Placed = placed[:7]
+	policy.Excludes = excludes[:7]
+	for i := 0; i < 3; i++ {
+		diskIDs, err = testDiskMgr.AllocChunks(ctx, policy)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(diskIDs))
+		require.True(t, diskIDs[0] >= 8)
+	}
+}
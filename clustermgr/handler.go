@@ -77,6 +77,7 @@ func NewHandler(service *Service) *rpc.Router {
 	rpc.RegisterArgsParser(&clustermgr.ListVolumeV2Args{}, "json")
 	rpc.RegisterArgsParser(&clustermgr.ListVolumeUnitArgs{}, "json")
 	rpc.RegisterArgsParser(&clustermgr.ListAllocatedVolumeArgs{}, "json")
+	rpc.RegisterArgsParser(&clustermgr.ListFailureDomainViolationsArgs{}, "json")
 
 	rpc.GET("/volume/get", service.VolumeGet, rpc.OptArgsQuery())
 
@@ -94,6 +95,8 @@ func NewHandler(service *Service) *rpc.Router {
 
 	rpc.POST("/volume/unlock", service.VolumeUnlock, rpc.OptArgsBody())
 
+	rpc.POST("/volume/redirect/set", service.VolumeRedirectSet, rpc.OptArgsBody())
+
 	rpc.POST("/volume/unit/alloc", service.VolumeUnitAlloc, rpc.OptArgsBody())
 
 	rpc.POST("/volume/unit/release", service.VolumeUnitRelease, rpc.OptArgsBody())
@@ -102,6 +105,10 @@ func NewHandler(service *Service) *rpc.Router {
 
 	rpc.GET("/volume/allocated/list", service.VolumeAllocatedList, rpc.OptArgsQuery())
 
+	rpc.GET("/volume/failure_domain/violations", service.VolumeFailureDomainViolations, rpc.OptArgsQuery())
+
+	rpc.GET("/volume/placement/violations", service.VolumePlacementViolations)
+
 	rpc.POST("/admin/update/volume/unit", service.AdminUpdateVolumeUnit, rpc.OptArgsBody())
 
 	rpc.POST("/admin/update/volume", service.AdminUpdateVolume, rpc.OptArgsBody())
@@ -128,5 +135,7 @@ func NewHandler(service *Service) *rpc.Router {
 
 	rpc.GET("/snapshot/dump", service.SnapshotDump)
 
+	rpc.GET("/snapshot/backup", service.SnapshotBackup)
+
 	return rpc.DefaultRouter
 }
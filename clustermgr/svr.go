@@ -74,6 +74,9 @@ const (
 	defaultHeartbeatNotifyIntervalS = 10
 	defaultMaxHeartbeatNotifyNum    = 2000
 	defaultMetricReportIntervalM    = 2
+	defaultFollowerReadMaxStaleMs   = 3000
+	defaultSnapshotBackupIntervalM  = 60
+	defaultSnapshotBackupReserveNum = 3
 )
 
 var (
@@ -87,12 +90,14 @@ type Config struct {
 	UnavailableIDC           string                    `json:"unavailable_idc"`
 	ClusterID                proto.ClusterID           `json:"cluster_id"`
 	Readonly                 bool                      `json:"readonly"`
+	Weight                   uint32                    `json:"weight"`
 	VolumeMgrConfig          volumemgr.VolumeMgrConfig `json:"volume_mgr_config"`
 	NormalDBPath             string                    `json:"normal_db_path"`
 	NormalDBOption           kvstore.RocksDBOption     `json:"normal_db_option"`
 	CodeModePolicies         []codemode.Policy         `json:"code_mode_policies"`
 	ClusterCfg               map[string]interface{}    `json:"cluster_config"`
 	RaftConfig               RaftConfig                `json:"raft_config"`
+	SnapshotBackupConfig     SnapshotBackupConfig      `json:"snapshot_backup"`
 	DiskMgrConfig            diskmgr.DiskMgrConfig     `json:"disk_mgr_config"`
 	ClusterReportIntervalS   int                       `json:"cluster_report_interval_s"`
 	ConsulAgentAddr          string                    `json:"consul_agent_addr"`
@@ -100,6 +105,10 @@ type Config struct {
 	MaxHeartbeatNotifyNum    int                       `json:"max_heartbeat_notify_num"`
 	ChunkSize                uint64                    `json:"chunk_size"`
 	MetricReportIntervalM    int                       `json:"metric_report_interval_m"`
+	// FollowerReadMaxStaleMs is the max staleness against leader that follower can still serve
+	// bounded stale read-only request locally, follower catches up with leader by periodic ReadIndex,
+	// leader is bounded too, as it may have been partitioned without quorum
+	FollowerReadMaxStaleMs int `json:"follower_read_max_stale_ms"`
 
 	cmd.Config
 }
@@ -126,11 +135,14 @@ type Service struct {
 	status uint32
 	// electedLeaderReadIndex indicate that service(elected leader) should execute ReadIndex or not before accept incoming request
 	electedLeaderReadIndex uint32
-	raftNode               *base.RaftNode
-	raftStartOnce          sync.Once
-	raftStartCh            chan interface{}
-	closeCh                chan interface{}
-	consulClient           *api.Client
+	// readIndexTime is the unix nano time of last successful ReadIndex,
+	// local data is not older than the quorum's at that time
+	readIndexTime int64
+	raftNode      *base.RaftNode
+	raftStartOnce sync.Once
+	raftStartCh   chan interface{}
+	closeCh       chan interface{}
+	consulClient  *api.Client
 	*Config
 }
 
@@ -193,7 +205,7 @@ func New(cfg *Config) (*Service, error) {
 	}
 
 	service := &Service{
-		dbs:          map[string]base.SnapshotDB{"volume": volumeDB, "normal": normalDB},
+		dbs:          map[string]base.SnapshotDB{base.VolumeDBName: volumeDB, base.NormalDBName: normalDB},
 		Config:       cfg,
 		raftStartCh:  make(chan interface{}),
 		status:       ServiceStatusNormal,
@@ -306,6 +318,10 @@ func New(cfg *Config) (*Service, error) {
 
 	// start service background loop
 	go service.loop()
+	go service.readIndexLoop()
+	if cfg.SnapshotBackupConfig.Dir != "" {
+		go service.snapshotBackupLoop()
+	}
 	return service, nil
 }
 
@@ -317,6 +333,10 @@ func (s *Service) Handler(w http.ResponseWriter, req *http.Request, f func(http.
 		s.forwardToLeader(w, req)
 		return
 	}
+	// read-only request must satisfy the read consistency of client
+	if req.Method == http.MethodGet && !s.checkReadConsistency(w, req) {
+		return
+	}
 	// service status is normal, then we should just execute f
 	if atomic.LoadUint32(&s.electedLeaderReadIndex) == NeedReadIndex {
 		span, ctx := trace.StartSpanFromHTTPHeaderSafe(req, "")
@@ -433,12 +453,36 @@ func (c *Config) checkAndFix() (err error) {
 	}
 	c.VolumeMgrConfig.CodeModePolicies = c.CodeModePolicies
 
+	c.DiskMgrConfig.FailureDomains = make(map[codemode.CodeMode]diskmgr.FailureDomain)
+	for _, modePolicy := range c.CodeModePolicies {
+		codeMode := modePolicy.ModeName.GetCodeMode()
+		fd := diskmgr.DefaultFailureDomain(codeMode)
+		if modePolicy.MaxUnitsPerRack > 0 {
+			fd.MaxUnitsPerRack = modePolicy.MaxUnitsPerRack
+		}
+		if modePolicy.MaxUnitsPerHost > 0 {
+			fd.MaxUnitsPerHost = modePolicy.MaxUnitsPerHost
+		}
+		c.DiskMgrConfig.FailureDomains[codeMode] = fd
+	}
+
 	c.DiskMgrConfig.IDC = c.IDC
 	c.VolumeMgrConfig.IDC = c.IDC
 	c.VolumeMgrConfig.UnavailableIDC = c.UnavailableIDC
 	c.VolumeMgrConfig.Region = c.Region
 	c.VolumeMgrConfig.ClusterID = c.ClusterID
 
+	if c.SnapshotBackupConfig.IntervalM <= 0 {
+		c.SnapshotBackupConfig.IntervalM = defaultSnapshotBackupIntervalM
+	}
+	if c.SnapshotBackupConfig.ReserveNum <= 0 {
+		c.SnapshotBackupConfig.ReserveNum = defaultSnapshotBackupReserveNum
+	}
+
+	if c.FollowerReadMaxStaleMs <= 0 {
+		c.FollowerReadMaxStaleMs = defaultFollowerReadMaxStaleMs
+	}
+
 	if c.RaftConfig.SnapshotPatchNum == 0 {
 		c.RaftConfig.SnapshotPatchNum = 64
 	}
@@ -453,8 +497,10 @@ func (s *Service) waitForRaftStart() {
 
 	// wait for wal log replay
 	for {
+		start := time.Now()
 		err := s.raftNode.ReadIndex(context.Background())
 		if err == nil {
+			atomic.StoreInt64(&s.readIndexTime, start.UnixNano())
 			break
 		}
 		log.Error("raftNode read index failed: ", err)
@@ -463,6 +509,69 @@ func (s *Service) waitForRaftStart() {
 	log.Info("raft start success")
 }
 
+// checkReadConsistency return false if read-only request has been replied or forwarded to leader,
+// linearizable request will be served after ReadIndex, and bounded stale request will be
+// forwarded to leader when follower lags too much or lost leader, stale leader which
+// may have lost quorum serves it only after ReadIndex confirms the leadership
+func (s *Service) checkReadConsistency(w http.ResponseWriter, req *http.Request) bool {
+	switch clustermgr.ReadConsistency(req.Header.Get(clustermgr.HeaderReadConsistency)) {
+	case clustermgr.ReadLinearizable:
+		span, ctx := trace.StartSpanFromHTTPHeaderSafe(req, "")
+		if err := s.readIndex(ctx); err != nil {
+			span.Errorf("linearizable read index failed, err: %s", err.Error())
+			rpc.ReplyErr(w, apierrors.CodeRaftReadIndex, apierrors.ErrRaftReadIndex.Error())
+			return false
+		}
+	default:
+		// node which lost leader or partitioned can not finish ReadIndex, and becomes stale
+		stale := time.Since(time.Unix(0, atomic.LoadInt64(&s.readIndexTime)))
+		if stale <= time.Duration(s.FollowerReadMaxStaleMs)*time.Millisecond {
+			return true
+		}
+		if !s.raftNode.IsLeader() {
+			log.Infof("follower can not serve stale read, stale: %s", stale)
+			s.forwardToLeader(w, req)
+			return false
+		}
+		span, ctx := trace.StartSpanFromHTTPHeaderSafe(req, "")
+		if err := s.readIndex(ctx); err != nil {
+			span.Errorf("stale leader read index failed, stale: %s, err: %s", stale, err.Error())
+			rpc.ReplyErr(w, apierrors.CodeRaftReadIndex, apierrors.ErrRaftReadIndex.Error())
+			return false
+		}
+	}
+	return true
+}
+
+// readIndex waits for ReadIndex and refreshes the time of last successful ReadIndex
+func (s *Service) readIndex(ctx context.Context) error {
+	start := time.Now()
+	if err := s.raftNode.ReadIndex(ctx); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&s.readIndexTime, start.UnixNano())
+	return nil
+}
+
+// readIndexLoop keeps follower catching up with leader and leader confirming
+// its leadership by ReadIndex, so that bounded stale request can be served without touching raft
+func (s *Service) readIndexLoop() {
+	interval := time.Duration(s.FollowerReadMaxStaleMs) * time.Millisecond / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.readIndex(context.Background()); err != nil {
+				log.Warnf("periodic read index failed, err: %s", err.Error())
+			}
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
 // forwardToLeader will forward http request to raft leader
 func (s *Service) forwardToLeader(w http.ResponseWriter, req *http.Request) {
 	url, err := url.Parse(s.RaftConfig.RaftNodeConfig.NodeProtocol + req.RequestURI)
@@ -525,6 +634,7 @@ func (s *Service) loop() {
 				ClusterID: s.ClusterID,
 				Readonly:  s.Readonly,
 				Nodes:     make([]string, 0),
+				Weight:    s.Weight,
 			}
 			spaceStatInfo := s.DiskMgr.Stat(ctx)
 			clusterInfo.Capacity = spaceStatInfo.TotalSpace
@@ -16,24 +16,31 @@ package clustermgr
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/cubefs/blobstore/api/clustermgr"
+	"github.com/cubefs/blobstore/clustermgr/base"
 	"github.com/cubefs/blobstore/clustermgr/diskmgr"
 	"github.com/cubefs/blobstore/clustermgr/volumemgr"
 	"github.com/cubefs/blobstore/common/codemode"
+	apierrors "github.com/cubefs/blobstore/common/errors"
 	"github.com/cubefs/blobstore/common/proto"
 	"github.com/cubefs/blobstore/common/raftserver"
 	"github.com/cubefs/blobstore/common/rpc"
 	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/testing/mocks"
 )
 
 var testServiceCfg = &Config{
@@ -142,6 +149,123 @@ func TestBidAlloc(t *testing.T) {
 	}
 }
 
+func TestReadConsistency(t *testing.T) {
+	testService := initTestService(t)
+	defer clear(testService)
+	defer testService.Close()
+	testClusterClient := initTestClusterClient(testService)
+
+	_, ctx := trace.StartSpanFromContext(context.Background(), "")
+	_, err := testClusterClient.Stat(ctx)
+	assert.NoError(t, err)
+	_, err = testClusterClient.Stat(clustermgr.WithReadConsistency(ctx, clustermgr.ReadLinearizable))
+	assert.NoError(t, err)
+
+	for _, consistency := range []clustermgr.ReadConsistency{"", clustermgr.ReadBoundedStale, clustermgr.ReadLinearizable} {
+		req, err := http.NewRequest(http.MethodGet, "/stat", nil)
+		assert.NoError(t, err)
+		req.Header.Set(clustermgr.HeaderReadConsistency, string(consistency))
+		assert.True(t, testService.checkReadConsistency(&mockWriter{}, req))
+	}
+}
+
+func TestReadConsistencyFollower(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRaftServer := mocks.NewMockRaftServer(ctrl)
+	mockRaftServer.EXPECT().IsLeader().AnyTimes().Return(false)
+
+	forwarded := 0
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		forwarded++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer leader.Close()
+
+	raftNode, err := base.NewRaftNode(&base.RaftNodeConfig{Nodes: map[uint64]string{2: leader.Listener.Addr().String()}}, nil)
+	assert.NoError(t, err)
+	raftNode.SetRaftServer(mockRaftServer)
+	testService := &Service{
+		raftNode: raftNode,
+		Config: &Config{
+			FollowerReadMaxStaleMs: 1000,
+			RaftConfig:             RaftConfig{RaftNodeConfig: base.RaftNodeConfig{NodeProtocol: "http://"}},
+		},
+	}
+	newRequest := func(consistency clustermgr.ReadConsistency) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "/stat", nil)
+		assert.NoError(t, err)
+		req.RequestURI = "/stat"
+		req.Header.Set(clustermgr.HeaderReadConsistency, string(consistency))
+		return req
+	}
+
+	// follower caught up with leader serves stale read locally
+	mockRaftServer.EXPECT().ReadIndex(gomock.Any()).Return(nil)
+	assert.NoError(t, testService.readIndex(context.Background()))
+	assert.True(t, testService.checkReadConsistency(httptest.NewRecorder(), newRequest(clustermgr.ReadBoundedStale)))
+
+	// follower lost leader can not finish read index, and replies no leader when stale
+	mockRaftServer.EXPECT().ReadIndex(gomock.Any()).Return(errors.New("no leader"))
+	assert.Error(t, testService.readIndex(context.Background()))
+	atomic.StoreInt64(&testService.readIndexTime, time.Now().Add(-2*time.Second).UnixNano())
+	w := httptest.NewRecorder()
+	assert.False(t, testService.checkReadConsistency(w, newRequest("")))
+	assert.Equal(t, apierrors.CodeNoLeader, w.Code)
+	assert.Equal(t, 0, forwarded)
+
+	// stale follower forwards request to leader
+	raftNode.SetLeaderHost(2, "")
+	w = httptest.NewRecorder()
+	assert.False(t, testService.checkReadConsistency(w, newRequest(clustermgr.ReadBoundedStale)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, forwarded)
+
+	// linearizable read on follower waits for read index
+	mockRaftServer.EXPECT().ReadIndex(gomock.Any()).Return(nil)
+	assert.True(t, testService.checkReadConsistency(httptest.NewRecorder(), newRequest(clustermgr.ReadLinearizable)))
+	mockRaftServer.EXPECT().ReadIndex(gomock.Any()).Return(errors.New("timeout"))
+	w = httptest.NewRecorder()
+	assert.False(t, testService.checkReadConsistency(w, newRequest(clustermgr.ReadLinearizable)))
+	assert.Equal(t, apierrors.CodeRaftReadIndex, w.Code)
+}
+
+func TestReadConsistencyStaleLeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRaftServer := mocks.NewMockRaftServer(ctrl)
+	mockRaftServer.EXPECT().IsLeader().AnyTimes().Return(true)
+
+	raftNode, err := base.NewRaftNode(&base.RaftNodeConfig{}, nil)
+	assert.NoError(t, err)
+	raftNode.SetRaftServer(mockRaftServer)
+	testService := &Service{raftNode: raftNode, Config: &Config{FollowerReadMaxStaleMs: 1000}}
+	newRequest := func() *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "/stat", nil)
+		assert.NoError(t, err)
+		req.Header.Set(clustermgr.HeaderReadConsistency, string(clustermgr.ReadBoundedStale))
+		return req
+	}
+
+	// leader confirmed by read index recently serves stale read locally
+	mockRaftServer.EXPECT().ReadIndex(gomock.Any()).Return(nil)
+	assert.NoError(t, testService.readIndex(context.Background()))
+	assert.True(t, testService.checkReadConsistency(httptest.NewRecorder(), newRequest()))
+
+	// stale leader confirms leadership before serving
+	atomic.StoreInt64(&testService.readIndexTime, time.Now().Add(-2*time.Second).UnixNano())
+	mockRaftServer.EXPECT().ReadIndex(gomock.Any()).Return(nil)
+	assert.True(t, testService.checkReadConsistency(httptest.NewRecorder(), newRequest()))
+	assert.True(t, testService.checkReadConsistency(httptest.NewRecorder(), newRequest()))
+
+	// partitioned leader without quorum can not serve
+	atomic.StoreInt64(&testService.readIndexTime, time.Now().Add(-2*time.Second).UnixNano())
+	mockRaftServer.EXPECT().ReadIndex(gomock.Any()).Return(errors.New("timeout"))
+	w := httptest.NewRecorder()
+	assert.False(t, testService.checkReadConsistency(w, newRequest()))
+	assert.Equal(t, apierrors.CodeRaftReadIndex, w.Code)
+}
+
 type mockWriter struct{}
 
 func (m *mockWriter) Write(data []byte) (int, error) {
@@ -256,6 +256,52 @@ func (s *Service) VolumeUnlock(c *rpc.Context) {
 	c.RespondError(s.VolumeMgr.UnlockVolume(ctx, args.Vid))
 }
 
+func (s *Service) VolumeRedirectSet(c *rpc.Context) {
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+	args := new(clustermgr.SetVolumeRedirectArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+	span.Infof("accept VolumeRedirectSet request, args: %v", args)
+
+	c.RespondError(s.VolumeMgr.SetVolumeRedirect(ctx, args.Vid, args.RedirectVid))
+}
+
+func (s *Service) VolumeFailureDomainViolations(c *rpc.Context) {
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+	args := new(clustermgr.ListFailureDomainViolationsArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+	span.Infof("accept VolumeFailureDomainViolations request, args: %v", args)
+
+	if err := s.raftNode.ReadIndex(ctx); err != nil {
+		span.Errorf("read index error: %v", err)
+		c.RespondError(apierrors.ErrRaftReadIndex)
+		return
+	}
+
+	ret, err := s.VolumeMgr.ListFailureDomainViolations(ctx, args)
+	if err != nil {
+		span.Errorf("list failure domain violations failed, args: %v, err: %v", args, errors.Detail(err))
+		c.RespondError(err)
+		return
+	}
+	c.RespondJSON(ret)
+}
+
+func (s *Service) VolumePlacementViolations(c *rpc.Context) {
+	ctx := c.Request.Context()
+	span := trace.SpanFromContextSafe(ctx)
+	span.Info("accept VolumePlacementViolations request")
+
+	c.RespondJSON(s.VolumeMgr.ListPlacementViolations(ctx))
+}
+
 func (s *Service) VolumeUnitAlloc(c *rpc.Context) {
 	ctx := c.Request.Context()
 	span := trace.SpanFromContextSafe(ctx)
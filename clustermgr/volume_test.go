@@ -117,6 +117,17 @@ func TestService_VolumeInfo(t *testing.T) {
 		assert.Equal(t, proto.Vid(5), list.Volumes[3].Vid)
 	}
 
+	// list failure domain and placement violations
+	{
+		ret, err := cmClient.ListFailureDomainViolations(ctx, &clustermgr.ListFailureDomainViolationsArgs{Count: 5})
+		assert.NoError(t, err)
+		assert.Equal(t, proto.Vid(5), ret.Marker)
+
+		violations, err := cmClient.ListPlacementViolations(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(violations.Vuids))
+	}
+
 	// list volume info v2
 	{
 		ret, err := cmClient.ListVolumeV2(ctx, &clustermgr.ListVolumeV2Args{Status: proto.VolumeStatusIdle})
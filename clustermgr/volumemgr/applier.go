@@ -48,6 +48,7 @@ const (
 	OperTypeAdminUpdateVolumeUnit
 	OperTypeInitCreateVolume
 	OperTypeIncreaseVolumeUnitsEpoch
+	OperTypeSetVolumeRedirect
 )
 
 type CreateVolumeCtx struct {
@@ -399,6 +400,21 @@ func (v *VolumeMgr) Apply(ctx context.Context, operTypes []int32, datas [][]byte
 				wg.Done()
 			})
 
+		case OperTypeSetVolumeRedirect:
+			args := &clustermgr.SetVolumeRedirectArgs{}
+			err := json.Unmarshal(datas[idx], args)
+			if err != nil {
+				errs[idx] = errors.Info(err, "json unmarshal failed, data: ", datas[idx]).Detail(err)
+				wg.Done()
+				continue
+			}
+			v.applyTaskPool.Run(v.getTaskIdx(args.Vid), func() {
+				if err = v.applySetVolumeRedirect(taskCtx, args); err != nil {
+					errs[idx] = errors.Info(err, "apply set volume redirect failed, args: ", args).Detail(err)
+				}
+				wg.Done()
+			})
+
 		default:
 			errs[idx] = errors.New("unsupported operation")
 			wg.Done()
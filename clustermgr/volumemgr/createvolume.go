@@ -282,7 +282,7 @@ func (v *VolumeMgr) allocChunkForAllUnits(ctx context.Context, vol *CreateVolume
 		span.Debugf("start alloc chunk for volume unit,volume is %#v", vol)
 		go func(ctx context.Context, idc string, idcUnits map[proto.VuidPrefix]*clustermgr.VolumeUnitInfo) {
 			defer wg.Done()
-			err := v.allocChunkForIdcUnits(ctx, idc, idcVuInfos)
+			err := v.allocChunkForIdcUnits(ctx, idc, vol.VolInfo.CodeMode, idcVuInfos)
 			span.Debugf("alloc chunk in idc:%v, error is %#v", idc, err)
 			errChan <- err
 		}(ctx, availableIDC[i], idcVuInfos)
@@ -300,17 +300,19 @@ func (v *VolumeMgr) allocChunkForAllUnits(ctx context.Context, vol *CreateVolume
 }
 
 // alloc chunk for each idc unit
-func (v *VolumeMgr) allocChunkForIdcUnits(ctx context.Context, idc string, vuInfos map[proto.VuidPrefix]*clustermgr.VolumeUnitInfo) (err error) {
+func (v *VolumeMgr) allocChunkForIdcUnits(ctx context.Context, idc string, mode codemode.CodeMode, vuInfos map[proto.VuidPrefix]*clustermgr.VolumeUnitInfo) (err error) {
 	span := trace.SpanFromContextSafe(ctx)
 	vuids := make([]proto.Vuid, 0, len(vuInfos))
 	excludes := make([]proto.DiskID, 0)
+	placed := make([]clustermgr.Unit, 0)
 
 	for _, vuInfo := range vuInfos {
 		vuids = append(vuids, vuInfo.Vuid)
 	}
 	policy := &diskmgr.AllocPolicy{
-		Idc:   idc,
-		Vuids: vuids,
+		Idc:      idc,
+		Vuids:    vuids,
+		CodeMode: mode,
 	}
 
 	// Notice: retryTime should never large than IncreaseEpochInterval
@@ -322,8 +324,8 @@ func (v *VolumeMgr) allocChunkForIdcUnits(ctx context.Context, idc string, vuInf
 		)
 		disks, err = v.diskMgr.AllocChunks(ctx, policy)
 		span.Debugf("alloc chunks, policy is %v, actives disk is %v, error is %v", policy, disks, err)
-		// no enough space error return directly, do not retry.
-		if err == diskmgr.ErrNoEnoughSpace {
+		// no enough space or failure domain violated error return directly, do not retry.
+		if err == diskmgr.ErrNoEnoughSpace || err == diskmgr.ErrFailureDomainViolated {
 			time.Sleep(defaulRetrySleepInterval * time.Second)
 			return err
 		}
@@ -341,6 +343,7 @@ func (v *VolumeMgr) allocChunkForIdcUnits(ctx context.Context, idc string, vuInf
 				return err
 			}
 			excludes = append(excludes, disks[i])
+			placed = append(placed, clustermgr.Unit{Vuid: vuid, DiskID: disks[i], Host: diskInfo.Host})
 			vuInfos[vuidPrefix].DiskID = disks[i]
 			vuInfos[vuidPrefix].Host = diskInfo.Host
 			vuInfos[vuidPrefix].Vuid = vuid
@@ -350,6 +353,7 @@ func (v *VolumeMgr) allocChunkForIdcUnits(ctx context.Context, idc string, vuInf
 			break
 		}
 		policy.Excludes = excludes
+		policy.Placed = placed
 		policy.Vuids = failVuids
 	}
 
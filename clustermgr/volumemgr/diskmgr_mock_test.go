@@ -11,6 +11,7 @@ import (
 	blobnode "github.com/cubefs/blobstore/api/blobnode"
 	clustermgr "github.com/cubefs/blobstore/api/clustermgr"
 	diskmgr "github.com/cubefs/blobstore/clustermgr/diskmgr"
+	codemode "github.com/cubefs/blobstore/common/codemode"
 	proto "github.com/cubefs/blobstore/common/proto"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -82,6 +83,21 @@ func (mr *MockDiskMgrAPIMockRecorder) CheckDiskInfoDuplicated(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckDiskInfoDuplicated", reflect.TypeOf((*MockDiskMgrAPI)(nil).CheckDiskInfoDuplicated), arg0, arg1)
 }
 
+// CheckFailureDomain mocks base method.
+func (m *MockDiskMgrAPI) CheckFailureDomain(arg0 context.Context, arg1 codemode.CodeMode, arg2 []clustermgr.Unit) ([]clustermgr.FailureDomainViolation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckFailureDomain", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]clustermgr.FailureDomainViolation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckFailureDomain indicates an expected call of CheckFailureDomain.
+func (mr *MockDiskMgrAPIMockRecorder) CheckFailureDomain(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckFailureDomain", reflect.TypeOf((*MockDiskMgrAPI)(nil).CheckFailureDomain), arg0, arg1, arg2)
+}
+
 // GetDiskInfo mocks base method.
 func (m *MockDiskMgrAPI) GetDiskInfo(arg0 context.Context, arg1 proto.DiskID) (*blobnode.DiskInfo, error) {
 	m.ctrl.T.Helper()
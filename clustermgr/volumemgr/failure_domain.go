@@ -0,0 +1,156 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package volumemgr
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	cm "github.com/cubefs/blobstore/api/clustermgr"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/util/errors"
+)
+
+// ListFailureDomainViolations scan one page of volumes, return the volumes whose units
+// violate the failure domain policy of code mode, marker will be zero when scan finished
+func (v *VolumeMgr) ListFailureDomainViolations(ctx context.Context, args *cm.ListFailureDomainViolationsArgs) (ret *cm.ListFailureDomainViolationsRet, err error) {
+	span := trace.SpanFromContextSafe(ctx)
+	if args.Count <= 0 || args.Count > defaultListVolumeMaxCount {
+		args.Count = defaultListVolumeMaxCount
+	}
+	vids, err := v.volumeTbl.ListVolume(args.Count, args.Marker)
+	if err != nil {
+		span.Errorf("list volume failed:%v", err)
+		return nil, errors.Info(err, "volumeMgr list volume failed").Detail(err)
+	}
+
+	ret = &cm.ListFailureDomainViolationsRet{Volumes: make([]cm.VolumeFailureDomainViolation, 0)}
+	for _, vid := range vids {
+		vol := v.all.getVol(vid)
+		if vol == nil {
+			return nil, ErrVolumeNotExist
+		}
+		vol.lock.RLock()
+		volInfo := vol.ToVolumeInfo()
+		vol.lock.RUnlock()
+
+		violations, err := v.diskMgr.CheckFailureDomain(ctx, volInfo.CodeMode, volInfo.Units)
+		if err != nil {
+			span.Errorf("check failure domain of volume[%d] failed:%v", vid, err)
+			return nil, err
+		}
+		if len(violations) > 0 {
+			ret.Volumes = append(ret.Volumes, cm.VolumeFailureDomainViolation{
+				Vid:        vid,
+				CodeMode:   volInfo.CodeMode,
+				Violations: violations,
+			})
+		}
+	}
+	if len(vids) == args.Count {
+		ret.Marker = vids[len(vids)-1]
+	}
+	return ret, nil
+}
+
+type placementViolations struct {
+	vuids    []proto.Vuid
+	scanTime int64
+}
+
+// ListPlacementViolations return the units which should be migrated to restore spread of volumes,
+// it is the result of last finished placement audit
+func (v *VolumeMgr) ListPlacementViolations(ctx context.Context) *cm.PlacementViolations {
+	ret := &cm.PlacementViolations{Vuids: make([]proto.Vuid, 0)}
+	if val := v.placementViolations.Load(); val != nil {
+		violations := val.(*placementViolations)
+		ret.Vuids = append(ret.Vuids, violations.vuids...)
+		ret.ScanTime = violations.scanTime
+	}
+	return ret
+}
+
+// isPlacementViolation returns true if the unit is found by last placement audit
+func (v *VolumeMgr) isPlacementViolation(vuid proto.Vuid) bool {
+	val := v.placementViolations.Load()
+	if val == nil {
+		return false
+	}
+	vuids := val.(*placementViolations).vuids
+	idx := sort.Search(len(vuids), func(i int) bool { return vuids[i] >= vuid })
+	return idx < len(vuids) && vuids[idx] == vuid
+}
+
+// auditPlacement scan all volumes page by page and record the units
+// which exceed the failure domain of volume's code mode
+func (v *VolumeMgr) auditPlacement(ctx context.Context) error {
+	span := trace.SpanFromContextSafe(ctx)
+	vuids := make([]proto.Vuid, 0)
+	args := &cm.ListFailureDomainViolationsArgs{Count: defaultListVolumeMaxCount}
+	for {
+		ret, err := v.ListFailureDomainViolations(ctx, args)
+		if err != nil {
+			return err
+		}
+		for _, vol := range ret.Volumes {
+			vuids = append(vuids, excessVuids(vol.Violations)...)
+		}
+		if ret.Marker == proto.InvalidVid {
+			break
+		}
+		args.Marker = ret.Marker
+	}
+	sort.Slice(vuids, func(i, j int) bool {
+		return vuids[i] < vuids[j]
+	})
+
+	span.Infof("placement audit finished, violating vuids count: %d", len(vuids))
+	span.Debugf("placement audit violating vuids: %v", vuids)
+	v.placementViolations.Store(&placementViolations{vuids: vuids, scanTime: time.Now().Unix()})
+	return nil
+}
+
+func (v *VolumeMgr) placementAuditLoop() {
+	ticker := time.NewTicker(time.Second * time.Duration(v.PlacementAuditIntervalS))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			span, ctx := trace.StartSpanFromContext(context.Background(), "placement-audit")
+			if err := v.auditPlacement(ctx); err != nil {
+				span.Errorf("placement audit failed: %s", errors.Detail(err))
+			}
+		case <-v.closeLoopChan:
+			return
+		}
+	}
+}
+
+// excessVuids keep the first Limit units of every rack or host, the rest units should be migrated
+func excessVuids(violations []cm.FailureDomainViolation) (ret []proto.Vuid) {
+	excess := make(map[proto.Vuid]bool)
+	for _, violation := range violations {
+		for _, vuid := range violation.Vuids[violation.Limit:] {
+			if !excess[vuid] {
+				excess[vuid] = true
+				ret = append(ret, vuid)
+			}
+		}
+	}
+	return
+}
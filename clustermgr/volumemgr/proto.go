@@ -54,6 +54,7 @@ func (vol *volume) ToRecord() *volumedb.VolumeRecord {
 		Free:           vol.volInfoBase.Free,
 		Used:           vol.volInfoBase.Used,
 		CreateByNodeID: vol.volInfoBase.CreateByNodeID,
+		RedirectVid:    vol.volInfoBase.RedirectVid,
 	}
 }
 
@@ -120,8 +121,9 @@ func (vol *volume) canLock() bool {
 	return vol.getStatus() == proto.VolumeStatusIdle
 }
 
+// redirected volume has been converted into another volume, keep it locked
 func (vol *volume) canUnlock() bool {
-	return vol.getStatus() == proto.VolumeStatusLock
+	return vol.getStatus() == proto.VolumeStatusLock && vol.volInfoBase.RedirectVid == proto.InvalidVid
 }
 
 func (vol *volume) isExpired() bool {
@@ -299,6 +301,7 @@ func volumeRecordToVolumeInfoBase(volRecord *volumedb.VolumeRecord) cm.VolumeInf
 		Total:          volRecord.Total,
 		Free:           volRecord.Free,
 		CreateByNodeID: volRecord.CreateByNodeID,
+		RedirectVid:    volRecord.RedirectVid,
 	}
 }
 
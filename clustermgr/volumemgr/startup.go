@@ -45,6 +45,8 @@ type VolumeMgrConfig struct {
 	RetainThreshold             int `json:"retain_threshold"`
 	FlushIntervalS              int `json:"flush_interval_s"`
 	CheckExpiredVolumeIntervalS int `json:"check_expired_volume_interval_s"`
+	// interval of scanning volume units which violate failure domain of code mode
+	PlacementAuditIntervalS int `json:"placement_audit_interval_s"`
 
 	VolumeSliceMapNum            uint32 `json:"volume_slice_map_num"`
 	ApplyConcurrency             uint32 `json:"apply_concurrency"`
@@ -86,6 +88,9 @@ func (c *VolumeMgrConfig) checkAndFix() {
 	if c.AllocatableDiskLoadThreshold <= 0 {
 		c.AllocatableDiskLoadThreshold = NoDiskLoadThreshold
 	}
+	if c.PlacementAuditIntervalS <= 0 {
+		c.PlacementAuditIntervalS = defaultPlacementAuditIntervalS
+	}
 }
 
 // NewVolumeMgr constructs a new volume manager.
@@ -175,6 +180,7 @@ func (v *VolumeMgr) SetRaftServer(raftServer raftserver.RaftServer) {
 func (v *VolumeMgr) Start() {
 	go v.taskLoop()
 	go v.loop()
+	go v.placementAuditLoop()
 }
 
 func (v *VolumeMgr) loadVolume(ctx context.Context) error {
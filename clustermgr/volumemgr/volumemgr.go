@@ -53,6 +53,7 @@ const (
 	defaultMinAllocableVolumeCount     = 5
 	defaultVolumeSliceMapNum           = 10
 	defaultListVolumeMaxCount          = 500
+	defaultPlacementAuditIntervalS     = 600
 )
 
 // notify queue key definition
@@ -111,6 +112,15 @@ type VolumeMgrAPI interface {
 	LockVolume(ctx context.Context, vid proto.Vid) error
 	UnlockVolume(ctx context.Context, vid proto.Vid) error
 
+	// SetVolumeRedirect redirect reading of locked volume vid into volume redirectVid
+	SetVolumeRedirect(ctx context.Context, vid, redirectVid proto.Vid) error
+
+	// ListFailureDomainViolations list volumes whose units violate failure domain policy of code mode
+	ListFailureDomainViolations(ctx context.Context, args *cm.ListFailureDomainViolationsArgs) (ret *cm.ListFailureDomainViolationsRet, err error)
+
+	// ListPlacementViolations list units found by last placement audit which should be migrated
+	ListPlacementViolations(ctx context.Context) *cm.PlacementViolations
+
 	// Stat return volume statistic info
 	Stat(ctx context.Context) (stat cm.VolumeStatInfo)
 }
@@ -137,9 +147,10 @@ type VolumeMgr struct {
 	configMgr      configmgr.ConfigMgrAPI
 	blobNodeClient blobnode.StorageAPI
 
-	lastFlushTime  time.Time
-	pendingEntries sync.Map
-	codeMode       map[codemode.CodeMode]codeModeConf
+	lastFlushTime       time.Time
+	pendingEntries      sync.Map
+	codeMode            map[codemode.CodeMode]codeModeConf
+	placementViolations atomic.Value
 
 	VolumeMgrConfig
 }
@@ -483,6 +494,59 @@ func (v *VolumeMgr) UnlockVolume(ctx context.Context, vid proto.Vid) error {
 	return nil
 }
 
+func (v *VolumeMgr) SetVolumeRedirect(ctx context.Context, vid, redirectVid proto.Vid) error {
+	span := trace.SpanFromContextSafe(ctx)
+	if vid == redirectVid || redirectVid == proto.InvalidVid {
+		return apierrors.ErrIllegalArguments
+	}
+	vol := v.all.getVol(vid)
+	redirectVol := v.all.getVol(redirectVid)
+	if vol == nil || redirectVol == nil {
+		span.Errorf("volume not found, vid: %d, redirect vid: %d", vid, redirectVid)
+		return apierrors.ErrVolumeNotExist
+	}
+
+	redirectVol.lock.RLock()
+	redirected := redirectVol.volInfoBase.RedirectVid != proto.InvalidVid
+	redirectVol.lock.RUnlock()
+	if redirected {
+		span.Warnf("redirect volume %d has been redirected", redirectVid)
+		return apierrors.ErrIllegalArguments
+	}
+
+	vol.lock.RLock()
+	status := vol.getStatus()
+	oldRedirectVid := vol.volInfoBase.RedirectVid
+	vol.lock.RUnlock()
+	if oldRedirectVid == redirectVid {
+		return nil
+	}
+	if status != proto.VolumeStatusLock || oldRedirectVid != proto.InvalidVid {
+		span.Warnf("can't redirect volume %d, status(%d), redirect vid(%d)", vid, status, oldRedirectVid)
+		return apierrors.ErrIllegalArguments
+	}
+
+	data, err := json.Marshal(&cm.SetVolumeRedirectArgs{Vid: vid, RedirectVid: redirectVid})
+	if err != nil {
+		span.Errorf("json marshal failed, vid: %d, error: %v", vid, err)
+		return apierrors.ErrCMUnexpect
+	}
+	proposeInfo := base.EncodeProposeInfo(v.GetModuleName(), OperTypeSetVolumeRedirect, data, base.ProposeContext{ReqID: span.TraceID()})
+	if err = v.raftServer.Propose(ctx, proposeInfo); err != nil {
+		span.Errorf("raft propose error: %v", err)
+		return apierrors.ErrRaftPropose
+	}
+
+	vol.lock.RLock()
+	oldRedirectVid = vol.volInfoBase.RedirectVid
+	vol.lock.RUnlock()
+	if oldRedirectVid != redirectVid {
+		span.Errorf("volume %d redirect vid(%d) is not %d", vid, oldRedirectVid, redirectVid)
+		return apierrors.ErrCMUnexpect
+	}
+	return nil
+}
+
 func (v *VolumeMgr) Stat(ctx context.Context) (stat cm.VolumeStatInfo) {
 	stat.TotalVolume = defaultVolumeStatusStat.StatTotal()
 	statAllocatable := v.allocator.StatAllocatable()
@@ -664,6 +728,24 @@ func (v *VolumeMgr) applyAdminUpdateVolume(ctx context.Context, volInfo *cm.Volu
 	return err
 }
 
+func (v *VolumeMgr) applySetVolumeRedirect(ctx context.Context, args *cm.SetVolumeRedirectArgs) error {
+	span := trace.SpanFromContextSafe(ctx)
+	vol := v.all.getVol(args.Vid)
+	if vol == nil {
+		span.Errorf("apply set volume redirect, vid %d not exist", args.Vid)
+		return ErrVolumeNotExist
+	}
+	vol.lock.Lock()
+	defer vol.lock.Unlock()
+	// only locked volume can be redirected, and the redirection can not be changed
+	if vol.getStatus() != proto.VolumeStatusLock || vol.volInfoBase.RedirectVid != proto.InvalidVid {
+		span.Warnf("volume can't redirect, status=%d, redirect vid=%d", vol.getStatus(), vol.volInfoBase.RedirectVid)
+		return nil
+	}
+	vol.volInfoBase.RedirectVid = args.RedirectVid
+	return v.volumeTbl.PutVolumeRecord(vol.ToRecord())
+}
+
 func (v *VolumeMgr) applyAdminUpdateVolumeUnit(ctx context.Context, unitInfo *cm.AdminUpdateUnitArgs) error {
 	span := trace.SpanFromContextSafe(ctx)
 	vol := v.all.getVol(unitInfo.Vuid.Vid())
@@ -417,7 +417,7 @@ func TestVolumeMgr_AllocChunkForIdcUnits(t *testing.T) {
 	})
 	_, ctx := trace.StartSpanFromContext(context.Background(), "allocChunkForIdc")
 	mockVolumeMgr.diskMgr = mockDiskMgr
-	mockVolumeMgr.allocChunkForIdcUnits(ctx, "z1", vuInfos)
+	mockVolumeMgr.allocChunkForIdcUnits(ctx, "z1", codemode.EC6P6, vuInfos)
 	for i := range vuInfos {
 		assert.Equal(t, vuInfos[i].DiskID, proto.DiskID(9999))
 	}
@@ -973,6 +973,147 @@ func TestVolumeMgr_UnlockVolume(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestVolumeMgr_ListFailureDomainViolations(t *testing.T) {
+	initMockVolumeMgr(t)
+	defer closeTestVolumeMgr()
+	ctr := gomock.NewController(t)
+	defer ctr.Finish()
+	mockDiskMgr := NewMockDiskMgrAPI(ctr)
+	mockVolumeMgr.diskMgr = mockDiskMgr
+
+	ctx := context.Background()
+	vol3 := mockVolumeMgr.all.getVol(3)
+	violation := clustermgr.FailureDomainViolation{
+		Idc:    "z0",
+		Domain: clustermgr.FailureDomainHost,
+		Name:   "127.0.0.1",
+		Limit:  1,
+		Vuids:  []proto.Vuid{vol3.vUnits[0].vuInfo.Vuid, vol3.vUnits[1].vuInfo.Vuid},
+	}
+	mockDiskMgr.EXPECT().CheckFailureDomain(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(ctx context.Context, mode codemode.CodeMode, units []clustermgr.Unit) ([]clustermgr.FailureDomainViolation, error) {
+			if units[0].Vuid.Vid() == 3 {
+				return []clustermgr.FailureDomainViolation{violation}, nil
+			}
+			return nil, nil
+		})
+
+	ret, err := mockVolumeMgr.ListFailureDomainViolations(ctx, &clustermgr.ListFailureDomainViolationsArgs{Count: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, proto.Vid(5), ret.Marker)
+	assert.Equal(t, 1, len(ret.Volumes))
+	assert.Equal(t, proto.Vid(3), ret.Volumes[0].Vid)
+	assert.Equal(t, vol3.volInfoBase.CodeMode, ret.Volumes[0].CodeMode)
+	assert.Equal(t, []clustermgr.FailureDomainViolation{violation}, ret.Volumes[0].Violations)
+
+	// scan to the end, marker should be reset
+	ret, err = mockVolumeMgr.ListFailureDomainViolations(ctx, &clustermgr.ListFailureDomainViolationsArgs{Marker: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, proto.Vid(0), ret.Marker)
+	assert.Equal(t, 0, len(ret.Volumes))
+
+	// check failed
+	mockDiskMgr = NewMockDiskMgrAPI(ctr)
+	mockVolumeMgr.diskMgr = mockDiskMgr
+	mockDiskMgr.EXPECT().CheckFailureDomain(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, diskmgr.ErrDiskNotExist)
+	_, err = mockVolumeMgr.ListFailureDomainViolations(ctx, &clustermgr.ListFailureDomainViolationsArgs{Count: 5})
+	assert.Error(t, err)
+}
+
+func TestVolumeMgr_PlacementAudit(t *testing.T) {
+	initMockVolumeMgr(t)
+	defer closeTestVolumeMgr()
+	ctr := gomock.NewController(t)
+	defer ctr.Finish()
+	mockDiskMgr := NewMockDiskMgrAPI(ctr)
+	mockVolumeMgr.diskMgr = mockDiskMgr
+
+	ctx := context.Background()
+	ret := mockVolumeMgr.ListPlacementViolations(ctx)
+	assert.Equal(t, 0, len(ret.Vuids))
+	assert.Equal(t, int64(0), ret.ScanTime)
+
+	vol3 := mockVolumeMgr.all.getVol(3)
+	vuids := []proto.Vuid{vol3.vUnits[0].vuInfo.Vuid, vol3.vUnits[1].vuInfo.Vuid, vol3.vUnits[2].vuInfo.Vuid}
+	mockDiskMgr.EXPECT().CheckFailureDomain(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(ctx context.Context, mode codemode.CodeMode, units []clustermgr.Unit) ([]clustermgr.FailureDomainViolation, error) {
+			if units[0].Vuid.Vid() != 3 {
+				return nil, nil
+			}
+			return []clustermgr.FailureDomainViolation{
+				{Domain: clustermgr.FailureDomainRack, Limit: 1, Vuids: vuids},
+				{Domain: clustermgr.FailureDomainHost, Limit: 1, Vuids: vuids[1:]},
+			}, nil
+		})
+
+	err := mockVolumeMgr.auditPlacement(ctx)
+	assert.NoError(t, err)
+	ret = mockVolumeMgr.ListPlacementViolations(ctx)
+	assert.Equal(t, vuids[1:], ret.Vuids)
+	assert.NotEqual(t, int64(0), ret.ScanTime)
+
+	// failed audit keeps the last result
+	mockDiskMgr = NewMockDiskMgrAPI(ctr)
+	mockVolumeMgr.diskMgr = mockDiskMgr
+	mockDiskMgr.EXPECT().CheckFailureDomain(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, diskmgr.ErrDiskNotExist)
+	err = mockVolumeMgr.auditPlacement(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, vuids[1:], mockVolumeMgr.ListPlacementViolations(ctx).Vuids)
+}
+
+func TestVolumeMgr_SetVolumeRedirect(t *testing.T) {
+	initMockVolumeMgr(t)
+	defer closeTestVolumeMgr()
+	ctr := gomock.NewController(t)
+	defer ctr.Finish()
+	mockRaftServer := mocks.NewMockRaftServer(ctr)
+	mockVolumeMgr.raftServer = mockRaftServer
+	mockRaftServer.EXPECT().Propose(gomock.Any(), gomock.Any()).Return(nil)
+
+	ctx := context.Background()
+	// failed case: illegal arguments or vid not exist
+	err := mockVolumeMgr.SetVolumeRedirect(ctx, 2, 2)
+	assert.Error(t, err)
+	err = mockVolumeMgr.SetVolumeRedirect(ctx, 2, 55)
+	assert.Error(t, err)
+
+	// failed case: volume not locked
+	err = mockVolumeMgr.SetVolumeRedirect(ctx, 2, 3)
+	assert.Error(t, err)
+
+	vol2 := mockVolumeMgr.all.getVol(2)
+	vol2.lock.Lock()
+	vol2.volInfoBase.Status = proto.VolumeStatusLock
+	vol2.lock.Unlock()
+
+	// not apply
+	err = mockVolumeMgr.SetVolumeRedirect(ctx, 2, 3)
+	assert.Error(t, err)
+
+	err = mockVolumeMgr.applySetVolumeRedirect(ctx, &clustermgr.SetVolumeRedirectArgs{Vid: 2, RedirectVid: 3})
+	assert.NoError(t, err)
+	ret, err := mockVolumeMgr.GetVolumeInfo(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, proto.Vid(3), ret.RedirectVid)
+
+	// redirect again is idempotent, and redirection can not be changed
+	err = mockVolumeMgr.SetVolumeRedirect(ctx, 2, 3)
+	assert.NoError(t, err)
+	err = mockVolumeMgr.SetVolumeRedirect(ctx, 2, 4)
+	assert.Error(t, err)
+	err = mockVolumeMgr.applySetVolumeRedirect(ctx, &clustermgr.SetVolumeRedirectArgs{Vid: 2, RedirectVid: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, proto.Vid(3), vol2.volInfoBase.RedirectVid)
+
+	// failed case: redirect into redirected volume
+	err = mockVolumeMgr.SetVolumeRedirect(ctx, 4, 2)
+	assert.Error(t, err)
+
+	// redirected volume can not unlock
+	err = mockVolumeMgr.UnlockVolume(ctx, 2)
+	assert.Error(t, err)
+}
+
 func TestVolumeMgr_Report(t *testing.T) {
 	initMockVolumeMgr(t)
 	defer closeTestVolumeMgr()
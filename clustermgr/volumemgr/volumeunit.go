@@ -86,11 +86,16 @@ func (v *VolumeMgr) AllocVolumeUnit(ctx context.Context, vuid proto.Vuid) (*cmap
 	}
 
 	excludes := make([]proto.DiskID, 0)
+	placed := make([]cmapi.Unit, 0)
 	targetDiskID := proto.DiskID(0)
 	vol.lock.RLock()
+	mode := vol.volInfoBase.CodeMode
 	targetDiskID = vol.vUnits[vuid.Index()].vuInfo.DiskID
 	for _, vu := range vol.vUnits {
 		excludes = append(excludes, vu.vuInfo.DiskID)
+		if vu.vuInfo.Vuid.Index() != vuid.Index() {
+			placed = append(placed, cmapi.Unit{Vuid: vu.vuInfo.Vuid, DiskID: vu.vuInfo.DiskID, Host: vu.vuInfo.Host})
+		}
 	}
 	vol.lock.RUnlock()
 
@@ -99,7 +104,16 @@ func (v *VolumeMgr) AllocVolumeUnit(ctx context.Context, vuid proto.Vuid) (*cmap
 		return nil, errors.Info(err, "get disk info failed").Detail(err)
 	}
 
-	policy := &diskmgr.AllocPolicy{Idc: diskInfo.Idc, Vuids: []proto.Vuid{newVuid.(proto.Vuid)}, Excludes: excludes}
+	// destination of unit violating failure domain must follow it,
+	// or it will be found and migrated again by next placement audit
+	policy := &diskmgr.AllocPolicy{
+		Idc:           diskInfo.Idc,
+		Vuids:         []proto.Vuid{newVuid.(proto.Vuid)},
+		Excludes:      excludes,
+		CodeMode:      mode,
+		Placed:        placed,
+		FailureDomain: v.isPlacementViolation(vuid),
+	}
 	allocDiskID, err := v.diskMgr.AllocChunks(ctx, policy)
 	if err != nil {
 		return nil, errors.Info(err, "alloc chunk failed").Detail(err)
@@ -119,6 +133,9 @@ func (v *VolumeMgr) PreUpdateVolumeUnit(ctx context.Context, args *cmapi.UpdateV
 	defer vol.lock.RUnlock()
 
 	unit := vol.vUnits[args.OldVuid.Index()]
+	if args.NewVuid == args.OldVuid {
+		return v.preMigrateVolumeUnit(ctx, unit, args)
+	}
 	if (proto.EncodeVuid(unit.vuidPrefix, unit.epoch) != args.OldVuid &&
 		proto.EncodeVuid(unit.vuidPrefix, unit.epoch) != args.NewVuid) ||
 		unit.nextEpoch < args.OldVuid.Epoch() {
@@ -152,6 +169,41 @@ func (v *VolumeMgr) PreUpdateVolumeUnit(ctx context.Context, args *cmapi.UpdateV
 	return nil
 }
 
+// preMigrateVolumeUnit check volume unit migrated to another disk on the same host, vuid is not changed
+func (v *VolumeMgr) preMigrateVolumeUnit(ctx context.Context, unit *volumeUnit, args *cmapi.UpdateVolumeArgs) (err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	if proto.EncodeVuid(unit.vuidPrefix, unit.epoch) != args.OldVuid {
+		span.Errorf("volume's vuid is %v", proto.EncodeVuid(unit.vuidPrefix, unit.epoch))
+		return ErrOldVuidNotMatch
+	}
+	// idempotent retry migrate volume unit
+	if unit.vuInfo.DiskID == args.NewDiskID {
+		return ErrRepeatUpdateUnit
+	}
+
+	diskInfo, err := v.diskMgr.GetDiskInfo(ctx, args.NewDiskID)
+	if err != nil {
+		span.Errorf("new diskID:%v not exist", args.NewDiskID)
+		return apierrors.ErrCMDiskNotFound
+	}
+	if diskInfo.Host != unit.vuInfo.Host {
+		span.Errorf("new diskID:%v host:%s, volume unit host:%s", args.NewDiskID, diskInfo.Host, unit.vuInfo.Host)
+		return ErrNewDiskIDNotMatch
+	}
+	chunkInfo, err := v.blobNodeClient.StatChunk(ctx, diskInfo.Host, &blobnode.StatChunkArgs{DiskID: args.NewDiskID, Vuid: args.NewVuid})
+	if err != nil {
+		span.Errorf("stat blob node chunk, disk id[%d], vuid[%d] failed: %s", args.NewDiskID, args.NewVuid, err.Error())
+		return apierrors.ErrStatChunkFailed
+	}
+	if chunkInfo == nil || chunkInfo.DiskID != args.NewDiskID {
+		span.Errorf("new diskID:%v not match", args.NewDiskID)
+		return ErrNewDiskIDNotMatch
+	}
+
+	return nil
+}
+
 // ReleaseVolumeUnit release old volumeUnit's old chunk
 func (v *VolumeMgr) ReleaseVolumeUnit(ctx context.Context, vuid proto.Vuid, diskID proto.DiskID, force bool) (err error) {
 	diskInfo, err := v.diskMgr.GetDiskInfo(ctx, diskID)
@@ -184,14 +236,16 @@ func (v *VolumeMgr) applyUpdateVolumeUnit(ctx context.Context, newVuid proto.Vui
 
 	vol.lock.Lock()
 
-	if vol.vUnits[index].vuInfo.Vuid == newVuid {
+	// vuid not changed when volume unit migrated to another disk
+	migrated := vol.vUnits[index].vuInfo.Vuid == newVuid
+	if migrated && vol.vUnits[index].vuInfo.DiskID == newDiskID {
 		vol.lock.Unlock()
 		return nil
 	}
 
 	// when apply wal log happened, the next epoch of volume unit in db may larger than args new vuid's epoch
 	// just return nil in this situation
-	if vol.vUnits[index].nextEpoch > newVuid.Epoch() {
+	if !migrated && vol.vUnits[index].nextEpoch > newVuid.Epoch() {
 		span.Debugf("vol nextEpoch: %d bigger than newVuid Epoch : %d", vol.vUnits[index].nextEpoch, newVuid.Epoch())
 		vol.lock.Unlock()
 		return nil
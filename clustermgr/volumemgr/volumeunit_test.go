@@ -53,7 +53,9 @@ func TestVolumeMgr_AllocVolumeUnit(t *testing.T) {
 	mockRaftServer := mocks.NewMockRaftServer(ctr)
 	mockRaftServer.EXPECT().IsLeader().AnyTimes().Return(false)
 	mockDiskMgr := NewMockDiskMgrAPI(ctr)
+	var failureDomain bool
 	mockDiskMgr.EXPECT().AllocChunks(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(ctx context.Context, policy *diskmgr.AllocPolicy) ([]proto.DiskID, error) {
+		failureDomain = policy.FailureDomain
 		var diskids []proto.DiskID
 		for i := range policy.Vuids {
 			diskids = append(diskids, proto.DiskID(i+1))
@@ -96,6 +98,21 @@ func TestVolumeMgr_AllocVolumeUnit(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, ret.Vuid, proto.EncodeVuid(vuidPrefix, 3))
 	assert.NotEqual(t, ret.DiskID, 0)
+	assert.False(t, failureDomain)
+
+	// unit violating failure domain is allocated following it
+	mockVolumeMgr.placementViolations.Store(&placementViolations{vuids: []proto.Vuid{proto.EncodeVuid(vuidPrefix, 1)}})
+	mockRaftServer.EXPECT().Propose(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, data []byte) error {
+		mockVolumeMgr.pendingEntries.Range(func(key, value interface{}) bool {
+			mockVolumeMgr.pendingEntries.Store(key, proto.EncodeVuid(vuidPrefix, 3))
+			return true
+		})
+		return nil
+	})
+	_, err = mockVolumeMgr.AllocVolumeUnit(ctx, proto.EncodeVuid(vuidPrefix, 1))
+	assert.NoError(t, err)
+	assert.True(t, failureDomain)
+	mockVolumeMgr.placementViolations.Store(&placementViolations{})
 
 	// failed case,raft propose error
 	mockRaftServer.EXPECT().Propose(gomock.Any(), gomock.Any()).Return(errors.New("error"))
@@ -207,6 +224,24 @@ func TestVolumeMgr_updateVolumeUnit(t *testing.T) {
 		args.NewVuid = proto.EncodeVuid(vuidPrefix1, 222)
 		err = mockVolumeMgr.PreUpdateVolumeUnit(context.Background(), args)
 		assert.Equal(t, ErrNewVuidNotMatch, err)
+
+		// success case, migrate to another disk of the same host
+		args.NewVuid = proto.EncodeVuid(vuidPrefix1, 1)
+		err = mockVolumeMgr.PreUpdateVolumeUnit(context.Background(), args)
+		assert.NoError(t, err)
+
+		// failed case, migrate vuid not match
+		args.OldVuid = proto.EncodeVuid(vuidPrefix1, 3)
+		args.NewVuid = proto.EncodeVuid(vuidPrefix1, 3)
+		err = mockVolumeMgr.PreUpdateVolumeUnit(context.Background(), args)
+		assert.Equal(t, ErrOldVuidNotMatch, err)
+
+		// repeat migrate
+		args.OldVuid = proto.EncodeVuid(vuidPrefix1, 1)
+		args.NewVuid = proto.EncodeVuid(vuidPrefix1, 1)
+		args.NewDiskID = 1
+		err = mockVolumeMgr.PreUpdateVolumeUnit(context.Background(), args)
+		assert.Equal(t, ErrRepeatUpdateUnit, err)
 	}
 
 	// test applyUpdateVolumeUnit()
@@ -255,6 +290,17 @@ func TestVolumeMgr_updateVolumeUnit(t *testing.T) {
 		err = mockVolumeMgr.applyUpdateVolumeUnit(ctx, proto.EncodeVuid(proto.EncodeVuidPrefix(2, 55), 1), 30)
 		assert.Error(t, err)
 
+		// success case, migrate to another disk, vuid not changed
+		volInfo.lock.Lock()
+		volInfo.vUnits[0].nextEpoch = 3
+		volInfo.lock.Unlock()
+		err = mockVolumeMgr.applyUpdateVolumeUnit(ctx, proto.EncodeVuid(proto.EncodeVuidPrefix(3, 0), 2), 5)
+		assert.NoError(t, err)
+		volInfo.lock.RLock()
+		assert.Equal(t, uint32(2), volInfo.vUnits[0].epoch)
+		assert.Equal(t, proto.DiskID(5), volInfo.vUnits[0].vuInfo.DiskID)
+		volInfo.lock.RUnlock()
+
 	}
 
 	// test applyUpdateVolumeUnit, refresh health return err
@@ -0,0 +1,21 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build dedup_kvstore
+// +build dedup_kvstore
+
+package main
+
+// local rocksdb dedup index requires cgo, build with -tags dedup_kvstore to enable it
+import _ "github.com/cubefs/blobstore/access/dedup"
@@ -27,4 +27,8 @@ type Policy struct {
 	// access/allocator will ignore this kind of code mode's allocation when enable is false
 	// clustermgr will ignore this kind of code mode's creation when enable is false
 	Enable bool `json:"enable"`
+	// max units of one volume in the same rack or host of an idc, clustermgr
+	// uses M/AZCount per rack and 1 per host when they are not set
+	MaxUnitsPerRack int `json:"max_units_per_rack,omitempty"`
+	MaxUnitsPerHost int `json:"max_units_per_host,omitempty"`
 }
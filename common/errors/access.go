@@ -22,6 +22,12 @@ const (
 	CodeAccessServiceDiscovery = 551 // service discovery for access api client
 	CodeAccessLimited          = 552 // read write limited for access api client
 	CodeAccessExceedSize       = 553 // exceed max size
+	CodeAccessMultipartNoSuch  = 554 // no such multipart upload
+	CodeAccessMultipartParts   = 555 // missing parts of multipart upload
+	CodeAccessContentMismatch  = 556 // mismatched content hash
+	CodeAccessExceedQuota      = 557 // exceed stored bytes quota of tenant
+	CodeAccessEncrypted        = 558 // unsupported request while encryption is enabled
+	CodeAccessNoTenant         = 559 // request without tenant while tenant is enabled
 )
 
 // errro of access
@@ -32,4 +38,10 @@ var (
 	ErrAccessServiceDiscovery = Error(CodeAccessServiceDiscovery)
 	ErrAccessLimited          = Error(CodeAccessLimited)
 	ErrAccessExceedSize       = Error(CodeAccessExceedSize)
+	ErrAccessMultipartNoSuch  = Error(CodeAccessMultipartNoSuch)
+	ErrAccessMultipartParts   = Error(CodeAccessMultipartParts)
+	ErrAccessContentMismatch  = Error(CodeAccessContentMismatch)
+	ErrAccessExceedQuota      = Error(CodeAccessExceedQuota)
+	ErrAccessEncrypted        = Error(CodeAccessEncrypted)
+	ErrAccessNoTenant         = Error(CodeAccessNoTenant)
 )
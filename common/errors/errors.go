@@ -54,6 +54,12 @@ var errCodeMap = map[int]string{
 	CodeAccessServiceDiscovery: "access client service discovery disconnect",
 	CodeAccessLimited:          "access limited",
 	CodeAccessExceedSize:       "access exceed object size",
+	CodeAccessMultipartNoSuch:  "access no such multipart upload",
+	CodeAccessMultipartParts:   "access multipart upload missing parts",
+	CodeAccessContentMismatch:  "access mismatched content hash",
+	CodeAccessExceedQuota:      "access exceed quota of tenant",
+	CodeAccessEncrypted:        "access unsupported with encryption enabled",
+	CodeAccessNoTenant:         "access request without tenant",
 
 	// clustermgr
 	CodeCMUnexpect:                "cm: unexpected error",
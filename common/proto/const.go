@@ -107,4 +107,5 @@ const (
 	CodeModeConfigKey    = "code_mode"
 	VolumeReserveSizeKey = "volume_reserve_size"
 	VolumeChunkSizeKey   = "volume_chunk_size"
+	TenantLimitConfigKey = "tenant_limit"
 )
@@ -59,6 +59,7 @@ const (
 	BalanceTaskType   = "balance_task"
 	DiskDropTaskType  = "disk_drop_task"
 	ManualMigrateType = "manual_migrate"
+	VolumeConvertType = "volume_convert"
 )
 
 type RepairState uint8
@@ -209,6 +210,95 @@ func (t *MigrateTask) SrcMigDiskID() DiskID {
 	return t.SourceDiskID
 }
 
+type VolumeConvertState uint8
+
+const (
+	VolumeConvertStateInited VolumeConvertState = iota + 1
+	VolumeConvertStatePrepared
+	VolumeConvertStateWorkCompleted
+	VolumeConvertStateFinished
+	VolumeConvertStateFinishedInAdvance
+)
+
+// VolumeConvertTask re-encodes all blobs of source volume into destination volume
+// of another code mode, blob ids are kept the same in destination volume
+type VolumeConvertTask struct {
+	TaskID        string             `json:"task_id" bson:"_id"`                     // task id
+	State         VolumeConvertState `json:"state" bson:"state"`                     // task state
+	WorkerRedoCnt uint8              `json:"worker_redo_cnt" bson:"worker_redo_cnt"` // worker redo task count
+
+	SourceIdc      string            `json:"source_idc" bson:"source_idc"`             // source idc
+	SourceVid      Vid               `json:"source_vid" bson:"source_vid"`             // source volume id
+	SourceCodeMode codemode.CodeMode `json:"source_code_mode" bson:"source_code_mode"` // source codemode
+	Sources        []VunitLocation   `json:"sources" bson:"sources"`                   // source volume units location
+
+	DestinationVid      Vid               `json:"destination_vid" bson:"destination_vid"`             // destination volume id
+	DestinationCodeMode codemode.CodeMode `json:"destination_code_mode" bson:"destination_code_mode"` // destination codemode
+	Destinations        []VunitLocation   `json:"destinations" bson:"destinations"`                   // destination volume units location
+
+	Ctime string `json:"ctime" bson:"ctime"` // create time
+	MTime string `json:"mtime" bson:"mtime"` // modify time
+
+	// unix time when source volume is redirected, source units are released a while after it
+	RedirectTime int64 `json:"redirect_time" bson:"redirect_time"`
+
+	FinishAdvanceReason string `json:"finish_advance_reason" bson:"finish_advance_reason"`
+}
+
+func (t *VolumeConvertTask) GetSrc() []VunitLocation {
+	return t.Sources
+}
+
+// GetDest returns the first destination unit which identifies the destination volume
+func (t *VolumeConvertTask) GetDest() VunitLocation {
+	if len(t.Destinations) == 0 {
+		return VunitLocation{}
+	}
+	return t.Destinations[0]
+}
+
+// SetDest replaces the destination unit with the same index
+func (t *VolumeConvertTask) SetDest(dest VunitLocation) {
+	idx := int(dest.Vuid.Index())
+	if idx < len(t.Destinations) {
+		t.Destinations[idx] = dest
+	}
+}
+
+// IsValid checks code modes and units of both volumes
+func (t *VolumeConvertTask) IsValid() bool {
+	if !t.SourceCodeMode.IsValid() || !t.DestinationCodeMode.IsValid() {
+		return false
+	}
+	if len(t.Sources) != t.SourceCodeMode.GetShardNum() ||
+		len(t.Destinations) != t.DestinationCodeMode.GetShardNum() {
+		return false
+	}
+	return CheckVunitLocations(t.Sources) && CheckVunitLocations(t.Destinations)
+}
+
+// Redirected returns true if source volume has been redirected to destination,
+// blobs are deleted in destination volume since then
+func (t *VolumeConvertTask) Redirected() bool {
+	return t.RedirectTime > 0
+}
+
+func (t *VolumeConvertTask) Running() bool {
+	return t.State == VolumeConvertStatePrepared || t.State == VolumeConvertStateWorkCompleted
+}
+
+func (t *VolumeConvertTask) Finished() bool {
+	return t.State == VolumeConvertStateFinished || t.State == VolumeConvertStateFinishedInAdvance
+}
+
+func (t *VolumeConvertTask) Copy() *VolumeConvertTask {
+	task := &VolumeConvertTask{}
+	*task = *t
+	task.Sources = append([]VunitLocation(nil), t.Sources...)
+	task.Destinations = append([]VunitLocation(nil), t.Destinations...)
+	return task
+}
+
 type InspectCheckPoint struct {
 	Id       string `json:"_id" bson:"_id"`
 	StartVid Vid    `json:"start_vid" bson:"start_vid"` // min vid in current batch volumes
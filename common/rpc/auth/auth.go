@@ -28,6 +28,8 @@ const (
 	TokenKeyLenth = 16
 
 	TokenHeaderKey = "BLOB-STORE-AUTH-TOKEN"
+	// TenantHeaderKey identity of tenant, signed in token if auth enabled
+	TenantHeaderKey = "BLOB-STORE-AUTH-TENANT"
 )
 
 var errMismatchToken = errors.New("mismatch token")
@@ -35,6 +37,8 @@ var errMismatchToken = errors.New("mismatch token")
 type Config struct {
 	EnableAuth bool   `json:"enable_auth"`
 	Secret     string `json:"secret"`
+	// Tenant identity of client, sent in header of every request
+	Tenant string `json:"tenant"`
 }
 
 // simply: use timestamp as a token calculate param
@@ -96,7 +100,17 @@ func verify(info *authInfo, secret []byte) (err error) {
 	return
 }
 
+// tenant is signed if present, so that it can not be added to request without tenant,
+// and request without tenant is signed the same as the versions before tenant.
 func genEncodeStr(req *http.Request) []byte {
 	calStr := req.URL.Path + req.URL.RawQuery
+	if tenant := req.Header.Get(TenantHeaderKey); tenant != "" {
+		calStr += "\n" + tenant
+	}
 	return []byte(calStr)
 }
+
+// Tenant returns tenant identity of the request
+func Tenant(req *http.Request) string {
+	return req.Header.Get(TenantHeaderKey)
+}
@@ -43,9 +43,24 @@ func init() {
 			w.Write(data)
 		})
 	})
+	http.HandleFunc("/get/tenant", func(w http.ResponseWriter, r *http.Request) {
+		authHandler.Handler(w, r, func(w http.ResponseWriter, r *http.Request) {
+			data, _ := json.Marshal(&ret{Name: Tenant(r)})
+			w.Write(data)
+		})
+	})
 	testServer = httptest.NewServer(http.DefaultServeMux)
 }
 
+type tamperTransport struct {
+	tr http.RoundTripper
+}
+
+func (t *tamperTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set(TenantHeaderKey, "tampered")
+	return t.tr.RoundTrip(req)
+}
+
 func TestAuth(t *testing.T) {
 	// invalid secret
 	tc := NewAuthTransport(&http.Transport{}, &Config{EnableAuth: true, Secret: "wrongSecret"})
@@ -75,3 +90,48 @@ func TestAuth(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, testName, result.Name)
 }
+
+func TestAuthTenant(t *testing.T) {
+	getTenant := func(tr http.RoundTripper) (int, string) {
+		client := http.Client{Transport: tr}
+		req, err := http.NewRequest("POST", testServer.URL+"/get/tenant?id=1", nil)
+		assert.NoError(t, err)
+		response, err := client.Do(req)
+		assert.NoError(t, err)
+		defer response.Body.Close()
+		result := &ret{}
+		if response.StatusCode == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(result))
+		}
+		return response.StatusCode, result.Name
+	}
+
+	tc := NewAuthTransport(&http.Transport{}, &Config{EnableAuth: true, Secret: testSecret, Tenant: "foo"})
+	code, tenant := getTenant(tc)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "foo", tenant)
+
+	// tenant is signed in token
+	tc = NewAuthTransport(&tamperTransport{tr: &http.Transport{}},
+		&Config{EnableAuth: true, Secret: testSecret, Tenant: "foo"})
+	code, _ = getTenant(tc)
+	assert.Equal(t, http.StatusForbidden, code)
+	// tenant added to request signed without tenant
+	tc = NewAuthTransport(&tamperTransport{tr: &http.Transport{}},
+		&Config{EnableAuth: true, Secret: testSecret})
+	code, _ = getTenant(tc)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = getTenant(NewTenantTransport(&http.Transport{}, "foo"))
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestAuthEncodeStrCompatible(t *testing.T) {
+	req, err := http.NewRequest("POST", testServer.URL+"/get/name?id=1", nil)
+	assert.NoError(t, err)
+	// the same as versions without tenant
+	assert.Equal(t, []byte("/get/nameid=1"), genEncodeStr(req))
+
+	req.Header.Set(TenantHeaderKey, "foo")
+	assert.Equal(t, []byte("/get/nameid=1\nfoo"), genEncodeStr(req))
+}
@@ -21,6 +21,7 @@ import (
 
 type AuthTransport struct {
 	Secret []byte
+	Tenant string
 	Tr     http.RoundTripper
 }
 
@@ -31,18 +32,37 @@ func NewAuthTransport(tr http.RoundTripper, cfg *Config) http.RoundTripper {
 		}
 		return &AuthTransport{
 			Secret: []byte(cfg.Secret),
+			Tenant: cfg.Tenant,
 			Tr:     tr,
 		}
 	}
 	return nil
 }
 
+// TenantTransport sets tenant header without auth token
+type TenantTransport struct {
+	Tenant string
+	Tr     http.RoundTripper
+}
+
+func NewTenantTransport(tr http.RoundTripper, tenant string) http.RoundTripper {
+	return &TenantTransport{Tenant: tenant, Tr: tr}
+}
+
+func (self *TenantTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set(TenantHeaderKey, self.Tenant)
+	return self.Tr.RoundTrip(req)
+}
+
 // a simple auth token
 func (self *AuthTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
 	now := time.Now().Unix()
 	if err != nil {
 		return self.Tr.RoundTrip(req)
 	}
+	if self.Tenant != "" {
+		req.Header.Set(TenantHeaderKey, self.Tenant)
+	}
 
 	info := &authInfo{timestamp: now, others: genEncodeStr(req)}
 
@@ -68,5 +68,8 @@ func NewTransport(cfg *TransportConfig) http.RoundTripper {
 			return authTr
 		}
 	}
+	if cfg.Auth.Tenant != "" {
+		return auth.NewTenantTransport(tr, cfg.Auth.Tenant)
+	}
 	return tr
 }
@@ -31,6 +31,7 @@ const (
 	BlobDeleteSwitchName  = "blob_delete"
 	ShardRepairSwitchName = "shard_repair"
 	VolInspectSwitchName  = "vol_inspect"
+	PlacementSwitchName   = "placement"
 )
 
 const (
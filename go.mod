@@ -5,6 +5,7 @@ go 1.15
 replace github.com/desertbit/grumble v1.1.1 => github.com/sejust/grumble v1.1.2-0.20210930091007-2c8622e565d3
 
 require (
+	github.com/DataDog/zstd v1.3.6-0.20190409195224-796139022798
 	github.com/Shopify/sarama v1.22.1
 	github.com/afex/hystrix-go v0.0.0-20180502004556-fa1af6a1f4f5
 	github.com/alicebob/miniredis/v2 v2.16.1
@@ -16,6 +17,7 @@ require (
 	github.com/globalsign/mgo v0.0.0-20181015135952-eeefdecb41b8
 	github.com/go-redis/redis/v8 v8.11.4
 	github.com/golang/mock v1.6.0
+	github.com/golang/snappy v0.0.1
 	github.com/google/uuid v1.3.0
 	github.com/gorilla/mux v1.8.0
 	github.com/hashicorp/consul/api v1.11.0
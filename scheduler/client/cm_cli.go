@@ -40,6 +40,9 @@ type VolumeInfoSimple struct {
 	Vid            proto.Vid             `json:"vid"`
 	CodeMode       codemode.CodeMode     `json:"code_mode"`
 	Status         proto.VolumeStatus    `json:"status"`
+	Used           uint64                `json:"used"`
+	Free           uint64                `json:"free"`
+	RedirectVid    proto.Vid             `json:"redirect_vid"`
 	VunitLocations []proto.VunitLocation `json:"vunit_locations"`
 }
 
@@ -57,6 +60,9 @@ func (vol *VolumeInfoSimple) set(info *cmapi.VolumeInfo) {
 	vol.Vid = info.Vid
 	vol.CodeMode = info.CodeMode
 	vol.Status = info.Status
+	vol.Used = info.Used
+	vol.Free = info.Free
+	vol.RedirectVid = info.RedirectVid
 	vol.VunitLocations = make([]proto.VunitLocation, len(info.Units))
 
 	// check volume info
@@ -199,6 +205,9 @@ type IClusterManager interface {
 	GetVolumeInfo(ctx context.Context, args *cmapi.GetVolumeArgs) (ret *cmapi.VolumeInfo, err error)
 	LockVolume(ctx context.Context, args *cmapi.LockVolumeArgs) (err error)
 	UnlockVolume(ctx context.Context, args *cmapi.UnlockVolumeArgs) (err error)
+	AllocVolume(ctx context.Context, args *cmapi.AllocVolumeArgs) (ret cmapi.AllocatedVolumeInfos, err error)
+	SetVolumeRedirect(ctx context.Context, args *cmapi.SetVolumeRedirectArgs) (err error)
+	ListPlacementViolations(ctx context.Context) (ret cmapi.PlacementViolations, err error)
 	UpdateVolume(ctx context.Context, args *cmapi.UpdateVolumeArgs) (err error)
 	AllocVolumeUnit(ctx context.Context, args *cmapi.AllocVolumeUnitArgs) (ret *cmapi.AllocVolumeUnit, err error)
 	ReleaseVolumeUnit(ctx context.Context, args *cmapi.ReleaseVolumeUnitArgs) (err error)
@@ -300,6 +309,60 @@ func (c *ClusterMgrClient) UnlockVolume(ctx context.Context, vid proto.Vid) (err
 	return
 }
 
+// AllocVolume alloc a new volume with code mode
+func (c *ClusterMgrClient) AllocVolume(ctx context.Context, mode codemode.CodeMode) (*VolumeInfoSimple, error) {
+	c.rwLock.Lock()
+	defer c.rwLock.Unlock()
+
+	pSpan := trace.SpanFromContextSafe(ctx)
+	span, ctx := trace.StartSpanFromContextWithTraceID(context.Background(), "AllocVolume", pSpan.TraceID())
+
+	span.Infof("AllocVolume args code mode %s", mode)
+	ret, err := c.cli.AllocVolume(ctx, &cmapi.AllocVolumeArgs{CodeMode: mode, Count: 1})
+	if err != nil {
+		span.Errorf("AllocVolume fail err %+v", err)
+		return nil, err
+	}
+	if len(ret.AllocVolumeInfos) == 0 {
+		return nil, errors.New("no volume allocated")
+	}
+	span.Infof("AllocVolume ret vid %d", ret.AllocVolumeInfos[0].Vid)
+	vol := &VolumeInfoSimple{}
+	vol.set(&ret.AllocVolumeInfos[0].VolumeInfo)
+	return vol, nil
+}
+
+// SetVolumeRedirect redirect reading of volume vid into volume redirectVid
+func (c *ClusterMgrClient) SetVolumeRedirect(ctx context.Context, vid, redirectVid proto.Vid) (err error) {
+	c.rwLock.Lock()
+	defer c.rwLock.Unlock()
+
+	pSpan := trace.SpanFromContextSafe(ctx)
+	span, ctx := trace.StartSpanFromContextWithTraceID(context.Background(), "SetVolumeRedirect", pSpan.TraceID())
+
+	span.Infof("SetVolumeRedirect args vid %d redirect vid %d", vid, redirectVid)
+	err = c.cli.SetVolumeRedirect(ctx, &cmapi.SetVolumeRedirectArgs{Vid: vid, RedirectVid: redirectVid})
+	span.Infof("SetVolumeRedirect ret err %+v", err)
+	return
+}
+
+// ListPlacementViolations returns volume units which should be migrated to restore spread of volumes
+func (c *ClusterMgrClient) ListPlacementViolations(ctx context.Context) (vuids []proto.Vuid, err error) {
+	c.rwLock.RLock()
+	defer c.rwLock.RUnlock()
+
+	pSpan := trace.SpanFromContextSafe(ctx)
+	span, ctx := trace.StartSpanFromContextWithTraceID(context.Background(), "ListPlacementViolations", pSpan.TraceID())
+
+	ret, err := c.cli.ListPlacementViolations(ctx)
+	if err != nil {
+		span.Errorf("ListPlacementViolations fail err %+v", err)
+		return
+	}
+	span.Debugf("ListPlacementViolations ret vuids %v scan time %d", ret.Vuids, ret.ScanTime)
+	return ret.Vuids, nil
+}
+
 // UpdateVolume update volume
 func (c *ClusterMgrClient) UpdateVolume(ctx context.Context, newVuid, oldVuid proto.Vuid, newDiskID proto.DiskID) (err error) {
 	c.rwLock.Lock()
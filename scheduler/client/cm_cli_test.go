@@ -56,6 +56,8 @@ type mockCM struct {
 	diskMap   map[proto.DiskID]*blobnode.DiskInfo
 	diskSlice []*blobnode.DiskInfo
 	diskRW    sync.RWMutex
+
+	placementVuids []proto.Vuid
 }
 
 func newMockCM() *mockCM {
@@ -100,7 +102,7 @@ func (c *mockCM) GetVolumeInfo(ctx context.Context, args *cmapi.GetVolumeArgs) (
 	return vol, nil
 }
 
-func (c *mockCM) AllocVolume(ctx context.Context, codeMode codemode.CodeMode, baseDiskID proto.DiskID) (ret *cmapi.VolumeInfo, err error) {
+func (c *mockCM) allocVolume(ctx context.Context, codeMode codemode.CodeMode, baseDiskID proto.DiskID) (ret *cmapi.VolumeInfo, err error) {
 	c.volRW.Lock()
 	defer c.volRW.Unlock()
 
@@ -136,6 +138,33 @@ func (c *mockCM) AllocVolume(ctx context.Context, codeMode codemode.CodeMode, ba
 	return
 }
 
+func (c *mockCM) AllocVolume(ctx context.Context, args *cmapi.AllocVolumeArgs) (ret cmapi.AllocatedVolumeInfos, err error) {
+	vol, err := c.allocVolume(ctx, args.CodeMode, 1)
+	if err != nil {
+		return
+	}
+	ret.AllocVolumeInfos = append(ret.AllocVolumeInfos, cmapi.AllocVolumeInfo{VolumeInfo: *vol})
+	return
+}
+
+func (c *mockCM) SetVolumeRedirect(ctx context.Context, args *cmapi.SetVolumeRedirectArgs) (err error) {
+	c.volRW.Lock()
+	defer c.volRW.Unlock()
+
+	vol, ok := c.volumeMap[args.Vid]
+	if !ok {
+		return cmerrors.ErrVolumeNotExist
+	}
+	vol.RedirectVid = args.RedirectVid
+	return
+}
+
+func (c *mockCM) ListPlacementViolations(ctx context.Context) (ret cmapi.PlacementViolations, err error) {
+	c.volRW.RLock()
+	defer c.volRW.RUnlock()
+	return cmapi.PlacementViolations{Vuids: c.placementVuids, ScanTime: 1}, nil
+}
+
 func (c *mockCM) addVunits(units []cmapi.Unit) {
 	c.vunitRW.Lock()
 	defer c.vunitRW.Unlock()
@@ -388,7 +417,7 @@ func TestCMClient(t *testing.T) {
 	_, err = cmCli.GetVolumeInfo(ctx, 0)
 	require.Error(t, err)
 
-	ret, err := cli.AllocVolume(ctx, codemode.EC6P10L2, 1)
+	ret, err := cli.allocVolume(ctx, codemode.EC6P10L2, 1)
 	require.NoError(t, err)
 	_, err = cmCli.GetVolumeInfo(ctx, ret.Vid)
 	require.NoError(t, err)
@@ -441,10 +470,22 @@ func TestCMClient(t *testing.T) {
 	volumes, marker, err := cmCli.ListVolume(ctx, defaultVolumeListMarker, 2)
 	require.NoError(t, err)
 	require.Equal(t, 1, len(volumes))
+
+	err = cmCli.SetVolumeRedirect(ctx, ret.Vid, ret.Vid+1)
+	require.NoError(t, err)
+	volInfo, err = cmCli.GetVolumeInfo(ctx, ret.Vid)
+	require.NoError(t, err)
+	require.Equal(t, ret.Vid+1, volInfo.RedirectVid)
 	require.Equal(t, defaultVolumeListMarker, marker)
 
-	_, err = cli.AllocVolume(ctx, codemode.EC6P6, 20)
+	cli.placementVuids = []proto.Vuid{ret.Units[1].Vuid}
+	vuids, err := cmCli.ListPlacementViolations(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []proto.Vuid{ret.Units[1].Vuid}, vuids)
+
+	allocVol, err := cmCli.AllocVolume(ctx, codemode.EC6P6)
 	require.NoError(t, err)
+	require.Equal(t, codemode.EC6P6, allocVol.CodeMode)
 
 	volumes, marker, err = cmCli.ListVolume(ctx, defaultVolumeListMarker, 1)
 	require.NoError(t, err)
@@ -29,6 +29,11 @@ const (
 	defaultMaxDiskFreeChunkCnt = int64(1024)
 	defaultMinDiskFreeChunkCnt = int64(20)
 
+	defaultPlacementCollectIntervalS      = 600
+	defaultPlacementMigratingVuidCntLimit = 20
+
+	defaultConvertReleaseSourceDelayS = 7200
+
 	defaultInspectTimeoutMs  = 10000
 	defaultListVolStep       = 100
 	defaultListVolIntervalMs = 10
@@ -39,6 +44,7 @@ const (
 	defaultRepairTable            = "repair_tbl"
 	defaultInspectCheckPointTable = "inspect_checkpoint_tbl"
 	defaultManualMigrateTable     = "manual_migrate_tbl"
+	defaultVolumeConvertTable     = "volume_convert_tbl"
 	defaultSvrRegisterTable       = "svr_register_tbl"
 	defaultArchiveTasksTable      = "archive_tasks_tbl"
 )
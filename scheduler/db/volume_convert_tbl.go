@@ -0,0 +1,135 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+)
+
+// IVolumeConvertTaskTbl define the interface of db used by volume convert
+type IVolumeConvertTaskTbl interface {
+	Insert(ctx context.Context, t *proto.VolumeConvertTask) error
+	Update(ctx context.Context, t *proto.VolumeConvertTask) error
+	Find(ctx context.Context, taskID string) (task *proto.VolumeConvertTask, err error)
+	FindAll(ctx context.Context) (tasks []*proto.VolumeConvertTask, err error)
+}
+
+// VolumeConvertTaskTbl volume convert task table
+type VolumeConvertTaskTbl struct {
+	coll *mongo.Collection
+	name string
+}
+
+// OpenVolumeConvertTaskTbl open volume convert task table
+func OpenVolumeConvertTaskTbl(coll *mongo.Collection, name string) (IVolumeConvertTaskTbl, error) {
+	tbl := &VolumeConvertTaskTbl{
+		coll: coll,
+		name: name,
+	}
+	err := ArchiveStoreInst().registerArchiveStore(name, tbl)
+	return tbl, err
+}
+
+// Insert insert task
+func (tbl *VolumeConvertTaskTbl) Insert(ctx context.Context, t *proto.VolumeConvertTask) error {
+	t.Ctime = time.Now().String()
+	t.MTime = time.Now().String()
+	_, err := tbl.coll.InsertOne(ctx, t)
+	return err
+}
+
+// Update update task
+func (tbl *VolumeConvertTaskTbl) Update(ctx context.Context, t *proto.VolumeConvertTask) error {
+	span := trace.SpanFromContextSafe(ctx)
+	span.Debugf("update volume convert tbl task %+v", *t)
+
+	t.MTime = time.Now().String()
+	return tbl.coll.FindOneAndReplace(ctx, bson.M{"_id": t.TaskID}, t).Err()
+}
+
+// Find find task by taskID
+func (tbl *VolumeConvertTaskTbl) Find(ctx context.Context, taskID string) (task *proto.VolumeConvertTask, err error) {
+	err = tbl.coll.FindOne(ctx, bson.M{"_id": taskID, DeleteMark: bson.M{"$ne": true}}).Decode(&task)
+	return
+}
+
+// FindAll return all tasks
+func (tbl *VolumeConvertTaskTbl) FindAll(ctx context.Context) (tasks []*proto.VolumeConvertTask, err error) {
+	cursor, err := tbl.coll.Find(ctx, bson.M{DeleteMark: bson.M{"$ne": true}})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &tasks)
+	return tasks, err
+}
+
+// QueryMarkDeleteTasks find mark delete tasks
+func (tbl *VolumeConvertTaskTbl) QueryMarkDeleteTasks(ctx context.Context, delayMin int) (records []*ArchiveRecord, err error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	type VolumeConvertTaskEx struct {
+		proto.VolumeConvertTask `bson:",inline"`
+		DelTime                 int64 `bson:"del_time"`
+	}
+	var tasks []*VolumeConvertTaskEx
+	cursor, err := tbl.coll.Find(ctx, bson.M{DeleteMark: true})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		content, err := json.MarshalIndent(task, "", "\t")
+		if err != nil {
+			span.Warnf("task_id %s marshal fail err:%+v", task.TaskID, err)
+			continue
+		}
+
+		if inDelayTime(task.DelTime, delayMin) {
+			span.Debugf("task_id %s is in delay time", task.TaskID)
+			continue
+		}
+
+		r := &ArchiveRecord{
+			TaskID:   task.TaskID,
+			TaskType: tbl.Name(),
+			Content:  string(content),
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// RemoveMarkDelete remove mark delete task by taskID
+func (tbl *VolumeConvertTaskTbl) RemoveMarkDelete(ctx context.Context, taskID string) error {
+	_, err := tbl.coll.DeleteOne(ctx, bson.M{"_id": taskID, DeleteMark: true})
+	return err
+}
+
+// Name return volume convert table name
+func (tbl *VolumeConvertTaskTbl) Name() string {
+	return tbl.name
+}
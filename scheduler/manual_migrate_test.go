@@ -64,7 +64,15 @@ func TestManualMigrateMgr(t *testing.T) {
 	require.Equal(t, 0, completed)
 
 	vid1 := MocManualMigrateInfoMap[20001]
-	err := mgr.AddTask(ctx, vid1.VunitLocations[0].Vuid, false)
+	// stale vuid of migrated volume unit
+	staleVuid := vid1.VunitLocations[0].Vuid
+	staleVuid = proto.EncodeVuid(staleVuid.VuidPrefix(), staleVuid.Epoch()+1)
+	err := mgr.AddTask(ctx, staleVuid, false)
+	require.ErrorIs(t, err, ErrVuidNotMatch)
+	inited, _, _ = mgr.migrate.StatQueueTaskCnt()
+	require.Equal(t, 0, inited)
+
+	err = mgr.AddTask(ctx, vid1.VunitLocations[0].Vuid, false)
 	require.NoError(t, err)
 	inited, prepared, completed = mgr.migrate.StatQueueTaskCnt()
 	require.Equal(t, 1, inited)
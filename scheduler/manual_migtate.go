@@ -16,6 +16,7 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 
 	api "github.com/cubefs/blobstore/api/scheduler"
 	"github.com/cubefs/blobstore/common/counter"
@@ -27,6 +28,9 @@ import (
 	"github.com/cubefs/blobstore/scheduler/db"
 )
 
+// ErrVuidNotMatch volume unit has been migrated and vuid is stale
+var ErrVuidNotMatch = errors.New("vuid not match volume unit")
+
 // IMigrateCmCliEx define the interface of clustermgr used by manual migrate
 type IMigrateCmCliEx interface {
 	GetDiskInfo(ctx context.Context, diskID proto.DiskID) (ret *client.DiskInfoSimple, err error)
@@ -86,6 +90,11 @@ func (mgr *ManualMigrateMgr) AddTask(ctx context.Context, vuid proto.Vuid, forbi
 		span.Errorf("get vid %d volume fail err:%+v", vuid.Vid(), err)
 		return err
 	}
+	// vuid changes after volume unit migrated, stale vuid should not be migrated again
+	if int(vuid.Index()) >= len(volume.VunitLocations) || volume.VunitLocations[vuid.Index()].Vuid != vuid {
+		span.Warnf("vuid %d not match volume unit of vid %d", vuid, vuid.Vid())
+		return ErrVuidNotMatch
+	}
 	diskID := volume.VunitLocations[vuid.Index()].DiskID
 	disk, err := mgr.cmCli.GetDiskInfo(ctx, diskID)
 	if err != nil {
@@ -107,6 +116,11 @@ func (mgr *ManualMigrateMgr) AddTask(ctx context.Context, vuid proto.Vuid, forbi
 	return nil
 }
 
+// IsMigratingVuid returns true if volume unit is migrating
+func (mgr *ManualMigrateMgr) IsMigratingVuid(vuid proto.Vuid) bool {
+	return mgr.migrate.IsMigratingVuid(vuid)
+}
+
 func (mgr *ManualMigrateMgr) genUniqTaskID(vid proto.Vid) string {
 	return base.GenTaskID("manual_migrate", vid)
 }
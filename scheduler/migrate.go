@@ -88,6 +88,17 @@ func (m *diskMigratingVuids) deleteMigratingVuid(diskID proto.DiskID, vuid proto
 	}
 }
 
+func (m *diskMigratingVuids) isMigratingVuid(vuid proto.Vuid) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for _, vuids := range m.vuids {
+		if _, ok := vuids[vuid]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *diskMigratingVuids) getCurrMigratingDisksCnt() int {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
@@ -426,8 +437,15 @@ func (mgr *MigrateMgr) finishTask() (err error) {
 func (mgr *MigrateMgr) notifyTinkerUpdateVolMapping(ctx context.Context, task *proto.MigrateTask) (err error) {
 	span := trace.SpanFromContextSafe(ctx)
 	span.Infof("notify tinker to update volume mapping, vid:%d, taskId: %s", task.SourceVuid.Vid(), task.TaskID)
+	return notifyTinkerUpdateVol(ctx, mgr.svrTbl, mgr.tinkerClient, task.SourceVuid.Vid(), mgr.ClusterID)
+}
+
+// notifyTinkerUpdateVol notifies all tinkers to update the cached volume info of vid
+func notifyTinkerUpdateVol(ctx context.Context, svrTbl db.ISvrRegisterTbl, tinkerCli ITinkerCli,
+	vid proto.Vid, clusterID proto.ClusterID) (err error) {
+	span := trace.SpanFromContextSafe(ctx)
 
-	hosts, err := mgr.getTinkerHosts(ctx)
+	hosts, err := getTinkerHosts(ctx, svrTbl)
 	if err != nil {
 		return
 	}
@@ -435,9 +453,8 @@ func (mgr *MigrateMgr) notifyTinkerUpdateVolMapping(ctx context.Context, task *p
 	for index, host := range hosts {
 		index := index
 		host := host
-		vid := task.SourceVuid.Vid()
 		g.Go(func() error {
-			return mgr.tinkerClient.UpdateVol(ctx, host, vid, mgr.ClusterID)
+			return tinkerCli.UpdateVol(ctx, host, vid, clusterID)
 		}, index)
 	}
 	for index, err := range g.Wait() {
@@ -480,6 +497,7 @@ func (mgr *MigrateMgr) finishTaskInAdvance(ctx context.Context, task *proto.Migr
 
 	mgr.finishTaskCounter.Add()
 	mgr.prepareQueue.RemoveTask(task.TaskID)
+	mgr.diskMigratingVuids.deleteMigratingVuid(task.SourceDiskID, task.SourceVuid)
 	VolTaskLockerInst().Unlock(ctx, task.SourceVuid.Vid())
 }
 
@@ -524,10 +542,10 @@ func (mgr *MigrateMgr) handleUpdateVolMappingFail(ctx context.Context, task *pro
 	return err
 }
 
-func (mgr *MigrateMgr) getTinkerHosts(ctx context.Context) (hosts []string, err error) {
+func getTinkerHosts(ctx context.Context, svrTbl db.ISvrRegisterTbl) (hosts []string, err error) {
 	span := trace.SpanFromContextSafe(ctx)
 
-	svrs, err := mgr.svrTbl.FindAll(ctx, proto.ServiceNameTinker, "")
+	svrs, err := svrTbl.FindAll(ctx, proto.ServiceNameTinker, "")
 	if err != nil {
 		span.Errorf("get tinker svr failed, err:%v", err)
 		return
@@ -657,6 +675,11 @@ func (mgr *MigrateMgr) IsMigratingDisk(diskID proto.DiskID) bool {
 	return mgr.diskMigratingVuids.isMigratingDisk(diskID)
 }
 
+// IsMigratingVuid returns true if volume unit is migrating
+func (mgr *MigrateMgr) IsMigratingVuid(vuid proto.Vuid) bool {
+	return mgr.diskMigratingVuids.isMigratingVuid(vuid)
+}
+
 // GetMigratingDiskNum returns migrating disk count
 func (mgr *MigrateMgr) GetMigratingDiskNum() int {
 	return mgr.diskMigratingVuids.getCurrMigratingDisksCnt()
@@ -429,6 +429,29 @@ func testMigrateLoad(t *testing.T, mgr *MigrateMgr) {
 	mgr.finishTaskInAdvance(context.Background(), tasks[0], "")
 }
 
+func TestMigrateFinishTaskInAdvance(t *testing.T) {
+	MockEmptyVolTaskLocker()
+	conf := &MigrateConfig{}
+	conf.CheckAndFix()
+	mgr, err := initMigrateMgr(nil, conf)
+	require.NoError(t, err)
+	err = mgr.Load()
+	require.NoError(t, err)
+
+	tasks, err := mgr.taskTbl.(*mockBaseMigrateTbl).FindByVid(context.Background(), 105)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(tasks))
+	task := tasks[0]
+	require.True(t, mgr.IsMigratingVuid(task.SourceVuid))
+
+	mgr.finishTaskInAdvance(context.Background(), task, "lock volume fail")
+	require.Equal(t, proto.MigrateStateFinishedInAdvance, task.State)
+	_, exist := mgr.prepareQueue.Query(task.TaskID)
+	require.False(t, exist)
+	// volume unit is released, so that it can be migrated again
+	require.False(t, mgr.IsMigratingVuid(task.SourceVuid))
+}
+
 func testPrepareTaskErr(t *testing.T, mgr *MigrateMgr) {
 	mockCmCli := NewMigrateMockCmClient(nil, nil, MockMigrateVolInfoMap, MockDisksMap)
 	mgr.clusterMgrClient = mockCmCli
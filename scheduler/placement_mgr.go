@@ -0,0 +1,154 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/taskswitch"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/util/defaulter"
+)
+
+// PlacementMgrConfig placement manager config
+type PlacementMgrConfig struct {
+	CollectTaskIntervalS int `json:"collect_task_interval_s"`
+	// max volume units migrating by placement manager at the same time
+	MigratingVuidCntLimit int `json:"migrating_vuid_cnt_limit"`
+}
+
+// CheckAndFix check and fix placement manager config
+func (conf *PlacementMgrConfig) CheckAndFix() {
+	defaulter.LessOrEqual(&conf.CollectTaskIntervalS, defaultPlacementCollectIntervalS)
+	defaulter.LessOrEqual(&conf.MigratingVuidCntLimit, defaultPlacementMigratingVuidCntLimit)
+}
+
+// IPlacementCmCli define the interface of clustermgr used by placement manager
+type IPlacementCmCli interface {
+	ListPlacementViolations(ctx context.Context) (vuids []proto.Vuid, err error)
+}
+
+// IPlacementMigrator define the interface of migrate manager used by placement manager
+type IPlacementMigrator interface {
+	AddTask(ctx context.Context, vuid proto.Vuid, forbiddenDirectDownload bool) (err error)
+	IsMigratingVuid(vuid proto.Vuid) bool
+}
+
+// PlacementMgr restores spread of volumes, it generates manual migrate tasks
+// for the volume units which violate failure domain found by clustermgr placement audit,
+// clustermgr allocates destination of these units following failure domain even if not enforced
+type PlacementMgr struct {
+	cmCli    IPlacementCmCli
+	migrator IPlacementMigrator
+
+	taskSwitch *taskswitch.TaskSwitch
+	cfg        *PlacementMgrConfig
+
+	// volume units added by placement manager and still migrating
+	migratingVuids map[proto.Vuid]struct{}
+
+	closeOnce *sync.Once
+	closeDone chan struct{}
+}
+
+// NewPlacementMgr returns placement manager
+func NewPlacementMgr(
+	cmCli IPlacementCmCli,
+	migrator IPlacementMigrator,
+	switchMgr *taskswitch.SwitchMgr,
+	conf *PlacementMgrConfig) (*PlacementMgr, error,
+) {
+	taskSwitch, err := switchMgr.AddSwitch(taskswitch.PlacementSwitchName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PlacementMgr{
+		cmCli:          cmCli,
+		migrator:       migrator,
+		taskSwitch:     taskSwitch,
+		cfg:            conf,
+		migratingVuids: make(map[proto.Vuid]struct{}),
+		closeOnce:      &sync.Once{},
+		closeDone:      make(chan struct{}),
+	}, nil
+}
+
+// Run run placement manager
+func (mgr *PlacementMgr) Run() {
+	go mgr.collectTaskLoop()
+}
+
+// Close close placement manager
+func (mgr *PlacementMgr) Close() {
+	mgr.closeOnce.Do(func() {
+		close(mgr.closeDone)
+	})
+}
+
+func (mgr *PlacementMgr) collectTaskLoop() {
+	t := time.NewTicker(time.Duration(mgr.cfg.CollectTaskIntervalS) * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			mgr.taskSwitch.WaitEnable()
+			mgr.collectTask()
+		case <-mgr.closeDone:
+			return
+		}
+	}
+}
+
+func (mgr *PlacementMgr) collectTask() {
+	span, ctx := trace.StartSpanFromContext(context.Background(), "PlacementMgr.collectTask")
+	defer span.Finish()
+
+	for vuid := range mgr.migratingVuids {
+		if !mgr.migrator.IsMigratingVuid(vuid) {
+			delete(mgr.migratingVuids, vuid)
+		}
+	}
+
+	vuids, err := mgr.cmCli.ListPlacementViolations(ctx)
+	if err != nil {
+		span.Errorf("list placement violations failed, err:%v", err)
+		return
+	}
+	span.Debugf("placement violations: %v, migrating vuids: %d", vuids, len(mgr.migratingVuids))
+
+	for _, vuid := range vuids {
+		if len(mgr.migratingVuids) >= mgr.cfg.MigratingVuidCntLimit {
+			span.Infof("too many migrating vuids, limit: %d", mgr.cfg.MigratingVuidCntLimit)
+			return
+		}
+		if mgr.migrator.IsMigratingVuid(vuid) {
+			continue
+		}
+		if err = mgr.migrator.AddTask(ctx, vuid, false); err != nil {
+			if err == ErrVuidNotMatch {
+				span.Debugf("skip stale vuid: %d", vuid)
+				continue
+			}
+			span.Errorf("add migrate task failed, vuid: %d, err:%v", vuid, err)
+			continue
+		}
+		mgr.migratingVuids[vuid] = struct{}{}
+	}
+}
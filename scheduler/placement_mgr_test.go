@@ -0,0 +1,107 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/taskswitch"
+)
+
+type mockPlacementCmCli struct {
+	retErr error
+	vuids  []proto.Vuid
+}
+
+func (m *mockPlacementCmCli) ListPlacementViolations(ctx context.Context) ([]proto.Vuid, error) {
+	return m.vuids, m.retErr
+}
+
+type mockPlacementMigrator struct {
+	retErr    error
+	migrating map[proto.Vuid]bool
+}
+
+func (m *mockPlacementMigrator) AddTask(ctx context.Context, vuid proto.Vuid, forbiddenDirectDownload bool) error {
+	if m.retErr != nil {
+		return m.retErr
+	}
+	m.migrating[vuid] = true
+	return nil
+}
+
+func (m *mockPlacementMigrator) IsMigratingVuid(vuid proto.Vuid) bool {
+	return m.migrating[vuid]
+}
+
+func newPlacementMgrForTest(t *testing.T) (*PlacementMgr, *mockPlacementCmCli, *mockPlacementMigrator) {
+	cmCli := &mockPlacementCmCli{}
+	migrator := &mockPlacementMigrator{migrating: make(map[proto.Vuid]bool)}
+	switchMgr := taskswitch.NewSwitchMgr(NewMigrateMockCmClient(nil, nil, nil, nil))
+	conf := &PlacementMgrConfig{MigratingVuidCntLimit: 2}
+	conf.CheckAndFix()
+	mgr, err := NewPlacementMgr(cmCli, migrator, switchMgr, conf)
+	require.NoError(t, err)
+	return mgr, cmCli, migrator
+}
+
+func TestPlacementMgrCollectTask(t *testing.T) {
+	mgr, cmCli, migrator := newPlacementMgrForTest(t)
+	require.Equal(t, defaultPlacementCollectIntervalS, mgr.cfg.CollectTaskIntervalS)
+
+	vuid1 := proto.EncodeVuid(proto.EncodeVuidPrefix(1, 1), 1)
+	vuid2 := proto.EncodeVuid(proto.EncodeVuidPrefix(1, 2), 1)
+	vuid3 := proto.EncodeVuid(proto.EncodeVuidPrefix(2, 1), 1)
+	vuid4 := proto.EncodeVuid(proto.EncodeVuidPrefix(3, 1), 1)
+
+	// list violations failed
+	cmCli.retErr = errors.New("mock error")
+	mgr.collectTask()
+	require.Equal(t, 0, len(mgr.migratingVuids))
+
+	// vuid already migrating by others will be skipped
+	cmCli.retErr = nil
+	cmCli.vuids = []proto.Vuid{vuid1, vuid2, vuid3, vuid4}
+	migrator.migrating[vuid1] = true
+	mgr.collectTask()
+	require.Equal(t, map[proto.Vuid]struct{}{vuid2: {}, vuid3: {}}, mgr.migratingVuids)
+
+	// limited by migrating count
+	mgr.collectTask()
+	require.Equal(t, 2, len(mgr.migratingVuids))
+	require.False(t, migrator.migrating[vuid4])
+
+	// finished tasks are released
+	delete(migrator.migrating, vuid2)
+	cmCli.vuids = []proto.Vuid{vuid4}
+	mgr.collectTask()
+	require.Equal(t, map[proto.Vuid]struct{}{vuid3: {}, vuid4: {}}, mgr.migratingVuids)
+
+	// add task failed
+	delete(migrator.migrating, vuid3)
+	delete(migrator.migrating, vuid4)
+	migrator.retErr = errors.New("mock error")
+	mgr.collectTask()
+	require.Equal(t, 0, len(mgr.migratingVuids))
+
+	mgr.Run()
+	mgr.Close()
+	mgr.Close()
+}
@@ -39,7 +39,9 @@ type Service struct {
 	balanceMgr     *BalanceMgr
 	diskDropMgr    *DiskDropMgr
 	manualMigMgr   *ManualMigrateMgr
+	placementMgr   *PlacementMgr
 	repairMgr      *RepairMgr
+	volConvertMgr  *VolumeConvertMgr
 	inspectMgr     *InspectMgr
 
 	svrTbl db.ISvrRegisterTbl
@@ -97,6 +99,16 @@ func (svr *Service) HTTPTaskAcquire(c *rpc.Context) {
 		return
 	}
 
+	volConvertTask, err := svr.volConvertMgr.AcquireTask(ctx, args.IDC)
+	if err == nil {
+		ret := &api.WorkerTask{
+			TaskType:      proto.VolumeConvertType,
+			VolumeConvert: volConvertTask,
+		}
+		c.RespondJSON(ret)
+		return
+	}
+
 	c.RespondError(comerrs.ErrNothingTodo)
 }
 
@@ -112,6 +124,12 @@ func (svr *Service) HTTPTaskReclaim(c *rpc.Context) {
 	}
 	span.Infof("reclaim task args==>%+v", args)
 
+	// destination of volume convert task is a whole volume, can not alloc one unit of it
+	if args.TaskType == proto.VolumeConvertType {
+		c.RespondError(rpc.NewError(http.StatusBadRequest, "illegal_type", comerrs.ErrIllegalTaskType))
+		return
+	}
+
 	newDst, err := base.AllocVunitSafe(ctx, svr.cmCli, args.Dest.Vuid, args.Src)
 	if err != nil {
 		c.RespondError(err)
@@ -156,6 +174,8 @@ func (svr *Service) HTTPTaskCancel(c *rpc.Context) {
 		err = svr.diskDropMgr.CancelTask(ctx, args)
 	case proto.ManualMigrateType:
 		err = svr.manualMigMgr.CancelTask(ctx, args)
+	case proto.VolumeConvertType:
+		err = svr.volConvertMgr.CancelTask(ctx, args)
 	default:
 		c.RespondError(rpc.NewError(http.StatusBadRequest, "illegal_type", comerrs.ErrIllegalTaskType))
 		return
@@ -185,6 +205,8 @@ func (svr *Service) HTTPTaskComplete(c *rpc.Context) {
 		err = svr.diskDropMgr.CompleteTask(ctx, args)
 	case proto.ManualMigrateType:
 		err = svr.manualMigMgr.CompleteTask(ctx, args)
+	case proto.VolumeConvertType:
+		err = svr.volConvertMgr.CompleteTask(ctx, args)
 	default:
 		c.RespondError(rpc.NewError(http.StatusBadRequest, "illegal_type", comerrs.ErrIllegalTaskType))
 		return
@@ -242,6 +264,7 @@ func (svr *Service) HTTPTaskRenewal(c *rpc.Context) {
 		Balance:       make(map[string]string),
 		DiskDrop:      make(map[string]string),
 		ManualMigrate: make(map[string]string),
+		VolumeConvert: make(map[string]string),
 	}
 
 	for taskID := range args.Repair {
@@ -264,6 +287,11 @@ func (svr *Service) HTTPTaskRenewal(c *rpc.Context) {
 		ret.ManualMigrate[taskID] = getErrMsg(err)
 	}
 
+	for taskID := range args.VolumeConvert {
+		err := svr.volConvertMgr.RenewalTask(ctx, idc, taskID)
+		ret.VolumeConvert[taskID] = getErrMsg(err)
+	}
+
 	c.RespondJSON(ret)
 }
 
@@ -308,6 +336,12 @@ func (svr *Service) HTTPTaskReport(c *rpc.Context) {
 			args.TaskStats,
 			args.IncreaseDataSizeByte,
 			args.IncreaseShardCnt)
+	case proto.VolumeConvertType:
+		svr.volConvertMgr.ReportWorkerTaskStats(
+			args.TaskId,
+			args.TaskStats,
+			args.IncreaseDataSizeByte,
+			args.IncreaseShardCnt)
 	}
 
 	c.Respond()
@@ -466,6 +500,29 @@ func (svr *Service) HTTPManualMigrateTaskDetail(c *rpc.Context) {
 	c.RespondJSON(taskDetail)
 }
 
+// HTTPVolumeConvertTaskDetail returns volume convert task detail stats
+func (svr *Service) HTTPVolumeConvertTaskDetail(c *rpc.Context) {
+	ctx := c.Request.Context()
+
+	args := new(api.TaskStatArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	taskInfo, runStats, err := svr.volConvertMgr.QueryTask(ctx, args.TaskId)
+	if err != nil {
+		c.RespondError(rpc.NewError(http.StatusNotFound, "not found", errors.New("task not found")))
+		return
+	}
+
+	taskDetail := api.VolumeConvertTaskDetail{
+		TaskInfo: taskInfo,
+		RunStats: runStats,
+	}
+	c.RespondJSON(taskDetail)
+}
+
 // HTTPRepairTaskDetail returns repair task detail stats
 func (svr *Service) HTTPRepairTaskDetail(c *rpc.Context) {
 	ctx := c.Request.Context()
@@ -590,6 +647,23 @@ func (svr *Service) HTTPStats(c *rpc.Context) {
 		},
 	}
 
+	// stats volume convert tasks
+	finishedCnt, dataSizeByte, shardCnt = svr.volConvertMgr.GetTaskStats()
+	preparing, workerDoing, finishing = svr.volConvertMgr.StatQueueTaskCnt()
+
+	volumeConvert := api.VolumeConvertTasksStat{
+		MigrateTasksStat: api.MigrateTasksStat{
+			PreparingCnt:   preparing,
+			WorkerDoingCnt: workerDoing,
+			FinishingCnt:   finishing,
+			StatsPerMin: api.PerMinStats{
+				FinishedCnt:    fmt.Sprint(finishedCnt),
+				DataAmountByte: base.DataMountFormat(dataSizeByte),
+				ShardCnt:       fmt.Sprint(shardCnt),
+			},
+		},
+	}
+
 	// stats inspect tasks
 	var finished, timeout [counter.SLOT]int
 	if svr.inspectMgr != nil {
@@ -613,6 +687,7 @@ func (svr *Service) HTTPStats(c *rpc.Context) {
 		Drop:          drop,
 		Balance:       balance,
 		ManualMigrate: manualMigrate,
+		VolumeConvert: volumeConvert,
 		Inspect:       inspect,
 	}
 
@@ -637,3 +712,22 @@ func (svr *Service) HTTPManualMigrateTaskAdd(c *rpc.Context) {
 	err := svr.manualMigMgr.AddTask(ctx, args.Vuid, !args.DirectDownload)
 	c.RespondError(rpc.Error2HTTPError(err))
 }
+
+// HTTPVolumeConvertTaskAdd adds volume convert task
+func (svr *Service) HTTPVolumeConvertTaskAdd(c *rpc.Context) {
+	ctx := c.Request.Context()
+
+	args := new(api.AddVolumeConvertArgs)
+	if err := c.ParseArgs(args); err != nil {
+		c.RespondError(err)
+		return
+	}
+
+	if !args.Valid() {
+		c.RespondError(comerrs.ErrIllegalArguments)
+		return
+	}
+
+	err := svr.volConvertMgr.AddTask(ctx, args.Vid, args.CodeMode)
+	c.RespondError(rpc.Error2HTTPError(err))
+}
@@ -135,6 +135,9 @@ func init() {
 	inspectMgr.taskSwitch.Enable()
 	inspectMgr.Run()
 
+	volConvertMgr := NewVolumeConvertMgr(newMockConvertCmClient(), NewMockTinkerClient(), serviceRegisterTbl,
+		newMockVolumeConvertTbl(), clusterID, &VolumeConvertMgrConfig{})
+
 	svr := &Service{
 		ClusterID:      clusterID,
 		clusterTopoMgr: topologyMgr,
@@ -143,6 +146,7 @@ func init() {
 		manualMigMgr:   manualMigMgr,
 		repairMgr:      repairMgr,
 		inspectMgr:     inspectMgr,
+		volConvertMgr:  volConvertMgr,
 		svrTbl:         serviceRegisterTbl,
 		cmCli:          clusterMgrCli,
 	}
@@ -392,6 +396,17 @@ func TestTaskAPI(t *testing.T) {
 	err = schedulerCli.AddManualMigrateTask(context.Background(), &scheduler.AddManualMigrateArgs{Vuid: 0})
 	require.Error(t, err)
 	require.EqualError(t, errors.ErrIllegalArguments, err.Error())
+
+	// volume convert task
+	err = schedulerCli.AddVolumeConvertTask(context.Background(), &scheduler.AddVolumeConvertArgs{Vid: 0})
+	require.Error(t, err)
+	require.EqualError(t, errors.ErrIllegalArguments, err.Error())
+	_, err = schedulerCli.VolumeConvertTaskDetail(context.Background(), &scheduler.TaskStatArgs{TaskId: ""})
+	require.Error(t, err)
+	err = schedulerCli.ReclaimTask(context.Background(), &scheduler.ReclaimTaskArgs{
+		TaskType: proto.VolumeConvertType,
+	})
+	require.EqualError(t, err, errors.ErrIllegalTaskType.Error())
 }
 
 func newServiceRegisterTbl() db.ISvrRegisterTbl {
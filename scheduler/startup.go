@@ -79,18 +79,20 @@ var ErrIllegalClusterID = errors.New("illegal cluster_id")
 type Config struct {
 	cmd.Config
 
-	ClusterID                 proto.ClusterID       `json:"cluster_id"`
-	Database                  db.Config             `json:"database"`
-	TaskArchiveStoreDB        db.ArchiveStoreConfig `json:"task_archive_store_db"`
-	TopologyUpdateIntervalMin int                   `json:"topology_update_interval_min"`
-	FreeChunkCounterBuckets   []float64             `json:"free_chunk_counter_buckets"`
-	ClusterMgr                clustermgr.Config     `json:"clustermgr"`
-	MqProxy                   mqproxy.LbConfig      `json:"mqproxy"`
-	Tinker                    tinker.Config         `json:"tinker"`
-	BalanceTask               BalanceMgrConfig      `json:"balance_task"`
-	DiskDropTask              DiskDropMgrConfig     `json:"disk_drop_task"`
-	RepairTask                RepairMgrCfg          `json:"repair_task"`
-	InspectTask               InspectMgrCfg         `json:"inspect_task"`
+	ClusterID                 proto.ClusterID        `json:"cluster_id"`
+	Database                  db.Config              `json:"database"`
+	TaskArchiveStoreDB        db.ArchiveStoreConfig  `json:"task_archive_store_db"`
+	TopologyUpdateIntervalMin int                    `json:"topology_update_interval_min"`
+	FreeChunkCounterBuckets   []float64              `json:"free_chunk_counter_buckets"`
+	ClusterMgr                clustermgr.Config      `json:"clustermgr"`
+	MqProxy                   mqproxy.LbConfig       `json:"mqproxy"`
+	Tinker                    tinker.Config          `json:"tinker"`
+	BalanceTask               BalanceMgrConfig       `json:"balance_task"`
+	DiskDropTask              DiskDropMgrConfig      `json:"disk_drop_task"`
+	RepairTask                RepairMgrCfg           `json:"repair_task"`
+	InspectTask               InspectMgrCfg          `json:"inspect_task"`
+	PlacementTask             PlacementMgrConfig     `json:"placement_task"`
+	VolumeConvertTask         VolumeConvertMgrConfig `json:"volume_convert_task"`
 
 	// inspect may be unavailable if NotMustNeedMqProxy is true
 	NotMustNeedMqProxy bool `json:"not_must_need_mq_proxy"`
@@ -114,6 +116,8 @@ func (c *Config) checkAndFix() (err error) {
 	c.checkAndFixDiskDropCfg()
 	c.checkAndFixRepairCfg()
 	c.checkAndFixInspectCfg()
+	c.PlacementTask.CheckAndFix()
+	c.VolumeConvertTask.CheckAndFix()
 
 	return nil
 }
@@ -133,6 +137,7 @@ func (c *Config) checkAndFixDataBaseCfg() {
 	defaulter.Empty(&c.Database.RepairTblName, defaultRepairTable)
 	defaulter.Empty(&c.Database.InspectCheckPointTblName, defaultInspectCheckPointTable)
 	defaulter.Empty(&c.Database.ManualMigrateTblName, defaultManualMigrateTable)
+	defaulter.Empty(&c.Database.VolumeConvertTblName, defaultVolumeConvertTable)
 	defaulter.Empty(&c.Database.SvrRegisterTblName, defaultSvrRegisterTable)
 }
 
@@ -242,6 +247,26 @@ func NewService(conf *Config) (svr *Service, err error) {
 		database.ManualMigrateTbl,
 		conf.ClusterID)
 
+	// new placement manager
+	placementMgr, err := NewPlacementMgr(
+		clusterMgrCli,
+		manualMigMgr,
+		switchMgr,
+		&conf.PlacementTask)
+	if err != nil {
+		log.Errorf("new placement mgr fail err %+v", err)
+		return nil, err
+	}
+
+	// new volume convert manager
+	volConvertMgr := NewVolumeConvertMgr(
+		clusterMgrCli,
+		tinkerCli,
+		database.SvrRegisterTbl,
+		database.VolumeConvertTbl,
+		conf.ClusterID,
+		&conf.VolumeConvertTask)
+
 	// new disk repair manager
 	repairMgr, err := NewRepairMgr(
 		&conf.RepairTask,
@@ -282,7 +307,9 @@ func NewService(conf *Config) (svr *Service, err error) {
 		balanceMgr:     balanceMgr,
 		diskDropMgr:    diskDropMgr,
 		manualMigMgr:   manualMigMgr,
+		placementMgr:   placementMgr,
 		repairMgr:      repairMgr,
+		volConvertMgr:  volConvertMgr,
 		inspectMgr:     inspectMgr,
 		svrTbl:         database.SvrRegisterTbl,
 
@@ -328,6 +355,11 @@ func (svr *Service) load() (err error) {
 		return
 	}
 
+	err = svr.volConvertMgr.Load()
+	if err != nil {
+		return
+	}
+
 	return
 }
 
@@ -337,6 +369,8 @@ func (svr *Service) Run() {
 	svr.balanceMgr.Run()
 	svr.diskDropMgr.Run()
 	svr.manualMigMgr.Run()
+	svr.placementMgr.Run()
+	svr.volConvertMgr.Run()
 
 	if svr.inspectMgr != nil {
 		svr.inspectMgr.Run()
@@ -348,6 +382,8 @@ func (svr *Service) Close() {
 	svr.balanceMgr.Close()
 	svr.repairMgr.Close()
 	svr.diskDropMgr.Close()
+	svr.placementMgr.Close()
+	svr.volConvertMgr.Close()
 }
 
 // NewHandler returns app server handler
@@ -357,6 +393,7 @@ func NewHandler(service *Service) *rpc.Router {
 	rpc.RegisterArgsParser(&api.CancelTaskArgs{}, "json")
 	rpc.RegisterArgsParser(&api.CompleteTaskArgs{}, "json")
 	rpc.RegisterArgsParser(&api.AddManualMigrateArgs{}, "json")
+	rpc.RegisterArgsParser(&api.AddVolumeConvertArgs{}, "json")
 
 	rpc.RegisterArgsParser(&api.CompleteInspectArgs{}, "json")
 
@@ -376,6 +413,7 @@ func NewHandler(service *Service) *rpc.Router {
 	rpc.POST("/task/cancel", service.HTTPTaskCancel, rpc.OptArgsBody())
 	rpc.POST("/task/complete", service.HTTPTaskComplete, rpc.OptArgsBody())
 	rpc.POST("/manual/migrate/task/add", service.HTTPManualMigrateTaskAdd, rpc.OptArgsBody())
+	rpc.POST("/volume/convert/task/add", service.HTTPVolumeConvertTaskAdd, rpc.OptArgsBody())
 
 	rpc.GET("/inspect/acquire", service.HTTPInspectAcquire, rpc.OptArgsQuery())
 	rpc.POST("/inspect/complete", service.HTTPInspectComplete, rpc.OptArgsBody())
@@ -387,6 +425,7 @@ func NewHandler(service *Service) *rpc.Router {
 	rpc.POST("/repair/task/detail", service.HTTPRepairTaskDetail, rpc.OptArgsBody())
 	rpc.POST("/drop/task/detail", service.HTTPDropTaskDetail, rpc.OptArgsBody())
 	rpc.POST("/manual/migrate/task/detail", service.HTTPManualMigrateTaskDetail, rpc.OptArgsBody())
+	rpc.POST("/volume/convert/task/detail", service.HTTPVolumeConvertTaskDetail, rpc.OptArgsBody())
 	rpc.GET("/stats", service.HTTPStats, rpc.OptArgsQuery())
 
 	rpc.GET("/service/list", service.HTTPServiceList, rpc.OptArgsQuery())
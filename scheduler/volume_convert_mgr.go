@@ -0,0 +1,624 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	api "github.com/cubefs/blobstore/api/scheduler"
+	"github.com/cubefs/blobstore/common/codemode"
+	"github.com/cubefs/blobstore/common/counter"
+	comerrs "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/rpc"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/scheduler/base"
+	"github.com/cubefs/blobstore/scheduler/client"
+	"github.com/cubefs/blobstore/scheduler/db"
+	"github.com/cubefs/blobstore/util/defaulter"
+	"github.com/cubefs/blobstore/util/log"
+)
+
+// volume convert
+
+const (
+	convertFinishReasonRedirected = "volume has been redirected"
+	convertFinishReasonSameMode   = "volume is already in the code mode"
+	convertFinishReasonNoSpace    = "no enough space in destination volume"
+)
+
+var errReleaseSourceDelayed = errors.New("release of source volume units is delayed")
+
+// VolumeConvertMgrConfig volume convert manager config
+type VolumeConvertMgrConfig struct {
+	// source volume units are released after the source volume has been redirected for a while,
+	// it should be longer than expiration of volume cache in access
+	ReleaseSourceDelayS int `json:"release_source_delay_s"`
+}
+
+// CheckAndFix check and fix volume convert manager config
+func (conf *VolumeConvertMgrConfig) CheckAndFix() {
+	defaulter.LessOrEqual(&conf.ReleaseSourceDelayS, defaultConvertReleaseSourceDelayS)
+}
+
+type convertCmCli interface {
+	GetVolumeInfo(ctx context.Context, vid proto.Vid) (ret *client.VolumeInfoSimple, err error)
+	LockVolume(ctx context.Context, vid proto.Vid) (err error)
+	UnlockVolume(ctx context.Context, vid proto.Vid) (err error)
+	AllocVolume(ctx context.Context, mode codemode.CodeMode) (ret *client.VolumeInfoSimple, err error)
+	SetVolumeRedirect(ctx context.Context, vid, redirectVid proto.Vid) (err error)
+	GetDiskInfo(ctx context.Context, diskID proto.DiskID) (ret *client.DiskInfoSimple, err error)
+	ReleaseVolumeUnit(ctx context.Context, vuid proto.Vuid, diskID proto.DiskID) (err error)
+}
+
+// VolumeConvertMgr volume convert task manager,
+// it re-encodes all blobs of the locked source volume into a new allocated volume
+// of another code mode, then redirects reading of source volume into the new one.
+// Worker redoes the task once after redirected to replay the deletes landed on source volume,
+// and source volume units are released a while after redirected.
+type VolumeConvertMgr struct {
+	mu sync.Mutex
+
+	clusterID proto.ClusterID
+	cfg       *VolumeConvertMgrConfig
+
+	taskTbl db.IVolumeConvertTaskTbl
+	svrTbl  db.ISvrRegisterTbl
+
+	prepareQueue *base.TaskQueue
+	workQueue    *base.WorkerTaskQueue
+	finishQueue  *base.TaskQueue
+
+	cmCli     convertCmCli
+	tinkerCli ITinkerCli
+
+	// for stats
+	finishTaskCounter counter.Counter
+	taskStatsMgr      *base.TaskStatsMgr
+
+	closeOnce *sync.Once
+	closeDone chan struct{}
+}
+
+// NewVolumeConvertMgr returns volume convert manager
+func NewVolumeConvertMgr(
+	cmCli convertCmCli,
+	tinkerCli ITinkerCli,
+	svrTbl db.ISvrRegisterTbl,
+	taskTbl db.IVolumeConvertTaskTbl,
+	clusterID proto.ClusterID,
+	conf *VolumeConvertMgrConfig) *VolumeConvertMgr {
+	cfg := base.TaskCommonConfig{}
+	cfg.CheckAndFix()
+
+	mgr := &VolumeConvertMgr{
+		clusterID:    clusterID,
+		cfg:          conf,
+		taskTbl:      taskTbl,
+		svrTbl:       svrTbl,
+		prepareQueue: base.NewTaskQueue(time.Duration(cfg.PrepareQueueRetryDelayS) * time.Second),
+		workQueue:    base.NewWorkerTaskQueue(time.Duration(cfg.CancelPunishDurationS) * time.Second),
+		finishQueue:  base.NewTaskQueue(time.Duration(cfg.FinishQueueRetryDelayS) * time.Second),
+
+		cmCli:     cmCli,
+		tinkerCli: tinkerCli,
+
+		closeOnce: &sync.Once{},
+		closeDone: make(chan struct{}),
+	}
+	mgr.taskStatsMgr = base.NewTaskStatsMgrAndRun(clusterID, proto.VolumeConvertType, mgr)
+	return mgr
+}
+
+// Load load volume convert task from database
+func (mgr *VolumeConvertMgr) Load() error {
+	log.Infof("VolumeConvertMgr start load...")
+	ctx := context.Background()
+
+	tasks, err := mgr.taskTbl.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	log.Infof("volume convert load tasks len %d", len(tasks))
+
+	for _, t := range tasks {
+		if t.Running() {
+			for _, vid := range []proto.Vid{t.SourceVid, t.DestinationVid} {
+				if err = VolTaskLockerInst().TryLock(ctx, vid); err != nil {
+					log.Panicf("volume convert task conflict, task:%+v, err:%+v", t, err.Error())
+				}
+			}
+		}
+
+		log.Infof("load task taskId %s state %d", t.TaskID, t.State)
+		switch t.State {
+		case proto.VolumeConvertStateInited:
+			mgr.prepareQueue.PushTask(t.TaskID, t)
+		case proto.VolumeConvertStatePrepared:
+			mgr.workQueue.AddPreparedTask(t.SourceIdc, t.TaskID, t)
+		case proto.VolumeConvertStateWorkCompleted:
+			mgr.finishQueue.PushTask(t.TaskID, t)
+		case proto.VolumeConvertStateFinished, proto.VolumeConvertStateFinishedInAdvance:
+			continue
+		default:
+			log.Panicf("unexpect volume convert state %d", t.State)
+		}
+	}
+	return nil
+}
+
+// Run run volume convert task includes prepare/finish phase
+func (mgr *VolumeConvertMgr) Run() {
+	go mgr.prepareTaskLoop()
+	go mgr.finishTaskLoop()
+}
+
+// Close close volume convert task manager
+func (mgr *VolumeConvertMgr) Close() {
+	mgr.closeOnce.Do(func() {
+		close(mgr.closeDone)
+	})
+}
+
+// AddTask add volume convert task which converts volume vid into code mode
+func (mgr *VolumeConvertMgr) AddTask(ctx context.Context, vid proto.Vid, mode codemode.CodeMode) error {
+	span := trace.SpanFromContextSafe(ctx)
+
+	volume, err := mgr.cmCli.GetVolumeInfo(ctx, vid)
+	if err != nil {
+		span.Errorf("get vid %d volume fail err:%+v", vid, err)
+		return err
+	}
+	if volume.CodeMode == mode || volume.RedirectVid != proto.InvalidVid {
+		span.Warnf("volume %d can not convert into code mode %s, volume:%+v", vid, mode, volume)
+		return comerrs.ErrIllegalArguments
+	}
+
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+
+	tasks, err := mgr.taskTbl.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		// blobs in destination volume can not be converted again,
+		// access reads the redirected volume only once
+		if t.DestinationVid == vid && t.State != proto.VolumeConvertStateFinishedInAdvance {
+			span.Warnf("volume %d is destination of task %s", vid, t.TaskID)
+			return comerrs.ErrIllegalArguments
+		}
+		if t.SourceVid == vid && !t.Finished() {
+			span.Warnf("volume %d is converting by task %s", vid, t.TaskID)
+			return ErrVidTaskConflict
+		}
+	}
+
+	task := &proto.VolumeConvertTask{
+		TaskID:              mgr.genUniqTaskID(vid),
+		State:               proto.VolumeConvertStateInited,
+		SourceVid:           vid,
+		SourceCodeMode:      volume.CodeMode,
+		DestinationCodeMode: mode,
+	}
+	if err = mgr.taskTbl.Insert(ctx, task); err != nil {
+		span.Errorf("insert volume convert task fail err:%+v", err)
+		return err
+	}
+	mgr.prepareQueue.PushTask(task.TaskID, task)
+
+	span.Infof("add volume convert task success! task_info:%+v", task)
+	return nil
+}
+
+func (mgr *VolumeConvertMgr) genUniqTaskID(vid proto.Vid) string {
+	return base.GenTaskID("volume_convert", vid)
+}
+
+func (mgr *VolumeConvertMgr) prepareTaskLoop() {
+	for {
+		select {
+		case <-mgr.closeDone:
+			return
+		default:
+		}
+
+		err := mgr.popTaskAndPrepare()
+		if err == base.ErrNoTaskInQueue {
+			time.Sleep(time.Duration(prepareIntervalS) * time.Second)
+		}
+	}
+}
+
+func (mgr *VolumeConvertMgr) popTaskAndPrepare() error {
+	_, task, exist := mgr.prepareQueue.PopTask()
+	if !exist {
+		return base.ErrNoTaskInQueue
+	}
+
+	var err error
+	span, ctx := trace.StartSpanFromContext(
+		context.Background(),
+		"VolumeConvertMgr.popTaskAndPrepare")
+	defer span.Finish()
+
+	defer func() {
+		if err != nil {
+			span.Errorf("prepare task %s fail %+v and retry task", task.(*proto.VolumeConvertTask).TaskID, err)
+			mgr.prepareQueue.RetryTask(task.(*proto.VolumeConvertTask).TaskID)
+		}
+	}()
+
+	// why:avoid to change task in queue
+	t := task.(*proto.VolumeConvertTask).Copy()
+	span.Infof("pop taskId %s task %+v", t.TaskID, t)
+	// whether vid has another running task
+	err = VolTaskLockerInst().TryLock(ctx, t.SourceVid)
+	if err != nil {
+		span.Warnf("TryLock fail vid %d has task running", t.SourceVid)
+		return base.ErrVolNotOnlyOneTask
+	}
+	defer func() {
+		if err != nil {
+			span.Errorf("prepare task taskId %s fail %+v and unlock VolTaskLock", t.TaskID, err)
+			VolTaskLockerInst().Unlock(ctx, t.SourceVid)
+		}
+	}()
+
+	err = mgr.prepareTask(ctx, t)
+	if err != nil {
+		span.Errorf("prepare task_id %s fail err %v", t.TaskID, err)
+		return err
+	}
+
+	span.Infof("prepare task_id %s success", t.TaskID)
+	return nil
+}
+
+func (mgr *VolumeConvertMgr) prepareTask(ctx context.Context, t *proto.VolumeConvertTask) error {
+	span := trace.SpanFromContextSafe(ctx)
+	span.Infof("start prepare volume convert taskId %s task %+v", t.TaskID, t)
+
+	srcVolume, err := mgr.cmCli.GetVolumeInfo(ctx, t.SourceVid)
+	if err != nil {
+		span.Errorf("prepare task get volume info fail err:%+v", err)
+		return err
+	}
+
+	// 1.check necessity of generating current task
+	if srcVolume.RedirectVid != proto.InvalidVid {
+		mgr.finishTaskInAdvance(ctx, t, convertFinishReasonRedirected)
+		return nil
+	}
+	if srcVolume.CodeMode == t.DestinationCodeMode {
+		mgr.finishTaskInAdvance(ctx, t, convertFinishReasonSameMode)
+		return nil
+	}
+
+	// 2.lock source volume to forbid writing, used size of volume is stable after locked
+	if err = mgr.cmCli.LockVolume(ctx, t.SourceVid); err != nil {
+		span.Errorf("lock volume %d fail err:%+v", t.SourceVid, err)
+		return err
+	}
+	if srcVolume, err = mgr.cmCli.GetVolumeInfo(ctx, t.SourceVid); err != nil {
+		span.Errorf("prepare task get volume info fail err:%+v", err)
+		return err
+	}
+
+	// 3.alloc destination volume, and persist it to avoid allocating again when retry
+	var dstVolume *client.VolumeInfoSimple
+	if t.DestinationVid == proto.InvalidVid {
+		if dstVolume, err = mgr.cmCli.AllocVolume(ctx, t.DestinationCodeMode); err != nil {
+			span.Errorf("alloc volume fail err:%+v", err)
+			return err
+		}
+		t.DestinationVid = dstVolume.Vid
+		base.LoopExecUntilSuccess(ctx, "volume convert prepare task update destination", func() error {
+			return mgr.taskTbl.Update(ctx, t)
+		})
+		if queued, ok := mgr.prepareQueue.Query(t.TaskID); ok {
+			queued.(*proto.VolumeConvertTask).DestinationVid = t.DestinationVid
+		}
+	} else if dstVolume, err = mgr.cmCli.GetVolumeInfo(ctx, t.DestinationVid); err != nil {
+		span.Errorf("prepare task get volume info fail err:%+v", err)
+		return err
+	}
+
+	if dstVolume.Free < srcVolume.Used {
+		span.Warnf("destination volume %d free %d < source volume %d used %d",
+			dstVolume.Vid, dstVolume.Free, srcVolume.Vid, srcVolume.Used)
+		if err = mgr.cmCli.UnlockVolume(ctx, t.SourceVid); err != nil {
+			return err
+		}
+		mgr.finishTaskInAdvance(ctx, t, convertFinishReasonNoSpace)
+		return nil
+	}
+
+	diskInfo, err := mgr.cmCli.GetDiskInfo(ctx, srcVolume.VunitLocations[0].DiskID)
+	if err != nil {
+		span.Errorf("get disk info fail err:%+v", err)
+		return err
+	}
+
+	if err = VolTaskLockerInst().TryLock(ctx, t.DestinationVid); err != nil {
+		span.Warnf("TryLock fail vid %d has task running", t.DestinationVid)
+		return base.ErrVolNotOnlyOneTask
+	}
+
+	// 4.generate src and destination for task & task persist
+	t.SourceIdc = diskInfo.Idc
+	t.SourceCodeMode = srcVolume.CodeMode
+	t.Sources = srcVolume.VunitLocations
+	t.Destinations = dstVolume.VunitLocations
+	t.State = proto.VolumeConvertStatePrepared
+	base.LoopExecUntilSuccess(ctx, "volume convert prepare task update task tbl", func() error {
+		return mgr.taskTbl.Update(ctx, t)
+	})
+
+	mgr.workQueue.AddPreparedTask(t.SourceIdc, t.TaskID, t)
+	mgr.prepareQueue.RemoveTask(t.TaskID)
+	return nil
+}
+
+func (mgr *VolumeConvertMgr) finishTaskInAdvance(ctx context.Context, t *proto.VolumeConvertTask, reason string) {
+	span := trace.SpanFromContextSafe(ctx)
+	span.Infof("volume convert task %s finish in advance: %s", t.TaskID, reason)
+
+	t.State = proto.VolumeConvertStateFinishedInAdvance
+	t.FinishAdvanceReason = reason
+	base.LoopExecUntilSuccess(ctx, "volume convert finish task in advance update task tbl", func() error {
+		return mgr.taskTbl.Update(ctx, t)
+	})
+
+	mgr.finishTaskCounter.Add()
+	mgr.prepareQueue.RemoveTask(t.TaskID)
+	VolTaskLockerInst().Unlock(ctx, t.SourceVid)
+}
+
+func (mgr *VolumeConvertMgr) finishTaskLoop() {
+	for {
+		select {
+		case <-mgr.closeDone:
+			return
+		default:
+		}
+
+		err := mgr.popTaskAndFinish()
+		if err == base.ErrNoTaskInQueue {
+			time.Sleep(time.Duration(finishIntervalS) * time.Second)
+		}
+	}
+}
+
+func (mgr *VolumeConvertMgr) popTaskAndFinish() error {
+	_, task, exist := mgr.finishQueue.PopTask()
+	if !exist {
+		return base.ErrNoTaskInQueue
+	}
+
+	span, ctx := trace.StartSpanFromContext(
+		context.Background(),
+		"VolumeConvertMgr.popTaskAndFinish")
+	defer span.Finish()
+
+	t := task.(*proto.VolumeConvertTask).Copy()
+	err := mgr.finishTask(ctx, t)
+	if err == errReleaseSourceDelayed {
+		span.Debugf("finish task_id %s later: %v", t.TaskID, err)
+		return err
+	}
+	if err != nil {
+		span.Errorf("finish task fail err %+v", err)
+		return err
+	}
+
+	span.Infof("finish task_id %s success", t.TaskID)
+	return nil
+}
+
+func (mgr *VolumeConvertMgr) finishTask(ctx context.Context, task *proto.VolumeConvertTask) (retErr error) {
+	span := trace.SpanFromContextSafe(ctx)
+
+	defer func() {
+		if retErr != nil {
+			mgr.finishQueue.RetryTask(task.TaskID)
+		}
+	}()
+
+	if task.State != proto.VolumeConvertStateWorkCompleted {
+		span.Panicf("taskId %s finish state expect %d but actual %d",
+			task.TaskID, proto.VolumeConvertStateWorkCompleted, task.State)
+	}
+	// access may read source volume by cache for a while after redirected
+	if task.Redirected() && time.Since(time.Unix(task.RedirectTime, 0)) < time.Duration(mgr.cfg.ReleaseSourceDelayS)*time.Second {
+		return errReleaseSourceDelayed
+	}
+	// save completed state firstly, worker will redo task if process restart
+	base.LoopExecUntilSuccess(ctx, "volume convert finish task update task state completed", func() error {
+		return mgr.taskTbl.Update(ctx, task)
+	})
+
+	if !task.Redirected() {
+		return mgr.redirectAndRedoTask(ctx, task)
+	}
+
+	if err := mgr.releaseSourceUnits(ctx, task); err != nil {
+		span.Errorf("release source units fail:%+v", err)
+		return err
+	}
+
+	task.State = proto.VolumeConvertStateFinished
+	base.LoopExecUntilSuccess(ctx, "volume convert finish task update task state finished", func() error {
+		return mgr.taskTbl.Update(ctx, task)
+	})
+
+	mgr.finishTaskCounter.Add()
+	// 1.remove task in memory
+	// 2.release lock of volume task
+	mgr.finishQueue.RemoveTask(task.TaskID)
+	VolTaskLockerInst().Unlock(ctx, task.SourceVid)
+	VolTaskLockerInst().Unlock(ctx, task.DestinationVid)
+
+	return nil
+}
+
+// redirectAndRedoTask redirects source volume, blobs are deleted in destination volume after all tinkers updated,
+// then worker redoes the task to replay the deletes which landed on source volume before redirected
+func (mgr *VolumeConvertMgr) redirectAndRedoTask(ctx context.Context, task *proto.VolumeConvertTask) error {
+	span := trace.SpanFromContextSafe(ctx)
+
+	// source volume keeps locked after redirected
+	err := mgr.cmCli.SetVolumeRedirect(ctx, task.SourceVid, task.DestinationVid)
+	if err != nil {
+		span.Errorf("SetVolumeRedirect fail:%+v", err)
+		return err
+	}
+	span.Infof("notify tinker to update volume, vid:%d, taskId: %s", task.SourceVid, task.TaskID)
+	if err = notifyTinkerUpdateVol(ctx, mgr.svrTbl, mgr.tinkerCli, task.SourceVid, mgr.clusterID); err != nil {
+		return base.ErrNotifyTinkerUpdateVol
+	}
+
+	task.RedirectTime = time.Now().Unix()
+	task.State = proto.VolumeConvertStatePrepared
+	task.WorkerRedoCnt++
+	base.LoopExecUntilSuccess(ctx, "volume convert redo task update task tbl", func() error {
+		return mgr.taskTbl.Update(ctx, task)
+	})
+
+	mgr.finishQueue.RemoveTask(task.TaskID)
+	mgr.workQueue.AddPreparedTask(task.SourceIdc, task.TaskID, task)
+	span.Infof("task %s redo again after redirected", task.TaskID)
+	return nil
+}
+
+func (mgr *VolumeConvertMgr) releaseSourceUnits(ctx context.Context, task *proto.VolumeConvertTask) error {
+	span := trace.SpanFromContextSafe(ctx)
+
+	for _, src := range task.Sources {
+		err := mgr.cmCli.ReleaseVolumeUnit(ctx, src.Vuid, src.DiskID)
+		if err == nil {
+			continue
+		}
+		// volume unit has been released or the disk is broken
+		code := rpc.DetectStatusCode(err)
+		if code == comerrs.CodeVuidNotFound || code == comerrs.CodeDiskBroken {
+			span.Warnf("ignore release volume unit %d fail:%+v", src.Vuid, err)
+			continue
+		}
+		return err
+	}
+	return nil
+}
+
+// AcquireTask acquire volume convert task
+func (mgr *VolumeConvertMgr) AcquireTask(ctx context.Context, idc string) (*proto.VolumeConvertTask, error) {
+	_, task, _ := mgr.workQueue.Acquire(idc)
+	if task != nil {
+		t := task.(*proto.VolumeConvertTask)
+		span := trace.SpanFromContextSafe(ctx)
+		span.Infof("acquire volume convert task %+v", t)
+		return t, nil
+	}
+	return nil, proto.ErrTaskEmpty
+}
+
+// CancelTask cancel volume convert task
+func (mgr *VolumeConvertMgr) CancelTask(ctx context.Context, args *api.CancelTaskArgs) error {
+	span := trace.SpanFromContextSafe(ctx)
+	span.Infof("cancel volume convert taskId %s", args.TaskId)
+
+	err := mgr.workQueue.Cancel(args.IDC, args.TaskId, args.Src, args.Dest)
+	if err != nil {
+		span.Errorf("cancel volume convert taskId %s fail error:%v", args.TaskId, err)
+	}
+
+	mgr.taskStatsMgr.CancelTask()
+	return err
+}
+
+// CompleteTask complete volume convert task
+func (mgr *VolumeConvertMgr) CompleteTask(ctx context.Context, args *api.CompleteTaskArgs) error {
+	span := trace.SpanFromContextSafe(ctx)
+	span.Infof("complete volume convert taskId %s", args.TaskId)
+
+	completeTask, err := mgr.workQueue.Complete(args.IDC, args.TaskId, args.Src, args.Dest)
+	if err != nil {
+		span.Errorf("complete volume convert taskId %s fail error:%v", args.TaskId, err)
+		return err
+	}
+
+	t := completeTask.(*proto.VolumeConvertTask)
+	t.State = proto.VolumeConvertStateWorkCompleted
+
+	mgr.finishQueue.PushTask(args.TaskId, t)
+	// delay saving task info in finish stage
+	return nil
+}
+
+// RenewalTask renewal volume convert task
+func (mgr *VolumeConvertMgr) RenewalTask(ctx context.Context, idc, taskID string) error {
+	span := trace.SpanFromContextSafe(ctx)
+	span.Debugf("Renewal volume convert taskID %s", taskID)
+	err := mgr.workQueue.Renewal(idc, taskID)
+	if err != nil {
+		span.Warnf("Renewal volume convert taskID %s fail error:%v", taskID, err)
+	}
+	return err
+}
+
+// ReportWorkerTaskStats reports task stats
+func (mgr *VolumeConvertMgr) ReportWorkerTaskStats(
+	taskID string,
+	s proto.TaskStatistics,
+	increaseDataSize,
+	increaseShardCnt int) {
+	mgr.taskStatsMgr.ReportWorkerTaskStats(taskID, s, increaseDataSize, increaseShardCnt)
+}
+
+// QueryTask return task statistics
+func (mgr *VolumeConvertMgr) QueryTask(ctx context.Context, taskID string) (proto.VolumeConvertTask, proto.TaskStatistics, error) {
+	taskInfo, err := mgr.taskTbl.Find(ctx, taskID)
+	if err != nil {
+		return proto.VolumeConvertTask{}, proto.TaskStatistics{}, err
+	}
+	detailRunInfo, err := mgr.taskStatsMgr.QueryTaskDetail(taskID)
+	if err != nil {
+		return *taskInfo, proto.TaskStatistics{}, nil
+	}
+	return *taskInfo, detailRunInfo.Statistics, nil
+}
+
+// GetTaskStats returns task stats
+func (mgr *VolumeConvertMgr) GetTaskStats() (finish, dataSize, shardCnt [counter.SLOT]int) {
+	increaseDataSize, increaseShardCnt := mgr.taskStatsMgr.Counters()
+	return mgr.finishTaskCounter.Show(), increaseDataSize, increaseShardCnt
+}
+
+// StatQueueTaskCnt returns task queue stats
+func (mgr *VolumeConvertMgr) StatQueueTaskCnt() (inited, prepared, completed int) {
+	todo, doing := mgr.prepareQueue.StatsTasks()
+	inited = todo + doing
+
+	todo, doing = mgr.workQueue.StatsTasks()
+	prepared = todo + doing
+
+	todo, doing = mgr.finishQueue.StatsTasks()
+	completed = todo + doing
+
+	return
+}
@@ -0,0 +1,474 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	api "github.com/cubefs/blobstore/api/scheduler"
+	"github.com/cubefs/blobstore/common/codemode"
+	comErr "github.com/cubefs/blobstore/common/errors"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/scheduler/base"
+	"github.com/cubefs/blobstore/scheduler/client"
+)
+
+type mockConvertCmClient struct {
+	mu     sync.Mutex
+	retErr error
+
+	nextVid    proto.Vid
+	volInfoMap map[proto.Vid]*client.VolumeInfoSimple
+	released   map[proto.Vuid]struct{}
+}
+
+func newMockConvertCmClient() *mockConvertCmClient {
+	return &mockConvertCmClient{
+		nextVid:    3000,
+		volInfoMap: make(map[proto.Vid]*client.VolumeInfoSimple),
+		released:   make(map[proto.Vuid]struct{}),
+	}
+}
+
+func (m *mockConvertCmClient) addVolume(vid proto.Vid, mode codemode.CodeMode, used, free uint64) {
+	vol := MockGenVolInfo(vid, mode, proto.VolumeStatusIdle)
+	vol.Used = used
+	vol.Free = free
+	m.volInfoMap[vid] = vol
+}
+
+func (m *mockConvertCmClient) GetVolumeInfo(ctx context.Context, vid proto.Vid) (*client.VolumeInfoSimple, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vol, ok := m.volInfoMap[vid]
+	if !ok {
+		return nil, errors.New("not exist")
+	}
+	ret := *vol
+	return &ret, m.retErr
+}
+
+func (m *mockConvertCmClient) LockVolume(ctx context.Context, vid proto.Vid) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.retErr != nil {
+		return m.retErr
+	}
+	m.volInfoMap[vid].Status = proto.VolumeStatusLock
+	return nil
+}
+
+func (m *mockConvertCmClient) UnlockVolume(ctx context.Context, vid proto.Vid) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.retErr != nil {
+		return m.retErr
+	}
+	m.volInfoMap[vid].Status = proto.VolumeStatusIdle
+	return nil
+}
+
+func (m *mockConvertCmClient) AllocVolume(ctx context.Context, mode codemode.CodeMode) (*client.VolumeInfoSimple, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.retErr != nil {
+		return nil, m.retErr
+	}
+	m.nextVid++
+	vol := MockGenVolInfo(m.nextVid, mode, proto.VolumeStatusActive)
+	vol.Free = 1 << 30
+	m.volInfoMap[vol.Vid] = vol
+	ret := *vol
+	return &ret, nil
+}
+
+func (m *mockConvertCmClient) SetVolumeRedirect(ctx context.Context, vid, redirectVid proto.Vid) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.retErr != nil {
+		return m.retErr
+	}
+	m.volInfoMap[vid].RedirectVid = redirectVid
+	return nil
+}
+
+func (m *mockConvertCmClient) GetDiskInfo(ctx context.Context, diskID proto.DiskID) (*client.DiskInfoSimple, error) {
+	disk := mockDiskInfo(diskID)
+	disk.Idc = "z0"
+	return disk, m.retErr
+}
+
+func (m *mockConvertCmClient) ReleaseVolumeUnit(ctx context.Context, vuid proto.Vuid, diskID proto.DiskID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.retErr != nil {
+		return m.retErr
+	}
+	m.released[vuid] = struct{}{}
+	return nil
+}
+
+type mockConvertTinkerClient struct {
+	mu      sync.Mutex
+	retErr  error
+	updated map[proto.Vid]int
+}
+
+func (m *mockConvertTinkerClient) UpdateVol(ctx context.Context, host string, vid proto.Vid, clusterID proto.ClusterID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.retErr != nil {
+		return m.retErr
+	}
+	m.updated[vid]++
+	return nil
+}
+
+type mockVolumeConvertTbl struct {
+	retErr error
+
+	tasksMap map[string]*proto.VolumeConvertTask
+	mu       sync.RWMutex
+}
+
+func newMockVolumeConvertTbl() *mockVolumeConvertTbl {
+	return &mockVolumeConvertTbl{tasksMap: make(map[string]*proto.VolumeConvertTask)}
+}
+
+func (tbl *mockVolumeConvertTbl) Insert(ctx context.Context, t *proto.VolumeConvertTask) error {
+	tbl.mu.Lock()
+	defer tbl.mu.Unlock()
+
+	if _, ok := tbl.tasksMap[t.TaskID]; ok {
+		return errors.New("mock taskId duplicate")
+	}
+	tbl.tasksMap[t.TaskID] = t.Copy()
+	return tbl.retErr
+}
+
+func (tbl *mockVolumeConvertTbl) Update(ctx context.Context, t *proto.VolumeConvertTask) error {
+	tbl.mu.Lock()
+	defer tbl.mu.Unlock()
+
+	if _, ok := tbl.tasksMap[t.TaskID]; !ok {
+		return errors.New("mock task not found")
+	}
+	tbl.tasksMap[t.TaskID] = t.Copy()
+	return tbl.retErr
+}
+
+func (tbl *mockVolumeConvertTbl) Find(ctx context.Context, taskID string) (*proto.VolumeConvertTask, error) {
+	tbl.mu.RLock()
+	defer tbl.mu.RUnlock()
+
+	task, ok := tbl.tasksMap[taskID]
+	if !ok {
+		return nil, base.ErrNoDocuments
+	}
+	return task.Copy(), tbl.retErr
+}
+
+func (tbl *mockVolumeConvertTbl) FindAll(ctx context.Context) (tasks []*proto.VolumeConvertTask, err error) {
+	tbl.mu.RLock()
+	defer tbl.mu.RUnlock()
+
+	for _, task := range tbl.tasksMap {
+		tasks = append(tasks, task.Copy())
+	}
+	return tasks, tbl.retErr
+}
+
+func (tbl *mockVolumeConvertTbl) taskBySource(vid proto.Vid) *proto.VolumeConvertTask {
+	tbl.mu.RLock()
+	defer tbl.mu.RUnlock()
+
+	for _, task := range tbl.tasksMap {
+		if task.SourceVid == vid {
+			return task.Copy()
+		}
+	}
+	return nil
+}
+
+func initVolumeConvertMgr() (*VolumeConvertMgr, *mockConvertCmClient, *mockVolumeConvertTbl) {
+	cmCli := newMockConvertCmClient()
+	tbl := newMockVolumeConvertTbl()
+	tinkerCli := &mockConvertTinkerClient{updated: make(map[proto.Vid]int)}
+	conf := &VolumeConvertMgrConfig{}
+	conf.CheckAndFix()
+	mgr := NewVolumeConvertMgr(cmCli, tinkerCli, NewMockRegisterTbl(nil), tbl, 1, conf)
+	// retry failed task immediately
+	mgr.prepareQueue = base.NewTaskQueue(0)
+	mgr.finishQueue = base.NewTaskQueue(0)
+	return mgr, cmCli, tbl
+}
+
+func TestVolumeConvertAddTask(t *testing.T) {
+	ctx := context.Background()
+	mgr, cmCli, tbl := initVolumeConvertMgr()
+	cmCli.addVolume(2001, codemode.EC6P6, 100, 100)
+	cmCli.addVolume(2002, codemode.EC12P4, 100, 100)
+
+	// volume not exist
+	err := mgr.AddTask(ctx, 2000, codemode.EC12P4)
+	require.Error(t, err)
+	// same code mode
+	err = mgr.AddTask(ctx, 2002, codemode.EC12P4)
+	require.ErrorIs(t, err, comErr.ErrIllegalArguments)
+
+	err = mgr.AddTask(ctx, 2001, codemode.EC12P4)
+	require.NoError(t, err)
+	task := tbl.taskBySource(2001)
+	require.NotNil(t, task)
+	require.Equal(t, proto.VolumeConvertStateInited, task.State)
+	require.Equal(t, codemode.EC6P6, task.SourceCodeMode)
+	require.Equal(t, codemode.EC12P4, task.DestinationCodeMode)
+	_, exist := mgr.prepareQueue.Query(task.TaskID)
+	require.True(t, exist)
+
+	// conflict with running task
+	err = mgr.AddTask(ctx, 2001, codemode.EC12P4)
+	require.ErrorIs(t, err, ErrVidTaskConflict)
+
+	// destination of another task
+	task.DestinationVid = 2002
+	require.NoError(t, tbl.Update(ctx, task))
+	err = mgr.AddTask(ctx, 2002, codemode.EC6P6)
+	require.ErrorIs(t, err, comErr.ErrIllegalArguments)
+
+	// redirected volume
+	cmCli.volInfoMap[2002].RedirectVid = 2003
+	err = mgr.AddTask(ctx, 2002, codemode.EC6P6)
+	require.ErrorIs(t, err, comErr.ErrIllegalArguments)
+}
+
+func TestVolumeConvertPrepareFinishInAdvance(t *testing.T) {
+	ctx := context.Background()
+	mgr, cmCli, tbl := initVolumeConvertMgr()
+	cmCli.addVolume(2011, codemode.EC6P6, 100, 100)
+	cmCli.addVolume(2012, codemode.EC6P6, 100, 100)
+	cmCli.addVolume(2013, codemode.EC6P6, 1<<31, 100)
+
+	for _, vid := range []proto.Vid{2011, 2012, 2013} {
+		require.NoError(t, mgr.AddTask(ctx, vid, codemode.EC12P4))
+	}
+	cmCli.volInfoMap[2011].RedirectVid = 2100
+	cmCli.volInfoMap[2012].CodeMode = codemode.EC12P4
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, mgr.popTaskAndPrepare())
+	}
+	require.Equal(t, base.ErrNoTaskInQueue, mgr.popTaskAndPrepare())
+
+	reasons := map[proto.Vid]string{
+		2011: convertFinishReasonRedirected,
+		2012: convertFinishReasonSameMode,
+		2013: convertFinishReasonNoSpace,
+	}
+	for vid, reason := range reasons {
+		task := tbl.taskBySource(vid)
+		require.Equal(t, proto.VolumeConvertStateFinishedInAdvance, task.State)
+		require.Equal(t, reason, task.FinishAdvanceReason)
+		// volume task lock is released
+		require.NoError(t, VolTaskLockerInst().TryLock(ctx, vid))
+		VolTaskLockerInst().Unlock(ctx, vid)
+	}
+	// source volume is unlocked again when no space
+	require.Equal(t, proto.VolumeStatusIdle, cmCli.volInfoMap[2013].Status)
+	// destination is allocated and persisted
+	require.NotEqual(t, proto.InvalidVid, tbl.taskBySource(2013).DestinationVid)
+
+	// destination of task finished in advance can be converted
+	dstVid := tbl.taskBySource(2013).DestinationVid
+	require.NoError(t, mgr.AddTask(ctx, dstVid, codemode.EC6P6))
+}
+
+func TestVolumeConvertPrepareErr(t *testing.T) {
+	ctx := context.Background()
+	mgr, cmCli, tbl := initVolumeConvertMgr()
+	cmCli.addVolume(2021, codemode.EC6P6, 100, 100)
+	require.NoError(t, mgr.AddTask(ctx, 2021, codemode.EC12P4))
+
+	// volume has another running task
+	require.NoError(t, VolTaskLockerInst().TryLock(ctx, 2021))
+	require.Error(t, mgr.popTaskAndPrepare())
+	VolTaskLockerInst().Unlock(ctx, 2021)
+
+	cmCli.retErr = errors.New("mock error")
+	require.Error(t, mgr.popTaskAndPrepare())
+	require.Equal(t, proto.VolumeConvertStateInited, tbl.taskBySource(2021).State)
+	// lock is released when prepare failed
+	require.NoError(t, VolTaskLockerInst().TryLock(ctx, 2021))
+	VolTaskLockerInst().Unlock(ctx, 2021)
+}
+
+func TestVolumeConvertWorkflow(t *testing.T) {
+	ctx := context.Background()
+	mgr, cmCli, tbl := initVolumeConvertMgr()
+	cmCli.addVolume(2031, codemode.EC6P6, 100, 100)
+	require.NoError(t, mgr.AddTask(ctx, 2031, codemode.EC12P4))
+
+	_, err := mgr.AcquireTask(ctx, "z0")
+	require.ErrorIs(t, err, proto.ErrTaskEmpty)
+
+	require.NoError(t, mgr.popTaskAndPrepare())
+	require.Equal(t, proto.VolumeStatusLock, cmCli.volInfoMap[2031].Status)
+	task := tbl.taskBySource(2031)
+	require.Equal(t, proto.VolumeConvertStatePrepared, task.State)
+	require.Equal(t, "z0", task.SourceIdc)
+	require.Len(t, task.Sources, codemode.EC6P6.GetShardNum())
+	require.Len(t, task.Destinations, codemode.EC12P4.GetShardNum())
+	// destination volume is locked by task
+	require.Error(t, VolTaskLockerInst().TryLock(ctx, task.DestinationVid))
+
+	inited, prepared, completed := mgr.StatQueueTaskCnt()
+	require.Equal(t, [3]int{0, 1, 0}, [3]int{inited, prepared, completed})
+
+	acquired, err := mgr.AcquireTask(ctx, "z0")
+	require.NoError(t, err)
+	require.Equal(t, task.TaskID, acquired.TaskID)
+
+	require.NoError(t, mgr.RenewalTask(ctx, "z0", task.TaskID))
+	require.Error(t, mgr.RenewalTask(ctx, "z0", "not_exist"))
+
+	cancelArgs := &api.CancelTaskArgs{
+		TaskId:   task.TaskID,
+		TaskType: proto.VolumeConvertType,
+		IDC:      "z0",
+		Src:      task.Sources,
+		Dest:     task.GetDest(),
+	}
+	require.NoError(t, mgr.CancelTask(ctx, cancelArgs))
+
+	completeArgs := &api.CompleteTaskArgs{
+		TaskId:   task.TaskID,
+		TaskType: proto.VolumeConvertType,
+		IDC:      "z0",
+		Src:      task.Sources,
+		Dest:     task.GetDest(),
+	}
+	require.NoError(t, mgr.CompleteTask(ctx, completeArgs))
+
+	// redirect fail and retry
+	cmCli.retErr = errors.New("mock error")
+	require.Error(t, mgr.popTaskAndFinish())
+	require.Equal(t, proto.VolumeConvertStateWorkCompleted, tbl.taskBySource(2031).State)
+	cmCli.retErr = nil
+
+	// notify tinker fail and retry
+	tinkerCli := mgr.tinkerCli.(*mockConvertTinkerClient)
+	tinkerCli.retErr = errors.New("mock error")
+	require.ErrorIs(t, mgr.popTaskAndFinish(), base.ErrNotifyTinkerUpdateVol)
+	require.False(t, tbl.taskBySource(2031).Redirected())
+	tinkerCli.retErr = nil
+
+	// worker redoes task after redirected
+	require.NoError(t, mgr.popTaskAndFinish())
+	require.Equal(t, base.ErrNoTaskInQueue, mgr.popTaskAndFinish())
+	require.Equal(t, task.DestinationVid, cmCli.volInfoMap[2031].RedirectVid)
+	require.Equal(t, 3, tinkerCli.updated[2031])
+	redoTask := tbl.taskBySource(2031)
+	require.Equal(t, proto.VolumeConvertStatePrepared, redoTask.State)
+	require.True(t, redoTask.Redirected())
+	require.Equal(t, uint8(1), redoTask.WorkerRedoCnt)
+	acquired, err = mgr.AcquireTask(ctx, "z0")
+	require.NoError(t, err)
+	require.True(t, acquired.Redirected())
+	require.NoError(t, mgr.CompleteTask(ctx, completeArgs))
+
+	// source units are released a while after redirected
+	require.ErrorIs(t, mgr.popTaskAndFinish(), errReleaseSourceDelayed)
+	require.Equal(t, 0, len(cmCli.released))
+	mgr.cfg.ReleaseSourceDelayS = 0
+	cmCli.retErr = errors.New("mock error")
+	require.Error(t, mgr.popTaskAndFinish())
+	require.Equal(t, proto.VolumeConvertStateWorkCompleted, tbl.taskBySource(2031).State)
+	cmCli.retErr = nil
+
+	require.NoError(t, mgr.popTaskAndFinish())
+	require.Equal(t, base.ErrNoTaskInQueue, mgr.popTaskAndFinish())
+	require.Equal(t, proto.VolumeConvertStateFinished, tbl.taskBySource(2031).State)
+	require.Equal(t, len(task.Sources), len(cmCli.released))
+	for _, src := range task.Sources {
+		_, ok := cmCli.released[src.Vuid]
+		require.True(t, ok)
+	}
+	// source volume keeps locked
+	require.Equal(t, proto.VolumeStatusLock, cmCli.volInfoMap[2031].Status)
+	for _, vid := range []proto.Vid{2031, task.DestinationVid} {
+		require.NoError(t, VolTaskLockerInst().TryLock(ctx, vid))
+		VolTaskLockerInst().Unlock(ctx, vid)
+	}
+
+	detail, _, err := mgr.QueryTask(ctx, task.TaskID)
+	require.NoError(t, err)
+	require.Equal(t, proto.VolumeConvertStateFinished, detail.State)
+	_, _, err = mgr.QueryTask(ctx, "not_exist")
+	require.ErrorIs(t, err, base.ErrNoDocuments)
+
+	finish, _, _ := mgr.GetTaskStats()
+	require.Equal(t, 1, finish[len(finish)-1])
+}
+
+func TestVolumeConvertLoad(t *testing.T) {
+	ctx := context.Background()
+	mgr, _, tbl := initVolumeConvertMgr()
+
+	states := []proto.VolumeConvertState{
+		proto.VolumeConvertStateInited,
+		proto.VolumeConvertStatePrepared,
+		proto.VolumeConvertStateWorkCompleted,
+		proto.VolumeConvertStateFinished,
+		proto.VolumeConvertStateFinishedInAdvance,
+	}
+	for i, state := range states {
+		vid := proto.Vid(2041 + i)
+		task := &proto.VolumeConvertTask{
+			TaskID:              mgr.genUniqTaskID(vid),
+			State:               state,
+			SourceIdc:           "z0",
+			SourceVid:           vid,
+			SourceCodeMode:      codemode.EC6P6,
+			DestinationCodeMode: codemode.EC12P4,
+		}
+		if state != proto.VolumeConvertStateInited {
+			task.DestinationVid = vid + 100
+		}
+		require.NoError(t, tbl.Insert(ctx, task))
+	}
+
+	require.NoError(t, mgr.Load())
+	inited, prepared, completed := mgr.StatQueueTaskCnt()
+	require.Equal(t, [3]int{1, 1, 1}, [3]int{inited, prepared, completed})
+
+	// running tasks hold locks of both volumes
+	for _, vid := range []proto.Vid{2042, 2142, 2043, 2143} {
+		require.Error(t, VolTaskLockerInst().TryLock(ctx, vid))
+		VolTaskLockerInst().Unlock(ctx, vid)
+	}
+	for _, vid := range []proto.Vid{2041, 2044, 2045} {
+		require.NoError(t, VolTaskLockerInst().TryLock(ctx, vid))
+		VolTaskLockerInst().Unlock(ctx, vid)
+	}
+
+	mgr.Run()
+	mgr.Close()
+	mgr.Close()
+}
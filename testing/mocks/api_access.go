@@ -66,6 +66,95 @@ func (mr *MockAccessAPIMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockAccessAPI)(nil).Get), arg0, arg1)
 }
 
+// GetRanges mocks base method.
+func (m *MockAccessAPI) GetRanges(arg0 context.Context, arg1 *access.GetRangesArgs) (*access.RangesBody, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRanges", arg0, arg1)
+	ret0, _ := ret[0].(*access.RangesBody)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRanges indicates an expected call of GetRanges.
+func (mr *MockAccessAPIMockRecorder) GetRanges(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRanges", reflect.TypeOf((*MockAccessAPI)(nil).GetRanges), arg0, arg1)
+}
+
+// MultipartAbort mocks base method.
+func (m *MockAccessAPI) MultipartAbort(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MultipartAbort", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MultipartAbort indicates an expected call of MultipartAbort.
+func (mr *MockAccessAPIMockRecorder) MultipartAbort(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MultipartAbort", reflect.TypeOf((*MockAccessAPI)(nil).MultipartAbort), arg0, arg1)
+}
+
+// MultipartComplete mocks base method.
+func (m *MockAccessAPI) MultipartComplete(arg0 context.Context, arg1 string) (access.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MultipartComplete", arg0, arg1)
+	ret0, _ := ret[0].(access.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MultipartComplete indicates an expected call of MultipartComplete.
+func (mr *MockAccessAPIMockRecorder) MultipartComplete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MultipartComplete", reflect.TypeOf((*MockAccessAPI)(nil).MultipartComplete), arg0, arg1)
+}
+
+// MultipartInit mocks base method.
+func (m *MockAccessAPI) MultipartInit(arg0 context.Context, arg1 *access.MultipartInitArgs) (access.MultipartInitResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MultipartInit", arg0, arg1)
+	ret0, _ := ret[0].(access.MultipartInitResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MultipartInit indicates an expected call of MultipartInit.
+func (mr *MockAccessAPIMockRecorder) MultipartInit(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MultipartInit", reflect.TypeOf((*MockAccessAPI)(nil).MultipartInit), arg0, arg1)
+}
+
+// MultipartList mocks base method.
+func (m *MockAccessAPI) MultipartList(arg0 context.Context, arg1 string) (access.MultipartListResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MultipartList", arg0, arg1)
+	ret0, _ := ret[0].(access.MultipartListResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MultipartList indicates an expected call of MultipartList.
+func (mr *MockAccessAPIMockRecorder) MultipartList(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MultipartList", reflect.TypeOf((*MockAccessAPI)(nil).MultipartList), arg0, arg1)
+}
+
+// MultipartPut mocks base method.
+func (m *MockAccessAPI) MultipartPut(arg0 context.Context, arg1 *access.MultipartPutArgs) (access.HashSumMap, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MultipartPut", arg0, arg1)
+	ret0, _ := ret[0].(access.HashSumMap)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MultipartPut indicates an expected call of MultipartPut.
+func (mr *MockAccessAPIMockRecorder) MultipartPut(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MultipartPut", reflect.TypeOf((*MockAccessAPI)(nil).MultipartPut), arg0, arg1)
+}
+
 // Put mocks base method.
 func (m *MockAccessAPI) Put(arg0 context.Context, arg1 *access.PutArgs) (access.Location, access.HashSumMap, error) {
 	m.ctrl.T.Helper()
@@ -37,6 +37,21 @@ func (m *MockStorageAPI) EXPECT() *MockStorageAPIMockRecorder {
 	return m.recorder
 }
 
+// AttachDisk mocks base method.
+func (m *MockStorageAPI) AttachDisk(arg0 context.Context, arg1 string, arg2 *blobnode.DiskAttachArgs) (*blobnode.DiskInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AttachDisk", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*blobnode.DiskInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AttachDisk indicates an expected call of AttachDisk.
+func (mr *MockStorageAPIMockRecorder) AttachDisk(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachDisk", reflect.TypeOf((*MockStorageAPI)(nil).AttachDisk), arg0, arg1, arg2)
+}
+
 // Close mocks base method.
 func (m *MockStorageAPI) Close(arg0 context.Context, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -94,6 +109,21 @@ func (mr *MockStorageAPIMockRecorder) DiskInfo(arg0, arg1, arg2 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiskInfo", reflect.TypeOf((*MockStorageAPI)(nil).DiskInfo), arg0, arg1, arg2)
 }
 
+// ExportChunk mocks base method.
+func (m *MockStorageAPI) ExportChunk(arg0 context.Context, arg1 string, arg2 *blobnode.ExportChunkArgs) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportChunk", arg0, arg1, arg2)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportChunk indicates an expected call of ExportChunk.
+func (mr *MockStorageAPIMockRecorder) ExportChunk(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportChunk", reflect.TypeOf((*MockStorageAPI)(nil).ExportChunk), arg0, arg1, arg2)
+}
+
 // GetShard mocks base method.
 func (m *MockStorageAPI) GetShard(arg0 context.Context, arg1 string, arg2 *blobnode.GetShardArgs) (io.ReadCloser, uint32, error) {
 	m.ctrl.T.Helper()
@@ -125,6 +155,20 @@ func (mr *MockStorageAPIMockRecorder) GetShards(arg0, arg1, arg2 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShards", reflect.TypeOf((*MockStorageAPI)(nil).GetShards), arg0, arg1, arg2)
 }
 
+// ImportChunk mocks base method.
+func (m *MockStorageAPI) ImportChunk(arg0 context.Context, arg1 string, arg2 *blobnode.ImportChunkArgs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportChunk", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ImportChunk indicates an expected call of ImportChunk.
+func (mr *MockStorageAPIMockRecorder) ImportChunk(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportChunk", reflect.TypeOf((*MockStorageAPI)(nil).ImportChunk), arg0, arg1, arg2)
+}
+
 // IsOnline mocks base method.
 func (m *MockStorageAPI) IsOnline(arg0 context.Context, arg1 string) bool {
 	m.ctrl.T.Helper()
@@ -184,6 +228,20 @@ func (mr *MockStorageAPIMockRecorder) MarkDeleteShard(arg0, arg1, arg2 interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDeleteShard", reflect.TypeOf((*MockStorageAPI)(nil).MarkDeleteShard), arg0, arg1, arg2)
 }
 
+// MigrateChunk mocks base method.
+func (m *MockStorageAPI) MigrateChunk(arg0 context.Context, arg1 string, arg2 *blobnode.MigrateChunkArgs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateChunk", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateChunk indicates an expected call of MigrateChunk.
+func (mr *MockStorageAPIMockRecorder) MigrateChunk(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateChunk", reflect.TypeOf((*MockStorageAPI)(nil).MigrateChunk), arg0, arg1, arg2)
+}
+
 // PutShard mocks base method.
 func (m *MockStorageAPI) PutShard(arg0 context.Context, arg1 string, arg2 *blobnode.PutShardArgs) (uint32, error) {
 	m.ctrl.T.Helper()
@@ -199,6 +257,21 @@ func (mr *MockStorageAPIMockRecorder) PutShard(arg0, arg1, arg2 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutShard", reflect.TypeOf((*MockStorageAPI)(nil).PutShard), arg0, arg1, arg2)
 }
 
+// PutShards mocks base method.
+func (m *MockStorageAPI) PutShards(arg0 context.Context, arg1 string, arg2 *blobnode.PutShardsArgs) (*blobnode.PutShardsRet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutShards", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*blobnode.PutShardsRet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutShards indicates an expected call of PutShards.
+func (mr *MockStorageAPIMockRecorder) PutShards(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutShards", reflect.TypeOf((*MockStorageAPI)(nil).PutShards), arg0, arg1, arg2)
+}
+
 // RangeGetShard mocks base method.
 func (m *MockStorageAPI) RangeGetShard(arg0 context.Context, arg1 string, arg2 *blobnode.RangeGetShardArgs) (io.ReadCloser, uint32, error) {
 	m.ctrl.T.Helper()
@@ -80,6 +80,20 @@ func (mr *MockISchedulerMockRecorder) AddManualMigrateTask(arg0, arg1 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddManualMigrateTask", reflect.TypeOf((*MockIScheduler)(nil).AddManualMigrateTask), arg0, arg1)
 }
 
+// AddVolumeConvertTask mocks base method.
+func (m *MockIScheduler) AddVolumeConvertTask(arg0 context.Context, arg1 *scheduler.AddVolumeConvertArgs) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddVolumeConvertTask", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddVolumeConvertTask indicates an expected call of AddVolumeConvertTask.
+func (mr *MockISchedulerMockRecorder) AddVolumeConvertTask(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVolumeConvertTask", reflect.TypeOf((*MockIScheduler)(nil).AddVolumeConvertTask), arg0, arg1)
+}
+
 // BalanceTaskDetail mocks base method.
 func (m *MockIScheduler) BalanceTaskDetail(arg0 context.Context, arg1 *scheduler.TaskStatArgs) (scheduler.MigrateTaskDetail, error) {
 	m.ctrl.T.Helper()
@@ -297,3 +311,18 @@ func (mr *MockISchedulerMockRecorder) Stats(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockIScheduler)(nil).Stats), arg0)
 }
+
+// VolumeConvertTaskDetail mocks base method.
+func (m *MockIScheduler) VolumeConvertTaskDetail(arg0 context.Context, arg1 *scheduler.TaskStatArgs) (scheduler.VolumeConvertTaskDetail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VolumeConvertTaskDetail", arg0, arg1)
+	ret0, _ := ret[0].(scheduler.VolumeConvertTaskDetail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VolumeConvertTaskDetail indicates an expected call of VolumeConvertTaskDetail.
+func (mr *MockISchedulerMockRecorder) VolumeConvertTaskDetail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VolumeConvertTaskDetail", reflect.TypeOf((*MockIScheduler)(nil).VolumeConvertTaskDetail), arg0, arg1)
+}
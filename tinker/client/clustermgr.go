@@ -71,10 +71,27 @@ func (c *clusterMgrClient) GetVolInfo(ctx context.Context, vid proto.Vid) (vol V
 	if err != nil {
 		return
 	}
+	if info, err = c.redirect(ctx, info); err != nil {
+		return
+	}
 	vol = parseFrom(info)
 	return
 }
 
+// redirect returns info of the redirect volume if volume had been converted,
+// the vid of returned info keeps the same.
+func (c *clusterMgrClient) redirect(ctx context.Context, info *clustermgr.VolumeInfo) (*clustermgr.VolumeInfo, error) {
+	if info.RedirectVid == proto.InvalidVid {
+		return info, nil
+	}
+	redirectInfo, err := c.client.GetVolumeInfo(ctx, &clustermgr.GetVolumeArgs{Vid: info.RedirectVid})
+	if err != nil {
+		return nil, err
+	}
+	redirectInfo.Vid = info.Vid
+	return redirectInfo, nil
+}
+
 // ListVolume lists volume info
 func (c *clusterMgrClient) ListVolume(ctx context.Context, marker proto.Vid, count int) ([]VolInfo, proto.Vid, error) {
 	ret, err := c.client.ListVolume(ctx, &clustermgr.ListVolumeArgs{Marker: marker, Count: count})
@@ -83,6 +100,9 @@ func (c *clusterMgrClient) ListVolume(ctx context.Context, marker proto.Vid, cou
 	}
 	vols := make([]VolInfo, 0, len(ret.Volumes))
 	for _, info := range ret.Volumes {
+		if info, err = c.redirect(ctx, info); err != nil {
+			return nil, 0, err
+		}
 		vols = append(vols, parseFrom(info))
 	}
 	return vols, ret.Marker, nil
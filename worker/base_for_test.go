@@ -182,7 +182,7 @@ func (getter *MockGetter) setVunitStatus(vuid proto.Vuid, status api.ChunkStatus
 	}
 }
 
-func (getter *MockGetter) PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, body io.Reader) (err error) {
+func (getter *MockGetter) PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, expireAt int64, body io.Reader) (err error) {
 	getter.mu.Lock()
 	defer getter.mu.Unlock()
 	if err, ok := getter.failVuid[location.Vuid]; ok {
@@ -191,9 +191,16 @@ func (getter *MockGetter) PutShard(ctx context.Context, location proto.VunitLoca
 	data := make([]byte, size)
 	body.Read(data)
 	getter.vunits[location.Vuid].putShard(bid, data)
+	getter.vunits[location.Vuid].setExpireAt(bid, expireAt)
 	return
 }
 
+func (getter *MockGetter) SetExpireAt(vuid proto.Vuid, bid proto.BlobID, expireAt int64) {
+	getter.mu.Lock()
+	defer getter.mu.Unlock()
+	getter.vunits[vuid].setExpireAt(bid, expireAt)
+}
+
 func (getter *MockGetter) GetShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (body io.ReadCloser, crc32 uint32, err error) {
 	getter.mu.Lock()
 	defer getter.mu.Unlock()
@@ -217,6 +224,16 @@ func (getter *MockGetter) Delete(ctx context.Context, vuid proto.Vuid, bid proto
 	getter.vunits[vuid].delete(bid)
 }
 
+func (getter *MockGetter) DeleteShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (err error) {
+	getter.mu.Lock()
+	defer getter.mu.Unlock()
+	if err, ok := getter.failVuid[location.Vuid]; ok {
+		return err
+	}
+	getter.vunits[location.Vuid].delete(bid)
+	return
+}
+
 func (getter *MockGetter) StatShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (si *client.ShardInfo, err error) {
 	getter.mu.Lock()
 	defer getter.mu.Unlock()
@@ -381,6 +398,12 @@ func (m *mockVunit) markDelete(bid proto.BlobID) {
 	m.bidInfos[bid].Flag = api.ShardStatusMarkDelete
 }
 
+func (m *mockVunit) setExpireAt(bid proto.BlobID, expireAt int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bidInfos[bid].ExpireAt = expireAt
+}
+
 func (m *mockVunit) recover(bid proto.BlobID) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/cubefs/blobstore/common/codemode"
 	"github.com/cubefs/blobstore/common/proto"
@@ -44,8 +45,9 @@ type BidInfoGetter interface {
 
 // ShardInfoSimple with blob id and size
 type ShardInfoSimple struct {
-	Bid  proto.BlobID
-	Size int64
+	Bid      proto.BlobID
+	Size     int64
+	ExpireAt int64 // unix seconds, 0 means never expire
 }
 
 // ShardInfoWithCrc with blob id and size and crc
@@ -126,7 +128,7 @@ func MergeBids(replicasBids map[proto.Vuid]*ReplicaBidsRet) []*ShardInfoSimple {
 
 	var allBidsList []*ShardInfoSimple
 	for _, bid := range allBidsMap {
-		bidInfo := ShardInfoSimple{Bid: bid.Bid, Size: bid.Size}
+		bidInfo := ShardInfoSimple{Bid: bid.Bid, Size: bid.Size, ExpireAt: bid.ExpireAt}
 		allBidsList = append(allBidsList, &bidInfo)
 	}
 	return allBidsList
@@ -158,7 +160,14 @@ func GetBenchmarkBids(
 
 	allBidsList := MergeBids(replicasBids)
 	benchMark := []*ShardInfoSimple{}
+	now := time.Now().Unix()
 	for _, bid := range allBidsList {
+		// expired shard is dropped by blobnode, needn't migrate
+		if bid.ExpireAt > 0 && bid.ExpireAt <= now {
+			span.Debugf("skip expired bid: bid[%d], expire at[%d]", bid.Bid, bid.ExpireAt)
+			continue
+		}
+
 		markDel := false
 		existStatus := base.NewBidExistStatus(mode)
 		notExistCnt := 0
@@ -191,7 +200,7 @@ func GetBenchmarkBids(
 		}
 
 		if existStatus.CanRecover() {
-			bidInfo := ShardInfoSimple{Bid: bid.Bid, Size: bid.Size}
+			bidInfo := ShardInfoSimple{Bid: bid.Bid, Size: bid.Size, ExpireAt: bid.ExpireAt}
 			benchMark = append(benchMark, &bidInfo)
 			continue
 		}
@@ -17,6 +17,7 @@ package worker
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -192,6 +193,23 @@ func testGetBenchmarkBids(t *testing.T, mode codemode.CodeMode) {
 	sizes2 := []int64{1024, 1024, 1024, 1024, 1024}
 	bidsEqual(t, benchmarkBids, bids2, sizes2)
 
+	// expired bid is skipped, expire time is carried
+	expireAt := time.Now().Add(time.Hour).Unix()
+	for _, replica := range replicas {
+		getter.SetExpireAt(replica.Vuid, 3, time.Now().Add(-time.Hour).Unix())
+		getter.SetExpireAt(replica.Vuid, 4, expireAt)
+	}
+	benchmarkBids, err = GetBenchmarkBids(context.Background(), getter, replicas, mode, []uint8{})
+	require.NoError(t, err)
+	bids3 := []proto.BlobID{4, 5, 6, 7}
+	sizes3 := []int64{1024, 1024, 1024, 1024}
+	bidsEqual(t, benchmarkBids, bids3, sizes3)
+	for _, bid := range benchmarkBids {
+		if bid.Bid == 4 {
+			require.Equal(t, expireAt, bid.ExpireAt)
+		}
+	}
+
 	// test broken many
 	allowFailCnt := n + m - codeInfo.PutQuorum
 	minWellReplicasCnt := n + allowFailCnt
@@ -17,6 +17,7 @@ package client
 import (
 	"context"
 	"io"
+	"time"
 
 	api "github.com/cubefs/blobstore/api/blobnode"
 	errcode "github.com/cubefs/blobstore/common/errors"
@@ -33,7 +34,8 @@ type IBlobNode interface {
 	StatShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (si *ShardInfo, err error)
 	ListShards(ctx context.Context, location proto.VunitLocation) (shards []*ShardInfo, err error)
 	GetShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (body io.ReadCloser, crc32 uint32, err error)
-	PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, body io.Reader) (err error)
+	PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, expireAt int64, body io.Reader) (err error)
+	DeleteShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (err error)
 }
 
 // BlobNodeClient blobnode client
@@ -76,6 +78,11 @@ func (si *ShardInfo) NotExist() bool {
 	return si.Flag == ShardStatusNotExist
 }
 
+// Expired returns true if shard is expired at now
+func (si *ShardInfo) Expired(now time.Time) bool {
+	return si.ExpireAt > 0 && si.ExpireAt <= now.Unix()
+}
+
 // NewBlobNodeClient returns blobnode client
 func NewBlobNodeClient(conf *api.Config) IBlobNode {
 	return &BlobNodeClient{
@@ -149,11 +156,39 @@ func (c *BlobNodeClient) ListShards(ctx context.Context, location proto.VunitLoc
 	return sis, nil
 }
 
-// PutShard put data to shard
-func (c *BlobNodeClient) PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, body io.Reader) (err error) {
+// PutShard put data to shard, expireAt is unix seconds the shard expires at, 0 means never expire
+func (c *BlobNodeClient) PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, expireAt int64, body io.Reader) (err error) {
 	pSpan := trace.SpanFromContextSafe(ctx)
 	_, ctx = trace.StartSpanFromContextWithTraceID(context.Background(), "PutShard", pSpan.TraceID())
 
-	_, err = c.cli.PutShard(ctx, location.Host, &api.PutShardArgs{DiskID: location.DiskID, Vuid: location.Vuid, Bid: bid, Body: body, Size: size, Type: api.BackgroundIO})
+	_, err = c.cli.PutShard(ctx, location.Host, &api.PutShardArgs{
+		DiskID: location.DiskID, Vuid: location.Vuid, Bid: bid, Body: body, Size: size, Type: api.BackgroundIO, ExpireAt: expireAt,
+	})
 	return
 }
+
+// DeleteShard mark delete and delete shard, shard which has been deleted is regarded as success
+func (c *BlobNodeClient) DeleteShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (err error) {
+	pSpan := trace.SpanFromContextSafe(ctx)
+	span, ctx := trace.StartSpanFromContextWithTraceID(context.Background(), "DeleteShard", pSpan.TraceID())
+
+	args := &api.DeleteShardArgs{DiskID: location.DiskID, Vuid: location.Vuid, Bid: bid}
+	err = c.cli.MarkDeleteShard(ctx, location.Host, args)
+	if err != nil {
+		switch rpc.DetectStatusCode(err) {
+		case errcode.CodeBidNotFound:
+			return nil
+		case errcode.CodeShardMarkDeleted:
+		default:
+			span.Errorf("MarkDeleteShard failed: location[%+v], bid[%d], err[%+v]", location, bid, err)
+			return err
+		}
+	}
+	err = c.cli.DeleteShard(ctx, location.Host, args)
+	if err != nil && rpc.DetectStatusCode(err) != errcode.CodeBidNotFound {
+		span.Errorf("DeleteShard failed: location[%+v], bid[%d], err[%+v]", location, bid, err)
+		return err
+	}
+	span.Debugf("DeleteShard success: location[%+v], bid[%d]", location, bid)
+	return nil
+}
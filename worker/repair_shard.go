@@ -19,6 +19,7 @@ import (
 	"context"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/cubefs/blobstore/common/codemode"
 	errcode "github.com/cubefs/blobstore/common/errors"
@@ -30,7 +31,7 @@ import (
 
 // IShardAccess define the interface of blobnode use by shard repair
 type IShardAccess interface {
-	PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, body io.Reader) (err error)
+	PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, expireAt int64, body io.Reader) (err error)
 	StatShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (si *client.ShardInfo, err error)
 	GetShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (body io.ReadCloser, crc32 uint32, err error)
 }
@@ -102,7 +103,7 @@ func (repairer *ShardRepairer) RepairShard(ctx context.Context, task proto.Shard
 		return nil
 	}
 	// get repair shards:
-	shouldRepairIdxs, shardSize, err := getRepairShards(ctx, shardInfos, task.CodeMode, badIdxs)
+	shouldRepairIdxs, shardSize, expireAt, err := getRepairShards(ctx, shardInfos, task.CodeMode, badIdxs)
 	if err != nil {
 		span.Errorf("get repair shards failed: task[%+v], err[%+v]", err, task)
 		return err
@@ -113,7 +114,7 @@ func (repairer *ShardRepairer) RepairShard(ctx context.Context, task proto.Shard
 	}
 
 	span.Infof("start recover blob: bid[%d], badIdx[%+v]", task.Bid, task.BadIdxs)
-	bidInfos := []*ShardInfoSimple{{Bid: task.Bid, Size: shardSize}}
+	bidInfos := []*ShardInfoSimple{{Bid: task.Bid, Size: shardSize, ExpireAt: expireAt}}
 	shardRecover := NewShardRecover(task.Sources, task.CodeMode, bidInfos, repairer.bufPool, repairer.cli, 1)
 	defer shardRecover.ReleaseBuf()
 	err = shardRecover.RecoverShards(ctx, task.BadIdxs, false)
@@ -136,7 +137,7 @@ func (repairer *ShardRepairer) RepairShard(ctx context.Context, task proto.Shard
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
-			err = repairer.cli.PutShard(ctx, dstLocation, task.Bid, shardSize, bytes.NewReader(data))
+			err = repairer.cli.PutShard(ctx, dstLocation, task.Bid, shardSize, expireAt, bytes.NewReader(data))
 			retErrs[i] = err
 		}(badi)
 	}
@@ -183,15 +184,20 @@ func getRepairShards(
 	ctx context.Context,
 	shardInfos []*ShardInfoEx,
 	mode codemode.CodeMode,
-	repaireIdxs []int) (repairIdx []int, shardSize int64, err error) {
+	repaireIdxs []int) (repairIdx []int, shardSize int64, expireAt int64, err error) {
 	span := trace.SpanFromContextSafe(ctx)
 	// step1:
-	// if any shards has mark delete will not need repair
+	// if any shards has mark delete or expired will not need repair
 	// other shard without repair are all not exist,will not need repair
+	now := time.Now()
 	bidNotExistCnt := 0
 	for idx, shard := range shardInfos {
 		if shard.MarkDeleted() {
-			return []int{}, 0, nil
+			return []int{}, 0, 0, nil
+		}
+		if shard.Normal() && shard.info.Expired(now) {
+			span.Infof("shard is expired and skip: bid[%d], expire at[%d]", shard.info.Bid, shard.info.ExpireAt)
+			return []int{}, 0, 0, nil
 		}
 
 		if contains(idx, repaireIdxs) {
@@ -205,7 +211,7 @@ func getRepairShards(
 	}
 	if bidNotExistCnt == base.AllReplCnt(mode)-len(repaireIdxs) {
 		span.Info("all shards without repair are not exist")
-		return []int{}, 0, nil
+		return []int{}, 0, 0, nil
 	}
 
 	// step2:check miss too many shards to repair
@@ -213,13 +219,14 @@ func getRepairShards(
 	for _, shard := range shardInfos {
 		if shard.Normal() {
 			shardSize = shard.ShardSize()
+			expireAt = shard.info.ExpireAt
 			existStatus.Exist(shard.info.Vuid.Index())
 			continue
 		}
 	}
 	if !existStatus.CanRecover() {
 		span.Errorf("shard maybe lost: mode[%d], existStatus[%+v]", mode, existStatus)
-		return nil, 0, errcode.ErrShardMayBeLost
+		return nil, 0, 0, errcode.ErrShardMayBeLost
 	}
 
 	// step 3:collect need repair shards
@@ -232,7 +239,7 @@ func getRepairShards(
 			shouldRepairIdx = append(shouldRepairIdx, idx)
 		}
 	}
-	return shouldRepairIdx, shardSize, nil
+	return shouldRepairIdx, shardSize, expireAt, nil
 }
 
 // due to the existence of blob delete and shard repair concurrent scenarios
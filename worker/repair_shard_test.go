@@ -17,6 +17,7 @@ package worker
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -72,7 +73,8 @@ func getRepairShardsTest(ctx context.Context,
 
 	}
 	// get repair shards:
-	return getRepairShards(ctx, shardInfos, mode, badIdxs)
+	repairIdx, shardSize, _, err = getRepairShards(ctx, shardInfos, mode, badIdxs)
+	return
 }
 
 func testGetRepairShards(t *testing.T, mode codemode.CodeMode) {
@@ -239,6 +241,36 @@ func testShardRepair(t *testing.T, mode codemode.CodeMode) {
 	require.EqualError(t, errcode.ErrShardMayBeLost, err.Error())
 }
 
+func TestShardRepairExpired(t *testing.T) {
+	mode := codemode.EC6P6
+	replicas, _ := genMockVol(1, mode)
+	getter := NewMockGetterWithBids(replicas, mode, []proto.BlobID{1, 2}, []int64{1025, 1025})
+	repairer := NewShardRepairer(getter, base.NewByteBufferPool(1024*2, 100))
+
+	expireAt := time.Now().Add(time.Hour).Unix()
+	for _, replica := range replicas {
+		getter.SetExpireAt(replica.Vuid, 1, expireAt)
+		getter.SetExpireAt(replica.Vuid, 2, time.Now().Add(-time.Hour).Unix())
+	}
+
+	// repaired shard carries expire time
+	getter.Delete(context.Background(), replicas[0].Vuid, 1)
+	task := proto.ShardRepairTask{Bid: 1, CodeMode: mode, Sources: replicas, BadIdxs: []uint8{0}}
+	require.NoError(t, repairer.RepairShard(context.Background(), task))
+	si, err := getter.StatShard(context.Background(), replicas[0], 1)
+	require.NoError(t, err)
+	require.True(t, si.Normal())
+	require.Equal(t, expireAt, si.ExpireAt)
+
+	// expired shard is not repaired
+	getter.Delete(context.Background(), replicas[0].Vuid, 2)
+	task.Bid = 2
+	require.NoError(t, repairer.RepairShard(context.Background(), task))
+	si, err = getter.StatShard(context.Background(), replicas[0], 2)
+	require.NoError(t, err)
+	require.True(t, si.NotExist())
+}
+
 func checkRepairShardResult(t *testing.T, getter *MockGetter, replicas []proto.VunitLocation, oldCrcs []uint32) {
 	for _, replica := range replicas {
 		newCrc32 := getter.getShardCrc32(replica.Vuid, 1)
@@ -102,6 +102,8 @@ type Config struct {
 	DiskDropConcurrency int `json:"disk_drop_concurrency"`
 	// tasklet concurrency of single manual migrate task
 	ManualMigrateConcurrency int `json:"manual_migrate_concurrency"`
+	// tasklet concurrency of single volume convert task
+	VolumeConvertConcurrency int `json:"volume_convert_concurrency"`
 	// shard repair concurrency
 	ShardRepairConcurrency int `json:"shard_repair_concurrency"`
 	// volume inspect concurrency
@@ -155,6 +157,7 @@ func (cfg *Config) checkAndFix() (err error) {
 	fixConfigItemInt(&cfg.BalanceConcurrency, 1)
 	fixConfigItemInt(&cfg.DiskDropConcurrency, 1)
 	fixConfigItemInt(&cfg.ManualMigrateConcurrency, 10)
+	fixConfigItemInt(&cfg.VolumeConvertConcurrency, 1)
 	fixConfigItemInt(&cfg.ShardRepairConcurrency, 1)
 	fixConfigItemInt(&cfg.InspectConcurrency, 1)
 	fixConfigItemInt(&cfg.DownloadShardConcurrency, 10)
@@ -198,6 +201,7 @@ func NewService(cfg *Config) (*Service, error) {
 		cfg.BalanceConcurrency,
 		cfg.DiskDropConcurrency,
 		cfg.ManualMigrateConcurrency,
+		cfg.VolumeConvertConcurrency,
 		schedulerCli,
 		&TaskWorkerCreator{})
 
@@ -349,10 +353,10 @@ func (s *Service) tryAcquireTask() {
 }
 
 func (s *Service) hasTaskRunnerResource() bool {
-	repair, balance, drop, manualMig := s.taskRunnerMgr.RunningTaskCnt()
-	log.Infof("task count:repair %d balance %d drop %d manualMig %d max %d",
-		repair, balance, drop, manualMig, s.MaxTaskRunnerCnt)
-	return (repair + balance + drop + manualMig) < s.MaxTaskRunnerCnt
+	repair, balance, drop, manualMig, convert := s.taskRunnerMgr.RunningTaskCnt()
+	log.Infof("task count:repair %d balance %d drop %d manualMig %d convert %d max %d",
+		repair, balance, drop, manualMig, convert, s.MaxTaskRunnerCnt)
+	return (repair + balance + drop + manualMig + convert) < s.MaxTaskRunnerCnt
 }
 
 func (s *Service) hasInspectTaskResource() bool {
@@ -375,8 +379,8 @@ func (s *Service) acquireTask() {
 	}
 
 	if !t.IsValid() {
-		span.Errorf("task is illegal: task type[%s], disk drop[%+v], balance[%+v], repair[%+v], manual[%+v]",
-			t.TaskType, t.DiskDrop, t.Balance, t.Repair, t.ManualMigrate)
+		span.Errorf("task is illegal: task type[%s], disk drop[%+v], balance[%+v], repair[%+v], manual[%+v], convert[%+v]",
+			t.TaskType, t.DiskDrop, t.Balance, t.Repair, t.ManualMigrate, t.VolumeConvert)
 		return
 	}
 
@@ -415,6 +419,13 @@ func (s *Service) acquireTask() {
 			blobNodeCli:              s.blobNodeCli,
 			downloadShardConcurrency: s.DownloadShardConcurrency,
 		})
+	case proto.VolumeConvertType:
+		taskID = t.VolumeConvert.TaskID
+		err = s.taskRunnerMgr.AddVolumeConvertTask(ctx, VolumeConvertTaskEx{
+			taskInfo:                 t.VolumeConvert,
+			downloadShardConcurrency: s.DownloadShardConcurrency,
+			blobNodeCli:              s.blobNodeCli,
+		})
 	default:
 		span.Fatalf("can not support task: type[%+v]", t.TaskType)
 	}
@@ -56,7 +56,11 @@ func (m *mBlobNodeCli) GetShards(ctx context.Context, location proto.VunitLocati
 	return nil, nil
 }
 
-func (m *mBlobNodeCli) PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, body io.Reader) (err error) {
+func (m *mBlobNodeCli) PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, expireAt int64, body io.Reader) (err error) {
+	return
+}
+
+func (m *mBlobNodeCli) DeleteShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (err error) {
 	return
 }
 
@@ -175,12 +179,13 @@ func newMockService() *Service {
 	wf := &mockWorkerFactory{
 		newRepairWorkerFn: NewMockRepairWorker,
 		newMigWorkerFn:    NewmockMigrateWorker,
+		newConvertFn:      NewMockVolumeConvertWorker,
 	}
 	return &Service{
 		shardRepairLimit: count.New(1),
 		inspectTaskMgr:   NewInspectTaskMgr(1, blobnode, scheduler),
 		taskRenter: NewTaskRenter("z0", scheduler, NewTaskRunnerMgr(0, 2, 2,
-			2, 2, scheduler, wf)),
+			2, 2, 2, scheduler, wf)),
 		schedulerCli: scheduler,
 		blobNodeCli:  blobnode,
 		Config:       Config{AcquireIntervalMs: 1},
@@ -190,7 +195,7 @@ func newMockService() *Service {
 		closeOnce: &sync.Once{},
 
 		taskRunnerMgr: NewTaskRunnerMgr(0, 2, 2,
-			2, 2, scheduler, wf),
+			2, 2, 2, scheduler, wf),
 	}
 }
 
@@ -40,7 +40,8 @@ type IVunitAccess interface {
 	StatShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (si *client.ShardInfo, err error)
 	ListShards(ctx context.Context, location proto.VunitLocation) (shards []*client.ShardInfo, err error)
 	GetShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (body io.ReadCloser, crc32 uint32, err error)
-	PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, body io.Reader) (err error)
+	PutShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID, size int64, expireAt int64, body io.Reader) (err error)
+	DeleteShard(ctx context.Context, location proto.VunitLocation, bid proto.BlobID) (err error)
 }
 
 // GenMigrateBids generates migrate blob ids
@@ -114,7 +115,7 @@ func MigrateBids(
 		if err != nil {
 			return OtherError(err)
 		}
-		err = tryPutShard(ctx, vunitAccess, destLocation, bid.Bid, bid.Size, bytes.NewReader(data))
+		err = tryPutShard(ctx, vunitAccess, destLocation, bid.Bid, bid.Size, bid.ExpireAt, bytes.NewReader(data))
 		if err != nil {
 			return DstError(err)
 		}
@@ -129,9 +130,10 @@ func tryPutShard(
 	location proto.VunitLocation,
 	bid proto.BlobID,
 	size int64,
+	expireAt int64,
 	body io.Reader) (err error) {
 	return retry.Timed(3, 1000).On(func() error {
-		return vunitAccess.PutShard(ctx, location, bid, size, body)
+		return vunitAccess.PutShard(ctx, location, bid, size, expireAt, body)
 	})
 }
 
@@ -65,6 +65,7 @@ func (tr *TaskRenter) renewalTask() {
 		Balance:       genRenewalArgs(tr.tm.GetBalanceAliveTask()),
 		DiskDrop:      genRenewalArgs(tr.tm.GetDiskDropAliveTask()),
 		ManualMigrate: genRenewalArgs(tr.tm.GetManualMigrateAliveTask()),
+		VolumeConvert: genRenewalArgs(tr.tm.GetVolumeConvertAliveTask()),
 	}
 
 	ret, err := tr.cli.RenewalTask(ctx, &alive)
@@ -117,6 +118,16 @@ func (tr *TaskRenter) stopRenewalFailTask(ctx context.Context, ret *api.TaskRene
 			}
 		}
 	}
+
+	for taskID, errMsg := range ret.VolumeConvert {
+		if len(errMsg) != 0 {
+			span.Infof("renewal fail should stop: taskID[%s], type[%s]", taskID, proto.VolumeConvertType)
+			err := tr.tm.StopTaskRunner(taskID, proto.VolumeConvertType)
+			if err != nil {
+				span.Errorf("stop task runner failed: taskID[%s], taskType[%s], err[%+v]", taskID, proto.VolumeConvertType, err)
+			}
+		}
+	}
 }
 
 func genRenewalArgs(runners []*TaskRunner) map[string]struct{} {
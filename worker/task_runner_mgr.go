@@ -39,6 +39,9 @@ type TaskRunnerMgr struct {
 	manualMigrate                      map[string]*TaskRunner
 	manualMigrateTaskletRunConcurrency int
 
+	volumeConvert                      map[string]*TaskRunner
+	volumeConvertTaskletRunConcurrency int
+
 	schedulerCli TaskSchedulerCli
 	wf           IWorkerFactory
 	mu           sync.Mutex
@@ -50,6 +53,7 @@ type TaskRunnerMgr struct {
 type IWorkerFactory interface {
 	NewRepairWorker(task VolRepairTaskEx) ITaskWorker
 	NewMigrateWorker(task MigrateTaskEx) ITaskWorker
+	NewVolumeConvertWorker(task VolumeConvertTaskEx) ITaskWorker
 }
 
 // TaskWorkerCreator task worker creator
@@ -65,13 +69,19 @@ func (wf *TaskWorkerCreator) NewMigrateWorker(task MigrateTaskEx) ITaskWorker {
 	return NewMigrateWorker(task)
 }
 
+// NewVolumeConvertWorker returns volume convert worker
+func (wf *TaskWorkerCreator) NewVolumeConvertWorker(task VolumeConvertTaskEx) ITaskWorker {
+	return NewVolumeConvertWorker(task)
+}
+
 // NewTaskRunnerMgr returns task runner manager
 func NewTaskRunnerMgr(
 	shardGetConcurrency,
 	repairTaskletRunConcurrency,
 	balanceTaskletRunConcurrency,
 	diskDropTaskletRunConcurrency,
-	manualMigrateTaskletRunConcurrency int,
+	manualMigrateTaskletRunConcurrency,
+	volumeConvertTaskletRunConcurrency int,
 	schedulerCli TaskSchedulerCli,
 	wf IWorkerFactory,
 ) *TaskRunnerMgr {
@@ -88,6 +98,9 @@ func NewTaskRunnerMgr(
 		manualMigrate:                      make(map[string]*TaskRunner),
 		manualMigrateTaskletRunConcurrency: manualMigrateTaskletRunConcurrency,
 
+		volumeConvert:                      make(map[string]*TaskRunner),
+		volumeConvertTaskletRunConcurrency: volumeConvertTaskletRunConcurrency,
+
 		wf:           wf,
 		schedulerCli: schedulerCli,
 
@@ -180,6 +193,27 @@ func (tm *TaskRunnerMgr) AddManualMigrateTask(ctx context.Context, task MigrateT
 	return nil
 }
 
+// AddVolumeConvertTask adds volume convert task
+func (tm *TaskRunnerMgr) AddVolumeConvertTask(ctx context.Context, task VolumeConvertTaskEx) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	w := tm.wf.NewVolumeConvertWorker(task)
+	runner := NewTaskRunner(
+		ctx,
+		task.taskInfo.TaskID,
+		w, task.taskInfo.SourceIdc,
+		tm.volumeConvertTaskletRunConcurrency,
+		tm.schedulerCli)
+	err := addRunner(tm.volumeConvert, task.taskInfo.TaskID, runner)
+	if err != nil {
+		return err
+	}
+
+	go runner.Run()
+	return nil
+}
+
 // GetRepairAliveTask returns repair alive task runner
 func (tm *TaskRunnerMgr) GetRepairAliveTask() []*TaskRunner {
 	tm.mu.Lock()
@@ -208,6 +242,13 @@ func (tm *TaskRunnerMgr) GetManualMigrateAliveTask() []*TaskRunner {
 	return getAliveTask(tm.manualMigrate)
 }
 
+// GetVolumeConvertAliveTask returns volume convert alive task runner
+func (tm *TaskRunnerMgr) GetVolumeConvertAliveTask() []*TaskRunner {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return getAliveTask(tm.volumeConvert)
+}
+
 // StopTaskRunner stops task runner
 func (tm *TaskRunnerMgr) StopTaskRunner(taskID, taskType string) error {
 	tm.mu.Lock()
@@ -222,6 +263,8 @@ func (tm *TaskRunnerMgr) StopTaskRunner(taskID, taskType string) error {
 		return stopRunner(tm.diskDrop, taskID)
 	case proto.ManualMigrateType:
 		return stopRunner(tm.manualMigrate, taskID)
+	case proto.VolumeConvertType:
+		return stopRunner(tm.volumeConvert, taskID)
 	default:
 		log.Panicf("unknown task type %s", taskType)
 	}
@@ -237,17 +280,18 @@ func (tm *TaskRunnerMgr) StopAllAliveRunner() {
 	runners = append(runners, getAliveTask(tm.balance)...)
 	runners = append(runners, getAliveTask(tm.diskDrop)...)
 	runners = append(runners, getAliveTask(tm.manualMigrate)...)
+	runners = append(runners, getAliveTask(tm.volumeConvert)...)
 	for _, r := range runners {
 		r.Stop()
 	}
 }
 
 // RunningTaskCnt return running task count
-func (tm *TaskRunnerMgr) RunningTaskCnt() (repair, balance, drop, manualMigrate int) {
+func (tm *TaskRunnerMgr) RunningTaskCnt() (repair, balance, drop, manualMigrate, volumeConvert int) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 	tm.removeStoppedRunner()
-	return len(tm.repair), len(tm.balance), len(tm.diskDrop), len(tm.manualMigrate)
+	return len(tm.repair), len(tm.balance), len(tm.diskDrop), len(tm.manualMigrate), len(tm.volumeConvert)
 }
 
 func (tm *TaskRunnerMgr) removeStoppedRunner() {
@@ -255,6 +299,7 @@ func (tm *TaskRunnerMgr) removeStoppedRunner() {
 	tm.balance = removeStoppedRunner(tm.balance)
 	tm.diskDrop = removeStoppedRunner(tm.diskDrop)
 	tm.manualMigrate = removeStoppedRunner(tm.manualMigrate)
+	tm.volumeConvert = removeStoppedRunner(tm.volumeConvert)
 }
 
 func removeStoppedRunner(tasks map[string]*TaskRunner) map[string]*TaskRunner {
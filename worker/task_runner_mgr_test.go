@@ -109,6 +109,13 @@ func NewmockMigrateWorker(task MigrateTaskEx) ITaskWorker {
 	}
 }
 
+func NewMockVolumeConvertWorker(task VolumeConvertTaskEx) ITaskWorker {
+	return &mockMigrateWorker{
+		tasklet:       mocktasklets,
+		taskletRetErr: nil,
+	}
+}
+
 func (w *mockMigrateWorker) GenTasklets(ctx context.Context) ([]Tasklet, *WorkError) {
 	time.Sleep(3600 * time.Second)
 	return w.tasklet, nil
@@ -145,6 +152,7 @@ func (w *mockMigrateWorker) GetBenchmarkBids() []*ShardInfoSimple {
 type mockWorkerFactory struct {
 	newRepairWorkerFn func(task VolRepairTaskEx) ITaskWorker
 	newMigWorkerFn    func(task MigrateTaskEx) ITaskWorker
+	newConvertFn      func(task VolumeConvertTaskEx) ITaskWorker
 }
 
 func (mwf *mockWorkerFactory) NewRepairWorker(task VolRepairTaskEx) ITaskWorker {
@@ -155,6 +163,10 @@ func (mwf *mockWorkerFactory) NewMigrateWorker(task MigrateTaskEx) ITaskWorker {
 	return mwf.newMigWorkerFn(task)
 }
 
+func (mwf *mockWorkerFactory) NewVolumeConvertWorker(task VolumeConvertTaskEx) ITaskWorker {
+	return mwf.newConvertFn(task)
+}
+
 type mockScheCli struct {
 	cancelRet   error
 	completeRet error
@@ -190,8 +202,9 @@ func initTestTaskRunnerMgr(t *testing.T, taskCnt int) *TaskRunnerMgr {
 	wf := mockWorkerFactory{
 		newRepairWorkerFn: NewMockRepairWorker,
 		newMigWorkerFn:    NewmockMigrateWorker,
+		newConvertFn:      NewMockVolumeConvertWorker,
 	}
-	tm := NewTaskRunnerMgr(0, 2, 2, 2, 2, &cli, &wf)
+	tm := NewTaskRunnerMgr(0, 2, 2, 2, 2, 2, &cli, &wf)
 	ctx := context.Background()
 	for i := 0; i < taskCnt; i++ {
 		taskID := fmt.Sprintf("repair_%d", i+1)
@@ -219,6 +232,15 @@ func initTestTaskRunnerMgr(t *testing.T, taskCnt int) *TaskRunnerMgr {
 		err := tm.AddDiskDropTask(ctx, task)
 		require.NoError(t, err)
 	}
+
+	for i := 0; i < taskCnt; i++ {
+		taskID := fmt.Sprintf("volumeConvert_%d", i+1)
+		task := VolumeConvertTaskEx{
+			taskInfo: &proto.VolumeConvertTask{TaskID: taskID},
+		}
+		err := tm.AddVolumeConvertTask(ctx, task)
+		require.NoError(t, err)
+	}
 	return tm
 }
 
@@ -228,9 +250,11 @@ func TestTaskRunnerMgr(t *testing.T) {
 	require.Equal(t, 10, len(tm.GetRepairAliveTask()))
 	require.Equal(t, 10, len(tm.GetBalanceAliveTask()))
 	require.Equal(t, 10, len(tm.GetDiskDropAliveTask()))
+	require.Equal(t, 10, len(tm.GetVolumeConvertAliveTask()))
 
 	tm.StopAllAliveRunner()
 	require.Equal(t, 0, len(tm.GetRepairAliveTask()))
 	require.Equal(t, 0, len(tm.GetBalanceAliveTask()))
 	require.Equal(t, 0, len(tm.GetDiskDropAliveTask()))
+	require.Equal(t, 0, len(tm.GetVolumeConvertAliveTask()))
 }
@@ -0,0 +1,286 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/cubefs/blobstore/common/codemode"
+	"github.com/cubefs/blobstore/common/ec"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/common/trace"
+	"github.com/cubefs/blobstore/worker/base"
+)
+
+// volume convert task use
+
+var (
+	convertEncodersMu sync.Mutex
+	convertEncoders   = make(map[codemode.CodeMode]ec.Encoder)
+)
+
+func getConvertEncoder(mode codemode.CodeMode) (ec.Encoder, error) {
+	convertEncodersMu.Lock()
+	defer convertEncodersMu.Unlock()
+
+	if encoder, ok := convertEncoders[mode]; ok {
+		return encoder, nil
+	}
+	encoder, err := ec.NewEncoder(&ec.Config{CodeMode: mode.Tactic(), EnableVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	convertEncoders[mode] = encoder
+	return encoder, nil
+}
+
+// VolumeConvertTaskEx volume convert task execution machine
+type VolumeConvertTaskEx struct {
+	taskInfo                 *proto.VolumeConvertTask
+	downloadShardConcurrency int
+	blobNodeCli              IVunitAccess
+}
+
+// VolumeConvertWorker re-encodes blobs of source volume into destination volume
+type VolumeConvertWorker struct {
+	t                        *proto.VolumeConvertTask
+	blobNodeCli              IVunitAccess
+	benchmarkBids            []*ShardInfoSimple
+	downloadShardConcurrency int
+}
+
+// NewVolumeConvertWorker returns volume convert worker
+func NewVolumeConvertWorker(task VolumeConvertTaskEx) ITaskWorker {
+	return &VolumeConvertWorker{
+		t:                        task.taskInfo,
+		blobNodeCli:              task.blobNodeCli,
+		downloadShardConcurrency: task.downloadShardConcurrency,
+	}
+}
+
+// destShardSize returns shard size in destination volume of the bid
+// whose shard size in source volume is srcShardSize
+func (w *VolumeConvertWorker) destShardSize(srcShardSize int64) (int64, error) {
+	if srcShardSize == 0 {
+		return 0, nil
+	}
+	dataSize := int(srcShardSize) * w.t.SourceCodeMode.Tactic().N
+	sizes, err := ec.GetBufferSizes(dataSize, w.t.DestinationCodeMode.Tactic())
+	if err != nil {
+		return 0, err
+	}
+	return int64(sizes.ShardSize), nil
+}
+
+// GenTasklets generates volume convert tasklets
+func (w *VolumeConvertWorker) GenTasklets(ctx context.Context) ([]Tasklet, *WorkError) {
+	span := trace.SpanFromContextSafe(ctx)
+	if base.BigBufPool == nil {
+		panic("BigBufPool should init before")
+	}
+
+	benchmarkBids, err := GetBenchmarkBids(ctx, w.blobNodeCli, w.t.Sources, w.t.SourceCodeMode, []uint8{})
+	if err != nil {
+		span.Errorf("get benchmark bids failed: err[%v]", err)
+		return nil, SrcError(err)
+	}
+	w.benchmarkBids = benchmarkBids
+
+	// blobs are deleted in destination volume after redirected, converting them again
+	// resurrects the deleted blobs, only deletes landed on source are replayed in check
+	if w.t.Redirected() {
+		span.Infof("source volume %d has been redirected, no bid need convert", w.t.SourceVid)
+		return nil, nil
+	}
+
+	// the bid which has been put into all destination units is not need to convert again
+	converted := make(map[proto.BlobID]int, len(benchmarkBids))
+	for _, dest := range w.t.Destinations {
+		destBids, err := GetSingleVunitNormalBids(ctx, w.blobNodeCli, dest)
+		if err != nil {
+			span.Errorf("get single vunit normal bids failed: dest[%+v], err[%+v]", dest, err)
+			return nil, OtherError(err)
+		}
+		for _, bid := range destBids {
+			converted[bid.Bid]++
+		}
+	}
+
+	var convertBids []*ShardInfoSimple
+	for _, bid := range benchmarkBids {
+		if converted[bid.Bid] == len(w.t.Destinations) {
+			span.Debugf("benchmarkBids bid exist in all dest: bid[%d]", bid.Bid)
+			continue
+		}
+		convertBids = append(convertBids, bid)
+	}
+
+	span.Debugf("task info: benchmarkBids size[%d], need convert bids size[%d]", len(benchmarkBids), len(convertBids))
+	tasklets := BidsSplit(ctx, convertBids, base.BigBufPool.GetBufSize())
+	return tasklets, nil
+}
+
+// ExecTasklet execute volume convert tasklet
+func (w *VolumeConvertWorker) ExecTasklet(ctx context.Context, tasklet Tasklet) *WorkError {
+	span := trace.SpanFromContextSafe(ctx)
+
+	srcN := w.t.SourceCodeMode.Tactic().N
+	dataIdxs := make([]uint8, srcN)
+	for i := range dataIdxs {
+		dataIdxs[i] = uint8(i)
+	}
+
+	shardRecover := NewShardRecover(w.t.Sources, w.t.SourceCodeMode, tasklet.bids, base.BigBufPool,
+		w.blobNodeCli, w.downloadShardConcurrency)
+	defer shardRecover.ReleaseBuf()
+
+	// step1 get data shards of source volume, recover them by ec if can not download
+	span.Infof("recover data shards: len bids[%d]", len(tasklet.bids))
+	if err := shardRecover.RecoverShards(ctx, dataIdxs, true); err != nil {
+		return SrcError(err)
+	}
+
+	encoder, err := getConvertEncoder(w.t.DestinationCodeMode)
+	if err != nil {
+		return OtherError(err)
+	}
+
+	// step2 encode data with destination code mode and put shards to destination
+	dstTactic := w.t.DestinationCodeMode.Tactic()
+	for _, bid := range tasklet.bids {
+		if bid.Size == 0 {
+			for _, dest := range w.t.Destinations {
+				if err = tryPutShard(ctx, w.blobNodeCli, dest, bid.Bid, 0, bid.ExpireAt, bytes.NewReader(nil)); err != nil {
+					return OtherError(err)
+				}
+			}
+			continue
+		}
+
+		sizes, err := ec.GetBufferSizes(srcN*int(bid.Size), dstTactic)
+		if err != nil {
+			return OtherError(err)
+		}
+
+		buf := make([]byte, sizes.ECSize)
+		for idx := 0; idx < srcN; idx++ {
+			data, err := shardRecover.GetShard(uint8(idx), bid.Bid)
+			if err != nil {
+				return OtherError(err)
+			}
+			copy(buf[idx*int(bid.Size):], data)
+		}
+
+		shards, err := encoder.Split(buf[:sizes.ECDataSize])
+		if err != nil {
+			return OtherError(err)
+		}
+		if err = encoder.Encode(shards); err != nil {
+			return OtherError(err)
+		}
+
+		for _, dest := range w.t.Destinations {
+			shard := shards[dest.Vuid.Index()]
+			err = tryPutShard(ctx, w.blobNodeCli, dest, bid.Bid, int64(len(shard)), bid.ExpireAt, bytes.NewReader(shard))
+			if err != nil {
+				// destination volume can not be reclaimed, so retry the task later
+				return OtherError(err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check replays deletes of source volume to destination units,
+// and checks all destination units have the converted bids
+func (w *VolumeConvertWorker) Check(ctx context.Context) *WorkError {
+	span := trace.SpanFromContextSafe(ctx)
+
+	// bids may be deleted in source volume during converting, list them again
+	srcBids, err := GetBenchmarkBids(ctx, w.blobNodeCli, w.t.Sources, w.t.SourceCodeMode, []uint8{})
+	if err != nil {
+		span.Errorf("get benchmark bids failed: err[%v]", err)
+		return SrcError(err)
+	}
+	srcBidsMap := make(map[proto.BlobID]struct{}, len(srcBids))
+	for _, bid := range srcBids {
+		srcBidsMap[bid.Bid] = struct{}{}
+	}
+
+	for _, dest := range w.t.Destinations {
+		destBids, err := GetSingleVunitNormalBids(ctx, w.blobNodeCli, dest)
+		if err != nil {
+			span.Errorf("get single vunit normal bids failed: dest[%+v], err[%+v]", dest, err)
+			return OtherError(err)
+		}
+		for _, bid := range destBids {
+			if _, ok := srcBidsMap[bid.Bid]; ok {
+				continue
+			}
+			span.Debugf("replay delete of source: dest[%+v], bid[%d]", dest, bid.Bid)
+			if err = w.blobNodeCli.DeleteShard(ctx, dest, bid.Bid); err != nil {
+				return OtherError(err)
+			}
+		}
+	}
+
+	// blobs deleted after redirected are absent in destination
+	if w.t.Redirected() {
+		return nil
+	}
+
+	expectBids := make([]*ShardInfoSimple, 0, len(srcBids))
+	for _, bid := range srcBids {
+		size, err := w.destShardSize(bid.Size)
+		if err != nil {
+			return OtherError(err)
+		}
+		expectBids = append(expectBids, &ShardInfoSimple{Bid: bid.Bid, Size: size})
+	}
+
+	for _, dest := range w.t.Destinations {
+		if wErr := CheckVunit(ctx, expectBids, dest, w.blobNodeCli); wErr != nil {
+			return OtherError(wErr.err)
+		}
+	}
+	return nil
+}
+
+// CancelArgs returns cancel args
+func (w *VolumeConvertWorker) CancelArgs() (taskID, taskType string, src []proto.VunitLocation, dest proto.VunitLocation) {
+	return w.t.TaskID, proto.VolumeConvertType, w.t.Sources, w.t.GetDest()
+}
+
+// CompleteArgs returns complete args
+func (w *VolumeConvertWorker) CompleteArgs() (taskID, taskType string, src []proto.VunitLocation, dest proto.VunitLocation) {
+	return w.t.TaskID, proto.VolumeConvertType, w.t.Sources, w.t.GetDest()
+}
+
+// ReclaimArgs returns reclaim args
+func (w *VolumeConvertWorker) ReclaimArgs() (taskID, taskType string, src []proto.VunitLocation, dest proto.VunitLocation) {
+	return w.t.TaskID, proto.VolumeConvertType, w.t.Sources, w.t.GetDest()
+}
+
+// TaskType returns task type
+func (w *VolumeConvertWorker) TaskType() (taskType string) {
+	return proto.VolumeConvertType
+}
+
+// GetBenchmarkBids returns benchmark bids
+func (w *VolumeConvertWorker) GetBenchmarkBids() []*ShardInfoSimple {
+	return w.benchmarkBids
+}
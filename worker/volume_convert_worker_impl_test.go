@@ -0,0 +1,197 @@
+// Copyright 2022 The CubeFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	api "github.com/cubefs/blobstore/api/blobnode"
+	"github.com/cubefs/blobstore/common/codemode"
+	"github.com/cubefs/blobstore/common/ec"
+	"github.com/cubefs/blobstore/common/proto"
+	"github.com/cubefs/blobstore/util/errors"
+	"github.com/cubefs/blobstore/worker/base"
+)
+
+func genMockVolumeConvertTask(srcMode, dstMode codemode.CodeMode) (*proto.VolumeConvertTask, *MockGetter) {
+	sources, _ := genMockVol(1, srcMode)
+	destinations, _ := genMockVol(2, dstMode)
+	getter := NewMockGetter(sources, srcMode)
+	for _, dest := range destinations {
+		getter.vunits[dest.Vuid] = newMockVunit(dest.Vuid, api.ChunkStatusNormal)
+	}
+	task := &proto.VolumeConvertTask{
+		TaskID:              "mock_task_id",
+		State:               proto.VolumeConvertStatePrepared,
+		SourceVid:           1,
+		SourceCodeMode:      srcMode,
+		Sources:             sources,
+		DestinationVid:      2,
+		DestinationCodeMode: dstMode,
+		Destinations:        destinations,
+	}
+	return task, getter
+}
+
+func readMockShard(t *testing.T, getter *MockGetter, location proto.VunitLocation, bid proto.BlobID) []byte {
+	body, _, err := getter.GetShard(context.Background(), location, bid)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+	return data
+}
+
+func TestVolumeConvertWorker(t *testing.T) {
+	ctx := context.Background()
+	srcMode, dstMode := codemode.EC6P6, codemode.EC12P4
+	task, getter := genMockVolumeConvertTask(srcMode, dstMode)
+	// data shards of source can be recovered by parity shards
+	getter.setFail(task.Sources[0].Vuid, errors.New("fake error"))
+
+	base.BigBufPool = base.NewByteBufferPool(2*1024, 500)
+	w := NewVolumeConvertWorker(VolumeConvertTaskEx{taskInfo: task, blobNodeCli: getter, downloadShardConcurrency: 1})
+	require.Equal(t, proto.VolumeConvertType, w.TaskType())
+
+	tasklets, wErr := w.GenTasklets(ctx)
+	require.Nil(t, wErr)
+	require.Equal(t, len(getter.getBids()), len(w.GetBenchmarkBids()))
+	require.Error(t, w.Check(ctx))
+
+	for _, tasklet := range tasklets {
+		require.Nil(t, w.ExecTasklet(ctx, tasklet))
+	}
+	require.Nil(t, w.Check(ctx))
+
+	// destination data shards carry the whole encoded data of source blob
+	getter.setWell(task.Sources[0].Vuid)
+	srcN, dstN := srcMode.Tactic().N, dstMode.Tactic().N
+	for i, bid := range getter.getBids() {
+		size := getter.getSizes()[i]
+		var expected []byte
+		for idx := 0; idx < srcN; idx++ {
+			expected = append(expected, readMockShard(t, getter, task.Sources[idx], bid)...)
+		}
+		var actual []byte
+		for idx := 0; idx < dstN; idx++ {
+			actual = append(actual, readMockShard(t, getter, task.Destinations[idx], bid)...)
+		}
+		if size == 0 {
+			require.Equal(t, 0, len(actual))
+			continue
+		}
+		sizes, err := ec.GetBufferSizes(srcN*int(size), dstMode.Tactic())
+		require.NoError(t, err)
+		require.Equal(t, sizes.ECDataSize, len(actual))
+		require.True(t, bytes.Equal(expected, actual[:len(expected)]))
+	}
+
+	// all bids have been converted
+	tasklets, wErr = w.GenTasklets(ctx)
+	require.Nil(t, wErr)
+	require.Equal(t, 0, len(tasklets))
+
+	// destination error can not reclaim task
+	getter.setFail(task.Destinations[0].Vuid, errors.New("fake error"))
+	_, wErr = w.GenTasklets(ctx)
+	require.Equal(t, OtherErr, wErr.errType)
+	wErr = w.Check(ctx)
+	require.Equal(t, OtherErr, wErr.errType)
+	getter.setWell(task.Destinations[0].Vuid)
+
+	getter.Delete(ctx, task.Destinations[3].Vuid, 2)
+	wErr = w.Check(ctx)
+	require.Equal(t, OtherErr, wErr.errType)
+	tasklets, wErr = w.GenTasklets(ctx)
+	require.Nil(t, wErr)
+	require.Equal(t, 1, len(tasklets))
+	require.Equal(t, proto.BlobID(2), tasklets[0].bids[0].Bid)
+
+	taskID, taskType, src, dest := w.CompleteArgs()
+	require.Equal(t, task.TaskID, taskID)
+	require.Equal(t, proto.VolumeConvertType, taskType)
+	require.Equal(t, task.Sources, src)
+	require.Equal(t, task.Destinations[0], dest)
+}
+
+func TestVolumeConvertWorkerSourceFail(t *testing.T) {
+	ctx := context.Background()
+	task, getter := genMockVolumeConvertTask(codemode.EC6P6, codemode.EC12P4)
+
+	base.BigBufPool = base.NewByteBufferPool(2*1024, 500)
+	w := NewVolumeConvertWorker(VolumeConvertTaskEx{taskInfo: task, blobNodeCli: getter, downloadShardConcurrency: 1})
+	tasklets, wErr := w.GenTasklets(ctx)
+	require.Nil(t, wErr)
+
+	// too many broken source units to recover data
+	for _, src := range task.Sources[:7] {
+		getter.setFail(src.Vuid, errors.New("fake error"))
+	}
+	for _, tasklet := range tasklets {
+		wErr = w.ExecTasklet(ctx, tasklet)
+		require.NotNil(t, wErr)
+		require.Equal(t, SrcErr, wErr.errType)
+	}
+}
+
+func TestVolumeConvertWorkerReplayDelete(t *testing.T) {
+	ctx := context.Background()
+	task, getter := genMockVolumeConvertTask(codemode.EC6P6, codemode.EC12P4)
+
+	base.BigBufPool = base.NewByteBufferPool(2*1024, 500)
+	w := NewVolumeConvertWorker(VolumeConvertTaskEx{taskInfo: task, blobNodeCli: getter, downloadShardConcurrency: 1})
+	tasklets, wErr := w.GenTasklets(ctx)
+	require.Nil(t, wErr)
+	for _, tasklet := range tasklets {
+		require.Nil(t, w.ExecTasklet(ctx, tasklet))
+	}
+
+	// blob deleted in source during converting is deleted in destination too
+	deleted := getter.getBids()[0]
+	for _, src := range task.Sources {
+		getter.MarkDelete(ctx, src.Vuid, deleted)
+	}
+	require.Nil(t, w.Check(ctx))
+	for _, dest := range task.Destinations {
+		si, err := getter.StatShard(ctx, dest, deleted)
+		require.NoError(t, err)
+		require.False(t, si.Normal())
+	}
+
+	// blob deleted in destination after redirected is not converted again
+	task.RedirectTime = 1
+	redirectDeleted := getter.getBids()[1]
+	for _, dest := range task.Destinations {
+		getter.Delete(ctx, dest.Vuid, redirectDeleted)
+	}
+	tasklets, wErr = w.GenTasklets(ctx)
+	require.Nil(t, wErr)
+	require.Equal(t, 0, len(tasklets))
+	require.Nil(t, w.Check(ctx))
+
+	getter.setFail(task.Destinations[0].Vuid, errors.New("fake error"))
+	wErr = w.Check(ctx)
+	require.Equal(t, OtherErr, wErr.errType)
+	getter.setWell(task.Destinations[0].Vuid)
+	for _, src := range task.Sources[:7] {
+		getter.setFail(src.Vuid, errors.New("fake error"))
+	}
+	wErr = w.Check(ctx)
+	require.Equal(t, SrcErr, wErr.errType)
+}